@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// getBatch returns the batch parsed by middleware.Body. If it has too
+// many items, it writes a 422 and returns false.
+func getBatch[C, U any](c *gin.Context) (web.BatchRequest[C, U], bool) {
+	req := middleware.GetBody[web.BatchRequest[C, U]](c)
+	if req.Len() > web.MaxBatchSize {
+		web.Error(c, http.StatusUnprocessableEntity, "a batch can have at most %d items", web.MaxBatchSize)
+		return req, false
+	}
+	return req, true
+}
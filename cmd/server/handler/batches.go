@@ -1,10 +1,13 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/batches"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
 	"github.com/gin-gonic/gin"
@@ -27,6 +30,22 @@ type CreateBatchesRequest struct {
 	SectionID          int    `binding:"required" json:"section_id"`
 }
 
+// BatchUpdateRequest contains pointers so that the Handler is able to
+// distinguish between omitted (nil) and given (not-nil) fields.
+type BatchUpdateRequest struct {
+	CurrentQuantity    *int    `json:"current_quantity"`
+	CurrentTemperature *int    `json:"current_temperature"`
+	Reason             *string `binding:"required" json:"reason"`
+}
+
+type BatchMoveRequest struct {
+	SectionID *int `binding:"required" json:"section_id"`
+}
+
+type BatchDisposeRequest struct {
+	Reason *string `binding:"required" json:"reason"`
+}
+
 func ConvertDate(c CreateBatchesRequest) (batches.CreateBatches, error) {
 	DueDate, err := time.Parse("2006-01-02", c.DueDate)
 	if err != nil {
@@ -63,9 +82,11 @@ func NewBatches(s batches.Service) *Batches {
 // @Accept		json
 // @Produce	json
 // @Param		request	body	CreateBatchesRequest	true	"Batch data"
+// @Param		Idempotency-Key	header	string	false	"Makes retries of the request return its first response"
 // @Success	201	{object}	web.response	"Created batch"
 // @Failure	400	{object}	web.errorResponse	"Invalid request body"
 // @Failure	409	{object}	web.errorResponse	"Batch number already exists"
+// @Failure	422	{object}	web.errorResponse	"Idempotency-Key reused for a different request"
 // @Failure	500	{object}	web.errorResponse	"Failed to create batch"
 // @Router	/api/v1/batches [post]
 func (s *Batches) Create() gin.HandlerFunc {
@@ -89,3 +110,209 @@ func (s *Batches) Create() gin.HandlerFunc {
 		web.Success(c, http.StatusCreated, batch)
 	}
 }
+
+// GetAll godoc
+//
+// @Summary	List batches
+// @Tags		Batches
+// @Produce	json
+// @Param		product_id	query	int	false	"Only batches of this product"
+// @Param		section_id	query	int	false	"Only batches in this section"
+// @Success	200	{object}	web.response	"List of batches"
+// @Failure	400	{object}	web.errorResponse	"Invalid filter"
+// @Failure	500	{object}	web.errorResponse	"Failed to list batches"
+// @Router	/api/v1/product-batches [get]
+func (s *Batches) GetAll() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var f batches.Filter
+		var err error
+		if f.ProductID, err = intQuery(c, "product_id"); err != nil {
+			web.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if f.SectionID, err = intQuery(c, "section_id"); err != nil {
+			web.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		list, err := s.service.List(c.Request.Context(), f)
+		if err != nil {
+			web.Error(c, checkErrorStatusBatches(err), err.Error())
+			return
+		}
+		web.Success(c, http.StatusOK, list)
+	}
+}
+
+// Get godoc
+//
+// @Summary	Get batch by ID
+// @Tags		Batches
+// @Produce	json
+// @Param		id	path	int	true	"Batch ID"
+// @Param		If-None-Match	header	string	false	"ETag of the cached batch"
+// @Success	200	{object}	web.response	"Batch with the given ID"
+// @Header		200	{string}	ETag	"Version of the batch"
+// @Success	304	"Batch did not change"
+// @Failure	400	{object}	web.errorResponse	"Invalid ID type"
+// @Failure	404	{object}	web.errorResponse	"Batch not found"
+// @Failure	500	{object}	web.errorResponse	"Failed to get batch"
+// @Router	/api/v1/product-batches/{id} [get]
+func (s *Batches) Get() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		batch, err := s.service.Get(c.Request.Context(), c.GetInt("id"))
+		if err != nil {
+			web.Error(c, checkErrorStatusBatches(err), err.Error())
+			return
+		}
+		if web.NotModified(c, batch.Version) {
+			return
+		}
+		web.Success(c, http.StatusOK, batch)
+	}
+}
+
+// Update godoc
+//
+// @Summary	Adjust the quantity or temperature of a batch
+// @Description	reason must be one of recount, damaged, returned or temperature_check.
+// @Tags		Batches
+// @Accept		json
+// @Produce	json
+// @Param		id		path	int					true	"Batch ID"
+// @Param		request	body	BatchUpdateRequest	true	"New quantity or temperature"
+// @Param		If-Match	header	string	false	"ETag the batch must have"
+// @Success	200	{object}	web.response	"Adjusted batch"
+// @Header		200	{string}	ETag	"New version of the batch"
+// @Failure	400	{object}	web.errorResponse	"Invalid ID type"
+// @Failure	404	{object}	web.errorResponse	"Batch not found"
+// @Failure	412	{object}	web.errorResponse	"Batch changed since it was read"
+// @Failure	422	{object}	web.errorResponse	"Invalid reason, quantity or missing fields"
+// @Failure	500	{object}	web.errorResponse	"Failed to adjust batch"
+// @Router	/api/v1/product-batches/{id} [patch]
+func (s *Batches) Update() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := middleware.GetBody[BatchUpdateRequest](c)
+		dto := batches.AdjustDTO{
+			CurrentQuantity:    *optional.FromPtr(req.CurrentQuantity),
+			CurrentTemperature: *optional.FromPtr(req.CurrentTemperature),
+			Reason:             *req.Reason,
+		}
+
+		batch, err := s.service.Adjust(c.Request.Context(), c.GetInt("id"), dto)
+		if err != nil {
+			web.Error(c, checkErrorStatusBatches(err), err.Error())
+			return
+		}
+		web.SetETag(c, batch.Version)
+		web.Success(c, http.StatusOK, batch)
+	}
+}
+
+// Move godoc
+//
+// @Summary	Move a batch to another section
+// @Tags		Batches
+// @Accept		json
+// @Produce	json
+// @Param		id		path	int					true	"Batch ID"
+// @Param		request	body	BatchMoveRequest	true	"Target section"
+// @Param		If-Match	header	string	false	"ETag the batch must have"
+// @Success	200	{object}	web.response	"Moved batch"
+// @Header		200	{string}	ETag	"New version of the batch"
+// @Failure	400	{object}	web.errorResponse	"Invalid ID type"
+// @Failure	404	{object}	web.errorResponse	"Batch not found"
+// @Failure	409	{object}	web.errorResponse	"Section not found, incompatible or batch depleted"
+// @Failure	412	{object}	web.errorResponse	"Batch changed since it was read"
+// @Failure	422	{object}	web.errorResponse	"Batch is already in the section"
+// @Failure	500	{object}	web.errorResponse	"Failed to move batch"
+// @Router	/api/v1/product-batches/{id}/move [post]
+func (s *Batches) Move() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := middleware.GetBody[BatchMoveRequest](c)
+
+		batch, err := s.service.Move(c.Request.Context(), c.GetInt("id"), *req.SectionID)
+		if err != nil {
+			web.Error(c, checkErrorStatusBatches(err), err.Error())
+			return
+		}
+		web.SetETag(c, batch.Version)
+		web.Success(c, http.StatusOK, batch)
+	}
+}
+
+// Dispose godoc
+//
+// @Summary	Dispose of the remaining stock of a batch
+// @Description	reason must be one of expired, damaged, contaminated or recalled.
+// @Tags		Batches
+// @Accept		json
+// @Produce	json
+// @Param		id		path	int					true	"Batch ID"
+// @Param		request	body	BatchDisposeRequest	true	"Reason for disposal"
+// @Param		If-Match	header	string	false	"ETag the batch must have"
+// @Success	200	{object}	web.response	"Disposed batch"
+// @Header		200	{string}	ETag	"New version of the batch"
+// @Failure	400	{object}	web.errorResponse	"Invalid ID type"
+// @Failure	404	{object}	web.errorResponse	"Batch not found"
+// @Failure	409	{object}	web.errorResponse	"Batch has no stock left"
+// @Failure	412	{object}	web.errorResponse	"Batch changed since it was read"
+// @Failure	422	{object}	web.errorResponse	"Invalid reason or batch not expired"
+// @Failure	500	{object}	web.errorResponse	"Failed to dispose of batch"
+// @Router	/api/v1/product-batches/{id}/dispose [post]
+func (s *Batches) Dispose() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := middleware.GetBody[BatchDisposeRequest](c)
+
+		batch, err := s.service.Dispose(c.Request.Context(), c.GetInt("id"), *req.Reason)
+		if err != nil {
+			web.Error(c, checkErrorStatusBatches(err), err.Error())
+			return
+		}
+		web.SetETag(c, batch.Version)
+		web.Success(c, http.StatusOK, batch)
+	}
+}
+
+// History godoc
+//
+// @Summary	List the movements of a batch
+// @Tags		Batches
+// @Produce	json
+// @Param		id	path	int	true	"Batch ID"
+// @Success	200	{object}	web.response	"Movements of the batch, oldest first"
+// @Failure	400	{object}	web.errorResponse	"Invalid ID type"
+// @Failure	404	{object}	web.errorResponse	"Batch not found"
+// @Failure	500	{object}	web.errorResponse	"Failed to get history"
+// @Router	/api/v1/product-batches/{id}/history [get]
+func (s *Batches) History() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		movements, err := s.service.History(c.Request.Context(), c.GetInt("id"))
+		if err != nil {
+			web.Error(c, checkErrorStatusBatches(err), err.Error())
+			return
+		}
+		web.Success(c, http.StatusOK, movements)
+	}
+}
+
+func checkErrorStatusBatches(err error) int {
+	switch {
+	case errors.Is(err, batches.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, batches.ErrSectionNotFound),
+		errors.Is(err, batches.ErrIncompatibleSection),
+		errors.Is(err, batches.ErrDepleted),
+		errors.Is(err, batches.ErrInvalidBatchNumber):
+		return http.StatusConflict
+	case errors.Is(err, batches.ErrSameSection),
+		errors.Is(err, batches.ErrNotExpired),
+		errors.Is(err, batches.ErrInvalidReason),
+		errors.Is(err, batches.ErrInvalidQuantity),
+		errors.Is(err, batches.ErrNoChanges):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, sqlutil.ErrVersionMismatch):
+		return http.StatusPreconditionFailed
+	}
+	return http.StatusInternalServerError
+}
@@ -2,6 +2,7 @@ package handler_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"testing"
@@ -9,6 +10,8 @@ import (
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/cmd/server/handler"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/batches"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
 	"github.com/gin-gonic/gin"
@@ -147,10 +150,154 @@ func TestCreateBatches(t *testing.T) {
 
 }
 
+func TestBatchesLifecycle(t *testing.T) {
+	t.Run("should list batches of a product", func(t *testing.T) {
+		batchesServiceMock := BatchesServiceMock{}
+		server := getBatchesServer(handler.NewBatches(&batchesServiceMock))
+
+		expected := []domain.Batches{{ID: 1, ProductID: 2}}
+		filter := batches.Filter{ProductID: *optional.FromVal(2)}
+		batchesServiceMock.On("List", mock.Anything, filter).Return(expected, nil)
+
+		request, response := testutil.MakeRequest(http.MethodGet, BATCHES_URL+"?product_id=2", nil)
+		server.ServeHTTP(response, request)
+
+		var received testutil.SuccessResponse[[]domain.Batches]
+		json.Unmarshal(response.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, expected, received.Data)
+	})
+	t.Run("should return 400 when filter is invalid", func(t *testing.T) {
+		batchesServiceMock := BatchesServiceMock{}
+		server := getBatchesServer(handler.NewBatches(&batchesServiceMock))
+
+		request, response := testutil.MakeRequest(http.MethodGet, BATCHES_URL+"?section_id=abc", nil)
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+	t.Run("should return 404 when batch is not found", func(t *testing.T) {
+		batchesServiceMock := BatchesServiceMock{}
+		server := getBatchesServer(handler.NewBatches(&batchesServiceMock))
+
+		batchesServiceMock.On("Get", mock.Anything, 9).Return(domain.Batches{}, batches.ErrNotFound)
+
+		request, response := testutil.MakeRequest(http.MethodGet, BATCHES_URL+"/9", nil)
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+	t.Run("should adjust a batch", func(t *testing.T) {
+		batchesServiceMock := BatchesServiceMock{}
+		server := getBatchesServer(handler.NewBatches(&batchesServiceMock))
+
+		expected := domain.Batches{ID: 1, CurrentQuantity: 7}
+		dto := batches.AdjustDTO{CurrentQuantity: *optional.FromVal(7), Reason: batches.ReasonRecount}
+		batchesServiceMock.On("Adjust", mock.Anything, 1, dto).Return(expected, nil)
+
+		body := handler.BatchUpdateRequest{CurrentQuantity: testutil.ToPtr(7), Reason: testutil.ToPtr(batches.ReasonRecount)}
+		request, response := testutil.MakeRequest(http.MethodPatch, BATCHES_URL+"/1", body)
+		server.ServeHTTP(response, request)
+
+		var received testutil.SuccessResponse[domain.Batches]
+		json.Unmarshal(response.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, expected, received.Data)
+	})
+	t.Run("should return 422 when reason is missing", func(t *testing.T) {
+		batchesServiceMock := BatchesServiceMock{}
+		server := getBatchesServer(handler.NewBatches(&batchesServiceMock))
+
+		body := handler.BatchUpdateRequest{CurrentQuantity: testutil.ToPtr(7)}
+		request, response := testutil.MakeRequest(http.MethodPatch, BATCHES_URL+"/1", body)
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, response.Code)
+	})
+	t.Run("should return 409 when moving to an incompatible section", func(t *testing.T) {
+		batchesServiceMock := BatchesServiceMock{}
+		server := getBatchesServer(handler.NewBatches(&batchesServiceMock))
+
+		batchesServiceMock.On("Move", mock.Anything, 1, 3).Return(domain.Batches{}, batches.ErrIncompatibleSection)
+
+		body := handler.BatchMoveRequest{SectionID: testutil.ToPtr(3)}
+		request, response := testutil.MakeRequest(http.MethodPost, BATCHES_URL+"/1/move", body)
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusConflict, response.Code)
+	})
+	t.Run("should return 422 when disposing of a batch that is not expired", func(t *testing.T) {
+		batchesServiceMock := BatchesServiceMock{}
+		server := getBatchesServer(handler.NewBatches(&batchesServiceMock))
+
+		batchesServiceMock.On("Dispose", mock.Anything, 1, batches.ReasonExpired).Return(domain.Batches{}, batches.ErrNotExpired)
+
+		body := handler.BatchDisposeRequest{Reason: testutil.ToPtr(batches.ReasonExpired)}
+		request, response := testutil.MakeRequest(http.MethodPost, BATCHES_URL+"/1/dispose", body)
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, response.Code)
+	})
+	t.Run("should send the new ETag when a batch is disposed of", func(t *testing.T) {
+		batchesServiceMock := BatchesServiceMock{}
+		server := getBatchesServer(handler.NewBatches(&batchesServiceMock))
+
+		batchesServiceMock.On("Dispose", mock.Anything, 1, batches.ReasonDamaged).Return(domain.Batches{ID: 1, Version: 4}, nil)
+
+		body := handler.BatchDisposeRequest{Reason: testutil.ToPtr(batches.ReasonDamaged)}
+		request, response := testutil.MakeRequest(http.MethodPost, BATCHES_URL+"/1/dispose", body)
+		request.Header.Set("If-Match", `"3"`)
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, `"4"`, response.Header().Get("ETag"))
+	})
+	t.Run("should return 412 when the batch is in another version", func(t *testing.T) {
+		batchesServiceMock := BatchesServiceMock{}
+		server := getBatchesServer(handler.NewBatches(&batchesServiceMock))
+
+		batchesServiceMock.On("Move", mock.MatchedBy(func(ctx context.Context) bool {
+			versions, ok := sqlutil.IfMatch(ctx)
+			return ok && len(versions) == 1 && versions[0] == 2
+		}), 1, 3).Return(domain.Batches{}, sqlutil.ErrVersionMismatch)
+
+		body := handler.BatchMoveRequest{SectionID: testutil.ToPtr(3)}
+		request, response := testutil.MakeRequest(http.MethodPost, BATCHES_URL+"/1/move", body)
+		request.Header.Set("If-Match", `"2"`)
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusPreconditionFailed, response.Code)
+	})
+	t.Run("should return the history of a batch", func(t *testing.T) {
+		batchesServiceMock := BatchesServiceMock{}
+		server := getBatchesServer(handler.NewBatches(&batchesServiceMock))
+
+		expected := []domain.BatchMovement{{ID: 1, ProductBatchID: 1, Type: domain.MovementReceipt, Quantity: 10}}
+		batchesServiceMock.On("History", mock.Anything, 1).Return(expected, nil)
+
+		request, response := testutil.MakeRequest(http.MethodGet, BATCHES_URL+"/1/history", nil)
+		server.ServeHTTP(response, request)
+
+		var received testutil.SuccessResponse[[]domain.BatchMovement]
+		json.Unmarshal(response.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, expected, received.Data)
+	})
+}
+
 func getBatchesServer(h *handler.Batches) *gin.Engine {
 	server := testutil.CreateServer()
 
 	server.POST(BATCHES_URL, middleware.Body[handler.CreateBatchesRequest](), h.Create())
+	server.GET(BATCHES_URL, h.GetAll())
+	server.GET(BATCHES_URL+"/:id", middleware.IntPathParam(), h.Get())
+	server.PATCH(BATCHES_URL+"/:id", middleware.IntPathParam(), middleware.IfMatch(), middleware.Body[handler.BatchUpdateRequest](), h.Update())
+	server.POST(BATCHES_URL+"/:id/move", middleware.IntPathParam(), middleware.IfMatch(), middleware.Body[handler.BatchMoveRequest](), h.Move())
+	server.POST(BATCHES_URL+"/:id/dispose", middleware.IntPathParam(), middleware.IfMatch(), middleware.Body[handler.BatchDisposeRequest](), h.Dispose())
+	server.GET(BATCHES_URL+"/:id/history", middleware.IntPathParam(), h.History())
 
 	return server
 }
@@ -163,3 +310,33 @@ func (m *BatchesServiceMock) Create(ctx context.Context, b batches.CreateBatches
 	args := m.Called(ctx, b)
 	return args.Get(0).(domain.Batches), args.Error(1)
 }
+
+func (m *BatchesServiceMock) Get(ctx context.Context, id int) (domain.Batches, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(domain.Batches), args.Error(1)
+}
+
+func (m *BatchesServiceMock) List(ctx context.Context, f batches.Filter) ([]domain.Batches, error) {
+	args := m.Called(ctx, f)
+	return args.Get(0).([]domain.Batches), args.Error(1)
+}
+
+func (m *BatchesServiceMock) Adjust(ctx context.Context, id int, dto batches.AdjustDTO) (domain.Batches, error) {
+	args := m.Called(ctx, id, dto)
+	return args.Get(0).(domain.Batches), args.Error(1)
+}
+
+func (m *BatchesServiceMock) Move(ctx context.Context, id int, sectionID int) (domain.Batches, error) {
+	args := m.Called(ctx, id, sectionID)
+	return args.Get(0).(domain.Batches), args.Error(1)
+}
+
+func (m *BatchesServiceMock) Dispose(ctx context.Context, id int, reason string) (domain.Batches, error) {
+	args := m.Called(ctx, id, reason)
+	return args.Get(0).(domain.Batches), args.Error(1)
+}
+
+func (m *BatchesServiceMock) History(ctx context.Context, id int) ([]domain.BatchMovement, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]domain.BatchMovement), args.Error(1)
+}
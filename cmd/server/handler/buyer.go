@@ -3,10 +3,14 @@ package handler
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/buyer"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/export"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
 	"github.com/gin-gonic/gin"
 )
@@ -24,12 +28,18 @@ func NewBuyer(b buyer.Service) *Buyer {
 // Buyer GoDoc
 //
 //	@Summary		Get a buyer by ID
-//	@Description	Get a buyer by ID
+//	@Description	Get a buyer by ID. Admins can get a deleted one with `include_deleted=true`.
 //	@Tags			Buyers
-//	@Param			id	path		int	true	"Buyer ID"
-//	@Success		200	{object}	domain.Buyer
-//	@Failure		400	{string}	string	"Invalid ID"
-//	@Failure		404	{string}	string	"Buyer not found"
+//	@Param			id				path		int		true	"Buyer ID"
+//	@Param			include_deleted	query		bool	false	"Include deleted buyers (admin only)"
+//	@Param			X-Admin-Token	header		string	false	"Admin token"
+//	@Param			If-None-Match	header		string	false	"ETag of the cached buyer"
+//	@Success		200				{object}	domain.Buyer
+//	@Header			200				{string}	ETag	"Version of the buyer"
+//	@Success		304				"Buyer not modified"
+//	@Failure		400				{string}	string	"Invalid ID"
+//	@Failure		403				{string}	string	"include_deleted is restricted to admins"
+//	@Failure		404				{string}	string	"Buyer not found"
 //	@Router			/api/v1/buyers/{id} [get]
 func (b *Buyer) Get() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -40,6 +50,9 @@ func (b *Buyer) Get() gin.HandlerFunc {
 			web.Error(c, http.StatusNotFound, "buyer not found")
 			return
 		}
+		if web.NotModified(c, buyer.Version) {
+			return
+		}
 		web.Success(c, http.StatusOK, buyer)
 	}
 }
@@ -49,16 +62,22 @@ func (b *Buyer) Get() gin.HandlerFunc {
 //	@Summary		Delete a buyer by ID
 //	@Description	Delete a buyer by ID
 //	@Tags			Buyers
-//	@Param			id	path		int		true	"Buyer ID"
-//	@Success		200	{string}	string	"Buyer deleted"
-//	@Failure		400	{string}	string	"Invalid ID"
-//	@Failure		404	{string}	string	"Buyer not found"
+//	@Param			id			path		int		true	"Buyer ID"
+//	@Param			If-Match	header		string	false	"ETag the buyer must have"
+//	@Success		200			{string}	string	"Buyer deleted"
+//	@Failure		400			{string}	string	"Invalid ID"
+//	@Failure		404			{string}	string	"Buyer not found"
+//	@Failure		412			{string}	string	"Buyer changed since it was read"
 //	@Router			/api/v1/buyers/{id} [delete]
 func (b *Buyer) Delete() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.GetInt("id")
 
 		err := b.buyerService.Delete(c, id)
+		if errors.Is(err, sqlutil.ErrVersionMismatch) {
+			web.Error(c, http.StatusPreconditionFailed, "buyer changed since it was read")
+			return
+		}
 		if err != nil {
 			web.Error(c, http.StatusNotFound, "buyer not found")
 			return
@@ -67,18 +86,64 @@ func (b *Buyer) Delete() gin.HandlerFunc {
 	}
 }
 
+// Buyer GoDoc
+//
+//	@Summary		Restore a deleted buyer by ID
+//	@Description	Undo the deletion of a buyer by ID
+//	@Tags			Buyers
+//	@Param			id				path		int		true	"Buyer ID"
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Success		200				{object}	domain.Buyer
+//	@Failure		400				{string}	string	"Invalid ID"
+//	@Failure		403				{string}	string	"Restricted to admins"
+//	@Failure		404				{string}	string	"Buyer not found"
+//	@Failure		500				{string}	string	"Buyer not restored"
+//	@Router			/api/v1/buyers/{id}/restore [post]
+func (b *Buyer) Restore() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetInt("id")
+
+		restored, err := b.buyerService.Restore(c, id)
+		if errors.Is(err, buyer.ErrNotFound) {
+			web.Error(c, http.StatusNotFound, "buyer not found")
+			return
+		}
+		if err != nil {
+			web.Error(c, http.StatusInternalServerError, "buyer not restored")
+			return
+		}
+		web.SetETag(c, restored.Version)
+		web.Success(c, http.StatusOK, restored)
+	}
+}
+
 // Buyer GoDoc
 //
 //	@Summary		Get all buyers
-//	@Description	Get all buyers
+//	@Description	Get all buyers, or only those with the given `ids`. Admins can include deleted ones with `include_deleted=true`.
 //	@Tags			Buyers
+//	@Param			ids				query	string	false	"Comma separated ids of the buyers to retrieve"
+//	@Param			include_deleted	query	bool	false	"Include deleted buyers (admin only)"
+//	@Param			X-Admin-Token	header	string	false	"Admin token"
 //	@Success		200	{array}		domain.Buyer
+//	@Failure		400	{string}	string	"include_deleted should be a boolean, or invalid ids"
+//	@Failure		403	{string}	string	"include_deleted is restricted to admins"
 //	@Failure		500	{string}	string	"Buyer not found"
 //	@Failure		204	{string}	string	"No buyers found"
 //	@Router			/api/v1/buyers [get]
 func (b *Buyer) GetAll() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		buyers, err := b.buyerService.GetAll(c)
+		ids, err := idsQuery(c, "ids")
+		if err != nil {
+			web.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		var buyers []domain.Buyer
+		if ids.HasVal {
+			buyers, err = b.buyerService.GetByIDs(c, ids.Val)
+		} else {
+			buyers, err = b.buyerService.GetAll(c)
+		}
 		if err != nil {
 			web.Error(c, http.StatusInternalServerError, "buyer not found")
 			return
@@ -98,7 +163,9 @@ func (b *Buyer) GetAll() gin.HandlerFunc {
 //	@Tags			Buyers
 //	@Accept			json
 //	@Param			buyer	body		domain.BuyerCreate	true	"Buyer object"
+//	@Param			Idempotency-Key	header	string	false	"Makes retries of the request return its first response"
 //	@Success		201		{object}	domain.Buyer
+//	@Failure		409		{object}	web.errorResponse	"Idempotency-Key request still in progress"
 //	@Failure		422		{string}	string	"Buyer not created"
 //	@Router			/api/v1/buyers [post]
 func (b *Buyer) Create() gin.HandlerFunc {
@@ -121,10 +188,13 @@ func (b *Buyer) Create() gin.HandlerFunc {
 //	@Tags			Buyers
 //	@Accept			json
 //	@Param			id		path		int				true	"Buyer ID"
-//	@Param			buyer	body		domain.Buyer	true	"Buyer object"
-//	@Success		200		{object}	domain.Buyer
-//	@Failure		400		{string}	string	"Invalid ID"
-//	@Failure		404		{string}	string	"Buyer not updated"
+//	@Param			buyer		body		domain.Buyer	true	"Buyer object"
+//	@Param			If-Match	header		string			false	"ETag the buyer must have"
+//	@Success		200			{object}	domain.Buyer
+//	@Header			200			{string}	ETag	"New version of the buyer"
+//	@Failure		400			{string}	string	"Invalid ID"
+//	@Failure		404			{string}	string	"Buyer not updated"
+//	@Failure		412			{string}	string	"Buyer changed since it was read"
 //	@Router			/api/v1/buyers/{id} [patch]
 func (b *Buyer) Update() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -132,14 +202,60 @@ func (b *Buyer) Update() gin.HandlerFunc {
 		buyer := middleware.GetBody[domain.Buyer](c)
 
 		buyerUpdated, err := b.buyerService.Update(c, buyer, id)
+		if errors.Is(err, sqlutil.ErrVersionMismatch) {
+			web.Error(c, http.StatusPreconditionFailed, "buyer changed since it was read")
+			return
+		}
 		if err != nil {
 			web.Error(c, http.StatusNotFound, "buyer not updated")
 			return
 		}
+		web.SetETag(c, buyerUpdated.Version)
 		web.Success(c, http.StatusOK, buyerUpdated)
 	}
 }
 
+// Buyer GoDoc
+//
+//	@Summary		Create and update buyers in batch
+//	@Description	Creates the buyers in `create` and updates those in `update`, which are sent with their `id`, like the update endpoint does. Each buyer gets its own status, in the order they were sent.
+//	@Tags			Buyers
+//	@Accept			json
+//	@Param			batch	body		web.batchRequest{create=[]domain.BuyerCreate,update=[]domain.Buyer}	true	"Buyers to create and update"
+//	@Param			Idempotency-Key	header	string	false	"Makes retries of the request return its first response"
+//	@Success		207		{object}	web.response{data=web.BatchResponse}	"Status of each buyer"
+//	@Failure		422		{string}	string	"Invalid batch"
+//	@Router			/api/v1/buyers/batch [post]
+func (b *Buyer) Batch() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, ok := getBatch[domain.BuyerCreate, domain.Buyer](c)
+		if !ok {
+			return
+		}
+		created, createErrs := b.buyerService.CreateBatch(c, req.Create)
+		ids, updates := req.Updates()
+		updated, updateErrs := b.buyerService.UpdateBatch(c, ids, updates)
+		web.Success(c, http.StatusMultiStatus, web.BatchResponse{
+			Create: web.BatchResults(created, createErrs, http.StatusCreated, buyerErrStatus),
+			Update: web.BatchResults(updated, updateErrs, http.StatusOK, buyerErrStatus),
+		})
+	}
+}
+
+// buyerErrStatus returns the status code of an error of the buyer
+// service.
+func buyerErrStatus(err error) int {
+	switch {
+	case errors.Is(err, buyer.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, buyer.ErrAlreadyExists):
+		return http.StatusConflict
+	case errors.Is(err, sqlutil.ErrVersionMismatch):
+		return http.StatusPreconditionFailed
+	}
+	return http.StatusInternalServerError
+}
+
 // ReportAllPurchaseOrders godoc
 //
 //	@Summary	Return purchaseOrder count for each buyer
@@ -147,9 +263,14 @@ func (b *Buyer) Update() gin.HandlerFunc {
 //	@Tags		Buyers
 //	@Accept		json
 //	@Produce	json
+//	@Produce	text/csv
+//	@Produce	application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//	@Param		format	query		string				false	"Export format: json, csv or xlsx"
 //	@Success	200	{object}	web.response		"Returns purchaseOrder count for buyers"
 //	@Success	204	{object}	web.response		"No content was found"
+//	@Failure	400	{object}	web.errorResponse	"Unsupported export format"
 //	@Failure	500	{object}	web.errorResponse	"Could not generate report"
+//	@Failure	429	{object}	web.errorResponse	"Report rate limit exceeded, see Retry-After"
 //	@Router		/api/v1/buyers/report-purchase-orders [get]
 func (h *Buyer) PurchaseOrderReport() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -157,6 +278,19 @@ func (h *Buyer) PurchaseOrderReport() gin.HandlerFunc {
 		if _, exists := c.Params.Get("id"); exists {
 			id = c.GetInt("id")
 		}
+		format, ok := negotiateFormat(c)
+		if !ok {
+			return
+		}
+		if id == 0 && format != export.JSON {
+			err := streamRows(c, format, "report-purchase-orders", purchaseOrderReportHeader, purchaseOrderReportRow, func(fn func(buyer.CountByBuyer) error) error {
+				return h.buyerService.StreamPurchaseOrderCounts(c.Request.Context(), fn)
+			})
+			if err != nil {
+				web.Error(c, checkErrorStatusReportPurchaseOrder(err), err.Error())
+			}
+			return
+		}
 
 		report, err := h.buyerService.CountPurchaseOrders(c, id)
 
@@ -166,6 +300,11 @@ func (h *Buyer) PurchaseOrderReport() gin.HandlerFunc {
 			return
 		}
 
+		if format != export.JSON {
+			exportRows(c, format, "report-purchase-orders", purchaseOrderReportHeader, report, purchaseOrderReportRow)
+			return
+		}
+
 		web.Success(c, http.StatusOK, report)
 	}
 }
@@ -177,8 +316,12 @@ func (h *Buyer) PurchaseOrderReport() gin.HandlerFunc {
 //	@Tags		Buyers
 //	@Accept		json
 //	@Produce	json
+//	@Produce	text/csv
+//	@Produce	application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 //	@Param		id	path		int					true	"Buyer ID"
+//	@Param		format	query		string				false	"Export format: json, csv or xlsx"
 //	@Success	200	{object}	web.response		"Returns purchaseOrder count for buyer"
+//	@Failure	400	{object}	web.errorResponse	"Unsupported export format"
 //	@Failure	404	{object}	web.response		"ID was not found"
 //	@Failure	500	{object}	web.errorResponse	"Could not generate report"
 //	@Router		/api/v1/buyers/report-purchase-orders/{id} [get]
@@ -193,3 +336,142 @@ func checkErrorStatusReportPurchaseOrder(err error) int {
 	}
 	return http.StatusInternalServerError
 }
+
+var purchaseOrderReportHeader = []string{"id", "card_number_id", "first_name", "last_name", "purchase_orders_count"}
+
+func purchaseOrderReportRow(r buyer.CountByBuyer) []string {
+	return []string{
+		strconv.Itoa(r.ID),
+		r.CardNumberID,
+		r.FirstName,
+		r.LastName,
+		strconv.Itoa(r.Count),
+	}
+}
+
+// PurchaseOrders godoc
+//
+//	@Summary		Get the purchase orders of a buyer
+//	@Description	Orders placed between `from` and `to`, oldest first, with their quantity and total
+//	@Tags			Buyers
+//	@Produce		json
+//	@Param			id		path		int					true	"Buyer ID"
+//	@Param			from	query		string				false	"Start date (YYYY-MM-DD or RFC 3339)"
+//	@Param			to		query		string				false	"End date, inclusive (YYYY-MM-DD or RFC 3339)"
+//	@Success		200		{object}	web.response		"Returns the purchase orders"
+//	@Failure		400		{object}	web.errorResponse	"Invalid ID type or dates"
+//	@Failure		404		{object}	web.errorResponse	"Buyer not found"
+//	@Failure		500		{object}	web.errorResponse	"Could not fetch purchase orders"
+//	@Router			/api/v1/buyers/{id}/purchase-orders [get]
+func (h *Buyer) PurchaseOrders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetInt("id")
+		from, to, err := dateRangeQuery(c)
+		if err != nil {
+			web.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		orders, err := h.buyerService.PurchaseOrders(c.Request.Context(), id, from, to)
+		if err != nil {
+			status := checkErrorStatusReportPurchaseOrder(err)
+			web.Error(c, status, err.Error())
+			return
+		}
+		web.Success(c, http.StatusOK, orders)
+	}
+}
+
+// SpendingReport godoc
+//
+//	@Summary		Report how much each buyer spent
+//	@Description	Number of orders placed between `from` and `to`, their total, average value and the date of the last one
+//	@Tags			Buyers
+//	@Produce		json
+//	@Produce		text/csv
+//	@Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//	@Param			from	query		string				false	"Start date (YYYY-MM-DD or RFC 3339)"
+//	@Param			to		query		string				false	"End date, inclusive (YYYY-MM-DD or RFC 3339)"
+//	@Param			format	query		string				false	"Export format: json, csv or xlsx"
+//	@Success		200		{object}	web.response		"Returns the spending of every buyer"
+//	@Failure		400		{object}	web.errorResponse	"Invalid dates or unsupported export format"
+//	@Failure		500		{object}	web.errorResponse	"Could not generate report"
+//	@Failure		429		{object}	web.errorResponse	"Report rate limit exceeded, see Retry-After"
+//	@Router			/api/v1/buyers/report-spending [get]
+func (h *Buyer) SpendingReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var id int
+		if _, exists := c.Params.Get("id"); exists {
+			id = c.GetInt("id")
+		}
+		from, to, err := dateRangeQuery(c)
+		if err != nil {
+			web.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		format, ok := negotiateFormat(c)
+		if !ok {
+			return
+		}
+		if id == 0 && format != export.JSON {
+			err := streamRows(c, format, "report-spending", spendingReportHeader, spendingReportRow, func(fn func(domain.BuyerSpending) error) error {
+				return h.buyerService.StreamSpending(c.Request.Context(), from, to, fn)
+			})
+			if err != nil {
+				web.Error(c, checkErrorStatusReportPurchaseOrder(err), err.Error())
+			}
+			return
+		}
+
+		report, err := h.buyerService.Spending(c.Request.Context(), id, from, to)
+		if err != nil {
+			status := checkErrorStatusReportPurchaseOrder(err)
+			web.Error(c, status, err.Error())
+			return
+		}
+
+		if format != export.JSON {
+			exportRows(c, format, "report-spending", spendingReportHeader, report, spendingReportRow)
+			return
+		}
+		web.Success(c, http.StatusOK, report)
+	}
+}
+
+// SpendingReportByID godoc
+//
+//	@Summary		Report how much a buyer spent
+//	@Description	Number of orders placed between `from` and `to`, their total, average value and the date of the last one
+//	@Tags			Buyers
+//	@Produce		json
+//	@Produce		text/csv
+//	@Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//	@Param			id		path		int					true	"Buyer ID"
+//	@Param			from	query		string				false	"Start date (YYYY-MM-DD or RFC 3339)"
+//	@Param			to		query		string				false	"End date, inclusive (YYYY-MM-DD or RFC 3339)"
+//	@Param			format	query		string				false	"Export format: json, csv or xlsx"
+//	@Success		200		{object}	web.response		"Returns the spending of the buyer"
+//	@Failure		400		{object}	web.errorResponse	"Invalid ID type, dates or unsupported export format"
+//	@Failure		404		{object}	web.errorResponse	"Buyer not found"
+//	@Failure		500		{object}	web.errorResponse	"Could not generate report"
+//	@Router			/api/v1/buyers/report-spending/{id} [get]
+func _() {} // Implementation is in the SpendingReport function
+
+var spendingReportHeader = []string{"id", "card_number_id", "first_name", "last_name", "purchase_orders_count", "total_spent", "average_order_value", "last_order_date"}
+
+func spendingReportRow(r domain.BuyerSpending) []string {
+	last := ""
+	if r.LastOrderDate != nil {
+		last = r.LastOrderDate.Format(time.RFC3339)
+	}
+	return []string{
+		strconv.Itoa(r.ID),
+		r.CardNumberID,
+		r.FirstName,
+		r.LastName,
+		strconv.Itoa(r.OrdersCount),
+		strconv.FormatFloat(r.TotalSpent, 'f', 2, 64),
+		strconv.FormatFloat(r.AverageOrderValue, 'f', 2, 64),
+		last,
+	}
+}
@@ -7,8 +7,10 @@ import (
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/buyer"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
 	"github.com/gin-gonic/gin"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/cmd/server/handler"
@@ -280,7 +282,7 @@ func TestGetPurchaseOrderReports(t *testing.T) {
 		server := getBuyerServer(buyerHandler)
 		expected := []buyer.CountByBuyer{{
 			ID:           10,
-			CardNumberID: 10,
+			CardNumberID: "0010",
 			FirstName:    "meli",
 			LastName:     "osasco",
 			Count:        10,
@@ -339,6 +341,122 @@ func TestGetPurchaseOrderReports(t *testing.T) {
 
 		assert.Equal(t, http.StatusInternalServerError, res.Code)
 	})
+	t.Run("should stream the count of every buyer as csv", func(t *testing.T) {
+		svcMock := ServiceMockBuyer{}
+		buyerHandler := handler.NewBuyer(&svcMock)
+		server := getBuyerServer(buyerHandler)
+
+		svcMock.On("StreamPurchaseOrderCounts", mock.Anything).Return([]buyer.CountByBuyer{
+			{ID: 1, CardNumberID: "0010", FirstName: "Ana", LastName: "Lima", Count: 2},
+		}, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, BUYER_URL+"/report-purchase-orders/?format=csv", nil)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Contains(t, res.Body.String(), "1,0010,Ana,Lima,2")
+		svcMock.AssertNotCalled(t, "CountPurchaseOrders", mock.Anything, mock.Anything)
+	})
+}
+
+func TestBuyerPurchaseOrders(t *testing.T) {
+	t.Run("should return the orders placed in the whole to date", func(t *testing.T) {
+		svcMock := ServiceMockBuyer{}
+		buyerHandler := handler.NewBuyer(&svcMock)
+		server := getBuyerServer(buyerHandler)
+
+		to := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC).Add(24*time.Hour - time.Nanosecond)
+		expected := []domain.PurchaseOrder{{ID: 3, OrderNumber: "ON-1", BuyerID: 1, Quantity: 2, UnitPrice: 10, Total: 20}}
+		svcMock.On("PurchaseOrders", mock.Anything, 1, *optional.New[time.Time](), *optional.FromVal(to)).Return(expected, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, BUYER_URL+"/1/purchase-orders?to=2023-01-31", nil)
+		server.ServeHTTP(res, req)
+
+		var received testutil.SuccessResponse[[]domain.PurchaseOrder]
+		json.Unmarshal(res.Body.Bytes(), &received)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, received.Data)
+	})
+	t.Run("should return status 404 when buyer not found", func(t *testing.T) {
+		svcMock := ServiceMockBuyer{}
+		buyerHandler := handler.NewBuyer(&svcMock)
+		server := getBuyerServer(buyerHandler)
+
+		svcMock.On("PurchaseOrders", mock.Anything, 1, mock.Anything, mock.Anything).Return([]domain.PurchaseOrder{}, buyer.ErrNotFound)
+
+		req, res := testutil.MakeRequest(http.MethodGet, BUYER_URL+"/1/purchase-orders", nil)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+	t.Run("should return status 400 when dates are invalid", func(t *testing.T) {
+		svcMock := ServiceMockBuyer{}
+		buyerHandler := handler.NewBuyer(&svcMock)
+		server := getBuyerServer(buyerHandler)
+
+		req, res := testutil.MakeRequest(http.MethodGet, BUYER_URL+"/1/purchase-orders?from=yesterday", nil)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+}
+
+func TestBuyerSpendingReport(t *testing.T) {
+	t.Run("should return the spending of a buyer", func(t *testing.T) {
+		svcMock := ServiceMockBuyer{}
+		buyerHandler := handler.NewBuyer(&svcMock)
+		server := getBuyerServer(buyerHandler)
+
+		last := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+		expected := []domain.BuyerSpending{{ID: 1, CardNumberID: "0010", OrdersCount: 2, TotalSpent: 30, AverageOrderValue: 15, LastOrderDate: &last}}
+		svcMock.On("Spending", mock.Anything, 1, mock.Anything, mock.Anything).Return(expected, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, BUYER_URL+"/report-spending/1", nil)
+		server.ServeHTTP(res, req)
+
+		var received testutil.SuccessResponse[[]domain.BuyerSpending]
+		json.Unmarshal(res.Body.Bytes(), &received)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, received.Data)
+	})
+	t.Run("should export the spending of every buyer as csv", func(t *testing.T) {
+		svcMock := ServiceMockBuyer{}
+		buyerHandler := handler.NewBuyer(&svcMock)
+		server := getBuyerServer(buyerHandler)
+
+		svcMock.On("StreamSpending", mock.Anything, mock.Anything, mock.Anything).Return([]domain.BuyerSpending{{ID: 1, CardNumberID: "0010"}}, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, BUYER_URL+"/report-spending/?format=csv", nil)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Contains(t, res.Body.String(), "1,0010,,,0,0.00,0.00,")
+		svcMock.AssertNotCalled(t, "Spending", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("should return status 500 when the spending cannot be streamed", func(t *testing.T) {
+		svcMock := ServiceMockBuyer{}
+		buyerHandler := handler.NewBuyer(&svcMock)
+		server := getBuyerServer(buyerHandler)
+
+		svcMock.On("StreamSpending", mock.Anything, mock.Anything, mock.Anything).Return([]domain.BuyerSpending{}, buyer.ErrInternalServerError)
+
+		req, res := testutil.MakeRequest(http.MethodGet, BUYER_URL+"/report-spending/?format=csv", nil)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+	})
+	t.Run("should return status 404 when buyer not found", func(t *testing.T) {
+		svcMock := ServiceMockBuyer{}
+		buyerHandler := handler.NewBuyer(&svcMock)
+		server := getBuyerServer(buyerHandler)
+
+		svcMock.On("Spending", mock.Anything, 7, mock.Anything, mock.Anything).Return([]domain.BuyerSpending{}, buyer.ErrNotFound)
+
+		req, res := testutil.MakeRequest(http.MethodGet, BUYER_URL+"/report-spending/7", nil)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
 }
 
 func getBuyerServer(h *handler.Buyer) *gin.Engine {
@@ -351,6 +469,9 @@ func getBuyerServer(h *handler.Buyer) *gin.Engine {
 		buyerRG.GET("/:id", middleware.IntPathParam(), h.Get())
 		buyerRG.GET("/report-purchase-orders/:id", middleware.IntPathParam(), h.PurchaseOrderReport())
 		buyerRG.GET("/report-purchase-orders/", h.PurchaseOrderReport())
+		buyerRG.GET("/report-spending/:id", middleware.IntPathParam(), h.SpendingReport())
+		buyerRG.GET("/report-spending/", h.SpendingReport())
+		buyerRG.GET("/:id/purchase-orders", middleware.IntPathParam(), h.PurchaseOrders())
 		buyerRG.DELETE("/:id", middleware.IntPathParam(), h.Delete())
 		buyerRG.PATCH("/:id", middleware.IntPathParam(), middleware.Body[domain.Buyer](), h.Update())
 	}
@@ -367,6 +488,21 @@ func (svc *ServiceMockBuyer) GetAll(c context.Context) ([]domain.Buyer, error) {
 	return args.Get(0).([]domain.Buyer), args.Error(1)
 }
 
+func (svc *ServiceMockBuyer) GetByIDs(c context.Context, ids []int) ([]domain.Buyer, error) {
+	args := svc.Called(c, ids)
+	return args.Get(0).([]domain.Buyer), args.Error(1)
+}
+
+func (svc *ServiceMockBuyer) CreateBatch(c context.Context, items []domain.BuyerCreate) ([]domain.Buyer, []error) {
+	args := svc.Called(c, items)
+	return args.Get(0).([]domain.Buyer), args.Get(1).([]error)
+}
+
+func (svc *ServiceMockBuyer) UpdateBatch(c context.Context, ids []int, items []domain.Buyer) ([]domain.Buyer, []error) {
+	args := svc.Called(c, ids, items)
+	return args.Get(0).([]domain.Buyer), args.Get(1).([]error)
+}
+
 func (svc *ServiceMockBuyer) Get(ctx context.Context, id int) (domain.Buyer, error) {
 	args := svc.Called(ctx, id)
 	return args.Get(0).(domain.Buyer), args.Error(1)
@@ -387,7 +523,42 @@ func (svc *ServiceMockBuyer) Delete(ctx context.Context, id int) error {
 	return args.Error(0)
 }
 
+func (svc *ServiceMockBuyer) Restore(ctx context.Context, id int) (domain.Buyer, error) {
+	args := svc.Called(ctx, id)
+	return args.Get(0).(domain.Buyer), args.Error(1)
+}
+
 func (svc *ServiceMockBuyer) CountPurchaseOrders(ctx context.Context, id int) ([]buyer.CountByBuyer, error) {
 	args := svc.Called(ctx, id)
 	return args.Get(0).([]buyer.CountByBuyer), args.Error(1)
 }
+
+func (svc *ServiceMockBuyer) StreamPurchaseOrderCounts(ctx context.Context, fn func(buyer.CountByBuyer) error) error {
+	args := svc.Called(ctx)
+	for _, entry := range args.Get(0).([]buyer.CountByBuyer) {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (svc *ServiceMockBuyer) PurchaseOrders(ctx context.Context, id int, from, to optional.Opt[time.Time]) ([]domain.PurchaseOrder, error) {
+	args := svc.Called(ctx, id, from, to)
+	return args.Get(0).([]domain.PurchaseOrder), args.Error(1)
+}
+
+func (svc *ServiceMockBuyer) Spending(ctx context.Context, id int, from, to optional.Opt[time.Time]) ([]domain.BuyerSpending, error) {
+	args := svc.Called(ctx, id, from, to)
+	return args.Get(0).([]domain.BuyerSpending), args.Error(1)
+}
+
+func (svc *ServiceMockBuyer) StreamSpending(ctx context.Context, from, to optional.Opt[time.Time], fn func(domain.BuyerSpending) error) error {
+	args := svc.Called(ctx, from, to)
+	for _, entry := range args.Get(0).([]domain.BuyerSpending) {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
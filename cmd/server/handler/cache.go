@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/cache"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+type Cache struct {
+	caches map[string]cache.StatsReporter
+}
+
+func NewCache(caches map[string]cache.StatsReporter) *Cache {
+	return &Cache{
+		caches: caches,
+	}
+}
+
+// Stats of the caches
+//
+//	@Summary		Get cache stats
+//	@Description	Hits, misses, evictions and size of each cache, by name.
+//	@Tags			Cache
+//	@Produce		json
+//	@Success		200	{object}	web.response{data=map[string]cache.Stats}	"Stats of each cache"
+//	@Router			/api/v1/cache/stats [get]
+func (h *Cache) Stats() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stats := make(map[string]cache.Stats, len(h.caches))
+		for name, reporter := range h.caches {
+			stats[name] = reporter.Stats()
+		}
+		web.Success(c, http.StatusOK, stats)
+	}
+}
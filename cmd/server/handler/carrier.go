@@ -1,10 +1,13 @@
 package handler
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/carrier"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
 	"github.com/gin-gonic/gin"
@@ -15,11 +18,45 @@ type Carrier struct {
 }
 
 type CarrierRequest struct {
-	CID         *int    `binding:"required" json:"cid"`
-	CompanyName *string `binding:"required" json:"company_name"`
-	Address     *string `binding:"required" json:"address"`
-	Telephone   *string `binding:"required" json:"telephone"`
-	LocalityID  *int    `binding:"required" json:"locality_id"`
+	CID         *CarrierCID `binding:"required" json:"cid" swaggertype:"string"`
+	CompanyName *string     `binding:"required" json:"company_name"`
+	Address     *string     `binding:"required" json:"address"`
+	Telephone   *string     `binding:"required" json:"telephone"`
+	LocalityID  *int        `binding:"required" json:"locality_id"`
+}
+
+// CarrierUpdateRequest contains pointers so that the Handler is able to
+// distinguish between omitted (nil) and given (not-nil) fields.
+type CarrierUpdateRequest struct {
+	CID         *CarrierCID `json:"cid" swaggertype:"string"`
+	CompanyName *string     `json:"company_name"`
+	Address     *string     `json:"address"`
+	Telephone   *string     `json:"telephone"`
+	LocalityID  *int        `json:"locality_id"`
+}
+
+// CarrierCID is the cid of a carrier. Clients of the deprecated
+// /carrier path send it as a number, so integers are also accepted.
+type CarrierCID string
+
+func (cid *CarrierCID) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] != '"' {
+		var n json.Number
+		if err := json.Unmarshal(data, &n); err != nil {
+			return err
+		}
+		if _, err := n.Int64(); err != nil {
+			return err
+		}
+		*cid = CarrierCID(n)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*cid = CarrierCID(s)
+	return nil
 }
 
 func NewCarrier(s carrier.Service) *Carrier {
@@ -30,16 +67,18 @@ func NewCarrier(s carrier.Service) *Carrier {
 
 // Create carrier
 //
-//	@Summary	Create new carrier
-//	@Tags		Carrier
-//	@Accept		json
-//	@Produce	json
-//	@Param		product	body		CarrierRequest		true	"Carrier to be added"
-//	@Success	201		{object}	web.response		"Returns created carrier"
-//	@Failure	409		{object}	web.errorResponse	"`cid` is not unique or `locality_id` not found"
-//	@Failure	422		{object}	web.errorResponse	"Missing fields or invalid field types"
-//	@Failure	500		{object}	web.errorResponse	"Could not save carrier"
-//	@Router		/api/v1/carrier [post]
+//	@Summary		Create new carrier
+//	@Description	Also served at the deprecated path /api/v1/carrier.
+//	@Tags			Carrier
+//	@Accept			json
+//	@Produce		json
+//	@Param			product	body		CarrierRequest		true	"Carrier to be added"
+//	@Param			Idempotency-Key	header	string	false	"Makes retries of the request return its first response"
+//	@Success		201		{object}	web.response		"Returns created carrier"
+//	@Failure		409		{object}	web.errorResponse	"`cid` is not unique or `locality_id` not found"
+//	@Failure		422		{object}	web.errorResponse	"Missing fields or invalid field types"
+//	@Failure		500		{object}	web.errorResponse	"Could not save carrier"
+//	@Router			/api/v1/carriers [post]
 func (i *Carrier) Create() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		req := middleware.GetBody[CarrierRequest](c)
@@ -56,20 +95,183 @@ func (i *Carrier) Create() gin.HandlerFunc {
 	}
 }
 
+// GetAll carriers
+//
+//	@Summary	List carriers
+//	@Tags		Carrier
+//	@Produce	json
+//	@Param		include_deleted	query		bool				false	"Include deleted carriers (admin only)"
+//	@Param		X-Admin-Token	header		string				false	"Admin token"
+//	@Success	200				{object}	web.response		"List of carriers"
+//	@Failure	400				{object}	web.errorResponse	"Invalid include_deleted"
+//	@Failure	403				{object}	web.errorResponse	"Not an admin"
+//	@Failure	500				{object}	web.errorResponse	"Could not list carriers"
+//	@Router		/api/v1/carriers [get]
+func (i *Carrier) GetAll() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		carriers, err := i.carrierService.GetAll(c.Request.Context())
+		if err != nil {
+			web.Error(c, checkErrorStatus(err), err.Error())
+			return
+		}
+
+		web.Success(c, http.StatusOK, carriers)
+	}
+}
+
+// Get carrier
+//
+//	@Summary	Get carrier by ID
+//	@Tags		Carrier
+//	@Produce	json
+//	@Param		id				path		int					true	"Carrier ID"
+//	@Param		include_deleted	query		bool				false	"Include deleted carriers (admin only)"
+//	@Param		X-Admin-Token	header		string				false	"Admin token"
+//	@Param		If-None-Match	header		string				false	"ETag of the cached carrier"
+//	@Success	200				{object}	web.response		"Carrier with the given ID"
+//	@Header		200				{string}	ETag				"Version of the carrier"
+//	@Success	304				"Carrier did not change"
+//	@Failure	400				{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	403				{object}	web.errorResponse	"Not an admin"
+//	@Failure	404				{object}	web.errorResponse	"Could not find carrier"
+//	@Failure	500				{object}	web.errorResponse	"Could not get carrier"
+//	@Router		/api/v1/carriers/{id} [get]
+func (i *Carrier) Get() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetInt("id")
+
+		p, err := i.carrierService.Get(c.Request.Context(), id)
+		if err != nil {
+			web.Error(c, checkErrorStatus(err), err.Error())
+			return
+		}
+
+		if web.NotModified(c, p.Version) {
+			return
+		}
+		web.Success(c, http.StatusOK, p)
+	}
+}
+
+// Update carrier
+//
+//	@Summary	Update carrier
+//	@Tags		Carrier
+//	@Accept		json
+//	@Produce	json
+//	@Param		id			path		int						true	"Carrier ID"
+//	@Param		carrier		body		CarrierUpdateRequest	true	"Fields to update"
+//	@Param		If-Match	header		string					false	"ETag the carrier must have"
+//	@Success	200			{object}	web.response			"Returns updated carrier"
+//	@Header		200			{string}	ETag					"New version of the carrier"
+//	@Failure	400			{object}	web.errorResponse		"Invalid ID type"
+//	@Failure	404			{object}	web.errorResponse		"Could not find carrier"
+//	@Failure	409			{object}	web.errorResponse		"`cid` is not unique or `locality_id` not found"
+//	@Failure	412			{object}	web.errorResponse		"Carrier changed since it was read"
+//	@Failure	422			{object}	web.errorResponse		"Invalid field types"
+//	@Failure	500			{object}	web.errorResponse		"Could not save carrier"
+//	@Router		/api/v1/carriers/{id} [patch]
+func (i *Carrier) Update() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetInt("id")
+
+		req := middleware.GetBody[CarrierUpdateRequest](c)
+		dto := mapCarrierUpdateRequestToDTO(&req)
+		p, err := i.carrierService.Update(c.Request.Context(), id, *dto)
+		if err != nil {
+			web.Error(c, checkErrorStatus(err), err.Error())
+			return
+		}
+
+		web.SetETag(c, p.Version)
+		web.Success(c, http.StatusOK, p)
+	}
+}
+
+// Delete carrier
+//
+//	@Summary	Delete carrier
+//	@Tags		Carrier
+//	@Param		id			path	int		true	"Carrier ID"
+//	@Param		If-Match	header	string	false	"ETag the carrier must have"
+//	@Success	204	"Carrier deleted"
+//	@Failure	400	{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	404	{object}	web.errorResponse	"Could not find carrier"
+//	@Failure	409	{object}	web.errorResponse	"Carrier is assigned to purchase orders"
+//	@Failure	412	{object}	web.errorResponse	"Carrier changed since it was read"
+//	@Failure	500	{object}	web.errorResponse	"Could not delete carrier"
+//	@Router		/api/v1/carriers/{id} [delete]
+func (i *Carrier) Delete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetInt("id")
+
+		if err := i.carrierService.Delete(c.Request.Context(), id); err != nil {
+			web.Error(c, checkErrorStatus(err), err.Error())
+			return
+		}
+
+		web.Success(c, http.StatusNoContent, nil)
+	}
+}
+
+// Restore carrier
+//
+//	@Summary	Restore deleted carrier
+//	@Tags		Carrier
+//	@Produce	json
+//	@Param		id				path		int					true	"Carrier ID"
+//	@Param		X-Admin-Token	header		string				true	"Admin token"
+//	@Success	200				{object}	web.response		"Returns restored carrier"
+//	@Failure	400				{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	403				{object}	web.errorResponse	"Restricted to admins"
+//	@Failure	404				{object}	web.errorResponse	"Could not find deleted carrier"
+//	@Failure	500				{object}	web.errorResponse	"Could not restore carrier"
+//	@Router		/api/v1/carriers/{id}/restore [post]
+func (i *Carrier) Restore() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetInt("id")
+
+		p, err := i.carrierService.Restore(c.Request.Context(), id)
+		if err != nil {
+			web.Error(c, checkErrorStatus(err), err.Error())
+			return
+		}
+
+		web.SetETag(c, p.Version)
+		web.Success(c, http.StatusOK, p)
+	}
+}
+
 func checkErrorStatus(err error) int {
-	if errors.Is(err, carrier.ErrAlreadyExists) ||
-		errors.Is(err, carrier.ErrLocalityIDNotFound) {
+	switch {
+	case errors.Is(err, carrier.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, carrier.ErrAlreadyExists),
+		errors.Is(err, carrier.ErrLocalityIDNotFound),
+		errors.Is(err, carrier.ErrInUse):
 		return http.StatusConflict
+	case errors.Is(err, sqlutil.ErrVersionMismatch):
+		return http.StatusPreconditionFailed
 	}
 	return http.StatusInternalServerError
 }
 
 func mapCarrierRequestToDTO(req *CarrierRequest) *carrier.CarrierDTO {
 	return &carrier.CarrierDTO{
-		CID:         *req.CID,
+		CID:         string(*req.CID),
 		CompanyName: *req.CompanyName,
 		Address:     *req.Address,
 		Telephone:   *req.Telephone,
 		LocalityID:  *req.LocalityID,
 	}
 }
+
+func mapCarrierUpdateRequestToDTO(req *CarrierUpdateRequest) *carrier.UpdateDTO {
+	dto := carrier.UpdateDTO{}
+	dto.CID = *optional.FromPtr((*string)(req.CID))
+	dto.CompanyName = *optional.FromPtr(req.CompanyName)
+	dto.Address = *optional.FromPtr(req.Address)
+	dto.Telephone = *optional.FromPtr(req.Telephone)
+	dto.LocalityID = *optional.FromPtr(req.LocalityID)
+	return &dto
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -11,6 +12,7 @@ import (
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/cmd/server/handler"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/carrier"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
 	"github.com/gin-gonic/gin"
@@ -19,7 +21,7 @@ import (
 )
 
 var carrierID = 1
-var CARRIER_URL = "/carrier/"
+var CARRIER_URL = "/carriers/"
 
 func TestCarrierCreate(t *testing.T) {
 	t.Run("Create a carrier successfully", func(t *testing.T) {
@@ -51,6 +53,46 @@ func TestCarrierCreate(t *testing.T) {
 		assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
 		carrierService.AssertNumberOfCalls(t, "Create", 0)
 	})
+	t.Run("Accepts a numeric cid on the deprecated path", func(t *testing.T) {
+		carrierService := CarrierServiceMock{}
+		h := handler.NewCarrier(&carrierService)
+		server := getCarrierServer(h)
+
+		expected := getTestCarrier()
+		carrierService.On("Create", mock.Anything, mock.MatchedBy(func(dto carrier.CarrierDTO) bool {
+			return dto.CID == "123"
+		})).Return(expected, nil)
+
+		body := map[string]any{
+			"cid":          123,
+			"company_name": "mercado livre",
+			"address":      "osasco",
+			"telephone":    "123456789",
+			"locality_id":  5,
+		}
+		req, res := testutil.MakeRequest(http.MethodPost, "/carrier/", body)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusCreated, res.Code)
+		carrierService.AssertNumberOfCalls(t, "Create", 1)
+	})
+	t.Run("Does not create a carrier with a fractional cid", func(t *testing.T) {
+		carrierService := CarrierServiceMock{}
+		h := handler.NewCarrier(&carrierService)
+		server := getCarrierServer(h)
+
+		body := map[string]any{
+			"cid":          1.5,
+			"company_name": "mercado livre",
+			"address":      "osasco",
+			"telephone":    "123456789",
+			"locality_id":  5,
+		}
+		res := requestCarrierPost(body, server)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+		carrierService.AssertNumberOfCalls(t, "Create", 0)
+	})
 	t.Run("Does not create any carrier and returns error: conflict", func(t *testing.T) {
 		carrierService := CarrierServiceMock{}
 		h := handler.NewCarrier(&carrierService)
@@ -78,20 +120,182 @@ func TestCarrierCreate(t *testing.T) {
 
 }
 
+func TestCarrierRead(t *testing.T) {
+	t.Run("List carriers", func(t *testing.T) {
+		carrierService := CarrierServiceMock{}
+		h := handler.NewCarrier(&carrierService)
+		server := getCarrierServer(h)
+
+		expected := []domain.Carrier{getTestCarrier()}
+		carrierService.On("GetAll", mock.Anything).Return(expected, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, CARRIER_URL, "")
+		server.ServeHTTP(res, req)
+
+		var received testutil.SuccessResponse[[]domain.Carrier]
+		json.Unmarshal(res.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, received.Data)
+	})
+	t.Run("Get a carrier by ID", func(t *testing.T) {
+		carrierService := CarrierServiceMock{}
+		h := handler.NewCarrier(&carrierService)
+		server := getCarrierServer(h)
+
+		expected := getTestCarrier()
+		carrierService.On("Get", mock.Anything, carrierID).Return(expected, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, fmt.Sprintf("%s%d", CARRIER_URL, carrierID), "")
+		server.ServeHTTP(res, req)
+
+		var received testutil.SuccessResponse[domain.Carrier]
+		json.Unmarshal(res.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, received.Data)
+	})
+	t.Run("Get returns not found", func(t *testing.T) {
+		carrierService := CarrierServiceMock{}
+		h := handler.NewCarrier(&carrierService)
+		server := getCarrierServer(h)
+
+		carrierService.On("Get", mock.Anything, carrierID).Return(domain.Carrier{}, carrier.ErrNotFound)
+
+		req, res := testutil.MakeRequest(http.MethodGet, fmt.Sprintf("%s%d", CARRIER_URL, carrierID), "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+}
+
+func TestCarrierUpdate(t *testing.T) {
+	t.Run("Update passes only the given fields", func(t *testing.T) {
+		carrierService := CarrierServiceMock{}
+		h := handler.NewCarrier(&carrierService)
+		server := getCarrierServer(h)
+
+		expected := getTestCarrier()
+		expected.Telephone = "987654321"
+		dto := carrier.UpdateDTO{Telephone: *optional.FromVal("987654321")}
+		carrierService.On("Update", mock.Anything, carrierID, dto).Return(expected, nil)
+
+		body := handler.CarrierUpdateRequest{Telephone: testutil.ToPtr("987654321")}
+		req, res := testutil.MakeRequest(http.MethodPatch, fmt.Sprintf("%s%d", CARRIER_URL, carrierID), body)
+		server.ServeHTTP(res, req)
+
+		var received testutil.SuccessResponse[domain.Carrier]
+		json.Unmarshal(res.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, received.Data)
+	})
+	t.Run("Update returns conflict", func(t *testing.T) {
+		carrierService := CarrierServiceMock{}
+		h := handler.NewCarrier(&carrierService)
+		server := getCarrierServer(h)
+
+		carrierService.On("Update", mock.Anything, carrierID, mock.Anything).Return(domain.Carrier{}, carrier.ErrAlreadyExists)
+
+		body := handler.CarrierUpdateRequest{CID: testutil.ToPtr(handler.CarrierCID("222222"))}
+		req, res := testutil.MakeRequest(http.MethodPatch, fmt.Sprintf("%s%d", CARRIER_URL, carrierID), body)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusConflict, res.Code)
+	})
+}
+
+func TestCarrierDelete(t *testing.T) {
+	t.Run("Delete a carrier", func(t *testing.T) {
+		carrierService := CarrierServiceMock{}
+		h := handler.NewCarrier(&carrierService)
+		server := getCarrierServer(h)
+
+		carrierService.On("Delete", mock.Anything, carrierID).Return(nil)
+
+		req, res := testutil.MakeRequest(http.MethodDelete, fmt.Sprintf("%s%d", CARRIER_URL, carrierID), "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNoContent, res.Code)
+	})
+	t.Run("Delete returns conflict if the carrier has purchase orders", func(t *testing.T) {
+		carrierService := CarrierServiceMock{}
+		h := handler.NewCarrier(&carrierService)
+		server := getCarrierServer(h)
+
+		carrierService.On("Delete", mock.Anything, carrierID).Return(carrier.ErrInUse)
+
+		req, res := testutil.MakeRequest(http.MethodDelete, fmt.Sprintf("%s%d", CARRIER_URL, carrierID), "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusConflict, res.Code)
+	})
+	t.Run("Delete returns not found", func(t *testing.T) {
+		carrierService := CarrierServiceMock{}
+		h := handler.NewCarrier(&carrierService)
+		server := getCarrierServer(h)
+
+		carrierService.On("Delete", mock.Anything, carrierID).Return(carrier.ErrNotFound)
+
+		req, res := testutil.MakeRequest(http.MethodDelete, fmt.Sprintf("%s%d", CARRIER_URL, carrierID), "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+}
+
+func TestCarrierRestore(t *testing.T) {
+	t.Run("Restore a carrier", func(t *testing.T) {
+		carrierService := CarrierServiceMock{}
+		h := handler.NewCarrier(&carrierService)
+		server := getCarrierServer(h)
+
+		expected := getTestCarrier()
+		carrierService.On("Restore", mock.Anything, carrierID).Return(expected, nil)
+
+		req, res := testutil.MakeRequest(http.MethodPost, fmt.Sprintf("%s%d/restore", CARRIER_URL, carrierID), "")
+		server.ServeHTTP(res, req)
+
+		var received testutil.SuccessResponse[domain.Carrier]
+		json.Unmarshal(res.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, received.Data)
+	})
+	t.Run("Restore returns not found", func(t *testing.T) {
+		carrierService := CarrierServiceMock{}
+		h := handler.NewCarrier(&carrierService)
+		server := getCarrierServer(h)
+
+		carrierService.On("Restore", mock.Anything, carrierID).Return(domain.Carrier{}, carrier.ErrNotFound)
+
+		req, res := testutil.MakeRequest(http.MethodPost, fmt.Sprintf("%s%d/restore", CARRIER_URL, carrierID), "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+}
+
 func getCarrierServer(h *handler.Carrier) *gin.Engine {
 	server := testutil.CreateServer()
 
 	carrierRG := server.Group(CARRIER_URL)
 	{
 		carrierRG.POST("", middleware.Body[handler.CarrierRequest](), h.Create())
+		carrierRG.GET("", h.GetAll())
+		carrierRG.GET("/:id", middleware.IntPathParam(), h.Get())
+		carrierRG.PATCH("/:id", middleware.IntPathParam(), middleware.Body[handler.CarrierUpdateRequest](), h.Update())
+		carrierRG.DELETE("/:id", middleware.IntPathParam(), h.Delete())
+		carrierRG.POST("/:id/restore", middleware.IntPathParam(), h.Restore())
 	}
+	server.POST("/carrier/", middleware.Body[handler.CarrierRequest](), h.Create())
 
 	return server
 }
 
 func getTestCarrierRequest() handler.CarrierRequest {
 	return handler.CarrierRequest{
-		CID:         testutil.ToPtr(10),
+		CID:         testutil.ToPtr(handler.CarrierCID("10")),
 		CompanyName: testutil.ToPtr("mercado livre"),
 		Address:     testutil.ToPtr("osasco"),
 		Telephone:   testutil.ToPtr("123456789"),
@@ -102,7 +306,7 @@ func getTestCarrierRequest() handler.CarrierRequest {
 func getTestCarrier() domain.Carrier {
 	return domain.Carrier{
 		ID:          carrierID,
-		CID:         10,
+		CID:         "10",
 		CompanyName: "mercado livre",
 		Address:     "osasco",
 		Telephone:   "12345689",
@@ -124,3 +328,28 @@ func (s *CarrierServiceMock) Create(c context.Context, carrier carrier.CarrierDT
 	args := s.Called(c, carrier)
 	return args.Get(0).(domain.Carrier), args.Error(1)
 }
+
+func (s *CarrierServiceMock) GetAll(c context.Context) ([]domain.Carrier, error) {
+	args := s.Called(c)
+	return args.Get(0).([]domain.Carrier), args.Error(1)
+}
+
+func (s *CarrierServiceMock) Get(c context.Context, id int) (domain.Carrier, error) {
+	args := s.Called(c, id)
+	return args.Get(0).(domain.Carrier), args.Error(1)
+}
+
+func (s *CarrierServiceMock) Update(c context.Context, id int, updates carrier.UpdateDTO) (domain.Carrier, error) {
+	args := s.Called(c, id, updates)
+	return args.Get(0).(domain.Carrier), args.Error(1)
+}
+
+func (s *CarrierServiceMock) Delete(c context.Context, id int) error {
+	args := s.Called(c, id)
+	return args.Error(0)
+}
+
+func (s *CarrierServiceMock) Restore(c context.Context, id int) (domain.Carrier, error) {
+	args := s.Called(c, id)
+	return args.Get(0).(domain.Carrier), args.Error(1)
+}
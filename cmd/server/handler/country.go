@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/localities"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type Country struct {
+	locService localities.Service
+}
+
+type CountryRequest struct {
+	Name *string `binding:"required" json:"country_name"`
+}
+
+func NewCountry(svc localities.Service) *Country {
+	return &Country{
+		locService: svc,
+	}
+}
+
+// GetAll godoc
+//
+//	@Summary	List countries
+//	@Tags		Countries
+//	@Produce	json
+//	@Success	200	{object}	web.response		"List of countries"
+//	@Failure	500	{object}	web.errorResponse	"Could not list countries"
+//	@Router		/api/v1/countries [get]
+func (h *Country) GetAll() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		countries, err := h.locService.GetAllCountries(c.Request.Context())
+		if err != nil {
+			web.Error(c, mapLocalityErrToStatus(err), err.Error())
+			return
+		}
+		web.Success(c, http.StatusOK, countries)
+	}
+}
+
+// Get godoc
+//
+//	@Summary	Get country by ID
+//	@Tags		Countries
+//	@Produce	json
+//	@Param		id				path		int					true	"Country ID"
+//	@Param		If-None-Match	header		string				false	"ETag of the cached country"
+//	@Success	200				{object}	web.response		"Country with the given ID"
+//	@Header		200				{string}	ETag				"Version of the country"
+//	@Success	304				"Country did not change"
+//	@Failure	400				{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	404				{object}	web.errorResponse	"Could not find country"
+//	@Failure	500				{object}	web.errorResponse	"Could not get country"
+//	@Router		/api/v1/countries/{id} [get]
+func (h *Country) Get() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		country, err := h.locService.GetCountry(c.Request.Context(), c.GetInt("id"))
+		if err != nil {
+			web.Error(c, mapLocalityErrToStatus(err), err.Error())
+			return
+		}
+		if web.NotModified(c, country.Version) {
+			return
+		}
+		web.Success(c, http.StatusOK, country)
+	}
+}
+
+// Create godoc
+//
+//	@Summary	Create new country
+//	@Tags		Countries
+//	@Accept		json
+//	@Produce	json
+//	@Param		country	body		CountryRequest		true	"Country to be added"
+//	@Param		Idempotency-Key	header	string	false	"Makes retries of the request return its first response"
+//	@Success	201		{object}	web.response		"Returns created country"
+//	@Failure	409		{object}	web.errorResponse	"Country is not unique"
+//	@Failure	422		{object}	web.errorResponse	"Missing fields, invalid field types or empty name"
+//	@Failure	500		{object}	web.errorResponse	"Could not save country"
+//	@Router		/api/v1/countries [post]
+func (h *Country) Create() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := middleware.GetBody[CountryRequest](c)
+		country, err := h.locService.CreateCountry(c.Request.Context(), *req.Name)
+		if err != nil {
+			web.Error(c, mapLocalityErrToStatus(err), err.Error())
+			return
+		}
+		web.Success(c, http.StatusCreated, country)
+	}
+}
+
+// Update godoc
+//
+//	@Summary	Rename country
+//	@Tags		Countries
+//	@Accept		json
+//	@Produce	json
+//	@Param		id		path		int					true	"Country ID"
+//	@Param		country	body		CountryRequest		true	"New name"
+//	@Param		If-Match	header		string				false	"ETag the country must have"
+//	@Success	200		{object}	web.response		"Returns updated country"
+//	@Header		200			{string}	ETag				"New version of the country"
+//	@Failure	400		{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	404		{object}	web.errorResponse	"Could not find country"
+//	@Failure	409		{object}	web.errorResponse	"Country is not unique"
+//	@Failure	412			{object}	web.errorResponse	"Country changed since it was read"
+//	@Failure	422		{object}	web.errorResponse	"Missing fields, invalid field types or empty name"
+//	@Failure	500		{object}	web.errorResponse	"Could not save country"
+//	@Router		/api/v1/countries/{id} [patch]
+func (h *Country) Update() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := middleware.GetBody[CountryRequest](c)
+		country, err := h.locService.UpdateCountry(c.Request.Context(), c.GetInt("id"), *optional.FromPtr(req.Name))
+		if err != nil {
+			web.Error(c, mapLocalityErrToStatus(err), err.Error())
+			return
+		}
+		web.SetETag(c, country.Version)
+		web.Success(c, http.StatusOK, country)
+	}
+}
+
+// Delete godoc
+//
+//	@Summary	Delete country
+//	@Tags		Countries
+//	@Param		id			path	int		true	"Country ID"
+//	@Param		If-Match	header	string	false	"ETag the country must have"
+//	@Success	204			"Country deleted"
+//	@Failure	400			{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	404			{object}	web.errorResponse	"Could not find country"
+//	@Failure	409			{object}	web.errorResponse	"Country has provinces"
+//	@Failure	412			{object}	web.errorResponse	"Country changed since it was read"
+//	@Failure	500			{object}	web.errorResponse	"Could not delete country"
+//	@Router		/api/v1/countries/{id} [delete]
+func (h *Country) Delete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := h.locService.DeleteCountry(c.Request.Context(), c.GetInt("id")); err != nil {
+			web.Error(c, mapLocalityErrToStatus(err), err.Error())
+			return
+		}
+		web.Success(c, http.StatusNoContent, nil)
+	}
+}
+
+// GetProvinces godoc
+//
+//	@Summary	List provinces of a country
+//	@Tags		Countries
+//	@Produce	json
+//	@Param		id	path		int					true	"Country ID"
+//	@Success	200	{object}	web.response		"Provinces of the country"
+//	@Failure	400	{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	404	{object}	web.errorResponse	"Could not find country"
+//	@Failure	500	{object}	web.errorResponse	"Could not list provinces"
+//	@Router		/api/v1/countries/{id}/provinces [get]
+func (h *Country) GetProvinces() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provinces, err := h.locService.GetProvinces(c.Request.Context(), c.GetInt("id"))
+		if err != nil {
+			web.Error(c, mapLocalityErrToStatus(err), err.Error())
+			return
+		}
+		web.Success(c, http.StatusOK, provinces)
+	}
+}
+
+// CreateProvince godoc
+//
+//	@Summary	Create province in a country
+//	@Tags		Countries
+//	@Accept		json
+//	@Produce	json
+//	@Param		id			path		int					true	"Country ID"
+//	@Param		province	body		ProvinceRequest		true	"Province to be added"
+//	@Param		Idempotency-Key	header	string	false	"Makes retries of the request return its first response"
+//	@Success	201			{object}	web.response		"Returns created province"
+//	@Failure	400			{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	404			{object}	web.errorResponse	"Could not find country"
+//	@Failure	409			{object}	web.errorResponse	"Province is not unique in the country"
+//	@Failure	422			{object}	web.errorResponse	"Missing fields, invalid field types or empty name"
+//	@Failure	500			{object}	web.errorResponse	"Could not save province"
+//	@Router		/api/v1/countries/{id}/provinces [post]
+func (h *Country) CreateProvince() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := middleware.GetBody[ProvinceRequest](c)
+		province, err := h.locService.CreateProvince(c.Request.Context(), c.GetInt("id"), *req.Name)
+		if err != nil {
+			web.Error(c, mapLocalityErrToStatus(err), err.Error())
+			return
+		}
+		web.Success(c, http.StatusCreated, province)
+	}
+}
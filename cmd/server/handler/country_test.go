@@ -0,0 +1,142 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/cmd/server/handler"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/localities"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const COUNTRY_URL = "/countries"
+
+func TestCountry(t *testing.T) {
+	t.Run("Returns 200 with all countries", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		server := getCountryServer(handler.NewCountry(&svc))
+
+		expected := []domain.Country{{ID: 1, Name: "Brazil"}}
+		svc.On("GetAllCountries", mock.Anything).Return(expected, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, COUNTRY_URL, "")
+		server.ServeHTTP(res, req)
+
+		var response testutil.SuccessResponse[[]domain.Country]
+		json.Unmarshal(res.Body.Bytes(), &response)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, response.Data)
+	})
+	t.Run("Returns 201 if country is created", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		server := getCountryServer(handler.NewCountry(&svc))
+
+		svc.On("CreateCountry", mock.Anything, "Chile").Return(domain.Country{ID: 3, Name: "Chile"}, nil)
+
+		body := handler.CountryRequest{Name: testutil.ToPtr("Chile")}
+		req, res := testutil.MakeRequest(http.MethodPost, COUNTRY_URL, body)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusCreated, res.Code)
+	})
+	t.Run("Returns 409 if country already exists", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		server := getCountryServer(handler.NewCountry(&svc))
+
+		svc.On("CreateCountry", mock.Anything, "Brazil").Return(domain.Country{}, localities.NewErrDuplicateName("country", "Brazil"))
+
+		body := handler.CountryRequest{Name: testutil.ToPtr("Brazil")}
+		req, res := testutil.MakeRequest(http.MethodPost, COUNTRY_URL, body)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusConflict, res.Code)
+	})
+	t.Run("Returns 422 if country name is empty", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		server := getCountryServer(handler.NewCountry(&svc))
+
+		svc.On("UpdateCountry", mock.Anything, 1, *optional.FromVal("")).Return(domain.Country{}, localities.NewErrInvalidName("country"))
+
+		body := handler.CountryRequest{Name: testutil.ToPtr("")}
+		req, res := testutil.MakeRequest(http.MethodPatch, COUNTRY_URL+"/1", body)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	})
+	t.Run("Returns 409 when deleting a country with provinces", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		server := getCountryServer(handler.NewCountry(&svc))
+
+		svc.On("DeleteCountry", mock.Anything, 1).Return(localities.NewErrInUse("country", 1, "provinces"))
+
+		req, res := testutil.MakeRequest(http.MethodDelete, COUNTRY_URL+"/1", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusConflict, res.Code)
+	})
+	t.Run("Returns 412 if the country is in another version", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		server := getCountryServer(handler.NewCountry(&svc))
+
+		svc.On("UpdateCountry", mock.Anything, 1, *optional.FromVal("Brasil")).Return(domain.Country{}, sqlutil.ErrVersionMismatch)
+
+		body := handler.CountryRequest{Name: testutil.ToPtr("Brasil")}
+		req, res := testutil.MakeRequest(http.MethodPatch, COUNTRY_URL+"/1", body)
+		req.Header.Set("If-Match", `"2"`)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusPreconditionFailed, res.Code)
+	})
+	t.Run("Returns 404 for provinces of a missing country", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		server := getCountryServer(handler.NewCountry(&svc))
+
+		svc.On("GetProvinces", mock.Anything, 9).Return([]domain.Province{}, localities.NewErrCountryNotFound(9))
+
+		req, res := testutil.MakeRequest(http.MethodGet, COUNTRY_URL+"/9/provinces", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+	t.Run("Returns 201 if province is created in the country", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		server := getCountryServer(handler.NewCountry(&svc))
+
+		expected := domain.Province{ID: 3, Name: "RJ", CountryID: 1}
+		svc.On("CreateProvince", mock.Anything, 1, "RJ").Return(expected, nil)
+
+		body := handler.ProvinceRequest{Name: testutil.ToPtr("RJ")}
+		req, res := testutil.MakeRequest(http.MethodPost, COUNTRY_URL+"/1/provinces", body)
+		server.ServeHTTP(res, req)
+
+		var response testutil.SuccessResponse[domain.Province]
+		json.Unmarshal(res.Body.Bytes(), &response)
+
+		assert.Equal(t, http.StatusCreated, res.Code)
+		assert.Equal(t, expected, response.Data)
+	})
+}
+
+func getCountryServer(h *handler.Country) *gin.Engine {
+	s := testutil.CreateServer()
+	rg := s.Group(COUNTRY_URL)
+	{
+		rg.GET("", h.GetAll())
+		rg.POST("", middleware.Body[handler.CountryRequest](), h.Create())
+		rg.GET("/:id", middleware.IntPathParam(), h.Get())
+		rg.PATCH("/:id", middleware.IntPathParam(), middleware.IfMatch(), middleware.Body[handler.CountryRequest](), h.Update())
+		rg.DELETE("/:id", middleware.IntPathParam(), middleware.IfMatch(), h.Delete())
+		rg.GET("/:id/provinces", middleware.IntPathParam(), h.GetProvinces())
+		rg.POST("/:id/provinces", middleware.IntPathParam(), middleware.Body[handler.ProvinceRequest](), h.CreateProvince())
+	}
+	return s
+}
@@ -1,12 +1,19 @@
 package handler
 
 import (
+	"errors"
+	"time"
+
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/employee"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/export"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
 
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,6 +22,11 @@ type Employee struct {
 	employeeService employee.Service
 }
 
+type TransferRequest struct {
+	WarehouseID   *int    `binding:"required" json:"warehouse_id"`
+	EffectiveDate *string `json:"effective_date"`
+}
+
 func NewEmployee(e employee.Service) *Employee {
 	return &Employee{
 		employeeService: e,
@@ -28,10 +40,16 @@ func NewEmployee(e employee.Service) *Employee {
 //	@Tags			Employees
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path		int	true	"ID do funcionário a ser obtido"
-//	@Success		200	{object}	domain.Employee
-//	@Failure		400	{string}	string	"invalid card id"
-//	@Failure		404	{string}	string	"invalid id"
+//	@Param			id				path		int		true	"ID do funcionário a ser obtido"
+//	@Param			include_deleted	query		bool	false	"Inclui funcionários removidos (somente admin)"
+//	@Param			X-Admin-Token	header		string	false	"Token de admin"
+//	@Param			If-None-Match	header		string	false	"ETag do funcionário em cache"
+//	@Success		200				{object}	domain.Employee
+//	@Header			200				{string}	ETag	"Versão do funcionário"
+//	@Success		304				"Funcionário não modificado"
+//	@Failure		400				{string}	string	"invalid card id"
+//	@Failure		403				{string}	string	"include_deleted is restricted to admins"
+//	@Failure		404				{string}	string	"invalid id"
 //	@Router			/api/v1/employees/{id} [get]
 func (e *Employee) Get() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -42,6 +60,9 @@ func (e *Employee) Get() gin.HandlerFunc {
 			web.Error(c, http.StatusNotFound, "invalid id")
 			return
 		}
+		if web.NotModified(c, employee.Version) {
+			return
+		}
 		web.Success(c, http.StatusOK, employee)
 	}
 }
@@ -53,8 +74,11 @@ func (e *Employee) Get() gin.HandlerFunc {
 //	@Tags			Employees
 //	@Accept			json
 //	@Produce		json
-//	@Success		200	{array}		domain.Employee
-//	@Failure		400	{string}	string	"employee not found"
+//	@Param			include_deleted	query		bool	false	"Inclui funcionários removidos (somente admin)"
+//	@Param			X-Admin-Token	header		string	false	"Token de admin"
+//	@Success		200				{array}		domain.Employee
+//	@Failure		400				{string}	string	"employee not found"
+//	@Failure		403				{string}	string	"include_deleted is restricted to admins"
 //	@Router			/api/v1/employees [get]
 func (e *Employee) GetAll() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -75,6 +99,7 @@ func (e *Employee) GetAll() gin.HandlerFunc {
 //	@Accept			json
 //	@Produce		json
 //	@Param			employee	body		domain.Employee	true	"Novo funcionário a ser criado"
+//	@Param			Idempotency-Key	header	string	false	"Faz com que novas tentativas da requisição recebam a primeira resposta"
 //	@Success		201			{object}	domain.Employee
 //	@Failure		409			{string}	string	"employee not created"
 //	@Failure		422			{string}	string	"employee card ID need to be only"
@@ -114,23 +139,36 @@ func (e *Employee) Create() gin.HandlerFunc {
 //	@Produce		json
 //	@Param			id			path		int				true	"ID do funcionário a ser atualizado"
 //	@Param			employee	body		domain.Employee	true	"Dados do funcionário a serem atualizados"
+//	@Param			If-Match	header		string			false	"ETag que o funcionário deve ter"
 //	@Success		200			{object}	domain.Employee
+//	@Header			200			{string}	ETag	"Nova versão do funcionário"
 //	@Failure		404			{string}	string	"action could not be processed correctly due to invalid data provided"
 //	@Failure		400			{string}	string	"invalid id"
+//	@Failure		409			{string}	string	"warehouse not found"
+//	@Failure		412			{string}	string	"employee changed since it was read"
 //	@Router			/api/v1/employees/{id} [patch]
 func (e *Employee) Update() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.GetInt("id")
-		employee := middleware.GetBody[domain.Employee](c)
+		emp := middleware.GetBody[domain.Employee](c)
 
-		employee.ID = id
-		employee, err := e.employeeService.Update(c, employee)
+		emp.ID = id
+		emp, err := e.employeeService.Update(c, emp)
 
+		if errors.Is(err, sqlutil.ErrVersionMismatch) {
+			web.Error(c, http.StatusPreconditionFailed, "employee changed since it was read")
+			return
+		}
+		if errors.Is(err, employee.ErrWarehouseNotFound) {
+			web.Error(c, http.StatusConflict, err.Error())
+			return
+		}
 		if err != nil {
 			web.Error(c, http.StatusNotFound, "employee does not exist")
 			return
 		}
-		web.Success(c, http.StatusOK, employee)
+		web.SetETag(c, emp.Version)
+		web.Success(c, http.StatusOK, emp)
 	}
 }
 
@@ -141,10 +179,12 @@ func (e *Employee) Update() gin.HandlerFunc {
 //	@Tags			Employees
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path	int	true	"ID do funcionário a ser removido"
+//	@Param			id			path	int		true	"ID do funcionário a ser removido"
+//	@Param			If-Match	header	string	false	"ETag que o funcionário deve ter"
 //	@Success		204	"No Content"
 //	@Failure		400	{string}	string	"invalid id"
 //	@Failure		404	{string}	string	"employee not deleted"
+//	@Failure		412	{string}	string	"employee changed since it was read"
 //	@Router			/api/v1/employees/{id} [delete]
 func (e *Employee) Delete() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -152,6 +192,10 @@ func (e *Employee) Delete() gin.HandlerFunc {
 
 		err := e.employeeService.Delete(c, id)
 
+		if errors.Is(err, sqlutil.ErrVersionMismatch) {
+			web.Error(c, http.StatusPreconditionFailed, "employee changed since it was read")
+			return
+		}
 		if err != nil {
 			web.Error(c, http.StatusNotFound, "employee not deleted")
 			return
@@ -160,13 +204,53 @@ func (e *Employee) Delete() gin.HandlerFunc {
 	}
 }
 
+// Restore restaura um funcionário removido.
+//
+//	@Summary		Restaura um funcionário
+//	@Description	Desfaz a remoção de um funcionário com base no ID fornecido
+//	@Tags			Employees
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		int		true	"ID do funcionário a ser restaurado"
+//	@Param			X-Admin-Token	header		string	true	"Token de administrador"
+//	@Success		200				{object}	domain.Employee
+//	@Failure		400				{string}	string	"invalid id"
+//	@Failure		403				{string}	string	"acesso restrito a administradores"
+//	@Failure		404				{string}	string	"employee not found"
+//	@Failure		500				{string}	string	"internal server error"
+//	@Router			/api/v1/employees/{id}/restore [post]
+func (e *Employee) Restore() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetInt("id")
+
+		restored, err := e.employeeService.Restore(c, id)
+		if err != nil {
+			web.Error(c, checkErrorStatusEmployee(err), err.Error())
+			return
+		}
+		web.SetETag(c, restored.Version)
+		web.Success(c, http.StatusOK, restored)
+	}
+}
+
 // @Summary	Get specific inbound order report from employee by ID
+// @Description	Orders, units received and distinct products of the employee between `from` and `to`, by warehouse and optionally by day or week, ranked within each warehouse
 // @Tags		Employees
 // @Accept		json
 // @Produce	json
+// @Produce	text/csv
+// @Produce	application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 // @Param     id      path    int    true "Employee ID"
+// @Param     from    query   string false "Start date (YYYY-MM-DD or RFC 3339)"
+// @Param     to      query   string false "End date, inclusive (YYYY-MM-DD or RFC 3339)"
+// @Param     warehouse_id query int false "Warehouse ID"
+// @Param     period  query   string false "Split by day or week"
+// @Param     format  query   string false "Export format: json, csv or xlsx"
 // @Success	200	{object}	web.response		"returns the specified report"
+// @Failure	400	{object}	web.errorResponse	"invalid filters or unsupported export format"
 // @Failure	404	{object}	web.errorResponse	"no report to be returned"
+// @Failure	500	{object}	web.errorResponse	"internal server error"
+// @Failure	429	{object}	web.errorResponse	"Limite de relatórios excedido; veja Retry-After"
 // @Router		/api/v1/employees/report-inbound-orders/{id} [get]
 func (e *Employee) GetInboundReport() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -174,9 +258,26 @@ func (e *Employee) GetInboundReport() gin.HandlerFunc {
 		if _, exists := c.Params.Get("id"); exists {
 			id = c.GetInt("id")
 		}
-		report, err := e.employeeService.GetInboundReport(c, id)
+		f, err := inboundReportQuery(c)
 		if err != nil {
-			web.Error(c, http.StatusNotFound, "invalid id")
+			web.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		format, ok := negotiateFormat(c)
+		if !ok {
+			return
+		}
+		report, err := e.employeeService.GetInboundReport(c, id, f)
+		if err != nil {
+			if errors.Is(err, employee.ErrNotFound) {
+				web.Error(c, http.StatusNotFound, "invalid id")
+				return
+			}
+			web.Error(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if format != export.JSON {
+			exportRows(c, format, "report-inbound-orders", inboundReportHeader, report, inboundReportRow)
 			return
 		}
 		web.Success(c, http.StatusOK, report)
@@ -184,10 +285,131 @@ func (e *Employee) GetInboundReport() gin.HandlerFunc {
 }
 
 // @Summary	Get all inbound orders reports
+// @Description	Orders, units received and distinct products of every employee between `from` and `to`, by warehouse and optionally by day or week, ranked within each warehouse. Orders are summed up as they are read, but ranking needs all of them, so CSV and XLSX exports are built in full before they are sent.
 // @Tags		Employees
 // @Accept		json
 // @Produce	json
+// @Produce	text/csv
+// @Produce	application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param     from    query   string false "Start date (YYYY-MM-DD or RFC 3339)"
+// @Param     to      query   string false "End date, inclusive (YYYY-MM-DD or RFC 3339)"
+// @Param     warehouse_id query int false "Warehouse ID"
+// @Param     period  query   string false "Split by day or week"
+// @Param     format  query   string false "Export format: json, csv or xlsx"
 // @Success	200	{object}	web.response		"returns all of the reports"
+// @Failure	400	{object}	web.errorResponse	"invalid filters or unsupported export format"
 // @Failure	404	{object}	web.errorResponse	"no report to be returned"
+// @Failure	500	{object}	web.errorResponse	"internal server error"
 // @Router		/api/v1/employees/report-inbound-orders [get]
 func _() {} //
+
+func inboundReportQuery(c *gin.Context) (f employee.ReportFilter, err error) {
+	if f.From, f.To, err = dateRangeQuery(c); err != nil {
+		return
+	}
+	if f.WarehouseID, err = intQuery(c, "warehouse_id"); err != nil {
+		return
+	}
+	f.Period, err = employee.ParsePeriod(c.Query("period"))
+	return
+}
+
+var inboundReportHeader = []string{"id", "card_number_id", "first_name", "last_name", "warehouse_id", "period_start", "inbound_orders_count", "units_received", "products_count", "rank"}
+
+func inboundReportRow(r domain.InboundReport) []string {
+	period := ""
+	if r.PeriodStart != nil {
+		period = r.PeriodStart.Format(dateLayout)
+	}
+	return []string{
+		strconv.Itoa(r.ID),
+		r.CardNumberID,
+		r.FirstName,
+		r.LastName,
+		strconv.Itoa(r.WarehouseID),
+		period,
+		strconv.Itoa(r.InboundOrdersCount),
+		strconv.Itoa(r.UnitsReceived),
+		strconv.Itoa(r.ProductsCount),
+		strconv.Itoa(r.Rank),
+	}
+}
+
+// Transfer transfere um funcionário para outro armazém.
+//
+//	@Summary		Transfere um funcionário para outro armazém
+//	@Description	Encerra a atribuição atual do funcionário na data efetiva (ou agora) e o atribui ao novo armazém a partir dela
+//	@Tags			Employees
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int				true	"ID do funcionário"
+//	@Param			transfer	body		TransferRequest	true	"Armazém de destino e data efetiva (YYYY-MM-DD ou RFC 3339)"
+//	@Param			Idempotency-Key	header	string	false	"Faz com que novas tentativas da requisição recebam a primeira resposta"
+//	@Success		201			{object}	domain.EmployeeAssignment
+//	@Failure		400			{object}	web.errorResponse	"invalid id or date"
+//	@Failure		404			{object}	web.errorResponse	"employee not found"
+//	@Failure		409			{object}	web.errorResponse	"warehouse not found"
+//	@Failure		422			{object}	web.errorResponse	"same warehouse or invalid transfer date"
+//	@Failure		500			{object}	web.errorResponse	"internal server error"
+//	@Router			/api/v1/employees/{id}/transfers [post]
+func (e *Employee) Transfer() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetInt("id")
+		req := middleware.GetBody[TransferRequest](c)
+
+		at := *optional.New[time.Time]()
+		if req.EffectiveDate != nil {
+			t, _, err := parseDate(*req.EffectiveDate)
+			if err != nil {
+				web.Error(c, http.StatusBadRequest, "effective_date: "+err.Error())
+				return
+			}
+			at = *optional.FromVal(t.UTC())
+		}
+
+		assignment, err := e.employeeService.Transfer(c.Request.Context(), id, *req.WarehouseID, at)
+		if err != nil {
+			web.Error(c, checkErrorStatusEmployee(err), err.Error())
+			return
+		}
+		web.Success(c, http.StatusCreated, assignment)
+	}
+}
+
+// Assignments obtém o histórico de armazéns de um funcionário.
+//
+//	@Summary		Obtém o histórico de armazéns de um funcionário
+//	@Description	Retorna as atribuições do funcionário, da mais antiga à atual
+//	@Tags			Employees
+//	@Produce		json
+//	@Param			id	path		int	true	"ID do funcionário"
+//	@Success		200	{array}		domain.EmployeeAssignment
+//	@Failure		400	{object}	web.errorResponse	"invalid id"
+//	@Failure		404	{object}	web.errorResponse	"employee not found"
+//	@Failure		500	{object}	web.errorResponse	"internal server error"
+//	@Router			/api/v1/employees/{id}/assignments [get]
+func (e *Employee) Assignments() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetInt("id")
+
+		assignments, err := e.employeeService.Assignments(c.Request.Context(), id)
+		if err != nil {
+			web.Error(c, checkErrorStatusEmployee(err), err.Error())
+			return
+		}
+		web.Success(c, http.StatusOK, assignments)
+	}
+}
+
+func checkErrorStatusEmployee(err error) int {
+	switch {
+	case errors.Is(err, employee.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, employee.ErrWarehouseNotFound):
+		return http.StatusConflict
+	case errors.Is(err, employee.ErrSameWarehouse), errors.Is(err, employee.ErrInvalidTransferDate):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
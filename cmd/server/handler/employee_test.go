@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/cmd/server/handler"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/employee"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
 	"github.com/gin-gonic/gin"
@@ -342,7 +344,7 @@ func TestGetInboundReports(t *testing.T) {
 		}}
 		url := fmt.Sprintf("%s/report-inbound-orders/%d", EMPLOYEE_URL, 1)
 
-		mockedService.On("GetInboundReport", mock.Anything, 1).Return(r, nil)
+		mockedService.On("GetInboundReport", mock.Anything, 1, employee.ReportFilter{}).Return(r, nil)
 
 		req, res := testutil.MakeRequest(http.MethodGet, url, nil)
 		server.ServeHTTP(res, req)
@@ -359,7 +361,7 @@ func TestGetInboundReports(t *testing.T) {
 
 		url := fmt.Sprintf("%s/report-inbound-orders/%d", EMPLOYEE_URL, 1)
 
-		mockedService.On("GetInboundReport", mock.Anything, 1).Return([]domain.InboundReport{}, employee.ErrNotFound)
+		mockedService.On("GetInboundReport", mock.Anything, 1, employee.ReportFilter{}).Return([]domain.InboundReport{}, employee.ErrNotFound)
 
 		req, res := testutil.MakeRequest(http.MethodGet, url, nil)
 		server.ServeHTTP(res, req)
@@ -388,7 +390,7 @@ func TestGetInboundReports(t *testing.T) {
 			}}
 		url := fmt.Sprintf("%s/report-inbound-orders/", EMPLOYEE_URL)
 
-		mockedService.On("GetInboundReport", mock.Anything, 0).Return(r, nil)
+		mockedService.On("GetInboundReport", mock.Anything, 0, employee.ReportFilter{}).Return(r, nil)
 
 		req, res := testutil.MakeRequest(http.MethodGet, url, nil)
 		server.ServeHTTP(res, req)
@@ -398,6 +400,130 @@ func TestGetInboundReports(t *testing.T) {
 		assert.Equal(t, http.StatusOK, res.Code)
 		assert.ElementsMatch(t, r, received.Data)
 	})
+	t.Run("should pass the filters to the service", func(t *testing.T) {
+		mockedService := EmployeeServiceMock{}
+		controller := handler.NewEmployee(&mockedService)
+		server := getEmployeeServer(controller)
+
+		week := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+		r := []domain.InboundReport{{ID: 1, WarehouseID: 2, PeriodStart: &week, InboundOrdersCount: 3, UnitsReceived: 30, ProductsCount: 2, Rank: 1}}
+		matchFilter := mock.MatchedBy(func(f employee.ReportFilter) bool {
+			from, _ := f.From.Value()
+			warehouseID, _ := f.WarehouseID.Value()
+			return from.Equal(week) && warehouseID == 2 && f.Period == employee.PeriodWeek
+		})
+		mockedService.On("GetInboundReport", mock.Anything, 0, matchFilter).Return(r, nil)
+
+		url := fmt.Sprintf("%s/report-inbound-orders/?from=2023-05-01&warehouse_id=2&period=week", EMPLOYEE_URL)
+		req, res := testutil.MakeRequest(http.MethodGet, url, nil)
+		server.ServeHTTP(res, req)
+
+		var received testutil.SuccessResponse[[]domain.InboundReport]
+		json.Unmarshal(res.Body.Bytes(), &received)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, r, received.Data)
+	})
+	t.Run("should return status 400 when the period is invalid", func(t *testing.T) {
+		mockedService := EmployeeServiceMock{}
+		controller := handler.NewEmployee(&mockedService)
+		server := getEmployeeServer(controller)
+
+		url := fmt.Sprintf("%s/report-inbound-orders/?period=month", EMPLOYEE_URL)
+		req, res := testutil.MakeRequest(http.MethodGet, url, nil)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+	t.Run("should return status 400 when the warehouse is not a number", func(t *testing.T) {
+		mockedService := EmployeeServiceMock{}
+		controller := handler.NewEmployee(&mockedService)
+		server := getEmployeeServer(controller)
+
+		url := fmt.Sprintf("%s/report-inbound-orders/?warehouse_id=abc", EMPLOYEE_URL)
+		req, res := testutil.MakeRequest(http.MethodGet, url, nil)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+}
+
+func TestTransferEmployee(t *testing.T) {
+	t.Run("returns 201 with the new assignment", func(t *testing.T) {
+		mockedService := EmployeeServiceMock{}
+		h := handler.NewEmployee(&mockedService)
+		server := getEmployeeServer(h)
+
+		at := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+		expected := domain.EmployeeAssignment{ID: 2, EmployeeID: 1, WarehouseID: 2, StartDate: &at}
+		mockedService.On("Transfer", mock.Anything, 1, 2, *optional.FromVal(at)).Return(expected, nil)
+
+		body := handler.TransferRequest{WarehouseID: testutil.ToPtr(2), EffectiveDate: testutil.ToPtr("2023-06-01")}
+		req, res := testutil.MakeRequest(http.MethodPost, EMPLOYEE_URL+"/1/transfers", body)
+		server.ServeHTTP(res, req)
+
+		var received testutil.SuccessResponse[domain.EmployeeAssignment]
+		json.Unmarshal(res.Body.Bytes(), &received)
+		assert.Equal(t, http.StatusCreated, res.Code)
+		assert.Equal(t, expected, received.Data)
+	})
+	t.Run("returns 422 without a warehouse", func(t *testing.T) {
+		mockedService := EmployeeServiceMock{}
+		h := handler.NewEmployee(&mockedService)
+		server := getEmployeeServer(h)
+
+		req, res := testutil.MakeRequest(http.MethodPost, EMPLOYEE_URL+"/1/transfers", handler.TransferRequest{})
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	})
+	t.Run("returns 400 with an invalid date", func(t *testing.T) {
+		mockedService := EmployeeServiceMock{}
+		h := handler.NewEmployee(&mockedService)
+		server := getEmployeeServer(h)
+
+		body := handler.TransferRequest{WarehouseID: testutil.ToPtr(2), EffectiveDate: testutil.ToPtr("01/06/2023")}
+		req, res := testutil.MakeRequest(http.MethodPost, EMPLOYEE_URL+"/1/transfers", body)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+	t.Run("maps service errors", func(t *testing.T) {
+		cases := map[error]int{
+			employee.ErrNotFound:            http.StatusNotFound,
+			employee.ErrWarehouseNotFound:   http.StatusConflict,
+			employee.ErrSameWarehouse:       http.StatusUnprocessableEntity,
+			employee.ErrInvalidTransferDate: http.StatusUnprocessableEntity,
+		}
+		for err, status := range cases {
+			mockedService := EmployeeServiceMock{}
+			h := handler.NewEmployee(&mockedService)
+			server := getEmployeeServer(h)
+
+			mockedService.On("Transfer", mock.Anything, 1, 2, mock.Anything).Return(domain.EmployeeAssignment{}, err)
+
+			body := handler.TransferRequest{WarehouseID: testutil.ToPtr(2)}
+			req, res := testutil.MakeRequest(http.MethodPost, EMPLOYEE_URL+"/1/transfers", body)
+			server.ServeHTTP(res, req)
+
+			assert.Equal(t, status, res.Code, err.Error())
+		}
+	})
+	t.Run("returns the assignment history", func(t *testing.T) {
+		mockedService := EmployeeServiceMock{}
+		h := handler.NewEmployee(&mockedService)
+		server := getEmployeeServer(h)
+
+		expected := []domain.EmployeeAssignment{{ID: 1, EmployeeID: 1, WarehouseID: 1}}
+		mockedService.On("Assignments", mock.Anything, 1).Return(expected, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, EMPLOYEE_URL+"/1/assignments", nil)
+		server.ServeHTTP(res, req)
+
+		var received testutil.SuccessResponse[[]domain.EmployeeAssignment]
+		json.Unmarshal(res.Body.Bytes(), &received)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, received.Data)
+	})
 }
 
 func getEmployeeServer(h *handler.Employee) *gin.Engine {
@@ -412,6 +538,8 @@ func getEmployeeServer(h *handler.Employee) *gin.Engine {
 		employeeRG.GET("/report-inbound-orders/", h.GetInboundReport())
 		employeeRG.PATCH("/:id", middleware.IntPathParam(), middleware.Body[domain.Employee](), h.Update())
 		employeeRG.DELETE("/:id", middleware.IntPathParam(), h.Delete())
+		employeeRG.POST("/:id/transfers", middleware.IntPathParam(), middleware.Body[handler.TransferRequest](), h.Transfer())
+		employeeRG.GET("/:id/assignments", middleware.IntPathParam(), h.Assignments())
 	}
 
 	return s
@@ -445,7 +573,22 @@ func (svc *EmployeeServiceMock) Delete(ctx context.Context, id int) error {
 	args := svc.Called(ctx, id)
 	return args.Error(0)
 }
-func (svc *EmployeeServiceMock) GetInboundReport(ctx context.Context, id int) ([]domain.InboundReport, error) {
+
+func (svc *EmployeeServiceMock) Restore(ctx context.Context, id int) (domain.Employee, error) {
 	args := svc.Called(ctx, id)
+	return args.Get(0).(domain.Employee), args.Error(1)
+}
+func (svc *EmployeeServiceMock) GetInboundReport(ctx context.Context, id int, f employee.ReportFilter) ([]domain.InboundReport, error) {
+	args := svc.Called(ctx, id, f)
 	return args.Get(0).([]domain.InboundReport), args.Error(1)
 }
+
+func (svc *EmployeeServiceMock) Transfer(ctx context.Context, id, warehouseID int, at optional.Opt[time.Time]) (domain.EmployeeAssignment, error) {
+	args := svc.Called(ctx, id, warehouseID, at)
+	return args.Get(0).(domain.EmployeeAssignment), args.Error(1)
+}
+
+func (svc *EmployeeServiceMock) Assignments(ctx context.Context, id int) ([]domain.EmployeeAssignment, error) {
+	args := svc.Called(ctx, id)
+	return args.Get(0).([]domain.EmployeeAssignment), args.Error(1)
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/export"
+	"github.com/gin-gonic/gin"
+)
+
+// negotiateFormat returns the report format requested by the client.
+// If the requested format is not supported, it responds with 400 and
+// returns false.
+func negotiateFormat(c *gin.Context) (export.Format, bool) {
+	format, err := export.Negotiate(c)
+	if err != nil {
+		web.Error(c, http.StatusBadRequest, err.Error())
+		return "", false
+	}
+	return format, true
+}
+
+// exportRows writes the given report entries as a CSV or XLSX file.
+// It is meant for reports that have to be fully built before any of
+// them is written, such as ranked ones; others should use streamRows.
+func exportRows[T any](c *gin.Context, format export.Format, filename string, header []string, entries []T, toRow func(T) []string) {
+	err := export.Stream(c, format, filename, header, func(write func([]string) error) error {
+		for _, entry := range entries {
+			if err := write(toRow(entry)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		web.Error(c, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// streamRows writes the report entries passed by stream to its
+// callback as a CSV or XLSX file, as they are read. If stream fails
+// before anything was written, its error is returned so that the
+// caller can still respond with an error status.
+func streamRows[T any](c *gin.Context, format export.Format, filename string, header []string, toRow func(T) []string, stream func(fn func(T) error) error) error {
+	return export.Stream(c, format, filename, header, func(write func([]string) error) error {
+		return stream(func(entry T) error {
+			return write(toRow(entry))
+		})
+	})
+}
@@ -1,11 +1,14 @@
 package handler
 
 import (
+	"errors"
+	"strconv"
 	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	inboundOrder "github.com/extmatperez/meli_bootcamp_go_w2-4/internal/inbound_order"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/export"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
 
 	"net/http"
@@ -28,14 +31,19 @@ func NewInboundOrder(i inboundOrder.Service) *InboundOrder {
 //		@Summary		Creates a new inbound order
 //		@Description	Creates a new inbound order based on the data provided
 //	    @Description    The date field in the inboundOrder body should follow the ISO-8601 standard, "2023-07-06T14:30:00Z"
+//	    @Description    The order either references an existing `product_batch_id`, or carries a `product_batch` to be received:
+//	    @Description    the batch is then created in its section along with the order and its receipt movement.
+//	    @Description    The employee, and the section of the batch, must belong to the warehouse of the order.
 //		@Tags			InboundOrder
 //		@Accept			json
 //		@Produce		json
-//		@Param			inboundOrder body		domain.InboundOrder true	"new inbound order"
-//		@Success		201			{object}	web.response		"returns inbound order"
-//		@Failure		409			{object}    web.errorResponse	"error creating inbound order"
+//		@Param			inboundOrder body		InboundOrderRequest true	"new inbound order"
+//		@Param			Idempotency-Key	header	string	false	"Makes retries of the request return its first response"
+//		@Success		201			{object}	web.response		"returns inbound order, along with the received batch if any"
+//		@Failure		409			{object}    web.errorResponse	"error creating inbound order, or a referenced entity was not found"
 //		@Failure		400		    {object}    web.errorResponse	"missing fields"
-//		@Failure		422			{object}    web.errorResponse	"invalid fields"
+//		@Failure		422			{object}    web.errorResponse	"invalid fields, or employee or section not in the warehouse"
+//		@Failure		500			{object}    web.errorResponse	"could not save inbound order"
 //		@Router			/api/v1/inbound-orders [post]
 func (i *InboundOrder) Create() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -53,36 +61,236 @@ func (i *InboundOrder) Create() gin.HandlerFunc {
 			web.Error(c, http.StatusBadRequest, "order must have a employee associated with")
 			return
 		}
-		if inboundOrder.ProductBatchID == nil {
+		if inboundOrder.ProductBatchID == nil && inboundOrder.ProductBatch == nil {
 			web.Error(c, http.StatusBadRequest, "order must have a product batch associated with")
 			return
 		}
+		if inboundOrder.ProductBatchID != nil && inboundOrder.ProductBatch != nil {
+			web.Error(c, http.StatusBadRequest, "order must have either a product_batch_id or a product_batch, not both")
+			return
+		}
 		if inboundOrder.WarehouseID == nil {
 			web.Error(c, http.StatusBadRequest, "order must have a warehouse associated with")
 			return
 		}
 
 		inboundValues := domain.InboundOrder{
-			OrderDate:      *inboundOrder.OrderDate,
-			OrderNumber:    *inboundOrder.OrderNumber,
-			EmployeeID:     *inboundOrder.EmployeeID,
-			ProductBatchID: *inboundOrder.ProductBatchID,
-			WarehouseID:    *inboundOrder.WarehouseID,
+			OrderDate:   *inboundOrder.OrderDate,
+			OrderNumber: *inboundOrder.OrderNumber,
+			EmployeeID:  *inboundOrder.EmployeeID,
+			WarehouseID: *inboundOrder.WarehouseID,
+		}
+
+		if inboundOrder.ProductBatch != nil {
+			batch, err := mapInboundBatchRequest(inboundOrder.ProductBatch)
+			if err != nil {
+				web.Error(c, http.StatusUnprocessableEntity, err.Error())
+				return
+			}
+			res, err := i.inboundOrderService.Receive(c.Request.Context(), inboundValues, batch)
+			if err != nil {
+				web.Error(c, mapInboundOrderErrToStatus(err), err.Error())
+				return
+			}
+			web.Success(c, http.StatusCreated, res)
+			return
 		}
 
+		inboundValues.ProductBatchID = *inboundOrder.ProductBatchID
 		res, err := i.inboundOrderService.Create(c.Request.Context(), inboundValues)
 		if err != nil {
-			web.Error(c, http.StatusConflict, "error creating inbound order")
+			web.Error(c, mapInboundOrderErrToStatus(err), err.Error())
 			return
 		}
 		web.Success(c, http.StatusCreated, res)
 	}
 }
 
+// Get inbound order.
+//
+//	@Summary	Get inbound order by ID
+//	@Tags		InboundOrder
+//	@Produce	json
+//	@Param		id	path		int					true	"Inbound order ID"
+//	@Success	200	{object}	web.response		"returns inbound order"
+//	@Failure	400	{object}	web.errorResponse	"invalid ID type"
+//	@Failure	404	{object}	web.errorResponse	"inbound order not found"
+//	@Failure	500	{object}	web.errorResponse	"could not fetch inbound order"
+//	@Router		/api/v1/inbound-orders/{id} [get]
+func (i *InboundOrder) Get() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		order, err := i.inboundOrderService.Get(c.Request.Context(), c.GetInt("id"))
+		if err != nil {
+			if errors.Is(err, inboundOrder.ErrNotFound) {
+				web.Error(c, http.StatusNotFound, err.Error())
+				return
+			}
+			web.Error(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		web.Success(c, http.StatusOK, order)
+	}
+}
+
+// List inbound orders.
+//
+//	@Summary	List inbound orders
+//	@Tags		InboundOrder
+//	@Produce	json
+//	@Param		warehouse_id	query		int					false	"Only orders received by this warehouse"
+//	@Param		employee_id		query		int					false	"Only orders received by this employee"
+//	@Param		from			query		string				false	"Start date (YYYY-MM-DD or RFC 3339)"
+//	@Param		to				query		string				false	"End date, inclusive (YYYY-MM-DD or RFC 3339)"
+//	@Success	200				{object}	web.response		"returns inbound orders in date order"
+//	@Failure	400				{object}	web.errorResponse	"invalid filters"
+//	@Failure	500				{object}	web.errorResponse	"could not fetch inbound orders"
+//	@Router		/api/v1/inbound-orders [get]
+func (i *InboundOrder) GetAll() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter, err := inboundFilterQuery(c)
+		if err != nil {
+			web.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		orders, err := i.inboundOrderService.List(c.Request.Context(), filter)
+		if err != nil {
+			web.Error(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		web.Success(c, http.StatusOK, orders)
+	}
+}
+
+// ReceivingReport of inbound orders.
+//
+//	@Summary		Report received stock
+//	@Description	Quantity received per warehouse, product and day, along with how many orders and employees took part and when the first and last orders were received.
+//	@Tags			InboundOrder
+//	@Produce		json
+//	@Produce		text/csv
+//	@Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//	@Param			warehouse_id	query		int					false	"Only orders received by this warehouse"
+//	@Param			employee_id		query		int					false	"Only orders received by this employee"
+//	@Param			from			query		string				false	"Start date (YYYY-MM-DD or RFC 3339)"
+//	@Param			to				query		string				false	"End date, inclusive (YYYY-MM-DD or RFC 3339)"
+//	@Param			format			query		string				false	"Export format: json, csv or xlsx"
+//	@Success		200				{object}	web.response		"returns the report"
+//	@Failure		400				{object}	web.errorResponse	"invalid filters or unsupported export format"
+//	@Failure		500				{object}	web.errorResponse	"could not build the report"
+//	@Failure		429				{object}	web.errorResponse	"Report rate limit exceeded, see Retry-After"
+//	@Router			/api/v1/inbound-orders/report-receiving [get]
+func (i *InboundOrder) ReceivingReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format, ok := negotiateFormat(c)
+		if !ok {
+			return
+		}
+		filter, err := inboundFilterQuery(c)
+		if err != nil {
+			web.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if format != export.JSON {
+			err := streamRows(c, format, "report-receiving", receivingReportHeader, receivingReportRow, func(fn func(domain.ReceivingReport) error) error {
+				return i.inboundOrderService.StreamReceivingReport(c.Request.Context(), filter, fn)
+			})
+			if err != nil {
+				web.Error(c, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+
+		report, err := i.inboundOrderService.ReceivingReport(c.Request.Context(), filter)
+		if err != nil {
+			web.Error(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		web.Success(c, http.StatusOK, report)
+	}
+}
+
+var receivingReportHeader = []string{"warehouse_id", "product_id", "day", "inbound_orders_count", "employees_count", "received_quantity", "first_received_at", "last_received_at"}
+
+func receivingReportRow(r domain.ReceivingReport) []string {
+	return []string{
+		strconv.Itoa(r.WarehouseID),
+		strconv.Itoa(r.ProductID),
+		r.Day,
+		strconv.Itoa(r.OrdersCount),
+		strconv.Itoa(r.EmployeesCount),
+		strconv.Itoa(r.ReceivedQuantity),
+		r.FirstReceivedAt.Format(time.RFC3339),
+		r.LastReceivedAt.Format(time.RFC3339),
+	}
+}
+
+func inboundFilterQuery(c *gin.Context) (inboundOrder.Filter, error) {
+	var f inboundOrder.Filter
+	var err error
+	if f.WarehouseID, err = intQuery(c, "warehouse_id"); err != nil {
+		return f, err
+	}
+	if f.EmployeeID, err = intQuery(c, "employee_id"); err != nil {
+		return f, err
+	}
+	f.From, f.To, err = dateRangeQuery(c)
+	return f, err
+}
+
+func mapInboundOrderErrToStatus(err error) int {
+	switch {
+	case errors.Is(err, inboundOrder.ErrEmployeeWarehouse),
+		errors.Is(err, inboundOrder.ErrSectionWarehouse),
+		errors.Is(err, inboundOrder.ErrInvalidQuantity):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, inboundOrder.ErrInternalServerError):
+		return http.StatusInternalServerError
+	}
+	return http.StatusConflict
+}
+
+func mapInboundBatchRequest(req *InboundBatchRequest) (domain.Batches, error) {
+	dueDate, _, err := parseDate(*req.DueDate)
+	if err != nil {
+		return domain.Batches{}, err
+	}
+	manufacturingDate, _, err := parseDate(*req.ManufacturingDate)
+	if err != nil {
+		return domain.Batches{}, err
+	}
+	return domain.Batches{
+		BatchNumber:        *req.BatchNumber,
+		CurrentTemperature: *req.CurrentTemperature,
+		DueDate:            dueDate,
+		InitialQuantity:    *req.InitialQuantity,
+		ManufacturingDate:  manufacturingDate,
+		ManufacturingHour:  *req.ManufacturingHour,
+		MinimumTemperature: *req.MinimumTemperature,
+		ProductID:          *req.ProductID,
+		SectionID:          *req.SectionID,
+	}, nil
+}
+
 type InboundOrderRequest struct {
-	OrderDate      *time.Time `json:"order_date" `
-	OrderNumber    *string    `json:"order_number" `
-	EmployeeID     *int       `json:"employee_id" `
-	ProductBatchID *int       `json:"product_batch_id" `
-	WarehouseID    *int       `json:"warehouse_id" `
+	OrderDate      *time.Time           `json:"order_date" `
+	OrderNumber    *string              `json:"order_number" `
+	EmployeeID     *int                 `json:"employee_id" `
+	ProductBatchID *int                 `json:"product_batch_id" `
+	ProductBatch   *InboundBatchRequest `json:"product_batch" `
+	WarehouseID    *int                 `json:"warehouse_id" `
+}
+
+// InboundBatchRequest is a batch received with an inbound order.
+// Its current quantity is always the initial one.
+type InboundBatchRequest struct {
+	BatchNumber        *int    `binding:"required" json:"batch_number"`
+	CurrentTemperature *int    `binding:"required" json:"current_temperature"`
+	DueDate            *string `binding:"required" json:"due_date"`
+	InitialQuantity    *int    `binding:"required" json:"initial_quantity"`
+	ManufacturingDate  *string `binding:"required" json:"manufacturing_date"`
+	ManufacturingHour  *int    `binding:"required" json:"manufacturing_hour"`
+	MinimumTemperature *int    `binding:"required" json:"minimum_temperature"`
+	ProductID          *int    `binding:"required" json:"product_id"`
+	SectionID          *int    `binding:"required" json:"section_id"`
 }
@@ -10,6 +10,7 @@ import (
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/cmd/server/handler"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	inboundorder "github.com/extmatperez/meli_bootcamp_go_w2-4/internal/inbound_order"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
 	"github.com/gin-gonic/gin"
@@ -25,7 +26,7 @@ func TestCreateInboundOrder(t *testing.T) {
 		controller := handler.NewInboundOrder(&mockedService)
 		server := getInboundServer(controller)
 		i := domain.InboundOrder{
-			OrderDate:      time.Now().AddDate(0, 0, 1),
+			OrderDate:      time.Date(2023, 7, 6, 14, 30, 0, 0, time.UTC),
 			OrderNumber:    "125",
 			EmployeeID:     1,
 			ProductBatchID: 1,
@@ -47,7 +48,7 @@ func TestCreateInboundOrder(t *testing.T) {
 		controller := handler.NewInboundOrder(&mockedService)
 		server := getInboundServer(controller)
 		i := domain.InboundOrder{
-			OrderDate:      time.Now().AddDate(0, 0, 1),
+			OrderDate:      time.Date(2023, 7, 6, 14, 30, 0, 0, time.UTC),
 			OrderNumber:    "125",
 			EmployeeID:     1,
 			ProductBatchID: 1,
@@ -182,12 +183,221 @@ func TestCreateInboundOrder(t *testing.T) {
 	})
 }
 
+func TestReceiveInboundOrder(t *testing.T) {
+	t.Run("should create the batch when the order carries it", func(t *testing.T) {
+		mockedService := InboundOrdersServiceMock{}
+		controller := handler.NewInboundOrder(&mockedService)
+		server := getInboundServer(controller)
+
+		body := getTestReceiveRequest()
+		expected := domain.InboundReceipt{
+			InboundOrder: domain.InboundOrder{ID: 1, OrderNumber: "125", EmployeeID: 1, ProductBatchID: 7, WarehouseID: 1},
+			ProductBatch: domain.Batches{ID: 7, BatchNumber: 99, InitialQuantity: 20, CurrentQuantity: 20, SectionID: 2},
+		}
+		mockedService.On("Receive", mock.Anything, mock.Anything, mock.MatchedBy(func(b domain.Batches) bool {
+			return b.BatchNumber == 99 && b.InitialQuantity == 20 && b.SectionID == 2
+		})).Return(expected, nil)
+
+		req, res := testutil.MakeRequest(http.MethodPost, INBOUND_URL, body)
+		server.ServeHTTP(res, req)
+
+		var received testutil.SuccessResponse[domain.InboundReceipt]
+		json.Unmarshal(res.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusCreated, res.Code)
+		assert.Equal(t, expected.ProductBatch.ID, received.Data.ProductBatch.ID)
+		mockedService.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+	t.Run("should return status 422 when the section is in another warehouse", func(t *testing.T) {
+		mockedService := InboundOrdersServiceMock{}
+		controller := handler.NewInboundOrder(&mockedService)
+		server := getInboundServer(controller)
+
+		mockedService.On("Receive", mock.Anything, mock.Anything, mock.Anything).Return(domain.InboundReceipt{}, inboundorder.ErrSectionWarehouse)
+
+		req, res := testutil.MakeRequest(http.MethodPost, INBOUND_URL, getTestReceiveRequest())
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	})
+	t.Run("should return status 422 when a batch date is invalid", func(t *testing.T) {
+		mockedService := InboundOrdersServiceMock{}
+		controller := handler.NewInboundOrder(&mockedService)
+		server := getInboundServer(controller)
+
+		body := getTestReceiveRequest()
+		body.ProductBatch.DueDate = testutil.ToPtr("tomorrow")
+
+		req, res := testutil.MakeRequest(http.MethodPost, INBOUND_URL, body)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	})
+	t.Run("should return status 400 when both a batch id and a batch are given", func(t *testing.T) {
+		mockedService := InboundOrdersServiceMock{}
+		controller := handler.NewInboundOrder(&mockedService)
+		server := getInboundServer(controller)
+
+		body := getTestReceiveRequest()
+		body.ProductBatchID = testutil.ToPtr(1)
+
+		req, res := testutil.MakeRequest(http.MethodPost, INBOUND_URL, body)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+}
+
+func TestGetInboundOrders(t *testing.T) {
+	t.Run("should return the inbound order by id", func(t *testing.T) {
+		mockedService := InboundOrdersServiceMock{}
+		controller := handler.NewInboundOrder(&mockedService)
+		server := getInboundServer(controller)
+
+		expected := domain.InboundOrder{ID: 3, OrderNumber: "125", EmployeeID: 1, ProductBatchID: 1, WarehouseID: 1}
+		mockedService.On("Get", mock.Anything, 3).Return(expected, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, INBOUND_URL+"/3", "")
+		server.ServeHTTP(res, req)
+
+		var received testutil.SuccessResponse[domain.InboundOrder]
+		json.Unmarshal(res.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, received.Data)
+	})
+	t.Run("should return status 404 when the order does not exist", func(t *testing.T) {
+		mockedService := InboundOrdersServiceMock{}
+		controller := handler.NewInboundOrder(&mockedService)
+		server := getInboundServer(controller)
+
+		mockedService.On("Get", mock.Anything, 3).Return(domain.InboundOrder{}, inboundorder.ErrNotFound)
+
+		req, res := testutil.MakeRequest(http.MethodGet, INBOUND_URL+"/3", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+	t.Run("should pass the filters to the service", func(t *testing.T) {
+		mockedService := InboundOrdersServiceMock{}
+		controller := handler.NewInboundOrder(&mockedService)
+		server := getInboundServer(controller)
+
+		mockedService.On("List", mock.Anything, mock.MatchedBy(func(f inboundorder.Filter) bool {
+			return f.WarehouseID.HasVal && f.WarehouseID.Val == 2 &&
+				!f.EmployeeID.HasVal &&
+				f.From.Val.Equal(time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)) &&
+				f.To.Val.Equal(time.Date(2023, 7, 2, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond))
+		})).Return([]domain.InboundOrder{}, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, INBOUND_URL+"?warehouse_id=2&from=2023-07-01&to=2023-07-01", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+	})
+	t.Run("should return status 400 when a filter is invalid", func(t *testing.T) {
+		mockedService := InboundOrdersServiceMock{}
+		controller := handler.NewInboundOrder(&mockedService)
+		server := getInboundServer(controller)
+
+		req, res := testutil.MakeRequest(http.MethodGet, INBOUND_URL+"?employee_id=abc", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+}
+
+func TestReceivingReport(t *testing.T) {
+	t.Run("should return the report", func(t *testing.T) {
+		mockedService := InboundOrdersServiceMock{}
+		controller := handler.NewInboundOrder(&mockedService)
+		server := getInboundServer(controller)
+
+		expected := getTestReceivingReport()
+		mockedService.On("ReceivingReport", mock.Anything, mock.Anything).Return(expected, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, INBOUND_URL+"/report-receiving", "")
+		server.ServeHTTP(res, req)
+
+		var received testutil.SuccessResponse[[]domain.ReceivingReport]
+		json.Unmarshal(res.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, received.Data)
+	})
+	t.Run("should export the report as CSV", func(t *testing.T) {
+		mockedService := InboundOrdersServiceMock{}
+		controller := handler.NewInboundOrder(&mockedService)
+		server := getInboundServer(controller)
+
+		mockedService.On("StreamReceivingReport", mock.Anything, mock.Anything).Return(getTestReceivingReport(), nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, INBOUND_URL+"/report-receiving?format=csv", "")
+		server.ServeHTTP(res, req)
+
+		expected := "warehouse_id,product_id,day,inbound_orders_count,employees_count,received_quantity,first_received_at,last_received_at\n" +
+			"1,2,2023-07-06,3,2,60,2023-07-06T08:00:00Z,2023-07-06T16:30:00Z\n"
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, res.Body.String())
+		mockedService.AssertNotCalled(t, "ReceivingReport", mock.Anything, mock.Anything)
+	})
+	t.Run("should return 500 if the export cannot be streamed", func(t *testing.T) {
+		mockedService := InboundOrdersServiceMock{}
+		controller := handler.NewInboundOrder(&mockedService)
+		server := getInboundServer(controller)
+
+		mockedService.On("StreamReceivingReport", mock.Anything, mock.Anything).Return([]domain.ReceivingReport{}, inboundorder.ErrInternalServerError)
+
+		req, res := testutil.MakeRequest(http.MethodGet, INBOUND_URL+"/report-receiving?format=csv", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+	})
+}
+
+func getTestReceivingReport() []domain.ReceivingReport {
+	return []domain.ReceivingReport{{
+		WarehouseID:      1,
+		ProductID:        2,
+		Day:              "2023-07-06",
+		OrdersCount:      3,
+		EmployeesCount:   2,
+		ReceivedQuantity: 60,
+		FirstReceivedAt:  time.Date(2023, 7, 6, 8, 0, 0, 0, time.UTC),
+		LastReceivedAt:   time.Date(2023, 7, 6, 16, 30, 0, 0, time.UTC),
+	}}
+}
+
+func getTestReceiveRequest() handler.InboundOrderRequest {
+	date := time.Date(2023, 7, 6, 14, 30, 0, 0, time.UTC)
+	return handler.InboundOrderRequest{
+		OrderDate:   &date,
+		OrderNumber: testutil.ToPtr("125"),
+		EmployeeID:  testutil.ToPtr(1),
+		WarehouseID: testutil.ToPtr(1),
+		ProductBatch: &handler.InboundBatchRequest{
+			BatchNumber:        testutil.ToPtr(99),
+			CurrentTemperature: testutil.ToPtr(4),
+			DueDate:            testutil.ToPtr("2023-08-01"),
+			InitialQuantity:    testutil.ToPtr(20),
+			ManufacturingDate:  testutil.ToPtr("2023-07-01"),
+			ManufacturingHour:  testutil.ToPtr(10),
+			MinimumTemperature: testutil.ToPtr(2),
+			ProductID:          testutil.ToPtr(1),
+			SectionID:          testutil.ToPtr(2),
+		},
+	}
+}
+
 func getInboundServer(h *handler.InboundOrder) *gin.Engine {
 	s := testutil.CreateServer()
 
 	inboundOrdersRG := s.Group(INBOUND_URL)
 	{
 		inboundOrdersRG.POST("", middleware.Body[handler.InboundOrderRequest](), h.Create())
+		inboundOrdersRG.GET("", h.GetAll())
+		inboundOrdersRG.GET("/report-receiving", h.ReceivingReport())
+		inboundOrdersRG.GET("/:id", middleware.IntPathParam(), h.Get())
 	}
 
 	return s
@@ -201,3 +411,33 @@ func (svc *InboundOrdersServiceMock) Create(c context.Context, i domain.InboundO
 	args := svc.Called(c, i)
 	return args.Get(0).(domain.InboundOrder), args.Error(1)
 }
+
+func (svc *InboundOrdersServiceMock) Receive(c context.Context, i domain.InboundOrder, b domain.Batches) (domain.InboundReceipt, error) {
+	args := svc.Called(c, i, b)
+	return args.Get(0).(domain.InboundReceipt), args.Error(1)
+}
+
+func (svc *InboundOrdersServiceMock) Get(c context.Context, id int) (domain.InboundOrder, error) {
+	args := svc.Called(c, id)
+	return args.Get(0).(domain.InboundOrder), args.Error(1)
+}
+
+func (svc *InboundOrdersServiceMock) List(c context.Context, f inboundorder.Filter) ([]domain.InboundOrder, error) {
+	args := svc.Called(c, f)
+	return args.Get(0).([]domain.InboundOrder), args.Error(1)
+}
+
+func (svc *InboundOrdersServiceMock) ReceivingReport(c context.Context, f inboundorder.Filter) ([]domain.ReceivingReport, error) {
+	args := svc.Called(c, f)
+	return args.Get(0).([]domain.ReceivingReport), args.Error(1)
+}
+
+func (svc *InboundOrdersServiceMock) StreamReceivingReport(c context.Context, f inboundorder.Filter, fn func(domain.ReceivingReport) error) error {
+	args := svc.Called(c, f)
+	for _, entry := range args.Get(0).([]domain.ReceivingReport) {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
@@ -3,10 +3,13 @@ package handler
 import (
 	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/localities"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/export"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
 	"github.com/gin-gonic/gin"
 )
@@ -27,6 +30,31 @@ type CarrierReportEntry struct {
 	CarrierCount int    `json:"carriers_count"`
 }
 
+type WarehouseReportEntry struct {
+	Level          string `json:"level"`
+	ID             int    `json:"id"`
+	Name           string `json:"name"`
+	WarehouseCount int    `json:"warehouses_count"`
+}
+
+type StockReportEntry struct {
+	Level          string `json:"level"`
+	ID             int    `json:"id"`
+	Name           string `json:"name"`
+	WarehouseCount int    `json:"warehouses_count"`
+	SectionCount   int    `json:"sections_count"`
+	StockQuantity  int    `json:"stock_quantity"`
+}
+
+type LocalityRequest struct {
+	Name *string `binding:"required" json:"locality_name"`
+}
+
+type LocalityUpdateRequest struct {
+	Name       *string `json:"locality_name"`
+	ProvinceID *int    `json:"province_id"`
+}
+
 func NewLocality(svc localities.Service) *Locality {
 	return &Locality{
 		locService: svc,
@@ -40,6 +68,7 @@ func NewLocality(svc localities.Service) *Locality {
 //	@Accept		json
 //	@Produce	json
 //	@Param		locality	body		localities.CreateDTO	true	"Locality to be added"
+//	@Param		Idempotency-Key	header	string	false	"Makes retries of the request return its first response"
 //	@Success	201			{object}	web.response			"Returns created locality"
 //	@Failure	409			{object}	web.errorResponse		"Locality is not unique"
 //	@Failure	422			{object}	web.errorResponse		"Missing fields or invalid field types"
@@ -66,9 +95,14 @@ func (h *Locality) Create() gin.HandlerFunc {
 //	@Tags		Localities
 //	@Accept		json
 //	@Produce	json
+//	@Produce	text/csv
+//	@Produce	application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//	@Param		format	query		string				false	"Export format: json, csv or xlsx"
 //	@Success	200	{object}	web.response		"Returns seller count for localities"
 //	@Success	204	{object}	web.response		"No content was found"
+//	@Failure	400	{object}	web.errorResponse	"Unsupported export format"
 //	@Failure	500	{object}	web.errorResponse	"Could not generate report"
+//	@Failure	429	{object}	web.errorResponse	"Report rate limit exceeded, see Retry-After"
 //	@Router		/api/v1/localities/report-sellers [get]
 func (h *Locality) SellerReport() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -76,6 +110,21 @@ func (h *Locality) SellerReport() gin.HandlerFunc {
 		if _, hasId := c.Params.Get("id"); hasId {
 			id = *optional.FromVal(c.GetInt("id"))
 		}
+		format, ok := negotiateFormat(c)
+		if !ok {
+			return
+		}
+		if !id.HasVal && format != export.JSON {
+			err := streamRows(c, format, "report-sellers", sellerReportHeader, func(e localities.CountByLocality) []string {
+				return sellerReportRow(mapCountToSellerReport(e))
+			}, func(fn func(localities.CountByLocality) error) error {
+				return h.locService.StreamSellers(c.Request.Context(), fn)
+			})
+			if err != nil {
+				web.Error(c, mapLocalityErrToStatus(err), err.Error())
+			}
+			return
+		}
 
 		report, err := h.locService.CountSellers(c, id)
 		data := MapSellerReportToDTO(report)
@@ -86,6 +135,11 @@ func (h *Locality) SellerReport() gin.HandlerFunc {
 			return
 		}
 
+		if format != export.JSON {
+			exportRows(c, format, "report-sellers", sellerReportHeader, data, sellerReportRow)
+			return
+		}
+
 		if len(report) == 0 {
 			web.Success(c, http.StatusNoContent, data)
 			return
@@ -100,8 +154,12 @@ func (h *Locality) SellerReport() gin.HandlerFunc {
 //	@Tags		Localities
 //	@Accept		json
 //	@Produce	json
+//	@Produce	text/csv
+//	@Produce	application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 //	@Param		id	path		int					true	"Locality ID"
+//	@Param		format	query		string				false	"Export format: json, csv or xlsx"
 //	@Success	200	{object}	web.response		"Returns seller count for locality"
+//	@Failure	400	{object}	web.errorResponse	"Unsupported export format"
 //	@Failure	404	{object}	web.response		"ID was not found"
 //	@Failure	500	{object}	web.errorResponse	"Could generate report"
 //	@Router		/api/v1/localities/report-sellers/{id} [get]
@@ -113,9 +171,14 @@ func _() {} // Implementation is in the SellerReport function
 //	@Tags		Localities
 //	@Accept		json
 //	@Produce	json
+//	@Produce	text/csv
+//	@Produce	application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//	@Param		format	query		string				false	"Export format: json, csv or xlsx"
 //	@Success	200	{object}	web.response		"Returns carrier count for localities"
 //	@Success	204	{object}	web.response		"No content was found"
+//	@Failure	400	{object}	web.errorResponse	"Unsupported export format"
 //	@Failure	500	{object}	web.errorResponse	"Could not generate report"
+//	@Failure	429	{object}	web.errorResponse	"Report rate limit exceeded, see Retry-After"
 //	@Router		/api/v1/localities/report-carriers [get]
 func (h *Locality) CarrierReport() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -123,6 +186,21 @@ func (h *Locality) CarrierReport() gin.HandlerFunc {
 		if _, hasId := c.Params.Get("id"); hasId {
 			id = *optional.FromVal(c.GetInt("id"))
 		}
+		format, ok := negotiateFormat(c)
+		if !ok {
+			return
+		}
+		if !id.HasVal && format != export.JSON {
+			err := streamRows(c, format, "report-carriers", carrierReportHeader, func(e localities.CountByLocality) []string {
+				return carrierReportRow(mapCountToCarrierReport(e))
+			}, func(fn func(localities.CountByLocality) error) error {
+				return h.locService.StreamCarriers(c.Request.Context(), fn)
+			})
+			if err != nil {
+				web.Error(c, mapLocalityErrToStatus(err), err.Error())
+			}
+			return
+		}
 
 		report, err := h.locService.CountCarriers(c, id)
 		data := MapCarrierReportToDTO(report)
@@ -133,6 +211,11 @@ func (h *Locality) CarrierReport() gin.HandlerFunc {
 			return
 		}
 
+		if format != export.JSON {
+			exportRows(c, format, "report-carriers", carrierReportHeader, data, carrierReportRow)
+			return
+		}
+
 		if len(report) == 0 {
 			web.Success(c, http.StatusNoContent, data)
 			return
@@ -147,22 +230,280 @@ func (h *Locality) CarrierReport() gin.HandlerFunc {
 //	@Tags		Localities
 //	@Accept		json
 //	@Produce	json
+//	@Produce	text/csv
+//	@Produce	application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 //	@Param		id	path		int					true	"Locality ID"
+//	@Param		format	query		string				false	"Export format: json, csv or xlsx"
 //	@Success	200	{object}	web.response		"Returns carrier count for locality"
+//	@Failure	400	{object}	web.errorResponse	"Unsupported export format"
 //	@Failure	404	{object}	web.response		"ID was not found"
 //	@Failure	500	{object}	web.errorResponse	"Could not generate report"
 //	@Router		/api/v1/localities/report-carriers/{id} [get]
 func _() {} // Implementation is in the CarrierReport function
 
-func mapLocalityErrToStatus(err error) int {
-	var invalidLocality *localities.ErrInvalidLocality
-	var notFound *localities.ErrNotFound
+// ReportAllWarehouses godoc
+//
+//	@Summary	Return warehouse count for each locality, province or country
+//	@Description	Regions are rolled up from every locality, so CSV and XLSX exports are built in full before they are sent, unlike the streamed seller and carrier reports.
+//	@Tags		Localities
+//	@Accept		json
+//	@Produce	json
+//	@Produce	text/csv
+//	@Produce	application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//	@Param		level	query		string				false	"Roll-up level: locality, province or country"
+//	@Param		format	query		string				false	"Export format: json, csv or xlsx"
+//	@Success	200	{object}	web.response		"Returns warehouse count for regions"
+//	@Success	204	{object}	web.response		"No content was found"
+//	@Failure	400	{object}	web.errorResponse	"Invalid level or unsupported export format"
+//	@Failure	500	{object}	web.errorResponse	"Could not generate report"
+//	@Failure	429	{object}	web.errorResponse	"Report rate limit exceeded, see Retry-After"
+//	@Router		/api/v1/localities/report-warehouses [get]
+func (h *Locality) WarehouseReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, level, ok := regionReportQuery(c)
+		if !ok {
+			return
+		}
+		format, ok := negotiateFormat(c)
+		if !ok {
+			return
+		}
 
-	if errors.As(err, &invalidLocality) {
-		return http.StatusConflict
+		report, err := h.locService.CountWarehouses(c, id, level)
+		if err != nil {
+			status := mapLocalityErrToStatus(err)
+			web.Error(c, status, err.Error())
+			return
+		}
+		data := MapWarehouseReportToDTO(report, level)
+
+		if format != export.JSON {
+			exportRows(c, format, "report-warehouses", warehouseReportHeader, data, warehouseReportRow)
+			return
+		}
+
+		if len(report) == 0 {
+			web.Success(c, http.StatusNoContent, data)
+			return
+		}
+		web.Success(c, http.StatusOK, data)
+	}
+}
+
+// ReportWarehousesByID godoc
+//
+//	@Summary	Return warehouse count for given locality, province or country
+//	@Tags		Localities
+//	@Accept		json
+//	@Produce	json
+//	@Produce	text/csv
+//	@Produce	application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//	@Param		id	path		int					true	"Locality, province or country ID"
+//	@Param		level	query		string				false	"Roll-up level: locality, province or country"
+//	@Param		format	query		string				false	"Export format: json, csv or xlsx"
+//	@Success	200	{object}	web.response		"Returns warehouse count for region"
+//	@Failure	400	{object}	web.errorResponse	"Invalid level or unsupported export format"
+//	@Failure	404	{object}	web.errorResponse	"ID was not found"
+//	@Failure	500	{object}	web.errorResponse	"Could not generate report"
+//	@Router		/api/v1/localities/report-warehouses/{id} [get]
+func _() {} // Implementation is in the WarehouseReport function
+
+// ReportAllStock godoc
+//
+//	@Summary	Return warehouses, sections and stock quantity for each locality, province or country
+//	@Description	Regions are rolled up from every locality, so CSV and XLSX exports are built in full before they are sent, unlike the streamed seller and carrier reports.
+//	@Tags		Localities
+//	@Accept		json
+//	@Produce	json
+//	@Produce	text/csv
+//	@Produce	application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//	@Param		level	query		string				false	"Roll-up level: locality, province or country"
+//	@Param		format	query		string				false	"Export format: json, csv or xlsx"
+//	@Success	200	{object}	web.response		"Returns stock for regions"
+//	@Success	204	{object}	web.response		"No content was found"
+//	@Failure	400	{object}	web.errorResponse	"Invalid level or unsupported export format"
+//	@Failure	500	{object}	web.errorResponse	"Could not generate report"
+//	@Failure	429	{object}	web.errorResponse	"Report rate limit exceeded, see Retry-After"
+//	@Router		/api/v1/localities/report-stock [get]
+func (h *Locality) StockReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, level, ok := regionReportQuery(c)
+		if !ok {
+			return
+		}
+		format, ok := negotiateFormat(c)
+		if !ok {
+			return
+		}
+
+		report, err := h.locService.Stock(c, id, level)
+		if err != nil {
+			status := mapLocalityErrToStatus(err)
+			web.Error(c, status, err.Error())
+			return
+		}
+		data := MapStockReportToDTO(report, level)
+
+		if format != export.JSON {
+			exportRows(c, format, "report-stock", stockReportHeader, data, stockReportRow)
+			return
+		}
+
+		if len(report) == 0 {
+			web.Success(c, http.StatusNoContent, data)
+			return
+		}
+		web.Success(c, http.StatusOK, data)
+	}
+}
+
+// ReportStockByID godoc
+//
+//	@Summary	Return warehouses, sections and stock quantity for given locality, province or country
+//	@Tags		Localities
+//	@Accept		json
+//	@Produce	json
+//	@Produce	text/csv
+//	@Produce	application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//	@Param		id	path		int					true	"Locality, province or country ID"
+//	@Param		level	query		string				false	"Roll-up level: locality, province or country"
+//	@Param		format	query		string				false	"Export format: json, csv or xlsx"
+//	@Success	200	{object}	web.response		"Returns stock for region"
+//	@Failure	400	{object}	web.errorResponse	"Invalid level or unsupported export format"
+//	@Failure	404	{object}	web.errorResponse	"ID was not found"
+//	@Failure	500	{object}	web.errorResponse	"Could not generate report"
+//	@Router		/api/v1/localities/report-stock/{id} [get]
+func _() {} // Implementation is in the StockReport function
+
+// regionReportQuery reads the optional region ID and the roll-up
+// level of a regional report, writing a 400 if the level is invalid.
+func regionReportQuery(c *gin.Context) (optional.Opt[int], localities.Level, bool) {
+	var id optional.Opt[int]
+	if _, hasId := c.Params.Get("id"); hasId {
+		id = *optional.FromVal(c.GetInt("id"))
+	}
+
+	level, err := localities.ParseLevel(c.DefaultQuery("level", string(localities.LevelLocality)))
+	if err != nil {
+		web.Error(c, http.StatusBadRequest, err.Error())
+		return id, "", false
+	}
+	return id, level, true
+}
+
+// Get godoc
+//
+//	@Summary	Get locality by ID
+//	@Tags		Localities
+//	@Produce	json
+//	@Param		id				path		int					true	"Locality ID"
+//	@Param		If-None-Match	header		string				false	"ETag of the cached locality"
+//	@Success	200				{object}	web.response		"Locality with its province and country"
+//	@Header		200				{string}	ETag				"Version of the locality, which also changes when its province or country is renamed"
+//	@Success	304				"Locality did not change"
+//	@Failure	400				{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	404				{object}	web.errorResponse	"Could not find locality"
+//	@Failure	500				{object}	web.errorResponse	"Could not get locality"
+//	@Router		/api/v1/localities/{id} [get]
+func (h *Locality) Get() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		loc, err := h.locService.Get(c.Request.Context(), c.GetInt("id"))
+		if err != nil {
+			web.Error(c, mapLocalityErrToStatus(err), err.Error())
+			return
+		}
+		if web.NotModified(c, loc.Version) {
+			return
+		}
+		web.Success(c, http.StatusOK, loc)
+	}
+}
+
+// Update godoc
+//
+//	@Summary		Update locality
+//	@Description	Renames the locality, or moves it to another province.
+//	@Tags			Localities
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int						true	"Locality ID"
+//	@Param			locality	body		LocalityUpdateRequest	true	"Fields to update"
+//	@Param			If-Match	header		string					false	"ETag the locality must have"
+//	@Success		200			{object}	web.response			"Returns updated locality"
+//	@Header			200			{string}	ETag					"New version of the locality"
+//	@Failure		400			{object}	web.errorResponse		"Invalid ID type"
+//	@Failure		404			{object}	web.errorResponse		"Could not find locality"
+//	@Failure		409			{object}	web.errorResponse		"Locality is not unique in its province, or `province_id` not found"
+//	@Failure		412			{object}	web.errorResponse		"Locality changed since it was read"
+//	@Failure		422			{object}	web.errorResponse		"Invalid field types or empty name"
+//	@Failure		500			{object}	web.errorResponse		"Could not save locality"
+//	@Router			/api/v1/localities/{id} [patch]
+func (h *Locality) Update() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := middleware.GetBody[LocalityUpdateRequest](c)
+		dto := localities.UpdateDTO{
+			Name:       *optional.FromPtr(req.Name),
+			ProvinceID: *optional.FromPtr(req.ProvinceID),
+		}
+
+		loc, err := h.locService.Update(c.Request.Context(), c.GetInt("id"), dto)
+		if err != nil {
+			web.Error(c, mapLocalityErrToStatus(err), err.Error())
+			return
+		}
+		web.SetETag(c, loc.Version)
+		web.Success(c, http.StatusOK, loc)
 	}
-	if errors.As(err, &notFound) {
+}
+
+// Delete godoc
+//
+//	@Summary	Delete locality
+//	@Tags		Localities
+//	@Param		id			path	int		true	"Locality ID"
+//	@Param		If-Match	header	string	false	"ETag the locality must have"
+//	@Success	204			"Locality deleted"
+//	@Failure	400			{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	404			{object}	web.errorResponse	"Could not find locality"
+//	@Failure	409			{object}	web.errorResponse	"Sellers, carriers or warehouses are in the locality"
+//	@Failure	412			{object}	web.errorResponse	"Locality changed since it was read"
+//	@Failure	500			{object}	web.errorResponse	"Could not delete locality"
+//	@Router		/api/v1/localities/{id} [delete]
+func (h *Locality) Delete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := h.locService.Delete(c.Request.Context(), c.GetInt("id")); err != nil {
+			web.Error(c, mapLocalityErrToStatus(err), err.Error())
+			return
+		}
+		web.Success(c, http.StatusNoContent, nil)
+	}
+}
+
+func mapLocalityErrToStatus(err error) int {
+	var (
+		invalidLocality  *localities.ErrInvalidLocality
+		notFound         *localities.ErrNotFound
+		countryNotFound  *localities.ErrCountryNotFound
+		provinceNotFound *localities.ErrProvinceNotFound
+		duplicate        *localities.ErrDuplicateName
+		invalidProvince  *localities.ErrInvalidProvince
+		inUse            *localities.ErrInUse
+		invalidName      *localities.ErrInvalidName
+	)
+	switch {
+	case errors.As(err, &notFound),
+		errors.As(err, &countryNotFound),
+		errors.As(err, &provinceNotFound):
 		return http.StatusNotFound
+	case errors.As(err, &invalidLocality),
+		errors.As(err, &duplicate),
+		errors.As(err, &invalidProvince),
+		errors.As(err, &inUse):
+		return http.StatusConflict
+	case errors.As(err, &invalidName):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, sqlutil.ErrVersionMismatch):
+		return http.StatusPreconditionFailed
 	}
 	return http.StatusInternalServerError
 }
@@ -202,3 +543,62 @@ func mapCountToSellerReport(count localities.CountByLocality) SellerReportEntry
 		SellerCount:  count.Count,
 	}
 }
+
+var sellerReportHeader = []string{"locality_id", "locality_name", "sellers_count"}
+
+func sellerReportRow(e SellerReportEntry) []string {
+	return []string{strconv.Itoa(e.LocalityID), e.LocalityName, strconv.Itoa(e.SellerCount)}
+}
+
+var carrierReportHeader = []string{"locality_id", "locality_name", "carriers_count"}
+
+func carrierReportRow(e CarrierReportEntry) []string {
+	return []string{strconv.Itoa(e.LocalityID), e.LocalityName, strconv.Itoa(e.CarrierCount)}
+}
+
+func MapWarehouseReportToDTO(report []localities.CountByLocality, level localities.Level) []WarehouseReportEntry {
+	dtos := make([]WarehouseReportEntry, 0)
+
+	for _, entry := range report {
+		dtos = append(dtos, WarehouseReportEntry{
+			Level:          string(level),
+			ID:             entry.ID,
+			Name:           entry.Name,
+			WarehouseCount: entry.Count,
+		})
+	}
+
+	return dtos
+}
+
+func MapStockReportToDTO(report []localities.StockByLocality, level localities.Level) []StockReportEntry {
+	dtos := make([]StockReportEntry, 0)
+
+	for _, entry := range report {
+		dtos = append(dtos, StockReportEntry{
+			Level:          string(level),
+			ID:             entry.ID,
+			Name:           entry.Name,
+			WarehouseCount: entry.Warehouses,
+			SectionCount:   entry.Sections,
+			StockQuantity:  entry.Count,
+		})
+	}
+
+	return dtos
+}
+
+var warehouseReportHeader = []string{"level", "id", "name", "warehouses_count"}
+
+func warehouseReportRow(e WarehouseReportEntry) []string {
+	return []string{e.Level, strconv.Itoa(e.ID), e.Name, strconv.Itoa(e.WarehouseCount)}
+}
+
+var stockReportHeader = []string{"level", "id", "name", "warehouses_count", "sections_count", "stock_quantity"}
+
+func stockReportRow(e StockReportEntry) []string {
+	return []string{
+		e.Level, strconv.Itoa(e.ID), e.Name,
+		strconv.Itoa(e.WarehouseCount), strconv.Itoa(e.SectionCount), strconv.Itoa(e.StockQuantity),
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/localities"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
 	"github.com/gin-gonic/gin"
@@ -21,6 +22,8 @@ import (
 const LOCALITY_URL = "/localities"
 const SELLER_REPORT_URL = "/report-sellers"
 const CARRIER_REPORT_URL = "/report-carriers"
+const WAREHOUSE_REPORT_URL = "/report-warehouses"
+const STOCK_REPORT_URL = "/report-stock"
 
 func TestLocalityCreate(t *testing.T) {
 	t.Run("Returns 201 if locality is created successfully", func(t *testing.T) {
@@ -242,6 +245,51 @@ func TestLocalityCarrierReport(t *testing.T) {
 	})
 }
 
+func TestLocalityReportExport(t *testing.T) {
+	t.Run("Returns seller report as CSV", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		h := handler.NewLocality(&svc)
+		server := getLocalityServer(h)
+
+		svc.On("StreamSellers", mock.Anything).Return(getSellerCounts(), nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, LOCALITY_URL+SELLER_REPORT_URL, nil)
+		req.Header.Set("Accept", "text/csv")
+		server.ServeHTTP(res, req)
+
+		expected := "locality_id,locality_name,sellers_count\n1,Melicidade,2\n2,Tesla,1\n"
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, res.Body.String())
+		svc.AssertNotCalled(t, "CountSellers", mock.Anything, mock.Anything)
+	})
+	t.Run("Returns 500 if the carrier report cannot be streamed", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		h := handler.NewLocality(&svc)
+		server := getLocalityServer(h)
+
+		svc.On("StreamCarriers", mock.Anything).Return([]localities.CountByLocality{}, localities.NewErrGeneric("error counting carriers"))
+
+		req, res := testutil.MakeRequest(http.MethodGet, LOCALITY_URL+CARRIER_REPORT_URL+"?format=csv", nil)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+	})
+	t.Run("Returns carrier report error before exporting", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		h := handler.NewLocality(&svc)
+		server := getLocalityServer(h)
+
+		id := *optional.FromVal(42)
+		svc.On("CountCarriers", mock.Anything, id).Return([]localities.CountByLocality{}, localities.NewErrNotFound(id.Val))
+
+		url := fmt.Sprintf("%s/%d?format=xlsx", LOCALITY_URL+CARRIER_REPORT_URL, id.Val)
+		req, res := testutil.MakeRequest(http.MethodGet, url, nil)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+}
+
 func getSellerCounts() []localities.CountByLocality {
 	return []localities.CountByLocality{
 		{
@@ -257,6 +305,208 @@ func getSellerCounts() []localities.CountByLocality {
 	}
 }
 
+func TestLocalityCRUD(t *testing.T) {
+	t.Run("Returns 200 with the locality", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		h := handler.NewLocality(&svc)
+		server := getLocalityServer(h)
+
+		expected := domain.Locality{ID: 1, Name: "Melicidade", ProvinceID: 1, Province: "SP", Country: "BR"}
+		svc.On("Get", mock.Anything, 1).Return(expected, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, LOCALITY_URL+"/1", "")
+		server.ServeHTTP(res, req)
+
+		var response testutil.SuccessResponse[domain.Locality]
+		json.Unmarshal(res.Body.Bytes(), &response)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, response.Data)
+	})
+	t.Run("Does not shadow the report routes", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		h := handler.NewLocality(&svc)
+		server := getLocalityServer(h)
+
+		svc.On("CountSellers", mock.Anything, mock.Anything).Return(getSellerCounts(), nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, LOCALITY_URL+SELLER_REPORT_URL, "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		svc.AssertNumberOfCalls(t, "Get", 0)
+	})
+	t.Run("Passes only the given fields on update", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		h := handler.NewLocality(&svc)
+		server := getLocalityServer(h)
+
+		dto := localities.UpdateDTO{ProvinceID: *optional.FromVal(2)}
+		svc.On("Update", mock.Anything, 1, dto).Return(domain.Locality{ID: 1, ProvinceID: 2}, nil)
+
+		body := handler.LocalityUpdateRequest{ProvinceID: testutil.ToPtr(2)}
+		req, res := testutil.MakeRequest(http.MethodPatch, LOCALITY_URL+"/1", body)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+	})
+	t.Run("Returns 409 if moved to a missing province", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		h := handler.NewLocality(&svc)
+		server := getLocalityServer(h)
+
+		svc.On("Update", mock.Anything, 1, mock.Anything).Return(domain.Locality{}, localities.NewErrInvalidProvince(9))
+
+		body := handler.LocalityUpdateRequest{ProvinceID: testutil.ToPtr(9)}
+		req, res := testutil.MakeRequest(http.MethodPatch, LOCALITY_URL+"/1", body)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusConflict, res.Code)
+	})
+	t.Run("Returns 204 on delete", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		h := handler.NewLocality(&svc)
+		server := getLocalityServer(h)
+
+		svc.On("Delete", mock.Anything, 1).Return(nil)
+
+		req, res := testutil.MakeRequest(http.MethodDelete, LOCALITY_URL+"/1", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNoContent, res.Code)
+	})
+	t.Run("Returns 409 when deleting a referenced locality", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		h := handler.NewLocality(&svc)
+		server := getLocalityServer(h)
+
+		svc.On("Delete", mock.Anything, 1).Return(localities.NewErrInUse("locality", 1, "sellers"))
+
+		req, res := testutil.MakeRequest(http.MethodDelete, LOCALITY_URL+"/1", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusConflict, res.Code)
+	})
+	t.Run("Returns 304 if the client has the version", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		h := handler.NewLocality(&svc)
+		server := getLocalityServer(h)
+
+		svc.On("Get", mock.Anything, 1).Return(domain.Locality{ID: 1, Version: 3}, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, LOCALITY_URL+"/1", "")
+		req.Header.Set("If-None-Match", `"3"`)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNotModified, res.Code)
+	})
+	t.Run("Sends the new ETag on update", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		h := handler.NewLocality(&svc)
+		server := getLocalityServer(h)
+
+		svc.On("Update", mock.Anything, 1, mock.Anything).Return(domain.Locality{ID: 1, Name: "Meli", Version: 4}, nil)
+
+		body := handler.LocalityUpdateRequest{Name: testutil.ToPtr("Meli")}
+		req, res := testutil.MakeRequest(http.MethodPatch, LOCALITY_URL+"/1", body)
+		req.Header.Set("If-Match", `"3"`)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, `"4"`, res.Header().Get("ETag"))
+	})
+	t.Run("Returns 412 if the locality is in another version", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		h := handler.NewLocality(&svc)
+		server := getLocalityServer(h)
+
+		svc.On("Delete", mock.Anything, 1).Return(sqlutil.ErrVersionMismatch)
+
+		req, res := testutil.MakeRequest(http.MethodDelete, LOCALITY_URL+"/1", "")
+		req.Header.Set("If-Match", `"2"`)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusPreconditionFailed, res.Code)
+	})
+}
+
+func TestLocalityRegionalReports(t *testing.T) {
+	t.Run("Returns warehouse count rolled up to the given level", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		h := handler.NewLocality(&svc)
+		server := getLocalityServer(h)
+
+		counts := []localities.CountByLocality{{ID: 1, Name: "SP", Count: 3}}
+		expected := handler.MapWarehouseReportToDTO(counts, localities.LevelProvince)
+		svc.On("CountWarehouses", mock.Anything, optional.Opt[int]{}, localities.LevelProvince).Return(counts, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, LOCALITY_URL+WAREHOUSE_REPORT_URL+"?level=province", nil)
+		server.ServeHTTP(res, req)
+
+		var response testutil.SuccessResponse[[]handler.WarehouseReportEntry]
+		json.Unmarshal(res.Body.Bytes(), &response)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, response.Data)
+	})
+	t.Run("Returns stock of a single locality by default", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		h := handler.NewLocality(&svc)
+		server := getLocalityServer(h)
+
+		stock := []localities.StockByLocality{{
+			CountByLocality: localities.CountByLocality{ID: 2, Name: "Tesla", Count: 40},
+			Warehouses:      1,
+			Sections:        2,
+		}}
+		id := *optional.FromVal(2)
+		svc.On("Stock", mock.Anything, id, localities.LevelLocality).Return(stock, nil)
+
+		url := fmt.Sprintf("%s/%d", LOCALITY_URL+STOCK_REPORT_URL, id.Val)
+		req, res := testutil.MakeRequest(http.MethodGet, url, nil)
+		server.ServeHTTP(res, req)
+
+		var response testutil.SuccessResponse[[]handler.StockReportEntry]
+		json.Unmarshal(res.Body.Bytes(), &response)
+
+		expected := []handler.StockReportEntry{{
+			Level:          "locality",
+			ID:             2,
+			Name:           "Tesla",
+			WarehouseCount: 1,
+			SectionCount:   2,
+			StockQuantity:  40,
+		}}
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, response.Data)
+	})
+	t.Run("Returns 404 if region is not found", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		h := handler.NewLocality(&svc)
+		server := getLocalityServer(h)
+
+		id := *optional.FromVal(9)
+		svc.On("Stock", mock.Anything, id, localities.LevelCountry).Return([]localities.StockByLocality{}, localities.NewErrCountryNotFound(id.Val))
+
+		url := fmt.Sprintf("%s/%d?level=country", LOCALITY_URL+STOCK_REPORT_URL, id.Val)
+		req, res := testutil.MakeRequest(http.MethodGet, url, nil)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+	t.Run("Returns 400 if level is invalid", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		h := handler.NewLocality(&svc)
+		server := getLocalityServer(h)
+
+		req, res := testutil.MakeRequest(http.MethodGet, LOCALITY_URL+WAREHOUSE_REPORT_URL+"?level=planet", nil)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+		svc.AssertNotCalled(t, "CountWarehouses")
+	})
+}
+
 func getLocalityServer(h *handler.Locality) *gin.Engine {
 	s := testutil.CreateServer()
 	rg := s.Group(LOCALITY_URL)
@@ -266,6 +516,13 @@ func getLocalityServer(h *handler.Locality) *gin.Engine {
 		rg.GET(SELLER_REPORT_URL+"/:id", middleware.IntPathParam(), h.SellerReport())
 		rg.GET(CARRIER_REPORT_URL, h.CarrierReport())
 		rg.GET(CARRIER_REPORT_URL+"/:id", middleware.IntPathParam(), h.CarrierReport())
+		rg.GET(WAREHOUSE_REPORT_URL, h.WarehouseReport())
+		rg.GET(WAREHOUSE_REPORT_URL+"/:id", middleware.IntPathParam(), h.WarehouseReport())
+		rg.GET(STOCK_REPORT_URL, h.StockReport())
+		rg.GET(STOCK_REPORT_URL+"/:id", middleware.IntPathParam(), h.StockReport())
+		rg.GET("/:id", middleware.IntPathParam(), h.Get())
+		rg.PATCH("/:id", middleware.IntPathParam(), middleware.IfMatch(), middleware.Body[handler.LocalityUpdateRequest](), h.Update())
+		rg.DELETE("/:id", middleware.IntPathParam(), middleware.IfMatch(), h.Delete())
 	}
 	return s
 }
@@ -284,7 +541,112 @@ func (s *LocalityServiceMock) CountSellers(c context.Context, id optional.Opt[in
 	return args.Get(0).([]localities.CountByLocality), args.Error(1)
 }
 
+func (s *LocalityServiceMock) CountWarehouses(c context.Context, id optional.Opt[int], level localities.Level) ([]localities.CountByLocality, error) {
+	args := s.Called(c, id, level)
+	return args.Get(0).([]localities.CountByLocality), args.Error(1)
+}
+
+func (s *LocalityServiceMock) Stock(c context.Context, id optional.Opt[int], level localities.Level) ([]localities.StockByLocality, error) {
+	args := s.Called(c, id, level)
+	return args.Get(0).([]localities.StockByLocality), args.Error(1)
+}
+
+func (s *LocalityServiceMock) StreamSellers(c context.Context, fn func(localities.CountByLocality) error) error {
+	args := s.Called(c)
+	for _, entry := range args.Get(0).([]localities.CountByLocality) {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (s *LocalityServiceMock) StreamCarriers(c context.Context, fn func(localities.CountByLocality) error) error {
+	args := s.Called(c)
+	for _, entry := range args.Get(0).([]localities.CountByLocality) {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
 func (s *LocalityServiceMock) CountCarriers(c context.Context, id optional.Opt[int]) ([]localities.CountByLocality, error) {
 	args := s.Called(c, id)
 	return args.Get(0).([]localities.CountByLocality), args.Error(1)
 }
+
+func (s *LocalityServiceMock) Get(c context.Context, id int) (domain.Locality, error) {
+	args := s.Called(c, id)
+	return args.Get(0).(domain.Locality), args.Error(1)
+}
+
+func (s *LocalityServiceMock) GetByProvince(c context.Context, provinceID int) ([]domain.Locality, error) {
+	args := s.Called(c, provinceID)
+	return args.Get(0).([]domain.Locality), args.Error(1)
+}
+
+func (s *LocalityServiceMock) CreateInProvince(c context.Context, provinceID int, name string) (domain.Locality, error) {
+	args := s.Called(c, provinceID, name)
+	return args.Get(0).(domain.Locality), args.Error(1)
+}
+
+func (s *LocalityServiceMock) Update(c context.Context, id int, updates localities.UpdateDTO) (domain.Locality, error) {
+	args := s.Called(c, id, updates)
+	return args.Get(0).(domain.Locality), args.Error(1)
+}
+
+func (s *LocalityServiceMock) Delete(c context.Context, id int) error {
+	args := s.Called(c, id)
+	return args.Error(0)
+}
+
+func (s *LocalityServiceMock) GetAllCountries(c context.Context) ([]domain.Country, error) {
+	args := s.Called(c)
+	return args.Get(0).([]domain.Country), args.Error(1)
+}
+
+func (s *LocalityServiceMock) GetCountry(c context.Context, id int) (domain.Country, error) {
+	args := s.Called(c, id)
+	return args.Get(0).(domain.Country), args.Error(1)
+}
+
+func (s *LocalityServiceMock) CreateCountry(c context.Context, name string) (domain.Country, error) {
+	args := s.Called(c, name)
+	return args.Get(0).(domain.Country), args.Error(1)
+}
+
+func (s *LocalityServiceMock) UpdateCountry(c context.Context, id int, name optional.Opt[string]) (domain.Country, error) {
+	args := s.Called(c, id, name)
+	return args.Get(0).(domain.Country), args.Error(1)
+}
+
+func (s *LocalityServiceMock) DeleteCountry(c context.Context, id int) error {
+	args := s.Called(c, id)
+	return args.Error(0)
+}
+
+func (s *LocalityServiceMock) GetProvinces(c context.Context, countryID int) ([]domain.Province, error) {
+	args := s.Called(c, countryID)
+	return args.Get(0).([]domain.Province), args.Error(1)
+}
+
+func (s *LocalityServiceMock) GetProvince(c context.Context, id int) (domain.Province, error) {
+	args := s.Called(c, id)
+	return args.Get(0).(domain.Province), args.Error(1)
+}
+
+func (s *LocalityServiceMock) CreateProvince(c context.Context, countryID int, name string) (domain.Province, error) {
+	args := s.Called(c, countryID, name)
+	return args.Get(0).(domain.Province), args.Error(1)
+}
+
+func (s *LocalityServiceMock) UpdateProvince(c context.Context, id int, name optional.Opt[string]) (domain.Province, error) {
+	args := s.Called(c, id, name)
+	return args.Get(0).(domain.Province), args.Error(1)
+}
+
+func (s *LocalityServiceMock) DeleteProvince(c context.Context, id int) error {
+	args := s.Called(c, id)
+	return args.Error(0)
+}
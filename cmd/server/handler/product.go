@@ -3,10 +3,15 @@ package handler
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/product"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/export"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
 	"github.com/gin-gonic/gin"
 )
@@ -65,13 +70,28 @@ func NewProduct(s product.Service) *Product {
 //	@Tags		Products
 //	@Accept		json
 //	@Produce	json
-//	@Success	200	{object}	web.response		"Returns all products"
-//	@Success	204	{object}	web.response		"No products to retrieve"
-//	@Failure	500	{object}	web.errorResponse	"Could not fetch products"
+//	@Param		ids				query		string				false	"Comma separated ids of the products to retrieve"
+//	@Param		include_deleted	query		bool				false	"Include deleted products (admin only)"
+//	@Param		X-Admin-Token	header		string				false	"Admin token"
+//	@Success	200				{object}	web.response		"Returns all products"
+//	@Success	204				{object}	web.response		"No products to retrieve"
+//	@Failure	400				{object}	web.errorResponse	"Invalid include_deleted or ids"
+//	@Failure	403				{object}	web.errorResponse	"Not an admin"
+//	@Failure	500				{object}	web.errorResponse	"Could not fetch products"
 //	@Router		/api/v1/products [get]
 func (p *Product) GetAll() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ps, err := p.productService.GetAll(c.Request.Context())
+		ids, err := idsQuery(c, "ids")
+		if err != nil {
+			web.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		var ps []domain.Product
+		if ids.HasVal {
+			ps, err = p.productService.GetByIDs(c.Request.Context(), ids.Val)
+		} else {
+			ps, err = p.productService.GetAll(c.Request.Context())
+		}
 
 		if err != nil {
 			errStatus := mapProductErrToStatus(err)
@@ -93,10 +113,16 @@ func (p *Product) GetAll() gin.HandlerFunc {
 //	@Tags		Products
 //	@Accept		json
 //	@Produce	json
-//	@Param		id	path		int					true	"Product ID"
-//	@Success	200	{object}	web.response		"Returns product"
-//	@Failure	400	{object}	web.errorResponse	"Invalid ID type"
-//	@Failure	404	{object}	web.errorResponse	"Could not find product"
+//	@Param		id				path		int					true	"Product ID"
+//	@Param		include_deleted	query		bool				false	"Include deleted products (admin only)"
+//	@Param		X-Admin-Token	header		string				false	"Admin token"
+//	@Param		If-None-Match	header		string				false	"ETag of the cached product"
+//	@Success	200				{object}	web.response		"Returns product"
+//	@Header		200				{string}	ETag				"Version of the product"
+//	@Success	304				"Product did not change"
+//	@Failure	400				{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	403				{object}	web.errorResponse	"Not an admin"
+//	@Failure	404				{object}	web.errorResponse	"Could not find product"
 //	@Router		/api/v1/products/{id} [get]
 func (p *Product) Get() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -109,6 +135,9 @@ func (p *Product) Get() gin.HandlerFunc {
 			web.Error(c, errStatus, err.Error())
 			return
 		}
+		if web.NotModified(c, p.Version) {
+			return
+		}
 		web.Success(c, http.StatusOK, p)
 	}
 }
@@ -119,8 +148,11 @@ func (p *Product) Get() gin.HandlerFunc {
 //	@Tags		Products
 //	@Accept		json
 //	@Produce	json
+//	@Produce	text/csv
+//	@Produce	application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//	@Param		format	query		string				false	"Export format: json, csv or xlsx"
 //	@Success	200	{object}	web.response		"Returns products records"
-//	@Failure	400	{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	400	{object}	web.errorResponse	"Invalid ID type or unsupported export format"
 //	@Failure	404	{object}	web.errorResponse	"Could not find product"
 //	@Router		/api/v1/products/report-records [get]
 //
@@ -130,14 +162,27 @@ func (p *Product) Get() gin.HandlerFunc {
 //	@Tags		Products
 //	@Accept		json
 //	@Produce	json
+//	@Produce	text/csv
+//	@Produce	application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 //	@Param		id	path		int					true	"Product ID"
+//	@Param		format	query		string				false	"Export format: json, csv or xlsx"
 //	@Success	200	{object}	web.response		"Returns product"
-//	@Failure	400	{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	400	{object}	web.errorResponse	"Invalid ID type or unsupported export format"
 //	@Failure	404	{object}	web.errorResponse	"Could not find product"
+//	@Failure	429	{object}	web.errorResponse	"Report rate limit exceeded, see Retry-After"
 //	@Router		/api/v1/products/report-records/{id} [get]
 func (p *Product) GetRecords() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.GetInt("id")
+		format, ok := negotiateFormat(c)
+		if !ok {
+			return
+		}
+		if format != export.JSON {
+			p.exportRecords(c, format, id)
+			return
+		}
+
 		if id != 0 {
 			p, err := p.productService.GetRecords(c.Request.Context(), id)
 			if err != nil {
@@ -159,6 +204,32 @@ func (p *Product) GetRecords() gin.HandlerFunc {
 	}
 }
 
+// exportRecords streams the product records straight from the
+// database, so that large reports are never fully held in memory.
+func (p *Product) exportRecords(c *gin.Context, format export.Format, id int) {
+	err := export.Stream(c, format, "report-records", productRecordHeader, func(write func([]string) error) error {
+		return p.productService.StreamRecords(c.Request.Context(), id, func(r domain.Product_Records) error {
+			return write(productRecordRow(r))
+		})
+	})
+	if err != nil {
+		errStatus := mapProductErrToStatus(err)
+		web.Error(c, errStatus, err.Error())
+	}
+}
+
+var productRecordHeader = []string{"id", "last_update_date", "purchase_price", "sale_price", "product_id"}
+
+func productRecordRow(r domain.Product_Records) []string {
+	return []string{
+		strconv.Itoa(r.ID),
+		r.LastUpdateDate.Format(time.RFC3339),
+		strconv.FormatFloat(r.PurchasePrice, 'f', 2, 64),
+		strconv.FormatFloat(r.SalePrice, 'f', 2, 64),
+		strconv.Itoa(r.ProductID),
+	}
+}
+
 // Create godoc
 //
 //	@Summary	Create new product
@@ -166,8 +237,9 @@ func (p *Product) GetRecords() gin.HandlerFunc {
 //	@Accept		json
 //	@Produce	json
 //	@Param		product	body		CreateRequest		true	"Product to be added"
+//	@Param		Idempotency-Key	header	string	false	"Makes retries of the request return its first response"
 //	@Success	201		{object}	web.response		"Returns created product"
-//	@Failure	409		{object}	web.errorResponse	"`product_code` is not unique"
+//	@Failure	409		{object}	web.errorResponse	"`product_code` is not unique or `product_type_id` not found"
 //	@Failure	422		{object}	web.errorResponse	"Missing fields or invalid field types"
 //	@Failure	500		{object}	web.errorResponse	"Could not save product"
 //	@Router		/api/v1/products [post]
@@ -194,12 +266,15 @@ func (p *Product) Create() gin.HandlerFunc {
 //	@Accept		json
 //	@Param		id	path	int	true	"Product ID"
 //	@Produce	json
-//	@Param		product	body		UpdateRequest		true	"Fields to update"
-//	@Success	200		{object}	web.response		"Returns updated product"
-//	@Failure	400		{object}	web.errorResponse	"Invalid ID type"
-//	@Failure	404		{object}	web.errorResponse	"Could not find product"
-//	@Failure	409		{object}	web.errorResponse	"`product_code` is not unique"
-//	@Failure	422		{object}	web.errorResponse	"Invalid field types"
+//	@Param		product		body		UpdateRequest		true	"Fields to update"
+//	@Param		If-Match	header		string				false	"ETag the product must have"
+//	@Success	200			{object}	web.response		"Returns updated product"
+//	@Header		200			{string}	ETag				"New version of the product"
+//	@Failure	400			{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	404			{object}	web.errorResponse	"Could not find product"
+//	@Failure	409			{object}	web.errorResponse	"`product_code` is not unique"
+//	@Failure	412			{object}	web.errorResponse	"Product changed since it was read"
+//	@Failure	422			{object}	web.errorResponse	"Invalid field types"
 //	@Failure	500		{object}	web.errorResponse	"Could not save product"
 //	@Router		/api/v1/products/{id} [patch]
 func (p *Product) Update() gin.HandlerFunc {
@@ -216,20 +291,60 @@ func (p *Product) Update() gin.HandlerFunc {
 			return
 		}
 
+		web.SetETag(c, p.Version)
 		web.Success(c, http.StatusOK, p)
 	}
 }
 
+// Batch godoc
+//
+//	@Summary		Create and update products in batch
+//	@Description	Creates the products in `create` and updates those in `update`, which are sent with their `id`. Each product gets its own status, in the order they were sent.
+//	@Tags			Products
+//	@Accept			json
+//	@Produce		json
+//	@Param			batch			body		web.batchRequest{create=[]CreateRequest,update=[]UpdateRequest}	true	"Products to create and update"
+//	@Param			Idempotency-Key	header		string				false	"Makes retries of the request return its first response"
+//	@Success		207				{object}	web.response{data=web.BatchResponse}	"Status of each product"
+//	@Failure		422				{object}	web.errorResponse	"Invalid batch"
+//	@Router			/api/v1/products/batch [post]
+func (p *Product) Batch() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, ok := getBatch[CreateRequest, UpdateRequest](c)
+		if !ok {
+			return
+		}
+		creates := make([]product.CreateDTO, len(req.Create))
+		for i := range req.Create {
+			creates[i] = *mapCreateRequestToDTO(&req.Create[i])
+		}
+		ids, reqs := req.Updates()
+		updates := make([]product.UpdateDTO, len(reqs))
+		for i := range reqs {
+			updates[i] = *mapUpdateRequestToDTO(&reqs[i])
+		}
+
+		created, createErrs := p.productService.CreateBatch(c.Request.Context(), creates)
+		updated, updateErrs := p.productService.UpdateBatch(c.Request.Context(), ids, updates)
+		web.Success(c, http.StatusMultiStatus, web.BatchResponse{
+			Create: web.BatchResults(created, createErrs, http.StatusCreated, mapProductErrToStatus),
+			Update: web.BatchResults(updated, updateErrs, http.StatusOK, mapProductErrToStatus),
+		})
+	}
+}
+
 // Delete godoc
 //
 //	@Summary	Delete product by ID
 //	@Tags		Products
 //	@Accept		json
 //	@Produce	json
-//	@Param		id	path		int					true	"Product ID"
-//	@Success	200	{object}	web.response		"Product deleted successfully"
-//	@Failure	400	{object}	web.errorResponse	"Invalid ID type"
-//	@Failure	404	{object}	web.errorResponse	"Could not find product"
+//	@Param		id			path		int					true	"Product ID"
+//	@Param		If-Match	header		string				false	"ETag the product must have"
+//	@Success	200			{object}	web.response		"Product deleted successfully"
+//	@Failure	400			{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	404			{object}	web.errorResponse	"Could not find product"
+//	@Failure	412			{object}	web.errorResponse	"Product changed since it was read"
 //	@Failure	500	{object}	web.errorResponse	"Could not delete product"
 //	@Router		/api/v1/products/{id} [delete]
 func (p *Product) Delete() gin.HandlerFunc {
@@ -247,6 +362,36 @@ func (p *Product) Delete() gin.HandlerFunc {
 	}
 }
 
+// Restore godoc
+//
+//	@Summary	Restore deleted product by ID
+//	@Tags		Products
+//	@Accept		json
+//	@Produce	json
+//	@Param		id				path		int					true	"Product ID"
+//	@Param		X-Admin-Token	header		string				true	"Admin token"
+//	@Success	200				{object}	web.response		"Returns restored product"
+//	@Failure	400				{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	403				{object}	web.errorResponse	"Restricted to admins"
+//	@Failure	404				{object}	web.errorResponse	"Could not find deleted product"
+//	@Failure	500				{object}	web.errorResponse	"Could not restore product"
+//	@Router		/api/v1/products/{id}/restore [post]
+func (p *Product) Restore() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetInt("id")
+
+		restored, err := p.productService.Restore(c.Request.Context(), id)
+
+		if err != nil {
+			errStatus := mapProductErrToStatus(err)
+			web.Error(c, errStatus, err.Error())
+			return
+		}
+		web.SetETag(c, restored.Version)
+		web.Success(c, http.StatusOK, restored)
+	}
+}
+
 // Create godoc
 //
 //	@Summary	Create new product record
@@ -254,15 +399,20 @@ func (p *Product) Delete() gin.HandlerFunc {
 //	@Accept		json
 //	@Produce	json
 //	@Param		product	record body		CreateRequestRecord		true	"Product record to be added"
+//	@Param		Idempotency-Key	header	string	false	"Makes retries of the request return its first response"
 //	@Success	201		{object}	web.response		"Returns created product record"
-//	@Failure	409		{object}	web.errorResponse	"`product_code` is not unique"
-//	@Failure	422		{object}	web.errorResponse	"Missing fields or invalid field types"
+//	@Failure	409		{object}	web.errorResponse	"`product_id` does not exist"
+//	@Failure	422		{object}	web.errorResponse	"Missing fields, invalid field types, negative prices or a future date"
 //	@Failure	500		{object}	web.errorResponse	"Could not save product"
 //	@Router		/api/v1/product-records [post]
 func (p *Product) CreateRecord() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		req := middleware.GetBody[CreateRequestRecord](c)
-		dto := mapCreateRequestRecord(&req)
+		dto, err := mapCreateRequestRecord(&req)
+		if err != nil {
+			web.Error(c, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
 		productRecord, err := p.productService.CreateRecord(c.Request.Context(), *dto)
 		if err != nil {
 			errStatus := mapProductErrToStatus(err)
@@ -274,16 +424,153 @@ func (p *Product) CreateRecord() gin.HandlerFunc {
 	}
 }
 
+// PriceHistory godoc
+//
+//	@Summary		Get the price history of a product
+//	@Description	Records between `from` and `to` in date order, with their margin and how much prices changed since the previous record.
+//	@Tags			Products
+//	@Produce		json
+//	@Param			id		path		int					true	"Product ID"
+//	@Param			from	query		string				false	"Start date (YYYY-MM-DD or RFC 3339)"
+//	@Param			to		query		string				false	"End date, inclusive (YYYY-MM-DD or RFC 3339)"
+//	@Success		200		{object}	web.response		"Returns the price history"
+//	@Failure		400		{object}	web.errorResponse	"Invalid ID type or dates"
+//	@Failure		404		{object}	web.errorResponse	"Could not find product"
+//	@Failure		500		{object}	web.errorResponse	"Could not fetch product records"
+//	@Router			/api/v1/products/{id}/price-history [get]
+func (p *Product) PriceHistory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetInt("id")
+		from, to, err := dateRangeQuery(c)
+		if err != nil {
+			web.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		history, err := p.productService.PriceHistory(c.Request.Context(), id, from, to)
+		if err != nil {
+			errStatus := mapProductErrToStatus(err)
+			web.Error(c, errStatus, err.Error())
+			return
+		}
+		web.Success(c, http.StatusOK, history)
+	}
+}
+
+// EffectivePrice godoc
+//
+//	@Summary	Get the price of a product at a given date
+//	@Tags		Products
+//	@Produce	json
+//	@Param		id	path		int					true	"Product ID"
+//	@Param		at	query		string				false	"Date (YYYY-MM-DD or RFC 3339), defaults to now"
+//	@Success	200	{object}	web.response		"Returns the price in effect"
+//	@Failure	400	{object}	web.errorResponse	"Invalid ID type or date"
+//	@Failure	404	{object}	web.errorResponse	"Could not find product or it had no price at that date"
+//	@Failure	500	{object}	web.errorResponse	"Could not fetch product records"
+//	@Router		/api/v1/products/{id}/price [get]
+func (p *Product) EffectivePrice() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetInt("id")
+		at, err := dateQuery(c, "at")
+		if err != nil {
+			web.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		price, err := p.productService.EffectivePrice(c.Request.Context(), id, at.Or(time.Now()))
+		if err != nil {
+			errStatus := mapProductErrToStatus(err)
+			web.Error(c, errStatus, err.Error())
+			return
+		}
+		web.Success(c, http.StatusOK, price)
+	}
+}
+
+// Search godoc
+//
+//	@Summary		Search products
+//	@Description	Matches `q` against the description and product code, most relevant first, and filters on the product attributes.
+//	@Tags			Products
+//	@Produce		json
+//	@Param			q					query		string				false	"Text to search for"
+//	@Param			type				query		int					false	"Product type ID"
+//	@Param			seller_id			query		int					false	"Seller ID"
+//	@Param			min_weight			query		number				false	"Minimum net weight"
+//	@Param			max_freezing_temp	query		number				false	"Maximum recommended freezing temperature"
+//	@Param			page				query		int					false	"Page number, from 1"
+//	@Param			page_size			query		int					false	"Products per page, up to 100 (default 20)"
+//	@Success		200					{object}	web.response		"Returns the page of matching products"
+//	@Failure		400					{object}	web.errorResponse	"Invalid query parameters"
+//	@Failure		500					{object}	web.errorResponse	"Could not search products"
+//	@Router			/api/v1/products/search [get]
+func (p *Product) Search() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		f, page, pageSize, err := searchQuery(c)
+		if err != nil {
+			web.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		result, err := p.productService.Search(c.Request.Context(), f, page, pageSize)
+		if err != nil {
+			errStatus := mapProductErrToStatus(err)
+			web.Error(c, errStatus, err.Error())
+			return
+		}
+		web.Success(c, http.StatusOK, result)
+	}
+}
+
+func searchQuery(c *gin.Context) (f product.SearchFilter, page, pageSize int, err error) {
+	f.Text = c.Query("q")
+	if f.TypeID, err = intQuery(c, "type"); err != nil {
+		return
+	}
+	if f.SellerID, err = intQuery(c, "seller_id"); err != nil {
+		return
+	}
+	if f.MinWeight, err = floatQuery(c, "min_weight"); err != nil {
+		return
+	}
+	if f.MaxFreezingTemp, err = floatQuery(c, "max_freezing_temp"); err != nil {
+		return
+	}
+	pageOpt, err := intQuery(c, "page")
+	if err != nil {
+		return
+	}
+	pageSizeOpt, err := intQuery(c, "page_size")
+	if err != nil {
+		return
+	}
+	return f, pageOpt.Or(1), pageSizeOpt.Or(product.DefaultPageSize), nil
+}
+
 func mapProductErrToStatus(err error) int {
 	var invalidProductCode *product.ErrInvalidProductCode
+	var invalidProductType *product.ErrInvalidProductType
 	var notFound *product.ErrNotFound
+	var invalidRecord *product.ErrInvalidRecord
+	var noPrice *product.ErrNoPrice
+	var invalidSearch *product.ErrInvalidSearch
 
-	if errors.As(err, &invalidProductCode) {
+	if errors.As(err, &invalidProductCode) || errors.As(err, &invalidProductType) {
 		return http.StatusConflict
 	}
-	if errors.As(err, &notFound) {
+	if errors.As(err, &notFound) || errors.As(err, &noPrice) {
 		return http.StatusNotFound
 	}
+	if errors.As(err, &invalidRecord) {
+		return http.StatusUnprocessableEntity
+	}
+	if errors.As(err, &invalidSearch) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, sqlutil.ErrVersionMismatch) {
+		return http.StatusPreconditionFailed
+	}
 	return http.StatusInternalServerError
 }
 
@@ -319,11 +606,15 @@ func mapCreateRequestToDTO(req *CreateRequest) *product.CreateDTO {
 	}
 }
 
-func mapCreateRequestRecord(req *CreateRequestRecord) *product.CreateRecordDTO {
+func mapCreateRequestRecord(req *CreateRequestRecord) (*product.CreateRecordDTO, error) {
+	lastDate, _, err := parseDate(*req.LastDate)
+	if err != nil {
+		return nil, err
+	}
 	return &product.CreateRecordDTO{
-		LastDate:      *req.LastDate,
+		LastDate:      lastDate,
 		PurchasePrice: *req.PurchasePrice,
 		SalePrice:     *req.SalePrice,
 		ProductID:     *req.ProductID,
-	}
+	}, nil
 }
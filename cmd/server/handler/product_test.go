@@ -6,11 +6,15 @@ import (
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/cmd/server/handler"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/product"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -126,6 +130,32 @@ func TestProductCreate(t *testing.T) {
 		req, res := testutil.MakeRequest(http.MethodPost, "/products/", body)
 		server.ServeHTTP(res, req)
 
+		assert.Equal(t, http.StatusConflict, res.Code)
+	})
+	t.Run("Returns 409 when product type doesn't exist", func(t *testing.T) {
+		mockSvc := ProductServiceMock{}
+		h := handler.NewProduct(&mockSvc)
+		server := getProductServer(h)
+
+		body := handler.CreateRequest{
+			Desc:       testutil.ToPtr("Sweet potato"),
+			ExpR:       testutil.ToPtr(3),
+			FreezeR:    testutil.ToPtr(1),
+			Height:     testutil.ToPtr[float32](200),
+			Length:     testutil.ToPtr[float32](40),
+			NetW:       testutil.ToPtr[float32](10),
+			Code:       testutil.ToPtr("SWP-1"),
+			FreezeTemp: testutil.ToPtr[float32](20),
+			Width:      testutil.ToPtr[float32](100),
+			TypeID:     testutil.ToPtr(9),
+			SellerID:   testutil.ToPtr(1),
+		}
+
+		mockSvc.On("Create", mock.Anything, mock.Anything).Return(domain.Product{}, product.NewErrInvalidProductType(*body.TypeID))
+
+		req, res := testutil.MakeRequest(http.MethodPost, "/products/", body)
+		server.ServeHTTP(res, req)
+
 		assert.Equal(t, http.StatusConflict, res.Code)
 	})
 }
@@ -293,6 +323,93 @@ func TestProductUpdate(t *testing.T) {
 
 		assert.Equal(t, http.StatusConflict, res.Code)
 	})
+	t.Run("Returns 412 when the product changed", func(t *testing.T) {
+		mockSvc := ProductServiceMock{}
+		h := handler.NewProduct(&mockSvc)
+		server := getProductServer(h)
+
+		id := 42
+		body := handler.UpdateRequest{
+			Desc: testutil.ToPtr("New description"),
+		}
+
+		mockSvc.On("Update", mock.Anything, id, mock.Anything).Return(domain.Product{}, sqlutil.ErrVersionMismatch)
+
+		url := fmt.Sprintf("/products/%d", id)
+		req, res := testutil.MakeRequest(http.MethodPatch, url, body)
+		req.Header.Set("If-Match", `"1"`)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusPreconditionFailed, res.Code)
+	})
+}
+
+func TestProductBatch(t *testing.T) {
+	t.Run("Reads the products with the given ids", func(t *testing.T) {
+		mockSvc := ProductServiceMock{}
+		server := getProductServer(handler.NewProduct(&mockSvc))
+
+		expected := getTestProducts()[:1]
+		mockSvc.On("GetByIDs", mock.Anything, []int{1, 2}).Return(expected, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, "/products/?ids=1,2", "")
+		server.ServeHTTP(res, req)
+
+		var received testutil.SuccessResponse[[]domain.Product]
+		json.Unmarshal(res.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, received.Data)
+	})
+	t.Run("Returns 400 when the ids are not positive", func(t *testing.T) {
+		mockSvc := ProductServiceMock{}
+		server := getProductServer(handler.NewProduct(&mockSvc))
+
+		req, res := testutil.MakeRequest(http.MethodGet, "/products/?ids=0", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+	t.Run("Returns the status of each product", func(t *testing.T) {
+		mockSvc := ProductServiceMock{}
+		server := getProductServer(handler.NewProduct(&mockSvc))
+
+		create := product.CreateDTO{Desc: "Sweet potato", ExpR: 3, FreezeR: 1, Height: 200, Length: 40, NetW: 10, Code: "SWP-1", FreezeTemp: 20, Width: 100, TypeID: 1, SellerID: 1}
+		update := product.UpdateDTO{Desc: *optional.FromVal("Potato")}
+		mockSvc.On("CreateBatch", mock.Anything, []product.CreateDTO{create}).Return([]domain.Product{{ID: 1}}, []error{nil})
+		mockSvc.On("UpdateBatch", mock.Anything, []int{2, 3}, []product.UpdateDTO{update, update}).
+			Return([]domain.Product{{}, {}}, []error{product.NewErrNotFound(2), sqlutil.ErrVersionMismatch})
+
+		body := map[string]any{
+			"create": []handler.CreateRequest{{
+				Desc:       testutil.ToPtr("Sweet potato"),
+				ExpR:       testutil.ToPtr(3),
+				FreezeR:    testutil.ToPtr(1),
+				Height:     testutil.ToPtr[float32](200),
+				Length:     testutil.ToPtr[float32](40),
+				NetW:       testutil.ToPtr[float32](10),
+				Code:       testutil.ToPtr("SWP-1"),
+				FreezeTemp: testutil.ToPtr[float32](20),
+				Width:      testutil.ToPtr[float32](100),
+				TypeID:     testutil.ToPtr(1),
+				SellerID:   testutil.ToPtr(1),
+			}},
+			"update": []any{
+				map[string]any{"id": 2, "description": "Potato"},
+				map[string]any{"id": 3, "description": "Potato"},
+			},
+		}
+		req, res := testutil.MakeRequest(http.MethodPost, "/products/batch", body)
+		server.ServeHTTP(res, req)
+
+		var received testutil.SuccessResponse[web.BatchResponse]
+		json.Unmarshal(res.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusMultiStatus, res.Code)
+		assert.Equal(t, http.StatusCreated, received.Data.Create[0].Status)
+		assert.Equal(t, http.StatusNotFound, received.Data.Update[0].Status)
+		assert.Equal(t, http.StatusPreconditionFailed, received.Data.Update[1].Status)
+	})
 }
 
 func TestProductDelete(t *testing.T) {
@@ -342,7 +459,7 @@ func TestProductRecordCreate(t *testing.T) {
 		}
 		created := domain.Product_Records{
 			ID:             12,
-			LastUpdateDate: "2022-01-03",
+			LastUpdateDate: time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC),
 			PurchasePrice:  20.20,
 			SalePrice:      30.30,
 			ProductID:      5,
@@ -445,6 +562,206 @@ func TestProductRecordRead(t *testing.T) {
 	})
 }
 
+func TestProductRecordExport(t *testing.T) {
+	t.Run("Streams records as CSV when requested by query", func(t *testing.T) {
+		mockSvc := ProductServiceMock{}
+		h := handler.NewProduct(&mockSvc)
+		server := getProductServer(h)
+
+		mockSvc.On("StreamRecords", mock.Anything, 0).Return(getTestProductRecord(), nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, "/products/report-records?format=csv", "")
+		server.ServeHTTP(res, req)
+
+		expected := "id,last_update_date,purchase_price,sale_price,product_id\n" +
+			"1,2022-10-11T00:00:00Z,10.50,27.60,3\n" +
+			"2,2022-02-01T00:00:00Z,10.50,27.60,3\n"
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "text/csv", res.Header().Get("Content-Type"))
+		assert.Equal(t, expected, res.Body.String())
+		mockSvc.AssertNotCalled(t, "GetAllRecords", mock.Anything)
+	})
+	t.Run("Streams records as XLSX when requested by Accept header", func(t *testing.T) {
+		mockSvc := ProductServiceMock{}
+		h := handler.NewProduct(&mockSvc)
+		server := getProductServer(h)
+
+		mockSvc.On("StreamRecords", mock.Anything, 3).Return(getTestProductRecord(), nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, "/products/report-records/3", "")
+		req.Header.Set("Accept", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Contains(t, res.Header().Get("Content-Disposition"), "report-records.xlsx")
+	})
+	t.Run("Returns 404 if product does not exist", func(t *testing.T) {
+		mockSvc := ProductServiceMock{}
+		h := handler.NewProduct(&mockSvc)
+		server := getProductServer(h)
+
+		mockSvc.On("StreamRecords", mock.Anything, 3).Return([]domain.Product_Records{}, product.NewErrNotFound(3))
+
+		req, res := testutil.MakeRequest(http.MethodGet, "/products/report-records/3?format=csv", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+	t.Run("Returns 400 for unsupported format", func(t *testing.T) {
+		mockSvc := ProductServiceMock{}
+		h := handler.NewProduct(&mockSvc)
+		server := getProductServer(h)
+
+		req, res := testutil.MakeRequest(http.MethodGet, "/products/report-records?format=pdf", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+}
+
+func TestProductPricing(t *testing.T) {
+	t.Run("Returns 422 if record date is invalid", func(t *testing.T) {
+		mockSvc := ProductServiceMock{}
+		h := handler.NewProduct(&mockSvc)
+		server := getProductServer(h)
+
+		body := handler.CreateRequestRecord{
+			LastDate:      testutil.ToPtr("03/01/2022"),
+			PurchasePrice: testutil.ToPtr[float64](20.20),
+			SalePrice:     testutil.ToPtr[float64](30.30),
+			ProductID:     testutil.ToPtr(4),
+		}
+
+		req, res := testutil.MakeRequest(http.MethodPost, "/product-records/", body)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+		mockSvc.AssertNotCalled(t, "CreateRecord", mock.Anything, mock.Anything)
+	})
+	t.Run("Returns 422 if record is rejected by the service", func(t *testing.T) {
+		mockSvc := ProductServiceMock{}
+		h := handler.NewProduct(&mockSvc)
+		server := getProductServer(h)
+
+		body := handler.CreateRequestRecord{
+			LastDate:      testutil.ToPtr("2022-01-03T10:00:00Z"),
+			PurchasePrice: testutil.ToPtr[float64](20.20),
+			SalePrice:     testutil.ToPtr[float64](-1),
+			ProductID:     testutil.ToPtr(4),
+		}
+
+		mockSvc.On("CreateRecord", mock.Anything, mock.Anything).Return(domain.Product_Records{}, product.NewErrInvalidRecord(""))
+		req, res := testutil.MakeRequest(http.MethodPost, "/product-records/", body)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	})
+	t.Run("Returns price history for the whole of the to date", func(t *testing.T) {
+		mockSvc := ProductServiceMock{}
+		h := handler.NewProduct(&mockSvc)
+		server := getProductServer(h)
+
+		from := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC).Add(24*time.Hour - time.Nanosecond)
+		expected := domain.PriceHistory{ProductID: 3, Timeline: []domain.PriceChange{}}
+		mockSvc.On("PriceHistory", mock.Anything, 3, *optional.FromVal(from), *optional.FromVal(to)).Return(expected, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, "/products/3/price-history?from=2022-01-01&to=2022-02-01", "")
+		server.ServeHTTP(res, req)
+
+		var received testutil.SuccessResponse[domain.PriceHistory]
+		json.Unmarshal(res.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, received.Data)
+	})
+	t.Run("Returns 400 if date range is invalid", func(t *testing.T) {
+		mockSvc := ProductServiceMock{}
+		h := handler.NewProduct(&mockSvc)
+		server := getProductServer(h)
+
+		req, res := testutil.MakeRequest(http.MethodGet, "/products/3/price-history?from=2022-02-01&to=2022-01-01", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+	t.Run("Returns 404 if product had no price at the date", func(t *testing.T) {
+		mockSvc := ProductServiceMock{}
+		h := handler.NewProduct(&mockSvc)
+		server := getProductServer(h)
+
+		at := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		mockSvc.On("EffectivePrice", mock.Anything, 3, at).Return(domain.PricePoint{}, product.NewErrNoPrice(3))
+
+		req, res := testutil.MakeRequest(http.MethodGet, "/products/3/price?at=2020-01-01", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+}
+
+func TestProductSearch(t *testing.T) {
+	t.Run("Searches with the given filters and page", func(t *testing.T) {
+		mockSvc := ProductServiceMock{}
+		h := handler.NewProduct(&mockSvc)
+		server := getProductServer(h)
+
+		f := product.SearchFilter{
+			Text:            "potato",
+			TypeID:          *optional.FromVal(2),
+			SellerID:        *optional.New[int](),
+			MinWeight:       *optional.FromVal[float32](1.5),
+			MaxFreezingTemp: *optional.FromVal[float32](-4),
+		}
+		expected := domain.ProductPage{Products: getTestProducts(), Page: 2, PageSize: 5, Total: 7}
+		mockSvc.On("Search", mock.Anything, f, 2, 5).Return(expected, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, "/products/search?q=potato&type=2&min_weight=1.5&max_freezing_temp=-4&page=2&page_size=5", "")
+		server.ServeHTTP(res, req)
+
+		var received testutil.SuccessResponse[domain.ProductPage]
+		json.Unmarshal(res.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, received.Data)
+	})
+	t.Run("Defaults to the first page", func(t *testing.T) {
+		mockSvc := ProductServiceMock{}
+		h := handler.NewProduct(&mockSvc)
+		server := getProductServer(h)
+
+		mockSvc.On("Search", mock.Anything, mock.Anything, 1, product.DefaultPageSize).Return(domain.ProductPage{}, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, "/products/search", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+	})
+	t.Run("Returns 400 if a filter is invalid", func(t *testing.T) {
+		mockSvc := ProductServiceMock{}
+		h := handler.NewProduct(&mockSvc)
+		server := getProductServer(h)
+
+		req, res := testutil.MakeRequest(http.MethodGet, "/products/search?min_weight=heavy", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+		mockSvc.AssertNotCalled(t, "Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("Returns 400 if the page is rejected by the service", func(t *testing.T) {
+		mockSvc := ProductServiceMock{}
+		h := handler.NewProduct(&mockSvc)
+		server := getProductServer(h)
+
+		mockSvc.On("Search", mock.Anything, mock.Anything, 0, product.DefaultPageSize).Return(domain.ProductPage{}, product.NewErrInvalidSearch(""))
+
+		req, res := testutil.MakeRequest(http.MethodGet, "/products/search?page=0", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+}
+
 func getProductServer(h *handler.Product) *gin.Engine {
 	server := testutil.CreateServer()
 
@@ -452,12 +769,16 @@ func getProductServer(h *handler.Product) *gin.Engine {
 	productRG := server.Group(PRODUCTS_URL)
 	{
 		productRG.POST("/", middleware.Body[handler.CreateRequest](), h.Create())
+		productRG.POST("/batch", middleware.Body[web.BatchRequest[handler.CreateRequest, handler.UpdateRequest]](), h.Batch())
 		productRG.GET("/", h.GetAll())
+		productRG.GET("/search", h.Search())
 		productRG.GET("/:id", middleware.IntPathParam(), h.Get())
-		productRG.PATCH("/:id", middleware.IntPathParam(), middleware.Body[handler.UpdateRequest](), h.Update())
-		productRG.DELETE("/:id", middleware.IntPathParam(), h.Delete())
+		productRG.PATCH("/:id", middleware.IntPathParam(), middleware.IfMatch(), middleware.Body[handler.UpdateRequest](), h.Update())
+		productRG.DELETE("/:id", middleware.IntPathParam(), middleware.IfMatch(), h.Delete())
 		productRG.GET("/report-records", h.GetRecords())
 		productRG.GET("/report-records/:id", middleware.IntPathParam(), h.GetRecords())
+		productRG.GET("/:id/price-history", middleware.IntPathParam(), h.PriceHistory())
+		productRG.GET("/:id/price", middleware.IntPathParam(), h.EffectivePrice())
 	}
 
 	return server
@@ -498,14 +819,14 @@ func getTestProductRecord() []domain.Product_Records {
 	return []domain.Product_Records{
 		{
 			ID:             1,
-			LastUpdateDate: "2022-10-11",
+			LastUpdateDate: time.Date(2022, 10, 11, 0, 0, 0, 0, time.UTC),
 			PurchasePrice:  10.5,
 			SalePrice:      27.6,
 			ProductID:      3,
 		},
 		{
 			ID:             2,
-			LastUpdateDate: "2022-02-01",
+			LastUpdateDate: time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC),
 			PurchasePrice:  10.5,
 			SalePrice:      27.6,
 			ProductID:      3,
@@ -527,6 +848,21 @@ func (s *ProductServiceMock) GetAll(c context.Context) ([]domain.Product, error)
 	return args.Get(0).([]domain.Product), args.Error(1)
 }
 
+func (s *ProductServiceMock) GetByIDs(c context.Context, ids []int) ([]domain.Product, error) {
+	args := s.Called(c, ids)
+	return args.Get(0).([]domain.Product), args.Error(1)
+}
+
+func (s *ProductServiceMock) CreateBatch(c context.Context, items []product.CreateDTO) ([]domain.Product, []error) {
+	args := s.Called(c, items)
+	return args.Get(0).([]domain.Product), args.Get(1).([]error)
+}
+
+func (s *ProductServiceMock) UpdateBatch(c context.Context, ids []int, items []product.UpdateDTO) ([]domain.Product, []error) {
+	args := s.Called(c, ids, items)
+	return args.Get(0).([]domain.Product), args.Get(1).([]error)
+}
+
 func (s *ProductServiceMock) Get(c context.Context, id int) (domain.Product, error) {
 	args := s.Called(c, id)
 	return args.Get(0).(domain.Product), args.Error(1)
@@ -542,6 +878,11 @@ func (s *ProductServiceMock) Delete(c context.Context, id int) error {
 	return args.Error(0)
 }
 
+func (s *ProductServiceMock) Restore(c context.Context, id int) (domain.Product, error) {
+	args := s.Called(c, id)
+	return args.Get(0).(domain.Product), args.Error(1)
+}
+
 func (r *ProductServiceMock) CreateRecord(ctx context.Context, product product.CreateRecordDTO) (domain.Product_Records, error) {
 	args := r.Called(ctx, product)
 	return args.Get(0).(domain.Product_Records), args.Error(1)
@@ -556,3 +897,28 @@ func (r *ProductServiceMock) GetRecords(ctx context.Context, id int) ([]domain.P
 	args := r.Called(ctx, id)
 	return args.Get(0).([]domain.Product_Records), args.Error(1)
 }
+
+func (r *ProductServiceMock) StreamRecords(ctx context.Context, id int, fn func(domain.Product_Records) error) error {
+	args := r.Called(ctx, id)
+	for _, record := range args.Get(0).([]domain.Product_Records) {
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (r *ProductServiceMock) PriceHistory(ctx context.Context, id int, from, to optional.Opt[time.Time]) (domain.PriceHistory, error) {
+	args := r.Called(ctx, id, from, to)
+	return args.Get(0).(domain.PriceHistory), args.Error(1)
+}
+
+func (r *ProductServiceMock) Search(ctx context.Context, f product.SearchFilter, page, pageSize int) (domain.ProductPage, error) {
+	args := r.Called(ctx, f, page, pageSize)
+	return args.Get(0).(domain.ProductPage), args.Error(1)
+}
+
+func (r *ProductServiceMock) EffectivePrice(ctx context.Context, id int, at time.Time) (domain.PricePoint, error) {
+	args := r.Called(ctx, id, at)
+	return args.Get(0).(domain.PricePoint), args.Error(1)
+}
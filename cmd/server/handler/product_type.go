@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	producttype "github.com/extmatperez/meli_bootcamp_go_w2-4/internal/product_type"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type ProductType struct {
+	productTypeService producttype.Service
+}
+
+type ProductTypeRequest struct {
+	Description *string `binding:"required" json:"description"`
+}
+
+func NewProductType(s producttype.Service) *ProductType {
+	return &ProductType{
+		productTypeService: s,
+	}
+}
+
+// Create product type
+//
+//	@Summary	Create new product type
+//	@Tags		ProductTypes
+//	@Accept		json
+//	@Produce	json
+//	@Param		product_type	body		ProductTypeRequest	true	"Product type to be added"
+//	@Param		Idempotency-Key	header	string	false	"Makes retries of the request return its first response"
+//	@Success	201				{object}	web.response		"Returns created product type"
+//	@Failure	409				{object}	web.errorResponse	"`description` is not unique"
+//	@Failure	422				{object}	web.errorResponse	"Missing or empty description"
+//	@Failure	500				{object}	web.errorResponse	"Could not save product type"
+//	@Router		/api/v1/product-types [post]
+func (i *ProductType) Create() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := middleware.GetBody[ProductTypeRequest](c)
+
+		t, err := i.productTypeService.Create(c.Request.Context(), *req.Description)
+		if err != nil {
+			web.Error(c, checkErrorStatusProductType(err), err.Error())
+			return
+		}
+
+		web.Success(c, http.StatusCreated, t)
+	}
+}
+
+// GetAll product types
+//
+//	@Summary	List product types
+//	@Tags		ProductTypes
+//	@Produce	json
+//	@Success	200	{object}	web.response		"List of product types"
+//	@Failure	500	{object}	web.errorResponse	"Could not list product types"
+//	@Router		/api/v1/product-types [get]
+func (i *ProductType) GetAll() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		types, err := i.productTypeService.GetAll(c.Request.Context())
+		if err != nil {
+			web.Error(c, checkErrorStatusProductType(err), err.Error())
+			return
+		}
+
+		web.Success(c, http.StatusOK, types)
+	}
+}
+
+// Get product type
+//
+//	@Summary	Get product type by ID
+//	@Tags		ProductTypes
+//	@Produce	json
+//	@Param		id				path		int					true	"Product type ID"
+//	@Param		If-None-Match	header		string				false	"ETag of the cached product type"
+//	@Success	200				{object}	web.response		"Product type with the given ID"
+//	@Header		200				{string}	ETag				"Version of the product type"
+//	@Success	304				"Product type did not change"
+//	@Failure	400				{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	404				{object}	web.errorResponse	"Could not find product type"
+//	@Failure	500				{object}	web.errorResponse	"Could not get product type"
+//	@Router		/api/v1/product-types/{id} [get]
+func (i *ProductType) Get() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetInt("id")
+
+		t, err := i.productTypeService.Get(c.Request.Context(), id)
+		if err != nil {
+			web.Error(c, checkErrorStatusProductType(err), err.Error())
+			return
+		}
+
+		if web.NotModified(c, t.Version) {
+			return
+		}
+		web.Success(c, http.StatusOK, t)
+	}
+}
+
+// Update product type
+//
+//	@Summary	Update product type
+//	@Tags		ProductTypes
+//	@Accept		json
+//	@Produce	json
+//	@Param		id				path		int					true	"Product type ID"
+//	@Param		product_type	body		ProductTypeRequest	true	"New description"
+//	@Param		If-Match		header		string				false	"ETag the product type must have"
+//	@Success	200				{object}	web.response		"Returns updated product type"
+//	@Header		200				{string}	ETag				"New version of the product type"
+//	@Failure	400				{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	404				{object}	web.errorResponse	"Could not find product type"
+//	@Failure	409				{object}	web.errorResponse	"`description` is not unique"
+//	@Failure	412				{object}	web.errorResponse	"Product type changed since it was read"
+//	@Failure	422				{object}	web.errorResponse	"Missing or empty description"
+//	@Failure	500				{object}	web.errorResponse	"Could not save product type"
+//	@Router		/api/v1/product-types/{id} [patch]
+func (i *ProductType) Update() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetInt("id")
+		req := middleware.GetBody[ProductTypeRequest](c)
+
+		t, err := i.productTypeService.Update(c.Request.Context(), id, *req.Description)
+		if err != nil {
+			web.Error(c, checkErrorStatusProductType(err), err.Error())
+			return
+		}
+
+		web.SetETag(c, t.Version)
+		web.Success(c, http.StatusOK, t)
+	}
+}
+
+// Delete product type
+//
+//	@Summary	Delete product type
+//	@Tags		ProductTypes
+//	@Param		id			path	int		true	"Product type ID"
+//	@Param		If-Match	header	string	false	"ETag the product type must have"
+//	@Success	204			"Product type deleted"
+//	@Failure	400			{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	404			{object}	web.errorResponse	"Could not find product type"
+//	@Failure	409			{object}	web.errorResponse	"Product type is referenced by products or sections"
+//	@Failure	412			{object}	web.errorResponse	"Product type changed since it was read"
+//	@Failure	500			{object}	web.errorResponse	"Could not delete product type"
+//	@Router		/api/v1/product-types/{id} [delete]
+func (i *ProductType) Delete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetInt("id")
+
+		if err := i.productTypeService.Delete(c.Request.Context(), id); err != nil {
+			web.Error(c, checkErrorStatusProductType(err), err.Error())
+			return
+		}
+
+		web.Success(c, http.StatusNoContent, nil)
+	}
+}
+
+func checkErrorStatusProductType(err error) int {
+	switch {
+	case errors.Is(err, producttype.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, producttype.ErrAlreadyExists),
+		errors.Is(err, producttype.ErrInUse):
+		return http.StatusConflict
+	case errors.Is(err, producttype.ErrInvalidDescription):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, sqlutil.ErrVersionMismatch):
+		return http.StatusPreconditionFailed
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,233 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/cmd/server/handler"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	producttype "github.com/extmatperez/meli_bootcamp_go_w2-4/internal/product_type"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const PRODUCT_TYPE_URL = "/product-types"
+
+func TestProductTypeCreate(t *testing.T) {
+	t.Run("Returns 201 if product type is created", func(t *testing.T) {
+		svc := ProductTypeServiceMock{}
+		server := getProductTypeServer(handler.NewProductType(&svc))
+
+		expected := domain.ProductType{ID: 1, Description: "frozen"}
+		svc.On("Create", mock.Anything, "frozen").Return(expected, nil)
+
+		body := handler.ProductTypeRequest{Description: testutil.ToPtr("frozen")}
+		req, res := testutil.MakeRequest(http.MethodPost, PRODUCT_TYPE_URL, body)
+		server.ServeHTTP(res, req)
+
+		var response testutil.SuccessResponse[domain.ProductType]
+		json.Unmarshal(res.Body.Bytes(), &response)
+
+		assert.Equal(t, http.StatusCreated, res.Code)
+		assert.Equal(t, expected, response.Data)
+	})
+	t.Run("Returns 409 if description exists", func(t *testing.T) {
+		svc := ProductTypeServiceMock{}
+		server := getProductTypeServer(handler.NewProductType(&svc))
+
+		svc.On("Create", mock.Anything, "frozen").Return(domain.ProductType{}, producttype.ErrAlreadyExists)
+
+		body := handler.ProductTypeRequest{Description: testutil.ToPtr("frozen")}
+		req, res := testutil.MakeRequest(http.MethodPost, PRODUCT_TYPE_URL, body)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusConflict, res.Code)
+	})
+	t.Run("Returns 422 if description is empty", func(t *testing.T) {
+		svc := ProductTypeServiceMock{}
+		server := getProductTypeServer(handler.NewProductType(&svc))
+
+		svc.On("Create", mock.Anything, " ").Return(domain.ProductType{}, producttype.ErrInvalidDescription)
+
+		body := handler.ProductTypeRequest{Description: testutil.ToPtr(" ")}
+		req, res := testutil.MakeRequest(http.MethodPost, PRODUCT_TYPE_URL, body)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	})
+}
+
+func TestProductTypeRead(t *testing.T) {
+	t.Run("Returns all product types", func(t *testing.T) {
+		svc := ProductTypeServiceMock{}
+		server := getProductTypeServer(handler.NewProductType(&svc))
+
+		expected := []domain.ProductType{{ID: 1, Description: "frozen"}, {ID: 2, Description: "fresh"}}
+		svc.On("GetAll", mock.Anything).Return(expected, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, PRODUCT_TYPE_URL, nil)
+		server.ServeHTTP(res, req)
+
+		var response testutil.SuccessResponse[[]domain.ProductType]
+		json.Unmarshal(res.Body.Bytes(), &response)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, response.Data)
+	})
+	t.Run("Returns 404 if product type is not found", func(t *testing.T) {
+		svc := ProductTypeServiceMock{}
+		server := getProductTypeServer(handler.NewProductType(&svc))
+
+		svc.On("Get", mock.Anything, 9).Return(domain.ProductType{}, producttype.ErrNotFound)
+
+		req, res := testutil.MakeRequest(http.MethodGet, fmt.Sprintf("%s/%d", PRODUCT_TYPE_URL, 9), nil)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+}
+
+func TestProductTypeUpdate(t *testing.T) {
+	t.Run("Returns 200 with updated product type", func(t *testing.T) {
+		svc := ProductTypeServiceMock{}
+		server := getProductTypeServer(handler.NewProductType(&svc))
+
+		expected := domain.ProductType{ID: 1, Description: "chilled"}
+		svc.On("Update", mock.Anything, 1, "chilled").Return(expected, nil)
+
+		body := handler.ProductTypeRequest{Description: testutil.ToPtr("chilled")}
+		req, res := testutil.MakeRequest(http.MethodPatch, fmt.Sprintf("%s/%d", PRODUCT_TYPE_URL, 1), body)
+		server.ServeHTTP(res, req)
+
+		var response testutil.SuccessResponse[domain.ProductType]
+		json.Unmarshal(res.Body.Bytes(), &response)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, response.Data)
+	})
+}
+
+func TestProductTypeDelete(t *testing.T) {
+	t.Run("Returns 204 if product type is deleted", func(t *testing.T) {
+		svc := ProductTypeServiceMock{}
+		server := getProductTypeServer(handler.NewProductType(&svc))
+
+		svc.On("Delete", mock.Anything, 1).Return(nil)
+
+		req, res := testutil.MakeRequest(http.MethodDelete, fmt.Sprintf("%s/%d", PRODUCT_TYPE_URL, 1), nil)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNoContent, res.Code)
+	})
+	t.Run("Returns 409 if product type is in use", func(t *testing.T) {
+		svc := ProductTypeServiceMock{}
+		server := getProductTypeServer(handler.NewProductType(&svc))
+
+		svc.On("Delete", mock.Anything, 1).Return(producttype.ErrInUse)
+
+		req, res := testutil.MakeRequest(http.MethodDelete, fmt.Sprintf("%s/%d", PRODUCT_TYPE_URL, 1), nil)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusConflict, res.Code)
+	})
+}
+
+func TestProductTypeVersions(t *testing.T) {
+	expectsVersion := func(version int) interface{} {
+		return mock.MatchedBy(func(ctx context.Context) bool {
+			versions, ok := sqlutil.IfMatch(ctx)
+			return ok && len(versions) == 1 && versions[0] == version
+		})
+	}
+
+	t.Run("Get returns 304 if the client has the version", func(t *testing.T) {
+		svc := ProductTypeServiceMock{}
+		server := getProductTypeServer(handler.NewProductType(&svc))
+
+		svc.On("Get", mock.Anything, 1).Return(domain.ProductType{ID: 1, Version: 3}, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, fmt.Sprintf("%s/%d", PRODUCT_TYPE_URL, 1), nil)
+		req.Header.Set("If-None-Match", `"3"`)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNotModified, res.Code)
+		assert.Equal(t, `"3"`, res.Header().Get("ETag"))
+	})
+	t.Run("Update sends the new ETag", func(t *testing.T) {
+		svc := ProductTypeServiceMock{}
+		server := getProductTypeServer(handler.NewProductType(&svc))
+
+		svc.On("Update", expectsVersion(3), 1, "chilled").Return(domain.ProductType{ID: 1, Description: "chilled", Version: 4}, nil)
+
+		body := handler.ProductTypeRequest{Description: testutil.ToPtr("chilled")}
+		req, res := testutil.MakeRequest(http.MethodPatch, fmt.Sprintf("%s/%d", PRODUCT_TYPE_URL, 1), body)
+		req.Header.Set("If-Match", `"3"`)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, `"4"`, res.Header().Get("ETag"))
+	})
+	t.Run("Delete returns 412 if the product type is in another version", func(t *testing.T) {
+		svc := ProductTypeServiceMock{}
+		server := getProductTypeServer(handler.NewProductType(&svc))
+
+		svc.On("Delete", expectsVersion(2), 1).Return(sqlutil.ErrVersionMismatch)
+
+		req, res := testutil.MakeRequest(http.MethodDelete, fmt.Sprintf("%s/%d", PRODUCT_TYPE_URL, 1), nil)
+		req.Header.Set("If-Match", `"2"`)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusPreconditionFailed, res.Code)
+	})
+}
+
+func getProductTypeServer(h *handler.ProductType) *gin.Engine {
+	server := testutil.CreateServer()
+
+	rg := server.Group(PRODUCT_TYPE_URL)
+	{
+		rg.POST("", middleware.Body[handler.ProductTypeRequest](), h.Create())
+		rg.GET("", h.GetAll())
+		rg.GET("/:id", middleware.IntPathParam(), h.Get())
+		rg.PATCH("/:id", middleware.IntPathParam(), middleware.IfMatch(), middleware.Body[handler.ProductTypeRequest](), h.Update())
+		rg.DELETE("/:id", middleware.IntPathParam(), middleware.IfMatch(), h.Delete())
+	}
+
+	return server
+}
+
+type ProductTypeServiceMock struct {
+	mock.Mock
+}
+
+func (m *ProductTypeServiceMock) Create(c context.Context, description string) (domain.ProductType, error) {
+	args := m.Called(c, description)
+	return args.Get(0).(domain.ProductType), args.Error(1)
+}
+
+func (m *ProductTypeServiceMock) GetAll(c context.Context) ([]domain.ProductType, error) {
+	args := m.Called(c)
+	return args.Get(0).([]domain.ProductType), args.Error(1)
+}
+
+func (m *ProductTypeServiceMock) Get(c context.Context, id int) (domain.ProductType, error) {
+	args := m.Called(c, id)
+	return args.Get(0).(domain.ProductType), args.Error(1)
+}
+
+func (m *ProductTypeServiceMock) Update(c context.Context, id int, description string) (domain.ProductType, error) {
+	args := m.Called(c, id, description)
+	return args.Get(0).(domain.ProductType), args.Error(1)
+}
+
+func (m *ProductTypeServiceMock) Delete(c context.Context, id int) error {
+	args := m.Called(c, id)
+	return args.Error(0)
+}
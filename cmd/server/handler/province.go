@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/localities"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type Province struct {
+	locService localities.Service
+}
+
+type ProvinceRequest struct {
+	Name *string `binding:"required" json:"province_name"`
+}
+
+func NewProvince(svc localities.Service) *Province {
+	return &Province{
+		locService: svc,
+	}
+}
+
+// Get godoc
+//
+//	@Summary	Get province by ID
+//	@Tags		Provinces
+//	@Produce	json
+//	@Param		id				path		int					true	"Province ID"
+//	@Param		If-None-Match	header		string				false	"ETag of the cached province"
+//	@Success	200				{object}	web.response		"Province with the given ID"
+//	@Header		200				{string}	ETag				"Version of the province"
+//	@Success	304				"Province did not change"
+//	@Failure	400				{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	404				{object}	web.errorResponse	"Could not find province"
+//	@Failure	500				{object}	web.errorResponse	"Could not get province"
+//	@Router		/api/v1/provinces/{id} [get]
+func (h *Province) Get() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		province, err := h.locService.GetProvince(c.Request.Context(), c.GetInt("id"))
+		if err != nil {
+			web.Error(c, mapLocalityErrToStatus(err), err.Error())
+			return
+		}
+		if web.NotModified(c, province.Version) {
+			return
+		}
+		web.Success(c, http.StatusOK, province)
+	}
+}
+
+// Update godoc
+//
+//	@Summary	Rename province
+//	@Tags		Provinces
+//	@Accept		json
+//	@Produce	json
+//	@Param		id			path		int					true	"Province ID"
+//	@Param		province	body		ProvinceRequest		true	"New name"
+//	@Param		If-Match	header		string				false	"ETag the province must have"
+//	@Success	200			{object}	web.response		"Returns updated province"
+//	@Header		200			{string}	ETag				"New version of the province"
+//	@Failure	400			{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	404			{object}	web.errorResponse	"Could not find province"
+//	@Failure	409			{object}	web.errorResponse	"Province is not unique in the country"
+//	@Failure	412			{object}	web.errorResponse	"Province changed since it was read"
+//	@Failure	422			{object}	web.errorResponse	"Missing fields, invalid field types or empty name"
+//	@Failure	500			{object}	web.errorResponse	"Could not save province"
+//	@Router		/api/v1/provinces/{id} [patch]
+func (h *Province) Update() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := middleware.GetBody[ProvinceRequest](c)
+		province, err := h.locService.UpdateProvince(c.Request.Context(), c.GetInt("id"), *optional.FromPtr(req.Name))
+		if err != nil {
+			web.Error(c, mapLocalityErrToStatus(err), err.Error())
+			return
+		}
+		web.SetETag(c, province.Version)
+		web.Success(c, http.StatusOK, province)
+	}
+}
+
+// Delete godoc
+//
+//	@Summary	Delete province
+//	@Tags		Provinces
+//	@Param		id			path	int		true	"Province ID"
+//	@Param		If-Match	header	string	false	"ETag the province must have"
+//	@Success	204			"Province deleted"
+//	@Failure	400			{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	404			{object}	web.errorResponse	"Could not find province"
+//	@Failure	409			{object}	web.errorResponse	"Province has localities"
+//	@Failure	412			{object}	web.errorResponse	"Province changed since it was read"
+//	@Failure	500			{object}	web.errorResponse	"Could not delete province"
+//	@Router		/api/v1/provinces/{id} [delete]
+func (h *Province) Delete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := h.locService.DeleteProvince(c.Request.Context(), c.GetInt("id")); err != nil {
+			web.Error(c, mapLocalityErrToStatus(err), err.Error())
+			return
+		}
+		web.Success(c, http.StatusNoContent, nil)
+	}
+}
+
+// GetLocalities godoc
+//
+//	@Summary	List localities of a province
+//	@Tags		Provinces
+//	@Produce	json
+//	@Param		id	path		int					true	"Province ID"
+//	@Success	200	{object}	web.response		"Localities of the province"
+//	@Failure	400	{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	404	{object}	web.errorResponse	"Could not find province"
+//	@Failure	500	{object}	web.errorResponse	"Could not list localities"
+//	@Router		/api/v1/provinces/{id}/localities [get]
+func (h *Province) GetLocalities() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locs, err := h.locService.GetByProvince(c.Request.Context(), c.GetInt("id"))
+		if err != nil {
+			web.Error(c, mapLocalityErrToStatus(err), err.Error())
+			return
+		}
+		web.Success(c, http.StatusOK, locs)
+	}
+}
+
+// CreateLocality godoc
+//
+//	@Summary	Create locality in a province
+//	@Tags		Provinces
+//	@Accept		json
+//	@Produce	json
+//	@Param		id			path		int					true	"Province ID"
+//	@Param		locality	body		LocalityRequest		true	"Locality to be added"
+//	@Param		Idempotency-Key	header	string	false	"Makes retries of the request return its first response"
+//	@Success	201			{object}	web.response		"Returns created locality"
+//	@Failure	400			{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	404			{object}	web.errorResponse	"Could not find province"
+//	@Failure	409			{object}	web.errorResponse	"Locality is not unique in the province"
+//	@Failure	422			{object}	web.errorResponse	"Missing fields, invalid field types or empty name"
+//	@Failure	500			{object}	web.errorResponse	"Could not save locality"
+//	@Router		/api/v1/provinces/{id}/localities [post]
+func (h *Province) CreateLocality() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := middleware.GetBody[LocalityRequest](c)
+		loc, err := h.locService.CreateInProvince(c.Request.Context(), c.GetInt("id"), *req.Name)
+		if err != nil {
+			web.Error(c, mapLocalityErrToStatus(err), err.Error())
+			return
+		}
+		web.Success(c, http.StatusCreated, loc)
+	}
+}
@@ -0,0 +1,113 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/cmd/server/handler"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/localities"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const PROVINCE_URL = "/provinces"
+
+func TestProvince(t *testing.T) {
+	t.Run("Returns 200 with the province", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		server := getProvinceServer(handler.NewProvince(&svc))
+
+		expected := domain.Province{ID: 1, Name: "SP", CountryID: 1}
+		svc.On("GetProvince", mock.Anything, 1).Return(expected, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, PROVINCE_URL+"/1", "")
+		server.ServeHTTP(res, req)
+
+		var response testutil.SuccessResponse[domain.Province]
+		json.Unmarshal(res.Body.Bytes(), &response)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, response.Data)
+	})
+	t.Run("Sends the new ETag on rename", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		server := getProvinceServer(handler.NewProvince(&svc))
+
+		svc.On("UpdateProvince", mock.Anything, 1, mock.Anything).Return(domain.Province{ID: 1, Name: "São Paulo", CountryID: 1, Version: 2}, nil)
+
+		body := handler.ProvinceRequest{Name: testutil.ToPtr("São Paulo")}
+		req, res := testutil.MakeRequest(http.MethodPatch, PROVINCE_URL+"/1", body)
+		req.Header.Set("If-Match", `"1"`)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, `"2"`, res.Header().Get("ETag"))
+	})
+	t.Run("Returns 409 when deleting a province with localities", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		server := getProvinceServer(handler.NewProvince(&svc))
+
+		svc.On("DeleteProvince", mock.Anything, 1).Return(localities.NewErrInUse("province", 1, "localities"))
+
+		req, res := testutil.MakeRequest(http.MethodDelete, PROVINCE_URL+"/1", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusConflict, res.Code)
+	})
+	t.Run("Returns 200 with the localities of the province", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		server := getProvinceServer(handler.NewProvince(&svc))
+
+		expected := []domain.Locality{{ID: 1, Name: "Melicidade", ProvinceID: 1, Province: "SP", Country: "BR"}}
+		svc.On("GetByProvince", mock.Anything, 1).Return(expected, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, PROVINCE_URL+"/1/localities", "")
+		server.ServeHTTP(res, req)
+
+		var response testutil.SuccessResponse[[]domain.Locality]
+		json.Unmarshal(res.Body.Bytes(), &response)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, response.Data)
+	})
+	t.Run("Returns 422 if the locality has no name", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		server := getProvinceServer(handler.NewProvince(&svc))
+
+		req, res := testutil.MakeRequest(http.MethodPost, PROVINCE_URL+"/1/localities", handler.LocalityRequest{})
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+		svc.AssertNumberOfCalls(t, "CreateInProvince", 0)
+	})
+	t.Run("Returns 404 if the province does not exist", func(t *testing.T) {
+		svc := LocalityServiceMock{}
+		server := getProvinceServer(handler.NewProvince(&svc))
+
+		svc.On("CreateInProvince", mock.Anything, 9, "Melicidade").Return(domain.Locality{}, localities.NewErrProvinceNotFound(9))
+
+		body := handler.LocalityRequest{Name: testutil.ToPtr("Melicidade")}
+		req, res := testutil.MakeRequest(http.MethodPost, PROVINCE_URL+"/9/localities", body)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+}
+
+func getProvinceServer(h *handler.Province) *gin.Engine {
+	s := testutil.CreateServer()
+	rg := s.Group(PROVINCE_URL)
+	{
+		rg.GET("/:id", middleware.IntPathParam(), h.Get())
+		rg.PATCH("/:id", middleware.IntPathParam(), middleware.IfMatch(), middleware.Body[handler.ProvinceRequest](), h.Update())
+		rg.DELETE("/:id", middleware.IntPathParam(), middleware.IfMatch(), h.Delete())
+		rg.GET("/:id/localities", middleware.IntPathParam(), h.GetLocalities())
+		rg.POST("/:id/localities", middleware.IntPathParam(), middleware.Body[handler.LocalityRequest](), h.CreateLocality())
+	}
+	return s
+}
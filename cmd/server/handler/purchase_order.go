@@ -3,9 +3,9 @@ package handler
 import (
 	"errors"
 	"net/http"
-	"time"
 
 	purchaseOrder "github.com/extmatperez/meli_bootcamp_go_w2-4/internal/purchase_order"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
 	"github.com/gin-gonic/gin"
@@ -15,13 +15,29 @@ type PurchaseOrder struct {
 	purchaseOrderService purchaseOrder.Service
 }
 
+// PurchaseOrderRequest takes either a product_id, whose price in effect
+// at order_date is used, or an explicit product_record_id.
+// Quantity defaults to 1.
 type PurchaseOrderRequest struct {
 	OrderNumber     *string `binding:"required" json:"order_number"`
 	OrderDate       *string `binding:"required" json:"order_date"`
 	TrackingCode    *string `binding:"required" json:"tracking_code"`
 	BuyerID         *int    `binding:"required" json:"buyer_id"`
-	ProductRecordID *int    `binding:"required" json:"product_record_id"`
+	ProductID       *int    `json:"product_id"`
+	ProductRecordID *int    `json:"product_record_id"`
+	Quantity        *int    `json:"quantity"`
 	OrderStatusID   *int    `binding:"required" json:"order_status_id"`
+	CarrierID       *int    `json:"carrier_id"`
+	WarehouseID     *int    `json:"warehouse_id"`
+}
+
+type DispatchRequest struct {
+	CarrierID   *int `binding:"required" json:"carrier_id"`
+	WarehouseID *int `binding:"required" json:"warehouse_id"`
+}
+
+type ShipmentEventRequest struct {
+	Status *string `binding:"required" json:"status"`
 }
 
 func NewPurchaseOrder(s purchaseOrder.Service) *PurchaseOrder {
@@ -37,9 +53,10 @@ func NewPurchaseOrder(s purchaseOrder.Service) *PurchaseOrder {
 //	@Accept		json
 //	@Produce	json
 //	@Param		purchaseOrder	body		PurchaseOrderRequest		true	"purchase order to be added"
+//	@Param		Idempotency-Key	header	string	false	"Makes retries of the request return its first response"
 //	@Success	201		{object}	web.response		"Returns created purchase order"
-//	@Failure	409		{object}	web.errorResponse	"`id` is not unique or `locality_id` not found"
-//	@Failure	422		{object}	web.errorResponse	"Missing fields or invalid field types"
+//	@Failure	409		{object}	web.errorResponse	"`order_number` or `tracking_code` is not unique, a foreign key was not found or the product had no price at `order_date`"
+//	@Failure	422		{object}	web.errorResponse	"Missing fields, invalid field types or invalid quantity"
 //	@Failure	500		{object}	web.errorResponse	"Could not save purchase order"
 //	@Router		/api/v1/purchase-orders [post]
 func (i *PurchaseOrder) Create() gin.HandlerFunc {
@@ -62,26 +79,166 @@ func (i *PurchaseOrder) Create() gin.HandlerFunc {
 	}
 }
 
+// Dispatch purchase order
+//
+//	@Summary	Dispatch purchase order
+//	@Tags		Purchase order
+//	@Accept		json
+//	@Produce	json
+//	@Param		id			path		int					true	"Purchase order ID"
+//	@Param		dispatch	body		DispatchRequest		true	"Carrier and source warehouse"
+//	@Success	200			{object}	web.response		"Returns the shipment, picked"
+//	@Failure	400			{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	404			{object}	web.errorResponse	"Could not find purchase order"
+//	@Failure	409			{object}	web.errorResponse	"Already dispatched, or `carrier_id` or `warehouse_id` not found"
+//	@Failure	422			{object}	web.errorResponse	"Missing fields or invalid field types"
+//	@Failure	500			{object}	web.errorResponse	"Could not dispatch purchase order"
+//	@Router		/api/v1/purchase-orders/{id}/dispatch [post]
+func (i *PurchaseOrder) Dispatch() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetInt("id")
+		req := middleware.GetBody[DispatchRequest](c)
+
+		shipment, err := i.purchaseOrderService.Dispatch(c.Request.Context(), id, *req.CarrierID, *req.WarehouseID)
+		if err != nil {
+			web.Error(c, checkErrorStatusPurchaseOrder(err), err.Error())
+			return
+		}
+
+		web.Success(c, http.StatusOK, shipment)
+	}
+}
+
+// Record shipment event
+//
+//	@Summary		Record shipment event
+//	@Description	Events are recorded in order: picked (on dispatch), in_transit, delivered.
+//	@Tags			Purchase order
+//	@Accept			json
+//	@Produce		json
+//	@Param			tracking_code	path		string					true	"Tracking code"
+//	@Param			event			body		ShipmentEventRequest	true	"Status of the shipment"
+//	@Param			Idempotency-Key	header	string	false	"Makes retries of the request return its first response"
+//	@Success		201				{object}	web.response			"Returns the shipment"
+//	@Failure		404				{object}	web.errorResponse		"Could not find purchase order"
+//	@Failure		409				{object}	web.errorResponse		"Not dispatched, or event out of order"
+//	@Failure		422				{object}	web.errorResponse		"Missing or invalid status"
+//	@Failure		500				{object}	web.errorResponse		"Could not record event"
+//	@Router			/api/v1/purchase-orders/track/{tracking_code}/events [post]
+func (i *PurchaseOrder) RecordShipmentEvent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := middleware.GetBody[ShipmentEventRequest](c)
+
+		shipment, err := i.purchaseOrderService.RecordShipmentEvent(c.Request.Context(), c.Param("tracking_code"), *req.Status)
+		if err != nil {
+			web.Error(c, checkErrorStatusPurchaseOrder(err), err.Error())
+			return
+		}
+
+		web.Success(c, http.StatusCreated, shipment)
+	}
+}
+
+// Track purchase order
+//
+//	@Summary	Track purchase order
+//	@Tags		Purchase order
+//	@Produce	json
+//	@Param		tracking_code	path		string				true	"Tracking code"
+//	@Success	200				{object}	web.response		"Shipment with its event timeline"
+//	@Failure	404				{object}	web.errorResponse	"Could not find purchase order"
+//	@Failure	500				{object}	web.errorResponse	"Could not track purchase order"
+//	@Router		/api/v1/purchase-orders/track/{tracking_code} [get]
+func (i *PurchaseOrder) Track() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shipment, err := i.purchaseOrderService.Track(c.Request.Context(), c.Param("tracking_code"))
+		if err != nil {
+			web.Error(c, checkErrorStatusPurchaseOrder(err), err.Error())
+			return
+		}
+
+		web.Success(c, http.StatusOK, shipment)
+	}
+}
+
+// Recommend carriers
+//
+//	@Summary		Recommend carriers for purchase order
+//	@Description	Ranks the carriers serving the locality, then the province, then the country of the source warehouse.
+//	@Tags			Purchase order
+//	@Produce		json
+//	@Param			id				path		int					true	"Purchase order ID"
+//	@Param			warehouse_id	query		int					false	"Source warehouse, required if the order was not dispatched"
+//	@Success		200				{object}	web.response		"Ranked carrier candidates with the reason"
+//	@Failure		400				{object}	web.errorResponse	"Invalid ID or query parameter"
+//	@Failure		404				{object}	web.errorResponse	"Could not find purchase order"
+//	@Failure		409				{object}	web.errorResponse	"`warehouse_id` not found"
+//	@Failure		422				{object}	web.errorResponse	"Missing `warehouse_id`"
+//	@Failure		500				{object}	web.errorResponse	"Could not recommend carriers"
+//	@Router			/api/v1/purchase-orders/{id}/carrier-recommendations [get]
+func (i *PurchaseOrder) RecommendCarriers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetInt("id")
+		warehouseID, err := intQuery(c, "warehouse_id")
+		if err != nil {
+			web.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		recommendation, err := i.purchaseOrderService.RecommendCarriers(c.Request.Context(), id, warehouseID)
+		if err != nil {
+			web.Error(c, checkErrorStatusPurchaseOrder(err), err.Error())
+			return
+		}
+
+		web.Success(c, http.StatusOK, recommendation)
+	}
+}
+
 func checkErrorStatusPurchaseOrder(err error) int {
+	if errors.Is(err, purchaseOrder.ErrNotFound) {
+		return http.StatusNotFound
+	}
 	if errors.Is(err, purchaseOrder.ErrAlreadyExists) ||
-		errors.Is(err, purchaseOrder.ErrFKNotFound) {
+		errors.Is(err, purchaseOrder.ErrFKNotFound) ||
+		errors.Is(err, purchaseOrder.ErrProductRecordIDNotFound) ||
+		errors.Is(err, purchaseOrder.ErrNoPriceAtDate) ||
+		errors.Is(err, purchaseOrder.ErrTrackingCodeExists) ||
+		errors.Is(err, purchaseOrder.ErrCarrierNotFound) ||
+		errors.Is(err, purchaseOrder.ErrWarehouseNotFound) ||
+		errors.Is(err, purchaseOrder.ErrAlreadyDispatched) ||
+		errors.Is(err, purchaseOrder.ErrNotDispatched) ||
+		errors.Is(err, purchaseOrder.ErrInvalidTransition) {
 		return http.StatusConflict
 	}
+	if errors.Is(err, purchaseOrder.ErrInvalidQuantity) ||
+		errors.Is(err, purchaseOrder.ErrInvalidShipmentStatus) ||
+		errors.Is(err, purchaseOrder.ErrNoWarehouse) {
+		return http.StatusUnprocessableEntity
+	}
 	return http.StatusInternalServerError
 }
 
 func mapPurchaseOrderRequestToDTO(req *PurchaseOrderRequest) (*purchaseOrder.PurchaseOrderDTO, error) {
-	orderDate, err := time.Parse("2006-01-02", *req.OrderDate)
+	orderDate, dateOnly, err := parseDate(*req.OrderDate)
 	if err != nil {
 		return &purchaseOrder.PurchaseOrderDTO{}, err
 	}
+	if (req.ProductID == nil) == (req.ProductRecordID == nil) {
+		return &purchaseOrder.PurchaseOrderDTO{}, errors.New("exactly one of product_id or product_record_id is required")
+	}
 
 	return &purchaseOrder.PurchaseOrderDTO{
 		OrderNumber:     *req.OrderNumber,
 		OrderDate:       orderDate,
+		DateOnly:        dateOnly,
 		TrackingCode:    *req.TrackingCode,
 		BuyerID:         *req.BuyerID,
-		ProductRecordID: *req.ProductRecordID,
+		ProductID:       optional.FromPtr(req.ProductID).Or(0),
+		ProductRecordID: optional.FromPtr(req.ProductRecordID).Or(0),
+		Quantity:        optional.FromPtr(req.Quantity).Or(1),
 		OrderStatusID:   *req.OrderStatusID,
-	}, err
+		CarrierID:       req.CarrierID,
+		WarehouseID:     req.WarehouseID,
+	}, nil
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/cmd/server/handler"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	purchaseorder "github.com/extmatperez/meli_bootcamp_go_w2-4/internal/purchase_order"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
 	"github.com/gin-gonic/gin"
@@ -134,11 +135,243 @@ func TestPurchaseOrderCreate(t *testing.T) {
 	})
 }
 
+func TestPurchaseOrderCreateByProduct(t *testing.T) {
+	t.Run("Resolves product_id with quantity", func(t *testing.T) {
+		svc := PurchaseOrderServiceMock{}
+		h := handler.NewPurchaseOrder(&svc)
+		server := getPurchaseOrderServer(h)
+
+		body := handler.PurchaseOrderRequest{
+			OrderNumber:   testutil.ToPtr("12345"),
+			OrderDate:     testutil.ToPtr("2022-12-03"),
+			TrackingCode:  testutil.ToPtr("12345"),
+			BuyerID:       testutil.ToPtr(1),
+			ProductID:     testutil.ToPtr(3),
+			Quantity:      testutil.ToPtr(4),
+			OrderStatusID: testutil.ToPtr(1),
+		}
+		svc.On("Create", mock.Anything, mock.MatchedBy(func(dto purchaseorder.PurchaseOrderDTO) bool {
+			return dto.ProductID == 3 && dto.ProductRecordID == 0 && dto.Quantity == 4
+		})).Return(domain.PurchaseOrder{ID: 1}, nil)
+
+		req, res := testutil.MakeRequest(http.MethodPost, PURCHASE_ORDER_URL, body)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusCreated, res.Code)
+	})
+	t.Run("Returns 422 if both product_id and product_record_id are given", func(t *testing.T) {
+		svc := PurchaseOrderServiceMock{}
+		h := handler.NewPurchaseOrder(&svc)
+		server := getPurchaseOrderServer(h)
+
+		body := handler.PurchaseOrderRequest{
+			OrderNumber:     testutil.ToPtr("12345"),
+			OrderDate:       testutil.ToPtr("2022-12-03"),
+			TrackingCode:    testutil.ToPtr("12345"),
+			BuyerID:         testutil.ToPtr(1),
+			ProductID:       testutil.ToPtr(3),
+			ProductRecordID: testutil.ToPtr(2),
+			OrderStatusID:   testutil.ToPtr(1),
+		}
+
+		req, res := testutil.MakeRequest(http.MethodPost, PURCHASE_ORDER_URL, body)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	})
+	t.Run("Returns 409 if product has no price at order date", func(t *testing.T) {
+		svc := PurchaseOrderServiceMock{}
+		h := handler.NewPurchaseOrder(&svc)
+		server := getPurchaseOrderServer(h)
+
+		body := handler.PurchaseOrderRequest{
+			OrderNumber:   testutil.ToPtr("12345"),
+			OrderDate:     testutil.ToPtr("2022-12-03"),
+			TrackingCode:  testutil.ToPtr("12345"),
+			BuyerID:       testutil.ToPtr(1),
+			ProductID:     testutil.ToPtr(3),
+			OrderStatusID: testutil.ToPtr(1),
+		}
+		svc.On("Create", mock.Anything, mock.Anything).Return(domain.PurchaseOrder{}, purchaseorder.ErrNoPriceAtDate)
+
+		req, res := testutil.MakeRequest(http.MethodPost, PURCHASE_ORDER_URL, body)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusConflict, res.Code)
+	})
+}
+
+func TestPurchaseOrderShipment(t *testing.T) {
+	t.Run("Dispatch returns the picked shipment", func(t *testing.T) {
+		svc := PurchaseOrderServiceMock{}
+		h := handler.NewPurchaseOrder(&svc)
+		server := getPurchaseOrderServer(h)
+
+		expected := domain.Shipment{
+			PurchaseOrderID: 1,
+			TrackingCode:    "TRACK001",
+			CarrierID:       testutil.ToPtr(2),
+			WarehouseID:     testutil.ToPtr(3),
+			Status:          domain.ShipmentPicked,
+			Events:          []domain.ShipmentEvent{{ID: 1, PurchaseOrderID: 1, Status: domain.ShipmentPicked}},
+		}
+		svc.On("Dispatch", mock.Anything, 1, 2, 3).Return(expected, nil)
+
+		body := handler.DispatchRequest{CarrierID: testutil.ToPtr(2), WarehouseID: testutil.ToPtr(3)}
+		req, res := testutil.MakeRequest(http.MethodPost, PURCHASE_ORDER_URL+"/1/dispatch", body)
+		server.ServeHTTP(res, req)
+
+		var received testutil.SuccessResponse[domain.Shipment]
+		json.Unmarshal(res.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, received.Data)
+	})
+	t.Run("Dispatch returns 422 without a carrier", func(t *testing.T) {
+		svc := PurchaseOrderServiceMock{}
+		h := handler.NewPurchaseOrder(&svc)
+		server := getPurchaseOrderServer(h)
+
+		body := handler.DispatchRequest{WarehouseID: testutil.ToPtr(3)}
+		req, res := testutil.MakeRequest(http.MethodPost, PURCHASE_ORDER_URL+"/1/dispatch", body)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+		svc.AssertNumberOfCalls(t, "Dispatch", 0)
+	})
+	t.Run("Dispatch returns 409 if already dispatched", func(t *testing.T) {
+		svc := PurchaseOrderServiceMock{}
+		h := handler.NewPurchaseOrder(&svc)
+		server := getPurchaseOrderServer(h)
+
+		svc.On("Dispatch", mock.Anything, 1, 2, 3).Return(domain.Shipment{}, purchaseorder.ErrAlreadyDispatched)
+
+		body := handler.DispatchRequest{CarrierID: testutil.ToPtr(2), WarehouseID: testutil.ToPtr(3)}
+		req, res := testutil.MakeRequest(http.MethodPost, PURCHASE_ORDER_URL+"/1/dispatch", body)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusConflict, res.Code)
+	})
+	t.Run("Records a shipment event by tracking code", func(t *testing.T) {
+		svc := PurchaseOrderServiceMock{}
+		h := handler.NewPurchaseOrder(&svc)
+		server := getPurchaseOrderServer(h)
+
+		svc.On("RecordShipmentEvent", mock.Anything, "TRACK001", domain.ShipmentInTransit).
+			Return(domain.Shipment{Status: domain.ShipmentInTransit}, nil)
+
+		body := handler.ShipmentEventRequest{Status: testutil.ToPtr(domain.ShipmentInTransit)}
+		req, res := testutil.MakeRequest(http.MethodPost, PURCHASE_ORDER_URL+"/track/TRACK001/events", body)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusCreated, res.Code)
+	})
+	t.Run("Returns 422 for an unknown shipment status", func(t *testing.T) {
+		svc := PurchaseOrderServiceMock{}
+		h := handler.NewPurchaseOrder(&svc)
+		server := getPurchaseOrderServer(h)
+
+		svc.On("RecordShipmentEvent", mock.Anything, "TRACK001", "lost").
+			Return(domain.Shipment{}, purchaseorder.ErrInvalidShipmentStatus)
+
+		body := handler.ShipmentEventRequest{Status: testutil.ToPtr("lost")}
+		req, res := testutil.MakeRequest(http.MethodPost, PURCHASE_ORDER_URL+"/track/TRACK001/events", body)
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	})
+	t.Run("Track returns the timeline", func(t *testing.T) {
+		svc := PurchaseOrderServiceMock{}
+		h := handler.NewPurchaseOrder(&svc)
+		server := getPurchaseOrderServer(h)
+
+		expected := domain.Shipment{PurchaseOrderID: 1, TrackingCode: "TRACK001", Status: domain.ShipmentPicked}
+		svc.On("Track", mock.Anything, "TRACK001").Return(expected, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, PURCHASE_ORDER_URL+"/track/TRACK001", "")
+		server.ServeHTTP(res, req)
+
+		var received testutil.SuccessResponse[domain.Shipment]
+		json.Unmarshal(res.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, received.Data)
+	})
+	t.Run("Track returns 404 for an unknown tracking code", func(t *testing.T) {
+		svc := PurchaseOrderServiceMock{}
+		h := handler.NewPurchaseOrder(&svc)
+		server := getPurchaseOrderServer(h)
+
+		svc.On("Track", mock.Anything, "NOPE").Return(domain.Shipment{}, purchaseorder.ErrNotFound)
+
+		req, res := testutil.MakeRequest(http.MethodGet, PURCHASE_ORDER_URL+"/track/NOPE", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+}
+
+func TestPurchaseOrderRecommendCarriers(t *testing.T) {
+	t.Run("Returns the ranked candidates", func(t *testing.T) {
+		svc := PurchaseOrderServiceMock{}
+		h := handler.NewPurchaseOrder(&svc)
+		server := getPurchaseOrderServer(h)
+
+		expected := domain.CarrierRecommendation{
+			PurchaseOrderID: 1,
+			WarehouseID:     2,
+			Candidates: []domain.CarrierCandidate{{
+				Carrier:  domain.Carrier{ID: 1, CID: "111111"},
+				Rank:     1,
+				Coverage: domain.CoverageLocality,
+				Reason:   "serves the locality of the warehouse",
+			}},
+		}
+		svc.On("RecommendCarriers", mock.Anything, 1, *optional.FromVal(2)).Return(expected, nil)
+
+		req, res := testutil.MakeRequest(http.MethodGet, PURCHASE_ORDER_URL+"/1/carrier-recommendations?warehouse_id=2", "")
+		server.ServeHTTP(res, req)
+
+		var received testutil.SuccessResponse[domain.CarrierRecommendation]
+		json.Unmarshal(res.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, received.Data)
+	})
+	t.Run("Returns 400 for an invalid warehouse_id", func(t *testing.T) {
+		svc := PurchaseOrderServiceMock{}
+		h := handler.NewPurchaseOrder(&svc)
+		server := getPurchaseOrderServer(h)
+
+		req, res := testutil.MakeRequest(http.MethodGet, PURCHASE_ORDER_URL+"/1/carrier-recommendations?warehouse_id=x", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+		svc.AssertNumberOfCalls(t, "RecommendCarriers", 0)
+	})
+	t.Run("Returns 422 if the order has no warehouse", func(t *testing.T) {
+		svc := PurchaseOrderServiceMock{}
+		h := handler.NewPurchaseOrder(&svc)
+		server := getPurchaseOrderServer(h)
+
+		svc.On("RecommendCarriers", mock.Anything, 1, mock.Anything).Return(domain.CarrierRecommendation{}, purchaseorder.ErrNoWarehouse)
+
+		req, res := testutil.MakeRequest(http.MethodGet, PURCHASE_ORDER_URL+"/1/carrier-recommendations", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	})
+}
+
 func getPurchaseOrderServer(h *handler.PurchaseOrder) *gin.Engine {
 	s := testutil.CreateServer()
 	rg := s.Group(PURCHASE_ORDER_URL)
 	{
 		rg.POST("", middleware.Body[handler.PurchaseOrderRequest](), h.Create())
+		rg.POST("/:id/dispatch", middleware.IntPathParam(), middleware.Body[handler.DispatchRequest](), h.Dispatch())
+		rg.GET("/:id/carrier-recommendations", middleware.IntPathParam(), h.RecommendCarriers())
+		rg.GET("/track/:tracking_code", h.Track())
+		rg.POST("/track/:tracking_code/events", middleware.Body[handler.ShipmentEventRequest](), h.RecordShipmentEvent())
 	}
 	return s
 }
@@ -151,3 +384,23 @@ func (r *PurchaseOrderServiceMock) Create(c context.Context, purchaseOrder purch
 	args := r.Called(c, purchaseOrder)
 	return args.Get(0).(domain.PurchaseOrder), args.Error(1)
 }
+
+func (r *PurchaseOrderServiceMock) Dispatch(c context.Context, id, carrierID, warehouseID int) (domain.Shipment, error) {
+	args := r.Called(c, id, carrierID, warehouseID)
+	return args.Get(0).(domain.Shipment), args.Error(1)
+}
+
+func (r *PurchaseOrderServiceMock) RecordShipmentEvent(c context.Context, trackingCode, status string) (domain.Shipment, error) {
+	args := r.Called(c, trackingCode, status)
+	return args.Get(0).(domain.Shipment), args.Error(1)
+}
+
+func (r *PurchaseOrderServiceMock) Track(c context.Context, trackingCode string) (domain.Shipment, error) {
+	args := r.Called(c, trackingCode)
+	return args.Get(0).(domain.Shipment), args.Error(1)
+}
+
+func (r *PurchaseOrderServiceMock) RecommendCarriers(c context.Context, id int, warehouseID optional.Opt[int]) (domain.CarrierRecommendation, error) {
+	args := r.Called(c, id, warehouseID)
+	return args.Get(0).(domain.CarrierRecommendation), args.Error(1)
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+const dateLayout = "2006-01-02"
+
+// parseDate accepts either a full RFC 3339 timestamp or a plain date.
+// It also reports whether the value was a plain date, so that callers
+// can treat it as a whole day.
+func parseDate(s string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, false, nil
+	}
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid date %q: expected YYYY-MM-DD or RFC 3339", s)
+	}
+	return t, true, nil
+}
+
+// dateQuery parses the given query parameter as a date, if present.
+func dateQuery(c *gin.Context, key string) (optional.Opt[time.Time], error) {
+	raw, ok := c.GetQuery(key)
+	if !ok {
+		return *optional.New[time.Time](), nil
+	}
+	t, _, err := parseDate(raw)
+	if err != nil {
+		return optional.Opt[time.Time]{}, fmt.Errorf("%s: %w", key, err)
+	}
+	return *optional.FromVal(t), nil
+}
+
+// dateRangeQuery parses the `from` and `to` query parameters.
+// A plain `to` date includes the whole day.
+func dateRangeQuery(c *gin.Context) (from, to optional.Opt[time.Time], err error) {
+	if from, err = dateQuery(c, "from"); err != nil {
+		return
+	}
+	raw, ok := c.GetQuery("to")
+	if !ok {
+		to = *optional.New[time.Time]()
+		return
+	}
+	t, dateOnly, err := parseDate(raw)
+	if err != nil {
+		err = fmt.Errorf("to: %w", err)
+		return
+	}
+	if dateOnly {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	to = *optional.FromVal(t)
+	if from.HasVal && to.Val.Before(from.Val) {
+		err = fmt.Errorf("from must not be after to")
+	}
+	return
+}
+
+// intQuery parses the given query parameter as an integer, if present.
+func intQuery(c *gin.Context, key string) (optional.Opt[int], error) {
+	raw, ok := c.GetQuery(key)
+	if !ok {
+		return *optional.New[int](), nil
+	}
+	val, err := strconv.Atoi(raw)
+	if err != nil {
+		return optional.Opt[int]{}, fmt.Errorf("%s: invalid integer %q", key, raw)
+	}
+	return *optional.FromVal(val), nil
+}
+
+// floatQuery parses the given query parameter as a number, if present.
+func floatQuery(c *gin.Context, key string) (optional.Opt[float32], error) {
+	raw, ok := c.GetQuery(key)
+	if !ok {
+		return *optional.New[float32](), nil
+	}
+	val, err := strconv.ParseFloat(raw, 32)
+	if err != nil {
+		return optional.Opt[float32]{}, fmt.Errorf("%s: invalid number %q", key, raw)
+	}
+	return *optional.FromVal(float32(val)), nil
+}
+
+// boolQuery parses the given query parameter as a boolean,
+// defaulting to false.
+func boolQuery(c *gin.Context, key string) (bool, error) {
+	raw, ok := c.GetQuery(key)
+	if !ok {
+		return false, nil
+	}
+	val, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("%s: invalid boolean %q", key, raw)
+	}
+	return val, nil
+}
+
+// idsQuery parses the given query parameter as a comma separated list
+// of ids, if present. Repeated ids are only kept once, and at most
+// web.MaxBatchSize are accepted.
+func idsQuery(c *gin.Context, key string) (optional.Opt[[]int], error) {
+	raw, ok := c.GetQuery(key)
+	if !ok {
+		return *optional.New[[]int](), nil
+	}
+	ids := []int{}
+	seen := map[int]bool{}
+	for _, s := range strings.Split(raw, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || id <= 0 {
+			return optional.Opt[[]int]{}, fmt.Errorf("%s: invalid id %q", key, s)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > web.MaxBatchSize {
+		return optional.Opt[[]int]{}, fmt.Errorf("%s: at most %d ids are allowed", key, web.MaxBatchSize)
+	}
+	return *optional.FromVal(ids), nil
+}
@@ -1,11 +1,15 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/section"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/export"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
 	"github.com/gin-gonic/gin"
 )
@@ -26,13 +30,28 @@ func NewSection(s section.Service) *Section {
 //	@Tags		Sections
 //	@Accept		json
 //	@Produce	json
-//	@Success	200	{object}	web.response		"Returns all sections"
-//	@Success	204	{object}	web.response		"No sections to retrieve"
-//	@Failure	500	{object}	web.errorResponse	"Could not fetch sections"
+//	@Param		ids				query		string				false	"Comma separated ids of the sections to retrieve"
+//	@Param		include_deleted	query		bool				false	"Include deleted sections (admin only)"
+//	@Param		X-Admin-Token	header		string				false	"Admin token"
+//	@Success	200				{object}	web.response		"Returns all sections"
+//	@Success	204				{object}	web.response		"No sections to retrieve"
+//	@Failure	400				{object}	web.errorResponse	"Invalid include_deleted or ids"
+//	@Failure	403				{object}	web.errorResponse	"Not an admin"
+//	@Failure	500				{object}	web.errorResponse	"Could not fetch sections"
 //	@Router		/api/v1/sections [get]
 func (s *Section) GetAll() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		sections, err := s.sectionService.GetAll(c.Request.Context())
+		ids, err := idsQuery(c, "ids")
+		if err != nil {
+			web.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		var sections []domain.Section
+		if ids.HasVal {
+			sections, err = s.sectionService.GetByIDs(c.Request.Context(), ids.Val)
+		} else {
+			sections, err = s.sectionService.GetAll(c.Request.Context())
+		}
 		if err != nil {
 			web.Error(c, http.StatusInternalServerError, err.Error())
 			return
@@ -51,10 +70,16 @@ func (s *Section) GetAll() gin.HandlerFunc {
 //	@Tags		Sections
 //	@Accept		json
 //	@Produce	json
-//	@Param		id	path		int					true	"Section ID"
-//	@Success	200	{object}	web.response		"Returns section"
-//	@Failure	400	{object}	web.errorResponse	"Invalid ID type"
-//	@Failure	404	{object}	web.errorResponse	"Could not find section"
+//	@Param		id				path		int					true	"Section ID"
+//	@Param		include_deleted	query		bool				false	"Include deleted sections (admin only)"
+//	@Param		X-Admin-Token	header		string				false	"Admin token"
+//	@Param		If-None-Match	header		string				false	"ETag of the cached section"
+//	@Success	200				{object}	web.response		"Returns section"
+//	@Header		200				{string}	ETag				"Version of the section"
+//	@Success	304				"Section did not change"
+//	@Failure	400				{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	403				{object}	web.errorResponse	"Not an admin"
+//	@Failure	404				{object}	web.errorResponse	"Could not find section"
 //	@Router		/api/v1/sections/{id} [get]
 func (s *Section) Get() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -65,6 +90,9 @@ func (s *Section) Get() gin.HandlerFunc {
 			web.Error(c, http.StatusNotFound, err.Error())
 			return
 		}
+		if web.NotModified(c, sec.Version) {
+			return
+		}
 		web.Success(c, http.StatusOK, sec)
 	}
 }
@@ -76,8 +104,9 @@ func (s *Section) Get() gin.HandlerFunc {
 //	@Accept		json
 //	@Produce	json
 //	@Param		product	body		section.CreateSection	true	"section to be added"
+//	@Param		Idempotency-Key	header	string	false	"Makes retries of the request return its first response"
 //	@Success	201		{object}	web.response			"Returns created section"
-//	@Failure	409		{object}	web.errorResponse		"`section_number` is not unique"
+//	@Failure	409		{object}	web.errorResponse		"`section_number` is not unique or `product_type_id` not found"
 //	@Failure	422		{object}	web.errorResponse		"Missing fields or invalid field types"
 //	@Failure	500		{object}	web.errorResponse		"Could not save section"
 //	@Router		/api/v1/sections [post]
@@ -87,7 +116,7 @@ func (s *Section) Create() gin.HandlerFunc {
 
 		sec, err := s.sectionService.Create(c, dto)
 		if err != nil {
-			if err == section.ErrInvalidSectionNumber {
+			if err == section.ErrInvalidSectionNumber || err == section.ErrProductTypeNotFound {
 				web.Error(c, http.StatusConflict, err.Error())
 			} else {
 				web.Error(c, http.StatusInternalServerError, err.Error())
@@ -105,14 +134,17 @@ func (s *Section) Create() gin.HandlerFunc {
 //	@Tags		Sections
 //	@Accept		json
 //	@Produce	json
-//	@Param		id		path		int						true	"Section ID"
-//	@Param		section	body		section.UpdateSection	true	"Fields to update"
-//	@Success	200		{object}	web.response			"Returns updated section"
-//	@Failure	400		{object}	web.errorResponse		"Invalid ID type"
-//	@Failure	404		{object}	web.errorResponse		"Could not find section"
-//	@Failure	409		{object}	web.errorResponse		"`section_number` is not unique"
-//	@Failure	422		{object}	web.errorResponse		"Invalid field types"
-//	@Failure	500		{object}	web.errorResponse		"Could not save section"
+//	@Param		id			path		int						true	"Section ID"
+//	@Param		section		body		section.UpdateSection	true	"Fields to update"
+//	@Param		If-Match	header		string					false	"ETag the section must have"
+//	@Success	200			{object}	web.response			"Returns updated section"
+//	@Header		200			{string}	ETag					"New version of the section"
+//	@Failure	400			{object}	web.errorResponse		"Invalid ID type"
+//	@Failure	404			{object}	web.errorResponse		"Could not find section"
+//	@Failure	409			{object}	web.errorResponse		"`section_number` is not unique"
+//	@Failure	412			{object}	web.errorResponse		"Section changed since it was read"
+//	@Failure	422			{object}	web.errorResponse		"Invalid field types"
+//	@Failure	500			{object}	web.errorResponse		"Could not save section"
 //	@Router		/api/v1/sections/{id} [patch]
 func (s *Section) Update() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -122,29 +154,68 @@ func (s *Section) Update() gin.HandlerFunc {
 		sec, err := s.sectionService.Update(c.Request.Context(), dto, id)
 
 		if err != nil {
-			if err == section.ErrInvalidSectionNumber {
-				web.Error(c, http.StatusConflict, err.Error())
-			} else if err == section.ErrNotFound {
-				web.Error(c, http.StatusNotFound, err.Error())
-			} else {
-				web.Error(c, http.StatusInternalServerError, err.Error())
-			}
+			web.Error(c, sectionErrStatus(err), err.Error())
 			return
 		}
 
+		web.SetETag(c, sec.Version)
 		web.Success(c, http.StatusOK, sec)
 	}
 }
 
+// Batch godoc
+//
+//	@Summary		Create and update sections in batch
+//	@Description	Creates the sections in `create` and updates those in `update`, which are sent with their `id`. Each section gets its own status, in the order they were sent.
+//	@Tags			Sections
+//	@Accept			json
+//	@Produce		json
+//	@Param			batch			body		web.batchRequest{create=[]section.CreateSection,update=[]section.UpdateSection}	true	"Sections to create and update"
+//	@Param			Idempotency-Key	header		string				false	"Makes retries of the request return its first response"
+//	@Success		207				{object}	web.response{data=web.BatchResponse}	"Status of each section"
+//	@Failure		422				{object}	web.errorResponse	"Invalid batch"
+//	@Router			/api/v1/sections/batch [post]
+func (s *Section) Batch() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, ok := getBatch[section.CreateSection, section.UpdateSection](c)
+		if !ok {
+			return
+		}
+		created, createErrs := s.sectionService.CreateBatch(c.Request.Context(), req.Create)
+		ids, dtos := req.Updates()
+		updated, updateErrs := s.sectionService.UpdateBatch(c.Request.Context(), ids, dtos)
+		web.Success(c, http.StatusMultiStatus, web.BatchResponse{
+			Create: web.BatchResults(created, createErrs, http.StatusCreated, sectionErrStatus),
+			Update: web.BatchResults(updated, updateErrs, http.StatusOK, sectionErrStatus),
+		})
+	}
+}
+
+// sectionErrStatus returns the status code of an error of the section
+// service.
+func sectionErrStatus(err error) int {
+	switch {
+	case errors.Is(err, section.ErrInvalidSectionNumber), errors.Is(err, section.ErrProductTypeNotFound):
+		return http.StatusConflict
+	case errors.Is(err, section.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, sqlutil.ErrVersionMismatch):
+		return http.StatusPreconditionFailed
+	}
+	return http.StatusInternalServerError
+}
+
 // Delete godoc
 //
 //	@Summary	Delete section by ID
 //	@Tags		Sections
 //	@Accept		json
 //	@Produce	json
-//	@Param		id	path		int					true	"Section ID"
-//	@Success	204	{object}	web.response		"Section deleted successfully"
-//	@Failure	404	{object}	web.errorResponse	"Could not find section"
+//	@Param		id			path		int					true	"Section ID"
+//	@Param		If-Match	header		string				false	"ETag the section must have"
+//	@Success	204			{object}	web.response		"Section deleted successfully"
+//	@Failure	404			{object}	web.errorResponse	"Could not find section"
+//	@Failure	412			{object}	web.errorResponse	"Section changed since it was read"
 //	@Failure	500	{object}	web.errorResponse	"Could not delete section"
 //	@Router		/api/v1/sections/{id} [delete]
 func (s *Section) Delete() gin.HandlerFunc {
@@ -153,6 +224,10 @@ func (s *Section) Delete() gin.HandlerFunc {
 
 		err := s.sectionService.Delete(c, id)
 
+		if errors.Is(err, sqlutil.ErrVersionMismatch) {
+			web.Error(c, http.StatusPreconditionFailed, err.Error())
+			return
+		}
 		if err != nil {
 			web.Error(c, http.StatusNotFound, "id %d not found", id)
 			return
@@ -163,20 +238,61 @@ func (s *Section) Delete() gin.HandlerFunc {
 	}
 }
 
+// Restore godoc
+//
+//	@Summary	Restore deleted section by ID
+//	@Tags		Sections
+//	@Accept		json
+//	@Produce	json
+//	@Param		id				path		int					true	"Section ID"
+//	@Param		X-Admin-Token	header		string				true	"Admin token"
+//	@Success	200				{object}	web.response		"Returns restored section"
+//	@Failure	400				{object}	web.errorResponse	"Invalid ID type"
+//	@Failure	403				{object}	web.errorResponse	"Restricted to admins"
+//	@Failure	404				{object}	web.errorResponse	"Could not find deleted section"
+//	@Failure	500				{object}	web.errorResponse	"Could not restore section"
+//	@Router		/api/v1/sections/{id}/restore [post]
+func (s *Section) Restore() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetInt("id")
+
+		sec, err := s.sectionService.Restore(c.Request.Context(), id)
+		if err != nil {
+			if errors.Is(err, section.ErrNotFound) {
+				web.Error(c, http.StatusNotFound, err.Error())
+				return
+			}
+			web.Error(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		web.SetETag(c, sec.Version)
+		web.Success(c, http.StatusOK, sec)
+	}
+}
+
 // GetReportProducts godoc
 //
 // @Summary	Get report of products for a section
 // @Tags		Sections
 // @Accept		json
 // @Produce	json
+// @Produce	text/csv
+// @Produce	application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 // @Param		id	path	int	true	"Section ID"
+// @Param		format	query	string	false	"Export format: json, csv or xlsx"
 // @Success	200	{object}	web.response	"Report of products"
+// @Failure	400	{object}	web.errorResponse	"Unsupported export format"
 // @Failure	404	{object}	web.errorResponse	"Could not find section"
 // @Failure	500	{object}	web.errorResponse	"Could not report"
+// @Failure	429	{object}	web.errorResponse	"Report rate limit exceeded, see Retry-After"
 // @Router	/api/v1/sections/{id}/report-products [get]
 func (s *Section) GetReportProducts() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.GetInt("id")
+		format, ok := negotiateFormat(c)
+		if !ok {
+			return
+		}
 
 		report, err := s.sectionService.GetReportProducts(c.Request.Context(), id)
 		if err != nil {
@@ -184,6 +300,10 @@ func (s *Section) GetReportProducts() gin.HandlerFunc {
 			return
 		}
 
+		if format != export.JSON {
+			exportRows(c, format, "report-products", sectionReportHeader, []domain.GetOneData{report}, sectionReportRow)
+			return
+		}
 		web.Success(c, http.StatusOK, report)
 	}
 }
@@ -194,12 +314,31 @@ func (s *Section) GetReportProducts() gin.HandlerFunc {
 // @Tags		Sections
 // @Accept		json
 // @Produce	json
+// @Produce	text/csv
+// @Produce	application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param		format	query	string	false	"Export format: json, csv or xlsx"
 // @Success	200	{object}	web.response	"Report of products"
 // @Success	204	{object}	web.errorResponse	"Status No Content"
+// @Failure	400	{object}	web.errorResponse	"Unsupported export format"
 // @Failure	404	{object}	web.errorResponse	"Could not find any section"
+// @Failure	500	{object}	web.errorResponse	"Could not stream the report"
+// @Failure	429	{object}	web.errorResponse	"Report rate limit exceeded, see Retry-After"
 // @Router	/api/v1/sections/report-products [get]
 func (s *Section) GetAllReportProducts() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		format, ok := negotiateFormat(c)
+		if !ok {
+			return
+		}
+		if format != export.JSON {
+			err := streamRows(c, format, "report-products", sectionReportHeader, sectionReportRow, func(fn func(domain.GetOneData) error) error {
+				return s.sectionService.StreamReportProducts(c.Request.Context(), fn)
+			})
+			if err != nil {
+				web.Error(c, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
 
 		report, err := s.sectionService.GetAllReportProducts(c.Request.Context())
 		if err != nil {
@@ -215,3 +354,13 @@ func (s *Section) GetAllReportProducts() gin.HandlerFunc {
 		web.Success(c, http.StatusOK, report)
 	}
 }
+
+var sectionReportHeader = []string{"section_id", "section_number", "products_count"}
+
+func sectionReportRow(r domain.GetOneData) []string {
+	return []string{
+		strconv.Itoa(r.SectionId),
+		strconv.Itoa(r.SectionNumber),
+		strconv.Itoa(r.ProductCount),
+	}
+}
@@ -310,6 +310,37 @@ func TestGetAllReportProducts(t *testing.T) {
 		assert.Equal(t, http.StatusOK, res.Code)
 		assert.Equal(t, expected, received.Data)
 	})
+	t.Run("streams the products of sections as CSV", func(t *testing.T) {
+		sectionService := SectionServiceMock{}
+		h := handler.NewSection(&sectionService)
+		server := getSectionServer(h)
+		report := []domain.GetOneData{
+			{SectionId: 123, SectionNumber: 2, ProductCount: 4},
+			{SectionId: 124, SectionNumber: 3, ProductCount: 1},
+		}
+
+		sectionService.On("StreamReportProducts", mock.Anything).Return(report, nil)
+
+		res := requestSectionGet(server, SECTIONS_URL+"/report-products?format=csv")
+
+		expected := "section_id,section_number,products_count\n" +
+			"123,2,4\n" +
+			"124,3,1\n"
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, expected, res.Body.String())
+		sectionService.AssertNotCalled(t, "GetAllReportProducts", mock.Anything)
+	})
+	t.Run("get a error 500 if the sections cannot be streamed", func(t *testing.T) {
+		sectionService := SectionServiceMock{}
+		h := handler.NewSection(&sectionService)
+		server := getSectionServer(h)
+
+		sectionService.On("StreamReportProducts", mock.Anything).Return([]domain.GetOneData{}, section.ErrGetSections)
+
+		res := requestSectionGet(server, SECTIONS_URL+"/report-products?format=csv")
+
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+	})
 }
 
 func TestGetReportProducts(t *testing.T) {
@@ -447,6 +478,21 @@ func (s *SectionServiceMock) GetAll(ctx context.Context) ([]domain.Section, erro
 	return args.Get(0).([]domain.Section), args.Error(1)
 }
 
+func (s *SectionServiceMock) GetByIDs(ctx context.Context, ids []int) ([]domain.Section, error) {
+	args := s.Called(ctx, ids)
+	return args.Get(0).([]domain.Section), args.Error(1)
+}
+
+func (s *SectionServiceMock) CreateBatch(ctx context.Context, items []section.CreateSection) ([]domain.Section, []error) {
+	args := s.Called(ctx, items)
+	return args.Get(0).([]domain.Section), args.Get(1).([]error)
+}
+
+func (s *SectionServiceMock) UpdateBatch(ctx context.Context, ids []int, items []section.UpdateSection) ([]domain.Section, []error) {
+	args := s.Called(ctx, ids, items)
+	return args.Get(0).([]domain.Section), args.Get(1).([]error)
+}
+
 func (s *SectionServiceMock) Get(ctx context.Context, id int) (domain.Section, error) {
 	args := s.Called(ctx, id)
 	return args.Get(0).(domain.Section), args.Error(1)
@@ -462,11 +508,26 @@ func (s *SectionServiceMock) Delete(ctx context.Context, id int) error {
 	return args.Error(0)
 }
 
+func (s *SectionServiceMock) Restore(ctx context.Context, id int) (domain.Section, error) {
+	args := s.Called(ctx, id)
+	return args.Get(0).(domain.Section), args.Error(1)
+}
+
 func (s *SectionServiceMock) GetAllReportProducts(ctx context.Context) ([]domain.GetOneData, error) {
 	args := s.Called(ctx)
 	return args.Get(0).([]domain.GetOneData), args.Error(1)
 }
 
+func (s *SectionServiceMock) StreamReportProducts(ctx context.Context, fn func(domain.GetOneData) error) error {
+	args := s.Called(ctx)
+	for _, entry := range args.Get(0).([]domain.GetOneData) {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
 func (s *SectionServiceMock) GetReportProducts(ctx context.Context, id int) (domain.GetOneData, error) {
 	args := s.Called(ctx, id)
 	return args.Get(0).(domain.GetOneData), args.Error(1)
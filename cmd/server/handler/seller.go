@@ -6,6 +6,8 @@ import (
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/seller"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/patch"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
 	"github.com/gin-gonic/gin"
@@ -24,16 +26,31 @@ func NewSeller(s seller.Service) *Seller {
 // GetAll retrieves all sellers.
 //
 //	@Summary		Get all sellers
-//	@Description	Retrieves all sellers
+//	@Description	Retrieves all sellers, or only those with the given `ids`. Admins can include deleted ones with `include_deleted=true`.
 //	@Tags			Sellers
 //	@Produce		json
+//	@Param			ids				query	string	false	"Comma separated ids of the sellers to retrieve"
+//	@Param			include_deleted	query	bool	false	"Include deleted sellers (admin only)"
+//	@Param			X-Admin-Token	header	string	false	"Admin token"
 //	@Success		200	{array}	domain.Seller	"Successfully retrieved sellers"
 //	@Success		204	"No Content"
+//	@Failure		400	{object}	web.errorResponse	"Bad Request"
+//	@Failure		403	{object}	web.errorResponse	"Forbidden"
 //	@Failure		500	{object}	web.errorResponse	"Internal Server Error"
 //	@Router			/api/v1/sellers [get]
 func (s *Seller) GetAll() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		sellers, err := s.sellerService.GetAll(c)
+		ids, err := idsQuery(c, "ids")
+		if err != nil {
+			web.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		var sellers []domain.Seller
+		if ids.HasVal {
+			sellers, err = s.sellerService.GetByIDs(c, ids.Val)
+		} else {
+			sellers, err = s.sellerService.GetAll(c)
+		}
 		if err != nil {
 			web.Error(c, http.StatusInternalServerError, err.Error())
 			return
@@ -49,13 +66,19 @@ func (s *Seller) GetAll() gin.HandlerFunc {
 // Get retrieves a seller by ID.
 //
 //	@Summary		Get a seller by ID
-//	@Description	Retrieves a seller based on the provided ID
+//	@Description	Retrieves a seller based on the provided ID. Admins can get a deleted one with `include_deleted=true`.
 //	@Produce		json
 //	@Tags			Sellers
-//	@Param			id	path		int					true	"Seller ID"
-//	@Success		200	{object}	domain.Seller		"Successfully retrieved seller"
-//	@Failure		400	{object}	web.errorResponse	"Bad Request"
-//	@Failure		404	{object}	web.errorResponse	"Not Found"
+//	@Param			id				path		int					true	"Seller ID"
+//	@Param			include_deleted	query		bool				false	"Include deleted sellers (admin only)"
+//	@Param			X-Admin-Token	header		string				false	"Admin token"
+//	@Param			If-None-Match	header		string				false	"ETag of the cached seller"
+//	@Success		200				{object}	domain.Seller		"Successfully retrieved seller"
+//	@Header			200				{string}	ETag				"Version of the seller"
+//	@Success		304				"Not Modified"
+//	@Failure		400				{object}	web.errorResponse	"Bad Request"
+//	@Failure		403				{object}	web.errorResponse	"Forbidden"
+//	@Failure		404				{object}	web.errorResponse	"Not Found"
 //	@Router			/api/v1/sellers/{id} [get]
 func (s *Seller) Get() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -65,6 +88,9 @@ func (s *Seller) Get() gin.HandlerFunc {
 			web.Error(c, http.StatusNotFound, errGetSeller.Error())
 			return
 		}
+		if web.NotModified(c, seller.Version) {
+			return
+		}
 		web.Success(c, http.StatusOK, seller)
 	}
 }
@@ -76,9 +102,11 @@ func (s *Seller) Get() gin.HandlerFunc {
 //	@Accept			json
 //	@Produce		json
 //	@Param			seller	body	domain.Seller	true	"Seller object"
+//	@Param			Idempotency-Key	header	string	false	"Makes retries of the request return its first response"
 //	@Tags			Sellers
 //	@Success		201	{object}	domain.Seller		"Successfully created seller"
 //	@Failure		404	{object}	web.errorResponse	"Not Found"
+//	@Failure		409	{object}	web.errorResponse	"Idempotency-Key request still in progress"
 //	@Failure		422	{object}	web.errorResponse	"Unprocessable Entity"
 //	@Failure		500	{object}	web.errorResponse	"Internal Server Error"
 //	@Router			/api/v1/sellers [post]
@@ -120,61 +148,196 @@ func (s *Seller) Create() gin.HandlerFunc {
 // Update updates an existing seller.
 //
 //	@Summary		Update an existing seller
-//	@Description	Updates an existing seller with the provided data
-//	@Accept			json
+//	@Description	Updates an existing seller with the provided data. With `If-Match` it only does if the seller is still in that version.
+//	@Description	Plain JSON bodies leave the fields with zero values unchanged. To set zero values or clear fields, send a JSON Merge Patch (`application/merge-patch+json`) or a JSON Patch (`application/json-patch+json`) instead.
+//	@Accept			json,application/merge-patch+json,application/json-patch+json
 //	@Produce		json
-//	@Param			id		path	int				true	"Seller ID"
-//	@Param			seller	body	domain.Seller	true	"Seller object"
+//	@Param			id			path	int				true	"Seller ID"
+//	@Param			seller		body	domain.Seller	true	"Seller object"
+//	@Param			If-Match	header	string			false	"ETag the seller must have"
 //	@Tags			Sellers
 //	@Success		200	{object}	domain.Seller		"Successfully updated seller"
+//	@Header			200	{string}	ETag				"New version of the seller"
 //	@Failure		400	{object}	web.errorResponse	"Bad Request"
 //	@Failure		404	{object}	web.errorResponse	"Not Found"
+//	@Failure		409	{object}	web.errorResponse	"CID already registered, locality not found or patch test failed"
+//	@Failure		412	{object}	web.errorResponse	"Precondition Failed"
+//	@Failure		422	{object}	web.errorResponse	"Invalid patch"
 //	@Failure		500	{object}	web.errorResponse	"Internal Server Error"
-//	@Router			/api/v1/sellers/{id} [put]
+//	@Router			/api/v1/sellers/{id} [patch]
 func (s *Seller) Update() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.GetInt("id")
 
-		sellerBody := middleware.GetBody[domain.Seller](c)
-
-		sellerUpdated, err := s.sellerService.Update(c, id, sellerBody)
+		var sellerUpdated domain.Seller
+		var err error
+		if p, ok := middleware.GetPatch(c); ok {
+			sellerUpdated, err = s.sellerService.Patch(c, id, p)
+		} else {
+			sellerUpdated, err = s.sellerService.Update(c, id, middleware.GetBody[domain.Seller](c))
+		}
 		if err != nil {
-			if errors.Is(err, seller.ErrNotFound) {
-				web.Error(c, http.StatusNotFound, err.Error())
-				return
-			} else if errors.Is(err, seller.ErrCidAlreadyExists) {
-				web.Error(c, http.StatusConflict, err.Error())
-				return
-			}
-			web.Error(c, http.StatusInternalServerError, err.Error())
+			web.Error(c, sellerErrStatus(err), err.Error())
 			return
 		}
+		web.SetETag(c, sellerUpdated.Version)
 		web.Success(c, http.StatusOK, sellerUpdated)
 	}
 }
 
+// Batch creates and updates sellers in a single call.
+//
+//	@Summary		Create and update sellers in batch
+//	@Description	Creates the sellers in `create` and updates those in `update`, which are sent with their `id`, like the update endpoint does. Each seller gets its own status, in the order they were sent.
+//	@Accept			json
+//	@Produce		json
+//	@Param			batch	body	web.batchRequest{create=[]domain.Seller,update=[]domain.Seller}	true	"Sellers to create and update"
+//	@Param			Idempotency-Key	header	string	false	"Makes retries of the request return its first response"
+//	@Tags			Sellers
+//	@Success		207	{object}	web.response{data=web.BatchResponse}	"Status of each seller"
+//	@Failure		422	{object}	web.errorResponse	"Unprocessable Entity"
+//	@Router			/api/v1/sellers/batch [post]
+func (s *Seller) Batch() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, ok := getBatch[domain.Seller, domain.Seller](c)
+		if !ok {
+			return
+		}
+		created, createErrs := s.sellerService.CreateBatch(c, req.Create)
+		ids, updates := req.Updates()
+		updated, updateErrs := s.sellerService.UpdateBatch(c, ids, updates)
+		web.Success(c, http.StatusMultiStatus, web.BatchResponse{
+			Create: web.BatchResults(created, createErrs, http.StatusCreated, sellerErrStatus),
+			Update: web.BatchResults(updated, updateErrs, http.StatusOK, sellerErrStatus),
+		})
+	}
+}
+
 // Delete deletes a seller by ID.
 //
 //	@Summary		Delete a seller by ID
-//	@Description	Deletes a seller based on the provided ID
-//	@Param			id	path	int	true	"Seller ID"
+//	@Description	Soft deletes a seller based on the provided ID, along with its products. It can be undone with the restore endpoint. With `dry_run=true` it only returns what would be hidden.
+//	@Param			id			path	int		true	"Seller ID"
+//	@Param			dry_run		query	bool	false	"Only preview what would be deleted"
+//	@Param			If-Match	header	string	false	"ETag the seller must have"
 //	@Tags			Sellers
+//	@Produce		json
+//	@Success		200	{object}	domain.SellerDeleteImpact	"Rows that would be hidden"
 //	@Success		204	"No Content"
 //	@Failure		400	{object}	web.errorResponse	"Bad Request"
 //	@Failure		404	{object}	web.errorResponse	"Not Found"
+//	@Failure		412	{object}	web.errorResponse	"Precondition Failed"
 //	@Failure		500	{object}	web.errorResponse	"Internal Server Error"
 //	@Router			/api/v1/sellers/{id} [delete]
 func (s *Seller) Delete() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.GetInt("id")
-		errDelete := s.sellerService.Delete(c, id)
+		dryRun, err := boolQuery(c, "dry_run")
+		if err != nil {
+			web.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		impact, errDelete := s.sellerService.Delete(c, id, dryRun)
 		if errDelete != nil {
-			if errDelete == seller.ErrNotFound {
-				web.Error(c, http.StatusNotFound, errDelete.Error())
-			} else {
-				web.Error(c, http.StatusInternalServerError, errDelete.Error())
-			}
+			web.Error(c, sellerErrStatus(errDelete), errDelete.Error())
+			return
+		}
+		if dryRun {
+			web.Success(c, http.StatusOK, impact)
+			return
 		}
 		web.Success(c, http.StatusNoContent, nil)
 	}
 }
+
+// Restore undoes the deletion of a seller.
+//
+//	@Summary		Restore a deleted seller
+//	@Description	Restores a deleted seller along with the products deleted with it
+//	@Produce		json
+//	@Tags			Sellers
+//	@Param			id				path		int					true	"Seller ID"
+//	@Param			X-Admin-Token	header		string				true	"Admin token"
+//	@Success		200				{object}	domain.Seller		"Successfully restored seller"
+//	@Failure		400				{object}	web.errorResponse	"Bad Request"
+//	@Failure		403				{object}	web.errorResponse	"Restricted to admins"
+//	@Failure		404				{object}	web.errorResponse	"Not Found"
+//	@Failure		500				{object}	web.errorResponse	"Internal Server Error"
+//	@Router			/api/v1/sellers/{id}/restore [post]
+func (s *Seller) Restore() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetInt("id")
+		restored, err := s.sellerService.Restore(c, id)
+		if err != nil {
+			web.Error(c, sellerErrStatus(err), err.Error())
+			return
+		}
+		web.SetETag(c, restored.Version)
+		web.Success(c, http.StatusOK, restored)
+	}
+}
+
+// Products retrieves the products of a seller.
+//
+//	@Summary		Get the products of a seller
+//	@Description	Retrieves the products sold by the seller with the provided ID
+//	@Produce		json
+//	@Tags			Sellers
+//	@Param			id	path		int					true	"Seller ID"
+//	@Success		200	{array}		domain.Product		"Successfully retrieved products"
+//	@Failure		400	{object}	web.errorResponse	"Bad Request"
+//	@Failure		404	{object}	web.errorResponse	"Not Found"
+//	@Failure		500	{object}	web.errorResponse	"Internal Server Error"
+//	@Router			/api/v1/sellers/{id}/products [get]
+func (s *Seller) Products() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetInt("id")
+		products, err := s.sellerService.Products(c, id)
+		if err != nil {
+			web.Error(c, sellerErrStatus(err), err.Error())
+			return
+		}
+		web.Success(c, http.StatusOK, products)
+	}
+}
+
+// Performance reports how a seller is doing.
+//
+//	@Summary		Get the performance of a seller
+//	@Description	Number of products, units in stock across batches, units sold in purchase orders and the revenue from them
+//	@Produce		json
+//	@Tags			Sellers
+//	@Param			id	path		int							true	"Seller ID"
+//	@Success		200	{object}	domain.SellerPerformance	"Successfully retrieved performance"
+//	@Failure		400	{object}	web.errorResponse			"Bad Request"
+//	@Failure		404	{object}	web.errorResponse			"Not Found"
+//	@Failure		500	{object}	web.errorResponse			"Internal Server Error"
+//	@Failure		429	{object}	web.errorResponse			"Report rate limit exceeded, see Retry-After"
+//	@Router			/api/v1/sellers/{id}/performance [get]
+func (s *Seller) Performance() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetInt("id")
+		perf, err := s.sellerService.Performance(c, id)
+		if err != nil {
+			web.Error(c, sellerErrStatus(err), err.Error())
+			return
+		}
+		web.Success(c, http.StatusOK, perf)
+	}
+}
+
+func sellerErrStatus(err error) int {
+	switch {
+	case errors.Is(err, seller.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, seller.ErrCidAlreadyExists), errors.Is(err, seller.ErrLocalityNotFound):
+		return http.StatusConflict
+	case errors.Is(err, sqlutil.ErrVersionMismatch):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, patch.ErrInvalidPatch):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, patch.ErrTestFailed):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
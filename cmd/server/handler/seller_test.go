@@ -6,12 +6,17 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/cmd/server/handler"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/seller"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/patch"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -20,6 +25,8 @@ import (
 
 var SELLER_URL = "/sellers"
 
+const ADMIN_TOKEN = "admin-token"
+
 func TestCreateSeller(t *testing.T) {
 	t.Run("Returns 201 if successful", func(t *testing.T) {
 		svcMock := SellerServiceMock{}
@@ -174,7 +181,7 @@ func TestDeleteSeller(t *testing.T) {
 		idToDelete := 1
 		url := fmt.Sprintf("%s/%d", SELLER_URL, idToDelete)
 
-		svcMock.On("Delete", mock.Anything, idToDelete).Return(seller.ErrNotFound)
+		svcMock.On("Delete", mock.Anything, idToDelete, false).Return(domain.SellerDeleteImpact{}, seller.ErrNotFound)
 
 		request, response := testutil.MakeRequest(http.MethodDelete, url, nil)
 		server.ServeHTTP(response, request)
@@ -190,7 +197,7 @@ func TestDeleteSeller(t *testing.T) {
 		idToDelete := 1
 		url := fmt.Sprintf("%s/%d", SELLER_URL, idToDelete)
 
-		svcMock.On("Delete", mock.Anything, idToDelete).Return(errors.New(""))
+		svcMock.On("Delete", mock.Anything, idToDelete, false).Return(domain.SellerDeleteImpact{}, errors.New(""))
 
 		request, response := testutil.MakeRequest(http.MethodDelete, url, nil)
 		server.ServeHTTP(response, request)
@@ -206,7 +213,7 @@ func TestDeleteSeller(t *testing.T) {
 		idToDelete := 1
 		url := fmt.Sprintf("%s/%d", SELLER_URL, idToDelete)
 
-		svcMock.On("Delete", mock.Anything, idToDelete).Return(nil)
+		svcMock.On("Delete", mock.Anything, idToDelete, false).Return(domain.SellerDeleteImpact{}, nil)
 
 		request, response := testutil.MakeRequest(http.MethodDelete, url, nil)
 		server.ServeHTTP(response, request)
@@ -214,7 +221,287 @@ func TestDeleteSeller(t *testing.T) {
 		assert.Equal(t, http.StatusNoContent, response.Code)
 
 	})
+	t.Run("returns the impact on a dry run", func(t *testing.T) {
+		svcMock := SellerServiceMock{}
+		sellerHandler := handler.NewSeller(&svcMock)
+		server := getSellerServer(sellerHandler)
+
+		expected := domain.SellerDeleteImpact{SellerID: 1, Products: 2, ProductRecords: 4}
+		svcMock.On("Delete", mock.Anything, 1, true).Return(expected, nil)
+
+		request, response := testutil.MakeRequest(http.MethodDelete, SELLER_URL+"/1?dry_run=true", nil)
+		server.ServeHTTP(response, request)
+
+		var received testutil.SuccessResponse[domain.SellerDeleteImpact]
+		json.Unmarshal(response.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, expected, received.Data)
+	})
+	t.Run("returns 400 if dry_run is not a boolean", func(t *testing.T) {
+		svcMock := SellerServiceMock{}
+		sellerHandler := handler.NewSeller(&svcMock)
+		server := getSellerServer(sellerHandler)
+
+		request, response := testutil.MakeRequest(http.MethodDelete, SELLER_URL+"/1?dry_run=maybe", nil)
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		svcMock.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestRestoreSeller(t *testing.T) {
+	t.Run("returns 200 with the restored seller", func(t *testing.T) {
+		svcMock := SellerServiceMock{}
+		server := getSellerServer(handler.NewSeller(&svcMock))
+
+		expected := domain.Seller{ID: 1, CID: 1, CompanyName: "meli"}
+		svcMock.On("Restore", mock.Anything, 1).Return(expected, nil)
+
+		request, response := testutil.MakeRequest(http.MethodPost, SELLER_URL+"/1/restore", nil)
+		server.ServeHTTP(response, request)
+
+		var received testutil.SuccessResponse[domain.Seller]
+		json.Unmarshal(response.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, expected, received.Data)
+	})
+	t.Run("returns 404 when the seller is not deleted", func(t *testing.T) {
+		svcMock := SellerServiceMock{}
+		server := getSellerServer(handler.NewSeller(&svcMock))
+
+		svcMock.On("Restore", mock.Anything, 1).Return(domain.Seller{}, seller.ErrNotFound)
+
+		request, response := testutil.MakeRequest(http.MethodPost, SELLER_URL+"/1/restore", nil)
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+func TestReadDeletedSellers(t *testing.T) {
+	includesDeleted := mock.MatchedBy(func(ctx context.Context) bool { return sqlutil.IncludeDeleted(ctx) })
+
+	t.Run("lists deleted sellers to admins", func(t *testing.T) {
+		svcMock := SellerServiceMock{}
+		server := getSellerServer(handler.NewSeller(&svcMock))
+
+		deletedAt := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+		expected := []domain.Seller{{ID: 1, CID: 1, DeletedAt: &deletedAt}}
+		svcMock.On("GetAll", includesDeleted).Return(expected, nil)
+
+		request, response := testutil.MakeRequest(http.MethodGet, SELLER_URL+"?include_deleted=true", nil)
+		request.Header.Set(middleware.ADMIN_TOKEN_HEADER, ADMIN_TOKEN)
+		server.ServeHTTP(response, request)
+
+		var received testutil.SuccessResponse[[]domain.Seller]
+		json.Unmarshal(response.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, expected, received.Data)
+	})
+	t.Run("returns 403 to anyone else", func(t *testing.T) {
+		svcMock := SellerServiceMock{}
+		server := getSellerServer(handler.NewSeller(&svcMock))
+
+		request, response := testutil.MakeRequest(http.MethodGet, SELLER_URL+"/1?include_deleted=true", nil)
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusForbidden, response.Code)
+		svcMock.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+	})
+}
+
+func TestSellerBatch(t *testing.T) {
+	t.Run("reads the sellers with the given ids", func(t *testing.T) {
+		svcMock := SellerServiceMock{}
+		server := getSellerServer(handler.NewSeller(&svcMock))
+
+		expected := []domain.Seller{{ID: 1, CID: 1}, {ID: 3, CID: 3}}
+		svcMock.On("GetByIDs", mock.Anything, []int{1, 3}).Return(expected, nil)
+
+		request, response := testutil.MakeRequest(http.MethodGet, SELLER_URL+"?ids=1,3,1", nil)
+		server.ServeHTTP(response, request)
+
+		var received testutil.SuccessResponse[[]domain.Seller]
+		json.Unmarshal(response.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, expected, received.Data)
+		svcMock.AssertNotCalled(t, "GetAll", mock.Anything)
+	})
+	t.Run("returns 400 when the ids are invalid", func(t *testing.T) {
+		svcMock := SellerServiceMock{}
+		server := getSellerServer(handler.NewSeller(&svcMock))
+
+		request, response := testutil.MakeRequest(http.MethodGet, SELLER_URL+"?ids=1,a", nil)
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+	t.Run("returns the status of each seller", func(t *testing.T) {
+		svcMock := SellerServiceMock{}
+		server := getSellerServer(handler.NewSeller(&svcMock))
+
+		creates := []domain.Seller{{CID: 1, CompanyName: "A", Address: "a", Telephone: "1"}, {CID: 2, CompanyName: "B", Address: "b", Telephone: "2"}}
+		svcMock.On("CreateBatch", mock.Anything, creates).Return([]domain.Seller{{ID: 7, CID: 1}, {}}, []error{nil, seller.ErrCidAlreadyExists})
+		svcMock.On("UpdateBatch", mock.Anything, []int{4}, []domain.Seller{{ID: 4, CompanyName: "C"}}).Return([]domain.Seller{{}}, []error{seller.ErrNotFound})
+
+		body := `{"create":[{"cid":1,"company_name":"A","address":"a","telephone":"1"},{"cid":2,"company_name":"B","address":"b","telephone":"2"}],"update":[{"id":4,"company_name":"C"}]}`
+		request, response := testutil.MakeRequest(http.MethodPost, SELLER_URL+"/batch", json.RawMessage(body))
+		server.ServeHTTP(response, request)
+
+		var received testutil.SuccessResponse[web.BatchResponse]
+		json.Unmarshal(response.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusMultiStatus, response.Code)
+		assert.Equal(t, http.StatusCreated, received.Data.Create[0].Status)
+		assert.Equal(t, http.StatusConflict, received.Data.Create[1].Status)
+		assert.Equal(t, seller.ErrCidAlreadyExists.Error(), received.Data.Create[1].Error)
+		assert.Equal(t, http.StatusNotFound, received.Data.Update[0].Status)
+	})
+	t.Run("returns 422 when the batch is too big", func(t *testing.T) {
+		svcMock := SellerServiceMock{}
+		server := getSellerServer(handler.NewSeller(&svcMock))
+
+		updates := make([]string, web.MaxBatchSize+1)
+		for i := range updates {
+			updates[i] = fmt.Sprintf(`{"id":%d}`, i+1)
+		}
+		body := `{"update":[` + strings.Join(updates, ",") + `]}`
+		request, response := testutil.MakeRequest(http.MethodPost, SELLER_URL+"/batch", json.RawMessage(body))
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, response.Code)
+		svcMock.AssertNotCalled(t, "UpdateBatch", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestSellerVersions(t *testing.T) {
+	expectsVersion := func(version int) interface{} {
+		return mock.MatchedBy(func(ctx context.Context) bool {
+			versions, ok := sqlutil.IfMatch(ctx)
+			return ok && len(versions) == 1 && versions[0] == version
+		})
+	}
+
+	t.Run("Get sends the version as ETag", func(t *testing.T) {
+		svcMock := SellerServiceMock{}
+		server := getSellerServer(handler.NewSeller(&svcMock))
+
+		svcMock.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, Version: 3}, nil)
+
+		request, response := testutil.MakeRequest(http.MethodGet, SELLER_URL+"/1", nil)
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, `"3"`, response.Header().Get("ETag"))
+	})
+	t.Run("Get returns 304 if the client has the version", func(t *testing.T) {
+		svcMock := SellerServiceMock{}
+		server := getSellerServer(handler.NewSeller(&svcMock))
+
+		svcMock.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, Version: 3}, nil)
+
+		request, response := testutil.MakeRequest(http.MethodGet, SELLER_URL+"/1", nil)
+		request.Header.Set("If-None-Match", `"3"`)
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusNotModified, response.Code)
+		assert.Empty(t, response.Body.Bytes())
+	})
+	t.Run("Update sends the new ETag", func(t *testing.T) {
+		svcMock := SellerServiceMock{}
+		server := getSellerServer(handler.NewSeller(&svcMock))
+
+		body := domain.Seller{CompanyName: "Meli"}
+		svcMock.On("Update", expectsVersion(3), 1, body).Return(domain.Seller{ID: 1, CompanyName: "Meli", Version: 4}, nil)
+
+		request, response := testutil.MakeRequest(http.MethodPatch, SELLER_URL+"/1", body)
+		request.Header.Set("If-Match", `"3"`)
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, `"4"`, response.Header().Get("ETag"))
+	})
+	t.Run("Update returns 412 if the seller is in another version", func(t *testing.T) {
+		svcMock := SellerServiceMock{}
+		server := getSellerServer(handler.NewSeller(&svcMock))
+
+		body := domain.Seller{CompanyName: "Meli"}
+		svcMock.On("Update", expectsVersion(2), 1, body).Return(domain.Seller{}, sqlutil.ErrVersionMismatch)
+
+		request, response := testutil.MakeRequest(http.MethodPatch, SELLER_URL+"/1", body)
+		request.Header.Set("If-Match", `"2"`)
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusPreconditionFailed, response.Code)
+	})
+	t.Run("Delete returns 412 if the seller is in another version", func(t *testing.T) {
+		svcMock := SellerServiceMock{}
+		server := getSellerServer(handler.NewSeller(&svcMock))
+
+		svcMock.On("Delete", expectsVersion(2), 1, false).Return(domain.SellerDeleteImpact{}, sqlutil.ErrVersionMismatch)
+
+		request, response := testutil.MakeRequest(http.MethodDelete, SELLER_URL+"/1", nil)
+		request.Header.Set("If-Match", `"2"`)
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusPreconditionFailed, response.Code)
+	})
+}
+
+func TestSellerCatalog(t *testing.T) {
+	t.Run("returns the products of a seller", func(t *testing.T) {
+		svcMock := SellerServiceMock{}
+		sellerHandler := handler.NewSeller(&svcMock)
+		server := getSellerServer(sellerHandler)
+
+		expected := []domain.Product{{ID: 1, SellerID: 1}}
+		svcMock.On("Products", mock.Anything, 1).Return(expected, nil)
+
+		request, response := testutil.MakeRequest(http.MethodGet, SELLER_URL+"/1/products", nil)
+		server.ServeHTTP(response, request)
+
+		var received testutil.SuccessResponse[[]domain.Product]
+		json.Unmarshal(response.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, expected, received.Data)
+	})
+	t.Run("returns 404 for the performance of a missing seller", func(t *testing.T) {
+		svcMock := SellerServiceMock{}
+		sellerHandler := handler.NewSeller(&svcMock)
+		server := getSellerServer(sellerHandler)
+
+		svcMock.On("Performance", mock.Anything, 1).Return(domain.SellerPerformance{}, seller.ErrNotFound)
+
+		request, response := testutil.MakeRequest(http.MethodGet, SELLER_URL+"/1/performance", nil)
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+	t.Run("returns the performance of a seller", func(t *testing.T) {
+		svcMock := SellerServiceMock{}
+		sellerHandler := handler.NewSeller(&svcMock)
+		server := getSellerServer(sellerHandler)
+
+		expected := domain.SellerPerformance{SellerID: 1, ProductsCount: 2, UnitsInStock: 10, UnitsSold: 3, Revenue: 45.5}
+		svcMock.On("Performance", mock.Anything, 1).Return(expected, nil)
+
+		request, response := testutil.MakeRequest(http.MethodGet, SELLER_URL+"/1/performance", nil)
+		server.ServeHTTP(response, request)
+
+		var received testutil.SuccessResponse[domain.SellerPerformance]
+		json.Unmarshal(response.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, expected, received.Data)
+	})
 }
+
 func TestUpdateSeller(t *testing.T) {
 	t.Run("Returns 200 if update is successful", func(t *testing.T) {
 		svcMock := SellerServiceMock{}
@@ -298,6 +585,53 @@ func TestUpdateSeller(t *testing.T) {
 
 		assert.Equal(t, http.StatusInternalServerError, response.Code)
 	})
+	t.Run("Applies a merge patch", func(t *testing.T) {
+		svcMock := SellerServiceMock{}
+		sellerHandler := handler.NewSeller(&svcMock)
+		server := getSellerServer(sellerHandler)
+
+		expected := domain.Seller{ID: 1, CID: 123, CompanyName: "TEST"}
+		url := fmt.Sprintf("%s/%d", SELLER_URL, expected.ID)
+		p := patch.MergePatch{Patch: map[string]any{"locality_id": float64(0), "address": nil}}
+		svcMock.On("Patch", mock.Anything, expected.ID, p).Return(expected, nil)
+
+		request, response := testutil.MakeRequest(http.MethodPatch, url, p.Patch)
+		request.Header.Set("Content-Type", patch.MergePatchContentType)
+		server.ServeHTTP(response, request)
+
+		var received testutil.SuccessResponse[domain.Seller]
+		json.Unmarshal(response.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, expected, received.Data)
+		svcMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("Returns 422 if receives a malformed JSON patch", func(t *testing.T) {
+		svcMock := SellerServiceMock{}
+		sellerHandler := handler.NewSeller(&svcMock)
+		server := getSellerServer(sellerHandler)
+
+		url := fmt.Sprintf("%s/%d", SELLER_URL, 1)
+		request, response := testutil.MakeRequest(http.MethodPatch, url, []map[string]any{{"op": "increment", "path": "/cid"}})
+		request.Header.Set("Content-Type", patch.JSONPatchContentType)
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, response.Code)
+	})
+	t.Run("Returns 409 if a JSON patch test fails", func(t *testing.T) {
+		svcMock := SellerServiceMock{}
+		sellerHandler := handler.NewSeller(&svcMock)
+		server := getSellerServer(sellerHandler)
+
+		url := fmt.Sprintf("%s/%d", SELLER_URL, 1)
+		svcMock.On("Patch", mock.Anything, 1, mock.Anything).Return(domain.Seller{}, patch.ErrTestFailed)
+
+		request, response := testutil.MakeRequest(http.MethodPatch, url, []map[string]any{{"op": "test", "path": "/cid", "value": 1}})
+		request.Header.Set("Content-Type", patch.JSONPatchContentType)
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusConflict, response.Code)
+	})
 }
 func TestReadSeller(t *testing.T) {
 	t.Run("returns 200 if getAll is successful", func(t *testing.T) {
@@ -417,11 +751,15 @@ func getSellerServer(h *handler.Seller) *gin.Engine {
 
 	sellerRG := s.Group(SELLER_URL)
 	{
-		sellerRG.GET("", h.GetAll())
-		sellerRG.GET("/:id", middleware.IntPathParam(), h.Get())
+		sellerRG.GET("", middleware.IncludeDeleted(ADMIN_TOKEN), h.GetAll())
+		sellerRG.GET("/:id", middleware.IntPathParam(), middleware.IncludeDeleted(ADMIN_TOKEN), h.Get())
 		sellerRG.POST("", middleware.Body[domain.Seller](), h.Create())
-		sellerRG.PATCH("/:id", middleware.IntPathParam(), middleware.Body[domain.Seller](), h.Update())
-		sellerRG.DELETE("/:id", middleware.IntPathParam(), h.Delete())
+		sellerRG.POST("/batch", middleware.Body[web.BatchRequest[domain.Seller, domain.Seller]](), h.Batch())
+		sellerRG.PATCH("/:id", middleware.IntPathParam(), middleware.IfMatch(), middleware.Patch[domain.Seller](), h.Update())
+		sellerRG.DELETE("/:id", middleware.IntPathParam(), middleware.IfMatch(), h.Delete())
+		sellerRG.POST("/:id/restore", middleware.IntPathParam(), h.Restore())
+		sellerRG.GET("/:id/products", middleware.IntPathParam(), h.Products())
+		sellerRG.GET("/:id/performance", middleware.IntPathParam(), h.Performance())
 	}
 
 	return s
@@ -436,6 +774,21 @@ func (svc *SellerServiceMock) GetAll(c context.Context) ([]domain.Seller, error)
 	return args.Get(0).([]domain.Seller), args.Error(1)
 }
 
+func (svc *SellerServiceMock) GetByIDs(c context.Context, ids []int) ([]domain.Seller, error) {
+	args := svc.Called(c, ids)
+	return args.Get(0).([]domain.Seller), args.Error(1)
+}
+
+func (svc *SellerServiceMock) CreateBatch(c context.Context, items []domain.Seller) ([]domain.Seller, []error) {
+	args := svc.Called(c, items)
+	return args.Get(0).([]domain.Seller), args.Get(1).([]error)
+}
+
+func (svc *SellerServiceMock) UpdateBatch(c context.Context, ids []int, items []domain.Seller) ([]domain.Seller, []error) {
+	args := svc.Called(c, ids, items)
+	return args.Get(0).([]domain.Seller), args.Get(1).([]error)
+}
+
 func (svc *SellerServiceMock) Get(ctx context.Context, id int) (domain.Seller, error) {
 	args := svc.Called(ctx, id)
 	return args.Get(0).(domain.Seller), args.Error(1)
@@ -451,7 +804,27 @@ func (svc *SellerServiceMock) Update(ctx context.Context, id int, s domain.Selle
 	return args.Get(0).(domain.Seller), args.Error(1)
 }
 
-func (svc *SellerServiceMock) Delete(ctx context.Context, id int) error {
+func (svc *SellerServiceMock) Patch(ctx context.Context, id int, p patch.Patch) (domain.Seller, error) {
+	args := svc.Called(ctx, id, p)
+	return args.Get(0).(domain.Seller), args.Error(1)
+}
+
+func (svc *SellerServiceMock) Delete(ctx context.Context, id int, dryRun bool) (domain.SellerDeleteImpact, error) {
+	args := svc.Called(ctx, id, dryRun)
+	return args.Get(0).(domain.SellerDeleteImpact), args.Error(1)
+}
+
+func (svc *SellerServiceMock) Restore(ctx context.Context, id int) (domain.Seller, error) {
+	args := svc.Called(ctx, id)
+	return args.Get(0).(domain.Seller), args.Error(1)
+}
+
+func (svc *SellerServiceMock) Products(ctx context.Context, id int) ([]domain.Product, error) {
+	args := svc.Called(ctx, id)
+	return args.Get(0).([]domain.Product), args.Error(1)
+}
+
+func (svc *SellerServiceMock) Performance(ctx context.Context, id int) (domain.SellerPerformance, error) {
 	args := svc.Called(ctx, id)
-	return args.Error(0)
+	return args.Get(0).(domain.SellerPerformance), args.Error(1)
 }
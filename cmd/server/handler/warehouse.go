@@ -1,10 +1,13 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/warehouse"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/patch"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
 	"github.com/gin-gonic/gin"
@@ -19,8 +22,14 @@ var (
 	ErrWarehouseNotFound   = "warehouse not found"
 	ErrInvalidID           = "Invalid ID"
 	ErrWarehouseNotDeleted = "Warehouse not deleted"
+	ErrExpiringWithinDays  = "expiring_within_days must be a non-negative integer"
+	ErrWarehouseChanged    = "warehouse changed since it was read"
 )
 
+// defaultExpiringWithinDays is how far ahead the dashboard
+// looks for batches that are due, unless told otherwise.
+const defaultExpiringWithinDays = 7
+
 type Warehouse struct {
 	warehouseService warehouse.Service
 }
@@ -34,12 +43,18 @@ func NewWarehouse(w warehouse.Service) *Warehouse {
 // Get retrieves a warehouse by ID.
 //
 //	@Summary		Retrieve a warehouse
-//	@Description	Get a warehouse by ID
+//	@Description	Get a warehouse by ID. Admins can get a deleted one with `include_deleted=true`.
 //	@Tags			Warehouses
-//	@Param			id	path	int	true	"Warehouse ID"
+//	@Param			id				path	int		true	"Warehouse ID"
+//	@Param			include_deleted	query	bool	false	"Include deleted warehouses (admin only)"
+//	@Param			X-Admin-Token	header	string	false	"Admin token"
+//	@Param			If-None-Match	header	string	false	"ETag of the cached warehouse"
 //	@Produce		json
 //	@Success		200	{object}	domain.Warehouse
+//	@Header			200	{string}	ETag	"Version of the warehouse"
+//	@Success		304	"warehouse did not change"
 //	@Failure		400	{string}	string	"Invalid ID"
+//	@Failure		403	{string}	string	"include_deleted is restricted to admins"
 //	@Failure		404	{string}	string	"Warehouse not found"
 //	@Router			/api/v1/warehouses/{id} [get]
 func (w *Warehouse) Get() gin.HandlerFunc {
@@ -51,6 +66,9 @@ func (w *Warehouse) Get() gin.HandlerFunc {
 			web.Error(c, http.StatusNotFound, ErrWarehouseNotFound)
 			return
 		}
+		if web.NotModified(c, warehouse.Version) {
+			return
+		}
 		web.Success(c, http.StatusOK, warehouse)
 	}
 }
@@ -58,16 +76,31 @@ func (w *Warehouse) Get() gin.HandlerFunc {
 // GetAll retrieves all warehouses.
 //
 //	@Summary		Retrieve all warehouses
-//	@Description	Get all warehouses
+//	@Description	Get all warehouses, or only those with the given `ids`. Admins can include deleted ones with `include_deleted=true`.
 //	@Tags			Warehouses
+//	@Param			ids				query	string	false	"Comma separated ids of the warehouses to retrieve"
+//	@Param			include_deleted	query	bool	false	"Include deleted warehouses (admin only)"
+//	@Param			X-Admin-Token	header	string	false	"Admin token"
 //	@Produce		json
 //	@Success		200	{array}	domain.Warehouse
 //	@Success		204	"warehouses is empty"
+//	@Failure		400	{string}	string	"include_deleted should be a boolean, or invalid ids"
+//	@Failure		403	{string}	string	"include_deleted is restricted to admins"
 //	@Failure		500	{string}	string	"something went wrong with the request"
 //	@Router			/api/v1/warehouses [get]
 func (w *Warehouse) GetAll() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		warehouses, err := w.warehouseService.GetAll(c)
+		ids, err := idsQuery(c, "ids")
+		if err != nil {
+			web.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		var warehouses []domain.Warehouse
+		if ids.HasVal {
+			warehouses, err = w.warehouseService.GetByIDs(c, ids.Val)
+		} else {
+			warehouses, err = w.warehouseService.GetAll(c)
+		}
 		if err != nil {
 			web.Error(c, http.StatusInternalServerError, ErrServerInternalError)
 			return
@@ -88,6 +121,7 @@ func (w *Warehouse) GetAll() gin.HandlerFunc {
 //	@Accept			json
 //	@Produce		json
 //	@Param			warehouse	body		domain.Warehouse	true	"Warehouse object"
+//	@Param			Idempotency-Key	header	string	false	"Makes retries of the request return its first response"
 //	@Success		201			{object}	domain.Warehouse
 //	@Failure		422			{string}	string	"warehousecode need to be passed, it can't be empty"
 //	@Failure		500			{string}	string	"something went wrong with the request"
@@ -114,51 +148,115 @@ func (w *Warehouse) Create() gin.HandlerFunc {
 // Update updates a warehouse.
 //
 //	@Summary		Update a warehouse
-//	@Description	Update a warehouse by ID
+//	@Description	Update a warehouse by ID. Plain JSON bodies leave the fields with zero values unchanged. To set zero values or clear fields, send a JSON Merge Patch (`application/merge-patch+json`) or a JSON Patch (`application/json-patch+json`) instead.
 //	@Tags			Warehouses
-//	@Accept			json
+//	@Accept			json,application/merge-patch+json,application/json-patch+json
 //	@Produce		json
 //	@Param			id			path		int					true	"Warehouse ID"
 //	@Param			warehouse	body		domain.Warehouse	true	"Updated warehouse object"
+//	@Param			If-Match	header		string				false	"ETag the warehouse must have"
 //	@Success		200			{object}	domain.Warehouse
+//	@Header			200			{string}	ETag	"New version of the warehouse"
 //	@Failure		422			{string}	string	"action could not be processed correctly due to invalid data provided"
 //	@Failure		404			{string}	string	"Invalid ID"
-//	@Failure		409			{string}	string	"Warehouse code must be unique"
+//	@Failure		409			{string}	string	"Warehouse code must be unique, locality not found or patch test failed"
+//	@Failure		412			{string}	string	"warehouse changed since it was read"
 //	@Router			/api/v1/warehouses/{id} [patch]
 func (w *Warehouse) Update() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.GetInt("id")
-		ware := middleware.GetBody[domain.Warehouse](c)
 
-		ware.ID = id
-		ware, err := w.warehouseService.Update(c, ware)
+		var ware domain.Warehouse
+		var err error
+		if p, ok := middleware.GetPatch(c); ok {
+			ware, err = w.warehouseService.Patch(c, id, p)
+		} else {
+			ware = middleware.GetBody[domain.Warehouse](c)
+			ware.ID = id
+			ware, err = w.warehouseService.Update(c, ware)
+		}
 		if err != nil {
 			if err == warehouse.ErrNotFound {
 				web.Error(c, http.StatusNotFound, ErrWarehouseNotFound)
+			} else if errors.Is(err, sqlutil.ErrVersionMismatch) {
+				web.Error(c, http.StatusPreconditionFailed, ErrWarehouseChanged)
+			} else if errors.Is(err, patch.ErrInvalidPatch) {
+				web.Error(c, http.StatusUnprocessableEntity, err.Error())
+			} else if errors.Is(err, patch.ErrTestFailed) || errors.Is(err, warehouse.ErrLocalityNotFound) {
+				web.Error(c, http.StatusConflict, err.Error())
 			} else {
 				web.Error(c, http.StatusConflict, ErrWarehouseCodeUnique)
 			}
 			return
 		}
+		web.SetETag(c, ware.Version)
 		web.Success(c, http.StatusOK, ware)
 	}
 }
 
+// Batch creates and updates warehouses in a single call.
+//
+//	@Summary		Create and update warehouses in batch
+//	@Description	Creates the warehouses in `create` and updates those in `update`, which are sent with their `id`, like the update endpoint does. Each warehouse gets its own status, in the order they were sent.
+//	@Tags			Warehouses
+//	@Accept			json
+//	@Produce		json
+//	@Param			batch	body		web.batchRequest{create=[]domain.Warehouse,update=[]domain.Warehouse}	true	"Warehouses to create and update"
+//	@Param			Idempotency-Key	header	string	false	"Makes retries of the request return its first response"
+//	@Success		207		{object}	web.response{data=web.BatchResponse}	"Status of each warehouse"
+//	@Failure		422		{string}	string	"action could not be processed correctly due to invalid data provided"
+//	@Router			/api/v1/warehouses/batch [post]
+func (w *Warehouse) Batch() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, ok := getBatch[domain.Warehouse, domain.Warehouse](c)
+		if !ok {
+			return
+		}
+		created, createErrs := w.warehouseService.CreateBatch(c, req.Create)
+		ids, updates := req.Updates()
+		updated, updateErrs := w.warehouseService.UpdateBatch(c, ids, updates)
+		web.Success(c, http.StatusMultiStatus, web.BatchResponse{
+			Create: web.BatchResults(created, createErrs, http.StatusCreated, warehouseErrStatus),
+			Update: web.BatchResults(updated, updateErrs, http.StatusOK, warehouseErrStatus),
+		})
+	}
+}
+
+// warehouseErrStatus returns the status code of an error of the
+// warehouse service.
+func warehouseErrStatus(err error) int {
+	switch {
+	case errors.Is(err, warehouse.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, warehouse.ErrInvalidWarehouseCode), errors.Is(err, warehouse.ErrLocalityNotFound):
+		return http.StatusConflict
+	case errors.Is(err, sqlutil.ErrVersionMismatch):
+		return http.StatusPreconditionFailed
+	}
+	return http.StatusInternalServerError
+}
+
 // Delete deletes a warehouse.
 //
 //	@Summary		Delete a warehouse
 //	@Description	Delete a warehouse by ID
 //	@Tags			Warehouses
-//	@Param			id	path	int	true	"Warehouse ID"
+//	@Param			id			path	int		true	"Warehouse ID"
+//	@Param			If-Match	header	string	false	"ETag the warehouse must have"
 //	@Success		204	"No Content"
 //	@Failure		400	{string}	string	"Invalid ID"
 //	@Failure		405	{string}	string	"Warehouse not deleted"
+//	@Failure		412	{string}	string	"warehouse changed since it was read"
 //	@Router			/api/v1/warehouses/{id} [delete]
 func (w *Warehouse) Delete() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.GetInt("id")
 
 		err := w.warehouseService.Delete(c, id)
+		if errors.Is(err, sqlutil.ErrVersionMismatch) {
+			web.Error(c, http.StatusPreconditionFailed, ErrWarehouseChanged)
+			return
+		}
 		if err != nil {
 			web.Error(c, http.StatusNotFound, ErrWarehouseNotFound)
 			return
@@ -166,3 +264,72 @@ func (w *Warehouse) Delete() gin.HandlerFunc {
 		web.Success(c, http.StatusNoContent, nil)
 	}
 }
+
+// Restore restores a deleted warehouse.
+//
+//	@Summary		Restore a warehouse
+//	@Description	Undo the deletion of a warehouse by ID
+//	@Tags			Warehouses
+//	@Param			id				path	int		true	"Warehouse ID"
+//	@Param			X-Admin-Token	header	string	true	"Admin token"
+//	@Produce		json
+//	@Success		200	{object}	domain.Warehouse
+//	@Failure		400	{string}	string	"Invalid ID"
+//	@Failure		403	{string}	string	"Restricted to admins"
+//	@Failure		404	{string}	string	"Warehouse not found"
+//	@Failure		500	{string}	string	"something went wrong with the request"
+//	@Router			/api/v1/warehouses/{id}/restore [post]
+func (w *Warehouse) Restore() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetInt("id")
+
+		restored, err := w.warehouseService.Restore(c, id)
+		if errors.Is(err, warehouse.ErrNotFound) {
+			web.Error(c, http.StatusNotFound, ErrWarehouseNotFound)
+			return
+		}
+		if err != nil {
+			web.Error(c, http.StatusInternalServerError, ErrServerInternalError)
+			return
+		}
+		web.SetETag(c, restored.Version)
+		web.Success(c, http.StatusOK, restored)
+	}
+}
+
+// Dashboard retrieves the dashboard of a warehouse.
+//
+//	@Summary		Retrieve a warehouse dashboard
+//	@Description	Get the sections, employee count, stock by product type, batches expiring soon and today's inbound orders of a warehouse
+//	@Tags			Warehouses
+//	@Param			id						path	int	true	"Warehouse ID"
+//	@Param			expiring_within_days	query	int	false	"Days ahead to look for expiring batches (default 7)"
+//	@Produce		json
+//	@Success		200	{object}	domain.WarehouseDashboard
+//	@Failure		400	{string}	string	"expiring_within_days must be a non-negative integer"
+//	@Failure		404	{string}	string	"Warehouse not found"
+//	@Failure		500	{string}	string	"something went wrong with the request"
+//	@Failure		429	{string}	string	"Report rate limit exceeded, see Retry-After"
+//	@Router			/api/v1/warehouses/{id}/dashboard [get]
+func (w *Warehouse) Dashboard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetInt("id")
+
+		days, err := intQuery(c, "expiring_within_days")
+		if err != nil || days.Or(0) < 0 {
+			web.Error(c, http.StatusBadRequest, ErrExpiringWithinDays)
+			return
+		}
+
+		dashboard, err := w.warehouseService.Dashboard(c, id, days.Or(defaultExpiringWithinDays))
+		if err != nil {
+			if errors.Is(err, warehouse.ErrNotFound) {
+				web.Error(c, http.StatusNotFound, ErrWarehouseNotFound)
+			} else {
+				web.Error(c, http.StatusInternalServerError, ErrServerInternalError)
+			}
+			return
+		}
+		web.Success(c, http.StatusOK, dashboard)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/cmd/server/handler"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/warehouse"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/patch"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
 	"github.com/gin-gonic/gin"
@@ -330,6 +331,43 @@ func TestWarehouseUpdate(t *testing.T) {
 		assert.Equal(t, response.Code, http.StatusNotFound)
 		assert.Equal(t, received.Message, handler.ErrWarehouseNotFound)
 	})
+
+	t.Run("test update, when the body is a merge patch", func(t *testing.T) {
+		svcMock := ServiceWarehouseMock{}
+		warehouseHandler := handler.NewWarehouse(&svcMock)
+		server := getWarehouseServer(warehouseHandler)
+
+		expectedWarehouse := domain.Warehouse{ID: 1, WarehouseCode: "cod"}
+		p := patch.MergePatch{Patch: map[string]any{"minimum_capacity": float64(0)}}
+
+		url := fmt.Sprintf("%s/%d", WAREHOUSE_URL, expectedWarehouse.ID)
+		svcMock.On("Patch", mock.Anything, expectedWarehouse.ID, p).Return(expectedWarehouse, nil)
+		request, response := testutil.MakeRequest(http.MethodPatch, url, p.Patch)
+		request.Header.Set("Content-Type", patch.MergePatchContentType)
+
+		server.ServeHTTP(response, request)
+
+		var received testutil.SuccessResponse[domain.Warehouse]
+		json.Unmarshal(response.Body.Bytes(), &received)
+
+		assert.Equal(t, response.Code, http.StatusOK)
+		assert.Equal(t, expectedWarehouse, received.Data)
+	})
+
+	t.Run("test update, when the patch can not be applied - 422", func(t *testing.T) {
+		svcMock := ServiceWarehouseMock{}
+		warehouseHandler := handler.NewWarehouse(&svcMock)
+		server := getWarehouseServer(warehouseHandler)
+
+		url := fmt.Sprintf("%s/%d", WAREHOUSE_URL, 1)
+		svcMock.On("Patch", mock.Anything, 1, mock.Anything).Return(domain.Warehouse{}, patch.ErrInvalidPatch)
+		request, response := testutil.MakeRequest(http.MethodPatch, url, map[string]any{"color": "red"})
+		request.Header.Set("Content-Type", patch.MergePatchContentType)
+
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, response.Code, http.StatusUnprocessableEntity)
+	})
 }
 
 func TestWarehouseDelete(t *testing.T) {
@@ -378,6 +416,69 @@ func TestWarehouseDelete(t *testing.T) {
 	})
 }
 
+func TestWarehouseDashboard(t *testing.T) {
+	t.Run("test dashboard, when the id is valid - 200", func(t *testing.T) {
+		svcMock := ServiceWarehouseMock{}
+		warehouseHandler := handler.NewWarehouse(&svcMock)
+		server := getWarehouseServer(warehouseHandler)
+
+		expectedDashboard := domain.WarehouseDashboard{
+			Warehouse:      domain.Warehouse{ID: 1, WarehouseCode: "cod"},
+			Sections:       []domain.SectionUsage{{SectionID: 1, TemperatureStatus: domain.TemperatureOK}},
+			EmployeesCount: 2,
+		}
+		svcMock.On("Dashboard", mock.Anything, 1, 7).Return(expectedDashboard, nil)
+
+		url := fmt.Sprintf("%s/%d/dashboard", WAREHOUSE_URL, 1)
+		request, response := testutil.MakeRequest(http.MethodGet, url, nil)
+		server.ServeHTTP(response, request)
+
+		var received testutil.SuccessResponse[domain.WarehouseDashboard]
+		json.Unmarshal(response.Body.Bytes(), &received)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, expectedDashboard, received.Data)
+	})
+	t.Run("test dashboard, with a custom expiring window - 200", func(t *testing.T) {
+		svcMock := ServiceWarehouseMock{}
+		warehouseHandler := handler.NewWarehouse(&svcMock)
+		server := getWarehouseServer(warehouseHandler)
+
+		svcMock.On("Dashboard", mock.Anything, 1, 30).Return(domain.WarehouseDashboard{}, nil)
+
+		url := fmt.Sprintf("%s/%d/dashboard?expiring_within_days=30", WAREHOUSE_URL, 1)
+		request, response := testutil.MakeRequest(http.MethodGet, url, nil)
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		svcMock.AssertExpectations(t)
+	})
+	t.Run("test dashboard, when the expiring window is invalid - 400", func(t *testing.T) {
+		svcMock := ServiceWarehouseMock{}
+		warehouseHandler := handler.NewWarehouse(&svcMock)
+		server := getWarehouseServer(warehouseHandler)
+
+		url := fmt.Sprintf("%s/%d/dashboard?expiring_within_days=-1", WAREHOUSE_URL, 1)
+		request, response := testutil.MakeRequest(http.MethodGet, url, nil)
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+	t.Run("test dashboard, when the id is not found - 404", func(t *testing.T) {
+		svcMock := ServiceWarehouseMock{}
+		warehouseHandler := handler.NewWarehouse(&svcMock)
+		server := getWarehouseServer(warehouseHandler)
+
+		svcMock.On("Dashboard", mock.Anything, 1, 7).Return(domain.WarehouseDashboard{}, warehouse.ErrNotFound)
+
+		url := fmt.Sprintf("%s/%d/dashboard", WAREHOUSE_URL, 1)
+		request, response := testutil.MakeRequest(http.MethodGet, url, nil)
+		server.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
 func getWarehouseServer(h *handler.Warehouse) *gin.Engine {
 	server := testutil.CreateServer()
 
@@ -386,8 +487,9 @@ func getWarehouseServer(h *handler.Warehouse) *gin.Engine {
 		warehouseRG.POST("", middleware.Body[domain.Warehouse](), h.Create())
 		warehouseRG.GET("", h.GetAll())
 		warehouseRG.GET("/:id", middleware.IntPathParam(), h.Get())
-		warehouseRG.PATCH("/:id", middleware.IntPathParam(), middleware.Body[domain.Warehouse](), h.Update())
+		warehouseRG.PATCH("/:id", middleware.IntPathParam(), middleware.Patch[domain.Warehouse](), h.Update())
 		warehouseRG.DELETE("/:id", middleware.IntPathParam(), h.Delete())
+		warehouseRG.GET("/:id/dashboard", middleware.IntPathParam(), h.Dashboard())
 	}
 
 	return server
@@ -407,6 +509,21 @@ func (r *ServiceWarehouseMock) GetAll(ctx context.Context) ([]domain.Warehouse,
 	return args.Get(0).([]domain.Warehouse), args.Error(1)
 }
 
+func (r *ServiceWarehouseMock) GetByIDs(ctx context.Context, ids []int) ([]domain.Warehouse, error) {
+	args := r.Called(ctx, ids)
+	return args.Get(0).([]domain.Warehouse), args.Error(1)
+}
+
+func (r *ServiceWarehouseMock) CreateBatch(ctx context.Context, items []domain.Warehouse) ([]domain.Warehouse, []error) {
+	args := r.Called(ctx, items)
+	return args.Get(0).([]domain.Warehouse), args.Get(1).([]error)
+}
+
+func (r *ServiceWarehouseMock) UpdateBatch(ctx context.Context, ids []int, items []domain.Warehouse) ([]domain.Warehouse, []error) {
+	args := r.Called(ctx, ids, items)
+	return args.Get(0).([]domain.Warehouse), args.Get(1).([]error)
+}
+
 func (r *ServiceWarehouseMock) Get(ctx context.Context, id int) (domain.Warehouse, error) {
 	args := r.Called(ctx, id)
 	return args.Get(0).(domain.Warehouse), args.Error(1)
@@ -427,7 +544,22 @@ func (r *ServiceWarehouseMock) Update(ctx context.Context, s domain.Warehouse) (
 	return args.Get(0).(domain.Warehouse), args.Error(1)
 }
 
+func (r *ServiceWarehouseMock) Patch(ctx context.Context, id int, p patch.Patch) (domain.Warehouse, error) {
+	args := r.Called(ctx, id, p)
+	return args.Get(0).(domain.Warehouse), args.Error(1)
+}
+
 func (r *ServiceWarehouseMock) Delete(ctx context.Context, id int) error {
 	args := r.Called(ctx, id)
 	return args.Error(0)
 }
+
+func (r *ServiceWarehouseMock) Restore(ctx context.Context, id int) (domain.Warehouse, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).(domain.Warehouse), args.Error(1)
+}
+
+func (r *ServiceWarehouseMock) Dashboard(ctx context.Context, id int, expiringWithinDays int) (domain.WarehouseDashboard, error) {
+	args := r.Called(ctx, id, expiringWithinDays)
+	return args.Get(0).(domain.WarehouseDashboard), args.Error(1)
+}
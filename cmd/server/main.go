@@ -2,6 +2,8 @@ package main
 
 import (
 	"database/sql"
+	"os"
+	"strings"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/cmd/server/routes"
 	"github.com/gin-gonic/gin"
@@ -16,6 +18,9 @@ func main() {
 	}
 
 	eng := gin.Default()
+	if err := eng.SetTrustedProxies(trustedProxies()); err != nil {
+		panic(err)
+	}
 	router := routes.NewRouter(eng, db)
 	router.MapRoutes()
 
@@ -23,3 +28,15 @@ func main() {
 		panic(err)
 	}
 }
+
+// trustedProxies returns the proxies listed in TRUSTED_PROXIES, separated
+// by commas. Only they can forward the IP of a client, which is the
+// remote address of the request otherwise, so that clients cannot forge
+// it to dodge rate limits.
+func trustedProxies() []string {
+	raw := os.Getenv("TRUSTED_PROXIES")
+	if raw == "" {
+		return nil
+	}
+	return strings.Split(raw, ",")
+}
@@ -2,6 +2,8 @@ package routes
 
 import (
 	"database/sql"
+	"os"
+	"time"
 
 	_ "github.com/extmatperez/meli_bootcamp_go_w2-4/docs"
 
@@ -11,13 +13,18 @@ import (
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/carrier"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/employee"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/idempotency"
 	inboundOrder "github.com/extmatperez/meli_bootcamp_go_w2-4/internal/inbound_order"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/localities"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/product"
+	producttype "github.com/extmatperez/meli_bootcamp_go_w2-4/internal/product_type"
 	purchaseorder "github.com/extmatperez/meli_bootcamp_go_w2-4/internal/purchase_order"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/section"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/seller"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/warehouse"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/cache"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/ratelimit"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -32,10 +39,88 @@ type router struct {
 	eng *gin.Engine
 	rg  *gin.RouterGroup
 	db  *sql.DB
+	// adminToken lets admins see and restore soft deleted rows.
+	// Nobody can if it is empty.
+	adminToken string
+	// idempotencyStore keeps the responses to creation requests sent
+	// with an Idempotency-Key.
+	idempotencyStore middleware.IdempotencyStore
+	// caches are shared by every repository that reads or writes
+	// the cached entities, so that writes anywhere invalidate them.
+	caches caches
+	// rateLimit limits the requests of each client to the API, and
+	// reportRateLimit those to the expensive report routes, on top.
+	rateLimit       gin.HandlerFunc
+	reportRateLimit gin.HandlerFunc
+}
+
+// Default request budgets of a client, which the RATE_LIMIT and
+// REPORT_RATE_LIMIT variables override, as in "100/1m".
+var (
+	defaultRateLimit       = ratelimit.Limit{Requests: 300, Period: time.Minute}
+	defaultReportRateLimit = ratelimit.Limit{Requests: 30, Period: time.Minute}
+)
+
+// limitFromEnv returns the limit set in the given variable, or def if
+// it is not set. It panics if the variable has an invalid limit.
+func limitFromEnv(name string, def ratelimit.Limit) ratelimit.Limit {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def
+	}
+	l, err := ratelimit.ParseLimit(raw)
+	if err != nil {
+		panic(name + ": " + err.Error())
+	}
+	return l
+}
+
+// Size and lifetime of the entries of the caches.
+const (
+	cacheSize = 1000
+	cacheTTL  = 5 * time.Minute
+)
+
+type caches struct {
+	products      cache.Cache[int, domain.Product]
+	sections      cache.Cache[int, domain.Section]
+	warehouses    cache.Cache[int, domain.Warehouse]
+	localities    cache.Cache[int, domain.Locality]
+	allLocalities cache.Cache[struct{}, []domain.Locality]
+}
+
+func newCaches() caches {
+	return caches{
+		products:      cache.NewLRU[int, domain.Product](cacheSize, cacheTTL),
+		sections:      cache.NewLRU[int, domain.Section](cacheSize, cacheTTL),
+		warehouses:    cache.NewLRU[int, domain.Warehouse](cacheSize, cacheTTL),
+		localities:    cache.NewLRU[int, domain.Locality](cacheSize, cacheTTL),
+		allLocalities: cache.NewLRU[struct{}, []domain.Locality](1, cacheTTL),
+	}
+}
+
+// byName returns the caches by the name their stats are reported with.
+func (c caches) byName() map[string]cache.StatsReporter {
+	return map[string]cache.StatsReporter{
+		"products":       c.products,
+		"sections":       c.sections,
+		"warehouses":     c.warehouses,
+		"localities":     c.localities,
+		"all_localities": c.allLocalities,
+	}
 }
 
 func NewRouter(eng *gin.Engine, db *sql.DB) Router {
-	return &router{eng: eng, db: db}
+	limits := ratelimit.NewMemoryStore()
+	return &router{
+		eng:              eng,
+		db:               db,
+		adminToken:       os.Getenv("ADMIN_TOKEN"),
+		idempotencyStore: idempotency.NewRepository(db),
+		caches:           newCaches(),
+		rateLimit:        middleware.RateLimit(limits, "api", limitFromEnv("RATE_LIMIT", defaultRateLimit)),
+		reportRateLimit:  middleware.RateLimit(limits, "reports", limitFromEnv("REPORT_RATE_LIMIT", defaultReportRateLimit)),
+	}
 }
 
 func (r *router) MapRoutes() {
@@ -43,6 +128,7 @@ func (r *router) MapRoutes() {
 	r.buildDocumentationRoutes()
 
 	r.buildSellerRoutes()
+	r.buildProductTypeRoutes()
 	r.buildProductRoutes()
 	r.buildSectionRoutes()
 	r.buildWarehouseRoutes()
@@ -53,6 +139,24 @@ func (r *router) MapRoutes() {
 	r.buildCarrierRoutes()
 	r.buildLocalityRoutes()
 	r.buildPurchaseOrderRoutes()
+	r.buildCacheRoutes()
+}
+
+func (r *router) buildCacheRoutes() {
+	h := handler.NewCache(r.caches.byName())
+	r.rg.GET("/cache/stats", h.Stats())
+}
+
+// productRepository returns a product repository that reads through
+// the shared product cache.
+func (r *router) productRepository() product.Repository {
+	return product.NewCachedRepository(product.NewRepository(r.db), r.caches.products)
+}
+
+// sectionRepository returns a section repository that reads through
+// the shared section cache.
+func (r *router) sectionRepository() section.Repository {
+	return section.NewCachedRepository(section.NewRepository(r.db), r.caches.sections)
 }
 
 func (r *router) buildDocumentationRoutes() {
@@ -60,71 +164,100 @@ func (r *router) buildDocumentationRoutes() {
 }
 
 func (r *router) setGroup() {
-	r.rg = r.eng.Group("/api/v1")
+	r.rg = r.eng.Group("/api/v1", r.rateLimit)
 }
 
 func (r *router) buildSellerRoutes() {
-	repo := seller.NewRepository(r.db)
+	repo := seller.NewPurgingRepository(seller.NewRepository(r.db), r.caches.products)
 	service := seller.NewService(repo)
 	handler := handler.NewSeller(service)
 
 	sellerGroup := r.rg.Group("/sellers")
 	{
-		sellerGroup.GET("/", handler.GetAll())
-		sellerGroup.GET("/:id", middleware.IntPathParam(), handler.Get())
-		sellerGroup.POST("/", middleware.Body[domain.Seller](), handler.Create())
-		sellerGroup.PATCH("/:id", middleware.IntPathParam(), middleware.Body[domain.Seller](), handler.Update())
-		sellerGroup.DELETE("/:id", middleware.IntPathParam(), handler.Delete())
+		sellerGroup.GET("/", middleware.IncludeDeleted(r.adminToken), handler.GetAll())
+		sellerGroup.GET("/:id", middleware.IntPathParam(), middleware.IncludeDeleted(r.adminToken), handler.Get())
+		sellerGroup.POST("/", middleware.Idempotent(r.idempotencyStore), middleware.Body[domain.Seller](), handler.Create())
+		sellerGroup.POST("/batch", middleware.Idempotent(r.idempotencyStore), middleware.Body[web.BatchRequest[domain.Seller, domain.Seller]](), handler.Batch())
+		sellerGroup.PATCH("/:id", middleware.IntPathParam(), middleware.IfMatch(), middleware.Patch[domain.Seller](), handler.Update())
+		sellerGroup.DELETE("/:id", middleware.IntPathParam(), middleware.IfMatch(), handler.Delete())
+		sellerGroup.POST("/:id/restore", middleware.AdminOnly(r.adminToken), middleware.IntPathParam(), handler.Restore())
+		sellerGroup.GET("/:id/products", middleware.IntPathParam(), handler.Products())
+		sellerGroup.GET("/:id/performance", r.reportRateLimit, middleware.IntPathParam(), handler.Performance())
+	}
+}
+
+func (r *router) buildProductTypeRoutes() {
+	repo := producttype.NewRepository(r.db)
+	service := producttype.NewService(repo)
+	h := handler.NewProductType(service)
+
+	rg := r.rg.Group("/product-types")
+	{
+		rg.POST("", middleware.Idempotent(r.idempotencyStore), middleware.Body[handler.ProductTypeRequest](), h.Create())
+		rg.GET("", h.GetAll())
+		rg.GET("/:id", middleware.IntPathParam(), h.Get())
+		rg.PATCH("/:id", middleware.IntPathParam(), middleware.IfMatch(), middleware.Body[handler.ProductTypeRequest](), h.Update())
+		rg.DELETE("/:id", middleware.IntPathParam(), middleware.IfMatch(), h.Delete())
 	}
 }
 
 func (r *router) buildProductRoutes() {
-	repo := product.NewRepository(r.db)
-	service := product.NewService(repo)
+	repo := r.productRepository()
+	service := product.NewService(repo, producttype.NewRepository(r.db))
 	h := handler.NewProduct(service)
 
-	r.rg.POST("/product-records/", middleware.Body[handler.CreateRequestRecord](), h.CreateRecord())
+	r.rg.POST("/product-records/", middleware.Idempotent(r.idempotencyStore), middleware.Body[handler.CreateRequestRecord](), h.CreateRecord())
 	productRG := r.rg.Group("/products")
 	{
-		productRG.POST("/", middleware.Body[handler.CreateRequest](), h.Create())
-		productRG.GET("/", h.GetAll())
-		productRG.GET("/:id", middleware.IntPathParam(), h.Get())
-		productRG.PATCH("/:id", middleware.IntPathParam(), middleware.Body[handler.UpdateRequest](), h.Update())
-		productRG.DELETE("/:id", middleware.IntPathParam(), h.Delete())
-		productRG.GET("/report-records", h.GetRecords())
-		productRG.GET("/report-records/:id", middleware.IntPathParam(), h.GetRecords())
+		productRG.POST("/", middleware.Idempotent(r.idempotencyStore), middleware.Body[handler.CreateRequest](), h.Create())
+		productRG.POST("/batch", middleware.Idempotent(r.idempotencyStore), middleware.Body[web.BatchRequest[handler.CreateRequest, handler.UpdateRequest]](), h.Batch())
+		productRG.GET("/", middleware.IncludeDeleted(r.adminToken), h.GetAll())
+		productRG.GET("/search", h.Search())
+		productRG.GET("/:id", middleware.IntPathParam(), middleware.IncludeDeleted(r.adminToken), h.Get())
+		productRG.PATCH("/:id", middleware.IntPathParam(), middleware.IfMatch(), middleware.Body[handler.UpdateRequest](), h.Update())
+		productRG.DELETE("/:id", middleware.IntPathParam(), middleware.IfMatch(), h.Delete())
+		productRG.POST("/:id/restore", middleware.AdminOnly(r.adminToken), middleware.IntPathParam(), h.Restore())
+		productRG.GET("/report-records", r.reportRateLimit, h.GetRecords())
+		productRG.GET("/report-records/:id", r.reportRateLimit, middleware.IntPathParam(), h.GetRecords())
+		productRG.GET("/:id/price-history", middleware.IntPathParam(), h.PriceHistory())
+		productRG.GET("/:id/price", middleware.IntPathParam(), h.EffectivePrice())
 	}
 }
 
 func (r *router) buildSectionRoutes() {
-	repository := section.NewRepository(r.db)
-	service := section.NewService(repository)
+	repository := r.sectionRepository()
+	service := section.NewService(repository, producttype.NewRepository(r.db))
 	h := handler.NewSection(service)
 
 	sec := r.rg.Group("/sections")
 	{
-		sec.POST("", middleware.Body[section.CreateSection](), h.Create())
-		sec.GET("", h.GetAll())
-		sec.GET("/:id", middleware.IntPathParam(), h.Get())
-		sec.DELETE("/:id", middleware.IntPathParam(), h.Delete())
-		sec.PATCH("/:id", middleware.IntPathParam(), middleware.Body[section.UpdateSection](), h.Update())
-		sec.GET("/report-products", h.GetAllReportProducts())
-		sec.GET("/report-products/:id", middleware.IntPathParam(), h.GetReportProducts())
+		sec.POST("", middleware.Idempotent(r.idempotencyStore), middleware.Body[section.CreateSection](), h.Create())
+		sec.POST("/batch", middleware.Idempotent(r.idempotencyStore), middleware.Body[web.BatchRequest[section.CreateSection, section.UpdateSection]](), h.Batch())
+		sec.GET("", middleware.IncludeDeleted(r.adminToken), h.GetAll())
+		sec.GET("/:id", middleware.IntPathParam(), middleware.IncludeDeleted(r.adminToken), h.Get())
+		sec.DELETE("/:id", middleware.IntPathParam(), middleware.IfMatch(), h.Delete())
+		sec.POST("/:id/restore", middleware.AdminOnly(r.adminToken), middleware.IntPathParam(), h.Restore())
+		sec.PATCH("/:id", middleware.IntPathParam(), middleware.IfMatch(), middleware.Body[section.UpdateSection](), h.Update())
+		sec.GET("/report-products", r.reportRateLimit, h.GetAllReportProducts())
+		sec.GET("/report-products/:id", r.reportRateLimit, middleware.IntPathParam(), h.GetReportProducts())
 	}
 }
 
 func (r *router) buildWarehouseRoutes() {
-	repo := warehouse.NewRepository(r.db)
+	repo := warehouse.NewCachedRepository(warehouse.NewRepository(r.db), r.caches.warehouses)
 	service := warehouse.NewService(repo)
 	h := handler.NewWarehouse(service)
 
 	rg := r.rg.Group("/warehouses")
 	{
-		rg.POST("", middleware.Body[domain.Warehouse](), h.Create())
-		rg.GET("", h.GetAll())
-		rg.GET("/:id", middleware.IntPathParam(), h.Get())
-		rg.PATCH("/:id", middleware.IntPathParam(), middleware.Body[domain.Warehouse](), h.Update())
-		rg.DELETE("/:id", middleware.IntPathParam(), h.Delete())
+		rg.POST("", middleware.Idempotent(r.idempotencyStore), middleware.Body[domain.Warehouse](), h.Create())
+		rg.POST("/batch", middleware.Idempotent(r.idempotencyStore), middleware.Body[web.BatchRequest[domain.Warehouse, domain.Warehouse]](), h.Batch())
+		rg.GET("", middleware.IncludeDeleted(r.adminToken), h.GetAll())
+		rg.GET("/:id", middleware.IntPathParam(), middleware.IncludeDeleted(r.adminToken), h.Get())
+		rg.PATCH("/:id", middleware.IntPathParam(), middleware.IfMatch(), middleware.Patch[domain.Warehouse](), h.Update())
+		rg.DELETE("/:id", middleware.IntPathParam(), middleware.IfMatch(), h.Delete())
+		rg.POST("/:id/restore", middleware.AdminOnly(r.adminToken), middleware.IntPathParam(), h.Restore())
+		rg.GET("/:id/dashboard", r.reportRateLimit, middleware.IntPathParam(), h.Dashboard())
 	}
 }
 
@@ -135,13 +268,16 @@ func (r *router) buildEmployeeRoutes() {
 
 	employeeRG := r.rg.Group("/employees")
 	{
-		employeeRG.GET("", h.GetAll())
-		employeeRG.POST("", middleware.Body[domain.Employee](), h.Create())
-		employeeRG.GET("/:id", middleware.IntPathParam(), h.Get())
-		employeeRG.GET("/report-inbound-orders/:id", middleware.IntPathParam(), h.GetInboundReport())
-		employeeRG.GET("/report-inbound-orders/", h.GetInboundReport())
-		employeeRG.PATCH("/:id", middleware.IntPathParam(), middleware.Body[domain.Employee](), h.Update())
-		employeeRG.DELETE("/:id", middleware.IntPathParam(), h.Delete())
+		employeeRG.GET("", middleware.IncludeDeleted(r.adminToken), h.GetAll())
+		employeeRG.POST("", middleware.Idempotent(r.idempotencyStore), middleware.Body[domain.Employee](), h.Create())
+		employeeRG.GET("/:id", middleware.IntPathParam(), middleware.IncludeDeleted(r.adminToken), h.Get())
+		employeeRG.GET("/report-inbound-orders/:id", r.reportRateLimit, middleware.IntPathParam(), h.GetInboundReport())
+		employeeRG.GET("/report-inbound-orders/", r.reportRateLimit, h.GetInboundReport())
+		employeeRG.PATCH("/:id", middleware.IntPathParam(), middleware.IfMatch(), middleware.Body[domain.Employee](), h.Update())
+		employeeRG.DELETE("/:id", middleware.IntPathParam(), middleware.IfMatch(), h.Delete())
+		employeeRG.POST("/:id/restore", middleware.AdminOnly(r.adminToken), middleware.IntPathParam(), h.Restore())
+		employeeRG.POST("/:id/transfers", middleware.Idempotent(r.idempotencyStore), middleware.IntPathParam(), middleware.Body[handler.TransferRequest](), h.Transfer())
+		employeeRG.GET("/:id/assignments", middleware.IntPathParam(), h.Assignments())
 	}
 }
 
@@ -152,24 +288,35 @@ func (r *router) buildBuyerRoutes() {
 
 	buyerRG := r.rg.Group("/buyers")
 	{
-		buyerRG.GET("", h.GetAll())
-		buyerRG.POST("", middleware.Body[domain.BuyerCreate](), h.Create())
-		buyerRG.GET("/:id", middleware.IntPathParam(), h.Get())
-		buyerRG.GET("/report-purchase-orders/:id", middleware.IntPathParam(), h.PurchaseOrderReport())
-		buyerRG.GET("/report-purchase-orders/", h.PurchaseOrderReport())
-		buyerRG.DELETE("/:id", middleware.IntPathParam(), h.Delete())
-		buyerRG.PATCH("/:id", middleware.IntPathParam(), middleware.Body[domain.Buyer](), h.Update())
+		buyerRG.GET("", middleware.IncludeDeleted(r.adminToken), h.GetAll())
+		buyerRG.POST("", middleware.Idempotent(r.idempotencyStore), middleware.Body[domain.BuyerCreate](), h.Create())
+		buyerRG.POST("/batch", middleware.Idempotent(r.idempotencyStore), middleware.Body[web.BatchRequest[domain.BuyerCreate, domain.Buyer]](), h.Batch())
+		buyerRG.GET("/:id", middleware.IntPathParam(), middleware.IncludeDeleted(r.adminToken), h.Get())
+		buyerRG.GET("/report-purchase-orders/:id", r.reportRateLimit, middleware.IntPathParam(), h.PurchaseOrderReport())
+		buyerRG.GET("/report-purchase-orders/", r.reportRateLimit, h.PurchaseOrderReport())
+		buyerRG.GET("/report-spending/:id", r.reportRateLimit, middleware.IntPathParam(), h.SpendingReport())
+		buyerRG.GET("/report-spending/", r.reportRateLimit, h.SpendingReport())
+		buyerRG.GET("/:id/purchase-orders", middleware.IntPathParam(), h.PurchaseOrders())
+		buyerRG.DELETE("/:id", middleware.IntPathParam(), middleware.IfMatch(), h.Delete())
+		buyerRG.POST("/:id/restore", middleware.AdminOnly(r.adminToken), middleware.IntPathParam(), h.Restore())
+		buyerRG.PATCH("/:id", middleware.IntPathParam(), middleware.IfMatch(), middleware.Body[domain.Buyer](), h.Update())
 	}
 }
 
 func (r *router) buildBatchRoutes() {
 	repo := batches.NewRepository(r.db)
-	service := batches.NewService(repo)
+	service := batches.NewService(repo, r.sectionRepository(), r.productRepository())
 	h := handler.NewBatches(service)
 
 	batchRG := r.rg.Group("/product-batches")
 	{
-		batchRG.POST("", middleware.Body[handler.CreateBatchesRequest](), h.Create())
+		batchRG.POST("", middleware.Idempotent(r.idempotencyStore), middleware.Body[handler.CreateBatchesRequest](), h.Create())
+		batchRG.GET("", h.GetAll())
+		batchRG.GET("/:id", middleware.IntPathParam(), h.Get())
+		batchRG.PATCH("/:id", middleware.IntPathParam(), middleware.IfMatch(), middleware.Body[handler.BatchUpdateRequest](), h.Update())
+		batchRG.POST("/:id/move", middleware.IntPathParam(), middleware.IfMatch(), middleware.Body[handler.BatchMoveRequest](), h.Move())
+		batchRG.POST("/:id/dispose", middleware.IntPathParam(), middleware.IfMatch(), middleware.Body[handler.BatchDisposeRequest](), h.Dispose())
+		batchRG.GET("/:id/history", middleware.IntPathParam(), h.History())
 	}
 }
 
@@ -180,7 +327,10 @@ func (r *router) buildInboundOrderRoutes() {
 
 	buyerRG := r.rg.Group("/inbound-orders")
 	{
-		buyerRG.POST("", middleware.Body[handler.InboundOrderRequest](), h.Create())
+		buyerRG.POST("", middleware.Idempotent(r.idempotencyStore), middleware.Body[handler.InboundOrderRequest](), h.Create())
+		buyerRG.GET("", h.GetAll())
+		buyerRG.GET("/report-receiving", r.reportRateLimit, h.ReceivingReport())
+		buyerRG.GET("/:id", middleware.IntPathParam(), h.Get())
 	}
 }
 
@@ -189,34 +339,78 @@ func (r *router) buildCarrierRoutes() {
 	service := carrier.NewService(repo)
 	h := handler.NewCarrier(service)
 
-	productRG := r.rg.Group("/carrier")
+	carrierRG := r.rg.Group("/carriers")
+	{
+		carrierRG.POST("", middleware.Idempotent(r.idempotencyStore), middleware.Body[handler.CarrierRequest](), h.Create())
+		carrierRG.GET("", middleware.IncludeDeleted(r.adminToken), h.GetAll())
+		carrierRG.GET("/:id", middleware.IntPathParam(), middleware.IncludeDeleted(r.adminToken), h.Get())
+		carrierRG.PATCH("/:id", middleware.IntPathParam(), middleware.IfMatch(), middleware.Body[handler.CarrierUpdateRequest](), h.Update())
+		carrierRG.DELETE("/:id", middleware.IntPathParam(), middleware.IfMatch(), h.Delete())
+		carrierRG.POST("/:id/restore", middleware.AdminOnly(r.adminToken), middleware.IntPathParam(), h.Restore())
+	}
+
+	// Deprecated: kept for clients of the singular path.
+	legacyRG := r.rg.Group("/carrier", middleware.Deprecated("/api/v1/carriers"))
 	{
-		productRG.POST("/", middleware.Body[handler.CarrierRequest](), h.Create())
+		legacyRG.POST("/", middleware.Idempotent(r.idempotencyStore), middleware.Body[handler.CarrierRequest](), h.Create())
 	}
 }
 
 func (r *router) buildLocalityRoutes() {
-	repo := localities.NewRepository(r.db)
+	repo := localities.NewCachedRepository(localities.NewRepository(r.db), r.caches.localities, r.caches.allLocalities)
 	service := localities.NewService(repo)
 	h := handler.NewLocality(service)
 
 	rg := r.rg.Group("/localities")
 	{
-		rg.POST("", middleware.Body[localities.CreateDTO](), h.Create())
-		rg.GET("/report-sellers", h.SellerReport())
-		rg.GET("/report-sellers/:id", middleware.IntPathParam(), h.SellerReport())
-		rg.GET("/report-carriers", h.CarrierReport())
-		rg.GET("/report-carriers/:id", middleware.IntPathParam(), h.CarrierReport())
+		rg.POST("", middleware.Idempotent(r.idempotencyStore), middleware.Body[localities.CreateDTO](), h.Create())
+		rg.GET("/report-sellers", r.reportRateLimit, h.SellerReport())
+		rg.GET("/report-sellers/:id", r.reportRateLimit, middleware.IntPathParam(), h.SellerReport())
+		rg.GET("/report-carriers", r.reportRateLimit, h.CarrierReport())
+		rg.GET("/report-carriers/:id", r.reportRateLimit, middleware.IntPathParam(), h.CarrierReport())
+		rg.GET("/report-warehouses", r.reportRateLimit, h.WarehouseReport())
+		rg.GET("/report-warehouses/:id", r.reportRateLimit, middleware.IntPathParam(), h.WarehouseReport())
+		rg.GET("/report-stock", r.reportRateLimit, h.StockReport())
+		rg.GET("/report-stock/:id", r.reportRateLimit, middleware.IntPathParam(), h.StockReport())
+		rg.GET("/:id", middleware.IntPathParam(), h.Get())
+		rg.PATCH("/:id", middleware.IntPathParam(), middleware.IfMatch(), middleware.Body[handler.LocalityUpdateRequest](), h.Update())
+		rg.DELETE("/:id", middleware.IntPathParam(), middleware.IfMatch(), h.Delete())
+	}
+
+	countryHandler := handler.NewCountry(service)
+	countryRG := r.rg.Group("/countries")
+	{
+		countryRG.GET("", countryHandler.GetAll())
+		countryRG.POST("", middleware.Idempotent(r.idempotencyStore), middleware.Body[handler.CountryRequest](), countryHandler.Create())
+		countryRG.GET("/:id", middleware.IntPathParam(), countryHandler.Get())
+		countryRG.PATCH("/:id", middleware.IntPathParam(), middleware.IfMatch(), middleware.Body[handler.CountryRequest](), countryHandler.Update())
+		countryRG.DELETE("/:id", middleware.IntPathParam(), middleware.IfMatch(), countryHandler.Delete())
+		countryRG.GET("/:id/provinces", middleware.IntPathParam(), countryHandler.GetProvinces())
+		countryRG.POST("/:id/provinces", middleware.Idempotent(r.idempotencyStore), middleware.IntPathParam(), middleware.Body[handler.ProvinceRequest](), countryHandler.CreateProvince())
+	}
+
+	provinceHandler := handler.NewProvince(service)
+	provinceRG := r.rg.Group("/provinces")
+	{
+		provinceRG.GET("/:id", middleware.IntPathParam(), provinceHandler.Get())
+		provinceRG.PATCH("/:id", middleware.IntPathParam(), middleware.IfMatch(), middleware.Body[handler.ProvinceRequest](), provinceHandler.Update())
+		provinceRG.DELETE("/:id", middleware.IntPathParam(), middleware.IfMatch(), provinceHandler.Delete())
+		provinceRG.GET("/:id/localities", middleware.IntPathParam(), provinceHandler.GetLocalities())
+		provinceRG.POST("/:id/localities", middleware.Idempotent(r.idempotencyStore), middleware.IntPathParam(), middleware.Body[handler.LocalityRequest](), provinceHandler.CreateLocality())
 	}
 }
 
 func (r *router) buildPurchaseOrderRoutes() {
 	repo := purchaseorder.NewRepository(r.db)
-	service := purchaseorder.NewService(repo)
+	service := purchaseorder.NewService(repo, r.productRepository(), carrier.NewRepository(r.db))
 	h := handler.NewPurchaseOrder(service)
 
 	purchaseOrderRG := r.rg.Group("/purchase-orders")
 	{
-		purchaseOrderRG.POST("", middleware.Body[handler.PurchaseOrderRequest](), h.Create())
+		purchaseOrderRG.POST("", middleware.Idempotent(r.idempotencyStore), middleware.Body[handler.PurchaseOrderRequest](), h.Create())
+		purchaseOrderRG.POST("/:id/dispatch", middleware.IntPathParam(), middleware.Body[handler.DispatchRequest](), h.Dispatch())
+		purchaseOrderRG.GET("/:id/carrier-recommendations", middleware.IntPathParam(), h.RecommendCarriers())
+		purchaseOrderRG.GET("/track/:tracking_code", h.Track())
+		purchaseOrderRG.POST("/track/:tracking_code/events", middleware.Idempotent(r.idempotencyStore), middleware.Body[handler.ShipmentEventRequest](), h.RecordShipmentEvent())
 	}
 }
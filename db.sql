@@ -24,6 +24,7 @@ USE `melisprint` ;
 CREATE TABLE IF NOT EXISTS `melisprint`.`countries` (
   `id` INT NOT NULL AUTO_INCREMENT,
   `country_name` VARCHAR(255) NOT NULL,
+  `version` INT UNSIGNED NOT NULL DEFAULT 1,
   CONSTRAINT `country_name_UNIQUE` UNIQUE (`country_name`),
   PRIMARY KEY (`id`))
 ENGINE = InnoDB;
@@ -36,6 +37,7 @@ CREATE TABLE IF NOT EXISTS `melisprint`.`provinces` (
   `id` INT NOT NULL AUTO_INCREMENT,
   `province_name` VARCHAR(255) NOT NULL,
   `country_id` INT NOT NULL,
+  `version` INT UNSIGNED NOT NULL DEFAULT 1,
   PRIMARY KEY (`id`),
   INDEX `country_id_idx` (`country_id` ASC) VISIBLE,
   CONSTRAINT `province_UNIQUE` UNIQUE (`province_name`, `country_id`),
@@ -54,6 +56,7 @@ CREATE TABLE IF NOT EXISTS `melisprint`.`localities` (
   `id` INT NOT NULL AUTO_INCREMENT,
   `locality_name` VARCHAR(255) NOT NULL,
   `province_id` INT NOT NULL,
+  `version` INT UNSIGNED NOT NULL DEFAULT 1,
   PRIMARY KEY (`id`),
   INDEX `province_id_idx` (`province_id` ASC) VISIBLE,
   CONSTRAINT `locality_UNIQUE` UNIQUE (`locality_name`, `province_id`),
@@ -75,6 +78,8 @@ CREATE TABLE IF NOT EXISTS `melisprint`.`sellers` (
   `address` VARCHAR(255) NOT NULL,
   `telephone` VARCHAR(255) NOT NULL,
   `locality_id` INT NOT NULL,
+  `version` INT UNSIGNED NOT NULL DEFAULT 1,
+  `deleted_at` DATETIME(6) NULL,
   PRIMARY KEY (`id`),
   INDEX `locality_id_idx` (`locality_id` ASC) VISIBLE,
   UNIQUE INDEX `cid_UNIQUE` (`cid` ASC) VISIBLE,
@@ -92,6 +97,7 @@ ENGINE = InnoDB;
 CREATE TABLE IF NOT EXISTS `melisprint`.`product_types` (
   `id` INT NOT NULL AUTO_INCREMENT,
   `description` VARCHAR(255) NOT NULL,
+  `version` INT UNSIGNED NOT NULL DEFAULT 1,
   PRIMARY KEY (`id`))
 ENGINE = InnoDB;
 
@@ -112,10 +118,13 @@ CREATE TABLE IF NOT EXISTS `melisprint`.`products` (
   `freezing_rate` int NOT NULL,
   `product_type_id` INT NOT NULL,
   `seller_id` INT NULL,
+  `version` INT UNSIGNED NOT NULL DEFAULT 1,
+  `deleted_at` DATETIME(6) NULL,
   PRIMARY KEY (`id`),
   INDEX `seller_id_idx` (`seller_id` ASC) VISIBLE,
   INDEX `product_type_id_idx` (`product_type_id` ASC) VISIBLE,
   UNIQUE INDEX `product_code_UNIQUE` (`product_code` ASC) VISIBLE,
+  FULLTEXT INDEX `ft_products_search` (`description`, `product_code`),
   CONSTRAINT `fk_seller_products`
     FOREIGN KEY (`seller_id`)
     REFERENCES `melisprint`.`sellers` (`id`)
@@ -140,6 +149,8 @@ CREATE TABLE IF NOT EXISTS `melisprint`.`warehouses` (
   `minimum_capacity` INT NOT NULL,
   `minimum_temperature` DECIMAL(19,2) NOT NULL,
   `locality_id` INT NOT NULL,
+  `version` INT UNSIGNED NOT NULL DEFAULT 1,
+  `deleted_at` DATETIME(6) NULL,
   PRIMARY KEY (`id`),
   INDEX `locality_id_idx` (`locality_id` ASC) VISIBLE,
   UNIQUE INDEX `warehouse_code_UNIQUE` (`warehouse_code` ASC) VISIBLE,
@@ -164,6 +175,8 @@ CREATE TABLE IF NOT EXISTS `melisprint`.`sections` (
   `maximum_capacity` INT NOT NULL,
   `warehouse_id` INT NOT NULL,
   `product_type_id` INT NOT NULL,
+  `version` INT UNSIGNED NOT NULL DEFAULT 1,
+  `deleted_at` DATETIME(6) NULL,
   PRIMARY KEY (`id`),
   INDEX `product_type_id_idx` (`product_type_id` ASC) VISIBLE,
   INDEX `warehouse_id_idx` (`warehouse_id` ASC) VISIBLE,
@@ -196,6 +209,7 @@ CREATE TABLE IF NOT EXISTS `melisprint`.`product_batches` (
   `minimum_temperature` DECIMAL(19,2) NOT NULL,
   `product_id` INT NOT NULL,
   `section_id` INT NOT NULL,
+  `version` INT UNSIGNED NOT NULL DEFAULT 1,
   PRIMARY KEY (`id`),
   INDEX `product_id_idx` (`product_id` ASC) VISIBLE,
   INDEX `section_id_idx` (`section_id` ASC) VISIBLE,
@@ -240,6 +254,8 @@ CREATE TABLE IF NOT EXISTS `melisprint`.`buyers` (
   `card_number_id` VARCHAR(255) NOT NULL,
   `first_name` VARCHAR(255) NOT NULL,
   `last_name` VARCHAR(255) NOT NULL,
+  `version` INT UNSIGNED NOT NULL DEFAULT 1,
+  `deleted_at` DATETIME(6) NULL,
   PRIMARY KEY (`id`),
   UNIQUE INDEX `card_number_id_UNIQUE` (`card_number_id` ASC) VISIBLE)
 ENGINE = InnoDB;
@@ -255,6 +271,8 @@ CREATE TABLE IF NOT EXISTS `melisprint`.`carriers` (
   `address` VARCHAR(255) NOT NULL,
   `telephone` VARCHAR(255) NOT NULL,
   `locality_id` INT NOT NULL,
+  `version` INT UNSIGNED NOT NULL DEFAULT 1,
+  `deleted_at` DATETIME(6) NULL,
   PRIMARY KEY (`id`),
   CONSTRAINT `cid` UNIQUE (`cid`),
   INDEX `locality_id_idx` (`locality_id` ASC) VISIBLE,
@@ -296,6 +314,7 @@ CREATE TABLE IF NOT EXISTS `melisprint`.`purchase_orders` (
   INDEX `warehouse_id_idx` (`warehouse_id` ASC) VISIBLE,
   INDEX `fk_product_record_orders_idx` (`product_record_id` ASC) VISIBLE,
   CONSTRAINT `order_number` UNIQUE (`order_number`),
+  CONSTRAINT `tracking_code` UNIQUE (`tracking_code`),
   CONSTRAINT `fk_buyer_purchase_orders`
     FOREIGN KEY (`buyer_id`)
     REFERENCES `melisprint`.`buyers` (`id`)
@@ -359,6 +378,8 @@ CREATE TABLE IF NOT EXISTS `melisprint`.`employees` (
   `first_name` VARCHAR(255) NOT NULL,
   `last_name` VARCHAR(255) NOT NULL,
   `warehouse_id` INT NOT NULL,
+  `version` INT UNSIGNED NOT NULL DEFAULT 1,
+  `deleted_at` DATETIME(6) NULL,
   PRIMARY KEY (`id`),
   INDEX `warehouse_id_idx` (`warehouse_id` ASC) VISIBLE,
   UNIQUE INDEX `card_number_id_UNIQUE` (`card_number_id` ASC) VISIBLE,
@@ -370,6 +391,31 @@ CREATE TABLE IF NOT EXISTS `melisprint`.`employees` (
 ENGINE = InnoDB;
 
 
+-- -----------------------------------------------------
+-- Table `melisprint`.`employee_assignments`
+-- -----------------------------------------------------
+CREATE TABLE IF NOT EXISTS `melisprint`.`employee_assignments` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `employee_id` INT NOT NULL,
+  `warehouse_id` INT NOT NULL,
+  `start_date` DATETIME(6) NULL,
+  `end_date` DATETIME(6) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `employee_id_idx` (`employee_id` ASC) VISIBLE,
+  INDEX `warehouse_id_idx` (`warehouse_id` ASC) VISIBLE,
+  CONSTRAINT `fk_employee_employee_assignments`
+    FOREIGN KEY (`employee_id`)
+    REFERENCES `melisprint`.`employees` (`id`)
+    ON DELETE CASCADE
+    ON UPDATE NO ACTION,
+  CONSTRAINT `fk_warehouse_employee_assignments`
+    FOREIGN KEY (`warehouse_id`)
+    REFERENCES `melisprint`.`warehouses` (`id`)
+    ON DELETE NO ACTION
+    ON UPDATE NO ACTION)
+ENGINE = InnoDB;
+
+
 -- -----------------------------------------------------
 -- Table `melisprint`.`inbound_orders`
 -- -----------------------------------------------------
@@ -403,6 +449,82 @@ CREATE TABLE IF NOT EXISTS `melisprint`.`inbound_orders` (
 ENGINE = InnoDB;
 
 
+-- -----------------------------------------------------
+-- Table `melisprint`.`batch_movements`
+-- -----------------------------------------------------
+CREATE TABLE IF NOT EXISTS `melisprint`.`batch_movements` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `product_batch_id` INT NOT NULL,
+  `movement_type` VARCHAR(255) NOT NULL,
+  `quantity` INT NOT NULL,
+  `section_id` INT NOT NULL,
+  `inbound_order_id` INT NULL,
+  `from_section_id` INT NULL,
+  `temperature` INT NULL,
+  `reason` VARCHAR(255) NULL,
+  `created_at` DATETIME(6) NOT NULL,
+  PRIMARY KEY (`id`),
+  INDEX `product_batch_id_idx` (`product_batch_id` ASC) VISIBLE,
+  INDEX `section_id_idx` (`section_id` ASC) VISIBLE,
+  INDEX `inbound_order_id_idx` (`inbound_order_id` ASC) VISIBLE,
+  INDEX `from_section_id_idx` (`from_section_id` ASC) VISIBLE,
+  CONSTRAINT `fk_product_batch_batch_movements`
+    FOREIGN KEY (`product_batch_id`)
+    REFERENCES `melisprint`.`product_batches` (`id`)
+    ON DELETE CASCADE
+    ON UPDATE NO ACTION,
+  CONSTRAINT `fk_section_batch_movements`
+    FOREIGN KEY (`section_id`)
+    REFERENCES `melisprint`.`sections` (`id`)
+    ON DELETE NO ACTION
+    ON UPDATE NO ACTION,
+  CONSTRAINT `fk_inbound_order_batch_movements`
+    FOREIGN KEY (`inbound_order_id`)
+    REFERENCES `melisprint`.`inbound_orders` (`id`)
+    ON DELETE NO ACTION
+    ON UPDATE NO ACTION,
+  CONSTRAINT `fk_from_section_batch_movements`
+    FOREIGN KEY (`from_section_id`)
+    REFERENCES `melisprint`.`sections` (`id`)
+    ON DELETE NO ACTION
+    ON UPDATE NO ACTION)
+ENGINE = InnoDB;
+
+
+-- -----------------------------------------------------
+-- Table `melisprint`.`shipment_events`
+-- -----------------------------------------------------
+CREATE TABLE IF NOT EXISTS `melisprint`.`shipment_events` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `purchase_order_id` INT NOT NULL,
+  `status` VARCHAR(255) NOT NULL,
+  `created_at` DATETIME(6) NOT NULL,
+  PRIMARY KEY (`id`),
+  INDEX `purchase_order_id_idx` (`purchase_order_id` ASC) VISIBLE,
+  CONSTRAINT `shipment_status` UNIQUE (`purchase_order_id`, `status`),
+  CONSTRAINT `fk_purchase_order_shipment_events`
+    FOREIGN KEY (`purchase_order_id`)
+    REFERENCES `melisprint`.`purchase_orders` (`id`)
+    ON DELETE CASCADE
+    ON UPDATE NO ACTION)
+ENGINE = InnoDB;
+
+
+-- -----------------------------------------------------
+-- Table `melisprint`.`idempotency_keys`
+-- -----------------------------------------------------
+CREATE TABLE IF NOT EXISTS `melisprint`.`idempotency_keys` (
+  `scope` VARCHAR(255) NOT NULL,
+  `idempotency_key` VARCHAR(255) NOT NULL,
+  `request_hash` CHAR(64) NOT NULL,
+  `status` INT NULL,
+  `content_type` VARCHAR(255) NULL,
+  `body` MEDIUMBLOB NULL,
+  `created_at` DATETIME(6) NOT NULL,
+  PRIMARY KEY (`scope`, `idempotency_key`))
+ENGINE = InnoDB;
+
+
 -- -----------------------------------------------------
 -- Table `melisprint`.`roles`
 -- -----------------------------------------------------
@@ -515,6 +637,8 @@ INSERT INTO `melisprint`.`purchase_orders` (`order_number`, `order_date`, `track
 INSERT INTO `melisprint`.`order_details` (`clean_liness_status`, `quantity`, `temperature`, `product_record_id`, `purchase_order_id`) VALUES ('Clean', 10, -18, 1, 1);
 INSERT INTO `melisprint`.`order_details` (`clean_liness_status`, `quantity`, `temperature`, `product_record_id`, `purchase_order_id`) VALUES ('Not clean', 20, -15, 2, 2);
 
+INSERT INTO `melisprint`.`shipment_events` (`purchase_order_id`, `status`, `created_at`) VALUES (1, 'picked', '2023-07-01 12:00:00');
+
 INSERT INTO `melisprint`.`employees` (`card_number_id`, `first_name`, `last_name`, `warehouse_id`) VALUES ('123456', 'John', 'Smith', 1);
 INSERT INTO `melisprint`.`employees` (`card_number_id`, `first_name`, `last_name`, `warehouse_id`) VALUES ('654321', 'Jane', 'Doe', 2);
 
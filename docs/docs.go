@@ -37,6 +37,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/handler.CreateBatchesRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of the request return its first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -58,6 +64,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
+                    "422": {
+                        "description": "Idempotency-Key reused for a different request",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Failed to create batch",
                         "schema": {
@@ -69,11 +81,31 @@ const docTemplate = `{
         },
         "/api/v1/buyers": {
             "get": {
-                "description": "Get all buyers",
+                "description": "Get all buyers, or only those with the given ` + "`" + `ids` + "`" + `. Admins can include deleted ones with ` + "`" + `include_deleted=true` + "`" + `.",
                 "tags": [
                     "Buyers"
                 ],
                 "summary": "Get all buyers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Comma separated ids of the buyers to retrieve",
+                        "name": "ids",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include deleted buyers (admin only)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -90,6 +122,18 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "400": {
+                        "description": "include_deleted should be a boolean, or invalid ids",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "include_deleted is restricted to admins",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Buyer not found",
                         "schema": {
@@ -116,6 +160,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/domain.BuyerCreate"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of the request return its first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -125,6 +175,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/domain.Buyer"
                         }
                     },
+                    "409": {
+                        "description": "Idempotency-Key request still in progress",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
                     "422": {
                         "description": "Buyer not created",
                         "schema": {
@@ -134,6 +190,82 @@ const docTemplate = `{
                 }
             }
         },
+        "/api/v1/buyers/batch": {
+            "post": {
+                "description": "Creates the buyers in ` + "`" + `create` + "`" + ` and updates those in ` + "`" + `update` + "`" + `, which are sent with their ` + "`" + `id` + "`" + `, like the update endpoint does. Each buyer gets its own status, in the order they were sent.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Buyers"
+                ],
+                "summary": "Create and update buyers in batch",
+                "parameters": [
+                    {
+                        "description": "Buyers to create and update",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/web.batchRequest"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "create": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/domain.BuyerCreate"
+                                            }
+                                        },
+                                        "update": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/domain.Buyer"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of the request return its first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "207": {
+                        "description": "Status of each buyer",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/web.response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/web.BatchResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid batch",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/api/v1/buyers/report-purchase-orders": {
             "get": {
                 "description": "Return purchaseOrder count for each buyer",
@@ -141,12 +273,22 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
                 ],
                 "tags": [
                     "Buyers"
                 ],
                 "summary": "Return purchaseOrder count for each buyer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Export format: json, csv or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "Returns purchaseOrder count for buyers",
@@ -160,6 +302,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/web.response"
                         }
                     },
+                    "400": {
+                        "description": "Unsupported export format",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Report rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Could not generate report",
                         "schema": {
@@ -176,7 +330,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
                 ],
                 "tags": [
                     "Buyers"
@@ -189,6 +345,12 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Export format: json, csv or xlsx",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -198,6 +360,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/web.response"
                         }
                     },
+                    "400": {
+                        "description": "Unsupported export format",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
                     "404": {
                         "description": "ID was not found",
                         "schema": {
@@ -213,9 +381,136 @@ const docTemplate = `{
                 }
             }
         },
+        "/api/v1/buyers/report-spending": {
+            "get": {
+                "description": "Number of orders placed between ` + "`" + `from` + "`" + ` and ` + "`" + `to` + "`" + `, their total, average value and the date of the last one",
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "Buyers"
+                ],
+                "summary": "Report how much each buyer spent",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start date (YYYY-MM-DD or RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date, inclusive (YYYY-MM-DD or RFC 3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Export format: json, csv or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the spending of every buyer",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid dates or unsupported export format",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Report rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not generate report",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/buyers/report-spending/{id}": {
+            "get": {
+                "description": "Number of orders placed between ` + "`" + `from` + "`" + ` and ` + "`" + `to` + "`" + `, their total, average value and the date of the last one",
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "Buyers"
+                ],
+                "summary": "Report how much a buyer spent",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Buyer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start date (YYYY-MM-DD or RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date, inclusive (YYYY-MM-DD or RFC 3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Export format: json, csv or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the spending of the buyer",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID type, dates or unsupported export format",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Buyer not found",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not generate report",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/api/v1/buyers/{id}": {
             "get": {
-                "description": "Get a buyer by ID",
+                "description": "Get a buyer by ID. Admins can get a deleted one with ` + "`" + `include_deleted=true` + "`" + `.",
                 "tags": [
                     "Buyers"
                 ],
@@ -227,6 +522,24 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include deleted buyers (admin only)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the cached buyer",
+                        "name": "If-None-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -234,14 +547,29 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/domain.Buyer"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the buyer"
+                            }
                         }
                     },
+                    "304": {
+                        "description": "Buyer not modified"
+                    },
                     "400": {
                         "description": "Invalid ID",
                         "schema": {
                             "type": "string"
                         }
                     },
+                    "403": {
+                        "description": "include_deleted is restricted to admins",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "404": {
                         "description": "Buyer not found",
                         "schema": {
@@ -263,6 +591,12 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the buyer must have",
+                        "name": "If-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -283,6 +617,12 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "412": {
+                        "description": "Buyer changed since it was read",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             },
@@ -311,6 +651,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/domain.Buyer"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the buyer must have",
+                        "name": "If-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -318,6 +664,12 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/domain.Buyer"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the buyer"
+                            }
                         }
                     },
                     "400": {
@@ -331,54 +683,68 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "412": {
+                        "description": "Buyer changed since it was read",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         },
-        "/api/v1/carrier": {
-            "post": {
-                "consumes": [
-                    "application/json"
-                ],
+        "/api/v1/buyers/{id}/purchase-orders": {
+            "get": {
+                "description": "Orders placed between ` + "`" + `from` + "`" + ` and ` + "`" + `to` + "`" + `, oldest first, with their quantity and total",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Carrier"
+                    "Buyers"
                 ],
-                "summary": "Create new carrier",
+                "summary": "Get the purchase orders of a buyer",
                 "parameters": [
                     {
-                        "description": "Carrier to be added",
-                        "name": "product",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handler.CarrierRequest"
-                        }
+                        "type": "integer",
+                        "description": "Buyer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start date (YYYY-MM-DD or RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date, inclusive (YYYY-MM-DD or RFC 3339)",
+                        "name": "to",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Returns created carrier",
+                    "200": {
+                        "description": "Returns the purchase orders",
                         "schema": {
                             "$ref": "#/definitions/web.response"
                         }
                     },
-                    "409": {
-                        "description": "` + "`" + `cid` + "`" + ` is not unique or ` + "`" + `locality_id` + "`" + ` not found",
+                    "400": {
+                        "description": "Invalid ID type or dates",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
-                    "422": {
-                        "description": "Missing fields or invalid field types",
+                    "404": {
+                        "description": "Buyer not found",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "500": {
-                        "description": "Could not save carrier",
+                        "description": "Could not fetch purchase orders",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
@@ -386,75 +752,56 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/employees": {
-            "get": {
-                "description": "Retorna uma lista com todas as informações dos funcionários cadastrados",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
+        "/api/v1/buyers/{id}/restore": {
+            "post": {
+                "description": "Undo the deletion of a buyer by ID",
                 "tags": [
-                    "Employees"
+                    "Buyers"
+                ],
+                "summary": "Restore a deleted buyer by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Buyer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    }
                 ],
-                "summary": "Obtém todas as informações dos funcionários",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/domain.Employee"
-                            }
+                            "$ref": "#/definitions/domain.Buyer"
                         }
                     },
                     "400": {
-                        "description": "employee not found",
+                        "description": "Invalid ID",
                         "schema": {
                             "type": "string"
                         }
-                    }
-                }
-            },
-            "post": {
-                "description": "Cria um novo funcionário com base nos dados fornecidos",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Employees"
-                ],
-                "summary": "Cria um novo funcionário",
-                "parameters": [
-                    {
-                        "description": "Novo funcionário a ser criado",
-                        "name": "employee",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/domain.Employee"
-                        }
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "Created",
+                    },
+                    "403": {
+                        "description": "Restricted to admins",
                         "schema": {
-                            "$ref": "#/definitions/domain.Employee"
+                            "type": "string"
                         }
                     },
-                    "409": {
-                        "description": "employee not created",
+                    "404": {
+                        "description": "Buyer not found",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "422": {
-                        "description": "employee card ID need to be only",
+                    "500": {
+                        "description": "Buyer not restored",
                         "schema": {
                             "type": "string"
                         }
@@ -462,36 +809,93 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/employees/report-inbound-orders": {
+        "/api/v1/cache/stats": {
             "get": {
-                "consumes": [
+                "description": "Hits, misses, evictions and size of each cache, by name.",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "Cache"
+                ],
+                "summary": "Get cache stats",
+                "responses": {
+                    "200": {
+                        "description": "Stats of each cache",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/web.response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object",
+                                            "additionalProperties": {
+                                                "$ref": "#/definitions/cache.Stats"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/carriers": {
+            "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Employees"
+                    "Carrier"
+                ],
+                "summary": "List carriers",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Include deleted carriers (admin only)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    }
                 ],
-                "summary": "Get all inbound orders reports",
                 "responses": {
                     "200": {
-                        "description": "returns all of the reports",
+                        "description": "List of carriers",
                         "schema": {
                             "$ref": "#/definitions/web.response"
                         }
                     },
-                    "404": {
-                        "description": "no report to be returned",
+                    "400": {
+                        "description": "Invalid include_deleted",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not list carriers",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/employees/report-inbound-orders/{id}": {
-            "get": {
+            },
+            "post": {
+                "description": "Also served at the deprecated path /api/v1/carrier.",
                 "consumes": [
                     "application/json"
                 ],
@@ -499,27 +903,47 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Employees"
+                    "Carrier"
                 ],
-                "summary": "Get specific inbound order report from employee by ID",
+                "summary": "Create new carrier",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Employee ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Carrier to be added",
+                        "name": "product",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.CarrierRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of the request return its first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "returns the specified report",
+                    "201": {
+                        "description": "Returns created carrier",
                         "schema": {
                             "$ref": "#/definitions/web.response"
                         }
                     },
-                    "404": {
-                        "description": "no report to be returned",
+                    "409": {
+                        "description": "` + "`" + `cid` + "`" + ` is not unique or ` + "`" + `locality_id` + "`" + ` not found",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Missing fields or invalid field types",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not save carrier",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
@@ -527,90 +951,141 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/employees/{id}": {
+        "/api/v1/carriers/{id}": {
             "get": {
-                "description": "Retorna as informações de um funcionário com base no ID fornecido",
-                "consumes": [
-                    "application/json"
-                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Employees"
+                    "Carrier"
                 ],
-                "summary": "Obtém as informações de um funcionário pelo ID",
+                "summary": "Get carrier by ID",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "ID do funcionário a ser obtido",
+                        "description": "Carrier ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include deleted carriers (admin only)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the cached carrier",
+                        "name": "If-None-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Carrier with the given ID",
                         "schema": {
-                            "$ref": "#/definitions/domain.Employee"
+                            "$ref": "#/definitions/web.response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the carrier"
+                            }
                         }
                     },
+                    "304": {
+                        "description": "Carrier did not change"
+                    },
                     "400": {
-                        "description": "invalid card id",
+                        "description": "Invalid ID type",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "404": {
-                        "description": "invalid id",
+                        "description": "Could not find carrier",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not get carrier",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
                         }
                     }
                 }
             },
             "delete": {
-                "description": "Remove um funcionário com base no ID fornecido",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
                 "tags": [
-                    "Employees"
+                    "Carrier"
                 ],
-                "summary": "Remove um funcionário",
+                "summary": "Delete carrier",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "ID do funcionário a ser removido",
+                        "description": "Carrier ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the carrier must have",
+                        "name": "If-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "204": {
-                        "description": "No Content"
+                        "description": "Carrier deleted"
                     },
                     "400": {
-                        "description": "invalid id",
+                        "description": "Invalid ID type",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "404": {
-                        "description": "employee not deleted",
+                        "description": "Could not find carrier",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Carrier is assigned to purchase orders",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Carrier changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not delete carrier",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
                         }
                     }
                 }
             },
             "patch": {
-                "description": "Atualiza as informações de um funcionário com base no ID fornecido e nos dados enviados",
                 "consumes": [
                     "application/json"
                 ],
@@ -618,145 +1093,137 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Employees"
+                    "Carrier"
                 ],
-                "summary": "Atualiza as informações de um funcionário",
+                "summary": "Update carrier",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "ID do funcionário a ser atualizado",
+                        "description": "Carrier ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Dados do funcionário a serem atualizados",
-                        "name": "employee",
+                        "description": "Fields to update",
+                        "name": "carrier",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/domain.Employee"
+                            "$ref": "#/definitions/handler.CarrierUpdateRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the carrier must have",
+                        "name": "If-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Returns updated carrier",
                         "schema": {
-                            "$ref": "#/definitions/domain.Employee"
+                            "$ref": "#/definitions/web.response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the carrier"
+                            }
                         }
                     },
                     "400": {
-                        "description": "invalid id",
+                        "description": "Invalid ID type",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "404": {
-                        "description": "action could not be processed correctly due to invalid data provided",
-                        "schema": {
-                            "type": "string"
-                        }
-                    }
-                }
-            }
-        },
-        "/api/v1/inbound-orders": {
-            "post": {
-                "description": "Creates a new inbound order based on the data provided\nThe date field in the inboundOrder body should follow the ISO-8601 standard, \"2023-07-06T14:30:00Z\"",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "InboundOrder"
-                ],
-                "summary": "Creates a new inbound order",
-                "parameters": [
-                    {
-                        "description": "new inbound order",
-                        "name": "inboundOrder",
-                        "in": "body",
-                        "required": true,
+                        "description": "Could not find carrier",
                         "schema": {
-                            "$ref": "#/definitions/domain.InboundOrder"
-                        }
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "returns inbound order",
-                        "schema": {
-                            "$ref": "#/definitions/web.response"
+                            "$ref": "#/definitions/web.errorResponse"
                         }
                     },
-                    "400": {
-                        "description": "missing fields",
+                    "409": {
+                        "description": "` + "`" + `cid` + "`" + ` is not unique or ` + "`" + `locality_id` + "`" + ` not found",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
-                    "409": {
-                        "description": "error creating inbound order",
+                    "412": {
+                        "description": "Carrier changed since it was read",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "422": {
-                        "description": "invalid fields",
+                        "description": "Invalid field types",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
-                    }
+                    },
+                    "500": {
+                        "description": "Could not save carrier",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
                 }
             }
         },
-        "/api/v1/localities": {
+        "/api/v1/carriers/{id}/restore": {
             "post": {
-                "consumes": [
-                    "application/json"
-                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Localities"
+                    "Carrier"
                 ],
-                "summary": "Create new locality",
+                "summary": "Restore deleted carrier",
                 "parameters": [
                     {
-                        "description": "Locality to be added",
-                        "name": "locality",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/localities.CreateDTO"
-                        }
+                        "type": "integer",
+                        "description": "Carrier ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Returns created locality",
+                    "200": {
+                        "description": "Returns restored carrier",
                         "schema": {
                             "$ref": "#/definitions/web.response"
                         }
                     },
-                    "409": {
-                        "description": "Locality is not unique",
+                    "400": {
+                        "description": "Invalid ID type",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
-                    "422": {
-                        "description": "Missing fields or invalid field types",
+                    "403": {
+                        "description": "Restricted to admins",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find deleted carrier",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "500": {
-                        "description": "Could not save locality",
+                        "description": "Could not restore carrier",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
@@ -764,42 +1231,31 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/localities/report-carriers": {
+        "/api/v1/countries": {
             "get": {
-                "consumes": [
-                    "application/json"
-                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Localities"
+                    "Countries"
                 ],
-                "summary": "Return carrier count for each locality",
+                "summary": "List countries",
                 "responses": {
                     "200": {
-                        "description": "Returns carrier count for localities",
-                        "schema": {
-                            "$ref": "#/definitions/web.response"
-                        }
-                    },
-                    "204": {
-                        "description": "No content was found",
+                        "description": "List of countries",
                         "schema": {
                             "$ref": "#/definitions/web.response"
                         }
                     },
                     "500": {
-                        "description": "Could not generate report",
+                        "description": "Could not list countries",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/localities/report-carriers/{id}": {
-            "get": {
+            },
+            "post": {
                 "consumes": [
                     "application/json"
                 ],
@@ -807,33 +1263,47 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Localities"
+                    "Countries"
                 ],
-                "summary": "Return carrier count for given locality",
+                "summary": "Create new country",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Locality ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Country to be added",
+                        "name": "country",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.CountryRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of the request return its first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Returns carrier count for locality",
+                    "201": {
+                        "description": "Returns created country",
                         "schema": {
                             "$ref": "#/definitions/web.response"
                         }
                     },
-                    "404": {
-                        "description": "ID was not found",
+                    "409": {
+                        "description": "Country is not unique",
                         "schema": {
-                            "$ref": "#/definitions/web.response"
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Missing fields, invalid field types or empty name",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "500": {
-                        "description": "Could not generate report",
+                        "description": "Could not save country",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
@@ -841,85 +1311,123 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/localities/report-sellers": {
+        "/api/v1/countries/{id}": {
             "get": {
-                "consumes": [
-                    "application/json"
-                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Localities"
+                    "Countries"
+                ],
+                "summary": "Get country by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Country ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the cached country",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
                 ],
-                "summary": "Return seller count for each locality",
                 "responses": {
                     "200": {
-                        "description": "Returns seller count for localities",
+                        "description": "Country with the given ID",
                         "schema": {
                             "$ref": "#/definitions/web.response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the country"
+                            }
                         }
                     },
-                    "204": {
-                        "description": "No content was found",
+                    "304": {
+                        "description": "Country did not change"
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
                         "schema": {
-                            "$ref": "#/definitions/web.response"
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find country",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "500": {
-                        "description": "Could not generate report",
+                        "description": "Could not get country",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/localities/report-sellers/{id}": {
-            "get": {
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
+            },
+            "delete": {
                 "tags": [
-                    "Localities"
+                    "Countries"
                 ],
-                "summary": "Return seller count for given locality",
+                "summary": "Delete country",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Locality ID",
+                        "description": "Country ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the country must have",
+                        "name": "If-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Returns seller count for locality",
+                    "204": {
+                        "description": "Country deleted"
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
                         "schema": {
-                            "$ref": "#/definitions/web.response"
+                            "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "404": {
-                        "description": "ID was not found",
+                        "description": "Could not find country",
                         "schema": {
-                            "$ref": "#/definitions/web.response"
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Country has provinces",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Country changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "500": {
-                        "description": "Could generate report",
+                        "description": "Could not delete country",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/product-records": {
-            "post": {
+            },
+            "patch": {
                 "consumes": [
                     "application/json"
                 ],
@@ -927,41 +1435,78 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Products"
+                    "Countries"
                 ],
-                "summary": "Create new product record",
+                "summary": "Rename country",
                 "parameters": [
                     {
-                        "description": "Product record to be added",
-                        "name": "record",
+                        "type": "integer",
+                        "description": "Country ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New name",
+                        "name": "country",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.CreateRequestRecord"
+                            "$ref": "#/definitions/handler.CountryRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the country must have",
+                        "name": "If-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Returns created product record",
+                    "200": {
+                        "description": "Returns updated country",
                         "schema": {
                             "$ref": "#/definitions/web.response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the country"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find country",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "409": {
-                        "description": "` + "`" + `product_code` + "`" + ` is not unique",
+                        "description": "Country is not unique",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Country changed since it was read",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "422": {
-                        "description": "Missing fields or invalid field types",
+                        "description": "Missing fields, invalid field types or empty name",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "500": {
-                        "description": "Could not save product",
+                        "description": "Could not save country",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
@@ -969,33 +1514,45 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/products": {
+        "/api/v1/countries/{id}/provinces": {
             "get": {
-                "consumes": [
-                    "application/json"
-                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Products"
+                    "Countries"
+                ],
+                "summary": "List provinces of a country",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Country ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get all products",
                 "responses": {
                     "200": {
-                        "description": "Returns all products",
+                        "description": "Provinces of the country",
                         "schema": {
                             "$ref": "#/definitions/web.response"
                         }
                     },
-                    "204": {
-                        "description": "No products to retrieve",
+                    "400": {
+                        "description": "Invalid ID type",
                         "schema": {
-                            "$ref": "#/definitions/web.response"
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find country",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "500": {
-                        "description": "Could not fetch products",
+                        "description": "Could not list provinces",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
@@ -1010,41 +1567,66 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Products"
+                    "Countries"
                 ],
-                "summary": "Create new product",
+                "summary": "Create province in a country",
                 "parameters": [
                     {
-                        "description": "Product to be added",
-                        "name": "product",
+                        "type": "integer",
+                        "description": "Country ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Province to be added",
+                        "name": "province",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.CreateRequest"
+                            "$ref": "#/definitions/handler.ProvinceRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of the request return its first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Returns created product",
+                        "description": "Returns created province",
                         "schema": {
                             "$ref": "#/definitions/web.response"
                         }
                     },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find country",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
                     "409": {
-                        "description": "` + "`" + `product_code` + "`" + ` is not unique",
+                        "description": "Province is not unique in the country",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "422": {
-                        "description": "Missing fields or invalid field types",
+                        "description": "Missing fields, invalid field types or empty name",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "500": {
-                        "description": "Could not save product",
+                        "description": "Could not save province",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
@@ -1052,188 +1634,274 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/products/report-records": {
+        "/api/v1/employees": {
             "get": {
+                "description": "Retorna uma lista com todas as informações dos funcionários cadastrados",
                 "consumes": [
-                    "application/json",
                     "application/json"
                 ],
                 "produces": [
-                    "application/json",
                     "application/json"
                 ],
                 "tags": [
-                    "Products",
-                    "Products"
+                    "Employees"
                 ],
-                "summary": "Get product records by productID",
+                "summary": "Obtém todas as informações dos funcionários",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Product ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "boolean",
+                        "description": "Inclui funcionários removidos (somente admin)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token de admin",
+                        "name": "X-Admin-Token",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Returns product",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/web.response"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Employee"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Invalid ID type",
+                        "description": "employee not found",
                         "schema": {
-                            "$ref": "#/definitions/web.errorResponse"
+                            "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "Could not find product",
+                    "403": {
+                        "description": "include_deleted is restricted to admins",
                         "schema": {
-                            "$ref": "#/definitions/web.errorResponse"
+                            "type": "string"
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/products/report-records/{id}": {
-            "get": {
+            },
+            "post": {
+                "description": "Cria um novo funcionário com base nos dados fornecidos",
                 "consumes": [
-                    "application/json",
                     "application/json"
                 ],
                 "produces": [
-                    "application/json",
                     "application/json"
                 ],
                 "tags": [
-                    "Products",
-                    "Products"
+                    "Employees"
                 ],
-                "summary": "Get product records by productID",
+                "summary": "Cria um novo funcionário",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Product ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Novo funcionário a ser criado",
+                        "name": "employee",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.Employee"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Faz com que novas tentativas da requisição recebam a primeira resposta",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Returns product",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/web.response"
+                            "$ref": "#/definitions/domain.Employee"
                         }
                     },
-                    "400": {
-                        "description": "Invalid ID type",
+                    "409": {
+                        "description": "employee not created",
                         "schema": {
-                            "$ref": "#/definitions/web.errorResponse"
+                            "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "Could not find product",
+                    "422": {
+                        "description": "employee card ID need to be only",
                         "schema": {
-                            "$ref": "#/definitions/web.errorResponse"
+                            "type": "string"
                         }
                     }
                 }
             }
         },
-        "/api/v1/products/{id}": {
+        "/api/v1/employees/report-inbound-orders": {
             "get": {
+                "description": "Orders, units received and distinct products of every employee between ` + "`" + `from` + "`" + ` and ` + "`" + `to` + "`" + `, by warehouse and optionally by day or week, ranked within each warehouse. Orders are summed up as they are read, but ranking needs all of them, so CSV and XLSX exports are built in full before they are sent.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
                 ],
                 "tags": [
-                    "Products"
+                    "Employees"
                 ],
-                "summary": "Get product by ID",
+                "summary": "Get all inbound orders reports",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start date (YYYY-MM-DD or RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date, inclusive (YYYY-MM-DD or RFC 3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "Product ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Warehouse ID",
+                        "name": "warehouse_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Split by day or week",
+                        "name": "period",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Export format: json, csv or xlsx",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Returns product",
+                        "description": "returns all of the reports",
                         "schema": {
                             "$ref": "#/definitions/web.response"
                         }
                     },
                     "400": {
-                        "description": "Invalid ID type",
+                        "description": "invalid filters or unsupported export format",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "404": {
-                        "description": "Could not find product",
+                        "description": "no report to be returned",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/api/v1/employees/report-inbound-orders/{id}": {
+            "get": {
+                "description": "Orders, units received and distinct products of the employee between ` + "`" + `from` + "`" + ` and ` + "`" + `to` + "`" + `, by warehouse and optionally by day or week, ranked within each warehouse",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
                 ],
                 "tags": [
-                    "Products"
+                    "Employees"
                 ],
-                "summary": "Delete product by ID",
+                "summary": "Get specific inbound order report from employee by ID",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Product ID",
+                        "description": "Employee ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start date (YYYY-MM-DD or RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date, inclusive (YYYY-MM-DD or RFC 3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Warehouse ID",
+                        "name": "warehouse_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Split by day or week",
+                        "name": "period",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Export format: json, csv or xlsx",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Product deleted successfully",
+                        "description": "returns the specified report",
                         "schema": {
                             "$ref": "#/definitions/web.response"
                         }
                     },
                     "400": {
-                        "description": "Invalid ID type",
+                        "description": "invalid filters or unsupported export format",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "404": {
-                        "description": "Could not find product",
+                        "description": "no report to be returned",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Limite de relatórios excedido; veja Retry-After",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "500": {
-                        "description": "Could not delete product",
+                        "description": "internal server error",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     }
                 }
-            },
-            "patch": {
+            }
+        },
+        "/api/v1/employees/{id}": {
+            "get": {
+                "description": "Retorna as informações de um funcionário com base no ID fornecido",
                 "consumes": [
                     "application/json"
                 ],
@@ -1241,69 +1909,74 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Products"
+                    "Employees"
                 ],
-                "summary": "Updates existing product",
+                "summary": "Obtém as informações de um funcionário pelo ID",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Product ID",
+                        "description": "ID do funcionário a ser obtido",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Fields to update",
-                        "name": "product",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handler.UpdateRequest"
-                        }
+                        "type": "boolean",
+                        "description": "Inclui funcionários removidos (somente admin)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token de admin",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag do funcionário em cache",
+                        "name": "If-None-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Returns updated product",
-                        "schema": {
-                            "$ref": "#/definitions/web.response"
-                        }
-                    },
-                    "400": {
-                        "description": "Invalid ID type",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/web.errorResponse"
+                            "$ref": "#/definitions/domain.Employee"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Versão do funcionário"
+                            }
                         }
                     },
-                    "404": {
-                        "description": "Could not find product",
-                        "schema": {
-                            "$ref": "#/definitions/web.errorResponse"
-                        }
+                    "304": {
+                        "description": "Funcionário não modificado"
                     },
-                    "409": {
-                        "description": "` + "`" + `product_code` + "`" + ` is not unique",
+                    "400": {
+                        "description": "invalid card id",
                         "schema": {
-                            "$ref": "#/definitions/web.errorResponse"
+                            "type": "string"
                         }
                     },
-                    "422": {
-                        "description": "Invalid field types",
+                    "403": {
+                        "description": "include_deleted is restricted to admins",
                         "schema": {
-                            "$ref": "#/definitions/web.errorResponse"
+                            "type": "string"
                         }
                     },
-                    "500": {
-                        "description": "Could not save product",
+                    "404": {
+                        "description": "invalid id",
                         "schema": {
-                            "$ref": "#/definitions/web.errorResponse"
+                            "type": "string"
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/purchase-orders": {
-            "post": {
+            },
+            "delete": {
+                "description": "Remove um funcionário com base no ID fornecido",
                 "consumes": [
                     "application/json"
                 ],
@@ -1311,50 +1984,50 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Purchase order"
+                    "Employees"
                 ],
-                "summary": "Create new purchase order",
+                "summary": "Remove um funcionário",
                 "parameters": [
                     {
-                        "description": "purchase order to be added",
-                        "name": "purchaseOrder",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handler.PurchaseOrderRequest"
-                        }
+                        "type": "integer",
+                        "description": "ID do funcionário a ser removido",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag que o funcionário deve ter",
+                        "name": "If-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Returns created purchase order",
-                        "schema": {
-                            "$ref": "#/definitions/web.response"
-                        }
+                    "204": {
+                        "description": "No Content"
                     },
-                    "409": {
-                        "description": "` + "`" + `id` + "`" + ` is not unique or ` + "`" + `locality_id` + "`" + ` not found",
+                    "400": {
+                        "description": "invalid id",
                         "schema": {
-                            "$ref": "#/definitions/web.errorResponse"
+                            "type": "string"
                         }
                     },
-                    "422": {
-                        "description": "Missing fields or invalid field types",
+                    "404": {
+                        "description": "employee not deleted",
                         "schema": {
-                            "$ref": "#/definitions/web.errorResponse"
+                            "type": "string"
                         }
                     },
-                    "500": {
-                        "description": "Could not save purchase order",
+                    "412": {
+                        "description": "employee changed since it was read",
                         "schema": {
-                            "$ref": "#/definitions/web.errorResponse"
+                            "type": "string"
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/sections": {
-            "get": {
+            },
+            "patch": {
+                "description": "Atualiza as informações de um funcionário com base no ID fornecido e nos dados enviados",
                 "consumes": [
                     "application/json"
                 ],
@@ -1362,130 +2035,87 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Sections"
+                    "Employees"
                 ],
-                "summary": "Get all sections",
-                "responses": {
-                    "200": {
-                        "description": "Returns all sections",
-                        "schema": {
-                            "$ref": "#/definitions/web.response"
-                        }
-                    },
-                    "204": {
-                        "description": "No sections to retrieve",
-                        "schema": {
-                            "$ref": "#/definitions/web.response"
-                        }
-                    },
-                    "500": {
-                        "description": "Could not fetch sections",
-                        "schema": {
-                            "$ref": "#/definitions/web.errorResponse"
-                        }
-                    }
-                }
-            },
-            "post": {
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Sections"
-                ],
-                "summary": "Create new section",
+                "summary": "Atualiza as informações de um funcionário",
                 "parameters": [
                     {
-                        "description": "section to be added",
-                        "name": "product",
+                        "type": "integer",
+                        "description": "ID do funcionário a ser atualizado",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Dados do funcionário a serem atualizados",
+                        "name": "employee",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/section.CreateSection"
+                            "$ref": "#/definitions/domain.Employee"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag que o funcionário deve ter",
+                        "name": "If-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Returns created section",
-                        "schema": {
-                            "$ref": "#/definitions/web.response"
-                        }
-                    },
-                    "409": {
-                        "description": "` + "`" + `section_number` + "`" + ` is not unique",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/web.errorResponse"
+                            "$ref": "#/definitions/domain.Employee"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Nova versão do funcionário"
+                            }
                         }
                     },
-                    "422": {
-                        "description": "Missing fields or invalid field types",
+                    "400": {
+                        "description": "invalid id",
                         "schema": {
-                            "$ref": "#/definitions/web.errorResponse"
+                            "type": "string"
                         }
                     },
-                    "500": {
-                        "description": "Could not save section",
-                        "schema": {
-                            "$ref": "#/definitions/web.errorResponse"
-                        }
-                    }
-                }
-            }
-        },
-        "/api/v1/sections/report-products": {
-            "get": {
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Sections"
-                ],
-                "summary": "Get report of products for all sections",
-                "responses": {
-                    "200": {
-                        "description": "Report of products",
+                    "404": {
+                        "description": "action could not be processed correctly due to invalid data provided",
                         "schema": {
-                            "$ref": "#/definitions/web.response"
+                            "type": "string"
                         }
                     },
-                    "204": {
-                        "description": "Status No Content",
+                    "409": {
+                        "description": "warehouse not found",
                         "schema": {
-                            "$ref": "#/definitions/web.errorResponse"
+                            "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "Could not find any section",
+                    "412": {
+                        "description": "employee changed since it was read",
                         "schema": {
-                            "$ref": "#/definitions/web.errorResponse"
+                            "type": "string"
                         }
                     }
                 }
             }
         },
-        "/api/v1/sections/{id}": {
+        "/api/v1/employees/{id}/assignments": {
             "get": {
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Retorna as atribuições do funcionário, da mais antiga à atual",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Sections"
+                    "Employees"
                 ],
-                "summary": "Get section by ID",
+                "summary": "Obtém o histórico de armazéns de um funcionário",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Section ID",
+                        "description": "ID do funcionário",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1493,26 +2123,38 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Returns section",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/web.response"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.EmployeeAssignment"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Invalid ID type",
+                        "description": "invalid id",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "404": {
-                        "description": "Could not find section",
+                        "description": "employee not found",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/api/v1/employees/{id}/restore": {
+            "post": {
+                "description": "Desfaz a remoção de um funcionário com base no ID fornecido",
                 "consumes": [
                     "application/json"
                 ],
@@ -1520,40 +2162,62 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Sections"
+                    "Employees"
                 ],
-                "summary": "Delete section by ID",
+                "summary": "Restaura um funcionário",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Section ID",
+                        "description": "ID do funcionário a ser restaurado",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token de administrador",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "Section deleted successfully",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/web.response"
+                            "$ref": "#/definitions/domain.Employee"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "acesso restrito a administradores",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "404": {
-                        "description": "Could not find section",
+                        "description": "employee not found",
                         "schema": {
-                            "$ref": "#/definitions/web.errorResponse"
+                            "type": "string"
                         }
                     },
                     "500": {
-                        "description": "Could not delete section",
+                        "description": "internal server error",
                         "schema": {
-                            "$ref": "#/definitions/web.errorResponse"
+                            "type": "string"
                         }
                     }
                 }
-            },
-            "patch": {
+            }
+        },
+        "/api/v1/employees/{id}/transfers": {
+            "post": {
+                "description": "Encerra a atribuição atual do funcionário na data efetiva (ou agora) e o atribui ao novo armazém a partir dela",
                 "consumes": [
                     "application/json"
                 ],
@@ -1561,60 +2225,66 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Sections"
+                    "Employees"
                 ],
-                "summary": "Updates existing section",
+                "summary": "Transfere um funcionário para outro armazém",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Section ID",
+                        "description": "ID do funcionário",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Fields to update",
-                        "name": "section",
+                        "description": "Armazém de destino e data efetiva (YYYY-MM-DD ou RFC 3339)",
+                        "name": "transfer",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/section.UpdateSection"
+                            "$ref": "#/definitions/handler.TransferRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Faz com que novas tentativas da requisição recebam a primeira resposta",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Returns updated section",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/web.response"
+                            "$ref": "#/definitions/domain.EmployeeAssignment"
                         }
                     },
                     "400": {
-                        "description": "Invalid ID type",
+                        "description": "invalid id or date",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "404": {
-                        "description": "Could not find section",
+                        "description": "employee not found",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "409": {
-                        "description": "` + "`" + `section_number` + "`" + ` is not unique",
+                        "description": "warehouse not found",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "422": {
-                        "description": "Invalid field types",
+                        "description": "same warehouse or invalid transfer date",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "500": {
-                        "description": "Could not save section",
+                        "description": "internal server error",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
@@ -1622,74 +2292,56 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/sections/{id}/report-products": {
+        "/api/v1/inbound-orders": {
             "get": {
-                "consumes": [
-                    "application/json"
-                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Sections"
+                    "InboundOrder"
                 ],
-                "summary": "Get report of products for a section",
+                "summary": "List inbound orders",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Section ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Only orders received by this warehouse",
+                        "name": "warehouse_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only orders received by this employee",
+                        "name": "employee_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start date (YYYY-MM-DD or RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date, inclusive (YYYY-MM-DD or RFC 3339)",
+                        "name": "to",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Report of products",
+                        "description": "returns inbound orders in date order",
                         "schema": {
                             "$ref": "#/definitions/web.response"
                         }
                     },
-                    "404": {
-                        "description": "Could not find section",
-                        "schema": {
-                            "$ref": "#/definitions/web.errorResponse"
-                        }
-                    },
-                    "500": {
-                        "description": "Could not report",
+                    "400": {
+                        "description": "invalid filters",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
-                    }
-                }
-            }
-        },
-        "/api/v1/sellers": {
-            "get": {
-                "description": "Retrieves all sellers",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Sellers"
-                ],
-                "summary": "Get all sellers",
-                "responses": {
-                    "200": {
-                        "description": "Successfully retrieved sellers",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/domain.Seller"
-                            }
-                        }
-                    },
-                    "204": {
-                        "description": "No Content"
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "could not fetch inbound orders",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
@@ -1697,7 +2349,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Creates a new seller with the provided data",
+                "description": "Creates a new inbound order based on the data provided\nThe date field in the inboundOrder body should follow the ISO-8601 standard, \"2023-07-06T14:30:00Z\"\nThe order either references an existing ` + "`" + `product_batch_id` + "`" + `, or carries a ` + "`" + `product_batch` + "`" + ` to be received:\nthe batch is then created in its section along with the order and its receipt movement.\nThe employee, and the section of the batch, must belong to the warehouse of the order.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1705,41 +2357,53 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Sellers"
+                    "InboundOrder"
                 ],
-                "summary": "Create a new seller",
+                "summary": "Creates a new inbound order",
                 "parameters": [
                     {
-                        "description": "Seller object",
-                        "name": "seller",
+                        "description": "new inbound order",
+                        "name": "inboundOrder",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/domain.Seller"
+                            "$ref": "#/definitions/handler.InboundOrderRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of the request return its first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Successfully created seller",
+                        "description": "returns inbound order, along with the received batch if any",
                         "schema": {
-                            "$ref": "#/definitions/domain.Seller"
+                            "$ref": "#/definitions/web.response"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "missing fields",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "error creating inbound order, or a referenced entity was not found",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "422": {
-                        "description": "Unprocessable Entity",
+                        "description": "invalid fields, or employee or section not in the warehouse",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "could not save inbound order",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
@@ -1747,136 +2411,117 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/sellers/{id}": {
+        "/api/v1/inbound-orders/report-receiving": {
             "get": {
-                "description": "Retrieves a seller based on the provided ID",
+                "description": "Quantity received per warehouse, product and day, along with how many orders and employees took part and when the first and last orders were received.",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
                 ],
                 "tags": [
-                    "Sellers"
+                    "InboundOrder"
                 ],
-                "summary": "Get a seller by ID",
+                "summary": "Report received stock",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Seller ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "Successfully retrieved seller",
-                        "schema": {
-                            "$ref": "#/definitions/domain.Seller"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/web.errorResponse"
-                        }
+                        "description": "Only orders received by this warehouse",
+                        "name": "warehouse_id",
+                        "in": "query"
                     },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/web.errorResponse"
-                        }
-                    }
-                }
-            },
-            "put": {
-                "description": "Updates an existing seller with the provided data",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Sellers"
-                ],
-                "summary": "Update an existing seller",
-                "parameters": [
                     {
                         "type": "integer",
-                        "description": "Seller ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Only orders received by this employee",
+                        "name": "employee_id",
+                        "in": "query"
                     },
                     {
-                        "description": "Seller object",
-                        "name": "seller",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/domain.Seller"
-                        }
+                        "type": "string",
+                        "description": "Start date (YYYY-MM-DD or RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date, inclusive (YYYY-MM-DD or RFC 3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Export format: json, csv or xlsx",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successfully updated seller",
+                        "description": "returns the report",
                         "schema": {
-                            "$ref": "#/definitions/domain.Seller"
+                            "$ref": "#/definitions/web.response"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "invalid filters or unsupported export format",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "429": {
+                        "description": "Report rate limit exceeded, see Retry-After",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "could not build the report",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Deletes a seller based on the provided ID",
+            }
+        },
+        "/api/v1/inbound-orders/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "Sellers"
+                    "InboundOrder"
                 ],
-                "summary": "Delete a seller by ID",
+                "summary": "Get inbound order by ID",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Seller ID",
+                        "description": "Inbound order ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "returns inbound order",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "invalid ID type",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "inbound order not found",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "could not fetch inbound order",
                         "schema": {
                             "$ref": "#/definitions/web.errorResponse"
                         }
@@ -1884,39 +2529,8 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/warehouses": {
-            "get": {
-                "description": "Get all warehouses",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Warehouses"
-                ],
-                "summary": "Retrieve all warehouses",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/domain.Warehouse"
-                            }
-                        }
-                    },
-                    "204": {
-                        "description": "warehouses is empty"
-                    },
-                    "500": {
-                        "description": "something went wrong with the request",
-                        "schema": {
-                            "type": "string"
-                        }
-                    }
-                }
-            },
+        "/api/v1/localities": {
             "post": {
-                "description": "Create a new warehouse",
                 "consumes": [
                     "application/json"
                 ],
@@ -1924,172 +2538,4624 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Warehouses"
+                    "Localities"
                 ],
-                "summary": "Create a warehouse",
+                "summary": "Create new locality",
                 "parameters": [
                     {
-                        "description": "Warehouse object",
-                        "name": "warehouse",
+                        "description": "Locality to be added",
+                        "name": "locality",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/domain.Warehouse"
+                            "$ref": "#/definitions/localities.CreateDTO"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of the request return its first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Created",
+                        "description": "Returns created locality",
                         "schema": {
-                            "$ref": "#/definitions/domain.Warehouse"
+                            "$ref": "#/definitions/web.response"
                         }
                     },
                     "409": {
-                        "description": "warehouse can be alreary exist",
+                        "description": "Locality is not unique",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "422": {
-                        "description": "warehousecode need to be passed, it can't be empty",
+                        "description": "Missing fields or invalid field types",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/web.errorResponse"
                         }
                     },
                     "500": {
-                        "description": "something went wrong with the request",
+                        "description": "Could not save locality",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/web.errorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/v1/warehouses/{id}": {
+        "/api/v1/localities/report-carriers": {
             "get": {
-                "description": "Get a warehouse by ID",
-                "produces": [
+                "consumes": [
                     "application/json"
                 ],
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
                 "tags": [
-                    "Warehouses"
+                    "Localities"
                 ],
-                "summary": "Retrieve a warehouse",
+                "summary": "Return carrier count for each locality",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Warehouse ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "string",
+                        "description": "Export format: json, csv or xlsx",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Returns carrier count for localities",
                         "schema": {
-                            "$ref": "#/definitions/domain.Warehouse"
+                            "$ref": "#/definitions/web.response"
                         }
                     },
-                    "400": {
-                        "description": "Invalid ID",
+                    "204": {
+                        "description": "No content was found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/web.response"
                         }
                     },
-                    "404": {
-                        "description": "Warehouse not found",
+                    "400": {
+                        "description": "Unsupported export format",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/web.errorResponse"
                         }
-                    }
-                }
-            },
-            "delete": {
-                "description": "Delete a warehouse by ID",
-                "tags": [
-                    "Warehouses"
-                ],
-                "summary": "Delete a warehouse",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Warehouse ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "204": {
-                        "description": "No Content"
                     },
-                    "400": {
-                        "description": "Invalid ID",
+                    "429": {
+                        "description": "Report rate limit exceeded, see Retry-After",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/web.errorResponse"
                         }
                     },
-                    "405": {
-                        "description": "Warehouse not deleted",
+                    "500": {
+                        "description": "Could not generate report",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/web.errorResponse"
                         }
                     }
                 }
-            },
-            "patch": {
-                "description": "Update a warehouse by ID",
+            }
+        },
+        "/api/v1/localities/report-carriers/{id}": {
+            "get": {
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
                 ],
                 "tags": [
-                    "Warehouses"
+                    "Localities"
                 ],
-                "summary": "Update a warehouse",
+                "summary": "Return carrier count for given locality",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Warehouse ID",
+                        "description": "Locality ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Updated warehouse object",
-                        "name": "warehouse",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/domain.Warehouse"
-                        }
+                        "type": "string",
+                        "description": "Export format: json, csv or xlsx",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Returns carrier count for locality",
                         "schema": {
-                            "$ref": "#/definitions/domain.Warehouse"
+                            "$ref": "#/definitions/web.response"
                         }
                     },
-                    "404": {
-                        "description": "Invalid ID",
+                    "400": {
+                        "description": "Unsupported export format",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/web.errorResponse"
                         }
                     },
-                    "409": {
-                        "description": "Warehouse code must be unique",
+                    "404": {
+                        "description": "ID was not found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not generate report",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/localities/report-sellers": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "Localities"
+                ],
+                "summary": "Return seller count for each locality",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Export format: json, csv or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns seller count for localities",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "204": {
+                        "description": "No content was found",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Unsupported export format",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Report rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not generate report",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/localities/report-sellers/{id}": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "Localities"
+                ],
+                "summary": "Return seller count for given locality",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Locality ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Export format: json, csv or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns seller count for locality",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Unsupported export format",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "500": {
+                        "description": "Could generate report",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/localities/report-stock": {
+            "get": {
+                "description": "Regions are rolled up from every locality, so CSV and XLSX exports are built in full before they are sent, unlike the streamed seller and carrier reports.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "Localities"
+                ],
+                "summary": "Return warehouses, sections and stock quantity for each locality, province or country",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Roll-up level: locality, province or country",
+                        "name": "level",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Export format: json, csv or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns stock for regions",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "204": {
+                        "description": "No content was found",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid level or unsupported export format",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Report rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not generate report",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/localities/report-stock/{id}": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "Localities"
+                ],
+                "summary": "Return warehouses, sections and stock quantity for given locality, province or country",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Locality, province or country ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Roll-up level: locality, province or country",
+                        "name": "level",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Export format: json, csv or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns stock for region",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid level or unsupported export format",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not generate report",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/localities/report-warehouses": {
+            "get": {
+                "description": "Regions are rolled up from every locality, so CSV and XLSX exports are built in full before they are sent, unlike the streamed seller and carrier reports.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "Localities"
+                ],
+                "summary": "Return warehouse count for each locality, province or country",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Roll-up level: locality, province or country",
+                        "name": "level",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Export format: json, csv or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns warehouse count for regions",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "204": {
+                        "description": "No content was found",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid level or unsupported export format",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Report rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not generate report",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/localities/report-warehouses/{id}": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "Localities"
+                ],
+                "summary": "Return warehouse count for given locality, province or country",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Locality, province or country ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Roll-up level: locality, province or country",
+                        "name": "level",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Export format: json, csv or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns warehouse count for region",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid level or unsupported export format",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not generate report",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/localities/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Localities"
+                ],
+                "summary": "Get locality by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Locality ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the cached locality",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Locality with its province and country",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the locality, which also changes when its province or country is renamed"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Locality did not change"
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find locality",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not get locality",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "tags": [
+                    "Localities"
+                ],
+                "summary": "Delete locality",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Locality ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the locality must have",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Locality deleted"
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find locality",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Sellers, carriers or warehouses are in the locality",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Locality changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not delete locality",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Renames the locality, or moves it to another province.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Localities"
+                ],
+                "summary": "Update locality",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Locality ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to update",
+                        "name": "locality",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.LocalityUpdateRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the locality must have",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns updated locality",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the locality"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find locality",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Locality is not unique in its province, or ` + "`" + `province_id` + "`" + ` not found",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Locality changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid field types or empty name",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not save locality",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/product-batches": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Batches"
+                ],
+                "summary": "List batches",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Only batches of this product",
+                        "name": "product_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only batches in this section",
+                        "name": "section_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of batches",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list batches",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/product-batches/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Batches"
+                ],
+                "summary": "Get batch by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Batch ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the cached batch",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Batch with the given ID",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the batch"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Batch did not change"
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Batch not found",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get batch",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "reason must be one of recount, damaged, returned or temperature_check.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Batches"
+                ],
+                "summary": "Adjust the quantity or temperature of a batch",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Batch ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New quantity or temperature",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.BatchUpdateRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the batch must have",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Adjusted batch",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the batch"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Batch not found",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Batch changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid reason, quantity or missing fields",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to adjust batch",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/product-batches/{id}/dispose": {
+            "post": {
+                "description": "reason must be one of expired, damaged, contaminated or recalled.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Batches"
+                ],
+                "summary": "Dispose of the remaining stock of a batch",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Batch ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason for disposal",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.BatchDisposeRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the batch must have",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Disposed batch",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the batch"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Batch not found",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Batch has no stock left",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Batch changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid reason or batch not expired",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to dispose of batch",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/product-batches/{id}/history": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Batches"
+                ],
+                "summary": "List the movements of a batch",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Batch ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Movements of the batch, oldest first",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Batch not found",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get history",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/product-batches/{id}/move": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Batches"
+                ],
+                "summary": "Move a batch to another section",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Batch ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Target section",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.BatchMoveRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the batch must have",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Moved batch",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the batch"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Batch not found",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Section not found, incompatible or batch depleted",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Batch changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Batch is already in the section",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to move batch",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/product-records": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Create new product record",
+                "parameters": [
+                    {
+                        "description": "Product record to be added",
+                        "name": "record",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.CreateRequestRecord"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of the request return its first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Returns created product record",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "409": {
+                        "description": "` + "`" + `product_id` + "`" + ` does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Missing fields, invalid field types, negative prices or a future date",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not save product",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/product-types": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ProductTypes"
+                ],
+                "summary": "List product types",
+                "responses": {
+                    "200": {
+                        "description": "List of product types",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not list product types",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ProductTypes"
+                ],
+                "summary": "Create new product type",
+                "parameters": [
+                    {
+                        "description": "Product type to be added",
+                        "name": "product_type",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.ProductTypeRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of the request return its first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Returns created product type",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "409": {
+                        "description": "` + "`" + `description` + "`" + ` is not unique",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Missing or empty description",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not save product type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/product-types/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ProductTypes"
+                ],
+                "summary": "Get product type by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product type ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the cached product type",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Product type with the given ID",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the product type"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Product type did not change"
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find product type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not get product type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "tags": [
+                    "ProductTypes"
+                ],
+                "summary": "Delete product type",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product type ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the product type must have",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Product type deleted"
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find product type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Product type is referenced by products or sections",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Product type changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not delete product type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ProductTypes"
+                ],
+                "summary": "Update product type",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product type ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New description",
+                        "name": "product_type",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.ProductTypeRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the product type must have",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns updated product type",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the product type"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find product type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "` + "`" + `description` + "`" + ` is not unique",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Product type changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Missing or empty description",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not save product type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/products": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Get all products",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Comma separated ids of the products to retrieve",
+                        "name": "ids",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include deleted products (admin only)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns all products",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "204": {
+                        "description": "No products to retrieve",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid include_deleted or ids",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not fetch products",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Create new product",
+                "parameters": [
+                    {
+                        "description": "Product to be added",
+                        "name": "product",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.CreateRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of the request return its first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Returns created product",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "409": {
+                        "description": "` + "`" + `product_code` + "`" + ` is not unique or ` + "`" + `product_type_id` + "`" + ` not found",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Missing fields or invalid field types",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not save product",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/products/batch": {
+            "post": {
+                "description": "Creates the products in ` + "`" + `create` + "`" + ` and updates those in ` + "`" + `update` + "`" + `, which are sent with their ` + "`" + `id` + "`" + `. Each product gets its own status, in the order they were sent.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Create and update products in batch",
+                "parameters": [
+                    {
+                        "description": "Products to create and update",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/web.batchRequest"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "create": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/handler.CreateRequest"
+                                            }
+                                        },
+                                        "update": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/handler.UpdateRequest"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of the request return its first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "207": {
+                        "description": "Status of each product",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/web.response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/web.BatchResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid batch",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/products/report-records": {
+            "get": {
+                "consumes": [
+                    "application/json",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "Products",
+                    "Products"
+                ],
+                "summary": "Get product records by productID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Export format: json, csv or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Export format: json, csv or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns product",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID type or unsupported export format",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find product",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Report rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/products/report-records/{id}": {
+            "get": {
+                "consumes": [
+                    "application/json",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "Products",
+                    "Products"
+                ],
+                "summary": "Get product records by productID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Export format: json, csv or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Export format: json, csv or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns product",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID type or unsupported export format",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find product",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Report rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/products/search": {
+            "get": {
+                "description": "Matches ` + "`" + `q` + "`" + ` against the description and product code, most relevant first, and filters on the product attributes.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Search products",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Text to search for",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Product type ID",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Seller ID",
+                        "name": "seller_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum net weight",
+                        "name": "min_weight",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Maximum recommended freezing temperature",
+                        "name": "max_freezing_temp",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number, from 1",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Products per page, up to 100 (default 20)",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the page of matching products",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not search products",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/products/{id}": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Get product by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include deleted products (admin only)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the cached product",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns product",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the product"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Product did not change"
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find product",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Delete product by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the product must have",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Product deleted successfully",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find product",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Product changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not delete product",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Updates existing product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to update",
+                        "name": "product",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.UpdateRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the product must have",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns updated product",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the product"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find product",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "` + "`" + `product_code` + "`" + ` is not unique",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Product changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid field types",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not save product",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/products/{id}/price": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Get the price of a product at a given date",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Date (YYYY-MM-DD or RFC 3339), defaults to now",
+                        "name": "at",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the price in effect",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID type or date",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find product or it had no price at that date",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not fetch product records",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/products/{id}/price-history": {
+            "get": {
+                "description": "Records between ` + "`" + `from` + "`" + ` and ` + "`" + `to` + "`" + ` in date order, with their margin and how much prices changed since the previous record.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Get the price history of a product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start date (YYYY-MM-DD or RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date, inclusive (YYYY-MM-DD or RFC 3339)",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the price history",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID type or dates",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find product",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not fetch product records",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/products/{id}/restore": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Restore deleted product by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns restored product",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Restricted to admins",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find deleted product",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not restore product",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/provinces/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Provinces"
+                ],
+                "summary": "Get province by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Province ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the cached province",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Province with the given ID",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the province"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Province did not change"
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find province",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not get province",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "tags": [
+                    "Provinces"
+                ],
+                "summary": "Delete province",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Province ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the province must have",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Province deleted"
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find province",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Province has localities",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Province changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not delete province",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Provinces"
+                ],
+                "summary": "Rename province",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Province ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New name",
+                        "name": "province",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.ProvinceRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the province must have",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns updated province",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the province"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find province",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Province is not unique in the country",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Province changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Missing fields, invalid field types or empty name",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not save province",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/provinces/{id}/localities": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Provinces"
+                ],
+                "summary": "List localities of a province",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Province ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Localities of the province",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find province",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not list localities",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Provinces"
+                ],
+                "summary": "Create locality in a province",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Province ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Locality to be added",
+                        "name": "locality",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.LocalityRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of the request return its first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Returns created locality",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find province",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Locality is not unique in the province",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Missing fields, invalid field types or empty name",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not save locality",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/purchase-orders": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Purchase order"
+                ],
+                "summary": "Create new purchase order",
+                "parameters": [
+                    {
+                        "description": "purchase order to be added",
+                        "name": "purchaseOrder",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.PurchaseOrderRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of the request return its first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Returns created purchase order",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "409": {
+                        "description": "` + "`" + `order_number` + "`" + ` or ` + "`" + `tracking_code` + "`" + ` is not unique, a foreign key was not found or the product had no price at ` + "`" + `order_date` + "`" + `",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Missing fields, invalid field types or invalid quantity",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not save purchase order",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/purchase-orders/track/{tracking_code}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Purchase order"
+                ],
+                "summary": "Track purchase order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tracking code",
+                        "name": "tracking_code",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Shipment with its event timeline",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find purchase order",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not track purchase order",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/purchase-orders/track/{tracking_code}/events": {
+            "post": {
+                "description": "Events are recorded in order: picked (on dispatch), in_transit, delivered.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Purchase order"
+                ],
+                "summary": "Record shipment event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tracking code",
+                        "name": "tracking_code",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Status of the shipment",
+                        "name": "event",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.ShipmentEventRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of the request return its first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Returns the shipment",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find purchase order",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Not dispatched, or event out of order",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Missing or invalid status",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not record event",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/purchase-orders/{id}/carrier-recommendations": {
+            "get": {
+                "description": "Ranks the carriers serving the locality, then the province, then the country of the source warehouse.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Purchase order"
+                ],
+                "summary": "Recommend carriers for purchase order",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Purchase order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Source warehouse, required if the order was not dispatched",
+                        "name": "warehouse_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ranked carrier candidates with the reason",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID or query parameter",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find purchase order",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "` + "`" + `warehouse_id` + "`" + ` not found",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Missing ` + "`" + `warehouse_id` + "`" + `",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not recommend carriers",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/purchase-orders/{id}/dispatch": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Purchase order"
+                ],
+                "summary": "Dispatch purchase order",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Purchase order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Carrier and source warehouse",
+                        "name": "dispatch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.DispatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns the shipment, picked",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find purchase order",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Already dispatched, or ` + "`" + `carrier_id` + "`" + ` or ` + "`" + `warehouse_id` + "`" + ` not found",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Missing fields or invalid field types",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not dispatch purchase order",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sections": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sections"
+                ],
+                "summary": "Get all sections",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Comma separated ids of the sections to retrieve",
+                        "name": "ids",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include deleted sections (admin only)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns all sections",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "204": {
+                        "description": "No sections to retrieve",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid include_deleted or ids",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not fetch sections",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sections"
+                ],
+                "summary": "Create new section",
+                "parameters": [
+                    {
+                        "description": "section to be added",
+                        "name": "product",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/section.CreateSection"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of the request return its first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Returns created section",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "409": {
+                        "description": "` + "`" + `section_number` + "`" + ` is not unique or ` + "`" + `product_type_id` + "`" + ` not found",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Missing fields or invalid field types",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not save section",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sections/batch": {
+            "post": {
+                "description": "Creates the sections in ` + "`" + `create` + "`" + ` and updates those in ` + "`" + `update` + "`" + `, which are sent with their ` + "`" + `id` + "`" + `. Each section gets its own status, in the order they were sent.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sections"
+                ],
+                "summary": "Create and update sections in batch",
+                "parameters": [
+                    {
+                        "description": "Sections to create and update",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/web.batchRequest"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "create": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/section.CreateSection"
+                                            }
+                                        },
+                                        "update": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/section.UpdateSection"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of the request return its first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "207": {
+                        "description": "Status of each section",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/web.response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/web.BatchResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid batch",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sections/report-products": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "Sections"
+                ],
+                "summary": "Get report of products for all sections",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Export format: json, csv or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Report of products",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "204": {
+                        "description": "Status No Content",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Unsupported export format",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find any section",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Report rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not stream the report",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sections/{id}": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sections"
+                ],
+                "summary": "Get section by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Section ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include deleted sections (admin only)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the cached section",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns section",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the section"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Section did not change"
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find section",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sections"
+                ],
+                "summary": "Delete section by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Section ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the section must have",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Section deleted successfully",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find section",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Section changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not delete section",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sections"
+                ],
+                "summary": "Updates existing section",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Section ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to update",
+                        "name": "section",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/section.UpdateSection"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the section must have",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns updated section",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the section"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find section",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "` + "`" + `section_number` + "`" + ` is not unique",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Section changed since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid field types",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not save section",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sections/{id}/report-products": {
+            "get": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "Sections"
+                ],
+                "summary": "Get report of products for a section",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Section ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Export format: json, csv or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Report of products",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Unsupported export format",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find section",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Report rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not report",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sections/{id}/restore": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sections"
+                ],
+                "summary": "Restore deleted section by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Section ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Returns restored section",
+                        "schema": {
+                            "$ref": "#/definitions/web.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID type",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Restricted to admins",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Could not find deleted section",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not restore section",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sellers": {
+            "get": {
+                "description": "Retrieves all sellers, or only those with the given ` + "`" + `ids` + "`" + `. Admins can include deleted ones with ` + "`" + `include_deleted=true` + "`" + `.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sellers"
+                ],
+                "summary": "Get all sellers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Comma separated ids of the sellers to retrieve",
+                        "name": "ids",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include deleted sellers (admin only)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved sellers",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Seller"
+                            }
+                        }
+                    },
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates a new seller with the provided data",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sellers"
+                ],
+                "summary": "Create a new seller",
+                "parameters": [
+                    {
+                        "description": "Seller object",
+                        "name": "seller",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.Seller"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of the request return its first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successfully created seller",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Seller"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Idempotency-Key request still in progress",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sellers/batch": {
+            "post": {
+                "description": "Creates the sellers in ` + "`" + `create` + "`" + ` and updates those in ` + "`" + `update` + "`" + `, which are sent with their ` + "`" + `id` + "`" + `, like the update endpoint does. Each seller gets its own status, in the order they were sent.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sellers"
+                ],
+                "summary": "Create and update sellers in batch",
+                "parameters": [
+                    {
+                        "description": "Sellers to create and update",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/web.batchRequest"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "create": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/domain.Seller"
+                                            }
+                                        },
+                                        "update": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/domain.Seller"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of the request return its first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "207": {
+                        "description": "Status of each seller",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/web.response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/web.BatchResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sellers/{id}": {
+            "get": {
+                "description": "Retrieves a seller based on the provided ID. Admins can get a deleted one with ` + "`" + `include_deleted=true` + "`" + `.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sellers"
+                ],
+                "summary": "Get a seller by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Seller ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include deleted sellers (admin only)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the cached seller",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved seller",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Seller"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the seller"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Soft deletes a seller based on the provided ID, along with its products. It can be undone with the restore endpoint. With ` + "`" + `dry_run=true` + "`" + ` it only returns what would be hidden.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sellers"
+                ],
+                "summary": "Delete a seller by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Seller ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only preview what would be deleted",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the seller must have",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rows that would be hidden",
+                        "schema": {
+                            "$ref": "#/definitions/domain.SellerDeleteImpact"
+                        }
+                    },
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Updates an existing seller with the provided data. With ` + "`" + `If-Match` + "`" + ` it only does if the seller is still in that version.\nPlain JSON bodies leave the fields with zero values unchanged. To set zero values or clear fields, send a JSON Merge Patch (` + "`" + `application/merge-patch+json` + "`" + `) or a JSON Patch (` + "`" + `application/json-patch+json` + "`" + `) instead.",
+                "consumes": [
+                    "application/json",
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sellers"
+                ],
+                "summary": "Update an existing seller",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Seller ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Seller object",
+                        "name": "seller",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.Seller"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the seller must have",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully updated seller",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Seller"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the seller"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "CID already registered, locality not found or patch test failed",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid patch",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sellers/{id}/performance": {
+            "get": {
+                "description": "Number of products, units in stock across batches, units sold in purchase orders and the revenue from them",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sellers"
+                ],
+                "summary": "Get the performance of a seller",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Seller ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved performance",
+                        "schema": {
+                            "$ref": "#/definitions/domain.SellerPerformance"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Report rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sellers/{id}/products": {
+            "get": {
+                "description": "Retrieves the products sold by the seller with the provided ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sellers"
+                ],
+                "summary": "Get the products of a seller",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Seller ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully retrieved products",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Product"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sellers/{id}/restore": {
+            "post": {
+                "description": "Restores a deleted seller along with the products deleted with it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sellers"
+                ],
+                "summary": "Restore a deleted seller",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Seller ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successfully restored seller",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Seller"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Restricted to admins",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/web.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/warehouses": {
+            "get": {
+                "description": "Get all warehouses, or only those with the given ` + "`" + `ids` + "`" + `. Admins can include deleted ones with ` + "`" + `include_deleted=true` + "`" + `.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouses"
+                ],
+                "summary": "Retrieve all warehouses",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Comma separated ids of the warehouses to retrieve",
+                        "name": "ids",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include deleted warehouses (admin only)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Warehouse"
+                            }
+                        }
+                    },
+                    "204": {
+                        "description": "warehouses is empty"
+                    },
+                    "400": {
+                        "description": "include_deleted should be a boolean, or invalid ids",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "include_deleted is restricted to admins",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "something went wrong with the request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new warehouse",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouses"
+                ],
+                "summary": "Create a warehouse",
+                "parameters": [
+                    {
+                        "description": "Warehouse object",
+                        "name": "warehouse",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.Warehouse"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of the request return its first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Warehouse"
+                        }
+                    },
+                    "409": {
+                        "description": "warehouse can be alreary exist",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "warehousecode need to be passed, it can't be empty",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "something went wrong with the request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/warehouses/batch": {
+            "post": {
+                "description": "Creates the warehouses in ` + "`" + `create` + "`" + ` and updates those in ` + "`" + `update` + "`" + `, which are sent with their ` + "`" + `id` + "`" + `, like the update endpoint does. Each warehouse gets its own status, in the order they were sent.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouses"
+                ],
+                "summary": "Create and update warehouses in batch",
+                "parameters": [
+                    {
+                        "description": "Warehouses to create and update",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/web.batchRequest"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "create": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/domain.Warehouse"
+                                            }
+                                        },
+                                        "update": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/domain.Warehouse"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Makes retries of the request return its first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "207": {
+                        "description": "Status of each warehouse",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/web.response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/web.BatchResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "action could not be processed correctly due to invalid data provided",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/warehouses/{id}": {
+            "get": {
+                "description": "Get a warehouse by ID. Admins can get a deleted one with ` + "`" + `include_deleted=true` + "`" + `.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouses"
+                ],
+                "summary": "Retrieve a warehouse",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Warehouse ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include deleted warehouses (admin only)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the cached warehouse",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Warehouse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the warehouse"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "warehouse did not change"
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "include_deleted is restricted to admins",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Warehouse not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a warehouse by ID",
+                "tags": [
+                    "Warehouses"
+                ],
+                "summary": "Delete a warehouse",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Warehouse ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the warehouse must have",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "405": {
+                        "description": "Warehouse not deleted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "warehouse changed since it was read",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Update a warehouse by ID. Plain JSON bodies leave the fields with zero values unchanged. To set zero values or clear fields, send a JSON Merge Patch (` + "`" + `application/merge-patch+json` + "`" + `) or a JSON Patch (` + "`" + `application/json-patch+json` + "`" + `) instead.",
+                "consumes": [
+                    "application/json",
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouses"
+                ],
+                "summary": "Update a warehouse",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Warehouse ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated warehouse object",
+                        "name": "warehouse",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.Warehouse"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag the warehouse must have",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Warehouse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the warehouse"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Warehouse code must be unique, locality not found or patch test failed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "warehouse changed since it was read",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "action could not be processed correctly due to invalid data provided",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/warehouses/{id}/dashboard": {
+            "get": {
+                "description": "Get the sections, employee count, stock by product type, batches expiring soon and today's inbound orders of a warehouse",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouses"
+                ],
+                "summary": "Retrieve a warehouse dashboard",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Warehouse ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Days ahead to look for expiring batches (default 7)",
+                        "name": "expiring_within_days",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.WarehouseDashboard"
+                        }
+                    },
+                    "400": {
+                        "description": "expiring_within_days must be a non-negative integer",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Warehouse not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Report rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "something went wrong with the request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/warehouses/{id}/restore": {
+            "post": {
+                "description": "Undo the deletion of a warehouse by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Warehouses"
+                ],
+                "summary": "Restore a warehouse",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Warehouse ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Warehouse"
                         }
                     },
-                    "422": {
-                        "description": "action could not be processed correctly due to invalid data provided",
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Restricted to admins",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Warehouse not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "something went wrong with the request",
                         "schema": {
                             "type": "string"
                         }
@@ -2099,6 +7165,61 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "cache.Stats": {
+            "type": "object",
+            "properties": {
+                "evictions": {
+                    "type": "integer"
+                },
+                "hits": {
+                    "type": "integer"
+                },
+                "misses": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.Batches": {
+            "type": "object",
+            "properties": {
+                "batch_number": {
+                    "type": "integer"
+                },
+                "current_quantity": {
+                    "type": "integer"
+                },
+                "current_temperature": {
+                    "type": "integer"
+                },
+                "due_date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "initial_quantity": {
+                    "type": "integer"
+                },
+                "manufacturing_date": {
+                    "type": "string"
+                },
+                "manufacturing_hour": {
+                    "type": "integer"
+                },
+                "minimum_temperature": {
+                    "type": "integer"
+                },
+                "product_id": {
+                    "type": "integer"
+                },
+                "section_id": {
+                    "type": "integer"
+                }
+            }
+        },
         "domain.Buyer": {
             "type": "object",
             "required": [
@@ -2109,6 +7230,9 @@ const docTemplate = `{
                 "card_number_id": {
                     "type": "string"
                 },
+                "deleted_at": {
+                    "type": "string"
+                },
                 "first_name": {
                     "type": "string"
                 },
@@ -2148,6 +7272,9 @@ const docTemplate = `{
                 "card_number_id": {
                     "type": "string"
                 },
+                "deleted_at": {
+                    "type": "string"
+                },
                 "first_name": {
                     "type": "string"
                 },
@@ -2162,6 +7289,26 @@ const docTemplate = `{
                 }
             }
         },
+        "domain.EmployeeAssignment": {
+            "type": "object",
+            "properties": {
+                "employee_id": {
+                    "type": "integer"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "warehouse_id": {
+                    "type": "integer"
+                }
+            }
+        },
         "domain.InboundOrder": {
             "type": "object",
             "properties": {
@@ -2171,30 +7318,180 @@ const docTemplate = `{
                 "id": {
                     "type": "integer"
                 },
-                "order_date": {
-                    "type": "string"
+                "order_date": {
+                    "type": "string"
+                },
+                "order_number": {
+                    "type": "string"
+                },
+                "product_batch_id": {
+                    "type": "integer"
+                },
+                "warehouse_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.Product": {
+            "type": "object",
+            "properties": {
+                "deleted_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "expiration_rate": {
+                    "type": "integer"
+                },
+                "freezing_rate": {
+                    "type": "integer"
+                },
+                "height": {
+                    "type": "number"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "length": {
+                    "type": "number"
+                },
+                "netweight": {
+                    "type": "number"
+                },
+                "product_code": {
+                    "type": "string"
+                },
+                "product_type_id": {
+                    "type": "integer"
+                },
+                "recommended_freezing_temperature": {
+                    "type": "number"
+                },
+                "seller_id": {
+                    "type": "integer"
+                },
+                "width": {
+                    "type": "number"
+                }
+            }
+        },
+        "domain.ProductTypeStock": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "product_type_id": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.SectionUsage": {
+            "type": "object",
+            "properties": {
+                "current_temperature": {
+                    "type": "number"
+                },
+                "maximum_capacity": {
+                    "type": "integer"
+                },
+                "minimum_temperature": {
+                    "type": "number"
+                },
+                "section_id": {
+                    "type": "integer"
+                },
+                "section_number": {
+                    "type": "integer"
+                },
+                "temperature_status": {
+                    "type": "string"
+                },
+                "used_capacity": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.Seller": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "cid": {
+                    "type": "integer"
+                },
+                "company_name": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "locality_id": {
+                    "type": "integer"
+                },
+                "telephone": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.SellerDeleteImpact": {
+            "type": "object",
+            "properties": {
+                "inbound_orders": {
+                    "type": "integer"
+                },
+                "product_batches": {
+                    "type": "integer"
+                },
+                "product_records": {
+                    "type": "integer"
+                },
+                "products": {
+                    "type": "integer"
+                },
+                "purchase_orders": {
+                    "type": "integer"
+                },
+                "seller_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.SellerPerformance": {
+            "type": "object",
+            "properties": {
+                "products_count": {
+                    "type": "integer"
+                },
+                "revenue": {
+                    "type": "number"
                 },
-                "order_number": {
-                    "type": "string"
+                "seller_id": {
+                    "type": "integer"
                 },
-                "product_batch_id": {
+                "units_in_stock": {
                     "type": "integer"
                 },
-                "warehouse_id": {
+                "units_sold": {
                     "type": "integer"
                 }
             }
         },
-        "domain.Seller": {
+        "domain.Warehouse": {
             "type": "object",
             "properties": {
                 "address": {
                     "type": "string"
                 },
-                "cid": {
-                    "type": "integer"
-                },
-                "company_name": {
+                "deleted_at": {
                     "type": "string"
                 },
                 "id": {
@@ -2203,17 +7500,38 @@ const docTemplate = `{
                 "locality_id": {
                     "type": "integer"
                 },
+                "minimum_capacity": {
+                    "type": "integer"
+                },
+                "minimum_temperature": {
+                    "type": "number"
+                },
                 "telephone": {
                     "type": "string"
+                },
+                "warehouse_code": {
+                    "type": "string"
                 }
             }
         },
-        "domain.Warehouse": {
+        "domain.WarehouseDashboard": {
             "type": "object",
             "properties": {
                 "address": {
                     "type": "string"
                 },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "employees_count": {
+                    "type": "integer"
+                },
+                "expiring_batches": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Batches"
+                    }
+                },
                 "id": {
                     "type": "integer"
                 },
@@ -2226,14 +7544,71 @@ const docTemplate = `{
                 "minimum_temperature": {
                     "type": "number"
                 },
+                "sections": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.SectionUsage"
+                    }
+                },
+                "stock_by_product_type": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.ProductTypeStock"
+                    }
+                },
                 "telephone": {
                     "type": "string"
                 },
+                "todays_inbound_orders": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.InboundOrder"
+                    }
+                },
                 "warehouse_code": {
                     "type": "string"
                 }
             }
         },
+        "handler.BatchDisposeRequest": {
+            "type": "object",
+            "required": [
+                "reason"
+            ],
+            "properties": {
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.BatchMoveRequest": {
+            "type": "object",
+            "required": [
+                "section_id"
+            ],
+            "properties": {
+                "section_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.BatchUpdateRequest": {
+            "type": "object",
+            "required": [
+                "reason"
+            ],
+            "properties": {
+                "current_quantity": {
+                    "type": "integer"
+                },
+                "current_temperature": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
         "handler.CarrierRequest": {
             "type": "object",
             "required": [
@@ -2248,8 +7623,28 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "cid": {
+                    "type": "string"
+                },
+                "company_name": {
+                    "type": "string"
+                },
+                "locality_id": {
                     "type": "integer"
                 },
+                "telephone": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.CarrierUpdateRequest": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "cid": {
+                    "type": "string"
+                },
                 "company_name": {
                     "type": "string"
                 },
@@ -2261,6 +7656,17 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.CountryRequest": {
+            "type": "object",
+            "required": [
+                "country_name"
+            ],
+            "properties": {
+                "country_name": {
+                    "type": "string"
+                }
+            }
+        },
         "handler.CreateBatchesRequest": {
             "type": "object",
             "required": [
@@ -2381,6 +7787,131 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.DispatchRequest": {
+            "type": "object",
+            "required": [
+                "carrier_id",
+                "warehouse_id"
+            ],
+            "properties": {
+                "carrier_id": {
+                    "type": "integer"
+                },
+                "warehouse_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.InboundBatchRequest": {
+            "type": "object",
+            "required": [
+                "batch_number",
+                "current_temperature",
+                "due_date",
+                "initial_quantity",
+                "manufacturing_date",
+                "manufacturing_hour",
+                "minimum_temperature",
+                "product_id",
+                "section_id"
+            ],
+            "properties": {
+                "batch_number": {
+                    "type": "integer"
+                },
+                "current_temperature": {
+                    "type": "integer"
+                },
+                "due_date": {
+                    "type": "string"
+                },
+                "initial_quantity": {
+                    "type": "integer"
+                },
+                "manufacturing_date": {
+                    "type": "string"
+                },
+                "manufacturing_hour": {
+                    "type": "integer"
+                },
+                "minimum_temperature": {
+                    "type": "integer"
+                },
+                "product_id": {
+                    "type": "integer"
+                },
+                "section_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.InboundOrderRequest": {
+            "type": "object",
+            "properties": {
+                "employee_id": {
+                    "type": "integer"
+                },
+                "order_date": {
+                    "type": "string"
+                },
+                "order_number": {
+                    "type": "string"
+                },
+                "product_batch": {
+                    "$ref": "#/definitions/handler.InboundBatchRequest"
+                },
+                "product_batch_id": {
+                    "type": "integer"
+                },
+                "warehouse_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.LocalityRequest": {
+            "type": "object",
+            "required": [
+                "locality_name"
+            ],
+            "properties": {
+                "locality_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.LocalityUpdateRequest": {
+            "type": "object",
+            "properties": {
+                "locality_name": {
+                    "type": "string"
+                },
+                "province_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.ProductTypeRequest": {
+            "type": "object",
+            "required": [
+                "description"
+            ],
+            "properties": {
+                "description": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.ProvinceRequest": {
+            "type": "object",
+            "required": [
+                "province_name"
+            ],
+            "properties": {
+                "province_name": {
+                    "type": "string"
+                }
+            }
+        },
         "handler.PurchaseOrderRequest": {
             "type": "object",
             "required": [
@@ -2388,13 +7919,15 @@ const docTemplate = `{
                 "order_date",
                 "order_number",
                 "order_status_id",
-                "product_record_id",
                 "tracking_code"
             ],
             "properties": {
                 "buyer_id": {
                     "type": "integer"
                 },
+                "carrier_id": {
+                    "type": "integer"
+                },
                 "order_date": {
                     "type": "string"
                 },
@@ -2404,11 +7937,45 @@ const docTemplate = `{
                 "order_status_id": {
                     "type": "integer"
                 },
+                "product_id": {
+                    "type": "integer"
+                },
                 "product_record_id": {
                     "type": "integer"
                 },
+                "quantity": {
+                    "type": "integer"
+                },
                 "tracking_code": {
                     "type": "string"
+                },
+                "warehouse_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.ShipmentEventRequest": {
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.TransferRequest": {
+            "type": "object",
+            "required": [
+                "warehouse_id"
+            ],
+            "properties": {
+                "effective_date": {
+                    "type": "string"
+                },
+                "warehouse_id": {
+                    "type": "integer"
                 }
             }
         },
@@ -2532,6 +8099,48 @@ const docTemplate = `{
                 }
             }
         },
+        "web.BatchResponse": {
+            "type": "object",
+            "properties": {
+                "create": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/web.BatchResult"
+                    }
+                },
+                "update": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/web.BatchResult"
+                    }
+                }
+            }
+        },
+        "web.BatchResult": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "web.batchRequest": {
+            "type": "object",
+            "properties": {
+                "create": {
+                    "type": "array",
+                    "items": {}
+                },
+                "update": {
+                    "type": "array",
+                    "items": {}
+                }
+            }
+        },
         "web.errorResponse": {
             "type": "object",
             "properties": {
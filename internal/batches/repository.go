@@ -3,15 +3,43 @@ package batches
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 )
 
+// Filter narrows the batches returned by List.
+// Omitted fields match every batch.
+type Filter struct {
+	ProductID optional.Opt[int]
+	SectionID optional.Opt[int]
+}
+
+// Change returns the new state of batch b, and the movement that
+// leads to it, or an error if b cannot change.
+type Change func(b domain.Batches) (domain.Batches, domain.BatchMovement, error)
+
 type Repository interface {
 	Create(ctx context.Context, b domain.Batches) (domain.Batches, error)
 	Exists(ctx context.Context, batchNumber int) bool
+	// Save inserts a batch along with its receipt movement.
 	Save(ctx context.Context, s domain.Batches) (int, error)
+	Get(ctx context.Context, id int) (domain.Batches, error)
+	List(ctx context.Context, f Filter) ([]domain.Batches, error)
+	// Apply locks the batch with the given id, and stores the new
+	// state that change returns for it along with the movement that
+	// led to it, all in a single transaction. Concurrent changes to a
+	// batch are thus applied one after the other. It returns the new
+	// state, or the error of change as is. It fails with
+	// sqlutil.ErrVersionMismatch before calling change if the batch
+	// is not in one of the versions expected by ctx.
+	Apply(ctx context.Context, id int, change Change) (domain.Batches, error)
+	Movements(ctx context.Context, id int) ([]domain.BatchMovement, error)
 }
 
 type repository struct {
@@ -54,14 +82,14 @@ func (r *repository) Exists(ctx context.Context, batchNumber int) bool {
 }
 
 func (r *repository) Save(ctx context.Context, s domain.Batches) (int, error) {
-	query := "INSERT INTO product_batches (batch_number, current_quantity, current_temperature, due_date, initial_quantity, manufacturing_date, manufacturing_hour, minimum_temperature, product_id, section_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);"
-	stmt, err := r.db.Prepare(query)
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		fmt.Println(err.Error())
 		return 0, err
 	}
+	defer tx.Rollback()
 
-	res, err := stmt.Exec(&s.BatchNumber, &s.CurrentQuantity, &s.CurrentTemperature, &s.DueDate, &s.InitialQuantity, &s.ManufacturingDate, &s.ManufacturingHour, &s.MinimumTemperature, &s.ProductID, &s.SectionID)
+	query := "INSERT INTO product_batches (batch_number, current_quantity, current_temperature, due_date, initial_quantity, manufacturing_date, manufacturing_hour, minimum_temperature, product_id, section_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);"
+	res, err := tx.ExecContext(ctx, query, s.BatchNumber, s.CurrentQuantity, s.CurrentTemperature, s.DueDate, s.InitialQuantity, s.ManufacturingDate, s.ManufacturingHour, s.MinimumTemperature, s.ProductID, s.SectionID)
 	if err != nil {
 		fmt.Println(err.Error())
 		return 0, err
@@ -73,5 +101,167 @@ func (r *repository) Save(ctx context.Context, s domain.Batches) (int, error) {
 		return 0, err
 	}
 
-	return int(id), nil
+	m := domain.BatchMovement{
+		ProductBatchID: int(id),
+		Type:           domain.MovementReceipt,
+		Quantity:       s.CurrentQuantity,
+		SectionID:      s.SectionID,
+	}
+	if _, err := insertMovement(ctx, tx, m); err != nil {
+		return 0, err
+	}
+
+	return int(id), tx.Commit()
+}
+
+const selectBatch = "SELECT id, batch_number, current_quantity, current_temperature, due_date, initial_quantity, manufacturing_date, manufacturing_hour, minimum_temperature, product_id, section_id, version FROM product_batches"
+
+func (r *repository) Get(ctx context.Context, id int) (domain.Batches, error) {
+	row := r.db.QueryRowContext(ctx, selectBatch+" WHERE id = ?;", id)
+	b, err := scanBatch(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Batches{}, ErrNotFound
+	}
+	if err != nil {
+		return domain.Batches{}, err
+	}
+	return b, nil
+}
+
+func (r *repository) List(ctx context.Context, f Filter) ([]domain.Batches, error) {
+	conds, args := []string{}, []any{}
+	if id, ok := f.ProductID.Value(); ok {
+		conds = append(conds, "product_id = ?")
+		args = append(args, id)
+	}
+	if id, ok := f.SectionID.Value(); ok {
+		conds = append(conds, "section_id = ?")
+		args = append(args, id)
+	}
+	query := selectBatch
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	rows, err := r.db.QueryContext(ctx, query+" ORDER BY id;", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	batches := []domain.Batches{}
+	for rows.Next() {
+		b, err := scanBatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, b)
+	}
+	return batches, rows.Err()
+}
+
+func (r *repository) Apply(ctx context.Context, id int, change Change) (domain.Batches, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.Batches{}, err
+	}
+	defer tx.Rollback()
+
+	b, err := scanBatch(tx.QueryRowContext(ctx, selectBatch+" WHERE id = ? FOR UPDATE;", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Batches{}, ErrNotFound
+	}
+	if err != nil {
+		return domain.Batches{}, err
+	}
+	if err := sqlutil.CheckVersion(ctx, b.Version); err != nil {
+		return domain.Batches{}, err
+	}
+	b, m, err := change(b)
+	if err != nil {
+		return domain.Batches{}, err
+	}
+
+	query := "UPDATE product_batches SET current_quantity = ?, current_temperature = ?, section_id = ?, version = version + 1 WHERE id = ?;"
+	if _, err := tx.ExecContext(ctx, query, b.CurrentQuantity, b.CurrentTemperature, b.SectionID, b.ID); err != nil {
+		// The section was deleted after it was checked.
+		if strings.HasPrefix(err.Error(), "Error 1452") {
+			return domain.Batches{}, ErrSectionNotFound
+		}
+		return domain.Batches{}, err
+	}
+
+	if _, err := insertMovement(ctx, tx, m); err != nil {
+		return domain.Batches{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Batches{}, err
+	}
+	b.Version++
+	return b, nil
+}
+
+func (r *repository) Movements(ctx context.Context, id int) ([]domain.BatchMovement, error) {
+	query := "SELECT id, product_batch_id, movement_type, quantity, section_id, inbound_order_id, from_section_id, temperature, reason, created_at " +
+		"FROM batch_movements WHERE product_batch_id = ? ORDER BY created_at, id;"
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movements := []domain.BatchMovement{}
+	for rows.Next() {
+		var m domain.BatchMovement
+		var inboundOrderID, fromSectionID, temperature sql.NullInt64
+		var reason sql.NullString
+		err := rows.Scan(&m.ID, &m.ProductBatchID, &m.Type, &m.Quantity, &m.SectionID,
+			&inboundOrderID, &fromSectionID, &temperature, &reason, sqlutil.Time(&m.CreatedAt))
+		if err != nil {
+			return nil, err
+		}
+		m.InboundOrderID = nullInt(inboundOrderID)
+		m.FromSectionID = nullInt(fromSectionID)
+		m.Temperature = nullInt(temperature)
+		if reason.Valid {
+			m.Reason = &reason.String
+		}
+		movements = append(movements, m)
+	}
+	return movements, rows.Err()
+}
+
+func insertMovement(ctx context.Context, tx *sql.Tx, m domain.BatchMovement) (domain.BatchMovement, error) {
+	m.CreatedAt = time.Now().UTC()
+	query := "INSERT INTO batch_movements (product_batch_id, movement_type, quantity, section_id, inbound_order_id, from_section_id, temperature, reason, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);"
+	res, err := tx.ExecContext(ctx, query, m.ProductBatchID, m.Type, m.Quantity, m.SectionID, m.InboundOrderID, m.FromSectionID, m.Temperature, m.Reason, m.CreatedAt)
+	if err != nil {
+		return domain.BatchMovement{}, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return domain.BatchMovement{}, err
+	}
+	m.ID = int(id)
+	return m, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanBatch(row scanner) (domain.Batches, error) {
+	var b domain.Batches
+	err := row.Scan(&b.ID, &b.BatchNumber, &b.CurrentQuantity, &b.CurrentTemperature, sqlutil.Time(&b.DueDate), &b.InitialQuantity,
+		sqlutil.Time(&b.ManufacturingDate), &b.ManufacturingHour, &b.MinimumTemperature, &b.ProductID, &b.SectionID, &b.Version)
+	return b, err
+}
+
+func nullInt(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	v := int(n.Int64)
+	return &v
 }
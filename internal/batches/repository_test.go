@@ -7,6 +7,8 @@ import (
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/batches"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
 
 	"github.com/stretchr/testify/assert"
@@ -66,3 +68,93 @@ func TestRepositoryCreate(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestRepositoryLifecycle(t *testing.T) {
+	t.Run("Records the receipt of a saved batch", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := batches.NewRepository(db)
+		batch := domain.Batches{
+			BatchNumber:       543210,
+			CurrentQuantity:   5,
+			DueDate:           time.Unix(100000, 0),
+			InitialQuantity:   5,
+			ManufacturingDate: time.Unix(100000, 0),
+			ProductID:         1,
+			SectionID:         1,
+		}
+
+		id, err := repo.Save(context.TODO(), batch)
+		assert.NoError(t, err)
+
+		movements, err := repo.Movements(context.TODO(), id)
+		assert.NoError(t, err)
+		assert.Len(t, movements, 1)
+		assert.Equal(t, domain.MovementReceipt, movements[0].Type)
+	})
+	t.Run("Applies a movement to a batch", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := batches.NewRepository(db)
+
+		reason := batches.ReasonDamaged
+		applied, err := repo.Apply(context.TODO(), 1, func(b domain.Batches) (domain.Batches, domain.BatchMovement, error) {
+			m := domain.BatchMovement{ProductBatchID: b.ID, Type: domain.MovementDisposal, Quantity: -b.CurrentQuantity, SectionID: b.SectionID, Reason: &reason}
+			b.CurrentQuantity = 0
+			return b, m, nil
+		})
+		assert.NoError(t, err)
+
+		received, err := repo.Get(context.TODO(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, received.CurrentQuantity)
+		assert.Equal(t, applied.Version, received.Version)
+
+		list, err := repo.List(context.TODO(), batches.Filter{SectionID: *optional.FromVal(applied.SectionID)})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, list)
+	})
+	t.Run("Leaves the batch as is when the change fails", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := batches.NewRepository(db)
+		before, err := repo.Get(context.TODO(), 1)
+		assert.NoError(t, err)
+
+		_, err = repo.Apply(context.TODO(), 1, func(b domain.Batches) (domain.Batches, domain.BatchMovement, error) {
+			return b, domain.BatchMovement{}, batches.ErrDepleted
+		})
+		assert.ErrorIs(t, err, batches.ErrDepleted)
+
+		after, err := repo.Get(context.TODO(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, before, after)
+	})
+	t.Run("Applies a movement only in the expected version", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := batches.NewRepository(db)
+		before, err := repo.Get(context.TODO(), 1)
+		assert.NoError(t, err)
+
+		ctx := sqlutil.WithIfMatch(context.TODO(), []int{before.Version + 1})
+		_, err = repo.Apply(ctx, 1, func(b domain.Batches) (domain.Batches, domain.BatchMovement, error) {
+			t.Fatal("change called for a batch in another version")
+			return b, domain.BatchMovement{}, nil
+		})
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+	})
+	t.Run("Returns not found for a missing batch", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := batches.NewRepository(db)
+
+		_, err := repo.Get(context.TODO(), 9999)
+		assert.ErrorIs(t, err, batches.ErrNotFound)
+	})
+}
@@ -6,14 +6,68 @@ import (
 	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/section"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 )
 
 // Errors
 var (
-	ErrInvalidBatchNumber = errors.New("batch number alredy exists")
-	ErrSavingBatch        = errors.New("error saving batch")
+	ErrInvalidBatchNumber  = errors.New("batch number alredy exists")
+	ErrSavingBatch         = errors.New("error saving batch")
+	ErrNotFound            = errors.New("batch not found")
+	ErrSectionNotFound     = errors.New("section not found")
+	ErrSameSection         = errors.New("batch is already in the section")
+	ErrIncompatibleSection = errors.New("section does not store the product type of the batch")
+	ErrDepleted            = errors.New("batch has no stock left")
+	ErrNotExpired          = errors.New("batch is not expired")
+	ErrInvalidReason       = errors.New("invalid reason")
+	ErrInvalidQuantity     = errors.New("quantity can't be negative")
+	ErrNoChanges           = errors.New("current_quantity or current_temperature must be given")
+	ErrInternal            = errors.New("internal server error")
 )
 
+// Reasons for adjusting a batch.
+const (
+	ReasonRecount          = "recount"
+	ReasonReturned         = "returned"
+	ReasonTemperatureCheck = "temperature_check"
+)
+
+// Reasons for disposing of a batch. ReasonDamaged
+// also applies to adjustments.
+const (
+	ReasonExpired      = "expired"
+	ReasonDamaged      = "damaged"
+	ReasonContaminated = "contaminated"
+	ReasonRecalled     = "recalled"
+)
+
+var (
+	adjustmentReasons = []string{ReasonRecount, ReasonDamaged, ReasonReturned, ReasonTemperatureCheck}
+	disposalReasons   = []string{ReasonExpired, ReasonDamaged, ReasonContaminated, ReasonRecalled}
+)
+
+// Sections is the part of the section repository
+// used to check where a batch is moved to.
+type Sections interface {
+	Get(ctx context.Context, id int) (domain.Section, error)
+}
+
+// Products is the part of the product repository
+// used to check the type of the product in a batch.
+type Products interface {
+	Get(ctx context.Context, id int) (domain.Product, error)
+}
+
+// AdjustDTO holds the new quantity and temperature of a batch.
+// At least one of them must be given.
+type AdjustDTO struct {
+	CurrentQuantity    optional.Opt[int]
+	CurrentTemperature optional.Opt[int]
+	Reason             string
+}
+
 type CreateBatches struct {
 	BatchNumber        int       `binding:"required" json:"batch_number"`
 	CurrentQuantity    int       `binding:"required" json:"current_quantity"`
@@ -29,15 +83,30 @@ type CreateBatches struct {
 
 type Service interface {
 	Create(ctx context.Context, batches CreateBatches) (domain.Batches, error)
+	Get(ctx context.Context, id int) (domain.Batches, error)
+	List(ctx context.Context, f Filter) ([]domain.Batches, error)
+	// Adjust corrects the quantity or temperature of a batch.
+	Adjust(ctx context.Context, id int, dto AdjustDTO) (domain.Batches, error)
+	// Move transfers a batch to another section that stores
+	// the same product type.
+	Move(ctx context.Context, id int, sectionID int) (domain.Batches, error)
+	// Dispose removes the remaining stock of a batch.
+	Dispose(ctx context.Context, id int, reason string) (domain.Batches, error)
+	// History returns the movements of a batch, oldest first.
+	History(ctx context.Context, id int) ([]domain.BatchMovement, error)
 }
 
 type service struct {
 	repository Repository
+	sections   Sections
+	products   Products
 }
 
-func NewService(r Repository) Service {
+func NewService(r Repository, sections Sections, products Products) Service {
 	return &service{
 		repository: r,
+		sections:   sections,
+		products:   products,
 	}
 }
 
@@ -67,3 +136,154 @@ func (s *service) Create(ctx context.Context, b CreateBatches) (domain.Batches,
 	batch.ID = i
 	return batch, nil
 }
+
+func (s *service) Get(ctx context.Context, id int) (domain.Batches, error) {
+	b, err := s.repository.Get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return domain.Batches{}, ErrNotFound
+	}
+	if err != nil {
+		return domain.Batches{}, ErrInternal
+	}
+	return b, nil
+}
+
+func (s *service) List(ctx context.Context, f Filter) ([]domain.Batches, error) {
+	batches, err := s.repository.List(ctx, f)
+	if err != nil {
+		return nil, ErrInternal
+	}
+	return batches, nil
+}
+
+func (s *service) Adjust(ctx context.Context, id int, dto AdjustDTO) (domain.Batches, error) {
+	if !validReason(dto.Reason, adjustmentReasons) {
+		return domain.Batches{}, ErrInvalidReason
+	}
+	if !dto.CurrentQuantity.HasVal && !dto.CurrentTemperature.HasVal {
+		return domain.Batches{}, ErrNoChanges
+	}
+	if dto.CurrentQuantity.HasVal && dto.CurrentQuantity.Val < 0 {
+		return domain.Batches{}, ErrInvalidQuantity
+	}
+
+	return s.apply(ctx, id, func(b domain.Batches) (domain.Batches, domain.BatchMovement, error) {
+		m := domain.BatchMovement{
+			ProductBatchID: b.ID,
+			Type:           domain.MovementAdjustment,
+			Quantity:       dto.CurrentQuantity.Or(b.CurrentQuantity) - b.CurrentQuantity,
+			SectionID:      b.SectionID,
+			Reason:         &dto.Reason,
+		}
+		if dto.CurrentTemperature.HasVal {
+			m.Temperature = &dto.CurrentTemperature.Val
+		}
+		b.CurrentQuantity = dto.CurrentQuantity.Or(b.CurrentQuantity)
+		b.CurrentTemperature = dto.CurrentTemperature.Or(b.CurrentTemperature)
+		return b, m, nil
+	})
+}
+
+func (s *service) Move(ctx context.Context, id int, sectionID int) (domain.Batches, error) {
+	return s.apply(ctx, id, func(b domain.Batches) (domain.Batches, domain.BatchMovement, error) {
+		if b.SectionID == sectionID {
+			return b, domain.BatchMovement{}, ErrSameSection
+		}
+		if b.CurrentQuantity == 0 {
+			return b, domain.BatchMovement{}, ErrDepleted
+		}
+
+		target, err := s.sections.Get(ctx, sectionID)
+		if errors.Is(err, section.ErrNotFound) {
+			return b, domain.BatchMovement{}, ErrSectionNotFound
+		}
+		if err != nil {
+			return b, domain.BatchMovement{}, ErrInternal
+		}
+		p, err := s.products.Get(ctx, b.ProductID)
+		if err != nil {
+			return b, domain.BatchMovement{}, ErrInternal
+		}
+		if p.ProductTypeID != target.ProductTypeID {
+			return b, domain.BatchMovement{}, ErrIncompatibleSection
+		}
+
+		from := b.SectionID
+		b.SectionID = sectionID
+		m := domain.BatchMovement{
+			ProductBatchID: b.ID,
+			Type:           domain.MovementTransfer,
+			Quantity:       b.CurrentQuantity,
+			SectionID:      sectionID,
+			FromSectionID:  &from,
+		}
+		return b, m, nil
+	})
+}
+
+func (s *service) Dispose(ctx context.Context, id int, reason string) (domain.Batches, error) {
+	if !validReason(reason, disposalReasons) {
+		return domain.Batches{}, ErrInvalidReason
+	}
+
+	return s.apply(ctx, id, func(b domain.Batches) (domain.Batches, domain.BatchMovement, error) {
+		if b.CurrentQuantity == 0 {
+			return b, domain.BatchMovement{}, ErrDepleted
+		}
+		if reason == ReasonExpired && b.DueDate.After(time.Now()) {
+			return b, domain.BatchMovement{}, ErrNotExpired
+		}
+
+		m := domain.BatchMovement{
+			ProductBatchID: b.ID,
+			Type:           domain.MovementDisposal,
+			Quantity:       -b.CurrentQuantity,
+			SectionID:      b.SectionID,
+			Reason:         &reason,
+		}
+		b.CurrentQuantity = 0
+		return b, m, nil
+	})
+}
+
+func (s *service) History(ctx context.Context, id int) ([]domain.BatchMovement, error) {
+	if _, err := s.Get(ctx, id); err != nil {
+		return nil, err
+	}
+
+	movements, err := s.repository.Movements(ctx, id)
+	if err != nil {
+		return nil, ErrInternal
+	}
+	return movements, nil
+}
+
+// apply changes the batch with the given id while the repository
+// holds it locked, so that change always sees the latest state.
+func (s *service) apply(ctx context.Context, id int, change Change) (domain.Batches, error) {
+	// The errors of change are already those of the service.
+	var changeErr error
+	b, err := s.repository.Apply(ctx, id, func(b domain.Batches) (domain.Batches, domain.BatchMovement, error) {
+		b, m, err := change(b)
+		changeErr = err
+		return b, m, err
+	})
+	switch {
+	case changeErr != nil:
+		return domain.Batches{}, changeErr
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrSectionNotFound), errors.Is(err, sqlutil.ErrVersionMismatch):
+		return domain.Batches{}, err
+	case err != nil:
+		return domain.Batches{}, ErrInternal
+	}
+	return b, nil
+}
+
+func validReason(reason string, reasons []string) bool {
+	for _, r := range reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
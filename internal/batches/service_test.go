@@ -7,6 +7,9 @@ import (
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/batches"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/section"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -14,7 +17,7 @@ import (
 func TestCreate(t *testing.T) {
 	t.Run("should return error when batch number already exists", func(t *testing.T) {
 		repositoryMock := RepositoryMock{}
-		svc := batches.NewService(&repositoryMock)
+		svc := batches.NewService(&repositoryMock, &SectionsMock{}, &ProductsMock{})
 
 		repositoryMock.On("Exists", mock.Anything, mock.Anything).Return(true)
 
@@ -25,7 +28,7 @@ func TestCreate(t *testing.T) {
 
 	t.Run("create a batches is a successfully", func(t *testing.T) {
 		repositoryMock := RepositoryMock{}
-		svc := batches.NewService(&repositoryMock)
+		svc := batches.NewService(&repositoryMock, &SectionsMock{}, &ProductsMock{})
 
 		fakeStruct := batches.CreateBatches{
 			BatchNumber:        113,
@@ -64,7 +67,7 @@ func TestCreate(t *testing.T) {
 
 	t.Run("error when save the creste", func(t *testing.T) {
 		repositoryMock := RepositoryMock{}
-		svc := batches.NewService(&repositoryMock)
+		svc := batches.NewService(&repositoryMock, &SectionsMock{}, &ProductsMock{})
 
 		fakeStruct := batches.CreateBatches{}
 
@@ -77,8 +80,218 @@ func TestCreate(t *testing.T) {
 	})
 }
 
+func TestAdjust(t *testing.T) {
+	t.Run("adjusts quantity and records the change", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := batches.NewService(&repositoryMock, &SectionsMock{}, &ProductsMock{})
+
+		batch := domain.Batches{ID: 1, CurrentQuantity: 10, CurrentTemperature: 2, SectionID: 1}
+		reason := batches.ReasonRecount
+		expected := batch
+		expected.CurrentQuantity = 7
+		movement := domain.BatchMovement{
+			ProductBatchID: 1,
+			Type:           domain.MovementAdjustment,
+			Quantity:       -3,
+			SectionID:      1,
+			Reason:         &reason,
+		}
+
+		repositoryMock.On("Apply", mock.Anything, 1).Return(batch, nil)
+
+		dto := batches.AdjustDTO{CurrentQuantity: *optional.FromVal(7), Reason: reason}
+		received, err := svc.Adjust(context.Background(), 1, dto)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, received)
+		assert.Equal(t, []domain.BatchMovement{movement}, repositoryMock.movements)
+	})
+
+	t.Run("should return error when reason is invalid", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := batches.NewService(&repositoryMock, &SectionsMock{}, &ProductsMock{})
+
+		dto := batches.AdjustDTO{CurrentQuantity: *optional.FromVal(7), Reason: "because"}
+		_, err := svc.Adjust(context.Background(), 1, dto)
+
+		assert.ErrorIs(t, err, batches.ErrInvalidReason)
+	})
+
+	t.Run("should return error when nothing changes", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := batches.NewService(&repositoryMock, &SectionsMock{}, &ProductsMock{})
+
+		_, err := svc.Adjust(context.Background(), 1, batches.AdjustDTO{Reason: batches.ReasonRecount})
+
+		assert.ErrorIs(t, err, batches.ErrNoChanges)
+	})
+
+	t.Run("should return error when batch is not found", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := batches.NewService(&repositoryMock, &SectionsMock{}, &ProductsMock{})
+
+		repositoryMock.On("Apply", mock.Anything, 1).Return(domain.Batches{}, batches.ErrNotFound)
+
+		dto := batches.AdjustDTO{CurrentTemperature: *optional.FromVal(4), Reason: batches.ReasonTemperatureCheck}
+		_, err := svc.Adjust(context.Background(), 1, dto)
+
+		assert.ErrorIs(t, err, batches.ErrNotFound)
+	})
+	t.Run("should return error when batch is in another version", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := batches.NewService(&repositoryMock, &SectionsMock{}, &ProductsMock{})
+
+		repositoryMock.On("Apply", mock.Anything, 1).Return(domain.Batches{}, sqlutil.ErrVersionMismatch)
+
+		dto := batches.AdjustDTO{CurrentTemperature: *optional.FromVal(4), Reason: batches.ReasonTemperatureCheck}
+		_, err := svc.Adjust(context.Background(), 1, dto)
+
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+	})
+}
+
+func TestMove(t *testing.T) {
+	batch := domain.Batches{ID: 1, CurrentQuantity: 10, ProductID: 2, SectionID: 1}
+
+	t.Run("moves batch to a compatible section", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		sections := SectionsMock{}
+		products := ProductsMock{}
+		svc := batches.NewService(&repositoryMock, &sections, &products)
+
+		repositoryMock.On("Apply", mock.Anything, 1).Return(batch, nil)
+		sections.On("Get", mock.Anything, 3).Return(domain.Section{ID: 3, ProductTypeID: 5}, nil)
+		products.On("Get", mock.Anything, 2).Return(domain.Product{ID: 2, ProductTypeID: 5}, nil)
+
+		received, err := svc.Move(context.Background(), 1, 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, received.SectionID)
+		movement := repositoryMock.movements[0]
+		assert.Equal(t, domain.MovementTransfer, movement.Type)
+		assert.Equal(t, 1, *movement.FromSectionID)
+		assert.Equal(t, 10, movement.Quantity)
+	})
+
+	t.Run("should return error when section does not exist", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		sections := SectionsMock{}
+		svc := batches.NewService(&repositoryMock, &sections, &ProductsMock{})
+
+		repositoryMock.On("Apply", mock.Anything, 1).Return(batch, nil)
+		sections.On("Get", mock.Anything, 3).Return(domain.Section{}, section.ErrNotFound)
+
+		_, err := svc.Move(context.Background(), 1, 3)
+
+		assert.ErrorIs(t, err, batches.ErrSectionNotFound)
+	})
+
+	t.Run("should return error when section stores another product type", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		sections := SectionsMock{}
+		products := ProductsMock{}
+		svc := batches.NewService(&repositoryMock, &sections, &products)
+
+		repositoryMock.On("Apply", mock.Anything, 1).Return(batch, nil)
+		sections.On("Get", mock.Anything, 3).Return(domain.Section{ID: 3, ProductTypeID: 6}, nil)
+		products.On("Get", mock.Anything, 2).Return(domain.Product{ID: 2, ProductTypeID: 5}, nil)
+
+		_, err := svc.Move(context.Background(), 1, 3)
+
+		assert.ErrorIs(t, err, batches.ErrIncompatibleSection)
+	})
+
+	t.Run("should return error when batch is already in the section", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := batches.NewService(&repositoryMock, &SectionsMock{}, &ProductsMock{})
+
+		repositoryMock.On("Apply", mock.Anything, 1).Return(batch, nil)
+
+		_, err := svc.Move(context.Background(), 1, 1)
+
+		assert.ErrorIs(t, err, batches.ErrSameSection)
+	})
+}
+
+func TestDispose(t *testing.T) {
+	t.Run("disposes of an expired batch", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := batches.NewService(&repositoryMock, &SectionsMock{}, &ProductsMock{})
+
+		batch := domain.Batches{ID: 1, CurrentQuantity: 10, SectionID: 1, DueDate: time.Now().AddDate(0, 0, -1)}
+		repositoryMock.On("Apply", mock.Anything, 1).Return(batch, nil)
+
+		received, err := svc.Dispose(context.Background(), 1, batches.ReasonExpired)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, received.CurrentQuantity)
+		movement := repositoryMock.movements[0]
+		assert.Equal(t, -10, movement.Quantity)
+		assert.Equal(t, batches.ReasonExpired, *movement.Reason)
+	})
+
+	t.Run("should return error when batch is not expired", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := batches.NewService(&repositoryMock, &SectionsMock{}, &ProductsMock{})
+
+		batch := domain.Batches{ID: 1, CurrentQuantity: 10, DueDate: time.Now().AddDate(0, 0, 1)}
+		repositoryMock.On("Apply", mock.Anything, 1).Return(batch, nil)
+
+		_, err := svc.Dispose(context.Background(), 1, batches.ReasonExpired)
+
+		assert.ErrorIs(t, err, batches.ErrNotExpired)
+	})
+
+	t.Run("should return error when batch is depleted", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := batches.NewService(&repositoryMock, &SectionsMock{}, &ProductsMock{})
+
+		repositoryMock.On("Apply", mock.Anything, 1).Return(domain.Batches{ID: 1}, nil)
+
+		_, err := svc.Dispose(context.Background(), 1, batches.ReasonDamaged)
+
+		assert.ErrorIs(t, err, batches.ErrDepleted)
+	})
+}
+
+func TestHistory(t *testing.T) {
+	t.Run("returns the movements of a batch", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := batches.NewService(&repositoryMock, &SectionsMock{}, &ProductsMock{})
+
+		expected := []domain.BatchMovement{{ID: 1, ProductBatchID: 1, Type: domain.MovementReceipt, Quantity: 10}}
+		repositoryMock.On("Get", mock.Anything, 1).Return(domain.Batches{ID: 1}, nil)
+		repositoryMock.On("Movements", mock.Anything, 1).Return(expected, nil)
+
+		received, err := svc.History(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, received)
+	})
+}
+
+type SectionsMock struct {
+	mock.Mock
+}
+
+func (m *SectionsMock) Get(ctx context.Context, id int) (domain.Section, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(domain.Section), args.Error(1)
+}
+
+type ProductsMock struct {
+	mock.Mock
+}
+
+func (m *ProductsMock) Get(ctx context.Context, id int) (domain.Product, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(domain.Product), args.Error(1)
+}
+
 type RepositoryMock struct {
 	mock.Mock
+	// movements are those returned by the changes passed to Apply.
+	movements []domain.BatchMovement
 }
 
 func (r *RepositoryMock) Exists(ctx context.Context, batchNumber int) bool {
@@ -95,3 +308,33 @@ func (r *RepositoryMock) Save(ctx context.Context, batch domain.Batches) (int, e
 	args := r.Called(ctx, batch)
 	return args.Int(0), args.Error(1)
 }
+
+func (r *RepositoryMock) Get(ctx context.Context, id int) (domain.Batches, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).(domain.Batches), args.Error(1)
+}
+
+func (r *RepositoryMock) List(ctx context.Context, f batches.Filter) ([]domain.Batches, error) {
+	args := r.Called(ctx, f)
+	return args.Get(0).([]domain.Batches), args.Error(1)
+}
+
+// Apply runs change on the batch returned for id, as the repository
+// does on the locked batch.
+func (r *RepositoryMock) Apply(ctx context.Context, id int, change batches.Change) (domain.Batches, error) {
+	args := r.Called(ctx, id)
+	if err := args.Error(1); err != nil {
+		return domain.Batches{}, err
+	}
+	b, m, err := change(args.Get(0).(domain.Batches))
+	if err != nil {
+		return domain.Batches{}, err
+	}
+	r.movements = append(r.movements, m)
+	return b, nil
+}
+
+func (r *RepositoryMock) Movements(ctx context.Context, id int) ([]domain.BatchMovement, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).([]domain.BatchMovement), args.Error(1)
+}
@@ -4,20 +4,52 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 )
 
 // Repository encapsulates the storage of a buyer.
 type Repository interface {
 	GetAll(ctx context.Context) ([]domain.Buyer, error)
 	Get(ctx context.Context, id int) (domain.Buyer, error)
+	// GetByIDs returns the buyers with the given ids, in a single
+	// query. Ids of missing buyers are skipped.
+	GetByIDs(ctx context.Context, ids []int) ([]domain.Buyer, error)
 	Exists(ctx context.Context, cardNumberID string) bool
+	// ExistingCardNumbers returns which of the given card numbers are taken, in a
+	// single query.
+	ExistingCardNumbers(ctx context.Context, cardNumberIDs []string) ([]string, error)
 	Save(ctx context.Context, b domain.Buyer) (int, error)
+	// SaveAll saves buyers in a single insert and returns their ids.
+	// If one of them fails, none is saved.
+	SaveAll(ctx context.Context, buyers []domain.Buyer) ([]int, error)
+	// Update saves b unless the buyer is no longer in b.Version,
+	// in which case it fails with sqlutil.ErrVersionMismatch.
 	Update(ctx context.Context, b domain.Buyer) error
+	// Delete soft deletes a buyer,
+	// if it is in one of the versions expected by ctx.
 	Delete(ctx context.Context, id int) error
+	// Restore undoes the deletion of a buyer.
+	Restore(ctx context.Context, id int) error
 	GetAllPurchaseOrders(ctx context.Context) ([]CountByBuyer, error)
+	// StreamPurchaseOrderCounts calls fn for the purchase order count
+	// of each buyer, as it is read from the database.
+	StreamPurchaseOrderCounts(ctx context.Context, fn func(CountByBuyer) error) error
 	GetPurchaseOrderByID(ctx context.Context, id int) (CountByBuyer, error)
+	// PurchaseOrders returns the orders of a buyer placed between from
+	// and to, both inclusive, oldest first.
+	PurchaseOrders(ctx context.Context, id int, from, to optional.Opt[time.Time]) ([]domain.PurchaseOrder, error)
+	// Spending sums up the orders placed between from and to by the
+	// given buyer, or by every buyer if id is zero.
+	Spending(ctx context.Context, id int, from, to optional.Opt[time.Time]) ([]domain.BuyerSpending, error)
+	// StreamSpending calls fn for the spending of each buyer, as
+	// Spending would return it with an id of zero, as it is read from
+	// the database.
+	StreamSpending(ctx context.Context, from, to optional.Opt[time.Time], fn func(domain.BuyerSpending) error) error
 }
 
 type repository struct {
@@ -31,7 +63,7 @@ func NewRepository(db *sql.DB) Repository {
 }
 
 func (r *repository) GetAll(ctx context.Context) ([]domain.Buyer, error) {
-	query := "SELECT * FROM buyers"
+	query := "SELECT id, card_number_id, first_name, last_name, version, deleted_at FROM buyers WHERE " + sqlutil.NotDeleted(ctx, "deleted_at")
 	rows, err := r.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -41,7 +73,7 @@ func (r *repository) GetAll(ctx context.Context) ([]domain.Buyer, error) {
 
 	for rows.Next() {
 		b := domain.Buyer{}
-		_ = rows.Scan(&b.ID, &b.CardNumberID, &b.FirstName, &b.LastName)
+		_ = rows.Scan(&b.ID, &b.CardNumberID, &b.FirstName, &b.LastName, &b.Version, sqlutil.NullTime(&b.DeletedAt))
 		buyers = append(buyers, b)
 	}
 
@@ -49,10 +81,10 @@ func (r *repository) GetAll(ctx context.Context) ([]domain.Buyer, error) {
 }
 
 func (r *repository) Get(ctx context.Context, id int) (domain.Buyer, error) {
-	query := "SELECT * FROM buyers WHERE id = ?;"
+	query := "SELECT id, card_number_id, first_name, last_name, version, deleted_at FROM buyers WHERE id = ? AND " + sqlutil.NotDeleted(ctx, "deleted_at") + ";"
 	row := r.db.QueryRow(query, id)
 	b := domain.Buyer{}
-	err := row.Scan(&b.ID, &b.CardNumberID, &b.FirstName, &b.LastName)
+	err := row.Scan(&b.ID, &b.CardNumberID, &b.FirstName, &b.LastName, &b.Version, sqlutil.NullTime(&b.DeletedAt))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.Buyer{}, ErrNotFound
@@ -63,6 +95,29 @@ func (r *repository) Get(ctx context.Context, id int) (domain.Buyer, error) {
 	return b, nil
 }
 
+func (r *repository) GetByIDs(ctx context.Context, ids []int) ([]domain.Buyer, error) {
+	if len(ids) == 0 {
+		return []domain.Buyer{}, nil
+	}
+	in, args := sqlutil.In(ids)
+	query := "SELECT id, card_number_id, first_name, last_name, version, deleted_at FROM buyers WHERE id IN " + in + " AND " + sqlutil.NotDeleted(ctx, "deleted_at")
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buyers := []domain.Buyer{}
+	for rows.Next() {
+		b := domain.Buyer{}
+		if err := rows.Scan(&b.ID, &b.CardNumberID, &b.FirstName, &b.LastName, &b.Version, sqlutil.NullTime(&b.DeletedAt)); err != nil {
+			return nil, err
+		}
+		buyers = append(buyers, b)
+	}
+	return buyers, rows.Err()
+}
+
 func (r *repository) Exists(ctx context.Context, cardNumberID string) bool {
 	query := "SELECT card_number_id FROM buyers WHERE card_number_id=?;"
 	row := r.db.QueryRow(query, cardNumberID)
@@ -70,6 +125,10 @@ func (r *repository) Exists(ctx context.Context, cardNumberID string) bool {
 	return err == nil
 }
 
+func (r *repository) ExistingCardNumbers(ctx context.Context, cardNumberIDs []string) ([]string, error) {
+	return sqlutil.ExistingKeys(ctx, r.db, "buyers", "card_number_id", cardNumberIDs)
+}
+
 func (r *repository) Save(ctx context.Context, b domain.Buyer) (int, error) {
 	query := "INSERT INTO buyers(card_number_id,first_name,last_name) VALUES (?,?,?)"
 	stmt, err := r.db.Prepare(query)
@@ -90,14 +149,40 @@ func (r *repository) Save(ctx context.Context, b domain.Buyer) (int, error) {
 	return int(id), nil
 }
 
+func (r *repository) SaveAll(ctx context.Context, buyers []domain.Buyer) ([]int, error) {
+	query := "INSERT INTO buyers(card_number_id,first_name,last_name) VALUES " + sqlutil.Rows(len(buyers), 3)
+	args := make([]any, 0, len(buyers)*3)
+	for _, b := range buyers {
+		args = append(args, b.CardNumberID, b.FirstName, b.LastName)
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(buyers))
+	for i, b := range buyers {
+		keys[i] = b.CardNumberID
+	}
+	ids, err := sqlutil.IDsByKey(ctx, tx, "buyers", "card_number_id", keys)
+	if err != nil {
+		return nil, err
+	}
+	return ids, tx.Commit()
+}
+
 func (r *repository) Update(ctx context.Context, b domain.Buyer) error {
-	query := "UPDATE buyers SET first_name=?, last_name=?  WHERE id=?"
+	query := "UPDATE buyers SET first_name=?, last_name=?, version=version+1 WHERE id=? AND version=? AND deleted_at IS NULL"
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return err
 	}
 
-	res, err := stmt.Exec(&b.FirstName, &b.LastName, &b.ID)
+	res, err := stmt.Exec(&b.FirstName, &b.LastName, &b.ID, &b.Version)
 	if err != nil {
 		return err
 	}
@@ -107,20 +192,20 @@ func (r *repository) Update(ctx context.Context, b domain.Buyer) error {
 		return err
 	}
 	if affected < 1 {
-		return ErrNotFound
+		return sqlutil.StaleOrMissing(ctx, r.db, "buyers", b.ID, ErrNotFound)
 	}
 
 	return nil
 }
 
 func (r *repository) Delete(ctx context.Context, id int) error {
-	query := "DELETE FROM buyers WHERE id = ?"
+	query := "UPDATE buyers SET deleted_at = ?, version = version + 1 WHERE id = ? AND deleted_at IS NULL AND " + sqlutil.VersionMatches(ctx, "version")
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return err
 	}
 
-	res, err := stmt.Exec(id)
+	res, err := stmt.Exec(time.Now().UTC(), id)
 	if err != nil {
 		return err
 	}
@@ -131,34 +216,35 @@ func (r *repository) Delete(ctx context.Context, id int) error {
 	}
 
 	if affected < 1 {
-		return ErrNotFound
+		return sqlutil.StaleOrMissing(ctx, r.db, "buyers", id, ErrNotFound)
 	}
 
 	return nil
 }
 
-func (r *repository) GetAllPurchaseOrders(ctx context.Context) ([]CountByBuyer, error) {
-	const query = `SELECT e.id, e.card_number_id, e.first_name, e.last_name, COUNT(i.id) as purchase_orders_count 
-	FROM buyers e 
-	LEFT JOIN purchase_orders i ON i.buyer_id = e.id 
-	GROUP BY e.id;`
+func (r *repository) Restore(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE buyers SET deleted_at=NULL, version=version+1 WHERE id=? AND deleted_at IS NOT NULL;", id)
+	if err != nil {
+		return err
+	}
 
-	rows, err := r.db.Query(query)
+	affected, err := res.RowsAffected()
 	if err != nil {
-		return []CountByBuyer{}, ErrInternalServerError
+		return err
 	}
-	defer rows.Close()
+	if affected < 1 {
+		return ErrNotFound
+	}
+
+	return nil
+}
 
+func (r *repository) GetAllPurchaseOrders(ctx context.Context) ([]CountByBuyer, error) {
 	var reports []CountByBuyer
-	for rows.Next() {
-		var e CountByBuyer
-		err := rows.Scan(&e.ID, &e.CardNumberID, &e.FirstName, &e.LastName, &e.Count)
-		if err != nil {
-			return []CountByBuyer{}, ErrInternalServerError
-		}
+	err := r.StreamPurchaseOrderCounts(ctx, func(e CountByBuyer) error {
 		reports = append(reports, e)
-	}
-	err = rows.Err()
+		return nil
+	})
 	if err != nil {
 		return []CountByBuyer{}, ErrInternalServerError
 	}
@@ -170,11 +256,36 @@ func (r *repository) GetAllPurchaseOrders(ctx context.Context) ([]CountByBuyer,
 	return reports, nil
 }
 
+func (r *repository) StreamPurchaseOrderCounts(ctx context.Context, fn func(CountByBuyer) error) error {
+	const query = `SELECT e.id, e.card_number_id, e.first_name, e.last_name, COUNT(i.id) as purchase_orders_count 
+	FROM buyers e 
+	LEFT JOIN purchase_orders i ON i.buyer_id = e.id 
+	WHERE e.deleted_at IS NULL
+	GROUP BY e.id;`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e CountByBuyer
+		if err := rows.Scan(&e.ID, &e.CardNumberID, &e.FirstName, &e.LastName, &e.Count); err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
 func (r *repository) GetPurchaseOrderByID(ctx context.Context, id int) (CountByBuyer, error) {
 	const query = `SELECT e.id, e.card_number_id, e.first_name, e.last_name, COUNT(i.id) as purchase_orders_count 
 	FROM buyers e 
 	LEFT JOIN purchase_orders i ON i.buyer_id = e.id 
-	WHERE e.id = ?
+	WHERE e.id = ? AND e.deleted_at IS NULL
 	GROUP BY e.id;`
 
 	row := r.db.QueryRow(query, id)
@@ -190,3 +301,134 @@ func (r *repository) GetPurchaseOrderByID(ctx context.Context, id int) (CountByB
 	return e, nil
 
 }
+
+// orderTotals selects the quantity and total of each purchase order,
+// which are the sum of its order details.
+const orderTotals = `SELECT po.id, SUM(od.quantity) AS quantity,
+		SUM(od.quantity * pr.sale_price) AS total
+	FROM purchase_orders po
+	INNER JOIN order_details od ON od.purchase_order_id = po.id
+	INNER JOIN product_records pr ON pr.id = od.product_record_id
+	GROUP BY po.id`
+
+// orderDateConds returns the conditions on po.order_date for the
+// given range.
+func orderDateConds(from, to optional.Opt[time.Time]) ([]string, []any) {
+	conds := []string{}
+	args := []any{}
+	if t, ok := from.Value(); ok {
+		conds = append(conds, "po.order_date >= ?")
+		args = append(args, t)
+	}
+	if t, ok := to.Value(); ok {
+		conds = append(conds, "po.order_date <= ?")
+		args = append(args, t)
+	}
+	return conds, args
+}
+
+func (r *repository) PurchaseOrders(ctx context.Context, id int, from, to optional.Opt[time.Time]) ([]domain.PurchaseOrder, error) {
+	conds, args := orderDateConds(from, to)
+	conds = append([]string{"po.buyer_id = ?"}, conds...)
+	args = append([]any{id}, args...)
+	query := `SELECT po.id, po.order_number, po.order_date, po.tracking_code, po.buyer_id,
+		po.product_record_id, po.order_status_id, po.carrier_id, po.warehouse_id,
+		COALESCE(t.quantity, 0), pr.sale_price, COALESCE(t.total, 0)
+	FROM purchase_orders po
+	INNER JOIN product_records pr ON pr.id = po.product_record_id
+	LEFT JOIN (` + orderTotals + `) t ON t.id = po.id
+	WHERE ` + strings.Join(conds, " AND ") + `
+	ORDER BY po.order_date, po.id;`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []domain.PurchaseOrder{}
+	for rows.Next() {
+		o := domain.PurchaseOrder{}
+		var carrierID, warehouseID sql.NullInt64
+		err := rows.Scan(&o.ID, &o.OrderNumber, sqlutil.Time(&o.OrderDate), &o.TrackingCode, &o.BuyerID,
+			&o.ProductRecordID, &o.OrderStatusID, &carrierID, &warehouseID,
+			&o.Quantity, &o.UnitPrice, &o.Total)
+		if err != nil {
+			return nil, err
+		}
+		if carrierID.Valid {
+			id := int(carrierID.Int64)
+			o.CarrierID = &id
+		}
+		if warehouseID.Valid {
+			id := int(warehouseID.Int64)
+			o.WarehouseID = &id
+		}
+		orders = append(orders, o)
+	}
+	return orders, rows.Err()
+}
+
+func (r *repository) Spending(ctx context.Context, id int, from, to optional.Opt[time.Time]) ([]domain.BuyerSpending, error) {
+	report := []domain.BuyerSpending{}
+	err := r.spending(ctx, id, from, to, func(s domain.BuyerSpending) error {
+		report = append(report, s)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (r *repository) StreamSpending(ctx context.Context, from, to optional.Opt[time.Time], fn func(domain.BuyerSpending) error) error {
+	return r.spending(ctx, 0, from, to, fn)
+}
+
+// spending calls fn for the spending of the given buyer, or of every
+// buyer if id is zero.
+func (r *repository) spending(ctx context.Context, id int, from, to optional.Opt[time.Time], fn func(domain.BuyerSpending) error) error {
+	conds, args := orderDateConds(from, to)
+	orderWhere := ""
+	if len(conds) > 0 {
+		orderWhere = " WHERE " + strings.Join(conds, " AND ")
+	}
+	buyerWhere := " WHERE b.deleted_at IS NULL"
+	if id != 0 {
+		buyerWhere += " AND b.id = ?"
+		args = append(args, id)
+	}
+	query := `SELECT b.id, b.card_number_id, b.first_name, b.last_name,
+		COUNT(o.id), COALESCE(SUM(o.total), 0), MAX(o.order_date)
+	FROM buyers b
+	LEFT JOIN (
+		SELECT po.id, po.buyer_id, po.order_date, COALESCE(t.total, 0) AS total
+		FROM purchase_orders po
+		LEFT JOIN (` + orderTotals + `) t ON t.id = po.id` + orderWhere + `
+	) o ON o.buyer_id = b.id` + buyerWhere + `
+	GROUP BY b.id, b.card_number_id, b.first_name, b.last_name
+	ORDER BY b.id;`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		s := domain.BuyerSpending{}
+		var last time.Time
+		err := rows.Scan(&s.ID, &s.CardNumberID, &s.FirstName, &s.LastName,
+			&s.OrdersCount, &s.TotalSpent, sqlutil.Time(&last))
+		if err != nil {
+			return err
+		}
+		if !last.IsZero() {
+			s.LastOrderDate = &last
+		}
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
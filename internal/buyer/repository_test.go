@@ -2,10 +2,13 @@ package buyer_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/buyer"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
 	"github.com/stretchr/testify/assert"
 )
@@ -45,6 +48,7 @@ func TestUpdate(t *testing.T) {
 			CardNumberID: "1234567890",
 			FirstName:    "Juan",
 			LastName:     "Perez",
+			Version:      1,
 		}
 		err := repo.Update(context.Background(), buyer)
 		assert.Nil(t, err)
@@ -53,19 +57,31 @@ func TestUpdate(t *testing.T) {
 	})
 }
 func TestDelete(t *testing.T) {
-	t.Run("error when delete buyer", func(t *testing.T) {
+	t.Run("delete buyer with purchase orders", func(t *testing.T) {
 		db := testutil.InitDatabase(t)
 		defer db.Close()
 		repo := buyer.NewRepository(db)
 		err := repo.Delete(context.Background(), 1)
-		assert.Error(t, err)
+		assert.NoError(t, err)
+		_, err = repo.Get(context.Background(), 1)
+		assert.ErrorIs(t, err, buyer.ErrNotFound)
 	})
-	t.Run("delete buyer", func(t *testing.T) {
+	t.Run("error when buyer is already deleted", func(t *testing.T) {
 		db := testutil.InitDatabase(t)
 		defer db.Close()
 		repo := buyer.NewRepository(db)
+		assert.NoError(t, repo.Delete(context.Background(), 2))
 		err := repo.Delete(context.Background(), 2)
-		assert.NotNil(t, err)
+		assert.ErrorIs(t, err, buyer.ErrNotFound)
+	})
+	t.Run("restore buyer", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+		repo := buyer.NewRepository(db)
+		assert.NoError(t, repo.Delete(context.Background(), 2))
+		assert.NoError(t, repo.Restore(context.Background(), 2))
+		_, err := repo.Get(context.Background(), 2)
+		assert.NoError(t, err)
 	})
 }
 func TestExists(t *testing.T) {
@@ -134,4 +150,66 @@ func TestGetPurchaseOrderByID(t *testing.T) {
 		_, err := repo.GetPurchaseOrderByID(context.Background(), 100)
 		assert.Error(t, err)
 	})
+	t.Run("Leaves out soft deleted buyers", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+		repo := buyer.NewRepository(db)
+		assert.NoError(t, repo.Delete(context.Background(), 1))
+		_, err := repo.GetPurchaseOrderByID(context.Background(), 1)
+		assert.ErrorIs(t, err, buyer.ErrNotFound)
+		purchaseOrders, _ := repo.GetAllPurchaseOrders(context.Background())
+		assert.Equal(t, 1, len(purchaseOrders))
+	})
+}
+
+func TestSpendingRepository(t *testing.T) {
+	db := testutil.InitDatabase(t)
+	defer db.Close()
+	repo := buyer.NewRepository(db)
+
+	id, err := repo.Save(context.Background(), domain.Buyer{CardNumberID: "000777", FirstName: "meli", LastName: "osasco"})
+	assert.NoError(t, err)
+	res, err := db.Exec(`INSERT INTO product_records(last_update_date,purchase_price,sale_price,product_id)
+		VALUES ('2023-01-01',5,12.5,1);`)
+	assert.NoError(t, err)
+	recordID, _ := res.LastInsertId()
+	for i, date := range []string{"2023-02-01", "2023-03-01"} {
+		res, err := db.Exec(`INSERT INTO purchase_orders(order_number,order_date,tracking_code,buyer_id,order_status_id,product_record_id)
+			VALUES (?,?,?,?,1,?);`, fmt.Sprintf("SPEND-%d", i), date, fmt.Sprintf("SPEND-TRACK-%d", i), id, recordID)
+		assert.NoError(t, err)
+		orderID, _ := res.LastInsertId()
+		_, err = db.Exec(`INSERT INTO order_details(clean_liness_status,quantity,temperature,product_record_id,purchase_order_id)
+			VALUES ('good',?,0,?,?);`, i+1, recordID, orderID)
+		assert.NoError(t, err)
+	}
+
+	t.Run("Gets the orders of a buyer with their totals", func(t *testing.T) {
+		orders, err := repo.PurchaseOrders(context.Background(), id, optional.Opt[time.Time]{}, optional.Opt[time.Time]{})
+		assert.NoError(t, err)
+		assert.Len(t, orders, 2)
+		assert.Equal(t, 2, orders[1].Quantity)
+		assert.Equal(t, 12.5, orders[1].UnitPrice)
+		assert.Equal(t, 25.0, orders[1].Total)
+	})
+	t.Run("Sums up the spending of a buyer in a date range", func(t *testing.T) {
+		from := *optional.FromVal(time.Date(2023, 2, 15, 0, 0, 0, 0, time.UTC))
+		report, err := repo.Spending(context.Background(), id, from, optional.Opt[time.Time]{})
+		assert.NoError(t, err)
+		assert.Len(t, report, 1)
+		assert.Equal(t, "000777", report[0].CardNumberID)
+		assert.Equal(t, 1, report[0].OrdersCount)
+		assert.Equal(t, 25.0, report[0].TotalSpent)
+		assert.Equal(t, time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), *report[0].LastOrderDate)
+	})
+	t.Run("Leaves out soft deleted buyers", func(t *testing.T) {
+		assert.NoError(t, repo.Delete(context.Background(), id))
+		report, err := repo.Spending(context.Background(), id, optional.Opt[time.Time]{}, optional.Opt[time.Time]{})
+		assert.NoError(t, err)
+		assert.Empty(t, report)
+		err = repo.StreamSpending(context.Background(), optional.Opt[time.Time]{}, optional.Opt[time.Time]{}, func(s domain.BuyerSpending) error {
+			assert.NotEqual(t, id, s.ID)
+			return nil
+		})
+		assert.NoError(t, err)
+	})
 }
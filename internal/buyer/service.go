@@ -3,8 +3,12 @@ package buyer
 import (
 	"context"
 	"errors"
+	"math"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 )
 
 // Error definitions
@@ -18,7 +22,7 @@ var (
 
 type CountByBuyer struct {
 	ID           int
-	CardNumberID int
+	CardNumberID string
 	FirstName    string
 	LastName     string
 	Count        int
@@ -29,9 +33,32 @@ type Service interface {
 	Create(ctx context.Context, b domain.BuyerCreate) (domain.Buyer, error)
 	GetAll(ctx context.Context) ([]domain.Buyer, error)
 	Get(ctx context.Context, id int) (domain.Buyer, error)
+	// GetByIDs returns the buyers with the given ids, skipping those
+	// that don't exist.
+	GetByIDs(ctx context.Context, ids []int) ([]domain.Buyer, error)
+	// CreateBatch creates buyers, saving all the valid ones at once.
+	// The result and error of each buyer are at its index.
+	CreateBatch(ctx context.Context, buyers []domain.BuyerCreate) ([]domain.Buyer, []error)
 	Update(ctx context.Context, b domain.Buyer, id int) (domain.Buyer, error)
+	// UpdateBatch updates the buyers with the given ids like Update
+	// does. The result and error of each buyer are at its index.
+	UpdateBatch(ctx context.Context, ids []int, buyers []domain.Buyer) ([]domain.Buyer, []error)
 	Delete(ctx context.Context, id int) error
+	// Restore undoes the deletion of a buyer.
+	Restore(ctx context.Context, id int) (domain.Buyer, error)
 	CountPurchaseOrders(ctx context.Context, id int) ([]CountByBuyer, error)
+	// StreamPurchaseOrderCounts calls fn for the purchase order count
+	// of every buyer without loading them all in memory.
+	StreamPurchaseOrderCounts(ctx context.Context, fn func(CountByBuyer) error) error
+	// PurchaseOrders returns the orders of a buyer placed between from
+	// and to, both inclusive. Either bound may be omitted.
+	PurchaseOrders(ctx context.Context, id int, from, to optional.Opt[time.Time]) ([]domain.PurchaseOrder, error)
+	// Spending reports how much the given buyer, or every buyer if id
+	// is zero, spent in orders placed between from and to.
+	Spending(ctx context.Context, id int, from, to optional.Opt[time.Time]) ([]domain.BuyerSpending, error)
+	// StreamSpending calls fn for the spending of every buyer, as
+	// Spending reports it, without loading them all in memory.
+	StreamSpending(ctx context.Context, from, to optional.Opt[time.Time], fn func(domain.BuyerSpending) error) error
 }
 
 type service struct {
@@ -58,22 +85,115 @@ func (s *service) Create(ctx context.Context, b domain.BuyerCreate) (domain.Buye
 	return buyerDomain, nil
 }
 
+func (s *service) CreateBatch(ctx context.Context, buyers []domain.BuyerCreate) ([]domain.Buyer, []error) {
+	created := make([]domain.Buyer, len(buyers))
+	errs := make([]error, len(buyers))
+	var valid []domain.Buyer
+	var indexes []int
+	keys := make([]string, len(buyers))
+	for i, b := range buyers {
+		keys[i] = b.CardNumberID
+	}
+	taken, err := s.repository.ExistingCardNumbers(ctx, keys)
+	if err != nil {
+		for i := range errs {
+			errs[i] = ErrSavingBuyer
+		}
+		return created, errs
+	}
+	cardNumbers := map[string]bool{}
+	for _, cardNumber := range taken {
+		cardNumbers[cardNumber] = true
+	}
+	for i, b := range buyers {
+		if cardNumbers[b.CardNumberID] {
+			errs[i] = ErrAlreadyExists
+			continue
+		}
+		cardNumbers[b.CardNumberID] = true
+		created[i] = *mapCreateToDomain(b)
+		valid = append(valid, created[i])
+		indexes = append(indexes, i)
+	}
+	if len(valid) == 0 {
+		return created, errs
+	}
+
+	ids, err := s.repository.SaveAll(ctx, valid)
+	if err != nil {
+		// One buyer failed the whole insert, so they are saved one by
+		// one to tell which.
+		ids = make([]int, len(valid))
+		for j, b := range valid {
+			ids[j], errs[indexes[j]] = s.repository.Save(ctx, b)
+		}
+	}
+	for j, i := range indexes {
+		if errs[i] != nil {
+			errs[i] = ErrSavingBuyer
+			continue
+		}
+		created[i].ID = ids[j]
+	}
+	return created, errs
+}
+
 func (s *service) Update(ctx context.Context, b domain.Buyer, id int) (domain.Buyer, error) {
 	buyer, err := s.repository.Get(ctx, id)
 	if err != nil {
 		return domain.Buyer{}, errors.New("error getting buyer")
 	}
+	return s.update(ctx, buyer, b)
+}
+
+func (s *service) UpdateBatch(ctx context.Context, ids []int, buyers []domain.Buyer) ([]domain.Buyer, []error) {
+	errs := make([]error, len(ids))
+	updated := make([]domain.Buyer, len(ids))
+	current, err := s.repository.GetByIDs(ctx, ids)
+	if err != nil {
+		for i := range errs {
+			errs[i] = ErrInternalServerError
+		}
+		return updated, errs
+	}
+	byID := make(map[int]domain.Buyer, len(current))
+	for _, b := range current {
+		byID[b.ID] = b
+	}
+	for i, id := range ids {
+		buyer, ok := byID[id]
+		if !ok {
+			errs[i] = ErrNotFound
+			continue
+		}
+		updated[i], errs[i] = s.update(ctx, buyer, buyers[i])
+		if errs[i] == nil {
+			byID[id] = updated[i]
+		}
+	}
+	return updated, errs
+}
+
+// update saves the non empty names of b over buyer.
+func (s *service) update(ctx context.Context, buyer, b domain.Buyer) (domain.Buyer, error) {
+	if err := sqlutil.CheckVersion(ctx, buyer.Version); err != nil {
+		return domain.Buyer{}, err
+	}
 	if b.FirstName != "" {
 		buyer.FirstName = b.FirstName
 	}
 	if b.LastName != "" {
 		buyer.LastName = b.LastName
 	}
-	err = s.repository.Update(ctx, buyer)
+	err := s.repository.Update(ctx, buyer)
+	if errors.Is(err, sqlutil.ErrVersionMismatch) {
+		return domain.Buyer{}, err
+	}
 	if err != nil {
 		return domain.Buyer{}, ErrNotFound
 	}
 
+	buyer.Version++
 	return buyer, nil
 }
 
@@ -86,6 +206,15 @@ func (s *service) GetAll(ctx context.Context) ([]domain.Buyer, error) {
 	return b, nil
 }
 
+func (s *service) GetByIDs(ctx context.Context, ids []int) ([]domain.Buyer, error) {
+	b, err := s.repository.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+
+	return b, nil
+}
+
 func (s *service) Get(ctx context.Context, id int) (domain.Buyer, error) {
 	b, err := s.repository.Get(ctx, id)
 	if err != nil {
@@ -97,6 +226,9 @@ func (s *service) Get(ctx context.Context, id int) (domain.Buyer, error) {
 
 func (s *service) Delete(ctx context.Context, id int) error {
 	err := s.repository.Delete(ctx, id)
+	if errors.Is(err, sqlutil.ErrVersionMismatch) {
+		return err
+	}
 	if err != nil {
 		return ErrNotFound
 	}
@@ -104,6 +236,18 @@ func (s *service) Delete(ctx context.Context, id int) error {
 	return nil
 }
 
+func (s *service) Restore(ctx context.Context, id int) (domain.Buyer, error) {
+	err := s.repository.Restore(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return domain.Buyer{}, ErrNotFound
+	}
+	if err != nil {
+		return domain.Buyer{}, ErrInternalServerError
+	}
+
+	return s.repository.Get(ctx, id)
+}
+
 func (s *service) CountPurchaseOrders(ctx context.Context, id int) ([]CountByBuyer, error) {
 	if id == 0 {
 		e, err := s.repository.GetAllPurchaseOrders(ctx)
@@ -121,6 +265,62 @@ func (s *service) CountPurchaseOrders(ctx context.Context, id int) ([]CountByBuy
 	return []CountByBuyer{e}, nil
 }
 
+func (s *service) StreamPurchaseOrderCounts(ctx context.Context, fn func(CountByBuyer) error) error {
+	if err := s.repository.StreamPurchaseOrderCounts(ctx, fn); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+func (s *service) PurchaseOrders(ctx context.Context, id int, from, to optional.Opt[time.Time]) ([]domain.PurchaseOrder, error) {
+	if _, err := s.repository.Get(ctx, id); err != nil {
+		return nil, ErrNotFound
+	}
+	orders, err := s.repository.PurchaseOrders(ctx, id, from, to)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	return orders, nil
+}
+
+func (s *service) Spending(ctx context.Context, id int, from, to optional.Opt[time.Time]) ([]domain.BuyerSpending, error) {
+	report, err := s.repository.Spending(ctx, id, from, to)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	if id != 0 && len(report) == 0 {
+		return nil, ErrNotFound
+	}
+	for i := range report {
+		summarizeSpending(&report[i])
+	}
+	return report, nil
+}
+
+func (s *service) StreamSpending(ctx context.Context, from, to optional.Opt[time.Time], fn func(domain.BuyerSpending) error) error {
+	err := s.repository.StreamSpending(ctx, from, to, func(r domain.BuyerSpending) error {
+		summarizeSpending(&r)
+		return fn(r)
+	})
+	if err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+// summarizeSpending sets the average order value of r and rounds its
+// total to cents.
+func summarizeSpending(r *domain.BuyerSpending) {
+	if r.OrdersCount > 0 {
+		r.AverageOrderValue = roundCents(r.TotalSpent / float64(r.OrdersCount))
+	}
+	r.TotalSpent = roundCents(r.TotalSpent)
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
 func mapCreateToDomain(b domain.BuyerCreate) *domain.Buyer {
 	return &domain.Buyer{
 		CardNumberID: b.CardNumberID,
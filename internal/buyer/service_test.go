@@ -4,9 +4,12 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/buyer"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -170,6 +173,7 @@ func TestUpdateBuyer(t *testing.T) {
 
 		returned, err := svc.Update(context.TODO(), buyerUpdate, 12)
 
+		buyerUpdate.Version = 1
 		assert.NoError(t, err)
 		assert.Equal(t, buyerUpdate, returned)
 	})
@@ -214,6 +218,54 @@ func TestUpdateBuyer(t *testing.T) {
 
 		assert.ErrorIs(t, err, buyer.ErrNotFound)
 	})
+	t.Run("Update buyer in another version", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := buyer.NewService(&repositoryMock)
+
+		repositoryMock.On("Get", mock.Anything, 12).Return(domain.Buyer{ID: 12, Version: 2}, nil)
+
+		ctx := sqlutil.WithIfMatch(context.TODO(), []int{1})
+		_, err := svc.Update(ctx, domain.Buyer{FirstName: "lucas"}, 12)
+
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+		repositoryMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+}
+
+func TestBatchBuyer(t *testing.T) {
+	t.Run("Create the valid buyers in a single save", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := buyer.NewService(&repositoryMock)
+
+		buyers := []domain.BuyerCreate{
+			{CardNumberID: "123", FirstName: "nome", LastName: "sobrenome"},
+			{CardNumberID: "123", FirstName: "outro", LastName: "sobrenome"},
+			{CardNumberID: "456", FirstName: "nome", LastName: "sobrenome"},
+		}
+		repositoryMock.On("ExistingCardNumbers", mock.Anything, []string{"123", "123", "456"}).Return([]string{"456"}, nil)
+		repositoryMock.On("SaveAll", mock.Anything, []domain.Buyer{{CardNumberID: "123", FirstName: "nome", LastName: "sobrenome"}}).Return([]int{3}, nil)
+
+		created, errs := svc.CreateBatch(context.TODO(), buyers)
+
+		assert.Equal(t, []error{nil, buyer.ErrAlreadyExists, buyer.ErrAlreadyExists}, errs)
+		assert.Equal(t, 3, created[0].ID)
+	})
+	t.Run("Update the buyers found", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := buyer.NewService(&repositoryMock)
+
+		current := domain.Buyer{ID: 1, CardNumberID: "123", FirstName: "nome", LastName: "sobrenome", Version: 1}
+		updated := current
+		updated.FirstName = "novo"
+		repositoryMock.On("GetByIDs", mock.Anything, []int{1, 2}).Return([]domain.Buyer{current}, nil)
+		repositoryMock.On("Update", mock.Anything, updated).Return(nil)
+
+		received, errs := svc.UpdateBatch(context.TODO(), []int{1, 2}, []domain.Buyer{{FirstName: "novo"}, {FirstName: "novo"}})
+
+		updated.Version = 2
+		assert.Equal(t, []error{nil, buyer.ErrNotFound}, errs)
+		assert.Equal(t, updated, received[0])
+	})
 }
 
 func TestDeleteBuyer(t *testing.T) {
@@ -240,6 +292,30 @@ func TestDeleteBuyer(t *testing.T) {
 		assert.Equal(t, errors.New("buyer not found"), err)
 	})
 }
+
+func TestRestoreBuyer(t *testing.T) {
+	t.Run("restores a deleted buyer", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := buyer.NewService(&repositoryMock)
+		expected := domain.Buyer{ID: 1, CardNumberID: "123"}
+
+		repositoryMock.On("Restore", mock.Anything, expected.ID).Return(nil)
+		repositoryMock.On("Get", mock.Anything, expected.ID).Return(expected, nil)
+		restored, err := svc.Restore(context.TODO(), expected.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, restored)
+	})
+	t.Run("returns not found when the buyer is not deleted", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := buyer.NewService(&repositoryMock)
+
+		repositoryMock.On("Restore", mock.Anything, 1).Return(buyer.ErrNotFound)
+		_, err := svc.Restore(context.TODO(), 1)
+
+		assert.ErrorIs(t, err, buyer.ErrNotFound)
+	})
+}
 func TestGetCountPurchaseOrders(t *testing.T) {
 	t.Run("return correct report for valid id", func(t *testing.T) {
 		mockedRepository := RepositoryMock{}
@@ -247,7 +323,7 @@ func TestGetCountPurchaseOrders(t *testing.T) {
 
 		r := buyer.CountByBuyer{
 			ID:           1,
-			CardNumberID: 10,
+			CardNumberID: "0010",
 			FirstName:    "melicidade",
 			LastName:     "osasco",
 			Count:        10,
@@ -264,14 +340,14 @@ func TestGetCountPurchaseOrders(t *testing.T) {
 
 		r := []buyer.CountByBuyer{{
 			ID:           1,
-			CardNumberID: 10,
+			CardNumberID: "0010",
 			FirstName:    "melicidade",
 			LastName:     "osasco",
 			Count:        10,
 		},
 			{
 				ID:           2,
-				CardNumberID: 20,
+				CardNumberID: "0020",
 				FirstName:    "melicidade-2",
 				LastName:     "osasco-2",
 				Count:        20,
@@ -304,6 +380,86 @@ type RepositoryMock struct {
 	mock.Mock
 }
 
+func TestPurchaseOrders(t *testing.T) {
+	t.Run("returns the orders of a buyer", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := buyer.NewService(&mockedRepository)
+
+		from := *optional.FromVal(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+		to := *optional.New[time.Time]()
+		expected := []domain.PurchaseOrder{{ID: 3, BuyerID: 1, Quantity: 2, UnitPrice: 10, Total: 20}}
+		mockedRepository.On("Get", mock.Anything, 1).Return(domain.Buyer{ID: 1}, nil)
+		mockedRepository.On("PurchaseOrders", mock.Anything, 1, from, to).Return(expected, nil)
+
+		orders, err := s.PurchaseOrders(context.TODO(), 1, from, to)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, orders)
+	})
+	t.Run("returns a error when buyer is not found", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := buyer.NewService(&mockedRepository)
+
+		mockedRepository.On("Get", mock.Anything, 1).Return(domain.Buyer{}, buyer.ErrNotFound)
+
+		_, err := s.PurchaseOrders(context.TODO(), 1, optional.Opt[time.Time]{}, optional.Opt[time.Time]{})
+		assert.ErrorIs(t, err, buyer.ErrNotFound)
+	})
+}
+
+func TestSpending(t *testing.T) {
+	t.Run("computes the average order value", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := buyer.NewService(&mockedRepository)
+
+		last := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+		mockedRepository.On("Spending", mock.Anything, 0, mock.Anything, mock.Anything).Return([]domain.BuyerSpending{
+			{ID: 1, CardNumberID: "0010", OrdersCount: 3, TotalSpent: 100, LastOrderDate: &last},
+			{ID: 2, CardNumberID: "0020"},
+		}, nil)
+
+		report, err := s.Spending(context.TODO(), 0, optional.Opt[time.Time]{}, optional.Opt[time.Time]{})
+		assert.NoError(t, err)
+		assert.Equal(t, 33.33, report[0].AverageOrderValue)
+		assert.Equal(t, 100.0, report[0].TotalSpent)
+		assert.Equal(t, 0.0, report[1].AverageOrderValue)
+		assert.Nil(t, report[1].LastOrderDate)
+	})
+	t.Run("returns a error when buyer is not found", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := buyer.NewService(&mockedRepository)
+
+		mockedRepository.On("Spending", mock.Anything, 5, mock.Anything, mock.Anything).Return([]domain.BuyerSpending{}, nil)
+
+		_, err := s.Spending(context.TODO(), 5, optional.Opt[time.Time]{}, optional.Opt[time.Time]{})
+		assert.ErrorIs(t, err, buyer.ErrNotFound)
+	})
+	t.Run("streams the average order value of every buyer", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := buyer.NewService(&mockedRepository)
+
+		mockedRepository.On("StreamSpending", mock.Anything, mock.Anything, mock.Anything).Return([]domain.BuyerSpending{
+			{ID: 1, CardNumberID: "0010", OrdersCount: 3, TotalSpent: 100},
+		}, nil)
+
+		var report []domain.BuyerSpending
+		err := s.StreamSpending(context.TODO(), optional.Opt[time.Time]{}, optional.Opt[time.Time]{}, func(r domain.BuyerSpending) error {
+			report = append(report, r)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 33.33, report[0].AverageOrderValue)
+	})
+	t.Run("returns a error when the spending cannot be streamed", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := buyer.NewService(&mockedRepository)
+
+		mockedRepository.On("StreamSpending", mock.Anything, mock.Anything, mock.Anything).Return([]domain.BuyerSpending{}, errors.New("connection lost"))
+
+		err := s.StreamSpending(context.TODO(), optional.Opt[time.Time]{}, optional.Opt[time.Time]{}, func(domain.BuyerSpending) error { return nil })
+		assert.ErrorIs(t, err, buyer.ErrInternalServerError)
+	})
+}
+
 func (r *RepositoryMock) GetAll(ctx context.Context) ([]domain.Buyer, error) {
 	args := r.Called(ctx)
 	return args.Get(0).([]domain.Buyer), args.Error(1)
@@ -314,16 +470,31 @@ func (r *RepositoryMock) Get(ctx context.Context, id int) (domain.Buyer, error)
 	return args.Get(0).(domain.Buyer), args.Error(1)
 }
 
+func (r *RepositoryMock) GetByIDs(ctx context.Context, ids []int) ([]domain.Buyer, error) {
+	args := r.Called(ctx, ids)
+	return args.Get(0).([]domain.Buyer), args.Error(1)
+}
+
 func (r *RepositoryMock) Exists(ctx context.Context, cardNumberID string) bool {
 	args := r.Called(ctx, cardNumberID)
 	return args.Get(0).(bool)
 }
 
+func (r *RepositoryMock) ExistingCardNumbers(ctx context.Context, cardNumberIDs []string) ([]string, error) {
+	args := r.Called(ctx, cardNumberIDs)
+	return args.Get(0).([]string), args.Error(1)
+}
+
 func (r *RepositoryMock) Save(ctx context.Context, s domain.Buyer) (int, error) {
 	args := r.Called(ctx, s)
 	return args.Get(0).(int), args.Error(1)
 }
 
+func (r *RepositoryMock) SaveAll(ctx context.Context, buyers []domain.Buyer) ([]int, error) {
+	args := r.Called(ctx, buyers)
+	return args.Get(0).([]int), args.Error(1)
+}
+
 func (r *RepositoryMock) Update(ctx context.Context, s domain.Buyer) error {
 	args := r.Called(ctx, s)
 	return args.Error(0)
@@ -333,11 +504,45 @@ func (r *RepositoryMock) Delete(ctx context.Context, id int) error {
 	args := r.Called(ctx, id)
 	return args.Error(0)
 }
+
+func (r *RepositoryMock) Restore(ctx context.Context, id int) error {
+	args := r.Called(ctx, id)
+	return args.Error(0)
+}
 func (r *RepositoryMock) GetAllPurchaseOrders(ctx context.Context) ([]buyer.CountByBuyer, error) {
 	args := r.Called(ctx)
 	return args.Get(0).([]buyer.CountByBuyer), args.Error(1)
 }
+func (r *RepositoryMock) StreamPurchaseOrderCounts(ctx context.Context, fn func(buyer.CountByBuyer) error) error {
+	args := r.Called(ctx)
+	for _, entry := range args.Get(0).([]buyer.CountByBuyer) {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
 func (r *RepositoryMock) GetPurchaseOrderByID(ctx context.Context, id int) (buyer.CountByBuyer, error) {
 	args := r.Called(ctx, id)
 	return args.Get(0).(buyer.CountByBuyer), args.Error(1)
 }
+
+func (r *RepositoryMock) PurchaseOrders(ctx context.Context, id int, from, to optional.Opt[time.Time]) ([]domain.PurchaseOrder, error) {
+	args := r.Called(ctx, id, from, to)
+	return args.Get(0).([]domain.PurchaseOrder), args.Error(1)
+}
+
+func (r *RepositoryMock) Spending(ctx context.Context, id int, from, to optional.Opt[time.Time]) ([]domain.BuyerSpending, error) {
+	args := r.Called(ctx, id, from, to)
+	return args.Get(0).([]domain.BuyerSpending), args.Error(1)
+}
+
+func (r *RepositoryMock) StreamSpending(ctx context.Context, from, to optional.Opt[time.Time], fn func(domain.BuyerSpending) error) error {
+	args := r.Called(ctx, from, to)
+	for _, entry := range args.Get(0).([]domain.BuyerSpending) {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
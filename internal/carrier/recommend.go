@@ -0,0 +1,43 @@
+package carrier
+
+import (
+	"sort"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+)
+
+var coverageOrder = map[string]int{
+	domain.CoverageLocality: 0,
+	domain.CoverageProvince: 1,
+	domain.CoverageCountry:  2,
+}
+
+var coverageReasons = map[string]string{
+	domain.CoverageLocality: "serves the locality of the warehouse",
+	domain.CoverageProvince: "serves the province of the warehouse",
+	domain.CoverageCountry:  "serves the country of the warehouse",
+}
+
+// Rank orders candidates from the closest coverage to the farthest,
+// then by company name, and sets their rank and reason.
+func Rank(candidates []domain.CarrierCandidate) []domain.CarrierCandidate {
+	ranked := make([]domain.CarrierCandidate, len(candidates))
+	copy(ranked, candidates)
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if coverageOrder[a.Coverage] != coverageOrder[b.Coverage] {
+			return coverageOrder[a.Coverage] < coverageOrder[b.Coverage]
+		}
+		if a.CompanyName != b.CompanyName {
+			return a.CompanyName < b.CompanyName
+		}
+		return a.ID < b.ID
+	})
+
+	for i := range ranked {
+		ranked[i].Rank = i + 1
+		ranked[i].Reason = coverageReasons[ranked[i].Coverage]
+	}
+	return ranked
+}
@@ -3,14 +3,34 @@ package carrier
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 )
 
 type Repository interface {
 	Create(ctx context.Context, p domain.Carrier) (int, error)
-	Exists(ctx context.Context, cid int) bool
+	Exists(ctx context.Context, cid string) bool
+	GetAll(ctx context.Context) ([]domain.Carrier, error)
+	Get(ctx context.Context, id int) (domain.Carrier, error)
+	// Update saves c unless the carrier is no longer in c.Version,
+	// in which case it fails with sqlutil.ErrVersionMismatch.
+	Update(ctx context.Context, c domain.Carrier) error
+	// Delete soft deletes a carrier,
+	// if it is in one of the versions expected by ctx.
+	Delete(ctx context.Context, id int) error
+	// Restore undoes the deletion of a carrier.
+	Restore(ctx context.Context, id int) error
+	// CountPurchaseOrders returns how many purchase orders
+	// are assigned to the carrier.
+	CountPurchaseOrders(ctx context.Context, id int) (int, error)
+	// Coverage returns the carriers in the country of the warehouse,
+	// with the closest level at which each one serves it. Candidates
+	// are neither ranked nor given a reason.
+	Coverage(ctx context.Context, warehouseID int) ([]domain.CarrierCandidate, error)
 }
 
 type repository struct {
@@ -23,7 +43,7 @@ func NewRepository(db *sql.DB) Repository {
 	}
 }
 
-func (r *repository) Exists(ctx context.Context, cid int) bool {
+func (r *repository) Exists(ctx context.Context, cid string) bool {
 	query := "SELECT cid FROM carriers WHERE cid=?;"
 	row := r.db.QueryRow(query, cid)
 	err := row.Scan(&cid)
@@ -40,7 +60,6 @@ func (r *repository) Create(ctx context.Context, i domain.Carrier) (int, error)
 	res, err := stmt.Exec(i.CID, i.CompanyName, i.Address, i.Telephone, i.LocalityID)
 	if err != nil {
 		if strings.HasPrefix(err.Error(), "Error 1452") {
-			println(err.Error())
 			return 0, ErrLocalityIDNotFound
 		}
 		return 0, err
@@ -53,3 +72,144 @@ func (r *repository) Create(ctx context.Context, i domain.Carrier) (int, error)
 
 	return int(id), nil
 }
+
+func (r *repository) GetAll(ctx context.Context) ([]domain.Carrier, error) {
+	query := "SELECT id, cid, company_name, address, telephone, locality_id, version, deleted_at FROM carriers WHERE " + sqlutil.NotDeleted(ctx, "deleted_at") + ";"
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	carriers := []domain.Carrier{}
+	for rows.Next() {
+		c := domain.Carrier{}
+		if err := rows.Scan(&c.ID, &c.CID, &c.CompanyName, &c.Address, &c.Telephone, &c.LocalityID, &c.Version, sqlutil.NullTime(&c.DeletedAt)); err != nil {
+			return nil, err
+		}
+		carriers = append(carriers, c)
+	}
+	return carriers, rows.Err()
+}
+
+func (r *repository) Get(ctx context.Context, id int) (domain.Carrier, error) {
+	query := "SELECT id, cid, company_name, address, telephone, locality_id, version, deleted_at FROM carriers WHERE id = ? AND " + sqlutil.NotDeleted(ctx, "deleted_at") + ";"
+	row := r.db.QueryRowContext(ctx, query, id)
+
+	c := domain.Carrier{}
+	err := row.Scan(&c.ID, &c.CID, &c.CompanyName, &c.Address, &c.Telephone, &c.LocalityID, &c.Version, sqlutil.NullTime(&c.DeletedAt))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Carrier{}, ErrNotFound
+	}
+	if err != nil {
+		return domain.Carrier{}, err
+	}
+	return c, nil
+}
+
+func (r *repository) Update(ctx context.Context, c domain.Carrier) error {
+	query := "UPDATE carriers SET cid=?, company_name=?, address=?, telephone=?, locality_id=?, version=version+1 WHERE id=? AND version=? AND deleted_at IS NULL;"
+	res, err := r.db.ExecContext(ctx, query, c.CID, c.CompanyName, c.Address, c.Telephone, c.LocalityID, c.ID, c.Version)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "Error 1452") {
+			return ErrLocalityIDNotFound
+		}
+		if strings.HasPrefix(err.Error(), "Error 1062") {
+			return ErrAlreadyExists
+		}
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected < 1 {
+		return sqlutil.StaleOrMissing(ctx, r.db, "carriers", c.ID, ErrNotFound)
+	}
+	return nil
+}
+
+func (r *repository) Delete(ctx context.Context, id int) error {
+	query := "UPDATE carriers SET deleted_at = ?, version = version + 1 WHERE id = ? AND deleted_at IS NULL AND " + sqlutil.VersionMatches(ctx, "version") + ";"
+	res, err := r.db.ExecContext(ctx, query, time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected < 1 {
+		return sqlutil.StaleOrMissing(ctx, r.db, "carriers", id, ErrNotFound)
+	}
+	return nil
+}
+
+func (r *repository) Restore(ctx context.Context, id int) error {
+	query := "UPDATE carriers SET deleted_at = NULL, version = version + 1 WHERE id = ? AND deleted_at IS NOT NULL;"
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected < 1 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *repository) CountPurchaseOrders(ctx context.Context, id int) (int, error) {
+	query := "SELECT COUNT(*) FROM purchase_orders WHERE carrier_id = ?;"
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *repository) Coverage(ctx context.Context, warehouseID int) ([]domain.CarrierCandidate, error) {
+	var localityID int
+	row := r.db.QueryRowContext(ctx, "SELECT locality_id FROM warehouses WHERE id = ? AND deleted_at IS NULL;", warehouseID)
+	if err := row.Scan(&localityID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWarehouseNotFound
+		}
+		return nil, err
+	}
+
+	query := `SELECT c.id, c.cid, c.company_name, c.address, c.telephone, c.locality_id,
+			CASE
+				WHEN cl.id = wl.id THEN ?
+				WHEN cl.province_id = wl.province_id THEN ?
+				ELSE ?
+			END
+		FROM localities wl
+		INNER JOIN provinces wp ON wp.id = wl.province_id
+		INNER JOIN provinces cp ON cp.country_id = wp.country_id
+		INNER JOIN localities cl ON cl.province_id = cp.id
+		INNER JOIN carriers c ON c.locality_id = cl.id
+		WHERE wl.id = ? AND c.deleted_at IS NULL;`
+	rows, err := r.db.QueryContext(ctx, query,
+		domain.CoverageLocality, domain.CoverageProvince, domain.CoverageCountry, localityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := []domain.CarrierCandidate{}
+	for rows.Next() {
+		c := domain.CarrierCandidate{}
+		err := rows.Scan(&c.ID, &c.CID, &c.CompanyName, &c.Address, &c.Telephone, &c.LocalityID, &c.Coverage)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/carrier"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
 
 	"github.com/stretchr/testify/assert"
@@ -19,7 +20,7 @@ func TestRepositoryCreate(t *testing.T) {
 		repo := carrier.NewRepository(db)
 
 		c := domain.Carrier{
-			CID:         873456,
+			CID:         "873456",
 			CompanyName: "meli",
 			Address:     "osasco",
 			Telephone:   "99999",
@@ -27,7 +28,7 @@ func TestRepositoryCreate(t *testing.T) {
 		}
 
 		id, _ := repo.Create(context.TODO(), c)
-		var receivedCid int
+		var receivedCid string
 
 		row := db.QueryRow(`SELECT cid FROM carriers WHERE id = ?;`, id)
 		row.Scan(&receivedCid)
@@ -41,7 +42,7 @@ func TestRepositoryCreate(t *testing.T) {
 		repo := carrier.NewRepository(db)
 
 		c := domain.Carrier{
-			CID:         873456,
+			CID:         "873456",
 			CompanyName: "meli",
 			Address:     "osasco",
 			Telephone:   "99999",
@@ -55,3 +56,134 @@ func TestRepositoryCreate(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestRepositoryGet(t *testing.T) {
+	t.Run("Gets a seeded carrier", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := carrier.NewRepository(db)
+
+		c, err := repo.Get(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "111111", c.CID)
+	})
+	t.Run("Returns not found", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := carrier.NewRepository(db)
+
+		_, err := repo.Get(context.TODO(), 9999)
+
+		assert.ErrorIs(t, err, carrier.ErrNotFound)
+	})
+}
+
+func TestRepositoryUpdateDelete(t *testing.T) {
+	t.Run("Updates a carrier", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := carrier.NewRepository(db)
+
+		c, err := repo.Get(context.TODO(), 1)
+		assert.NoError(t, err)
+		c.CompanyName = "updated"
+
+		err = repo.Update(context.TODO(), c)
+		assert.NoError(t, err)
+
+		updated, err := repo.Get(context.TODO(), 1)
+		assert.NoError(t, err)
+		c.Version++
+		assert.Equal(t, c, updated)
+	})
+	t.Run("Deletes a carrier without purchase orders", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := carrier.NewRepository(db)
+
+		id, err := repo.Create(context.TODO(), domain.Carrier{CID: "873456", LocalityID: 1})
+		assert.NoError(t, err)
+
+		count, err := repo.CountPurchaseOrders(context.TODO(), id)
+		assert.NoError(t, err)
+		assert.Zero(t, count)
+
+		err = repo.Delete(context.TODO(), id)
+		assert.NoError(t, err)
+
+		err = repo.Delete(context.TODO(), id)
+		assert.ErrorIs(t, err, carrier.ErrNotFound)
+	})
+	t.Run("Hides deleted carriers unless asked for them", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := carrier.NewRepository(db)
+		id, err := repo.Create(context.TODO(), domain.Carrier{CID: "873457", LocalityID: 1})
+		assert.NoError(t, err)
+		assert.NoError(t, repo.Delete(context.TODO(), id))
+
+		_, err = repo.Get(context.TODO(), id)
+		assert.ErrorIs(t, err, carrier.ErrNotFound)
+		all, err := repo.GetAll(sqlutil.WithDeleted(context.TODO()))
+		assert.NoError(t, err)
+		assert.Contains(t, carrierIDs(all), id)
+
+		assert.NoError(t, repo.Restore(context.TODO(), id))
+		_, err = repo.Get(context.TODO(), id)
+		assert.NoError(t, err)
+	})
+	t.Run("Deletes a carrier only in the expected version", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := carrier.NewRepository(db)
+		id, err := repo.Create(context.TODO(), domain.Carrier{CID: "873458", LocalityID: 1})
+		assert.NoError(t, err)
+
+		err = repo.Delete(sqlutil.WithIfMatch(context.TODO(), []int{2}), id)
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+
+		err = repo.Delete(sqlutil.WithIfMatch(context.TODO(), []int{1}), id)
+		assert.NoError(t, err)
+	})
+}
+
+func TestRepositoryCoverage(t *testing.T) {
+	t.Run("Returns the carriers in the country of the warehouse", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := carrier.NewRepository(db)
+
+		candidates, err := repo.Coverage(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.Len(t, candidates, 1)
+		assert.Equal(t, 1, candidates[0].ID)
+		assert.Equal(t, domain.CoverageLocality, candidates[0].Coverage)
+	})
+	t.Run("Returns warehouse not found", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := carrier.NewRepository(db)
+
+		_, err := repo.Coverage(context.TODO(), 9999)
+
+		assert.ErrorIs(t, err, carrier.ErrWarehouseNotFound)
+	})
+}
+
+func carrierIDs(carriers []domain.Carrier) []int {
+	ids := make([]int, 0, len(carriers))
+	for _, c := range carriers {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
@@ -5,24 +5,45 @@ import (
 	"errors"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 )
 
 var (
 	ErrAlreadyExists       = errors.New("cid already exists")
 	ErrInternalServerError = errors.New("internal server error")
 	ErrLocalityIDNotFound  = errors.New("locality_id not found")
+	ErrNotFound            = errors.New("carrier not found")
+	ErrInUse               = errors.New("carrier is assigned to purchase orders")
+	ErrWarehouseNotFound   = errors.New("warehouse not found")
 )
 
 type CarrierDTO struct {
-	CID         int
+	CID         string
 	CompanyName string
 	Address     string
 	Telephone   string
 	LocalityID  int
 }
 
+type UpdateDTO struct {
+	CID         optional.Opt[string]
+	CompanyName optional.Opt[string]
+	Address     optional.Opt[string]
+	Telephone   optional.Opt[string]
+	LocalityID  optional.Opt[int]
+}
+
 type Service interface {
 	Create(c context.Context, carrier CarrierDTO) (domain.Carrier, error)
+	GetAll(c context.Context) ([]domain.Carrier, error)
+	Get(c context.Context, id int) (domain.Carrier, error)
+	Update(c context.Context, id int, updates UpdateDTO) (domain.Carrier, error)
+	// Delete soft deletes a carrier, unless purchase orders
+	// are assigned to it.
+	Delete(c context.Context, id int) error
+	// Restore undoes the deletion of a carrier.
+	Restore(c context.Context, id int) (domain.Carrier, error)
 }
 
 type service struct {
@@ -51,6 +72,86 @@ func (s *service) Create(c context.Context, carrier CarrierDTO) (domain.Carrier,
 	return i, nil
 }
 
+func (s *service) GetAll(c context.Context) ([]domain.Carrier, error) {
+	carriers, err := s.repo.GetAll(c)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	return carriers, nil
+}
+
+func (s *service) Get(c context.Context, id int) (domain.Carrier, error) {
+	carrier, err := s.repo.Get(c, id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return domain.Carrier{}, ErrNotFound
+		}
+		return domain.Carrier{}, ErrInternalServerError
+	}
+	return carrier, nil
+}
+
+func (s *service) Update(c context.Context, id int, updates UpdateDTO) (domain.Carrier, error) {
+	carrier, err := s.Get(c, id)
+	if err != nil {
+		return domain.Carrier{}, err
+	}
+	if err := sqlutil.CheckVersion(c, carrier.Version); err != nil {
+		return domain.Carrier{}, err
+	}
+
+	if updates.CID.HasVal && updates.CID.Val != carrier.CID && s.repo.Exists(c, updates.CID.Val) {
+		return domain.Carrier{}, ErrAlreadyExists
+	}
+
+	updated := applyUpdates(carrier, updates)
+	if err := s.repo.Update(c, updated); err != nil {
+		switch {
+		case errors.Is(err, ErrAlreadyExists), errors.Is(err, ErrLocalityIDNotFound), errors.Is(err, sqlutil.ErrVersionMismatch):
+			return domain.Carrier{}, err
+		}
+		return domain.Carrier{}, ErrInternalServerError
+	}
+	updated.Version++
+	return updated, nil
+}
+
+func (s *service) Delete(c context.Context, id int) error {
+	carrier, err := s.Get(c, id)
+	if err != nil {
+		return err
+	}
+	if err := sqlutil.CheckVersion(c, carrier.Version); err != nil {
+		return err
+	}
+
+	orders, err := s.repo.CountPurchaseOrders(c, id)
+	if err != nil {
+		return ErrInternalServerError
+	}
+	if orders > 0 {
+		return ErrInUse
+	}
+
+	if err := s.repo.Delete(c, id); err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, sqlutil.ErrVersionMismatch) {
+			return err
+		}
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+func (s *service) Restore(c context.Context, id int) (domain.Carrier, error) {
+	if err := s.repo.Restore(c, id); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return domain.Carrier{}, err
+		}
+		return domain.Carrier{}, ErrInternalServerError
+	}
+	return s.Get(c, id)
+}
+
 func mapCarrierDTOToDomain(carrier *CarrierDTO) domain.Carrier {
 	return domain.Carrier{
 		CID:         carrier.CID,
@@ -60,3 +161,12 @@ func mapCarrierDTOToDomain(carrier *CarrierDTO) domain.Carrier {
 		LocalityID:  carrier.LocalityID,
 	}
 }
+
+func applyUpdates(c domain.Carrier, updates UpdateDTO) domain.Carrier {
+	c.CID = updates.CID.Or(c.CID)
+	c.CompanyName = updates.CompanyName.Or(c.CompanyName)
+	c.Address = updates.Address.Or(c.Address)
+	c.Telephone = updates.Telephone.Or(c.Telephone)
+	c.LocalityID = updates.LocalityID.Or(c.LocalityID)
+	return c
+}
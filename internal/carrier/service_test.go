@@ -7,6 +7,8 @@ import (
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/carrier"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -72,9 +74,223 @@ func TestCreate(t *testing.T) {
 	})
 }
 
+func TestGet(t *testing.T) {
+	t.Run("Get returns the carrier", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := carrier.NewService(&repositoryMock)
+
+		expected := getTestCarrier()
+		repositoryMock.On("Get", mock.Anything, carrierID).Return(expected, nil)
+
+		result, err := svc.Get(context.TODO(), carrierID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, result)
+	})
+	t.Run("Get returns not found", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := carrier.NewService(&repositoryMock)
+
+		repositoryMock.On("Get", mock.Anything, carrierID).Return(domain.Carrier{}, carrier.ErrNotFound)
+
+		_, err := svc.Get(context.TODO(), carrierID)
+
+		assert.ErrorIs(t, err, carrier.ErrNotFound)
+	})
+	t.Run("GetAll returns internal server error", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := carrier.NewService(&repositoryMock)
+
+		repositoryMock.On("GetAll", mock.Anything).Return([]domain.Carrier{}, errors.New(""))
+
+		_, err := svc.GetAll(context.TODO())
+
+		assert.ErrorIs(t, err, carrier.ErrInternalServerError)
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	t.Run("Update merges the given fields", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := carrier.NewService(&repositoryMock)
+
+		stored := getTestCarrier()
+		expected := stored
+		expected.CID = "20"
+		expected.Telephone = "987654321"
+
+		repositoryMock.On("Get", mock.Anything, carrierID).Return(stored, nil)
+		repositoryMock.On("Exists", mock.Anything, "20").Return(false)
+		repositoryMock.On("Update", mock.Anything, expected).Return(nil)
+
+		updates := carrier.UpdateDTO{
+			CID:       *optional.FromVal("20"),
+			Telephone: *optional.FromVal("987654321"),
+		}
+		result, err := svc.Update(context.TODO(), carrierID, updates)
+
+		expected.Version++
+		assert.NoError(t, err)
+		assert.Equal(t, expected, result)
+	})
+	t.Run("Update does not check the cid if it is unchanged", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := carrier.NewService(&repositoryMock)
+
+		stored := getTestCarrier()
+		repositoryMock.On("Get", mock.Anything, carrierID).Return(stored, nil)
+		repositoryMock.On("Update", mock.Anything, stored).Return(nil)
+
+		updates := carrier.UpdateDTO{CID: *optional.FromVal(stored.CID)}
+		_, err := svc.Update(context.TODO(), carrierID, updates)
+
+		assert.NoError(t, err)
+		repositoryMock.AssertNumberOfCalls(t, "Exists", 0)
+	})
+	t.Run("Update returns conflict if the cid is taken", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := carrier.NewService(&repositoryMock)
+
+		repositoryMock.On("Get", mock.Anything, carrierID).Return(getTestCarrier(), nil)
+		repositoryMock.On("Exists", mock.Anything, "20").Return(true)
+
+		updates := carrier.UpdateDTO{CID: *optional.FromVal("20")}
+		_, err := svc.Update(context.TODO(), carrierID, updates)
+
+		assert.ErrorIs(t, err, carrier.ErrAlreadyExists)
+		repositoryMock.AssertNumberOfCalls(t, "Update", 0)
+	})
+	t.Run("Update returns locality_id not found", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := carrier.NewService(&repositoryMock)
+
+		repositoryMock.On("Get", mock.Anything, carrierID).Return(getTestCarrier(), nil)
+		repositoryMock.On("Update", mock.Anything, mock.Anything).Return(carrier.ErrLocalityIDNotFound)
+
+		updates := carrier.UpdateDTO{LocalityID: *optional.FromVal(99)}
+		_, err := svc.Update(context.TODO(), carrierID, updates)
+
+		assert.ErrorIs(t, err, carrier.ErrLocalityIDNotFound)
+	})
+	t.Run("Update returns not found", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := carrier.NewService(&repositoryMock)
+
+		repositoryMock.On("Get", mock.Anything, carrierID).Return(domain.Carrier{}, carrier.ErrNotFound)
+
+		_, err := svc.Update(context.TODO(), carrierID, carrier.UpdateDTO{})
+
+		assert.ErrorIs(t, err, carrier.ErrNotFound)
+	})
+	t.Run("Update fails if the carrier is not in the expected version", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := carrier.NewService(&repositoryMock)
+
+		stored := getTestCarrier()
+		stored.Version = 2
+		repositoryMock.On("Get", mock.Anything, carrierID).Return(stored, nil)
+
+		ctx := sqlutil.WithIfMatch(context.TODO(), []int{1})
+		_, err := svc.Update(ctx, carrierID, carrier.UpdateDTO{Telephone: *optional.FromVal("987654321")})
+
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+		repositoryMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+	t.Run("Update fails if the carrier changed after it was read", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := carrier.NewService(&repositoryMock)
+
+		repositoryMock.On("Get", mock.Anything, carrierID).Return(getTestCarrier(), nil)
+		repositoryMock.On("Update", mock.Anything, mock.Anything).Return(sqlutil.ErrVersionMismatch)
+
+		_, err := svc.Update(context.TODO(), carrierID, carrier.UpdateDTO{Telephone: *optional.FromVal("987654321")})
+
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+	})
+}
+
+func TestDelete(t *testing.T) {
+	t.Run("Delete removes a carrier without purchase orders", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := carrier.NewService(&repositoryMock)
+
+		repositoryMock.On("Get", mock.Anything, carrierID).Return(getTestCarrier(), nil)
+		repositoryMock.On("CountPurchaseOrders", mock.Anything, carrierID).Return(0, nil)
+		repositoryMock.On("Delete", mock.Anything, carrierID).Return(nil)
+
+		err := svc.Delete(context.TODO(), carrierID)
+
+		assert.NoError(t, err)
+		repositoryMock.AssertNumberOfCalls(t, "Delete", 1)
+	})
+	t.Run("Delete is blocked by purchase orders", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := carrier.NewService(&repositoryMock)
+
+		repositoryMock.On("Get", mock.Anything, carrierID).Return(getTestCarrier(), nil)
+		repositoryMock.On("CountPurchaseOrders", mock.Anything, carrierID).Return(2, nil)
+
+		err := svc.Delete(context.TODO(), carrierID)
+
+		assert.ErrorIs(t, err, carrier.ErrInUse)
+		repositoryMock.AssertNumberOfCalls(t, "Delete", 0)
+	})
+	t.Run("Delete returns not found", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := carrier.NewService(&repositoryMock)
+
+		repositoryMock.On("Get", mock.Anything, carrierID).Return(domain.Carrier{}, carrier.ErrNotFound)
+
+		err := svc.Delete(context.TODO(), carrierID)
+
+		assert.ErrorIs(t, err, carrier.ErrNotFound)
+	})
+}
+
+func TestRestore(t *testing.T) {
+	t.Run("restores a deleted carrier", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := carrier.NewService(&repositoryMock)
+		expected := getTestCarrier()
+
+		repositoryMock.On("Restore", mock.Anything, expected.ID).Return(nil)
+		repositoryMock.On("Get", mock.Anything, expected.ID).Return(expected, nil)
+		restored, err := svc.Restore(context.TODO(), expected.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, restored)
+	})
+	t.Run("returns not found when the carrier is not deleted", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := carrier.NewService(&repositoryMock)
+
+		repositoryMock.On("Restore", mock.Anything, 1).Return(carrier.ErrNotFound)
+		_, err := svc.Restore(context.TODO(), 1)
+
+		assert.ErrorIs(t, err, carrier.ErrNotFound)
+	})
+}
+
+func TestRank(t *testing.T) {
+	t.Run("Ties at the same coverage are ordered by company name", func(t *testing.T) {
+		candidates := []domain.CarrierCandidate{
+			{Carrier: domain.Carrier{ID: 1, CompanyName: "b"}, Coverage: domain.CoverageProvince},
+			{Carrier: domain.Carrier{ID: 2, CompanyName: "a"}, Coverage: domain.CoverageProvince},
+			{Carrier: domain.Carrier{ID: 3, CompanyName: "c"}, Coverage: domain.CoverageLocality},
+		}
+
+		ranked := carrier.Rank(candidates)
+
+		assert.Equal(t, []int{3, 2, 1}, []int{ranked[0].ID, ranked[1].ID, ranked[2].ID})
+		assert.Equal(t, "serves the locality of the warehouse", ranked[0].Reason)
+		assert.Equal(t, 3, ranked[2].Rank)
+		assert.Zero(t, candidates[0].Rank)
+	})
+}
+
 func getTestCarrierDTO() carrier.CarrierDTO {
 	return carrier.CarrierDTO{
-		CID:         10,
+		CID:         "10",
 		CompanyName: "mercado livre",
 		Address:     "osasco",
 		Telephone:   "12345689",
@@ -85,7 +301,7 @@ func getTestCarrierDTO() carrier.CarrierDTO {
 func getTestCarrier() domain.Carrier {
 	return domain.Carrier{
 		ID:          carrierID,
-		CID:         10,
+		CID:         "10",
 		CompanyName: "mercado livre",
 		Address:     "osasco",
 		Telephone:   "12345689",
@@ -102,7 +318,42 @@ func (r *RepositoryMock) Create(ctx context.Context, i domain.Carrier) (int, err
 	return args.Get(0).(int), args.Error(1)
 }
 
-func (r *RepositoryMock) Exists(ctx context.Context, cid int) bool {
+func (r *RepositoryMock) Exists(ctx context.Context, cid string) bool {
 	args := r.Called(ctx, cid)
 	return args.Get(0).(bool)
 }
+
+func (r *RepositoryMock) GetAll(ctx context.Context) ([]domain.Carrier, error) {
+	args := r.Called(ctx)
+	return args.Get(0).([]domain.Carrier), args.Error(1)
+}
+
+func (r *RepositoryMock) Get(ctx context.Context, id int) (domain.Carrier, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).(domain.Carrier), args.Error(1)
+}
+
+func (r *RepositoryMock) Update(ctx context.Context, c domain.Carrier) error {
+	args := r.Called(ctx, c)
+	return args.Error(0)
+}
+
+func (r *RepositoryMock) Delete(ctx context.Context, id int) error {
+	args := r.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (r *RepositoryMock) Restore(ctx context.Context, id int) error {
+	args := r.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (r *RepositoryMock) CountPurchaseOrders(ctx context.Context, id int) (int, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).(int), args.Error(1)
+}
+
+func (r *RepositoryMock) Coverage(ctx context.Context, warehouseID int) ([]domain.CarrierCandidate, error) {
+	args := r.Called(ctx, warehouseID)
+	return args.Get(0).([]domain.CarrierCandidate), args.Error(1)
+}
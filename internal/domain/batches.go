@@ -14,4 +14,31 @@ type Batches struct {
 	MinimumTemperature int       `json:"minimum_temperature"`
 	ProductID          int       `json:"product_id"`
 	SectionID          int       `json:"section_id"`
+	Version            int       `json:"-"`
+}
+
+// Types of batch movements.
+const (
+	MovementReceipt    = "receipt"
+	MovementAdjustment = "adjustment"
+	MovementTransfer   = "transfer"
+	MovementDisposal   = "disposal"
+)
+
+// BatchMovement is a change in the stock of a batch. Quantity is
+// the change in the current quantity, or the quantity moved for
+// transfers, and SectionID the section the batch is in afterwards. InboundOrderID is set for receipts,
+// FromSectionID for transfers, and Temperature for adjustments
+// that changed the temperature of the batch.
+type BatchMovement struct {
+	ID             int       `json:"id"`
+	ProductBatchID int       `json:"product_batch_id"`
+	Type           string    `json:"movement_type"`
+	Quantity       int       `json:"quantity"`
+	SectionID      int       `json:"section_id"`
+	InboundOrderID *int      `json:"inbound_order_id"`
+	FromSectionID  *int      `json:"from_section_id"`
+	Temperature    *int      `json:"temperature"`
+	Reason         *string   `json:"reason"`
+	CreatedAt      time.Time `json:"created_at"`
 }
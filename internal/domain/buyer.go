@@ -1,11 +1,15 @@
 package domain
 
+import "time"
+
 // Buyer represents a buyer
 type Buyer struct {
-	ID           int    `json:"id"`
-	CardNumberID string `json:"card_number_id"`
-	FirstName    string `binding:"required" json:"first_name"`
-	LastName     string `binding:"required" json:"last_name"`
+	ID           int        `json:"id"`
+	CardNumberID string     `json:"card_number_id"`
+	FirstName    string     `binding:"required" json:"first_name"`
+	LastName     string     `binding:"required" json:"last_name"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+	Version      int        `json:"-"`
 }
 
 // BuyerCreate represents the data for creating a buyer
@@ -20,3 +24,17 @@ type BuyerCreate struct {
 type ErrorResponse struct {
 	Message string `json:"message"`
 }
+
+// BuyerSpending sums up the purchase orders of a buyer. Order totals
+// are the quantities ordered times the sale price of their product
+// record. LastOrderDate is nil if the buyer has no orders.
+type BuyerSpending struct {
+	ID                int        `json:"id"`
+	CardNumberID      string     `json:"card_number_id"`
+	FirstName         string     `json:"first_name"`
+	LastName          string     `json:"last_name"`
+	OrdersCount       int        `json:"purchase_orders_count"`
+	TotalSpent        float64    `json:"total_spent"`
+	AverageOrderValue float64    `json:"average_order_value"`
+	LastOrderDate     *time.Time `json:"last_order_date"`
+}
@@ -1,10 +1,40 @@
 package domain
 
+import "time"
+
 type Carrier struct {
-	ID          int    `json:"id"`
-	CID         int    `json:"cid"`
-	CompanyName string `json:"company_name"`
-	Address     string `json:"address"`
-	Telephone   string `json:"telephone"`
-	LocalityID  int    `json:"locality_id"`
+	ID          int        `json:"id"`
+	CID         string     `json:"cid"`
+	CompanyName string     `json:"company_name"`
+	Address     string     `json:"address"`
+	Telephone   string     `json:"telephone"`
+	LocalityID  int        `json:"locality_id"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	Version     int        `json:"-"`
+}
+
+// Coverage levels of a carrier, from the closest to the
+// farthest from a warehouse.
+const (
+	CoverageLocality = "locality"
+	CoverageProvince = "province"
+	CoverageCountry  = "country"
+)
+
+// CarrierCandidate is a carrier that can ship from a warehouse.
+// Coverage is the closest level at which the carrier serves
+// the warehouse, and Rank is 1 for the best candidate.
+type CarrierCandidate struct {
+	Carrier
+	Rank     int    `json:"rank"`
+	Coverage string `json:"coverage"`
+	Reason   string `json:"reason"`
+}
+
+// CarrierRecommendation ranks the carriers that can ship a
+// purchase order from its source warehouse.
+type CarrierRecommendation struct {
+	PurchaseOrderID int                `json:"purchase_order_id"`
+	WarehouseID     int                `json:"warehouse_id"`
+	Candidates      []CarrierCandidate `json:"candidates"`
 }
@@ -1,18 +1,41 @@
 package domain
 
+import "time"
+
 type Employee struct {
-	ID           int    `json:"id"`
-	CardNumberID string `json:"card_number_id"`
-	FirstName    string `json:"first_name"`
-	LastName     string `json:"last_name"`
-	WarehouseID  int    `json:"warehouse_id"`
+	ID           int        `json:"id"`
+	CardNumberID string     `json:"card_number_id"`
+	FirstName    string     `json:"first_name"`
+	LastName     string     `json:"last_name"`
+	WarehouseID  int        `json:"warehouse_id"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+	Version      int        `json:"-"`
 }
 
+// InboundReport sums up the inbound orders an employee received at a
+// warehouse, within a day or a week starting at PeriodStart if the
+// report is split by period. Rank is the position of the employee among
+// those of the same warehouse and period, by orders and then by units.
 type InboundReport struct {
-	ID                 int    `json:"id"`
-	CardNumberID       string `json:"card_number_id"`
-	FirstName          string `json:"first_name"`
-	LastName           string `json:"last_name"`
-	WarehouseID        int    `json:"warehouse_id"`
-	InboundOrdersCount int    `json:"inbound_orders_count"`
+	ID                 int        `json:"id"`
+	CardNumberID       string     `json:"card_number_id"`
+	FirstName          string     `json:"first_name"`
+	LastName           string     `json:"last_name"`
+	WarehouseID        int        `json:"warehouse_id"`
+	PeriodStart        *time.Time `json:"period_start,omitempty"`
+	InboundOrdersCount int        `json:"inbound_orders_count"`
+	UnitsReceived      int        `json:"units_received"`
+	ProductsCount      int        `json:"products_count"`
+	Rank               int        `json:"rank"`
+}
+
+// EmployeeAssignment is a period during which an employee worked at a
+// warehouse. StartDate is nil for a period that began before the
+// assignments were recorded, and EndDate is nil for the current one.
+type EmployeeAssignment struct {
+	ID          int        `json:"id"`
+	EmployeeID  int        `json:"employee_id"`
+	WarehouseID int        `json:"warehouse_id"`
+	StartDate   *time.Time `json:"start_date"`
+	EndDate     *time.Time `json:"end_date"`
 }
@@ -10,3 +10,22 @@ type InboundOrder struct {
 	ProductBatchID int       `json:"product_batch_id"`
 	WarehouseID    int       `json:"warehouse_id"`
 }
+
+// InboundReceipt is an inbound order along with the batch it brought in.
+type InboundReceipt struct {
+	InboundOrder
+	ProductBatch Batches `json:"product_batch"`
+}
+
+// ReceivingReport is the stock received by a warehouse
+// for a product on a single day.
+type ReceivingReport struct {
+	WarehouseID      int       `json:"warehouse_id"`
+	ProductID        int       `json:"product_id"`
+	Day              string    `json:"day"`
+	OrdersCount      int       `json:"inbound_orders_count"`
+	EmployeesCount   int       `json:"employees_count"`
+	ReceivedQuantity int       `json:"received_quantity"`
+	FirstReceivedAt  time.Time `json:"first_received_at"`
+	LastReceivedAt   time.Time `json:"last_received_at"`
+}
@@ -1,8 +1,23 @@
 package domain
 
 type Locality struct {
-	ID       int    `json:"id"`
-	Name     string `json:"locality_name"`
-	Province string `json:"province_name"`
-	Country  string `json:"country_name"`
+	ID         int    `json:"id"`
+	Name       string `json:"locality_name"`
+	ProvinceID int    `json:"province_id"`
+	Province   string `json:"province_name"`
+	Country    string `json:"country_name"`
+	Version    int    `json:"-"`
+}
+
+type Country struct {
+	ID      int    `json:"id"`
+	Name    string `json:"country_name"`
+	Version int    `json:"-"`
+}
+
+type Province struct {
+	ID        int    `json:"id"`
+	Name      string `json:"province_name"`
+	CountryID int    `json:"country_id"`
+	Version   int    `json:"-"`
 }
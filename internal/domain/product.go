@@ -1,17 +1,30 @@
 package domain
 
+import "time"
+
 // Product represents an underlying URL with statistics on how it is used.
 type Product struct {
-	ID             int     `json:"id"`
-	Description    string  `json:"description"`
-	ExpirationRate int     `json:"expiration_rate"`
-	FreezingRate   int     `json:"freezing_rate"`
-	Height         float32 `json:"height"`
-	Length         float32 `json:"length"`
-	Netweight      float32 `json:"netweight"`
-	ProductCode    string  `json:"product_code"`
-	RecomFreezTemp float32 `json:"recommended_freezing_temperature"`
-	Width          float32 `json:"width"`
-	ProductTypeID  int     `json:"product_type_id"`
-	SellerID       int     `json:"seller_id"`
+	ID             int        `json:"id"`
+	Description    string     `json:"description"`
+	ExpirationRate int        `json:"expiration_rate"`
+	FreezingRate   int        `json:"freezing_rate"`
+	Height         float32    `json:"height"`
+	Length         float32    `json:"length"`
+	Netweight      float32    `json:"netweight"`
+	ProductCode    string     `json:"product_code"`
+	RecomFreezTemp float32    `json:"recommended_freezing_temperature"`
+	Width          float32    `json:"width"`
+	ProductTypeID  int        `json:"product_type_id"`
+	SellerID       int        `json:"seller_id"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+	Version        int        `json:"-"`
+}
+
+// ProductPage is one page of a product search, ordered by relevance.
+// Total counts every match, not only those in the page.
+type ProductPage struct {
+	Products []Product `json:"products"`
+	Page     int       `json:"page"`
+	PageSize int       `json:"page_size"`
+	Total    int       `json:"total"`
 }
@@ -0,0 +1,7 @@
+package domain
+
+type ProductType struct {
+	ID          int    `json:"id"`
+	Description string `json:"description"`
+	Version     int    `json:"-"`
+}
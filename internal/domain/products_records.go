@@ -1,10 +1,41 @@
 package domain
 
+import "time"
+
 // Product represents an underlying URL with statistics on how it is used.
 type Product_Records struct {
-	ID             int     `json:"id"`
-	LastUpdateDate string  `json:"last_update_date"`
-	PurchasePrice  float64 `json:"purchase_price"`
-	SalePrice      float64 `json:"sale_price"`
-	ProductID      int     `json:"product_id"`
+	ID             int       `json:"id"`
+	LastUpdateDate time.Time `json:"last_update_date"`
+	PurchasePrice  float64   `json:"purchase_price"`
+	SalePrice      float64   `json:"sale_price"`
+	ProductID      int       `json:"product_id"`
+}
+
+// PricePoint is the price of a product as set by one of its records.
+// The margin is relative to the sale price, and is zero when the
+// product is sold for free.
+type PricePoint struct {
+	RecordID      int       `json:"record_id"`
+	Date          time.Time `json:"date"`
+	PurchasePrice float64   `json:"purchase_price"`
+	SalePrice     float64   `json:"sale_price"`
+	MarginPct     float64   `json:"margin_percentage"`
+}
+
+// PriceChange is a PricePoint along with how much
+// the prices moved since the previous record.
+type PriceChange struct {
+	PricePoint
+	PurchaseDelta float64 `json:"purchase_price_delta"`
+	SaleDelta     float64 `json:"sale_price_delta"`
+}
+
+// PriceHistory is the price timeline of a product over a date range.
+// The total deltas compare the last price in the range with the price
+// in effect when the range started.
+type PriceHistory struct {
+	ProductID     int           `json:"product_id"`
+	Timeline      []PriceChange `json:"timeline"`
+	PurchaseDelta float64       `json:"purchase_price_delta"`
+	SaleDelta     float64       `json:"sale_price_delta"`
 }
@@ -2,6 +2,7 @@ package domain
 
 import "time"
 
+// CarrierID and WarehouseID are set once the order is dispatched.
 type PurchaseOrder struct {
 	ID              int       `json:"id"`
 	OrderNumber     string    `json:"order_number"`
@@ -10,4 +11,39 @@ type PurchaseOrder struct {
 	BuyerID         int       `json:"buyer_id"`
 	ProductRecordID int       `json:"product_record_id"`
 	OrderStatusID   int       `json:"order_status_id"`
+	CarrierID       *int      `json:"carrier_id"`
+	WarehouseID     *int      `json:"warehouse_id"`
+	Quantity        int       `json:"quantity"`
+	UnitPrice       float64   `json:"unit_price"`
+	Total           float64   `json:"total"`
+}
+
+// Statuses of a shipment, in the order they happen.
+const (
+	ShipmentPicked    = "picked"
+	ShipmentInTransit = "in_transit"
+	ShipmentDelivered = "delivered"
+)
+
+// ShipmentStatuses lists the shipment statuses in the
+// order they must be recorded.
+var ShipmentStatuses = []string{ShipmentPicked, ShipmentInTransit, ShipmentDelivered}
+
+type ShipmentEvent struct {
+	ID              int       `json:"id"`
+	PurchaseOrderID int       `json:"purchase_order_id"`
+	Status          string    `json:"status"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// Shipment is the tracking view of a dispatched purchase order.
+// Status is the status of its latest event.
+type Shipment struct {
+	PurchaseOrderID int             `json:"purchase_order_id"`
+	OrderNumber     string          `json:"order_number"`
+	TrackingCode    string          `json:"tracking_code"`
+	CarrierID       *int            `json:"carrier_id"`
+	WarehouseID     *int            `json:"warehouse_id"`
+	Status          string          `json:"status"`
+	Events          []ShipmentEvent `json:"events"`
 }
@@ -1,15 +1,19 @@
 package domain
 
+import "time"
+
 type Section struct {
-	ID                 int     `json:"id"`
-	SectionNumber      int     `json:"section_number"`
-	CurrentTemperature float64 `json:"current_temperature"`
-	MinimumTemperature float64 `json:"minimum_temperature"`
-	CurrentCapacity    int     `json:"current_capacity"`
-	MinimumCapacity    int     `json:"minimum_capacity"`
-	MaximumCapacity    int     `json:"maximum_capacity"`
-	WarehouseID        int     `json:"warehouse_id"`
-	ProductTypeID      int     `json:"product_type_id"`
+	ID                 int        `json:"id"`
+	SectionNumber      int        `json:"section_number"`
+	CurrentTemperature float64    `json:"current_temperature"`
+	MinimumTemperature float64    `json:"minimum_temperature"`
+	CurrentCapacity    int        `json:"current_capacity"`
+	MinimumCapacity    int        `json:"minimum_capacity"`
+	MaximumCapacity    int        `json:"maximum_capacity"`
+	WarehouseID        int        `json:"warehouse_id"`
+	ProductTypeID      int        `json:"product_type_id"`
+	DeletedAt          *time.Time `json:"deleted_at,omitempty"`
+	Version            int        `json:"-"`
 }
 
 type GetOneData struct {
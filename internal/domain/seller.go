@@ -1,10 +1,34 @@
 package domain
 
+import "time"
+
 type Seller struct {
-	ID          int    `json:"id"`
-	CID         int    `json:"cid"`
-	CompanyName string `json:"company_name"`
-	Address     string `json:"address"`
-	Telephone   string `json:"telephone"`
-	LocalityID  int    `json:"locality_id"`
+	ID          int        `json:"id"`
+	CID         int        `json:"cid"`
+	CompanyName string     `json:"company_name"`
+	Address     string     `json:"address"`
+	Telephone   string     `json:"telephone"`
+	LocalityID  int        `json:"locality_id"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	Version     int        `json:"-"`
+}
+
+// SellerPerformance sums up the catalog and sales of a seller.
+// Revenue is what its sold units were priced at in product_records.
+type SellerPerformance struct {
+	SellerID      int     `json:"seller_id"`
+	ProductsCount int     `json:"products_count"`
+	UnitsInStock  int     `json:"units_in_stock"`
+	UnitsSold     int     `json:"units_sold"`
+	Revenue       float64 `json:"revenue"`
+}
+
+// SellerDeleteImpact counts the rows deleted in cascade with a seller.
+type SellerDeleteImpact struct {
+	SellerID       int `json:"seller_id"`
+	Products       int `json:"products"`
+	ProductBatches int `json:"product_batches"`
+	InboundOrders  int `json:"inbound_orders"`
+	ProductRecords int `json:"product_records"`
+	PurchaseOrders int `json:"purchase_orders"`
 }
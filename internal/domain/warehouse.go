@@ -1,11 +1,49 @@
 package domain
 
+import "time"
+
 type Warehouse struct {
-	ID                 int     `json:"id"`
-	Address            string  `json:"address"`
-	Telephone          string  `json:"telephone"`
-	WarehouseCode      string  `json:"warehouse_code"`
-	MinimumCapacity    int     `json:"minimum_capacity"`
-	MinimumTemperature float32 `json:"minimum_temperature"`
-	LocalityID         int     `json:"locality_id"`
+	ID                 int        `json:"id"`
+	Address            string     `json:"address"`
+	Telephone          string     `json:"telephone"`
+	WarehouseCode      string     `json:"warehouse_code"`
+	MinimumCapacity    int        `json:"minimum_capacity"`
+	MinimumTemperature float32    `json:"minimum_temperature"`
+	LocalityID         int        `json:"locality_id"`
+	DeletedAt          *time.Time `json:"deleted_at,omitempty"`
+	Version            int        `json:"-"`
+}
+
+// Temperature statuses of a section.
+const (
+	TemperatureOK           = "ok"
+	TemperatureBelowMinimum = "below_minimum"
+)
+
+// WarehouseDashboard gathers the current state of a warehouse.
+type WarehouseDashboard struct {
+	Warehouse
+	Sections           []SectionUsage     `json:"sections"`
+	EmployeesCount     int                `json:"employees_count"`
+	StockByProductType []ProductTypeStock `json:"stock_by_product_type"`
+	ExpiringBatches    []Batches          `json:"expiring_batches"`
+	TodaysInbound      []InboundOrder     `json:"todays_inbound_orders"`
+}
+
+// SectionUsage is the capacity and temperature of a section.
+type SectionUsage struct {
+	SectionID          int     `json:"section_id"`
+	SectionNumber      int     `json:"section_number"`
+	UsedCapacity       int     `json:"used_capacity"`
+	MaximumCapacity    int     `json:"maximum_capacity"`
+	CurrentTemperature float64 `json:"current_temperature"`
+	MinimumTemperature float64 `json:"minimum_temperature"`
+	TemperatureStatus  string  `json:"temperature_status"`
+}
+
+// ProductTypeStock is the quantity of a product type in stock.
+type ProductTypeStock struct {
+	ProductTypeID int    `json:"product_type_id"`
+	Description   string `json:"description"`
+	Quantity      int    `json:"quantity"`
 }
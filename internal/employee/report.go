@@ -0,0 +1,229 @@
+package employee
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+)
+
+// Period is the length of the time buckets of a productivity report.
+type Period string
+
+const (
+	PeriodAll  Period = ""
+	PeriodDay  Period = "day"
+	PeriodWeek Period = "week"
+)
+
+var ErrInvalidPeriod = errors.New("period must be day or week")
+
+// ParsePeriod parses the period of a report, which defaults to
+// the whole time window.
+func ParsePeriod(s string) (Period, error) {
+	switch p := Period(s); p {
+	case PeriodAll, PeriodDay, PeriodWeek:
+		return p, nil
+	default:
+		return "", ErrInvalidPeriod
+	}
+}
+
+// start returns the start of the period that t falls in. Weeks start
+// on Monday, and days and weeks are in UTC.
+func (p Period) start(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if p == PeriodWeek {
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	}
+	return day
+}
+
+// ReportFilter selects the inbound orders of a productivity report.
+// Orders are filtered on the warehouse the employee was assigned to
+// when they happened.
+type ReportFilter struct {
+	From        optional.Opt[time.Time]
+	To          optional.Opt[time.Time]
+	WarehouseID optional.Opt[int]
+	Period      Period
+}
+
+// GetInboundReport obtém a produtividade de um funcionário, ou de todos
+// se o ID for zero.
+func (s *service) GetInboundReport(ctx context.Context, id int, f ReportFilter) ([]domain.InboundReport, error) {
+	var employees []domain.Employee
+	if id != 0 {
+		e, err := s.repository.Get(ctx, id)
+		if err != nil {
+			return []domain.InboundReport{}, ErrNotFound
+		}
+		employees = []domain.Employee{e}
+	} else {
+		all, err := s.repository.GetAll(ctx)
+		if err != nil {
+			return []domain.InboundReport{}, ErrInternalServerError
+		}
+		employees = all
+	}
+	if len(employees) == 0 {
+		return []domain.InboundReport{}, ErrNotFound
+	}
+
+	// A atividade é somada à medida que é lida, pois o relatório só
+	// pode ser classificado depois de somar todos os pedidos.
+	p := newProductivity(employees, f)
+	if err := s.repository.StreamInboundActivity(ctx, id, f, p.add); err != nil {
+		return []domain.InboundReport{}, ErrInternalServerError
+	}
+	return p.report(), nil
+}
+
+type reportKey struct {
+	employeeID  int
+	warehouseID int
+	period      time.Time
+}
+
+// productivity sums up the activity of the employees by warehouse
+// and period, one inbound order at a time, so that only the totals
+// are held in memory.
+type productivity struct {
+	employees []domain.Employee
+	byID      map[int]domain.Employee
+	filter    ReportFilter
+	entries   map[reportKey]*domain.InboundReport
+	products  map[reportKey]map[int]struct{}
+	active    map[int]bool
+}
+
+func newProductivity(employees []domain.Employee, f ReportFilter) *productivity {
+	byID := make(map[int]domain.Employee, len(employees))
+	for _, e := range employees {
+		byID[e.ID] = e
+	}
+	return &productivity{
+		employees: employees,
+		byID:      byID,
+		filter:    f,
+		entries:   map[reportKey]*domain.InboundReport{},
+		products:  map[reportKey]map[int]struct{}{},
+		active:    map[int]bool{},
+	}
+}
+
+// add sums up an inbound order. Orders of unknown employees are
+// skipped.
+func (p *productivity) add(a InboundActivity) error {
+	e, ok := p.byID[a.EmployeeID]
+	if !ok {
+		return nil
+	}
+	key := reportKey{employeeID: e.ID, warehouseID: a.WarehouseID}
+	if p.filter.Period != PeriodAll {
+		key.period = p.filter.Period.start(a.OrderDate)
+	}
+	r, ok := p.entries[key]
+	if !ok {
+		r = newInboundReport(e, a.WarehouseID)
+		if p.filter.Period != PeriodAll {
+			period := key.period
+			r.PeriodStart = &period
+		}
+		p.entries[key] = r
+		p.products[key] = map[int]struct{}{}
+	}
+	r.InboundOrdersCount++
+	r.UnitsReceived += a.Units
+	p.products[key][a.ProductID] = struct{}{}
+	p.active[e.ID] = true
+	return nil
+}
+
+// report returns the ranked totals. Without periods, employees with
+// no orders are reported at their current warehouse.
+func (p *productivity) report() []domain.InboundReport {
+	report := make([]domain.InboundReport, 0, len(p.entries))
+	for key, r := range p.entries {
+		r.ProductsCount = len(p.products[key])
+		report = append(report, *r)
+	}
+	if p.filter.Period == PeriodAll {
+		for _, e := range p.employees {
+			if p.active[e.ID] {
+				continue
+			}
+			if id, ok := p.filter.WarehouseID.Value(); ok && id != e.WarehouseID {
+				continue
+			}
+			report = append(report, *newInboundReport(e, e.WarehouseID))
+		}
+	}
+
+	rank(report)
+	return report
+}
+
+func newInboundReport(e domain.Employee, warehouseID int) *domain.InboundReport {
+	return &domain.InboundReport{
+		ID:           e.ID,
+		CardNumberID: e.CardNumberID,
+		FirstName:    e.FirstName,
+		LastName:     e.LastName,
+		WarehouseID:  warehouseID,
+	}
+}
+
+// rank sorts the report by period and warehouse, then from the most
+// to the least productive employee, and sets their rank. Employees
+// with as many orders and units share the same rank.
+func rank(report []domain.InboundReport) {
+	sort.Slice(report, func(i, j int) bool {
+		a, b := report[i], report[j]
+		if pa, pb := periodOf(a), periodOf(b); !pa.Equal(pb) {
+			return pa.Before(pb)
+		}
+		if a.WarehouseID != b.WarehouseID {
+			return a.WarehouseID < b.WarehouseID
+		}
+		if a.InboundOrdersCount != b.InboundOrdersCount {
+			return a.InboundOrdersCount > b.InboundOrdersCount
+		}
+		if a.UnitsReceived != b.UnitsReceived {
+			return a.UnitsReceived > b.UnitsReceived
+		}
+		return a.ID < b.ID
+	})
+
+	groupStart := 0
+	for i := range report {
+		r := &report[i]
+		if i == 0 || !sameGroup(report[i-1], *r) {
+			groupStart = i
+			r.Rank = 1
+			continue
+		}
+		prev := report[i-1]
+		if r.InboundOrdersCount == prev.InboundOrdersCount && r.UnitsReceived == prev.UnitsReceived {
+			r.Rank = prev.Rank
+		} else {
+			r.Rank = i - groupStart + 1
+		}
+	}
+}
+
+func periodOf(r domain.InboundReport) time.Time {
+	if r.PeriodStart == nil {
+		return time.Time{}
+	}
+	return *r.PeriodStart
+}
+
+func sameGroup(a, b domain.InboundReport) bool {
+	return a.WarehouseID == b.WarehouseID && periodOf(a).Equal(periodOf(b))
+}
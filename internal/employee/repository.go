@@ -4,8 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 )
 
 // Repository encapsulates the storage of a employee.
@@ -14,10 +17,29 @@ type Repository interface {
 	Get(ctx context.Context, id int) (domain.Employee, error)
 	Exists(ctx context.Context, cardNumberID string) bool
 	Save(ctx context.Context, e domain.Employee) (int, error)
+	// Update saves e unless the employee is no longer in e.Version,
+	// in which case it fails with sqlutil.ErrVersionMismatch. If e is
+	// in another warehouse, the employee is transferred there from now
+	// on, in the same transaction.
 	Update(ctx context.Context, e domain.Employee) error
+	// Delete soft deletes an employee,
+	// if it is in one of the versions expected by ctx.
 	Delete(ctx context.Context, id int) error
-	GetInboundReport(ctx context.Context, id int) (domain.InboundReport, error)
-	GetAllInboundReports(ctx context.Context) ([]domain.InboundReport, error)
+	// Restore undoes the deletion of an employee.
+	Restore(ctx context.Context, id int) error
+	// InboundActivity returns the inbound orders received by the given
+	// employee, or by every employee if id is zero, that match f.
+	InboundActivity(ctx context.Context, id int, f ReportFilter) ([]InboundActivity, error)
+	// StreamInboundActivity calls fn for each inbound order that
+	// InboundActivity would return, as it is read from the database.
+	StreamInboundActivity(ctx context.Context, id int, f ReportFilter, fn func(InboundActivity) error) error
+	// Transfer closes the current assignment of an employee at the
+	// given time and assigns them to another warehouse from then on,
+	// in a single transaction. It returns ErrInvalidTransferDate unless
+	// the time is after the start of the current assignment.
+	Transfer(ctx context.Context, id, warehouseID int, at time.Time) (domain.EmployeeAssignment, error)
+	// Assignments returns the assignments of an employee, oldest first.
+	Assignments(ctx context.Context, id int) ([]domain.EmployeeAssignment, error)
 }
 
 type repository struct {
@@ -31,7 +53,7 @@ func NewRepository(db *sql.DB) Repository {
 }
 
 func (r *repository) GetAll(ctx context.Context) ([]domain.Employee, error) {
-	query := "SELECT * FROM employees"
+	query := "SELECT id, card_number_id, first_name, last_name, warehouse_id, version, deleted_at FROM employees WHERE " + sqlutil.NotDeleted(ctx, "deleted_at")
 	rows, err := r.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -41,7 +63,7 @@ func (r *repository) GetAll(ctx context.Context) ([]domain.Employee, error) {
 
 	for rows.Next() {
 		e := domain.Employee{}
-		_ = rows.Scan(&e.ID, &e.CardNumberID, &e.FirstName, &e.LastName, &e.WarehouseID)
+		_ = rows.Scan(&e.ID, &e.CardNumberID, &e.FirstName, &e.LastName, &e.WarehouseID, &e.Version, sqlutil.NullTime(&e.DeletedAt))
 		employees = append(employees, e)
 	}
 
@@ -49,10 +71,10 @@ func (r *repository) GetAll(ctx context.Context) ([]domain.Employee, error) {
 }
 
 func (r *repository) Get(ctx context.Context, id int) (domain.Employee, error) {
-	query := "SELECT * FROM employees WHERE id=?;"
+	query := "SELECT id, card_number_id, first_name, last_name, warehouse_id, version, deleted_at FROM employees WHERE id=? AND " + sqlutil.NotDeleted(ctx, "deleted_at") + ";"
 	row := r.db.QueryRow(query, id)
 	e := domain.Employee{}
-	err := row.Scan(&e.ID, &e.CardNumberID, &e.FirstName, &e.LastName, &e.WarehouseID)
+	err := row.Scan(&e.ID, &e.CardNumberID, &e.FirstName, &e.LastName, &e.WarehouseID, &e.Version, sqlutil.NullTime(&e.DeletedAt))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.Employee{}, ErrNotFound
@@ -71,13 +93,14 @@ func (r *repository) Exists(ctx context.Context, cardNumberID string) bool {
 }
 
 func (r *repository) Save(ctx context.Context, e domain.Employee) (int, error) {
-	query := "INSERT INTO employees(card_number_id,first_name,last_name,warehouse_id) VALUES (?,?,?,?)"
-	stmt, err := r.db.Prepare(query)
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
+	defer tx.Rollback()
 
-	res, err := stmt.Exec(&e.CardNumberID, &e.FirstName, &e.LastName, &e.WarehouseID)
+	query := "INSERT INTO employees(card_number_id,first_name,last_name,warehouse_id) VALUES (?,?,?,?)"
+	res, err := tx.ExecContext(ctx, query, &e.CardNumberID, &e.FirstName, &e.LastName, &e.WarehouseID)
 	if err != nil {
 		return 0, err
 	}
@@ -87,40 +110,62 @@ func (r *repository) Save(ctx context.Context, e domain.Employee) (int, error) {
 		return 0, err
 	}
 
+	if _, err := insertAssignment(ctx, tx, int(id), e.WarehouseID, time.Now().UTC()); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
 	return int(id), nil
 }
 
 func (r *repository) Update(ctx context.Context, e domain.Employee) error {
-	query := "UPDATE employees SET first_name=?, last_name=?, warehouse_id=?  WHERE id=?"
-	stmt, err := r.db.Prepare(query)
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	res, err := stmt.Exec(&e.FirstName, &e.LastName, &e.WarehouseID, &e.ID)
-	if err != nil {
+	var current int
+	row := tx.QueryRowContext(ctx, "SELECT warehouse_id FROM employees WHERE id=? AND version=? AND deleted_at IS NULL FOR UPDATE;", e.ID, e.Version)
+	if err := row.Scan(&current); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sqlutil.StaleOrMissing(ctx, tx, "employees", e.ID, ErrNotFound)
+		}
 		return err
 	}
 
-	affected, err := res.RowsAffected()
-	if err != nil {
+	if e.WarehouseID != current {
+		if err := warehouseExists(ctx, tx, e.WarehouseID); err != nil {
+			return err
+		}
+	}
+
+	query := "UPDATE employees SET first_name=?, last_name=?, warehouse_id=?, version=version+1 WHERE id=?;"
+	if _, err := tx.ExecContext(ctx, query, e.FirstName, e.LastName, e.WarehouseID, e.ID); err != nil {
+		if strings.HasPrefix(err.Error(), "Error 1452") {
+			return ErrWarehouseNotFound
+		}
 		return err
 	}
-	if affected < 1 {
-		return ErrNotFound
+	if e.WarehouseID != current {
+		if _, err := reassign(ctx, tx, e.ID, current, e.WarehouseID, time.Now().UTC()); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return tx.Commit()
 }
 
 func (r *repository) Delete(ctx context.Context, id int) error {
-	query := "DELETE FROM employees WHERE id=?"
+	query := "UPDATE employees SET deleted_at=?, version=version+1 WHERE id=? AND deleted_at IS NULL AND " + sqlutil.VersionMatches(ctx, "version")
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return err
 	}
 
-	res, err := stmt.Exec(id)
+	res, err := stmt.Exec(time.Now().UTC(), id)
 	if err != nil {
 		return err
 	}
@@ -130,62 +175,237 @@ func (r *repository) Delete(ctx context.Context, id int) error {
 		return err
 	}
 
+	if affected < 1 {
+		return sqlutil.StaleOrMissing(ctx, r.db, "employees", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+func (r *repository) Restore(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE employees SET deleted_at=NULL, version=version+1 WHERE id=? AND deleted_at IS NOT NULL;", id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
 	if affected < 1 {
 		return ErrNotFound
 	}
 
 	return nil
 }
-func (r *repository) GetInboundReport(ctx context.Context, id int) (domain.InboundReport, error) {
-	const query = `SELECT e.id, e.card_number_id, e.first_name, e.last_name, e.warehouse_id, COUNT(i.id) as inbound_orders_count 
-					FROM employees e 
-					LEFT JOIN inbound_orders i ON i.employee_id = e.id 
-					WHERE e.id = ? 
-					GROUP BY e.id;`
 
-	row := r.db.QueryRow(query, id)
-	e := domain.InboundReport{}
-	err := row.Scan(&e.ID, &e.CardNumberID, &e.FirstName, &e.LastName, &e.WarehouseID, &e.InboundOrdersCount)
+// InboundActivity is an inbound order as seen by the productivity
+// report. WarehouseID is the warehouse the employee was assigned to at
+// the order date, or their current one if no assignment covers it.
+type InboundActivity struct {
+	EmployeeID  int
+	WarehouseID int
+	OrderDate   time.Time
+	Units       int
+	ProductID   int
+}
+
+func (r *repository) InboundActivity(ctx context.Context, id int, f ReportFilter) ([]InboundActivity, error) {
+	activity := []InboundActivity{}
+	err := r.StreamInboundActivity(ctx, id, f, func(a InboundActivity) error {
+		activity = append(activity, a)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
+func (r *repository) StreamInboundActivity(ctx context.Context, id int, f ReportFilter, fn func(InboundActivity) error) error {
+	conds := []string{}
+	args := []any{}
+	if id != 0 {
+		conds = append(conds, "io.employee_id = ?")
+		args = append(args, id)
+	}
+	if from, ok := f.From.Value(); ok {
+		conds = append(conds, "io.order_date >= ?")
+		args = append(args, from)
+	}
+	if to, ok := f.To.Value(); ok {
+		conds = append(conds, "io.order_date <= ?")
+		args = append(args, to)
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	query := `SELECT io.employee_id, COALESCE((
+			SELECT a.warehouse_id FROM employee_assignments a
+			WHERE a.employee_id = io.employee_id
+				AND (a.start_date IS NULL OR a.start_date <= io.order_date)
+				AND (a.end_date IS NULL OR a.end_date > io.order_date)
+			ORDER BY a.start_date DESC
+			LIMIT 1
+		), e.warehouse_id) AS warehouse_id,
+		io.order_date, b.initial_quantity, b.product_id
+	FROM inbound_orders io
+	INNER JOIN employees e ON e.id = io.employee_id
+	INNER JOIN product_batches b ON b.id = io.product_batch_id` + where
+	if warehouseID, ok := f.WarehouseID.Value(); ok {
+		query = "SELECT * FROM (" + query + ") activity WHERE activity.warehouse_id = ?"
+		args = append(args, warehouseID)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query+";", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		a := InboundActivity{}
+		if err := rows.Scan(&a.EmployeeID, &a.WarehouseID, sqlutil.Time(&a.OrderDate), &a.Units, &a.ProductID); err != nil {
+			return err
+		}
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *repository) Transfer(ctx context.Context, id, warehouseID int, at time.Time) (domain.EmployeeAssignment, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
+		return domain.EmployeeAssignment{}, err
+	}
+	defer tx.Rollback()
+
+	var current int
+	row := tx.QueryRowContext(ctx, "SELECT warehouse_id FROM employees WHERE id = ? AND deleted_at IS NULL FOR UPDATE;", id)
+	if err := row.Scan(&current); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return domain.InboundReport{}, ErrNotFound
+			return domain.EmployeeAssignment{}, ErrNotFound
 		}
-		return domain.InboundReport{}, ErrInternalServerError
+		return domain.EmployeeAssignment{}, err
 	}
 
-	return e, nil
+	// Checked under the lock of the employee, so that a concurrent
+	// transfer cannot start an assignment after the time.
+	var latest time.Time
+	row = tx.QueryRowContext(ctx, "SELECT MAX(start_date) FROM employee_assignments WHERE employee_id = ?;", id)
+	if err := row.Scan(sqlutil.Time(&latest)); err != nil {
+		return domain.EmployeeAssignment{}, err
+	}
+	if !latest.IsZero() && !at.After(latest) {
+		return domain.EmployeeAssignment{}, ErrInvalidTransferDate
+	}
+
+	if err := warehouseExists(ctx, tx, warehouseID); err != nil {
+		return domain.EmployeeAssignment{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE employees SET warehouse_id = ?, version = version + 1 WHERE id = ?;", warehouseID, id); err != nil {
+		if strings.HasPrefix(err.Error(), "Error 1452") {
+			return domain.EmployeeAssignment{}, ErrWarehouseNotFound
+		}
+		return domain.EmployeeAssignment{}, err
+	}
 
+	a, err := reassign(ctx, tx, id, current, warehouseID, at)
+	if err != nil {
+		return domain.EmployeeAssignment{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return domain.EmployeeAssignment{}, err
+	}
+	return a, nil
 }
 
-func (r *repository) GetAllInboundReports(ctx context.Context) ([]domain.InboundReport, error) {
-	const query = `SELECT e.id, e.card_number_id, e.first_name, e.last_name, e.warehouse_id, COUNT(i.id) as inbound_orders_count 
-					FROM employees e 
-					LEFT JOIN inbound_orders i ON i.employee_id = e.id 
-					GROUP BY e.id;`
+// warehouseExists returns ErrWarehouseNotFound unless the given
+// warehouse exists and is not deleted, locking it until the end of tx
+// so that it is not deleted while employees are moved there.
+func warehouseExists(ctx context.Context, tx *sql.Tx, id int) error {
+	var found int
+	err := tx.QueryRowContext(ctx, "SELECT id FROM warehouses WHERE id = ? AND deleted_at IS NULL FOR SHARE;", id).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrWarehouseNotFound
+	}
+	return err
+}
 
-	rows, err := r.db.Query(query)
+// reassign closes the current assignment of an employee, who worked at
+// warehouse from, at the given time and assigns them to warehouse to
+// from then on.
+func reassign(ctx context.Context, tx *sql.Tx, id, from, to int, at time.Time) (domain.EmployeeAssignment, error) {
+	res, err := tx.ExecContext(ctx, "UPDATE employee_assignments SET end_date = ? WHERE employee_id = ? AND end_date IS NULL;", at, id)
+	if err != nil {
+		return domain.EmployeeAssignment{}, err
+	}
+	closed, err := res.RowsAffected()
+	if err != nil {
+		return domain.EmployeeAssignment{}, err
+	}
+	if closed == 0 {
+		// The employee was created before assignments were recorded,
+		// keep where they worked until now.
+		query := "INSERT INTO employee_assignments(employee_id, warehouse_id, start_date, end_date) VALUES (?, ?, NULL, ?);"
+		if _, err := tx.ExecContext(ctx, query, id, from, at); err != nil {
+			return domain.EmployeeAssignment{}, err
+		}
+	}
+	return insertAssignment(ctx, tx, id, to, at)
+}
+
+func (r *repository) Assignments(ctx context.Context, id int) ([]domain.EmployeeAssignment, error) {
+	query := `SELECT id, employee_id, warehouse_id, start_date, end_date
+		FROM employee_assignments WHERE employee_id = ?
+		ORDER BY start_date IS NOT NULL, start_date, id;`
+	rows, err := r.db.QueryContext(ctx, query, id)
 	if err != nil {
-		return []domain.InboundReport{}, ErrInternalServerError
+		return nil, err
 	}
 	defer rows.Close()
 
-	var reports []domain.InboundReport
+	assignments := []domain.EmployeeAssignment{}
 	for rows.Next() {
-		var e domain.InboundReport
-		err := rows.Scan(&e.ID, &e.CardNumberID, &e.FirstName, &e.LastName, &e.WarehouseID, &e.InboundOrdersCount)
-		if err != nil {
-			return []domain.InboundReport{}, ErrInternalServerError
+		a := domain.EmployeeAssignment{}
+		var start, end time.Time
+		if err := rows.Scan(&a.ID, &a.EmployeeID, &a.WarehouseID, sqlutil.Time(&start), sqlutil.Time(&end)); err != nil {
+			return nil, err
+		}
+		if !start.IsZero() {
+			a.StartDate = &start
+		}
+		if !end.IsZero() {
+			a.EndDate = &end
 		}
-		reports = append(reports, e)
+		assignments = append(assignments, a)
 	}
-	err = rows.Err()
+	return assignments, rows.Err()
+}
+
+func insertAssignment(ctx context.Context, tx *sql.Tx, id, warehouseID int, at time.Time) (domain.EmployeeAssignment, error) {
+	query := "INSERT INTO employee_assignments(employee_id, warehouse_id, start_date) VALUES (?, ?, ?);"
+	res, err := tx.ExecContext(ctx, query, id, warehouseID, at)
 	if err != nil {
-		return []domain.InboundReport{}, ErrInternalServerError
+		if strings.HasPrefix(err.Error(), "Error 1452") {
+			return domain.EmployeeAssignment{}, ErrWarehouseNotFound
+		}
+		return domain.EmployeeAssignment{}, err
 	}
-
-	if len(reports) == 0 {
-		return []domain.InboundReport{}, ErrNotFound
+	assignmentID, err := res.LastInsertId()
+	if err != nil {
+		return domain.EmployeeAssignment{}, err
 	}
-
-	return reports, nil
+	return domain.EmployeeAssignment{
+		ID:          int(assignmentID),
+		EmployeeID:  id,
+		WarehouseID: warehouseID,
+		StartDate:   &at,
+	}, nil
 }
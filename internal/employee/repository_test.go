@@ -2,10 +2,14 @@ package employee_test
 
 import (
 	"context"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/employee"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
 	"github.com/stretchr/testify/assert"
 )
@@ -100,6 +104,7 @@ func TestRepoUpdate(t *testing.T) {
 		id, _ := repo.Save(context.TODO(), emp)
 
 		emp.ID = id
+		emp.Version = 1
 		emp.FirstName = "Joao"
 
 		err := repo.Update(context.TODO(), emp)
@@ -138,7 +143,7 @@ func TestRepoReport(t *testing.T) {
 
 		id, _ := repo.Save(context.TODO(), emp)
 
-		_, err := repo.GetInboundReport(context.TODO(), id)
+		_, err := repo.InboundActivity(context.TODO(), id, employee.ReportFilter{})
 		assert.NoError(t, err)
 	})
 	t.Run("Gets report for every ID", func(t *testing.T) {
@@ -147,9 +152,154 @@ func TestRepoReport(t *testing.T) {
 
 		repo := employee.NewRepository(db)
 
-		_, err := repo.GetAllInboundReports(context.TODO())
+		_, err := repo.InboundActivity(context.TODO(), 0, employee.ReportFilter{})
 		assert.NoError(t, err)
 	})
+	t.Run("Filters by warehouse", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := employee.NewRepository(db)
+		f := employee.ReportFilter{WarehouseID: *optional.FromVal(1)}
+
+		activity, err := repo.InboundActivity(context.TODO(), 0, f)
+		assert.NoError(t, err)
+		for _, a := range activity {
+			assert.Equal(t, 1, a.WarehouseID)
+		}
+	})
+}
+
+func TestRepoTransfer(t *testing.T) {
+	t.Run("Attributes inbound orders to the warehouse at the order date", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := employee.NewRepository(db)
+		emp := getTestEmployee()
+		emp.CardNumberID = "TRANSFER-1"
+		id, err := repo.Save(context.TODO(), emp)
+		assert.NoError(t, err)
+
+		// Backdate the first assignment so that the orders fall in it.
+		_, err = db.Exec("UPDATE employee_assignments SET start_date = '2023-01-01' WHERE employee_id = ?;", id)
+		assert.NoError(t, err)
+
+		at := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+		a, err := repo.Transfer(context.TODO(), id, 2, at)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, a.WarehouseID)
+
+		var batchID int
+		assert.NoError(t, db.QueryRow("SELECT id FROM product_batches LIMIT 1;").Scan(&batchID))
+		for i, date := range []string{"2023-03-01", "2023-07-01", "2023-08-01"} {
+			_, err := db.Exec(`INSERT INTO inbound_orders(order_date, order_number, employee_id, product_batch_id, warehouse_id)
+				VALUES (?, ?, ?, ?, 1);`, date, "TRANSFER-ORDER-"+strconv.Itoa(i), id, batchID)
+			assert.NoError(t, err)
+		}
+
+		activity, err := repo.InboundActivity(context.TODO(), id, employee.ReportFilter{})
+		assert.NoError(t, err)
+		orders := map[int]int{}
+		for _, a := range activity {
+			orders[a.WarehouseID]++
+		}
+		assert.Equal(t, map[int]int{1: 1, 2: 2}, orders)
+
+		assignments, err := repo.Assignments(context.TODO(), id)
+		assert.NoError(t, err)
+		assert.Len(t, assignments, 2)
+		assert.Equal(t, at, *assignments[0].EndDate)
+		assert.Nil(t, assignments[1].EndDate)
+	})
+	t.Run("Records a change of warehouse on update as a transfer", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := employee.NewRepository(db)
+		emp := getTestEmployee()
+		emp.CardNumberID = "TRANSFER-2"
+		id, err := repo.Save(context.TODO(), emp)
+		assert.NoError(t, err)
+
+		emp, err = repo.Get(context.TODO(), id)
+		assert.NoError(t, err)
+		emp.WarehouseID = 2
+		assert.NoError(t, repo.Update(context.TODO(), emp))
+
+		assignments, err := repo.Assignments(context.TODO(), id)
+		assert.NoError(t, err)
+		assert.Len(t, assignments, 2)
+		assert.Equal(t, 2, assignments[1].WarehouseID)
+	})
+	t.Run("Leaves the warehouse as is when the update fails", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := employee.NewRepository(db)
+		emp := getTestEmployee()
+		emp.CardNumberID = "TRANSFER-3"
+		id, err := repo.Save(context.TODO(), emp)
+		assert.NoError(t, err)
+
+		emp, err = repo.Get(context.TODO(), id)
+		assert.NoError(t, err)
+		emp.WarehouseID = 2
+		emp.Version++
+		assert.ErrorIs(t, repo.Update(context.TODO(), emp), sqlutil.ErrVersionMismatch)
+
+		assignments, err := repo.Assignments(context.TODO(), id)
+		assert.NoError(t, err)
+		assert.Len(t, assignments, 1)
+	})
+	t.Run("Does not transfer before the start of the current assignment", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := employee.NewRepository(db)
+		emp := getTestEmployee()
+		emp.CardNumberID = "TRANSFER-5"
+		id, err := repo.Save(context.TODO(), emp)
+		assert.NoError(t, err)
+
+		_, err = db.Exec("UPDATE employee_assignments SET start_date = '2023-01-01' WHERE employee_id = ?;", id)
+		assert.NoError(t, err)
+
+		_, err = repo.Transfer(context.TODO(), id, 2, time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC))
+		assert.NoError(t, err)
+		_, err = repo.Transfer(context.TODO(), id, 1, time.Date(2023, 1, 5, 0, 0, 0, 0, time.UTC))
+		assert.ErrorIs(t, err, employee.ErrInvalidTransferDate)
+
+		assignments, err := repo.Assignments(context.TODO(), id)
+		assert.NoError(t, err)
+		assert.Len(t, assignments, 2)
+		assert.Nil(t, assignments[1].EndDate)
+	})
+	t.Run("Does not transfer to a deleted warehouse", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := employee.NewRepository(db)
+		emp := getTestEmployee()
+		emp.CardNumberID = "TRANSFER-4"
+		id, err := repo.Save(context.TODO(), emp)
+		assert.NoError(t, err)
+
+		_, err = db.Exec("UPDATE warehouses SET deleted_at = NOW() WHERE id = 2;")
+		assert.NoError(t, err)
+
+		_, err = repo.Transfer(context.TODO(), id, 2, time.Now().UTC())
+		assert.ErrorIs(t, err, employee.ErrWarehouseNotFound)
+
+		emp, err = repo.Get(context.TODO(), id)
+		assert.NoError(t, err)
+		emp.WarehouseID = 2
+		assert.ErrorIs(t, repo.Update(context.TODO(), emp), employee.ErrWarehouseNotFound)
+
+		assignments, err := repo.Assignments(context.TODO(), id)
+		assert.NoError(t, err)
+		assert.Len(t, assignments, 1)
+	})
 }
 
 func getTestEmployee() domain.Employee {
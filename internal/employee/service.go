@@ -3,8 +3,11 @@ package employee
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 )
 
 // Errors
@@ -12,6 +15,9 @@ var (
 	ErrNotFound            = errors.New("employee not found")
 	ErrAlreadyExists       = errors.New("employee id already exists")
 	ErrInternalServerError = errors.New("internal server error")
+	ErrWarehouseNotFound   = errors.New("warehouse not found")
+	ErrSameWarehouse       = errors.New("employee is already assigned to this warehouse")
+	ErrInvalidTransferDate = errors.New("transfer date must be after the start of the current assignment and not in the future")
 )
 
 // Service define a interface para o serviço de funcionários.
@@ -20,8 +26,16 @@ type Service interface {
 	Create(ctx context.Context, e domain.Employee) (domain.Employee, error)
 	Get(ctx context.Context, id int) (domain.Employee, error)
 	Delete(ctx context.Context, id int) error
+	// Restore undoes the deletion of an employee.
+	Restore(ctx context.Context, id int) (domain.Employee, error)
 	Update(ctx context.Context, e domain.Employee) (domain.Employee, error)
-	GetInboundReport(ctx context.Context, id int) ([]domain.InboundReport, error)
+	// GetInboundReport reports the productivity of an employee, or of
+	// every employee if id is zero, over the inbound orders matching f.
+	GetInboundReport(ctx context.Context, id int, f ReportFilter) ([]domain.InboundReport, error)
+	// Transfer assigns an employee to another warehouse from the
+	// given time on, or from now if it is omitted.
+	Transfer(ctx context.Context, id, warehouseID int, at optional.Opt[time.Time]) (domain.EmployeeAssignment, error)
+	Assignments(ctx context.Context, id int) ([]domain.EmployeeAssignment, error)
 }
 
 type service struct {
@@ -75,12 +89,27 @@ func (s *service) Get(ctx context.Context, id int) (domain.Employee, error) {
 // Delete remove um funcionário.
 func (s *service) Delete(ctx context.Context, id int) error {
 	err := s.repository.Delete(ctx, id)
+	if errors.Is(err, sqlutil.ErrVersionMismatch) {
+		return err
+	}
 	if err != nil {
 		return ErrNotFound
 	}
 	return nil
 }
 
+// Restore restaura um funcionário removido.
+func (s *service) Restore(ctx context.Context, id int) (domain.Employee, error) {
+	err := s.repository.Restore(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return domain.Employee{}, ErrNotFound
+	}
+	if err != nil {
+		return domain.Employee{}, ErrInternalServerError
+	}
+	return s.repository.Get(ctx, id)
+}
+
 // Update atualiza as informações de um funcionário.
 func (s *service) Update(ctx context.Context, e domain.Employee) (domain.Employee, error) {
 	currentEmployee, err := s.repository.Get(ctx, e.ID)
@@ -89,6 +118,10 @@ func (s *service) Update(ctx context.Context, e domain.Employee) (domain.Employe
 		return domain.Employee{}, ErrNotFound
 	}
 
+	if err := sqlutil.CheckVersion(ctx, currentEmployee.Version); err != nil {
+		return domain.Employee{}, err
+	}
+
 	if e.FirstName != "" {
 		currentEmployee.FirstName = e.FirstName
 	}
@@ -107,29 +140,58 @@ func (s *service) Update(ctx context.Context, e domain.Employee) (domain.Employe
 	}
 
 	if e.WarehouseID != 0 {
+		// O repositório registra a mudança de armazém como uma
+		// transferência, na mesma transação.
 		currentEmployee.WarehouseID = e.WarehouseID
 	}
 
 	err = s.repository.Update(ctx, currentEmployee)
+	if errors.Is(err, sqlutil.ErrVersionMismatch) || errors.Is(err, ErrWarehouseNotFound) {
+		return domain.Employee{}, err
+	}
 	if err != nil {
 		return domain.Employee{}, ErrNotFound
 	}
+	currentEmployee.Version++
 	return currentEmployee, nil
 }
 
-func (s *service) GetInboundReport(ctx context.Context, id int) ([]domain.InboundReport, error) {
-	if id == 0 {
-		e, err := s.repository.GetAllInboundReports(ctx)
-		if err != nil {
-			return []domain.InboundReport{}, err
-		}
-		return e, nil
+// Transfer valida e registra a transferência de um funcionário.
+func (s *service) Transfer(ctx context.Context, id, warehouseID int, at optional.Opt[time.Time]) (domain.EmployeeAssignment, error) {
+	e, err := s.repository.Get(ctx, id)
+	if err != nil {
+		return domain.EmployeeAssignment{}, ErrNotFound
+	}
+	if e.WarehouseID == warehouseID {
+		return domain.EmployeeAssignment{}, ErrSameWarehouse
+	}
 
+	now := time.Now().UTC()
+	date := at.Or(now)
+	if date.After(now) {
+		return domain.EmployeeAssignment{}, ErrInvalidTransferDate
 	}
-	e, err := s.repository.GetInboundReport(ctx, id)
+
+	a, err := s.repository.Transfer(ctx, id, warehouseID, date)
 	if err != nil {
-		return []domain.InboundReport{}, err
+		switch {
+		case errors.Is(err, ErrNotFound), errors.Is(err, ErrWarehouseNotFound), errors.Is(err, ErrInvalidTransferDate):
+			return domain.EmployeeAssignment{}, err
+		default:
+			return domain.EmployeeAssignment{}, ErrInternalServerError
+		}
 	}
+	return a, nil
+}
 
-	return []domain.InboundReport{e}, nil
+// Assignments obtém o histórico de armazéns de um funcionário.
+func (s *service) Assignments(ctx context.Context, id int) ([]domain.EmployeeAssignment, error) {
+	if _, err := s.repository.Get(ctx, id); err != nil {
+		return nil, ErrNotFound
+	}
+	assignments, err := s.repository.Assignments(ctx, id)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	return assignments, nil
 }
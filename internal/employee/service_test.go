@@ -2,10 +2,14 @@ package employee_test
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/employee"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -155,6 +159,7 @@ func TestUpdateEmployee(t *testing.T) {
 		mockedRepository.On("Update", mock.Anything, newE).Return(nil)
 
 		updatedEmployee, err := s.Update(context.TODO(), newE)
+		newE.Version = 1
 		assert.NoError(t, err)
 		assert.Equal(t, newE, updatedEmployee)
 	})
@@ -225,6 +230,18 @@ func TestUpdateEmployee(t *testing.T) {
 		_, err := s.Update(context.TODO(), newE)
 		assert.ErrorIs(t, err, employee.ErrNotFound)
 	})
+	t.Run("returns version mismatch when the employee is in another version", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := employee.NewService(&mockedRepository)
+
+		mockedRepository.On("Get", mock.Anything, 1).Return(domain.Employee{ID: 1, Version: 2}, nil)
+
+		ctx := sqlutil.WithIfMatch(context.TODO(), []int{1})
+		_, err := s.Update(ctx, domain.Employee{ID: 1, FirstName: "Lucas"})
+
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+		mockedRepository.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
 }
 func TestDeleteEmployee(t *testing.T) {
 	t.Run("sucessfuly deletes a employee", func(t *testing.T) {
@@ -244,75 +261,250 @@ func TestDeleteEmployee(t *testing.T) {
 		assert.ErrorIs(t, err, employee.ErrNotFound)
 	})
 }
-func TestGetInboundReport(t *testing.T) {
-	t.Run("return correct report for valid id", func(t *testing.T) {
+
+func TestRestoreEmployee(t *testing.T) {
+	t.Run("restores a deleted employee", func(t *testing.T) {
 		mockedRepository := RepositoryMock{}
 		s := employee.NewService(&mockedRepository)
+		expected := domain.Employee{ID: 1, CardNumberID: "126"}
 
-		r := domain.InboundReport{
-			ID:           1,
-			CardNumberID: "126",
-			FirstName:    "Lucas",
-			LastName:     "Melo",
-			WarehouseID:  1,
-		}
+		mockedRepository.On("Restore", mock.Anything, expected.ID).Return(nil)
+		mockedRepository.On("Get", mock.Anything, expected.ID).Return(expected, nil)
+		restored, err := s.Restore(context.TODO(), expected.ID)
 
-		mockedRepository.On("GetInboundReport", mock.Anything, r.ID).Return(r, nil)
-		report, err := s.GetInboundReport(context.TODO(), 1)
 		assert.NoError(t, err)
-		assert.Equal(t, []domain.InboundReport{r}, report)
+		assert.Equal(t, expected, restored)
 	})
-	t.Run("return all reports when a id is not received", func(t *testing.T) {
+	t.Run("returns not found when the employee is not deleted", func(t *testing.T) {
 		mockedRepository := RepositoryMock{}
 		s := employee.NewService(&mockedRepository)
 
-		r := []domain.InboundReport{{
+		mockedRepository.On("Restore", mock.Anything, 1).Return(employee.ErrNotFound)
+		_, err := s.Restore(context.TODO(), 1)
+
+		assert.ErrorIs(t, err, employee.ErrNotFound)
+	})
+}
+func TestGetInboundReport(t *testing.T) {
+	lucas := domain.Employee{ID: 1, CardNumberID: "126", FirstName: "Lucas", LastName: "Melo", WarehouseID: 1}
+	func2 := domain.Employee{ID: 2, CardNumberID: "125", FirstName: "Func2", LastName: "FuncLastName2", WarehouseID: 1}
+	mon := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	wed := time.Date(2023, 5, 3, 10, 0, 0, 0, time.UTC)
+	nextMon := time.Date(2023, 5, 8, 10, 0, 0, 0, time.UTC)
+
+	t.Run("return correct report for valid id", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := employee.NewService(&mockedRepository)
+
+		activity := []employee.InboundActivity{
+			{EmployeeID: 1, WarehouseID: 1, OrderDate: mon, Units: 10, ProductID: 1},
+			{EmployeeID: 1, WarehouseID: 1, OrderDate: wed, Units: 5, ProductID: 1},
+			{EmployeeID: 1, WarehouseID: 1, OrderDate: wed, Units: 2, ProductID: 2},
+		}
+		mockedRepository.On("Get", mock.Anything, 1).Return(lucas, nil)
+		mockedRepository.On("StreamInboundActivity", mock.Anything, 1, employee.ReportFilter{}).Return(activity, nil)
+		report, err := s.GetInboundReport(context.TODO(), 1, employee.ReportFilter{})
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.InboundReport{{
 			ID:                 1,
 			CardNumberID:       "126",
 			FirstName:          "Lucas",
 			LastName:           "Melo",
 			WarehouseID:        1,
-			InboundOrdersCount: 5,
-		}, {
-			ID:                 2,
-			CardNumberID:       "125",
-			FirstName:          "Func2",
-			LastName:           "FuncLastName2",
-			WarehouseID:        1,
-			InboundOrdersCount: 10,
-		}}
+			InboundOrdersCount: 3,
+			UnitsReceived:      17,
+			ProductsCount:      2,
+			Rank:               1,
+		}}, report)
+	})
+	t.Run("return all reports when a id is not received", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := employee.NewService(&mockedRepository)
 
-		mockedRepository.On("GetAllInboundReports", mock.Anything).Return(r, nil)
-		reports, err := s.GetInboundReport(context.TODO(), 0)
+		activity := []employee.InboundActivity{
+			{EmployeeID: 2, WarehouseID: 1, OrderDate: mon, Units: 1, ProductID: 1},
+		}
+		mockedRepository.On("GetAll", mock.Anything).Return([]domain.Employee{lucas, func2}, nil)
+		mockedRepository.On("StreamInboundActivity", mock.Anything, 0, employee.ReportFilter{}).Return(activity, nil)
+		reports, err := s.GetInboundReport(context.TODO(), 0, employee.ReportFilter{})
 		assert.NoError(t, err)
-		assert.ElementsMatch(t, r, reports)
+		assert.Len(t, reports, 2)
+		assert.Equal(t, 2, reports[0].ID)
+		assert.Equal(t, 1, reports[0].Rank)
+		assert.Equal(t, 1, reports[1].ID)
+		assert.Equal(t, 0, reports[1].InboundOrdersCount)
+		assert.Equal(t, 2, reports[1].Rank)
 	})
-	t.Run("returns a error when id is not found", func(t *testing.T) {
+	t.Run("split the report by week and rank within each warehouse", func(t *testing.T) {
 		mockedRepository := RepositoryMock{}
 		s := employee.NewService(&mockedRepository)
 
-		r := domain.InboundReport{
-			ID:           1000000,
-			CardNumberID: "126",
-			FirstName:    "Lucas",
-			LastName:     "Melo",
-			WarehouseID:  1,
+		f := employee.ReportFilter{Period: employee.PeriodWeek}
+		activity := []employee.InboundActivity{
+			{EmployeeID: 1, WarehouseID: 1, OrderDate: mon, Units: 10, ProductID: 1},
+			{EmployeeID: 2, WarehouseID: 1, OrderDate: wed, Units: 10, ProductID: 1},
+			{EmployeeID: 2, WarehouseID: 1, OrderDate: wed, Units: 1, ProductID: 1},
+			{EmployeeID: 1, WarehouseID: 2, OrderDate: nextMon, Units: 3, ProductID: 1},
+			{EmployeeID: 2, WarehouseID: 1, OrderDate: nextMon, Units: 3, ProductID: 1},
 		}
+		mockedRepository.On("GetAll", mock.Anything).Return([]domain.Employee{lucas, func2}, nil)
+		mockedRepository.On("StreamInboundActivity", mock.Anything, 0, f).Return(activity, nil)
+		reports, err := s.GetInboundReport(context.TODO(), 0, f)
+		assert.NoError(t, err)
 
-		mockedRepository.On("GetInboundReport", mock.Anything, r.ID).Return(domain.InboundReport{}, employee.ErrNotFound)
-		_, err := s.GetInboundReport(context.TODO(), r.ID)
+		week1 := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+		week2 := time.Date(2023, 5, 8, 0, 0, 0, 0, time.UTC)
+		type row struct {
+			id, warehouse int
+			period        time.Time
+			orders, rank  int
+		}
+		var got []row
+		for _, r := range reports {
+			got = append(got, row{r.ID, r.WarehouseID, *r.PeriodStart, r.InboundOrdersCount, r.Rank})
+		}
+		assert.Equal(t, []row{
+			{2, 1, week1, 2, 1},
+			{1, 1, week1, 1, 2},
+			{2, 1, week2, 1, 1},
+			{1, 2, week2, 1, 1},
+		}, got)
+	})
+	t.Run("employees with as many orders and units share a rank", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := employee.NewService(&mockedRepository)
+
+		activity := []employee.InboundActivity{
+			{EmployeeID: 1, WarehouseID: 1, OrderDate: mon, Units: 4, ProductID: 1},
+			{EmployeeID: 2, WarehouseID: 1, OrderDate: wed, Units: 4, ProductID: 2},
+		}
+		mockedRepository.On("GetAll", mock.Anything).Return([]domain.Employee{lucas, func2}, nil)
+		mockedRepository.On("StreamInboundActivity", mock.Anything, 0, employee.ReportFilter{}).Return(activity, nil)
+		reports, err := s.GetInboundReport(context.TODO(), 0, employee.ReportFilter{})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, reports[0].Rank)
+		assert.Equal(t, 1, reports[1].Rank)
+	})
+	t.Run("returns a error when id is not found", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := employee.NewService(&mockedRepository)
+
+		mockedRepository.On("Get", mock.Anything, 1000000).Return(domain.Employee{}, employee.ErrNotFound)
+		_, err := s.GetInboundReport(context.TODO(), 1000000, employee.ReportFilter{})
 		assert.ErrorIs(t, err, employee.ErrNotFound)
 	})
 	t.Run("return an error when there isnt any report", func(t *testing.T) {
 		mockedRepository := RepositoryMock{}
 		s := employee.NewService(&mockedRepository)
 
-		mockedRepository.On("GetAllInboundReports", mock.Anything).Return([]domain.InboundReport{}, employee.ErrNotFound)
-		_, err := s.GetInboundReport(context.TODO(), 0)
+		mockedRepository.On("GetAll", mock.Anything).Return([]domain.Employee{}, nil)
+		_, err := s.GetInboundReport(context.TODO(), 0, employee.ReportFilter{})
 		assert.ErrorIs(t, err, employee.ErrNotFound)
 	})
 }
 
+func TestTransferEmployee(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	current := []domain.EmployeeAssignment{{ID: 1, EmployeeID: 1, WarehouseID: 1, StartDate: &start}}
+
+	t.Run("transfers an employee at the given date", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := employee.NewService(&mockedRepository)
+
+		at := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+		expected := domain.EmployeeAssignment{ID: 2, EmployeeID: 1, WarehouseID: 2, StartDate: &at}
+		mockedRepository.On("Get", mock.Anything, 1).Return(domain.Employee{ID: 1, WarehouseID: 1}, nil)
+		mockedRepository.On("Transfer", mock.Anything, 1, 2, at).Return(expected, nil)
+
+		a, err := s.Transfer(context.TODO(), 1, 2, *optional.FromVal(at))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, a)
+	})
+	t.Run("returns error when the employee is already there", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := employee.NewService(&mockedRepository)
+
+		mockedRepository.On("Get", mock.Anything, 1).Return(domain.Employee{ID: 1, WarehouseID: 1}, nil)
+
+		_, err := s.Transfer(context.TODO(), 1, 1, optional.Opt[time.Time]{})
+		assert.ErrorIs(t, err, employee.ErrSameWarehouse)
+	})
+	t.Run("returns error when the date is before the current assignment", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := employee.NewService(&mockedRepository)
+
+		at := start.AddDate(0, 0, -1)
+		mockedRepository.On("Get", mock.Anything, 1).Return(domain.Employee{ID: 1, WarehouseID: 1}, nil)
+		mockedRepository.On("Transfer", mock.Anything, 1, 2, at).Return(domain.EmployeeAssignment{}, employee.ErrInvalidTransferDate)
+
+		_, err := s.Transfer(context.TODO(), 1, 2, *optional.FromVal(at))
+		assert.ErrorIs(t, err, employee.ErrInvalidTransferDate)
+	})
+	t.Run("returns error when the date is in the future", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := employee.NewService(&mockedRepository)
+
+		mockedRepository.On("Get", mock.Anything, 1).Return(domain.Employee{ID: 1, WarehouseID: 1}, nil)
+
+		_, err := s.Transfer(context.TODO(), 1, 2, *optional.FromVal(time.Now().Add(time.Hour)))
+		assert.ErrorIs(t, err, employee.ErrInvalidTransferDate)
+	})
+	t.Run("returns error when the warehouse does not exist", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := employee.NewService(&mockedRepository)
+
+		mockedRepository.On("Get", mock.Anything, 1).Return(domain.Employee{ID: 1, WarehouseID: 1}, nil)
+		mockedRepository.On("Transfer", mock.Anything, 1, 9, mock.Anything).Return(domain.EmployeeAssignment{}, employee.ErrWarehouseNotFound)
+
+		_, err := s.Transfer(context.TODO(), 1, 9, optional.Opt[time.Time]{})
+		assert.ErrorIs(t, err, employee.ErrWarehouseNotFound)
+	})
+	t.Run("update transfers the employee when the warehouse changes", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := employee.NewService(&mockedRepository)
+
+		mockedRepository.On("Get", mock.Anything, 1).Return(domain.Employee{ID: 1, FirstName: "Lucas", WarehouseID: 1}, nil)
+		mockedRepository.On("Update", mock.Anything, domain.Employee{ID: 1, FirstName: "Lucas", WarehouseID: 2}).Return(nil)
+
+		e, err := s.Update(context.TODO(), domain.Employee{ID: 1, WarehouseID: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, e.WarehouseID)
+		assert.Equal(t, 1, e.Version)
+		mockedRepository.AssertNotCalled(t, "Transfer", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("update returns error when the new warehouse does not exist", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := employee.NewService(&mockedRepository)
+
+		mockedRepository.On("Get", mock.Anything, 1).Return(domain.Employee{ID: 1, WarehouseID: 1}, nil)
+		mockedRepository.On("Update", mock.Anything, domain.Employee{ID: 1, WarehouseID: 9}).Return(employee.ErrWarehouseNotFound)
+
+		_, err := s.Update(context.TODO(), domain.Employee{ID: 1, WarehouseID: 9})
+		assert.ErrorIs(t, err, employee.ErrWarehouseNotFound)
+	})
+	t.Run("returns the assignment history", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := employee.NewService(&mockedRepository)
+
+		mockedRepository.On("Get", mock.Anything, 1).Return(domain.Employee{ID: 1}, nil)
+		mockedRepository.On("Assignments", mock.Anything, 1).Return(current, nil)
+
+		a, err := s.Assignments(context.TODO(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, current, a)
+	})
+	t.Run("returns internal error when the history cannot be read", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := employee.NewService(&mockedRepository)
+
+		mockedRepository.On("Get", mock.Anything, 1).Return(domain.Employee{ID: 1}, nil)
+		mockedRepository.On("Assignments", mock.Anything, 1).Return([]domain.EmployeeAssignment{}, errors.New("db down"))
+
+		_, err := s.Assignments(context.TODO(), 1)
+		assert.ErrorIs(t, err, employee.ErrInternalServerError)
+	})
+}
+
 type RepositoryMock struct {
 	mock.Mock
 }
@@ -346,11 +538,32 @@ func (r *RepositoryMock) Delete(ctx context.Context, id int) error {
 	args := r.Called(ctx, id)
 	return args.Error(0)
 }
-func (r *RepositoryMock) GetAllInboundReports(ctx context.Context) ([]domain.InboundReport, error) {
-	args := r.Called(ctx)
-	return args.Get(0).([]domain.InboundReport), args.Error(1)
+
+func (r *RepositoryMock) Restore(ctx context.Context, id int) error {
+	args := r.Called(ctx, id)
+	return args.Error(0)
 }
-func (r *RepositoryMock) GetInboundReport(ctx context.Context, id int) (domain.InboundReport, error) {
+func (r *RepositoryMock) InboundActivity(ctx context.Context, id int, f employee.ReportFilter) ([]employee.InboundActivity, error) {
+	args := r.Called(ctx, id, f)
+	return args.Get(0).([]employee.InboundActivity), args.Error(1)
+}
+
+func (r *RepositoryMock) StreamInboundActivity(ctx context.Context, id int, f employee.ReportFilter, fn func(employee.InboundActivity) error) error {
+	args := r.Called(ctx, id, f)
+	for _, a := range args.Get(0).([]employee.InboundActivity) {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) Transfer(ctx context.Context, id, warehouseID int, at time.Time) (domain.EmployeeAssignment, error) {
+	args := r.Called(ctx, id, warehouseID, at)
+	return args.Get(0).(domain.EmployeeAssignment), args.Error(1)
+}
+
+func (r *RepositoryMock) Assignments(ctx context.Context, id int) ([]domain.EmployeeAssignment, error) {
 	args := r.Called(ctx, id)
-	return args.Get(0).(domain.InboundReport), args.Error(1)
+	return args.Get(0).([]domain.EmployeeAssignment), args.Error(1)
 }
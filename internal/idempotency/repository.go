@@ -0,0 +1,72 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
+)
+
+const (
+	// TTL is how long the response to a key is kept.
+	TTL = 24 * time.Hour
+	// claimTimeout is how long a key stays claimed by a request that
+	// never completed it, e.g. because the server stopped.
+	claimTimeout = time.Minute
+)
+
+type repository struct {
+	db *sql.DB
+}
+
+// NewRepository returns an IdempotencyStore backed by the
+// idempotency_keys table.
+func NewRepository(db *sql.DB) middleware.IdempotencyStore {
+	return &repository{
+		db: db,
+	}
+}
+
+func (r *repository) Claim(ctx context.Context, scope, key, requestHash string) (middleware.StoredResponse, bool, error) {
+	now := time.Now()
+	query := "DELETE FROM idempotency_keys WHERE scope=? AND idempotency_key=? AND (created_at < ? OR (status IS NULL AND created_at < ?))"
+	if _, err := r.db.ExecContext(ctx, query, scope, key, now.Add(-TTL), now.Add(-claimTimeout)); err != nil {
+		return middleware.StoredResponse{}, false, err
+	}
+
+	query = "INSERT INTO idempotency_keys(scope, idempotency_key, request_hash, created_at) VALUES (?,?,?,?)"
+	_, err := r.db.ExecContext(ctx, query, scope, key, requestHash, now)
+	if err == nil {
+		return middleware.StoredResponse{}, true, nil
+	}
+	if !strings.HasPrefix(err.Error(), "Error 1062") {
+		return middleware.StoredResponse{}, false, err
+	}
+
+	var stored middleware.StoredResponse
+	var status sql.NullInt64
+	var contentType sql.NullString
+	query = "SELECT request_hash, status, content_type, body FROM idempotency_keys WHERE scope=? AND idempotency_key=?"
+	row := r.db.QueryRowContext(ctx, query, scope, key)
+	if err := row.Scan(&stored.RequestHash, &status, &contentType, &stored.Body); err != nil {
+		return middleware.StoredResponse{}, false, err
+	}
+	stored.Done = status.Valid
+	stored.Status = int(status.Int64)
+	stored.ContentType = contentType.String
+	return stored, false, nil
+}
+
+func (r *repository) Complete(ctx context.Context, scope, key string, res middleware.StoredResponse) error {
+	query := "UPDATE idempotency_keys SET status=?, content_type=?, body=? WHERE scope=? AND idempotency_key=?"
+	_, err := r.db.ExecContext(ctx, query, res.Status, res.ContentType, res.Body, scope, key)
+	return err
+}
+
+func (r *repository) Release(ctx context.Context, scope, key string) error {
+	query := "DELETE FROM idempotency_keys WHERE scope=? AND idempotency_key=?"
+	_, err := r.db.ExecContext(ctx, query, scope, key)
+	return err
+}
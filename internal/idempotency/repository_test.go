@@ -0,0 +1,140 @@
+package idempotency_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/idempotency"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+const scope = "POST /api/v1/sellers"
+
+func TestRepoClaim(t *testing.T) {
+	t.Run("Claims an unused key", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := idempotency.NewRepository(db)
+
+		_, claimed, err := repo.Claim(context.TODO(), scope, "a", "hash")
+		assert.NoError(t, err)
+		assert.True(t, claimed)
+	})
+	t.Run("Returns the claim in flight for a used key", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := idempotency.NewRepository(db)
+
+		_, _, err := repo.Claim(context.TODO(), scope, "a", "hash")
+		assert.NoError(t, err)
+
+		stored, claimed, err := repo.Claim(context.TODO(), scope, "a", "hash")
+		assert.NoError(t, err)
+		assert.False(t, claimed)
+		assert.False(t, stored.Done)
+		assert.Equal(t, "hash", stored.RequestHash)
+	})
+	t.Run("Returns the hash of the request that used the key", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := idempotency.NewRepository(db)
+
+		_, _, err := repo.Claim(context.TODO(), scope, "a", "first")
+		assert.NoError(t, err)
+
+		stored, claimed, err := repo.Claim(context.TODO(), scope, "a", "second")
+		assert.NoError(t, err)
+		assert.False(t, claimed)
+		assert.Equal(t, "first", stored.RequestHash)
+	})
+	t.Run("Keeps keys apart by scope", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := idempotency.NewRepository(db)
+
+		_, _, err := repo.Claim(context.TODO(), scope, "a", "hash")
+		assert.NoError(t, err)
+
+		_, claimed, err := repo.Claim(context.TODO(), "POST /api/v1/buyers", "a", "hash")
+		assert.NoError(t, err)
+		assert.True(t, claimed)
+	})
+	t.Run("Claims again a key whose request never completed", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := idempotency.NewRepository(db)
+
+		_, err := db.Exec("INSERT INTO idempotency_keys(scope, idempotency_key, request_hash, created_at) VALUES (?,?,?,?);",
+			scope, "a", "hash", time.Now().Add(-2*time.Minute))
+		assert.NoError(t, err)
+
+		_, claimed, err := repo.Claim(context.TODO(), scope, "a", "hash")
+		assert.NoError(t, err)
+		assert.True(t, claimed)
+	})
+	t.Run("Claims again a key whose response expired", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := idempotency.NewRepository(db)
+
+		_, err := db.Exec("INSERT INTO idempotency_keys(scope, idempotency_key, request_hash, status, content_type, body, created_at) VALUES (?,?,?,?,?,?,?);",
+			scope, "a", "hash", 201, "application/json", []byte("{}"), time.Now().Add(-idempotency.TTL-time.Minute))
+		assert.NoError(t, err)
+
+		_, claimed, err := repo.Claim(context.TODO(), scope, "a", "hash")
+		assert.NoError(t, err)
+		assert.True(t, claimed)
+	})
+}
+
+func TestRepoComplete(t *testing.T) {
+	t.Run("Stores the response of a claimed key", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := idempotency.NewRepository(db)
+
+		_, _, err := repo.Claim(context.TODO(), scope, "a", "hash")
+		assert.NoError(t, err)
+
+		res := middleware.StoredResponse{
+			RequestHash: "hash",
+			Done:        true,
+			Status:      201,
+			ContentType: "application/json; charset=utf-8",
+			Body:        []byte(`{"data":1}`),
+		}
+		assert.NoError(t, repo.Complete(context.TODO(), scope, "a", res))
+
+		stored, claimed, err := repo.Claim(context.TODO(), scope, "a", "hash")
+		assert.NoError(t, err)
+		assert.False(t, claimed)
+		assert.Equal(t, res, stored)
+	})
+}
+
+func TestRepoRelease(t *testing.T) {
+	t.Run("Frees a claimed key", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := idempotency.NewRepository(db)
+
+		_, _, err := repo.Claim(context.TODO(), scope, "a", "hash")
+		assert.NoError(t, err)
+		assert.NoError(t, repo.Release(context.TODO(), scope, "a"))
+
+		_, claimed, err := repo.Claim(context.TODO(), scope, "a", "other")
+		assert.NoError(t, err)
+		assert.True(t, claimed)
+	})
+}
@@ -0,0 +1,44 @@
+package inboundorder
+
+import (
+	"strings"
+	"time"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+)
+
+// Filter selects inbound orders. Unset fields match every order,
+// and the date range includes both of its ends.
+type Filter struct {
+	WarehouseID optional.Opt[int]
+	EmployeeID  optional.Opt[int]
+	From        optional.Opt[time.Time]
+	To          optional.Opt[time.Time]
+}
+
+// where returns the WHERE clause matching the filter on the
+// inbound_orders table, aliased as io, along with its arguments.
+func (f Filter) where() (string, []any) {
+	var conds []string
+	var args []any
+	if f.WarehouseID.HasVal {
+		conds = append(conds, "io.warehouse_id = ?")
+		args = append(args, f.WarehouseID.Val)
+	}
+	if f.EmployeeID.HasVal {
+		conds = append(conds, "io.employee_id = ?")
+		args = append(args, f.EmployeeID.Val)
+	}
+	if f.From.HasVal {
+		conds = append(conds, "io.order_date >= ?")
+		args = append(args, f.From.Val)
+	}
+	if f.To.HasVal {
+		conds = append(conds, "io.order_date <= ?")
+		args = append(args, f.To.Val)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
@@ -3,13 +3,34 @@ package inboundorder
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"strings"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 )
 
 // Repository encapsulates the storage of a employee.
 type Repository interface {
 	Save(ctx context.Context, i domain.InboundOrder) (int, error)
+	// Receive stores the batch brought in by an inbound order, the
+	// order itself and the receipt movement of the batch, all in a
+	// single transaction. It fails with ErrEmployeeWarehouse or
+	// ErrSectionWarehouse if the employee or the section of the batch
+	// is not in the warehouse of the order, and keeps them there until
+	// the order is stored.
+	Receive(ctx context.Context, i domain.InboundOrder, b domain.Batches) (domain.InboundReceipt, error)
+	EmployeeWarehouse(ctx context.Context, employeeID int) (int, error)
+	BatchWarehouse(ctx context.Context, batchID int) (int, error)
+	Get(ctx context.Context, id int) (domain.InboundOrder, error)
+	List(ctx context.Context, f Filter) ([]domain.InboundOrder, error)
+	// ReceivingReport sums the quantities received by the filtered
+	// orders, per warehouse, product and day.
+	ReceivingReport(ctx context.Context, f Filter) ([]domain.ReceivingReport, error)
+	// StreamReceivingReport calls fn for each entry of the receiving
+	// report, as it is read from the database.
+	StreamReceivingReport(ctx context.Context, f Filter, fn func(domain.ReceivingReport) error) error
 }
 
 type repository struct {
@@ -32,6 +53,9 @@ func (r *repository) Save(ctx context.Context, i domain.InboundOrder) (int, erro
 
 	res, err := stmt.Exec(&i.OrderDate, &i.OrderNumber, &i.EmployeeID, &i.ProductBatchID, &i.WarehouseID)
 	if err != nil {
+		if isDuplicateEntry(err) {
+			return 0, ErrAlreadyExists
+		}
 		return 0, err
 	}
 
@@ -42,3 +66,188 @@ func (r *repository) Save(ctx context.Context, i domain.InboundOrder) (int, erro
 
 	return int(id), nil
 }
+
+func (r *repository) Receive(ctx context.Context, i domain.InboundOrder, b domain.Batches) (domain.InboundReceipt, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.InboundReceipt{}, err
+	}
+	defer tx.Rollback()
+
+	// Locking reads, so that neither the employee nor the section
+	// moves to another warehouse or is deleted before the order is
+	// stored.
+	if err := inWarehouse(ctx, tx, employeeWarehouse+" FOR SHARE;", i.EmployeeID, i.WarehouseID, ErrEmployeeNotFound, ErrEmployeeWarehouse); err != nil {
+		return domain.InboundReceipt{}, err
+	}
+	if err := inWarehouse(ctx, tx, sectionWarehouse+" FOR SHARE;", b.SectionID, i.WarehouseID, ErrSectionNotFound, ErrSectionWarehouse); err != nil {
+		return domain.InboundReceipt{}, err
+	}
+
+	queryBatch := "INSERT INTO product_batches (batch_number, current_quantity, current_temperature, due_date, initial_quantity, manufacturing_date, manufacturing_hour, minimum_temperature, product_id, section_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);"
+	res, err := tx.ExecContext(ctx, queryBatch, b.BatchNumber, b.CurrentQuantity, b.CurrentTemperature, b.DueDate, b.InitialQuantity, b.ManufacturingDate, b.ManufacturingHour, b.MinimumTemperature, b.ProductID, b.SectionID)
+	if err != nil {
+		if isDuplicateEntry(err) {
+			return domain.InboundReceipt{}, ErrBatchNumberExists
+		}
+		if isMissingReference(err) {
+			return domain.InboundReceipt{}, ErrProductNotFound
+		}
+		return domain.InboundReceipt{}, err
+	}
+	batchID, err := res.LastInsertId()
+	if err != nil {
+		return domain.InboundReceipt{}, err
+	}
+	b.ID = int(batchID)
+	i.ProductBatchID = b.ID
+
+	queryOrder := "INSERT INTO inbound_orders(order_date,order_number,employee_id,product_batch_id,warehouse_id) VALUES (?,?,?,?,?)"
+	res, err = tx.ExecContext(ctx, queryOrder, i.OrderDate, i.OrderNumber, i.EmployeeID, i.ProductBatchID, i.WarehouseID)
+	if err != nil {
+		if isDuplicateEntry(err) {
+			return domain.InboundReceipt{}, ErrAlreadyExists
+		}
+		return domain.InboundReceipt{}, err
+	}
+	orderID, err := res.LastInsertId()
+	if err != nil {
+		return domain.InboundReceipt{}, err
+	}
+	i.ID = int(orderID)
+
+	queryMovement := "INSERT INTO batch_movements(product_batch_id,movement_type,quantity,section_id,inbound_order_id,created_at) VALUES (?,?,?,?,?,?)"
+	_, err = tx.ExecContext(ctx, queryMovement, b.ID, domain.MovementReceipt, b.InitialQuantity, b.SectionID, i.ID, time.Now().UTC())
+	if err != nil {
+		return domain.InboundReceipt{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return domain.InboundReceipt{}, err
+	}
+	return domain.InboundReceipt{InboundOrder: i, ProductBatch: b}, nil
+}
+
+// Queries of the warehouse of an employee and of a section.
+const (
+	employeeWarehouse = "SELECT warehouse_id FROM employees WHERE id = ? AND deleted_at IS NULL"
+	sectionWarehouse  = "SELECT warehouse_id FROM sections WHERE id = ? AND deleted_at IS NULL"
+)
+
+func (r *repository) EmployeeWarehouse(ctx context.Context, employeeID int) (int, error) {
+	return warehouseOf(ctx, r.db, employeeWarehouse+";", employeeID, ErrEmployeeNotFound)
+}
+
+func (r *repository) BatchWarehouse(ctx context.Context, batchID int) (int, error) {
+	query := "SELECT s.warehouse_id FROM product_batches b INNER JOIN sections s ON s.id = b.section_id WHERE b.id = ? AND s.deleted_at IS NULL;"
+	return warehouseOf(ctx, r.db, query, batchID, ErrBatchNotFound)
+}
+
+// inWarehouse returns mismatch unless the query of the warehouse of
+// the entity with the given ID returns warehouseID.
+func inWarehouse(ctx context.Context, q sqlutil.Querier, query string, id, warehouseID int, notFound, mismatch error) error {
+	actual, err := warehouseOf(ctx, q, query, id, notFound)
+	if err != nil {
+		return err
+	}
+	if actual != warehouseID {
+		return mismatch
+	}
+	return nil
+}
+
+// warehouseOf runs a query returning a single warehouse ID,
+// and returns notFound if it has no results.
+func warehouseOf(ctx context.Context, q sqlutil.Querier, query string, id int, notFound error) (int, error) {
+	var warehouseID int
+	err := q.QueryRowContext(ctx, query, id).Scan(&warehouseID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, notFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return warehouseID, nil
+}
+
+func (r *repository) Get(ctx context.Context, id int) (domain.InboundOrder, error) {
+	query := "SELECT id, order_date, order_number, employee_id, product_batch_id, warehouse_id FROM inbound_orders WHERE id = ?;"
+	row := r.db.QueryRowContext(ctx, query, id)
+
+	i := domain.InboundOrder{}
+	err := row.Scan(&i.ID, sqlutil.Time(&i.OrderDate), &i.OrderNumber, &i.EmployeeID, &i.ProductBatchID, &i.WarehouseID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.InboundOrder{}, ErrNotFound
+	}
+	if err != nil {
+		return domain.InboundOrder{}, err
+	}
+	return i, nil
+}
+
+func (r *repository) List(ctx context.Context, f Filter) ([]domain.InboundOrder, error) {
+	where, args := f.where()
+	query := "SELECT io.id, io.order_date, io.order_number, io.employee_id, io.product_batch_id, io.warehouse_id FROM inbound_orders io" +
+		where + " ORDER BY io.order_date, io.id;"
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []domain.InboundOrder{}
+	for rows.Next() {
+		i := domain.InboundOrder{}
+		if err := rows.Scan(&i.ID, sqlutil.Time(&i.OrderDate), &i.OrderNumber, &i.EmployeeID, &i.ProductBatchID, &i.WarehouseID); err != nil {
+			return nil, err
+		}
+		orders = append(orders, i)
+	}
+	return orders, rows.Err()
+}
+
+func (r *repository) ReceivingReport(ctx context.Context, f Filter) ([]domain.ReceivingReport, error) {
+	report := []domain.ReceivingReport{}
+	err := r.StreamReceivingReport(ctx, f, func(e domain.ReceivingReport) error {
+		report = append(report, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (r *repository) StreamReceivingReport(ctx context.Context, f Filter, fn func(domain.ReceivingReport) error) error {
+	where, args := f.where()
+	query := "SELECT io.warehouse_id, b.product_id, DATE_FORMAT(io.order_date, '%Y-%m-%d') AS day, " +
+		"COUNT(io.id), COUNT(DISTINCT io.employee_id), SUM(b.initial_quantity), MIN(io.order_date), MAX(io.order_date) " +
+		"FROM inbound_orders io INNER JOIN product_batches b ON b.id = io.product_batch_id" +
+		where + " GROUP BY io.warehouse_id, b.product_id, day ORDER BY day, io.warehouse_id, b.product_id;"
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e := domain.ReceivingReport{}
+		err := rows.Scan(&e.WarehouseID, &e.ProductID, &e.Day, &e.OrdersCount, &e.EmployeesCount,
+			&e.ReceivedQuantity, sqlutil.Time(&e.FirstReceivedAt), sqlutil.Time(&e.LastReceivedAt))
+		if err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func isDuplicateEntry(err error) bool {
+	return strings.HasPrefix(err.Error(), "Error 1062")
+}
+
+func isMissingReference(err error) bool {
+	return strings.HasPrefix(err.Error(), "Error 1452")
+}
@@ -2,11 +2,13 @@ package inboundorder_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	inboundorder "github.com/extmatperez/meli_bootcamp_go_w2-4/internal/inbound_order"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
 
 	"github.com/stretchr/testify/assert"
@@ -56,3 +58,160 @@ func TestCreate(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestRepoReceive(t *testing.T) {
+	t.Run("Creates batch, order and receipt movement", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := inboundorder.NewRepository(db)
+
+		order := domain.InboundOrder{
+			OrderDate:   time.Now(),
+			OrderNumber: "R-123456",
+			EmployeeID:  1,
+			WarehouseID: 1,
+		}
+		batch := domain.Batches{
+			BatchNumber:     9001,
+			CurrentQuantity: 20,
+			InitialQuantity: 20,
+			DueDate:         time.Now().AddDate(0, 1, 0),
+			ProductID:       1,
+			SectionID:       1,
+		}
+
+		receipt, err := repo.Receive(context.TODO(), order, batch)
+		assert.NoError(t, err)
+		assert.Equal(t, receipt.ProductBatch.ID, receipt.ProductBatchID)
+
+		var movements int
+		row := db.QueryRow(`SELECT COUNT(*) FROM batch_movements WHERE product_batch_id = ? AND inbound_order_id = ?;`, receipt.ProductBatchID, receipt.ID)
+		row.Scan(&movements)
+		assert.Equal(t, 1, movements)
+	})
+	t.Run("Rolls back the batch if the order fails", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := inboundorder.NewRepository(db)
+
+		order := domain.InboundOrder{OrderDate: time.Now(), OrderNumber: "R-654321", EmployeeID: 1, WarehouseID: 1}
+		batch := domain.Batches{BatchNumber: 9002, InitialQuantity: 20, ProductID: 1, SectionID: 1}
+
+		_, err := repo.Receive(context.TODO(), order, batch)
+		assert.NoError(t, err)
+
+		batch.BatchNumber = 9003
+		_, err = repo.Receive(context.TODO(), order, batch)
+		assert.ErrorIs(t, err, inboundorder.ErrAlreadyExists)
+
+		var batches int
+		row := db.QueryRow(`SELECT COUNT(*) FROM product_batches WHERE batch_number = ?;`, batch.BatchNumber)
+		row.Scan(&batches)
+		assert.Equal(t, 0, batches)
+	})
+	t.Run("Rejects an employee of another warehouse", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := inboundorder.NewRepository(db)
+
+		order := domain.InboundOrder{OrderDate: time.Now(), OrderNumber: "R-111111", EmployeeID: 2, WarehouseID: 1}
+		batch := domain.Batches{BatchNumber: 9004, InitialQuantity: 20, ProductID: 1, SectionID: 1}
+
+		_, err := repo.Receive(context.TODO(), order, batch)
+		assert.ErrorIs(t, err, inboundorder.ErrEmployeeWarehouse)
+
+		var batches int
+		row := db.QueryRow(`SELECT COUNT(*) FROM product_batches WHERE batch_number = ?;`, batch.BatchNumber)
+		row.Scan(&batches)
+		assert.Equal(t, 0, batches)
+	})
+	t.Run("Rejects a section of another warehouse", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := inboundorder.NewRepository(db)
+
+		order := domain.InboundOrder{OrderDate: time.Now(), OrderNumber: "R-222222", EmployeeID: 2, WarehouseID: 2}
+		batch := domain.Batches{BatchNumber: 9005, InitialQuantity: 20, ProductID: 1, SectionID: 1}
+
+		_, err := repo.Receive(context.TODO(), order, batch)
+		assert.ErrorIs(t, err, inboundorder.ErrSectionWarehouse)
+	})
+	t.Run("Returns not found for unknown sections", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := inboundorder.NewRepository(db)
+
+		order := domain.InboundOrder{OrderDate: time.Now(), OrderNumber: "R-333333", EmployeeID: 1, WarehouseID: 1}
+		batch := domain.Batches{BatchNumber: 9006, InitialQuantity: 20, ProductID: 1, SectionID: 9999}
+
+		_, err := repo.Receive(context.TODO(), order, batch)
+		assert.ErrorIs(t, err, inboundorder.ErrSectionNotFound)
+	})
+	t.Run("Rejects deleted employees and sections", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := inboundorder.NewRepository(db)
+
+		order := domain.InboundOrder{OrderDate: time.Now(), OrderNumber: "R-444444", EmployeeID: 1, WarehouseID: 1}
+		batch := domain.Batches{BatchNumber: 9007, InitialQuantity: 20, ProductID: 1, SectionID: 1}
+
+		_, err := db.Exec(`UPDATE sections SET deleted_at = NOW() WHERE id = 1;`)
+		assert.NoError(t, err)
+		_, err = repo.Receive(context.TODO(), order, batch)
+		assert.ErrorIs(t, err, inboundorder.ErrSectionNotFound)
+
+		_, err = db.Exec(`UPDATE employees SET deleted_at = NOW() WHERE id = 1;`)
+		assert.NoError(t, err)
+		_, err = repo.Receive(context.TODO(), order, batch)
+		assert.ErrorIs(t, err, inboundorder.ErrEmployeeNotFound)
+	})
+}
+
+func TestWarehouseLookups(t *testing.T) {
+	t.Run("Returns the warehouse of employees", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := inboundorder.NewRepository(db)
+
+		employeeWarehouse, err := repo.EmployeeWarehouse(context.TODO(), 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, employeeWarehouse)
+	})
+}
+
+func TestRepoReceivingReport(t *testing.T) {
+	t.Run("Sums received quantities per warehouse, product and day", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := inboundorder.NewRepository(db)
+
+		day := time.Date(2030, 1, 2, 9, 0, 0, 0, time.UTC)
+		for n, quantity := range []int{20, 30} {
+			order := domain.InboundOrder{OrderDate: day.Add(time.Duration(n) * time.Hour), OrderNumber: fmt.Sprintf("RR-%d", n), EmployeeID: 1, WarehouseID: 1}
+			batch := domain.Batches{BatchNumber: 9100 + n, InitialQuantity: quantity, ProductID: 1, SectionID: 1}
+			_, err := repo.Receive(context.TODO(), order, batch)
+			assert.NoError(t, err)
+		}
+
+		from := day.Truncate(24 * time.Hour)
+		report, err := repo.ReceivingReport(context.TODO(), inboundorder.Filter{
+			WarehouseID: *optional.FromVal(1),
+			From:        *optional.FromVal(from),
+			To:          *optional.FromVal(from.AddDate(0, 0, 1)),
+		})
+		assert.NoError(t, err)
+		assert.Len(t, report, 1)
+		assert.Equal(t, "2030-01-02", report[0].Day)
+		assert.Equal(t, 50, report[0].ReceivedQuantity)
+		assert.Equal(t, 2, report[0].OrdersCount)
+		assert.Equal(t, day, report[0].FirstReceivedAt)
+	})
+}
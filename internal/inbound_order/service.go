@@ -12,10 +12,28 @@ var (
 	ErrNotFound            = errors.New("InboundOrder not found")
 	ErrAlreadyExists       = errors.New("InboundOrder id already exists")
 	ErrInternalServerError = errors.New("internal server error")
+	ErrEmployeeNotFound    = errors.New("employee not found")
+	ErrSectionNotFound     = errors.New("section not found")
+	ErrBatchNotFound       = errors.New("product batch not found")
+	ErrProductNotFound     = errors.New("product not found")
+	ErrBatchNumberExists   = errors.New("batch number already exists")
+	ErrEmployeeWarehouse   = errors.New("employee does not work at warehouse_id")
+	ErrSectionWarehouse    = errors.New("section is not in warehouse_id")
+	ErrInvalidQuantity     = errors.New("batch quantity must be greater than zero")
 )
 
 type Service interface {
 	Create(ctx context.Context, e domain.InboundOrder) (domain.InboundOrder, error)
+	// Receive creates the given batch in its section and the inbound
+	// order that brings it into the warehouse. The employee and the
+	// section must both belong to the warehouse of the order.
+	Receive(ctx context.Context, i domain.InboundOrder, b domain.Batches) (domain.InboundReceipt, error)
+	Get(ctx context.Context, id int) (domain.InboundOrder, error)
+	List(ctx context.Context, f Filter) ([]domain.InboundOrder, error)
+	ReceivingReport(ctx context.Context, f Filter) ([]domain.ReceivingReport, error)
+	// StreamReceivingReport calls fn for each entry of the receiving
+	// report without loading them all in memory.
+	StreamReceivingReport(ctx context.Context, f Filter, fn func(domain.ReceivingReport) error) error
 }
 
 type service struct {
@@ -29,9 +47,18 @@ func NewService(r Repository) Service {
 }
 
 func (s *service) Create(ctx context.Context, i domain.InboundOrder) (domain.InboundOrder, error) {
+	if err := validateWarehouse(ctx, i.WarehouseID, s.repository.EmployeeWarehouse, i.EmployeeID, ErrEmployeeWarehouse); err != nil {
+		return domain.InboundOrder{}, err
+	}
+	if err := validateWarehouse(ctx, i.WarehouseID, s.repository.BatchWarehouse, i.ProductBatchID, ErrSectionWarehouse); err != nil {
+		return domain.InboundOrder{}, err
+	}
 
 	id, err := s.repository.Save(ctx, i)
 	if err != nil {
+		if errors.Is(err, ErrAlreadyExists) {
+			return domain.InboundOrder{}, ErrAlreadyExists
+		}
 		return domain.InboundOrder{}, ErrInternalServerError
 	}
 
@@ -39,3 +66,82 @@ func (s *service) Create(ctx context.Context, i domain.InboundOrder) (domain.Inb
 
 	return i, nil
 }
+
+func (s *service) Receive(ctx context.Context, i domain.InboundOrder, b domain.Batches) (domain.InboundReceipt, error) {
+	if b.InitialQuantity <= 0 {
+		return domain.InboundReceipt{}, ErrInvalidQuantity
+	}
+
+	// The whole batch is in stock when it is received.
+	b.CurrentQuantity = b.InitialQuantity
+
+	receipt, err := s.repository.Receive(ctx, i, b)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrAlreadyExists),
+			errors.Is(err, ErrBatchNumberExists),
+			errors.Is(err, ErrProductNotFound),
+			errors.Is(err, ErrEmployeeNotFound),
+			errors.Is(err, ErrSectionNotFound),
+			errors.Is(err, ErrEmployeeWarehouse),
+			errors.Is(err, ErrSectionWarehouse):
+			return domain.InboundReceipt{}, err
+		}
+		return domain.InboundReceipt{}, ErrInternalServerError
+	}
+	return receipt, nil
+}
+
+func (s *service) Get(ctx context.Context, id int) (domain.InboundOrder, error) {
+	i, err := s.repository.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return domain.InboundOrder{}, ErrNotFound
+		}
+		return domain.InboundOrder{}, ErrInternalServerError
+	}
+	return i, nil
+}
+
+func (s *service) List(ctx context.Context, f Filter) ([]domain.InboundOrder, error) {
+	orders, err := s.repository.List(ctx, f)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	return orders, nil
+}
+
+func (s *service) ReceivingReport(ctx context.Context, f Filter) ([]domain.ReceivingReport, error) {
+	report, err := s.repository.ReceivingReport(ctx, f)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	return report, nil
+}
+
+func (s *service) StreamReceivingReport(ctx context.Context, f Filter, fn func(domain.ReceivingReport) error) error {
+	if err := s.repository.StreamReceivingReport(ctx, f, fn); err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+// validateWarehouse checks that the entity with the given ID is in
+// the warehouse of the order, as returned by lookup. If it is not,
+// mismatch is returned.
+func validateWarehouse(ctx context.Context, warehouseID int, lookup func(context.Context, int) (int, error), id int, mismatch error) error {
+	actual, err := lookup(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrEmployeeNotFound),
+			errors.Is(err, ErrSectionNotFound),
+			errors.Is(err, ErrBatchNotFound):
+			return err
+		}
+		return ErrInternalServerError
+	}
+	if actual != warehouseID {
+		return mismatch
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package inboundorder_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -25,6 +26,8 @@ func TestCreateEmployee(t *testing.T) {
 			WarehouseID:    1,
 		}
 
+		mockedRepository.On("EmployeeWarehouse", mock.Anything, 1).Return(1, nil)
+		mockedRepository.On("BatchWarehouse", mock.Anything, 1).Return(1, nil)
 		mockedRepository.On("Save", mock.Anything, i).Return(1, nil)
 
 		report, err := s.Create(context.TODO(), i)
@@ -45,13 +48,130 @@ func TestCreateEmployee(t *testing.T) {
 			WarehouseID:    1,
 		}
 
+		mockedRepository.On("EmployeeWarehouse", mock.Anything, 1).Return(1, nil)
+		mockedRepository.On("BatchWarehouse", mock.Anything, 1).Return(1, nil)
 		mockedRepository.On("Save", mock.Anything, i).Return(0, inboundorder.ErrInternalServerError)
 
 		_, err := s.Create(context.TODO(), i)
 		assert.ErrorIs(t, err, inboundorder.ErrInternalServerError)
 
 	})
+	t.Run("should return a error when the employee works at another warehouse", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := inboundorder.NewService(&mockedRepository)
+
+		i := domain.InboundOrder{OrderNumber: "140", EmployeeID: 1, ProductBatchID: 1, WarehouseID: 1}
+
+		mockedRepository.On("EmployeeWarehouse", mock.Anything, 1).Return(2, nil)
+
+		_, err := s.Create(context.TODO(), i)
+		assert.ErrorIs(t, err, inboundorder.ErrEmployeeWarehouse)
+		mockedRepository.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	})
+	t.Run("should return a error when the batch is in another warehouse", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := inboundorder.NewService(&mockedRepository)
+
+		i := domain.InboundOrder{OrderNumber: "140", EmployeeID: 1, ProductBatchID: 1, WarehouseID: 1}
+
+		mockedRepository.On("EmployeeWarehouse", mock.Anything, 1).Return(1, nil)
+		mockedRepository.On("BatchWarehouse", mock.Anything, 1).Return(2, nil)
+
+		_, err := s.Create(context.TODO(), i)
+		assert.ErrorIs(t, err, inboundorder.ErrSectionWarehouse)
+	})
+}
+
+func TestReceive(t *testing.T) {
+	t.Run("should create the batch with all of its quantity in stock", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := inboundorder.NewService(&mockedRepository)
+
+		i := domain.InboundOrder{OrderNumber: "140", EmployeeID: 1, WarehouseID: 1}
+		b := domain.Batches{BatchNumber: 99, InitialQuantity: 20, SectionID: 2}
+		stored := b
+		stored.CurrentQuantity = 20
+		expected := domain.InboundReceipt{InboundOrder: i, ProductBatch: stored}
+
+		mockedRepository.On("Receive", mock.Anything, i, stored).Return(expected, nil)
+
+		receipt, err := s.Receive(context.TODO(), i, b)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, receipt)
+	})
+	t.Run("should return a error when the section is in another warehouse", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := inboundorder.NewService(&mockedRepository)
+
+		i := domain.InboundOrder{OrderNumber: "140", EmployeeID: 1, WarehouseID: 1}
+		b := domain.Batches{BatchNumber: 99, InitialQuantity: 20, SectionID: 2}
+
+		mockedRepository.On("Receive", mock.Anything, mock.Anything, mock.Anything).Return(domain.InboundReceipt{}, inboundorder.ErrSectionWarehouse)
+
+		_, err := s.Receive(context.TODO(), i, b)
+		assert.ErrorIs(t, err, inboundorder.ErrSectionWarehouse)
+	})
+	t.Run("should return a error when the section does not exist", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := inboundorder.NewService(&mockedRepository)
+
+		i := domain.InboundOrder{OrderNumber: "140", EmployeeID: 1, WarehouseID: 1}
+		b := domain.Batches{BatchNumber: 99, InitialQuantity: 20, SectionID: 2}
+
+		mockedRepository.On("Receive", mock.Anything, mock.Anything, mock.Anything).Return(domain.InboundReceipt{}, inboundorder.ErrSectionNotFound)
+
+		_, err := s.Receive(context.TODO(), i, b)
+		assert.ErrorIs(t, err, inboundorder.ErrSectionNotFound)
+	})
+	t.Run("should return a error when the batch number is taken", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := inboundorder.NewService(&mockedRepository)
+
+		i := domain.InboundOrder{OrderNumber: "140", EmployeeID: 1, WarehouseID: 1}
+		b := domain.Batches{BatchNumber: 99, InitialQuantity: 20, SectionID: 2}
+
+		mockedRepository.On("Receive", mock.Anything, mock.Anything, mock.Anything).Return(domain.InboundReceipt{}, inboundorder.ErrBatchNumberExists)
+
+		_, err := s.Receive(context.TODO(), i, b)
+		assert.ErrorIs(t, err, inboundorder.ErrBatchNumberExists)
+	})
+	t.Run("should return a error when the quantity is not positive", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := inboundorder.NewService(&mockedRepository)
+
+		_, err := s.Receive(context.TODO(), domain.InboundOrder{}, domain.Batches{})
+		assert.ErrorIs(t, err, inboundorder.ErrInvalidQuantity)
+	})
+}
+
+func TestQueries(t *testing.T) {
+	t.Run("should return not found when the order does not exist", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := inboundorder.NewService(&mockedRepository)
+
+		mockedRepository.On("Get", mock.Anything, 1).Return(domain.InboundOrder{}, inboundorder.ErrNotFound)
+
+		_, err := s.Get(context.TODO(), 1)
+		assert.ErrorIs(t, err, inboundorder.ErrNotFound)
+	})
+	t.Run("should return a generic error when the repository fails", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := inboundorder.NewService(&mockedRepository)
+
+		mockedRepository.On("ReceivingReport", mock.Anything, mock.Anything).Return([]domain.ReceivingReport{}, errors.New("connection lost"))
+
+		_, err := s.ReceivingReport(context.TODO(), inboundorder.Filter{})
+		assert.ErrorIs(t, err, inboundorder.ErrInternalServerError)
+	})
+	t.Run("should return a generic error when the report cannot be streamed", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := inboundorder.NewService(&mockedRepository)
 
+		mockedRepository.On("StreamReceivingReport", mock.Anything, mock.Anything).Return([]domain.ReceivingReport{}, errors.New("connection lost"))
+
+		err := s.StreamReceivingReport(context.TODO(), inboundorder.Filter{}, func(domain.ReceivingReport) error { return nil })
+		assert.ErrorIs(t, err, inboundorder.ErrInternalServerError)
+	})
 }
 
 type RepositoryMock struct {
@@ -62,3 +182,43 @@ func (r *RepositoryMock) Save(ctx context.Context, i domain.InboundOrder) (int,
 	args := r.Called(ctx, i)
 	return args.Get(0).(int), args.Error(1)
 }
+
+func (r *RepositoryMock) Receive(ctx context.Context, i domain.InboundOrder, b domain.Batches) (domain.InboundReceipt, error) {
+	args := r.Called(ctx, i, b)
+	return args.Get(0).(domain.InboundReceipt), args.Error(1)
+}
+
+func (r *RepositoryMock) EmployeeWarehouse(ctx context.Context, employeeID int) (int, error) {
+	args := r.Called(ctx, employeeID)
+	return args.Get(0).(int), args.Error(1)
+}
+
+func (r *RepositoryMock) BatchWarehouse(ctx context.Context, batchID int) (int, error) {
+	args := r.Called(ctx, batchID)
+	return args.Get(0).(int), args.Error(1)
+}
+
+func (r *RepositoryMock) Get(ctx context.Context, id int) (domain.InboundOrder, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).(domain.InboundOrder), args.Error(1)
+}
+
+func (r *RepositoryMock) List(ctx context.Context, f inboundorder.Filter) ([]domain.InboundOrder, error) {
+	args := r.Called(ctx, f)
+	return args.Get(0).([]domain.InboundOrder), args.Error(1)
+}
+
+func (r *RepositoryMock) ReceivingReport(ctx context.Context, f inboundorder.Filter) ([]domain.ReceivingReport, error) {
+	args := r.Called(ctx, f)
+	return args.Get(0).([]domain.ReceivingReport), args.Error(1)
+}
+
+func (r *RepositoryMock) StreamReceivingReport(ctx context.Context, f inboundorder.Filter, fn func(domain.ReceivingReport) error) error {
+	args := r.Called(ctx, f)
+	for _, entry := range args.Get(0).([]domain.ReceivingReport) {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
@@ -0,0 +1,69 @@
+package localities
+
+import (
+	"context"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/cache"
+)
+
+type cachedRepository struct {
+	Repository
+	localities cache.Cache[int, domain.Locality]
+	all        cache.Cache[struct{}, []domain.Locality]
+}
+
+// NewCachedRepository decorates repo to keep the localities it reads
+// by id in localities, and the list of all of them in all. Its writes
+// purge both, since the names of provinces and countries are part of
+// every locality. The cached lists must not be modified.
+func NewCachedRepository(repo Repository, localities cache.Cache[int, domain.Locality], all cache.Cache[struct{}, []domain.Locality]) Repository {
+	return &cachedRepository{repo, localities, all}
+}
+
+func (r *cachedRepository) GetAll(c context.Context) ([]domain.Locality, error) {
+	return cache.GetOrLoad(c, r.all, struct{}{}, func() ([]domain.Locality, error) {
+		return r.Repository.GetAll(c)
+	})
+}
+
+func (r *cachedRepository) Get(c context.Context, id int) (domain.Locality, error) {
+	return cache.GetOrLoad(c, r.localities, id, func() (domain.Locality, error) {
+		return r.Repository.Get(c, id)
+	})
+}
+
+func (r *cachedRepository) Save(c context.Context, loc domain.Locality) (int, error) {
+	defer r.purge(c)
+	return r.Repository.Save(c, loc)
+}
+
+func (r *cachedRepository) SaveInProvince(c context.Context, name string, provinceID int) (int, error) {
+	defer r.purge(c)
+	return r.Repository.SaveInProvince(c, name, provinceID)
+}
+
+func (r *cachedRepository) Update(c context.Context, loc domain.Locality) error {
+	defer r.purge(c)
+	return r.Repository.Update(c, loc)
+}
+
+func (r *cachedRepository) Delete(c context.Context, id int) error {
+	defer r.purge(c)
+	return r.Repository.Delete(c, id)
+}
+
+func (r *cachedRepository) UpdateCountry(c context.Context, country domain.Country) error {
+	defer r.purge(c)
+	return r.Repository.UpdateCountry(c, country)
+}
+
+func (r *cachedRepository) UpdateProvince(c context.Context, province domain.Province) error {
+	defer r.purge(c)
+	return r.Repository.UpdateProvince(c, province)
+}
+
+func (r *cachedRepository) purge(c context.Context) {
+	r.localities.Purge(c)
+	r.all.Purge(c)
+}
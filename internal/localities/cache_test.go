@@ -0,0 +1,49 @@
+package localities_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/localities"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/cache"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCachedRepository(t *testing.T) {
+	locs := []domain.Locality{{ID: 1, Name: "Palermo", Province: "Buenos Aires", Country: "Argentina"}}
+
+	t.Run("Loads the localities once for every report", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		repo := localities.NewCachedRepository(&mockRepo, cache.NewLRU[int, domain.Locality](10, time.Minute), cache.NewLRU[struct{}, []domain.Locality](1, time.Minute))
+		svc := localities.NewService(repo)
+
+		mockRepo.On("GetAll", mock.Anything).Return(locs, nil).Once()
+		mockRepo.On("CountSellersByLocalities", mock.Anything, []int{1}).Return([]localities.Count{{LocalityID: 1, Count: 2}}, nil)
+
+		svc.CountSellers(context.TODO(), optional.Opt[int]{})
+		report, err := svc.CountSellers(context.TODO(), optional.Opt[int]{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []localities.CountByLocality{{ID: 1, Name: "Palermo", Count: 2}}, report)
+		mockRepo.AssertExpectations(t)
+	})
+	t.Run("Purges the localities when a province changes", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		all := cache.NewLRU[struct{}, []domain.Locality](1, time.Minute)
+		repo := localities.NewCachedRepository(&mockRepo, cache.NewLRU[int, domain.Locality](10, time.Minute), all)
+
+		province := domain.Province{ID: 1, Name: "CABA"}
+		mockRepo.On("GetAll", mock.Anything).Return(locs, nil).Twice()
+		mockRepo.On("UpdateProvince", mock.Anything, province).Return(nil)
+
+		repo.GetAll(context.TODO())
+		repo.UpdateProvince(context.TODO(), province)
+		repo.GetAll(context.TODO())
+
+		mockRepo.AssertExpectations(t)
+	})
+}
@@ -2,6 +2,7 @@ package localities
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 )
@@ -41,3 +42,84 @@ func NewErrNotFound(id int) *ErrNotFound {
 func (e ErrNotFound) Error() string {
 	return fmt.Sprintf("locality with ID %d not found", e.ID)
 }
+
+type ErrCountryNotFound struct {
+	ID int
+}
+
+func NewErrCountryNotFound(id int) *ErrCountryNotFound {
+	return &ErrCountryNotFound{id}
+}
+
+func (e ErrCountryNotFound) Error() string {
+	return fmt.Sprintf("country with ID %d not found", e.ID)
+}
+
+type ErrProvinceNotFound struct {
+	ID int
+}
+
+func NewErrProvinceNotFound(id int) *ErrProvinceNotFound {
+	return &ErrProvinceNotFound{id}
+}
+
+func (e ErrProvinceNotFound) Error() string {
+	return fmt.Sprintf("province with ID %d not found", e.ID)
+}
+
+// ErrDuplicateName is returned when a country, or a province
+// in the same country, already has the given name.
+type ErrDuplicateName struct {
+	Entity string
+	Name   string
+}
+
+func NewErrDuplicateName(entity, name string) *ErrDuplicateName {
+	return &ErrDuplicateName{entity, name}
+}
+
+func (e ErrDuplicateName) Error() string {
+	return fmt.Sprintf("%s already exists: %s", e.Entity, e.Name)
+}
+
+// ErrInvalidProvince is returned when a locality is
+// moved to a province that does not exist.
+type ErrInvalidProvince struct {
+	ID int
+}
+
+func NewErrInvalidProvince(id int) *ErrInvalidProvince {
+	return &ErrInvalidProvince{id}
+}
+
+func (e ErrInvalidProvince) Error() string {
+	return fmt.Sprintf("province_id %d not found", e.ID)
+}
+
+// ErrInUse is returned when deleting a country, province or
+// locality that other records still reference.
+type ErrInUse struct {
+	Entity       string
+	ID           int
+	ReferencedBy []string
+}
+
+func NewErrInUse(entity string, id int, referencedBy ...string) *ErrInUse {
+	return &ErrInUse{entity, id, referencedBy}
+}
+
+func (e ErrInUse) Error() string {
+	return fmt.Sprintf("%s with ID %d is referenced by %s", e.Entity, e.ID, strings.Join(e.ReferencedBy, ", "))
+}
+
+type ErrInvalidName struct {
+	Entity string
+}
+
+func NewErrInvalidName(entity string) *ErrInvalidName {
+	return &ErrInvalidName{entity}
+}
+
+func (e ErrInvalidName) Error() string {
+	return fmt.Sprintf("%s name cannot be empty", e.Entity)
+}
@@ -0,0 +1,297 @@
+package localities
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
+)
+
+// UpdateDTO holds the fields of a locality to update.
+// Setting ProvinceID moves the locality to that province.
+type UpdateDTO struct {
+	Name       optional.Opt[string]
+	ProvinceID optional.Opt[int]
+}
+
+func (svc *service) GetAllCountries(c context.Context) ([]domain.Country, error) {
+	countries, err := svc.repo.GetAllCountries(c)
+	if err != nil {
+		return nil, NewErrGeneric("error fetching countries")
+	}
+	return countries, nil
+}
+
+func (svc *service) GetCountry(c context.Context, id int) (domain.Country, error) {
+	country, err := svc.repo.GetCountry(c, id)
+	if err != nil {
+		return domain.Country{}, domainErr(err, "error fetching country")
+	}
+	return country, nil
+}
+
+func (svc *service) CreateCountry(c context.Context, name string) (domain.Country, error) {
+	country := domain.Country{Name: strings.TrimSpace(name)}
+	if country.Name == "" {
+		return domain.Country{}, NewErrInvalidName("country")
+	}
+
+	id, err := svc.repo.SaveCountry(c, country)
+	if err != nil {
+		return domain.Country{}, domainErr(err, "error saving country")
+	}
+	country.ID = id
+	return country, nil
+}
+
+func (svc *service) UpdateCountry(c context.Context, id int, name optional.Opt[string]) (domain.Country, error) {
+	country, err := svc.GetCountry(c, id)
+	if err != nil {
+		return domain.Country{}, err
+	}
+	if err := sqlutil.CheckVersion(c, country.Version); err != nil {
+		return domain.Country{}, err
+	}
+	if !name.HasVal {
+		return country, nil
+	}
+
+	country.Name = strings.TrimSpace(name.Val)
+	if country.Name == "" {
+		return domain.Country{}, NewErrInvalidName("country")
+	}
+	if err := svc.repo.UpdateCountry(c, country); err != nil {
+		return domain.Country{}, domainErr(err, "error saving country")
+	}
+	country.Version++
+	return country, nil
+}
+
+func (svc *service) DeleteCountry(c context.Context, id int) error {
+	country, err := svc.GetCountry(c, id)
+	if err != nil {
+		return err
+	}
+	if err := sqlutil.CheckVersion(c, country.Version); err != nil {
+		return err
+	}
+
+	provinces, err := svc.repo.GetProvinces(c, id)
+	if err != nil {
+		return NewErrGeneric("error fetching provinces")
+	}
+	if len(provinces) > 0 {
+		return NewErrInUse("country", id, "provinces")
+	}
+
+	if err := svc.repo.DeleteCountry(c, id); err != nil {
+		return domainErr(err, "error deleting country")
+	}
+	return nil
+}
+
+func (svc *service) GetProvinces(c context.Context, countryID int) ([]domain.Province, error) {
+	if _, err := svc.GetCountry(c, countryID); err != nil {
+		return nil, err
+	}
+
+	provinces, err := svc.repo.GetProvinces(c, countryID)
+	if err != nil {
+		return nil, NewErrGeneric("error fetching provinces")
+	}
+	return provinces, nil
+}
+
+func (svc *service) GetProvince(c context.Context, id int) (domain.Province, error) {
+	province, err := svc.repo.GetProvince(c, id)
+	if err != nil {
+		return domain.Province{}, domainErr(err, "error fetching province")
+	}
+	return province, nil
+}
+
+func (svc *service) CreateProvince(c context.Context, countryID int, name string) (domain.Province, error) {
+	province := domain.Province{Name: strings.TrimSpace(name), CountryID: countryID}
+	if province.Name == "" {
+		return domain.Province{}, NewErrInvalidName("province")
+	}
+	if _, err := svc.GetCountry(c, countryID); err != nil {
+		return domain.Province{}, err
+	}
+
+	id, err := svc.repo.SaveProvince(c, province)
+	if err != nil {
+		return domain.Province{}, domainErr(err, "error saving province")
+	}
+	province.ID = id
+	return province, nil
+}
+
+func (svc *service) UpdateProvince(c context.Context, id int, name optional.Opt[string]) (domain.Province, error) {
+	province, err := svc.GetProvince(c, id)
+	if err != nil {
+		return domain.Province{}, err
+	}
+	if err := sqlutil.CheckVersion(c, province.Version); err != nil {
+		return domain.Province{}, err
+	}
+	if !name.HasVal {
+		return province, nil
+	}
+
+	province.Name = strings.TrimSpace(name.Val)
+	if province.Name == "" {
+		return domain.Province{}, NewErrInvalidName("province")
+	}
+	if err := svc.repo.UpdateProvince(c, province); err != nil {
+		return domain.Province{}, domainErr(err, "error saving province")
+	}
+	province.Version++
+	return province, nil
+}
+
+func (svc *service) DeleteProvince(c context.Context, id int) error {
+	province, err := svc.GetProvince(c, id)
+	if err != nil {
+		return err
+	}
+	if err := sqlutil.CheckVersion(c, province.Version); err != nil {
+		return err
+	}
+
+	locs, err := svc.repo.GetByProvince(c, id)
+	if err != nil {
+		return NewErrGeneric("error fetching localities")
+	}
+	if len(locs) > 0 {
+		return NewErrInUse("province", id, "localities")
+	}
+
+	if err := svc.repo.DeleteProvince(c, id); err != nil {
+		return domainErr(err, "error deleting province")
+	}
+	return nil
+}
+
+func (svc *service) Get(c context.Context, id int) (domain.Locality, error) {
+	loc, err := svc.repo.Get(c, id)
+	if err != nil {
+		return domain.Locality{}, domainErr(err, "error fetching locality")
+	}
+	return loc, nil
+}
+
+func (svc *service) GetByProvince(c context.Context, provinceID int) ([]domain.Locality, error) {
+	if _, err := svc.GetProvince(c, provinceID); err != nil {
+		return nil, err
+	}
+
+	locs, err := svc.repo.GetByProvince(c, provinceID)
+	if err != nil {
+		return nil, NewErrGeneric("error fetching localities")
+	}
+	return locs, nil
+}
+
+func (svc *service) CreateInProvince(c context.Context, provinceID int, name string) (domain.Locality, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return domain.Locality{}, NewErrInvalidName("locality")
+	}
+	if _, err := svc.GetProvince(c, provinceID); err != nil {
+		return domain.Locality{}, err
+	}
+
+	id, err := svc.repo.SaveInProvince(c, name, provinceID)
+	if err != nil {
+		return domain.Locality{}, domainErr(err, "error saving locality")
+	}
+	return svc.Get(c, id)
+}
+
+func (svc *service) Update(c context.Context, id int, updates UpdateDTO) (domain.Locality, error) {
+	loc, err := svc.Get(c, id)
+	if err != nil {
+		return domain.Locality{}, err
+	}
+	if err := sqlutil.CheckVersion(c, loc.Version); err != nil {
+		return domain.Locality{}, err
+	}
+
+	loc.Name = strings.TrimSpace(updates.Name.Or(loc.Name))
+	if loc.Name == "" {
+		return domain.Locality{}, NewErrInvalidName("locality")
+	}
+	loc.ProvinceID = updates.ProvinceID.Or(loc.ProvinceID)
+
+	if err := svc.repo.Update(c, loc); err != nil {
+		return domain.Locality{}, domainErr(err, "error saving locality")
+	}
+	// The province and country names may have changed.
+	return svc.Get(c, id)
+}
+
+func (svc *service) Delete(c context.Context, id int) error {
+	loc, err := svc.Get(c, id)
+	if err != nil {
+		return err
+	}
+	if err := sqlutil.CheckVersion(c, loc.Version); err != nil {
+		return err
+	}
+
+	usage, err := svc.repo.Usage(c, id)
+	if err != nil {
+		return NewErrGeneric("error checking locality references")
+	}
+	if refs := usage.referencedBy(); len(refs) > 0 {
+		return NewErrInUse("locality", id, refs...)
+	}
+
+	if err := svc.repo.Delete(c, id); err != nil {
+		return domainErr(err, "error deleting locality")
+	}
+	return nil
+}
+
+func (u Usage) referencedBy() []string {
+	refs := make([]string, 0)
+	if u.Sellers > 0 {
+		refs = append(refs, "sellers")
+	}
+	if u.Carriers > 0 {
+		refs = append(refs, "carriers")
+	}
+	if u.Warehouses > 0 {
+		refs = append(refs, "warehouses")
+	}
+	return refs
+}
+
+// domainErr returns err if it is one of the errors of this package
+// or sqlutil.ErrVersionMismatch, or a generic error with the given
+// message otherwise.
+func domainErr(err error, message string) error {
+	var (
+		notFound         *ErrNotFound
+		countryNotFound  *ErrCountryNotFound
+		provinceNotFound *ErrProvinceNotFound
+		duplicate        *ErrDuplicateName
+		invalidProvince  *ErrInvalidProvince
+		inUse            *ErrInUse
+	)
+	switch {
+	case errors.As(err, &notFound),
+		errors.As(err, &countryNotFound),
+		errors.As(err, &provinceNotFound),
+		errors.As(err, &duplicate),
+		errors.As(err, &invalidProvince),
+		errors.As(err, &inUse),
+		errors.Is(err, sqlutil.ErrVersionMismatch):
+		return err
+	}
+	return NewErrGeneric(message)
+}
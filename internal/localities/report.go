@@ -0,0 +1,163 @@
+package localities
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+)
+
+// Level is the region that report rows are rolled up to.
+type Level string
+
+const (
+	LevelLocality Level = "locality"
+	LevelProvince Level = "province"
+	LevelCountry  Level = "country"
+)
+
+func ParseLevel(s string) (Level, error) {
+	switch level := Level(s); level {
+	case LevelLocality, LevelProvince, LevelCountry:
+		return level, nil
+	}
+	return "", fmt.Errorf("level must be one of locality, province or country")
+}
+
+// StockByLocality is the stock in a region. Count is the sum of
+// the current quantities of the batches in its sections.
+type StockByLocality struct {
+	CountByLocality
+	Warehouses int
+	Sections   int
+}
+
+func (svc *service) CountWarehouses(c context.Context, id optional.Opt[int], level Level) ([]CountByLocality, error) {
+	regions, ids, err := svc.regions(c, id, level)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := svc.repo.CountWarehousesByLocalities(c, ids)
+	if err != nil {
+		return nil, NewErrGeneric("error counting warehouses")
+	}
+
+	totals := make(map[int]*CountByLocality)
+	for _, stat := range stats {
+		region := regions[stat.LocalityID]
+		if _, ok := totals[region.ID]; !ok {
+			totals[region.ID] = &CountByLocality{ID: region.ID, Name: region.Name}
+		}
+		totals[region.ID].Count += stat.Count
+	}
+
+	report := make([]CountByLocality, 0, len(totals))
+	for _, total := range totals {
+		report = append(report, *total)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].ID < report[j].ID })
+
+	return filterReport(report, id, level, func(e CountByLocality) int { return e.ID })
+}
+
+func (svc *service) Stock(c context.Context, id optional.Opt[int], level Level) ([]StockByLocality, error) {
+	regions, ids, err := svc.regions(c, id, level)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := svc.repo.StockByLocalities(c, ids)
+	if err != nil {
+		return nil, NewErrGeneric("error summing stock")
+	}
+
+	totals := make(map[int]*StockByLocality)
+	for _, stat := range stats {
+		region := regions[stat.LocalityID]
+		if _, ok := totals[region.ID]; !ok {
+			totals[region.ID] = &StockByLocality{CountByLocality: CountByLocality{ID: region.ID, Name: region.Name}}
+		}
+		totals[region.ID].Count += stat.Quantity
+		totals[region.ID].Warehouses += stat.Warehouses
+		totals[region.ID].Sections += stat.Sections
+	}
+
+	report := make([]StockByLocality, 0, len(totals))
+	for _, total := range totals {
+		report = append(report, *total)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].ID < report[j].ID })
+
+	return filterReport(report, id, level, func(e StockByLocality) int { return e.ID })
+}
+
+type region struct {
+	ID   int
+	Name string
+}
+
+// regions maps each locality to the region it is rolled up to,
+// and returns the IDs of the localities to report on. At the
+// locality level, only the given locality is reported.
+func (svc *service) regions(c context.Context, id optional.Opt[int], level Level) (map[int]region, []int, error) {
+	locs, err := svc.repo.GetAll(c)
+	if err != nil {
+		return nil, nil, NewErrGeneric("error fetching localities")
+	}
+
+	countryIDs := make(map[string]int)
+	if level == LevelCountry {
+		countries, err := svc.repo.GetAllCountries(c)
+		if err != nil {
+			return nil, nil, NewErrGeneric("error fetching countries")
+		}
+		for _, country := range countries {
+			countryIDs[country.Name] = country.ID
+		}
+	}
+
+	regions := make(map[int]region)
+	for _, loc := range locs {
+		regions[loc.ID] = regionOf(loc, level, countryIDs)
+	}
+
+	if level == LevelLocality {
+		return regions, getReportIDs(id, getLocalityIndex(locs)), nil
+	}
+	return regions, getReportIDs(optional.Opt[int]{}, getLocalityIndex(locs)), nil
+}
+
+func regionOf(loc domain.Locality, level Level, countryIDs map[string]int) region {
+	switch level {
+	case LevelProvince:
+		return region{loc.ProvinceID, loc.Province}
+	case LevelCountry:
+		return region{countryIDs[loc.Country], loc.Country}
+	}
+	return region{loc.ID, loc.Name}
+}
+
+// filterReport keeps only the row of the region with the given ID,
+// if any, and fails if there is no such region.
+func filterReport[T any](report []T, id optional.Opt[int], level Level, idOf func(T) int) ([]T, error) {
+	if !id.HasVal {
+		return report, nil
+	}
+
+	for _, e := range report {
+		if idOf(e) == id.Val {
+			return []T{e}, nil
+		}
+	}
+
+	switch level {
+	case LevelProvince:
+		return nil, NewErrProvinceNotFound(id.Val)
+	case LevelCountry:
+		return nil, NewErrCountryNotFound(id.Val)
+	}
+	return nil, NewErrNotFound(id.Val)
+}
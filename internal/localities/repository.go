@@ -3,10 +3,12 @@ package localities
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"log"
 	"strings"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 )
 
 type Count struct {
@@ -14,11 +16,64 @@ type Count struct {
 	Count      int
 }
 
+// Usage counts the records that reference a locality. Soft deleted
+// ones are left out, though they still keep it from being deleted.
+type Usage struct {
+	Sellers    int
+	Carriers   int
+	Warehouses int
+}
+
+// Stock sums the warehouses, sections and current
+// batch quantities in a locality.
+type Stock struct {
+	LocalityID int
+	Warehouses int
+	Sections   int
+	Quantity   int
+}
+
 type Repository interface {
 	Save(c context.Context, loc domain.Locality) (int, error)
 	GetAll(c context.Context) ([]domain.Locality, error)
 	CountSellersByLocalities(c context.Context, ids []int) ([]Count, error)
 	CountCarriersByLocalities(c context.Context, ids []int) ([]Count, error)
+	CountWarehousesByLocalities(c context.Context, ids []int) ([]Count, error)
+	StockByLocalities(c context.Context, ids []int) ([]Stock, error)
+	// StreamSellerCounts calls fn for the seller count of each
+	// locality, as it is read from the database.
+	StreamSellerCounts(c context.Context, fn func(CountByLocality) error) error
+	// StreamCarrierCounts calls fn for the carrier count of each
+	// locality, as it is read from the database.
+	StreamCarrierCounts(c context.Context, fn func(CountByLocality) error) error
+
+	Get(c context.Context, id int) (domain.Locality, error)
+	GetByProvince(c context.Context, provinceID int) ([]domain.Locality, error)
+	SaveInProvince(c context.Context, name string, provinceID int) (int, error)
+	// Update saves loc unless the locality is no longer in
+	// loc.Version, in which case it fails with sqlutil.ErrVersionMismatch.
+	Update(c context.Context, loc domain.Locality) error
+	// Delete removes the locality id if it is in one of the
+	// versions expected by c. The same goes for countries and
+	// provinces.
+	Delete(c context.Context, id int) error
+	Usage(c context.Context, id int) (Usage, error)
+
+	GetAllCountries(c context.Context) ([]domain.Country, error)
+	GetCountry(c context.Context, id int) (domain.Country, error)
+	SaveCountry(c context.Context, country domain.Country) (int, error)
+	// UpdateCountry renames a country in country.Version. As the
+	// name is part of its localities, their versions change too.
+	UpdateCountry(c context.Context, country domain.Country) error
+	DeleteCountry(c context.Context, id int) error
+
+	GetProvinces(c context.Context, countryID int) ([]domain.Province, error)
+	GetProvince(c context.Context, id int) (domain.Province, error)
+	SaveProvince(c context.Context, province domain.Province) (int, error)
+	// UpdateProvince renames a province in province.Version. As
+	// the name is part of its localities, their versions change too.
+	UpdateProvince(c context.Context, province domain.Province) error
+	DeleteProvince(c context.Context, id int) error
 }
 
 type repository struct {
@@ -45,12 +100,22 @@ func (r *repository) Save(ctx context.Context, loc domain.Locality) (int, error)
 	localityQuery := `
 		INSERT INTO localities (locality_name, province_id)
 			SELECT ?, p.id FROM provinces p
-		WHERE p.province_name = ?;`
+			JOIN countries c ON c.id = p.country_id
+		WHERE p.province_name = ? AND c.country_name = ?;`
 
-	r.db.Exec(countryQuery, loc.Country)
-	r.db.Exec(provinceQuery, loc.Province, loc.Country)
-	result, err := r.db.Exec(localityQuery, loc.Name, loc.Province)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
 
+	if _, err := tx.ExecContext(ctx, countryQuery, loc.Country); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, provinceQuery, loc.Province, loc.Country); err != nil {
+		return 0, err
+	}
+	result, err := tx.ExecContext(ctx, localityQuery, loc.Name, loc.Province, loc.Country)
 	if err != nil {
 		if isDuplicateError(err) {
 			return 0, NewErrInvalidLocality(loc)
@@ -62,12 +127,15 @@ func (r *repository) Save(ctx context.Context, loc domain.Locality) (int, error)
 	if err != nil {
 		return 0, err
 	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
 
 	return int(id), nil
 }
 
 func (r *repository) GetAll(c context.Context) ([]domain.Locality, error) {
-	query := `SELECT l.id, l.locality_name, p.province_name, c.country_name
+	query := `SELECT l.id, l.locality_name, p.id, p.province_name, c.country_name
 		FROM countries c JOIN provinces p ON c.id = p.country_id
 		JOIN localities l ON p.id = l.province_id;`
 
@@ -79,7 +147,7 @@ func (r *repository) GetAll(c context.Context) ([]domain.Locality, error) {
 	locs := make([]domain.Locality, 0)
 	for rows.Next() {
 		var loc domain.Locality
-		err := rows.Scan(&loc.ID, &loc.Name, &loc.Province, &loc.Country)
+		err := rows.Scan(&loc.ID, &loc.Name, &loc.ProvinceID, &loc.Province, &loc.Country)
 		if err != nil {
 			log.Println(err.Error())
 			return nil, err
@@ -97,7 +165,7 @@ func (r *repository) CountSellersByLocalities(c context.Context, ids []int) ([]C
 
 	query := `SELECT l.id, COUNT(s.id)
 		FROM localities l
-		LEFT JOIN sellers s ON s.locality_id = l.id
+		LEFT JOIN sellers s ON s.locality_id = l.id AND s.deleted_at IS NULL
 		WHERE l.id IN (?` + strings.Repeat(",?", len(ids)-1) + `)
 		GROUP BY l.id, l.locality_name;`
 
@@ -128,7 +196,7 @@ func (r *repository) CountCarriersByLocalities(c context.Context, ids []int) ([]
 
 	query := `SELECT l.id, COUNT(c.id)
 		FROM localities l
-		LEFT JOIN carriers c ON c.locality_id = l.id
+		LEFT JOIN carriers c ON c.locality_id = l.id AND c.deleted_at IS NULL
 		WHERE l.id IN (?` + strings.Repeat(",?", len(ids)-1) + `)
 		GROUP BY l.id, l.locality_name;`
 
@@ -152,6 +220,364 @@ func (r *repository) CountCarriersByLocalities(c context.Context, ids []int) ([]
 	return counts, nil
 }
 
+func (r *repository) StreamSellerCounts(c context.Context, fn func(CountByLocality) error) error {
+	return r.streamCounts(c, "sellers", fn)
+}
+
+func (r *repository) StreamCarrierCounts(c context.Context, fn func(CountByLocality) error) error {
+	return r.streamCounts(c, "carriers", fn)
+}
+
+// streamCounts calls fn for the count of the rows of table in each
+// locality, leaving out the soft deleted ones.
+func (r *repository) streamCounts(c context.Context, table string, fn func(CountByLocality) error) error {
+	query := `SELECT l.id, l.locality_name, COUNT(t.id)
+		FROM localities l
+		LEFT JOIN ` + table + ` t ON t.locality_id = l.id AND t.deleted_at IS NULL
+		GROUP BY l.id, l.locality_name
+		ORDER BY l.id;`
+
+	rows, err := r.db.QueryContext(c, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var count CountByLocality
+		if err := rows.Scan(&count.ID, &count.Name, &count.Count); err != nil {
+			return err
+		}
+		if err := fn(count); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *repository) CountWarehousesByLocalities(c context.Context, ids []int) ([]Count, error) {
+	if len(ids) == 0 {
+		return make([]Count, 0), nil
+	}
+
+	query := `SELECT l.id, COUNT(w.id)
+		FROM localities l
+		LEFT JOIN warehouses w ON w.locality_id = l.id AND w.deleted_at IS NULL
+		WHERE l.id IN (?` + strings.Repeat(",?", len(ids)-1) + `)
+		GROUP BY l.id;`
+
+	rows, err := r.db.QueryContext(c, query, convertToAny(ids)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make([]Count, 0)
+	for rows.Next() {
+		var count Count
+		if err := rows.Scan(&count.LocalityID, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
+
+func (r *repository) StockByLocalities(c context.Context, ids []int) ([]Stock, error) {
+	if len(ids) == 0 {
+		return make([]Stock, 0), nil
+	}
+
+	query := `SELECT l.id, COUNT(DISTINCT w.id), COUNT(DISTINCT s.id), COALESCE(SUM(b.current_quantity), 0)
+		FROM localities l
+		LEFT JOIN warehouses w ON w.locality_id = l.id AND w.deleted_at IS NULL
+		LEFT JOIN sections s ON s.warehouse_id = w.id AND s.deleted_at IS NULL
+		LEFT JOIN product_batches b ON b.section_id = s.id
+		WHERE l.id IN (?` + strings.Repeat(",?", len(ids)-1) + `)
+		GROUP BY l.id;`
+
+	rows, err := r.db.QueryContext(c, query, convertToAny(ids)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stocks := make([]Stock, 0)
+	for rows.Next() {
+		var stock Stock
+		if err := rows.Scan(&stock.LocalityID, &stock.Warehouses, &stock.Sections, &stock.Quantity); err != nil {
+			return nil, err
+		}
+		stocks = append(stocks, stock)
+	}
+	return stocks, rows.Err()
+}
+
+const selectLocality = `SELECT l.id, l.locality_name, p.id, p.province_name, c.country_name, l.version
+	FROM countries c JOIN provinces p ON c.id = p.country_id
+	JOIN localities l ON p.id = l.province_id`
+
+func (r *repository) Get(ctx context.Context, id int) (domain.Locality, error) {
+	row := r.db.QueryRowContext(ctx, selectLocality+" WHERE l.id = ?;", id)
+
+	var loc domain.Locality
+	err := row.Scan(&loc.ID, &loc.Name, &loc.ProvinceID, &loc.Province, &loc.Country, &loc.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Locality{}, NewErrNotFound(id)
+	}
+	if err != nil {
+		return domain.Locality{}, err
+	}
+	return loc, nil
+}
+
+func (r *repository) GetByProvince(ctx context.Context, provinceID int) ([]domain.Locality, error) {
+	rows, err := r.db.QueryContext(ctx, selectLocality+" WHERE p.id = ? ORDER BY l.locality_name;", provinceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locs := make([]domain.Locality, 0)
+	for rows.Next() {
+		var loc domain.Locality
+		if err := rows.Scan(&loc.ID, &loc.Name, &loc.ProvinceID, &loc.Province, &loc.Country, &loc.Version); err != nil {
+			return nil, err
+		}
+		locs = append(locs, loc)
+	}
+	return locs, rows.Err()
+}
+
+func (r *repository) SaveInProvince(ctx context.Context, name string, provinceID int) (int, error) {
+	query := `INSERT INTO localities (locality_name, province_id) VALUES (?, ?);`
+	result, err := r.db.ExecContext(ctx, query, name, provinceID)
+	if err != nil {
+		if isDuplicateError(err) {
+			return 0, NewErrDuplicateName("locality", name)
+		}
+		if isMissingReference(err) {
+			return 0, NewErrProvinceNotFound(provinceID)
+		}
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func (r *repository) Update(ctx context.Context, loc domain.Locality) error {
+	query := `UPDATE localities SET locality_name = ?, province_id = ?, version = version + 1 WHERE id = ? AND version = ?;`
+	result, err := r.db.ExecContext(ctx, query, loc.Name, loc.ProvinceID, loc.ID, loc.Version)
+	if err != nil {
+		if isDuplicateError(err) {
+			return NewErrDuplicateName("locality", loc.Name)
+		}
+		if isMissingReference(err) {
+			return NewErrInvalidProvince(loc.ProvinceID)
+		}
+		return err
+	}
+	return r.changed(ctx, result, "localities", loc.ID, NewErrNotFound(loc.ID))
+}
+
+func (r *repository) Delete(ctx context.Context, id int) error {
+	return r.delete(ctx, "localities", id, "locality", NewErrNotFound(id))
+}
+
+func (r *repository) Usage(ctx context.Context, id int) (Usage, error) {
+	query := `SELECT
+		(SELECT COUNT(*) FROM sellers WHERE locality_id = ? AND deleted_at IS NULL),
+		(SELECT COUNT(*) FROM carriers WHERE locality_id = ? AND deleted_at IS NULL),
+		(SELECT COUNT(*) FROM warehouses WHERE locality_id = ? AND deleted_at IS NULL);`
+
+	var u Usage
+	err := r.db.QueryRowContext(ctx, query, id, id, id).Scan(&u.Sellers, &u.Carriers, &u.Warehouses)
+	if err != nil {
+		return Usage{}, err
+	}
+	return u, nil
+}
+
+func (r *repository) GetAllCountries(ctx context.Context) ([]domain.Country, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, country_name, version FROM countries ORDER BY country_name;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	countries := make([]domain.Country, 0)
+	for rows.Next() {
+		var country domain.Country
+		if err := rows.Scan(&country.ID, &country.Name, &country.Version); err != nil {
+			return nil, err
+		}
+		countries = append(countries, country)
+	}
+	return countries, rows.Err()
+}
+
+func (r *repository) GetCountry(ctx context.Context, id int) (domain.Country, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT id, country_name, version FROM countries WHERE id = ?;`, id)
+
+	var country domain.Country
+	err := row.Scan(&country.ID, &country.Name, &country.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Country{}, NewErrCountryNotFound(id)
+	}
+	if err != nil {
+		return domain.Country{}, err
+	}
+	return country, nil
+}
+
+func (r *repository) SaveCountry(ctx context.Context, country domain.Country) (int, error) {
+	result, err := r.db.ExecContext(ctx, `INSERT INTO countries (country_name) VALUES (?);`, country.Name)
+	if err != nil {
+		if isDuplicateError(err) {
+			return 0, NewErrDuplicateName("country", country.Name)
+		}
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func (r *repository) UpdateCountry(ctx context.Context, country domain.Country) error {
+	query := `UPDATE countries c
+		LEFT JOIN provinces p ON p.country_id = c.id
+		LEFT JOIN localities l ON l.province_id = p.id
+		SET c.country_name = ?, c.version = c.version + 1, l.version = l.version + 1
+		WHERE c.id = ? AND c.version = ?;`
+	result, err := r.db.ExecContext(ctx, query, country.Name, country.ID, country.Version)
+	if err != nil {
+		if isDuplicateError(err) {
+			return NewErrDuplicateName("country", country.Name)
+		}
+		return err
+	}
+	return r.changed(ctx, result, "countries", country.ID, NewErrCountryNotFound(country.ID))
+}
+
+func (r *repository) DeleteCountry(ctx context.Context, id int) error {
+	return r.delete(ctx, "countries", id, "country", NewErrCountryNotFound(id))
+}
+
+func (r *repository) GetProvinces(ctx context.Context, countryID int) ([]domain.Province, error) {
+	query := `SELECT id, province_name, country_id, version FROM provinces WHERE country_id = ? ORDER BY province_name;`
+	rows, err := r.db.QueryContext(ctx, query, countryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	provinces := make([]domain.Province, 0)
+	for rows.Next() {
+		var province domain.Province
+		if err := rows.Scan(&province.ID, &province.Name, &province.CountryID, &province.Version); err != nil {
+			return nil, err
+		}
+		provinces = append(provinces, province)
+	}
+	return provinces, rows.Err()
+}
+
+func (r *repository) GetProvince(ctx context.Context, id int) (domain.Province, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT id, province_name, country_id, version FROM provinces WHERE id = ?;`, id)
+
+	var province domain.Province
+	err := row.Scan(&province.ID, &province.Name, &province.CountryID, &province.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Province{}, NewErrProvinceNotFound(id)
+	}
+	if err != nil {
+		return domain.Province{}, err
+	}
+	return province, nil
+}
+
+func (r *repository) SaveProvince(ctx context.Context, province domain.Province) (int, error) {
+	query := `INSERT INTO provinces (province_name, country_id) VALUES (?, ?);`
+	result, err := r.db.ExecContext(ctx, query, province.Name, province.CountryID)
+	if err != nil {
+		if isDuplicateError(err) {
+			return 0, NewErrDuplicateName("province", province.Name)
+		}
+		if isMissingReference(err) {
+			return 0, NewErrCountryNotFound(province.CountryID)
+		}
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func (r *repository) UpdateProvince(ctx context.Context, province domain.Province) error {
+	query := `UPDATE provinces p
+		LEFT JOIN localities l ON l.province_id = p.id
+		SET p.province_name = ?, p.version = p.version + 1, l.version = l.version + 1
+		WHERE p.id = ? AND p.version = ?;`
+	result, err := r.db.ExecContext(ctx, query, province.Name, province.ID, province.Version)
+	if err != nil {
+		if isDuplicateError(err) {
+			return NewErrDuplicateName("province", province.Name)
+		}
+		return err
+	}
+	return r.changed(ctx, result, "provinces", province.ID, NewErrProvinceNotFound(province.ID))
+}
+
+func (r *repository) DeleteProvince(ctx context.Context, id int) error {
+	return r.delete(ctx, "provinces", id, "province", NewErrProvinceNotFound(id))
+}
+
+// delete deletes the row id of table if it is in one of the versions
+// expected by ctx. If the row is still referenced, which the service
+// checks beforehand, it returns ErrInUse.
+func (r *repository) delete(ctx context.Context, table string, id int, entity string, notFound error) error {
+	query := "DELETE FROM " + table + " WHERE id = ? AND " + sqlutil.VersionMatches(ctx, "version") + ";"
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		if isReferencedError(err) {
+			return NewErrInUse(entity, id, "other records")
+		}
+		return err
+	}
+	return r.changed(ctx, result, table, id, notFound)
+}
+
+// changed tells why a write guarded by a version changed no row of
+// table, returning notFound or sqlutil.ErrVersionMismatch.
+func (r *repository) changed(ctx context.Context, result sql.Result, table string, id int, notFound error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sqlutil.StaleOrDeleted(ctx, r.db, table, id, notFound)
+	}
+	return nil
+}
+
+func isMissingReference(err error) bool {
+	return strings.HasPrefix(err.Error(), "Error 1452")
+}
+
+func isReferencedError(err error) bool {
+	return strings.HasPrefix(err.Error(), "Error 1451")
+}
+
 func isDuplicateError(err error) bool {
 	return strings.HasPrefix(err.Error(), "Error 1062")
 }
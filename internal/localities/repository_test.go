@@ -2,10 +2,12 @@ package localities_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/localities"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
 
 	"github.com/stretchr/testify/assert"
@@ -51,3 +53,166 @@ func TestRepositoryCreate(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestRepositoryHierarchy(t *testing.T) {
+	t.Run("Saves a locality and its new country and province", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := localities.NewRepository(db)
+
+		id, err := repo.Save(context.TODO(), domain.Locality{Name: "Santiago", Province: "RM", Country: "Chile"})
+		assert.NoError(t, err)
+
+		loc, err := repo.Get(context.TODO(), id)
+		assert.NoError(t, err)
+		assert.Equal(t, "RM", loc.Province)
+		assert.Equal(t, "Chile", loc.Country)
+	})
+	t.Run("Does not delete a province with localities", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := localities.NewRepository(db)
+
+		err := repo.DeleteProvince(context.TODO(), 1)
+
+		var expectedErr *localities.ErrInUse
+		assert.ErrorAs(t, err, &expectedErr)
+	})
+	t.Run("Counts the references to a locality", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := localities.NewRepository(db)
+
+		usage, err := repo.Usage(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, usage.Carriers)
+		assert.Equal(t, 1, usage.Warehouses)
+	})
+	t.Run("Returns not found for a missing country", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := localities.NewRepository(db)
+
+		_, err := repo.GetCountry(context.TODO(), 9999)
+
+		var expectedErr *localities.ErrCountryNotFound
+		assert.ErrorAs(t, err, &expectedErr)
+	})
+	t.Run("Updates a locality only in the version it was read", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := localities.NewRepository(db)
+
+		loc, err := repo.Get(context.TODO(), 1)
+		assert.NoError(t, err)
+
+		loc.Name = "Renamed"
+		assert.NoError(t, repo.Update(context.TODO(), loc))
+
+		err = repo.Update(context.TODO(), loc)
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+	})
+	t.Run("Renaming a province changes the version of its localities", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := localities.NewRepository(db)
+
+		before, err := repo.Get(context.TODO(), 1)
+		assert.NoError(t, err)
+		province, err := repo.GetProvince(context.TODO(), before.ProvinceID)
+		assert.NoError(t, err)
+
+		province.Name = "Renamed"
+		assert.NoError(t, repo.UpdateProvince(context.TODO(), province))
+
+		after, err := repo.Get(context.TODO(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "Renamed", after.Province)
+		assert.Equal(t, before.Version+1, after.Version)
+	})
+	t.Run("Deletes a country only in the expected version", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := localities.NewRepository(db)
+
+		id, err := repo.SaveCountry(context.TODO(), domain.Country{Name: "Chile"})
+		assert.NoError(t, err)
+
+		err = repo.DeleteCountry(sqlutil.WithIfMatch(context.TODO(), []int{2}), id)
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+
+		err = repo.DeleteCountry(sqlutil.WithIfMatch(context.TODO(), []int{1}), id)
+		assert.NoError(t, err)
+	})
+}
+
+func TestRepositoryStock(t *testing.T) {
+	t.Run("Counts warehouses in each locality", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := localities.NewRepository(db)
+
+		counts, err := repo.CountWarehousesByLocalities(context.TODO(), []int{1})
+
+		assert.NoError(t, err)
+		assert.Len(t, counts, 1)
+		assert.Equal(t, 1, counts[0].LocalityID)
+	})
+	t.Run("Sums the stock in each locality", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := localities.NewRepository(db)
+
+		stock, err := repo.StockByLocalities(context.TODO(), []int{1})
+
+		assert.NoError(t, err)
+		assert.Len(t, stock, 1)
+		assert.Equal(t, 1, stock[0].LocalityID)
+	})
+}
+
+func TestRepositoryStreamCounts(t *testing.T) {
+	t.Run("Streams the seller count of every locality", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := localities.NewRepository(db)
+
+		var counts []localities.CountByLocality
+		err := repo.StreamSellerCounts(context.TODO(), func(c localities.CountByLocality) error {
+			counts = append(counts, c)
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, counts)
+		assert.Equal(t, 1, counts[0].ID)
+		assert.NotEmpty(t, counts[0].Name)
+	})
+	t.Run("Stops streaming when the callback fails", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := localities.NewRepository(db)
+
+		stop := errors.New("stop")
+		calls := 0
+		err := repo.StreamCarrierCounts(context.TODO(), func(localities.CountByLocality) error {
+			calls++
+			return stop
+		})
+
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, calls)
+	})
+}
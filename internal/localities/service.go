@@ -32,6 +32,46 @@ type Service interface {
 	//  the slice will only contain the count for that locality; otherwise,
 	//  the slice will contain all localities.
 	CountCarriers(c context.Context, id optional.Opt[int]) ([]CountByLocality, error)
+	// StreamSellers calls fn for the seller count of every locality
+	// without loading them all in memory.
+	StreamSellers(c context.Context, fn func(CountByLocality) error) error
+	// StreamCarriers calls fn for the carrier count of every locality
+	// without loading them all in memory.
+	StreamCarriers(c context.Context, fn func(CountByLocality) error) error
+	// godoc CountWarehouses
+	//  Returns slice of Warehouse count by region, where regions are
+	//  localities, provinces or countries depending on level. If id is
+	//  specified, the slice will only contain the count for that region.
+	CountWarehouses(c context.Context, id optional.Opt[int], level Level) ([]CountByLocality, error)
+	// godoc Stock
+	//  Returns slice of warehouses, sections and stock quantity by region,
+	//  where regions are localities, provinces or countries depending on
+	//  level. If id is specified, the slice will only contain that region.
+	Stock(c context.Context, id optional.Opt[int], level Level) ([]StockByLocality, error)
+
+	Get(c context.Context, id int) (domain.Locality, error)
+	// GetByProvince returns the localities of a province.
+	GetByProvince(c context.Context, provinceID int) ([]domain.Locality, error)
+	CreateInProvince(c context.Context, provinceID int, name string) (domain.Locality, error)
+	Update(c context.Context, id int, updates UpdateDTO) (domain.Locality, error)
+	// Delete removes a locality, unless sellers, carriers
+	// or warehouses are in it.
+	Delete(c context.Context, id int) error
+
+	GetAllCountries(c context.Context) ([]domain.Country, error)
+	GetCountry(c context.Context, id int) (domain.Country, error)
+	CreateCountry(c context.Context, name string) (domain.Country, error)
+	UpdateCountry(c context.Context, id int, name optional.Opt[string]) (domain.Country, error)
+	// DeleteCountry removes a country without provinces.
+	DeleteCountry(c context.Context, id int) error
+
+	// GetProvinces returns the provinces of a country.
+	GetProvinces(c context.Context, countryID int) ([]domain.Province, error)
+	GetProvince(c context.Context, id int) (domain.Province, error)
+	CreateProvince(c context.Context, countryID int, name string) (domain.Province, error)
+	UpdateProvince(c context.Context, id int, name optional.Opt[string]) (domain.Province, error)
+	// DeleteProvince removes a province without localities.
+	DeleteProvince(c context.Context, id int) error
 }
 
 type service struct {
@@ -56,7 +96,12 @@ func (svc *service) Create(c context.Context, loc CreateDTO) (domain.Locality, e
 	}
 	locDomain.ID = id
 
-	return locDomain, nil
+	// Read back the locality to get the ID of its province.
+	saved, err := svc.repo.Get(c, id)
+	if err != nil {
+		return locDomain, nil
+	}
+	return saved, nil
 }
 
 func (svc *service) CountSellers(c context.Context, id optional.Opt[int]) ([]CountByLocality, error) {
@@ -113,6 +158,20 @@ func (svc *service) CountCarriers(c context.Context, id optional.Opt[int]) ([]Co
 	return report, nil
 }
 
+func (svc *service) StreamSellers(c context.Context, fn func(CountByLocality) error) error {
+	if err := svc.repo.StreamSellerCounts(c, fn); err != nil {
+		return NewErrGeneric("error counting sellers")
+	}
+	return nil
+}
+
+func (svc *service) StreamCarriers(c context.Context, fn func(CountByLocality) error) error {
+	if err := svc.repo.StreamCarrierCounts(c, fn); err != nil {
+		return NewErrGeneric("error counting carriers")
+	}
+	return nil
+}
+
 func newSellersByLocality(loc domain.Locality, count int) CountByLocality {
 	return CountByLocality{
 		ID:    loc.ID,
@@ -8,6 +8,7 @@ import (
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/localities"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -31,6 +32,7 @@ func TestCreate(t *testing.T) {
 			Country:  "Brasil",
 		}
 		repo.On("Save", mock.Anything, mock.Anything).Return(1, nil)
+		repo.On("Get", mock.Anything, 1).Return(expected, nil)
 
 		received, err := svc.Create(context.TODO(), dto)
 
@@ -77,6 +79,227 @@ func TestCreate(t *testing.T) {
 	})
 }
 
+func TestHierarchy(t *testing.T) {
+	t.Run("Lists the provinces of an existing country", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		expected := []domain.Province{{ID: 1, Name: "SP", CountryID: 1}}
+		repo.On("GetCountry", mock.Anything, 1).Return(domain.Country{ID: 1, Name: "Brasil"}, nil)
+		repo.On("GetProvinces", mock.Anything, 1).Return(expected, nil)
+
+		received, err := svc.GetProvinces(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, received)
+	})
+	t.Run("Does not list provinces of a missing country", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		repo.On("GetCountry", mock.Anything, 9).Return(domain.Country{}, localities.NewErrCountryNotFound(9))
+
+		_, err := svc.GetProvinces(context.TODO(), 9)
+
+		var expectedErr *localities.ErrCountryNotFound
+		assert.ErrorAs(t, err, &expectedErr)
+		repo.AssertNumberOfCalls(t, "GetProvinces", 0)
+	})
+	t.Run("Does not create a country with an empty name", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		_, err := svc.CreateCountry(context.TODO(), "  ")
+
+		var expectedErr *localities.ErrInvalidName
+		assert.ErrorAs(t, err, &expectedErr)
+		repo.AssertNumberOfCalls(t, "SaveCountry", 0)
+	})
+	t.Run("Does not delete a country with provinces", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		repo.On("GetCountry", mock.Anything, 1).Return(domain.Country{ID: 1}, nil)
+		repo.On("GetProvinces", mock.Anything, 1).Return([]domain.Province{{ID: 1}}, nil)
+
+		err := svc.DeleteCountry(context.TODO(), 1)
+
+		var expectedErr *localities.ErrInUse
+		assert.ErrorAs(t, err, &expectedErr)
+		repo.AssertNumberOfCalls(t, "DeleteCountry", 0)
+	})
+	t.Run("Does not delete a province with localities", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		repo.On("GetProvince", mock.Anything, 1).Return(domain.Province{ID: 1}, nil)
+		repo.On("GetByProvince", mock.Anything, 1).Return([]domain.Locality{{ID: 1}}, nil)
+
+		err := svc.DeleteProvince(context.TODO(), 1)
+
+		var expectedErr *localities.ErrInUse
+		assert.ErrorAs(t, err, &expectedErr)
+		repo.AssertNumberOfCalls(t, "DeleteProvince", 0)
+	})
+	t.Run("Creates a province in a country", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		repo.On("GetCountry", mock.Anything, 1).Return(domain.Country{ID: 1}, nil)
+		repo.On("SaveProvince", mock.Anything, domain.Province{Name: "RJ", CountryID: 1}).Return(3, nil)
+
+		received, err := svc.CreateProvince(context.TODO(), 1, "RJ")
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.Province{ID: 3, Name: "RJ", CountryID: 1}, received)
+	})
+	t.Run("Renames a province", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		repo.On("GetProvince", mock.Anything, 1).Return(domain.Province{ID: 1, Name: "SP", CountryID: 1}, nil)
+		repo.On("UpdateProvince", mock.Anything, domain.Province{ID: 1, Name: "São Paulo", CountryID: 1}).Return(nil)
+
+		received, err := svc.UpdateProvince(context.TODO(), 1, *optional.FromVal("São Paulo"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "São Paulo", received.Name)
+		assert.Equal(t, 1, received.Version)
+	})
+	t.Run("Does not rename a country in another version", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		repo.On("GetCountry", mock.Anything, 1).Return(domain.Country{ID: 1, Name: "Brasil", Version: 2}, nil)
+
+		ctx := sqlutil.WithIfMatch(context.TODO(), []int{1})
+		_, err := svc.UpdateCountry(ctx, 1, *optional.FromVal("Brazil"))
+
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+		repo.AssertNumberOfCalls(t, "UpdateCountry", 0)
+	})
+	t.Run("Does not delete a province in another version", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		repo.On("GetProvince", mock.Anything, 1).Return(domain.Province{ID: 1, Version: 2}, nil)
+
+		ctx := sqlutil.WithIfMatch(context.TODO(), []int{1})
+		err := svc.DeleteProvince(ctx, 1)
+
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+		repo.AssertNumberOfCalls(t, "DeleteProvince", 0)
+	})
+}
+
+func TestLocalityCRUD(t *testing.T) {
+	stored := domain.Locality{ID: 1, Name: "Melicidade", ProvinceID: 1, Province: "SP", Country: "Brasil"}
+
+	t.Run("Moves a locality to another province", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		moved := stored
+		moved.ProvinceID = 2
+		expected := moved
+		expected.Province = "RJ"
+
+		repo.On("Get", mock.Anything, 1).Return(stored, nil).Once()
+		repo.On("Update", mock.Anything, moved).Return(nil)
+		repo.On("Get", mock.Anything, 1).Return(expected, nil).Once()
+
+		received, err := svc.Update(context.TODO(), 1, localities.UpdateDTO{ProvinceID: *optional.FromVal(2)})
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, received)
+	})
+	t.Run("Does not move a locality to a missing province", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		repo.On("Get", mock.Anything, 1).Return(stored, nil)
+		repo.On("Update", mock.Anything, mock.Anything).Return(localities.NewErrInvalidProvince(9))
+
+		_, err := svc.Update(context.TODO(), 1, localities.UpdateDTO{ProvinceID: *optional.FromVal(9)})
+
+		var expectedErr *localities.ErrInvalidProvince
+		assert.ErrorAs(t, err, &expectedErr)
+	})
+	t.Run("Update fails if the locality is not in the expected version", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		inVersion := stored
+		inVersion.Version = 2
+		repo.On("Get", mock.Anything, 1).Return(inVersion, nil)
+
+		ctx := sqlutil.WithIfMatch(context.TODO(), []int{1})
+		_, err := svc.Update(ctx, 1, localities.UpdateDTO{Name: *optional.FromVal("Meli")})
+
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+		repo.AssertNumberOfCalls(t, "Update", 0)
+	})
+	t.Run("Update fails if the locality changed after it was read", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		repo.On("Get", mock.Anything, 1).Return(stored, nil)
+		repo.On("Update", mock.Anything, mock.Anything).Return(sqlutil.ErrVersionMismatch)
+
+		_, err := svc.Update(context.TODO(), 1, localities.UpdateDTO{Name: *optional.FromVal("Meli")})
+
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+	})
+	t.Run("Deletes an unreferenced locality", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		repo.On("Get", mock.Anything, 1).Return(stored, nil)
+		repo.On("Usage", mock.Anything, 1).Return(localities.Usage{}, nil)
+		repo.On("Delete", mock.Anything, 1).Return(nil)
+
+		err := svc.Delete(context.TODO(), 1)
+
+		assert.NoError(t, err)
+	})
+	t.Run("Does not delete a referenced locality", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		repo.On("Get", mock.Anything, 1).Return(stored, nil)
+		repo.On("Usage", mock.Anything, 1).Return(localities.Usage{Sellers: 2, Warehouses: 1}, nil)
+
+		err := svc.Delete(context.TODO(), 1)
+
+		var expectedErr *localities.ErrInUse
+		assert.ErrorAs(t, err, &expectedErr)
+		assert.Equal(t, []string{"sellers", "warehouses"}, expectedErr.ReferencedBy)
+		repo.AssertNumberOfCalls(t, "Delete", 0)
+	})
+	t.Run("Returns not found for a missing locality", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		repo.On("Get", mock.Anything, 9).Return(domain.Locality{}, localities.NewErrNotFound(9))
+
+		err := svc.Delete(context.TODO(), 9)
+
+		var expectedErr *localities.ErrNotFound
+		assert.ErrorAs(t, err, &expectedErr)
+	})
+	t.Run("Returns generic error if repository fails", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		repo.On("Get", mock.Anything, 1).Return(domain.Locality{}, ErrRepository)
+
+		_, err := svc.Get(context.TODO(), 1)
+
+		var expectedErr *localities.ErrGeneric
+		assert.ErrorAs(t, err, &expectedErr)
+	})
+}
+
 func TestSellerReport(t *testing.T) {
 	t.Run("Returns all localities when id is omitted", func(t *testing.T) {
 		repo := RepositoryMock{}
@@ -337,10 +560,119 @@ func TestCarrierReport(t *testing.T) {
 
 		_, err := svc.CountCarriers(context.TODO(), noID)
 
+		assert.ErrorAs(t, err, &expectedErr)
+	})
+	t.Run("Streams the count of every locality", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		expected := []localities.CountByLocality{{ID: 1, Name: "Melicidade", Count: 3}}
+		repo.On("StreamCarrierCounts", mock.Anything).Return(expected, nil)
+
+		var received []localities.CountByLocality
+		err := svc.StreamCarriers(context.TODO(), func(e localities.CountByLocality) error {
+			received = append(received, e)
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, received)
+	})
+	t.Run("Returns generic domain error if repository stream fails", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		var expectedErr *localities.ErrGeneric
+		repo.On("StreamCarrierCounts", mock.Anything).Return([]localities.CountByLocality{}, ErrRepository)
+
+		err := svc.StreamCarriers(context.TODO(), func(localities.CountByLocality) error { return nil })
+
 		assert.ErrorAs(t, err, &expectedErr)
 	})
 }
 
+func TestRegionalReports(t *testing.T) {
+	locs := []domain.Locality{
+		{ID: 1, Name: "Melicidade", ProvinceID: 1, Province: "SP", Country: "BR"},
+		{ID: 2, Name: "Tesla", ProvinceID: 1, Province: "SP", Country: "BR"},
+		{ID: 3, Name: "Palermo", ProvinceID: 2, Province: "BA", Country: "AR"},
+	}
+	countries := []domain.Country{{ID: 1, Name: "BR"}, {ID: 2, Name: "AR"}}
+
+	t.Run("Counts warehouses by locality", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		id := *optional.FromVal(2)
+		repo.On("GetAll", mock.Anything).Return(locs, nil)
+		repo.On("CountWarehousesByLocalities", mock.Anything, []int{2}).Return([]localities.Count{{LocalityID: 2, Count: 3}}, nil)
+
+		received, err := svc.CountWarehouses(context.TODO(), id, localities.LevelLocality)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []localities.CountByLocality{{ID: 2, Name: "Tesla", Count: 3}}, received)
+	})
+	t.Run("Rolls warehouse counts up to provinces", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		counts := []localities.Count{{LocalityID: 1, Count: 2}, {LocalityID: 2, Count: 1}, {LocalityID: 3, Count: 4}}
+		repo.On("GetAll", mock.Anything).Return(locs, nil)
+		repo.On("CountWarehousesByLocalities", mock.Anything, mock.Anything).Return(counts, nil)
+
+		received, err := svc.CountWarehouses(context.TODO(), optional.Opt[int]{}, localities.LevelProvince)
+
+		expected := []localities.CountByLocality{{ID: 1, Name: "SP", Count: 3}, {ID: 2, Name: "BA", Count: 4}}
+		assert.NoError(t, err)
+		assert.Equal(t, expected, received)
+	})
+	t.Run("Rolls stock up to a single country", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		stock := []localities.Stock{
+			{LocalityID: 1, Warehouses: 1, Sections: 2, Quantity: 50},
+			{LocalityID: 2, Warehouses: 1, Sections: 1, Quantity: 10},
+			{LocalityID: 3, Warehouses: 2, Sections: 4, Quantity: 99},
+		}
+		repo.On("GetAll", mock.Anything).Return(locs, nil)
+		repo.On("GetAllCountries", mock.Anything).Return(countries, nil)
+		repo.On("StockByLocalities", mock.Anything, mock.Anything).Return(stock, nil)
+
+		received, err := svc.Stock(context.TODO(), *optional.FromVal(1), localities.LevelCountry)
+
+		expected := []localities.StockByLocality{{
+			CountByLocality: localities.CountByLocality{ID: 1, Name: "BR", Count: 60},
+			Warehouses:      2,
+			Sections:        3,
+		}}
+		assert.NoError(t, err)
+		assert.Equal(t, expected, received)
+	})
+	t.Run("Returns ProvinceNotFound for a missing province", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		repo.On("GetAll", mock.Anything).Return(locs, nil)
+		repo.On("StockByLocalities", mock.Anything, mock.Anything).Return([]localities.Stock{}, nil)
+
+		_, err := svc.Stock(context.TODO(), *optional.FromVal(9), localities.LevelProvince)
+
+		assert.ErrorAs(t, err, new(*localities.ErrProvinceNotFound))
+	})
+	t.Run("Returns generic domain error if repository fails", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := localities.NewService(&repo)
+
+		repo.On("GetAll", mock.Anything).Return(locs, nil)
+		repo.On("StockByLocalities", mock.Anything, mock.Anything).Return([]localities.Stock{}, ErrRepository)
+
+		_, err := svc.Stock(context.TODO(), optional.Opt[int]{}, localities.LevelLocality)
+
+		assert.ErrorAs(t, err, new(*localities.ErrGeneric))
+	})
+}
+
 func getLocalities() []domain.Locality {
 	return []domain.Locality{
 		{
@@ -373,7 +705,117 @@ func (r *RepositoryMock) CountSellersByLocalities(c context.Context, ids []int)
 	return args.Get(0).([]localities.Count), args.Error(1)
 }
 
+func (r *RepositoryMock) StreamSellerCounts(c context.Context, fn func(localities.CountByLocality) error) error {
+	args := r.Called(c)
+	for _, entry := range args.Get(0).([]localities.CountByLocality) {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (r *RepositoryMock) StreamCarrierCounts(c context.Context, fn func(localities.CountByLocality) error) error {
+	args := r.Called(c)
+	for _, entry := range args.Get(0).([]localities.CountByLocality) {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
 func (r *RepositoryMock) CountCarriersByLocalities(c context.Context, ids []int) ([]localities.Count, error) {
 	args := r.Called(c, ids)
 	return args.Get(0).([]localities.Count), args.Error(1)
 }
+
+func (r *RepositoryMock) Get(c context.Context, id int) (domain.Locality, error) {
+	args := r.Called(c, id)
+	return args.Get(0).(domain.Locality), args.Error(1)
+}
+
+func (r *RepositoryMock) GetByProvince(c context.Context, provinceID int) ([]domain.Locality, error) {
+	args := r.Called(c, provinceID)
+	return args.Get(0).([]domain.Locality), args.Error(1)
+}
+
+func (r *RepositoryMock) SaveInProvince(c context.Context, name string, provinceID int) (int, error) {
+	args := r.Called(c, name, provinceID)
+	return args.Get(0).(int), args.Error(1)
+}
+
+func (r *RepositoryMock) Update(c context.Context, loc domain.Locality) error {
+	args := r.Called(c, loc)
+	return args.Error(0)
+}
+
+func (r *RepositoryMock) Delete(c context.Context, id int) error {
+	args := r.Called(c, id)
+	return args.Error(0)
+}
+
+func (r *RepositoryMock) Usage(c context.Context, id int) (localities.Usage, error) {
+	args := r.Called(c, id)
+	return args.Get(0).(localities.Usage), args.Error(1)
+}
+
+func (r *RepositoryMock) CountWarehousesByLocalities(c context.Context, ids []int) ([]localities.Count, error) {
+	args := r.Called(c, ids)
+	return args.Get(0).([]localities.Count), args.Error(1)
+}
+
+func (r *RepositoryMock) StockByLocalities(c context.Context, ids []int) ([]localities.Stock, error) {
+	args := r.Called(c, ids)
+	return args.Get(0).([]localities.Stock), args.Error(1)
+}
+
+func (r *RepositoryMock) GetAllCountries(c context.Context) ([]domain.Country, error) {
+	args := r.Called(c)
+	return args.Get(0).([]domain.Country), args.Error(1)
+}
+
+func (r *RepositoryMock) GetCountry(c context.Context, id int) (domain.Country, error) {
+	args := r.Called(c, id)
+	return args.Get(0).(domain.Country), args.Error(1)
+}
+
+func (r *RepositoryMock) SaveCountry(c context.Context, country domain.Country) (int, error) {
+	args := r.Called(c, country)
+	return args.Get(0).(int), args.Error(1)
+}
+
+func (r *RepositoryMock) UpdateCountry(c context.Context, country domain.Country) error {
+	args := r.Called(c, country)
+	return args.Error(0)
+}
+
+func (r *RepositoryMock) DeleteCountry(c context.Context, id int) error {
+	args := r.Called(c, id)
+	return args.Error(0)
+}
+
+func (r *RepositoryMock) GetProvinces(c context.Context, countryID int) ([]domain.Province, error) {
+	args := r.Called(c, countryID)
+	return args.Get(0).([]domain.Province), args.Error(1)
+}
+
+func (r *RepositoryMock) GetProvince(c context.Context, id int) (domain.Province, error) {
+	args := r.Called(c, id)
+	return args.Get(0).(domain.Province), args.Error(1)
+}
+
+func (r *RepositoryMock) SaveProvince(c context.Context, province domain.Province) (int, error) {
+	args := r.Called(c, province)
+	return args.Get(0).(int), args.Error(1)
+}
+
+func (r *RepositoryMock) UpdateProvince(c context.Context, province domain.Province) error {
+	args := r.Called(c, province)
+	return args.Error(0)
+}
+
+func (r *RepositoryMock) DeleteProvince(c context.Context, id int) error {
+	args := r.Called(c, id)
+	return args.Error(0)
+}
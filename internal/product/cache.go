@@ -0,0 +1,45 @@
+package product
+
+import (
+	"context"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/cache"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
+)
+
+type cachedRepository struct {
+	Repository
+	products cache.Cache[int, domain.Product]
+}
+
+// NewCachedRepository decorates repo to read products by id through c.
+// Its updates, deletions and restorations remove the product from c.
+// Reads that include deleted products skip c.
+func NewCachedRepository(repo Repository, c cache.Cache[int, domain.Product]) Repository {
+	return &cachedRepository{repo, c}
+}
+
+func (r *cachedRepository) Get(ctx context.Context, id int) (domain.Product, error) {
+	if sqlutil.IncludeDeleted(ctx) {
+		return r.Repository.Get(ctx, id)
+	}
+	return cache.GetOrLoad(ctx, r.products, id, func() (domain.Product, error) {
+		return r.Repository.Get(ctx, id)
+	})
+}
+
+func (r *cachedRepository) Update(ctx context.Context, p domain.Product) error {
+	defer r.products.Delete(ctx, p.ID)
+	return r.Repository.Update(ctx, p)
+}
+
+func (r *cachedRepository) Delete(ctx context.Context, id int) error {
+	defer r.products.Delete(ctx, id)
+	return r.Repository.Delete(ctx, id)
+}
+
+func (r *cachedRepository) Restore(ctx context.Context, id int) error {
+	defer r.products.Delete(ctx, id)
+	return r.Repository.Restore(ctx, id)
+}
@@ -0,0 +1,60 @@
+package product_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/product"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/cache"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCachedRepository(t *testing.T) {
+	p := domain.Product{ID: 1, ProductCode: "SWP-1", Version: 1}
+
+	t.Run("Reads products through the cache", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		c := cache.NewLRU[int, domain.Product](10, time.Minute)
+		repo := product.NewCachedRepository(&mockRepo, c)
+
+		mockRepo.On("Get", mock.Anything, 1).Return(p, nil).Once()
+
+		repo.Get(context.TODO(), 1)
+		received, err := repo.Get(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, p, received)
+		assert.Equal(t, cache.Stats{Hits: 1, Misses: 1, Size: 1}, c.Stats())
+		mockRepo.AssertExpectations(t)
+	})
+	t.Run("Removes updated products from the cache", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		c := cache.NewLRU[int, domain.Product](10, time.Minute)
+		repo := product.NewCachedRepository(&mockRepo, c)
+
+		mockRepo.On("Get", mock.Anything, 1).Return(p, nil).Twice()
+		mockRepo.On("Update", mock.Anything, p).Return(sqlutil.ErrVersionMismatch)
+
+		repo.Get(context.TODO(), 1)
+		repo.Update(context.TODO(), p)
+		repo.Get(context.TODO(), 1)
+
+		mockRepo.AssertExpectations(t)
+	})
+	t.Run("Does not cache reads of deleted products", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		c := cache.NewLRU[int, domain.Product](10, time.Minute)
+		repo := product.NewCachedRepository(&mockRepo, c)
+
+		ctx := sqlutil.WithDeleted(context.TODO())
+		mockRepo.On("Get", ctx, 1).Return(p, nil)
+
+		repo.Get(ctx, 1)
+
+		assert.Equal(t, 0, c.Stats().Size)
+	})
+}
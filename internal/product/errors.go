@@ -37,3 +37,51 @@ func NewErrNotFound(id int) *ErrNotFound {
 func (e ErrNotFound) Error() string {
 	return fmt.Sprintf("product with ID %d not found", e.ID)
 }
+
+type ErrInvalidProductType struct {
+	TypeID int
+}
+
+func NewErrInvalidProductType(typeID int) *ErrInvalidProductType {
+	return &ErrInvalidProductType{typeID}
+}
+
+func (e ErrInvalidProductType) Error() string {
+	return fmt.Sprintf("invalid product type: product type with ID %d not found", e.TypeID)
+}
+
+type ErrInvalidRecord struct {
+	Reason string
+}
+
+func NewErrInvalidRecord(reason string) *ErrInvalidRecord {
+	return &ErrInvalidRecord{reason}
+}
+
+func (e ErrInvalidRecord) Error() string {
+	return fmt.Sprintf("invalid product record: %s", e.Reason)
+}
+
+type ErrNoPrice struct {
+	ProductID int
+}
+
+func NewErrNoPrice(productID int) *ErrNoPrice {
+	return &ErrNoPrice{productID}
+}
+
+func (e ErrNoPrice) Error() string {
+	return fmt.Sprintf("product with ID %d has no price at the given date", e.ProductID)
+}
+
+type ErrInvalidSearch struct {
+	Reason string
+}
+
+func NewErrInvalidSearch(reason string) *ErrInvalidSearch {
+	return &ErrInvalidSearch{reason}
+}
+
+func (e ErrInvalidSearch) Error() string {
+	return fmt.Sprintf("invalid search: %s", e.Reason)
+}
@@ -0,0 +1,89 @@
+package product
+
+import (
+	"sort"
+	"time"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+)
+
+// EffectiveRecord returns the record that sets the price in effect at
+// the given time: the latest one dated at or before it. It returns
+// false if every record is later than at.
+func EffectiveRecord(records []domain.Product_Records, at time.Time) (domain.Product_Records, bool) {
+	var effective domain.Product_Records
+	found := false
+	for _, r := range records {
+		if r.LastUpdateDate.After(at) {
+			continue
+		}
+		if !found || !r.LastUpdateDate.Before(effective.LastUpdateDate) {
+			effective = r
+			found = true
+		}
+	}
+	return effective, found
+}
+
+// MarginPct returns the margin of a sale as a percentage of its price.
+func MarginPct(purchase, sale float64) float64 {
+	if sale == 0 {
+		return 0
+	}
+	return (sale - purchase) / sale * 100
+}
+
+func pricePoint(r domain.Product_Records) domain.PricePoint {
+	return domain.PricePoint{
+		RecordID:      r.ID,
+		Date:          r.LastUpdateDate,
+		PurchasePrice: r.PurchasePrice,
+		SalePrice:     r.SalePrice,
+		MarginPct:     MarginPct(r.PurchasePrice, r.SalePrice),
+	}
+}
+
+// priceHistory builds the timeline of the records between from and to.
+// Deltas of the first entry are relative to the price in effect before
+// the range, if there was one.
+func priceHistory(id int, records []domain.Product_Records, from, to optional.Opt[time.Time]) domain.PriceHistory {
+	sorted := make([]domain.Product_Records, len(records))
+	copy(sorted, records)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].LastUpdateDate.Before(sorted[j].LastUpdateDate)
+	})
+
+	history := domain.PriceHistory{ProductID: id, Timeline: []domain.PriceChange{}}
+	var prev, start *domain.Product_Records
+	for i := range sorted {
+		r := &sorted[i]
+		if from.HasVal && r.LastUpdateDate.Before(from.Val) {
+			prev = r
+			continue
+		}
+		if to.HasVal && r.LastUpdateDate.After(to.Val) {
+			break
+		}
+
+		change := domain.PriceChange{PricePoint: pricePoint(*r)}
+		if prev != nil {
+			change.PurchaseDelta = r.PurchasePrice - prev.PurchasePrice
+			change.SaleDelta = r.SalePrice - prev.SalePrice
+		}
+		if start == nil {
+			start = r
+			if prev != nil {
+				start = prev
+			}
+		}
+		history.Timeline = append(history.Timeline, change)
+		prev = r
+	}
+
+	if start != nil {
+		history.PurchaseDelta = prev.PurchasePrice - start.PurchasePrice
+		history.SaleDelta = prev.SalePrice - start.SalePrice
+	}
+	return history
+}
@@ -4,22 +4,48 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
+	"strings"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 )
 
 // Repository encapsulates the storage of a Product.
 type Repository interface {
 	GetAll(ctx context.Context) ([]domain.Product, error)
 	Get(ctx context.Context, id int) (domain.Product, error)
+	// GetByIDs returns the products with the given ids, in a single
+	// query. Ids of missing products are skipped.
+	GetByIDs(ctx context.Context, ids []int) ([]domain.Product, error)
 	Exists(ctx context.Context, productCode string) bool
+	// ExistingCodes returns which of the given product codes are taken, in a
+	// single query.
+	ExistingCodes(ctx context.Context, codes []string) ([]string, error)
 	Save(ctx context.Context, p domain.Product) (int, error)
+	// SaveAll saves products in a single insert and returns their ids.
+	// If one of them fails, none is saved.
+	SaveAll(ctx context.Context, ps []domain.Product) ([]int, error)
+	// Update saves p unless the product is no longer in p.Version,
+	// in which case it fails with sqlutil.ErrVersionMismatch.
 	Update(ctx context.Context, p domain.Product) error
+	// Delete soft deletes a product,
+	// if it is in one of the versions expected by ctx.
 	Delete(ctx context.Context, id int) error
+	// Restore undoes the deletion of a product.
+	Restore(ctx context.Context, id int) error
 	SaveRecord(ctx context.Context, p domain.Product_Records) (int, error)
 	GetAllRecords(ctx context.Context) ([]domain.Product_Records, error)
 	GetRecordsbyProd(ctx context.Context, id int) ([]domain.Product_Records, error)
+	GetRecord(ctx context.Context, id int) (domain.Product_Records, error)
+	// StreamRecords calls fn for each product record, as it is read
+	// from the database. If id is not zero, only the records of that
+	// product are read.
+	StreamRecords(ctx context.Context, id int, fn func(domain.Product_Records) error) error
+	// Search returns the products matching f, most relevant first,
+	// skipping offset of them and returning at most limit. It also
+	// returns how many products match in total.
+	Search(ctx context.Context, f SearchFilter, limit, offset int) ([]domain.Product, int, error)
 }
 
 type repository struct {
@@ -33,7 +59,9 @@ func NewRepository(db *sql.DB) Repository {
 }
 
 func (r *repository) GetAll(ctx context.Context) ([]domain.Product, error) {
-	query := "SELECT * FROM products;"
+	query := `SELECT id,description,expiration_rate,freezing_rate,
+		height,length,net_weight,product_code,recommended_freezing_temperature,
+		width,product_type_id,seller_id,version,deleted_at FROM products WHERE ` + sqlutil.NotDeleted(ctx, "deleted_at") + ";"
 	rows, err := r.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -43,7 +71,7 @@ func (r *repository) GetAll(ctx context.Context) ([]domain.Product, error) {
 
 	for rows.Next() {
 		p := domain.Product{}
-		_ = rows.Scan(&p.ID, &p.Description, &p.ExpirationRate, &p.FreezingRate, &p.Height, &p.Length, &p.Netweight, &p.ProductCode, &p.RecomFreezTemp, &p.Width, &p.ProductTypeID, &p.SellerID)
+		_ = rows.Scan(&p.ID, &p.Description, &p.ExpirationRate, &p.FreezingRate, &p.Height, &p.Length, &p.Netweight, &p.ProductCode, &p.RecomFreezTemp, &p.Width, &p.ProductTypeID, &p.SellerID, &p.Version, sqlutil.NullTime(&p.DeletedAt))
 		products = append(products, p)
 	}
 
@@ -53,10 +81,10 @@ func (r *repository) GetAll(ctx context.Context) ([]domain.Product, error) {
 func (r *repository) Get(ctx context.Context, id int) (domain.Product, error) {
 	query := `SELECT id,description,expiration_rate,freezing_rate,
 		height,length,net_weight,product_code,recommended_freezing_temperature,
-		width,product_type_id,seller_id FROM products WHERE id=?;`
+		width,product_type_id,seller_id,version,deleted_at FROM products WHERE id=? AND ` + sqlutil.NotDeleted(ctx, "deleted_at") + ";"
 	row := r.db.QueryRow(query, id)
 	p := domain.Product{}
-	err := row.Scan(&p.ID, &p.Description, &p.ExpirationRate, &p.FreezingRate, &p.Height, &p.Length, &p.Netweight, &p.ProductCode, &p.RecomFreezTemp, &p.Width, &p.ProductTypeID, &p.SellerID)
+	err := row.Scan(&p.ID, &p.Description, &p.ExpirationRate, &p.FreezingRate, &p.Height, &p.Length, &p.Netweight, &p.ProductCode, &p.RecomFreezTemp, &p.Width, &p.ProductTypeID, &p.SellerID, &p.Version, sqlutil.NullTime(&p.DeletedAt))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.Product{}, NewErrNotFound(id)
@@ -67,6 +95,32 @@ func (r *repository) Get(ctx context.Context, id int) (domain.Product, error) {
 	return p, nil
 }
 
+func (r *repository) GetByIDs(ctx context.Context, ids []int) ([]domain.Product, error) {
+	if len(ids) == 0 {
+		return []domain.Product{}, nil
+	}
+	in, args := sqlutil.In(ids)
+	query := `SELECT id,description,expiration_rate,freezing_rate,
+		height,length,net_weight,product_code,recommended_freezing_temperature,
+		width,product_type_id,seller_id,version,deleted_at FROM products WHERE id IN ` + in + ` AND ` + sqlutil.NotDeleted(ctx, "deleted_at") + ";"
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []domain.Product{}
+	for rows.Next() {
+		p := domain.Product{}
+		if err := rows.Scan(&p.ID, &p.Description, &p.ExpirationRate, &p.FreezingRate, &p.Height, &p.Length, &p.Netweight, &p.ProductCode, &p.RecomFreezTemp, &p.Width, &p.ProductTypeID, &p.SellerID, &p.Version, sqlutil.NullTime(&p.DeletedAt)); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+
+	return products, rows.Err()
+}
+
 func (r *repository) Exists(ctx context.Context, productCode string) bool {
 	query := "SELECT product_code FROM products WHERE product_code=?;"
 	row := r.db.QueryRow(query, productCode)
@@ -74,6 +128,10 @@ func (r *repository) Exists(ctx context.Context, productCode string) bool {
 	return err == nil
 }
 
+func (r *repository) ExistingCodes(ctx context.Context, codes []string) ([]string, error) {
+	return sqlutil.ExistingKeys(ctx, r.db, "products", "product_code", codes)
+}
+
 func (r *repository) Save(ctx context.Context, p domain.Product) (int, error) {
 	query := `INSERT INTO products(description,expiration_rate,freezing_rate,
 		height,length,net_weight,product_code,recommended_freezing_temperature,
@@ -98,18 +156,51 @@ func (r *repository) Save(ctx context.Context, p domain.Product) (int, error) {
 	return int(id), nil
 }
 
+func (r *repository) SaveAll(ctx context.Context, ps []domain.Product) ([]int, error) {
+	query := `INSERT INTO products(description,expiration_rate,freezing_rate,
+		height,length,net_weight,product_code,recommended_freezing_temperature,
+		width,product_type_id,seller_id)
+		VALUES ` + sqlutil.Rows(len(ps), 11)
+
+	args := make([]any, 0, len(ps)*11)
+	for _, p := range ps {
+		args = append(args, p.Description, p.ExpirationRate, p.FreezingRate, p.Height, p.Length, p.Netweight, p.ProductCode, p.RecomFreezTemp, p.Width, p.ProductTypeID, p.SellerID)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, len(ps))
+	for i, p := range ps {
+		keys[i] = p.ProductCode
+	}
+	ids, err := sqlutil.IDsByKey(ctx, tx, "products", "product_code", keys)
+	if err != nil {
+		return nil, err
+	}
+	return ids, tx.Commit()
+}
+
 func (r *repository) Update(ctx context.Context, p domain.Product) error {
 	query := `UPDATE products SET 
 		description=?, expiration_rate=?, freezing_rate=?, height=?,
 		length=?, net_weight=?, product_code=?, 
 		recommended_freezing_temperature=?, width=?,
-		product_type_id=?, seller_id=? WHERE id=?`
+		product_type_id=?, seller_id=?, version=version+1
+		WHERE id=? AND version=? AND deleted_at IS NULL`
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return err
 	}
 
-	res, err := stmt.Exec(p.Description, p.ExpirationRate, p.FreezingRate, p.Height, p.Length, p.Netweight, p.ProductCode, p.RecomFreezTemp, p.Width, p.ProductTypeID, p.SellerID, p.ID)
+	res, err := stmt.Exec(p.Description, p.ExpirationRate, p.FreezingRate, p.Height, p.Length, p.Netweight, p.ProductCode, p.RecomFreezTemp, p.Width, p.ProductTypeID, p.SellerID, p.ID, p.Version)
 	if err != nil {
 		return err
 	}
@@ -119,20 +210,20 @@ func (r *repository) Update(ctx context.Context, p domain.Product) error {
 		return err
 	}
 	if affected < 1 {
-		return NewErrNotFound(p.ID)
+		return sqlutil.StaleOrMissing(ctx, r.db, "products", p.ID, NewErrNotFound(p.ID))
 	}
 
 	return nil
 }
 
 func (r *repository) Delete(ctx context.Context, id int) error {
-	query := "DELETE FROM products WHERE id=?"
+	query := "UPDATE products SET deleted_at=?, version=version+1 WHERE id=? AND deleted_at IS NULL AND " + sqlutil.VersionMatches(ctx, "version")
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return err
 	}
 
-	res, err := stmt.Exec(id)
+	res, err := stmt.Exec(time.Now().UTC(), id)
 	if err != nil {
 		return err
 	}
@@ -142,6 +233,23 @@ func (r *repository) Delete(ctx context.Context, id int) error {
 		return err
 	}
 
+	if affected < 1 {
+		return sqlutil.StaleOrMissing(ctx, r.db, "products", id, NewErrNotFound(id))
+	}
+
+	return nil
+}
+
+func (r *repository) Restore(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE products SET deleted_at=NULL, version=version+1 WHERE id=? AND deleted_at IS NOT NULL;", id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
 	if affected < 1 {
 		return NewErrNotFound(id)
 	}
@@ -170,17 +278,21 @@ func (r *repository) SaveRecord(ctx context.Context, p domain.Product_Records) (
 }
 
 func (r *repository) GetAllRecords(ctx context.Context) ([]domain.Product_Records, error) {
-	query := "SELECT * FROM product_records;"
+	query := "SELECT id, last_update_date, purchase_price, sale_price, product_id FROM product_records;"
 	rows, err := r.db.Query(query)
 	if err != nil {
 		return nil, err
 	}
 
+	defer rows.Close()
+
 	var products []domain.Product_Records
 
 	for rows.Next() {
 		p := domain.Product_Records{}
-		_ = rows.Scan(&p.ID, &p.LastUpdateDate, &p.PurchasePrice, &p.SalePrice, &p.ProductID)
+		if err := rows.Scan(&p.ID, sqlutil.Time(&p.LastUpdateDate), &p.PurchasePrice, &p.SalePrice, &p.ProductID); err != nil {
+			return nil, err
+		}
 		products = append(products, p)
 	}
 
@@ -190,17 +302,186 @@ func (r *repository) GetAllRecords(ctx context.Context) ([]domain.Product_Record
 func (r *repository) GetRecordsbyProd(ctx context.Context, id int) ([]domain.Product_Records, error) {
 	query := "select r.id, r.last_update_date, r.purchase_price, r.sale_price, r.product_id from product_records as r INNER JOIN products as p ON p.id = r.product_id where p.id = ?;"
 	rows, err := r.db.Query(query, id)
-	fmt.Println(rows)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	var products []domain.Product_Records
 	for rows.Next() {
 		p := domain.Product_Records{}
-		_ = rows.Scan(&p.ID, &p.LastUpdateDate, &p.PurchasePrice, &p.SalePrice, &p.ProductID)
+		if err := rows.Scan(&p.ID, sqlutil.Time(&p.LastUpdateDate), &p.PurchasePrice, &p.SalePrice, &p.ProductID); err != nil {
+			return nil, err
+		}
 		products = append(products, p)
 	}
 
 	return products, nil
 }
+
+func (r *repository) GetRecord(ctx context.Context, id int) (domain.Product_Records, error) {
+	query := "SELECT id, last_update_date, purchase_price, sale_price, product_id FROM product_records WHERE id = ?;"
+	row := r.db.QueryRowContext(ctx, query, id)
+
+	p := domain.Product_Records{}
+	err := row.Scan(&p.ID, sqlutil.Time(&p.LastUpdateDate), &p.PurchasePrice, &p.SalePrice, &p.ProductID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Product_Records{}, NewErrNotFound(id)
+	}
+	if err != nil {
+		return domain.Product_Records{}, err
+	}
+	return p, nil
+}
+
+func (r *repository) StreamRecords(ctx context.Context, id int, fn func(domain.Product_Records) error) error {
+	query := "SELECT id, last_update_date, purchase_price, sale_price, product_id FROM product_records"
+	args := []any{}
+	if id != 0 {
+		query += " WHERE product_id = ?"
+		args = append(args, id)
+	}
+	rows, err := r.db.QueryContext(ctx, query+";", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p := domain.Product_Records{}
+		if err := rows.Scan(&p.ID, sqlutil.Time(&p.LastUpdateDate), &p.PurchasePrice, &p.SalePrice, &p.ProductID); err != nil {
+			return err
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// textMatch is the condition a product must meet to match the search
+// text, and the score used to sort the matches by relevance.
+type textMatch struct {
+	cond      string
+	condArgs  []any
+	score     string
+	scoreArgs []any
+}
+
+// fulltextMatch matches the text against the FULLTEXT index on the
+// description and product code. Products whose code starts with the
+// text always match, as FULLTEXT ignores short and partial words, and
+// an exact code ranks first.
+func fulltextMatch(text string) textMatch {
+	const against = "MATCH(description, product_code) AGAINST (? IN NATURAL LANGUAGE MODE)"
+	prefix := escapeLike(text) + "%"
+	return textMatch{
+		cond:      "(" + against + " OR product_code LIKE ?)",
+		condArgs:  []any{text, prefix},
+		score:     "CASE WHEN product_code = ? THEN 1000 ELSE 0 END + " + against,
+		scoreArgs: []any{text, text},
+	}
+}
+
+// likeMatch is the fallback for backends without FULLTEXT search. It
+// ranks an exact code first, then codes starting with the text, then
+// any other product containing it.
+func likeMatch(text string) textMatch {
+	contains := "%" + escapeLike(text) + "%"
+	return textMatch{
+		cond:      "(description LIKE ? OR product_code LIKE ?)",
+		condArgs:  []any{contains, contains},
+		score:     "CASE WHEN product_code = ? THEN 3 WHEN product_code LIKE ? THEN 2 ELSE 1 END",
+		scoreArgs: []any{text, escapeLike(text) + "%"},
+	}
+}
+
+func (r *repository) Search(ctx context.Context, f SearchFilter, limit, offset int) ([]domain.Product, int, error) {
+	if f.Text == "" {
+		return r.search(ctx, f, textMatch{score: "0"}, limit, offset)
+	}
+	products, total, err := r.search(ctx, f, fulltextMatch(f.Text), limit, offset)
+	if err != nil && fulltextUnsupported(err) {
+		return r.search(ctx, f, likeMatch(f.Text), limit, offset)
+	}
+	return products, total, err
+}
+
+func (r *repository) search(ctx context.Context, f SearchFilter, m textMatch, limit, offset int) ([]domain.Product, int, error) {
+	conds := []string{"deleted_at IS NULL"}
+	args := []any{}
+	if m.cond != "" {
+		conds = append(conds, m.cond)
+		args = append(args, m.condArgs...)
+	}
+	if typeID, ok := f.TypeID.Value(); ok {
+		conds = append(conds, "product_type_id = ?")
+		args = append(args, typeID)
+	}
+	if sellerID, ok := f.SellerID.Value(); ok {
+		conds = append(conds, "seller_id = ?")
+		args = append(args, sellerID)
+	}
+	if weight, ok := f.MinWeight.Value(); ok {
+		conds = append(conds, "net_weight >= ?")
+		args = append(args, weight)
+	}
+	if temp, ok := f.MaxFreezingTemp.Value(); ok {
+		conds = append(conds, "recommended_freezing_temperature <= ?")
+		args = append(args, temp)
+	}
+	where := " WHERE " + strings.Join(conds, " AND ")
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM products"+where+";", args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT id,description,expiration_rate,freezing_rate,
+		height,length,net_weight,product_code,recommended_freezing_temperature,
+		width,product_type_id,seller_id,` + m.score + ` AS relevance
+		FROM products` + where + " ORDER BY relevance DESC, id LIMIT ? OFFSET ?;"
+	queryArgs := append(append(append([]any{}, m.scoreArgs...), args...), limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	products := []domain.Product{}
+	for rows.Next() {
+		p := domain.Product{}
+		var relevance float64
+		if err := rows.Scan(&p.ID, &p.Description, &p.ExpirationRate, &p.FreezingRate, &p.Height, &p.Length, &p.Netweight, &p.ProductCode, &p.RecomFreezTemp, &p.Width, &p.ProductTypeID, &p.SellerID, &relevance); err != nil {
+			return nil, 0, err
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return products, total, nil
+}
+
+// fulltextUnsupported reports whether err means that the database
+// cannot run a FULLTEXT search: a MySQL table without the index (1191)
+// or on an engine without FULLTEXT support (1214), or another backend
+// rejecting the MATCH syntax.
+func fulltextUnsupported(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	msg := err.Error()
+	if !strings.HasPrefix(msg, "Error ") {
+		return true
+	}
+	return strings.HasPrefix(msg, "Error 1191") || strings.HasPrefix(msg, "Error 1214")
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike escapes the LIKE wildcards in s, so that it matches
+// literally.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -3,9 +3,11 @@ package product_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	product "github.com/extmatperez/meli_bootcamp_go_w2-4/internal/product"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
 
 	"github.com/stretchr/testify/assert"
@@ -100,6 +102,7 @@ func TestRepoUpdate(t *testing.T) {
 		id, _ := repo.Save(context.TODO(), p)
 
 		p.ID = id
+		p.Version = 1
 		p.ProductCode = "NEW CODE"
 
 		err := repo.Update(context.TODO(), p)
@@ -136,7 +139,7 @@ func TestRecordRepoCreate(t *testing.T) {
 		repo := product.NewRepository(db)
 
 		record := domain.Product_Records{
-			LastUpdateDate: "2022-01-03",
+			LastUpdateDate: time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC),
 			PurchasePrice:  20.20,
 			SalePrice:      30.30,
 			ProductID:      2,
@@ -157,7 +160,7 @@ func TestRecordRepoCreate(t *testing.T) {
 		repo := product.NewRepository(db)
 
 		record := domain.Product_Records{
-			LastUpdateDate: "2022-01-03",
+			LastUpdateDate: time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC),
 			SalePrice:      30.30,
 			ProductID:      20000,
 		}
@@ -174,7 +177,7 @@ func TestRecordRepoGet(t *testing.T) {
 		repo := product.NewRepository(db)
 
 		record := domain.Product_Records{
-			LastUpdateDate: "2022-01-03",
+			LastUpdateDate: time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC),
 			PurchasePrice:  20.20,
 			SalePrice:      30.30,
 			ProductID:      2,
@@ -185,6 +188,36 @@ func TestRecordRepoGet(t *testing.T) {
 		received, _ := repo.GetAllRecords(context.TODO())
 		assert.True(t, len(received) > 0)
 	})
+	t.Run("Gets product record by ID", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := product.NewRepository(db)
+
+		record := domain.Product_Records{
+			LastUpdateDate: time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC),
+			PurchasePrice:  20.20,
+			SalePrice:      30.30,
+			ProductID:      2,
+		}
+
+		id, _ := repo.SaveRecord(context.TODO(), record)
+		record.ID = id
+
+		received, err := repo.GetRecord(context.TODO(), id)
+		assert.NoError(t, err)
+		assert.Equal(t, record, received)
+	})
+	t.Run("Returns not found on unknown record ID", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := product.NewRepository(db)
+
+		var expectedErr *product.ErrNotFound
+		_, err := repo.GetRecord(context.TODO(), 9999)
+		assert.ErrorAs(t, err, &expectedErr)
+	})
 }
 
 func TestRecordReport(t *testing.T) {
@@ -197,7 +230,7 @@ func TestRecordReport(t *testing.T) {
 		p := getTestProduct()
 		id, _ := repo.Save(context.TODO(), p)
 		record := domain.Product_Records{
-			LastUpdateDate: "2022-01-03",
+			LastUpdateDate: time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC),
 			PurchasePrice:  20.20,
 			SalePrice:      30.30,
 			ProductID:      id,
@@ -210,6 +243,41 @@ func TestRecordReport(t *testing.T) {
 	})
 }
 
+func TestRepoSearch(t *testing.T) {
+	db := testutil.InitDatabase(t)
+	defer db.Close()
+
+	repo := product.NewRepository(db)
+	codes := []string{"SRCH-10", "SRCH-1", "SRCH-2", "OTHER-1"}
+	ids := map[string]int{}
+	for i, code := range codes {
+		p := getTestProduct()
+		p.ProductCode = code
+		p.Netweight = float32(i + 1)
+		id, err := repo.Save(context.TODO(), p)
+		assert.NoError(t, err)
+		ids[code] = id
+	}
+
+	t.Run("Ranks an exact code first", func(t *testing.T) {
+		products, total, err := repo.Search(context.TODO(), product.SearchFilter{Text: "SRCH-1"}, 10, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, total)
+		assert.Equal(t, ids["SRCH-1"], products[0].ID)
+		assert.Equal(t, ids["SRCH-10"], products[1].ID)
+	})
+	t.Run("Filters and paginates", func(t *testing.T) {
+		f := product.SearchFilter{Text: "SRCH", MinWeight: *optional.FromVal[float32](2)}
+		products, total, err := repo.Search(context.TODO(), f, 1, 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, total)
+		assert.Len(t, products, 1)
+		assert.Equal(t, ids["SRCH-2"], products[0].ID)
+	})
+}
+
 func getTestProduct() domain.Product {
 	return domain.Product{
 		Description:    "abc",
@@ -0,0 +1,47 @@
+package product
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// SearchFilter selects the products returned by a search. Text is
+// matched against the description and the product code, the other
+// fields filter on the product attributes.
+type SearchFilter struct {
+	Text            string
+	TypeID          optional.Opt[int]
+	SellerID        optional.Opt[int]
+	MinWeight       optional.Opt[float32]
+	MaxFreezingTemp optional.Opt[float32]
+}
+
+func (s *service) Search(c context.Context, f SearchFilter, page, pageSize int) (domain.ProductPage, error) {
+	if page < 1 {
+		return domain.ProductPage{}, NewErrInvalidSearch("page must be greater than zero")
+	}
+	if pageSize < 1 || pageSize > MaxPageSize {
+		return domain.ProductPage{}, NewErrInvalidSearch(fmt.Sprintf("page_size must be between 1 and %d", MaxPageSize))
+	}
+	f.Text = strings.TrimSpace(f.Text)
+
+	products, total, err := s.repo.Search(c, f, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return domain.ProductPage{}, NewErrGeneric("could not search products")
+	}
+	return domain.ProductPage{
+		Products: products,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+	}, nil
+}
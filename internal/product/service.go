@@ -2,10 +2,14 @@ package product
 
 import (
 	"context"
+	"errors"
 	"strconv"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	producttype "github.com/extmatperez/meli_bootcamp_go_w2-4/internal/product_type"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 )
 
 type CreateDTO struct {
@@ -23,7 +27,7 @@ type CreateDTO struct {
 }
 
 type CreateRecordDTO struct {
-	LastDate      string
+	LastDate      time.Time
 	PurchasePrice float64
 	SalePrice     float64
 	ProductID     int
@@ -45,27 +49,64 @@ type UpdateDTO struct {
 
 type Service interface {
 	Create(c context.Context, product CreateDTO) (domain.Product, error)
+	// CreateBatch creates products, saving all the valid ones at once.
+	// The result and error of each product are at its index.
+	CreateBatch(c context.Context, products []CreateDTO) ([]domain.Product, []error)
 	GetAll(c context.Context) ([]domain.Product, error)
 	Get(c context.Context, id int) (domain.Product, error)
+	// GetByIDs returns the products with the given ids, skipping those
+	// that don't exist.
+	GetByIDs(c context.Context, ids []int) ([]domain.Product, error)
 	Update(c context.Context, id int, updates UpdateDTO) (domain.Product, error)
+	// UpdateBatch updates the products with the given ids like Update
+	// does. The result and error of each product are at its index.
+	UpdateBatch(c context.Context, ids []int, updates []UpdateDTO) ([]domain.Product, []error)
 	Delete(c context.Context, id int) error
+	// Restore undoes the deletion of a product.
+	Restore(c context.Context, id int) (domain.Product, error)
 	CreateRecord(c context.Context, product CreateRecordDTO) (domain.Product_Records, error)
 	GetAllRecords(c context.Context) ([]domain.Product_Records, error)
 	GetRecords(c context.Context, id int) ([]domain.Product_Records, error)
+	// StreamRecords calls fn for each product record without loading
+	// them all in memory. If id is not zero, only the records of that
+	// product are streamed.
+	StreamRecords(c context.Context, id int, fn func(domain.Product_Records) error) error
+	// PriceHistory returns the price timeline of a product between
+	// from and to, both inclusive. Either bound may be omitted.
+	PriceHistory(c context.Context, id int, from, to optional.Opt[time.Time]) (domain.PriceHistory, error)
+	// EffectivePrice returns the price of a product in effect at the
+	// given time, i.e. the one set by its latest record up to then.
+	EffectivePrice(c context.Context, id int, at time.Time) (domain.PricePoint, error)
+	// Search returns the given page of the products matching f, most
+	// relevant first. Pages are numbered from 1.
+	Search(c context.Context, f SearchFilter, page, pageSize int) (domain.ProductPage, error)
+}
+
+// ProductTypes is the part of the product type repository
+// used to check the classification of a product.
+type ProductTypes interface {
+	Get(ctx context.Context, id int) (domain.ProductType, error)
 }
 
 type service struct {
-	repo Repository
+	repo  Repository
+	types ProductTypes
 }
 
-func NewService(repo Repository) Service {
-	return &service{repo}
+func NewService(repo Repository, types ProductTypes) Service {
+	return &service{repo, types}
 }
 
 func (s *service) Create(c context.Context, product CreateDTO) (domain.Product, error) {
 	if s.repo.Exists(c, product.Code) {
 		return domain.Product{}, NewErrInvalidProductCode(product.Code)
 	}
+	if _, err := s.types.Get(c, product.TypeID); err != nil {
+		if errors.Is(err, producttype.ErrNotFound) {
+			return domain.Product{}, NewErrInvalidProductType(product.TypeID)
+		}
+		return domain.Product{}, NewErrGeneric("error checking product type")
+	}
 
 	p := MapCreateToDomain(&product)
 	id, err := s.repo.Save(c, *p)
@@ -77,12 +118,83 @@ func (s *service) Create(c context.Context, product CreateDTO) (domain.Product,
 	return *p, nil
 }
 
+func (s *service) CreateBatch(c context.Context, products []CreateDTO) ([]domain.Product, []error) {
+	created := make([]domain.Product, len(products))
+	errs := make([]error, len(products))
+	var valid []domain.Product
+	var indexes []int
+	keys := make([]string, len(products))
+	for i, product := range products {
+		keys[i] = product.Code
+	}
+	taken, err := s.repo.ExistingCodes(c, keys)
+	if err != nil {
+		for i := range errs {
+			errs[i] = NewErrGeneric("error checking product codes")
+		}
+		return created, errs
+	}
+	codes := map[string]bool{}
+	for _, code := range taken {
+		codes[code] = true
+	}
+	types := map[int]error{}
+	for i, product := range products {
+		if codes[product.Code] {
+			errs[i] = NewErrInvalidProductCode(product.Code)
+			continue
+		}
+		typeErr, checked := types[product.TypeID]
+		if !checked {
+			if _, err := s.types.Get(c, product.TypeID); err != nil {
+				typeErr = NewErrGeneric("error checking product type")
+				if errors.Is(err, producttype.ErrNotFound) {
+					typeErr = NewErrInvalidProductType(product.TypeID)
+				}
+			}
+			types[product.TypeID] = typeErr
+		}
+		if typeErr != nil {
+			errs[i] = typeErr
+			continue
+		}
+		codes[product.Code] = true
+		created[i] = *MapCreateToDomain(&product)
+		valid = append(valid, created[i])
+		indexes = append(indexes, i)
+	}
+	if len(valid) == 0 {
+		return created, errs
+	}
+
+	ids, err := s.repo.SaveAll(c, valid)
+	if err != nil {
+		// One product failed the whole insert, so they are saved one by
+		// one to tell which.
+		ids = make([]int, len(valid))
+		for j, p := range valid {
+			ids[j], errs[indexes[j]] = s.repo.Save(c, p)
+		}
+	}
+	for j, i := range indexes {
+		if errs[i] != nil {
+			errs[i] = NewErrGeneric("error saving product")
+			continue
+		}
+		created[i].ID = ids[j]
+	}
+	return created, errs
+}
+
 func (s *service) CreateRecord(c context.Context, product CreateRecordDTO) (domain.Product_Records, error) {
 	idProd := product.ProductID
 	_, err := s.repo.Get(c, idProd)
 	if err != nil {
 		return domain.Product_Records{}, NewErrInvalidProductCode(strconv.Itoa(idProd))
 	}
+	if err := validateRecord(product); err != nil {
+		return domain.Product_Records{}, err
+	}
 	p := MapCreateRecord(&product)
 	id, err := s.repo.SaveRecord(c, *p)
 	if err != nil {
@@ -118,6 +230,50 @@ func (s *service) GetRecords(c context.Context, id int) ([]domain.Product_Record
 	return p, nil
 }
 
+func (s *service) StreamRecords(c context.Context, id int, fn func(domain.Product_Records) error) error {
+	if id != 0 {
+		if _, err := s.repo.Get(c, id); err != nil {
+			return NewErrNotFound(id)
+		}
+	}
+	if err := s.repo.StreamRecords(c, id, fn); err != nil {
+		return NewErrGeneric("could not fetch product records")
+	}
+	return nil
+}
+
+func (s *service) PriceHistory(c context.Context, id int, from, to optional.Opt[time.Time]) (domain.PriceHistory, error) {
+	records, err := s.productRecords(c, id)
+	if err != nil {
+		return domain.PriceHistory{}, err
+	}
+	return priceHistory(id, records, from, to), nil
+}
+
+func (s *service) EffectivePrice(c context.Context, id int, at time.Time) (domain.PricePoint, error) {
+	records, err := s.productRecords(c, id)
+	if err != nil {
+		return domain.PricePoint{}, err
+	}
+	r, ok := EffectiveRecord(records, at)
+	if !ok {
+		return domain.PricePoint{}, NewErrNoPrice(id)
+	}
+	return pricePoint(r), nil
+}
+
+// productRecords returns the records of an existing product.
+func (s *service) productRecords(c context.Context, id int) ([]domain.Product_Records, error) {
+	if _, err := s.repo.Get(c, id); err != nil {
+		return nil, NewErrNotFound(id)
+	}
+	records, err := s.repo.GetRecordsbyProd(c, id)
+	if err != nil {
+		return nil, NewErrGeneric("could not fetch product records")
+	}
+	return records, nil
+}
+
 func (s *service) Get(c context.Context, id int) (domain.Product, error) {
 	p, err := s.repo.Get(c, id)
 	if err != nil {
@@ -127,11 +283,55 @@ func (s *service) Get(c context.Context, id int) (domain.Product, error) {
 	return p, nil
 }
 
+func (s *service) GetByIDs(c context.Context, ids []int) ([]domain.Product, error) {
+	ps, err := s.repo.GetByIDs(c, ids)
+	if err != nil {
+		return nil, NewErrGeneric("could not fetch products")
+	}
+	return ps, nil
+}
+
 func (s *service) Update(c context.Context, id int, updates UpdateDTO) (domain.Product, error) {
 	p, err := s.repo.Get(c, id)
 	if err != nil {
 		return domain.Product{}, NewErrNotFound(id)
 	}
+	return s.update(c, p, updates)
+}
+
+func (s *service) UpdateBatch(c context.Context, ids []int, updates []UpdateDTO) ([]domain.Product, []error) {
+	updated := make([]domain.Product, len(ids))
+	errs := make([]error, len(ids))
+	current, err := s.repo.GetByIDs(c, ids)
+	if err != nil {
+		for i := range errs {
+			errs[i] = NewErrGeneric("could not fetch products")
+		}
+		return updated, errs
+	}
+	byID := make(map[int]domain.Product, len(current))
+	for _, p := range current {
+		byID[p.ID] = p
+	}
+	for i, id := range ids {
+		p, ok := byID[id]
+		if !ok {
+			errs[i] = NewErrNotFound(id)
+			continue
+		}
+		updated[i], errs[i] = s.update(c, p, updates[i])
+		if errs[i] == nil {
+			byID[id] = updated[i]
+		}
+	}
+	return updated, errs
+}
+
+// update applies updates over p and saves it.
+func (s *service) update(c context.Context, p domain.Product, updates UpdateDTO) (domain.Product, error) {
+	if err := sqlutil.CheckVersion(c, p.Version); err != nil {
+		return domain.Product{}, err
+	}
 
 	if code, hasVal := updates.Code.Value(); hasVal && p.ProductCode != code && s.repo.Exists(c, code) {
 		return domain.Product{}, NewErrInvalidProductCode(updates.Code.Val)
@@ -139,14 +339,21 @@ func (s *service) Update(c context.Context, id int, updates UpdateDTO) (domain.P
 
 	updated := applyUpdates(p, updates)
 	if err := s.repo.Update(c, updated); err != nil {
+		if errors.Is(err, sqlutil.ErrVersionMismatch) {
+			return domain.Product{}, err
+		}
 		return domain.Product{}, NewErrGeneric("could not save changes")
 	}
 
+	updated.Version++
 	return updated, nil
 }
 
 func (s *service) Delete(c context.Context, id int) error {
 	err := s.repo.Delete(c, id)
+	if errors.Is(err, sqlutil.ErrVersionMismatch) {
+		return err
+	}
 	if err != nil {
 		switch err.(type) {
 		case *ErrNotFound:
@@ -158,6 +365,23 @@ func (s *service) Delete(c context.Context, id int) error {
 	return nil
 }
 
+func (s *service) Restore(c context.Context, id int) (domain.Product, error) {
+	err := s.repo.Restore(c, id)
+	if err != nil {
+		switch err.(type) {
+		case *ErrNotFound:
+			return domain.Product{}, NewErrNotFound(id)
+		default:
+			return domain.Product{}, NewErrGeneric("could not restore product")
+		}
+	}
+	p, err := s.repo.Get(c, id)
+	if err != nil {
+		return domain.Product{}, NewErrGeneric("could not restore product")
+	}
+	return p, nil
+}
+
 func MapCreateToDomain(product *CreateDTO) *domain.Product {
 	return &domain.Product{
 		Description:    product.Desc,
@@ -183,6 +407,19 @@ func MapCreateRecord(productRec *CreateRecordDTO) *domain.Product_Records {
 	}
 }
 
+func validateRecord(r CreateRecordDTO) error {
+	if r.PurchasePrice < 0 {
+		return NewErrInvalidRecord("purchase_price must not be negative")
+	}
+	if r.SalePrice < 0 {
+		return NewErrInvalidRecord("sale_price must not be negative")
+	}
+	if r.LastDate.After(time.Now()) {
+		return NewErrInvalidRecord("last_update_date must not be in the future")
+	}
+	return nil
+}
+
 func applyUpdates(p domain.Product, updates UpdateDTO) domain.Product {
 	p.Description = updates.Desc.Or(p.Description)
 	p.ExpirationRate = updates.ExpR.Or(p.ExpirationRate)
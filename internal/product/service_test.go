@@ -4,10 +4,13 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/product"
+	producttype "github.com/extmatperez/meli_bootcamp_go_w2-4/internal/product_type"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -17,7 +20,8 @@ var ErrRepository = errors.New("error in the repository layer")
 func TestCreate(t *testing.T) {
 	t.Run("Creates valid product", func(t *testing.T) {
 		mockRepo := RepositoryMock{}
-		svc := product.NewService(&mockRepo)
+		types := ProductTypesMock{}
+		svc := product.NewService(&mockRepo, &types)
 
 		dto := product.CreateDTO{
 			Desc:       "Sweet potato",
@@ -36,6 +40,7 @@ func TestCreate(t *testing.T) {
 		expected.ID = 1
 
 		mockRepo.On("Exists", mock.Anything, mock.Anything).Return(false)
+		types.On("Get", mock.Anything, 1).Return(domain.ProductType{ID: 1}, nil)
 		mockRepo.On("Save", mock.Anything, mock.Anything).Return(expected.ID, nil)
 
 		p, err := svc.Create(context.TODO(), dto)
@@ -45,7 +50,8 @@ func TestCreate(t *testing.T) {
 	})
 	t.Run("Doesn't create product if product code exists", func(t *testing.T) {
 		mockRepo := RepositoryMock{}
-		svc := product.NewService(&mockRepo)
+		types := ProductTypesMock{}
+		svc := product.NewService(&mockRepo, &types)
 
 		dto := product.CreateDTO{
 			Desc:       "Sweet potato",
@@ -70,7 +76,8 @@ func TestCreate(t *testing.T) {
 	})
 	t.Run("Returns generic domain error if repository fails", func(t *testing.T) {
 		mockRepo := RepositoryMock{}
-		svc := product.NewService(&mockRepo)
+		types := ProductTypesMock{}
+		svc := product.NewService(&mockRepo, &types)
 
 		dto := product.CreateDTO{
 			Desc:       "Sweet potato",
@@ -88,18 +95,35 @@ func TestCreate(t *testing.T) {
 		var expectedErr *product.ErrGeneric
 
 		mockRepo.On("Exists", mock.Anything, mock.Anything).Return(false)
+		types.On("Get", mock.Anything, 1).Return(domain.ProductType{ID: 1}, nil)
 		mockRepo.On("Save", mock.Anything, mock.Anything).Return(0, ErrRepository)
 
 		_, err := svc.Create(context.TODO(), dto)
 
 		assert.ErrorAs(t, err, &expectedErr)
 	})
+	t.Run("Doesn't create product if product type doesn't exist", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		types := ProductTypesMock{}
+		svc := product.NewService(&mockRepo, &types)
+
+		dto := product.CreateDTO{Code: "SWP-1", TypeID: 9}
+		var expectedErr *product.ErrInvalidProductType
+
+		mockRepo.On("Exists", mock.Anything, mock.Anything).Return(false)
+		types.On("Get", mock.Anything, 9).Return(domain.ProductType{}, producttype.ErrNotFound)
+
+		_, err := svc.Create(context.TODO(), dto)
+
+		assert.ErrorAs(t, err, &expectedErr)
+		mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	})
 }
 
 func TestRead(t *testing.T) {
 	t.Run("Gets all products", func(t *testing.T) {
 		mockRepo := RepositoryMock{}
-		svc := product.NewService(&mockRepo)
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
 
 		expected := getTestProducts()
 
@@ -111,7 +135,7 @@ func TestRead(t *testing.T) {
 	})
 	t.Run("Gets correct product by ID", func(t *testing.T) {
 		mockRepo := RepositoryMock{}
-		svc := product.NewService(&mockRepo)
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
 
 		expected := getTestProducts()[0]
 
@@ -123,7 +147,7 @@ func TestRead(t *testing.T) {
 	})
 	t.Run("Returns not found for nonexistent ID", func(t *testing.T) {
 		mockRepo := RepositoryMock{}
-		svc := product.NewService(&mockRepo)
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
 
 		p := getTestProducts()[0]
 		var expectedErr *product.ErrNotFound
@@ -135,7 +159,7 @@ func TestRead(t *testing.T) {
 	})
 	t.Run("Returns generic domain error if repository fails", func(t *testing.T) {
 		mockRepo := RepositoryMock{}
-		svc := product.NewService(&mockRepo)
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
 
 		var expectedErr *product.ErrGeneric
 
@@ -149,7 +173,7 @@ func TestRead(t *testing.T) {
 func TestUpdate(t *testing.T) {
 	t.Run("Updates given fields for existing product", func(t *testing.T) {
 		mockRepo := RepositoryMock{}
-		svc := product.NewService(&mockRepo)
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
 
 		toUpdate := domain.Product{
 			ID:             1,
@@ -190,12 +214,13 @@ func TestUpdate(t *testing.T) {
 
 		received, err := svc.Update(context.TODO(), toUpdate.ID, updates)
 
+		expected.Version++
 		assert.NoError(t, err)
 		assert.Equal(t, expected, received)
 	})
 	t.Run("Update fails if product code is not unique", func(t *testing.T) {
 		mockRepo := RepositoryMock{}
-		svc := product.NewService(&mockRepo)
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
 
 		toUpdate := getTestProducts()[1]
 		updates := product.UpdateDTO{Code: *optional.FromVal("SWP-1")}
@@ -210,7 +235,7 @@ func TestUpdate(t *testing.T) {
 	})
 	t.Run("Update succeds if product code doesn't change", func(t *testing.T) {
 		mockRepo := RepositoryMock{}
-		svc := product.NewService(&mockRepo)
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
 
 		toUpdate := getTestProducts()[1]
 		updates := product.UpdateDTO{Code: *optional.FromVal(toUpdate.ProductCode)}
@@ -220,12 +245,13 @@ func TestUpdate(t *testing.T) {
 
 		received, err := svc.Update(context.TODO(), toUpdate.ID, updates)
 
+		toUpdate.Version++
 		assert.NoError(t, err)
 		assert.Equal(t, toUpdate, received)
 	})
 	t.Run("Returns not found for nonexistent ID", func(t *testing.T) {
 		mockRepo := RepositoryMock{}
-		svc := product.NewService(&mockRepo)
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
 
 		toUpdate := getTestProducts()[1]
 		updates := product.UpdateDTO{Desc: *optional.FromVal("Garlic")}
@@ -239,7 +265,7 @@ func TestUpdate(t *testing.T) {
 	})
 	t.Run("Returns generic domain error if repository fails", func(t *testing.T) {
 		mockRepo := RepositoryMock{}
-		svc := product.NewService(&mockRepo)
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
 
 		toUpdate := getTestProducts()[1]
 		updates := product.UpdateDTO{Desc: *optional.FromVal("Garlic")}
@@ -253,12 +279,85 @@ func TestUpdate(t *testing.T) {
 
 		assert.ErrorAs(t, err, &expectedErr)
 	})
+	t.Run("Update fails if the product is not in the expected version", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
+
+		toUpdate := getTestProducts()[1]
+		toUpdate.Version = 2
+		mockRepo.On("Get", mock.Anything, toUpdate.ID).Return(toUpdate, nil)
+
+		ctx := sqlutil.WithIfMatch(context.TODO(), []int{1})
+		_, err := svc.Update(ctx, toUpdate.ID, product.UpdateDTO{Desc: *optional.FromVal("new")})
+
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+}
+
+func TestBatch(t *testing.T) {
+	t.Run("Creates the valid products in a single save", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		types := ProductTypesMock{}
+		svc := product.NewService(&mockRepo, &types)
+
+		dtos := []product.CreateDTO{{Code: "A", TypeID: 1}, {Code: "B", TypeID: 2}, {Code: "A", TypeID: 1}, {Code: "C", TypeID: 1}}
+		mockRepo.On("ExistingCodes", mock.Anything, []string{"A", "B", "A", "C"}).Return([]string(nil), nil)
+		types.On("Get", mock.Anything, 1).Return(domain.ProductType{ID: 1}, nil).Once()
+		types.On("Get", mock.Anything, 2).Return(domain.ProductType{}, producttype.ErrNotFound)
+		valid := []domain.Product{{ProductCode: "A", ProductTypeID: 1}, {ProductCode: "C", ProductTypeID: 1}}
+		mockRepo.On("SaveAll", mock.Anything, valid).Return([]int{5, 6}, nil)
+
+		created, errs := svc.CreateBatch(context.TODO(), dtos)
+
+		assert.NoError(t, errs[0])
+		assert.Equal(t, product.NewErrInvalidProductType(2), errs[1])
+		assert.Equal(t, product.NewErrInvalidProductCode("A"), errs[2])
+		assert.NoError(t, errs[3])
+		assert.Equal(t, 5, created[0].ID)
+		assert.Equal(t, 6, created[3].ID)
+	})
+	t.Run("Saves the products one by one to tell which failed the single save", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		types := ProductTypesMock{}
+		svc := product.NewService(&mockRepo, &types)
+
+		dtos := []product.CreateDTO{{Code: "A", TypeID: 1, SellerID: 1}, {Code: "B", TypeID: 1, SellerID: 99}}
+		mockRepo.On("ExistingCodes", mock.Anything, []string{"A", "B"}).Return([]string(nil), nil)
+		types.On("Get", mock.Anything, 1).Return(domain.ProductType{ID: 1}, nil)
+		mockRepo.On("SaveAll", mock.Anything, mock.Anything).Return([]int(nil), errors.New("foreign key fails"))
+		mockRepo.On("Save", mock.Anything, domain.Product{ProductCode: "A", ProductTypeID: 1, SellerID: 1}).Return(5, nil)
+		mockRepo.On("Save", mock.Anything, domain.Product{ProductCode: "B", ProductTypeID: 1, SellerID: 99}).Return(0, errors.New("foreign key fails"))
+
+		created, errs := svc.CreateBatch(context.TODO(), dtos)
+
+		assert.NoError(t, errs[0])
+		assert.Equal(t, product.NewErrGeneric("error saving product"), errs[1])
+		assert.Equal(t, 5, created[0].ID)
+	})
+	t.Run("Updates the products it finds", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
+
+		current := domain.Product{ID: 1, Description: "Sweet potato", ProductCode: "SWP-1", Version: 1}
+		updated := current
+		updated.Description = "Potato"
+		mockRepo.On("GetByIDs", mock.Anything, []int{1, 2}).Return([]domain.Product{current}, nil)
+		mockRepo.On("Update", mock.Anything, updated).Return(nil)
+
+		dto := product.UpdateDTO{Desc: *optional.FromVal("Potato")}
+		received, errs := svc.UpdateBatch(context.TODO(), []int{1, 2}, []product.UpdateDTO{dto, dto})
+
+		updated.Version = 2
+		assert.Equal(t, []error{nil, product.NewErrNotFound(2)}, errs)
+		assert.Equal(t, updated, received[0])
+	})
 }
 
 func TestDelete(t *testing.T) {
 	t.Run("Deletes existing product", func(t *testing.T) {
 		mockRepo := RepositoryMock{}
-		svc := product.NewService(&mockRepo)
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
 
 		deleteID := 1
 
@@ -270,7 +369,7 @@ func TestDelete(t *testing.T) {
 	})
 	t.Run("Returns not found for nonexistent ID", func(t *testing.T) {
 		mockRepo := RepositoryMock{}
-		svc := product.NewService(&mockRepo)
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
 
 		deleteID := 1
 		var expectedErr *product.ErrNotFound
@@ -283,7 +382,7 @@ func TestDelete(t *testing.T) {
 	})
 	t.Run("Returns generic domain error if repository fails", func(t *testing.T) {
 		mockRepo := RepositoryMock{}
-		svc := product.NewService(&mockRepo)
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
 
 		deleteID := 1
 		var expectedErr *product.ErrGeneric
@@ -296,13 +395,38 @@ func TestDelete(t *testing.T) {
 	})
 }
 
+func TestRestore(t *testing.T) {
+	t.Run("restores a deleted product", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
+		expected := domain.Product{ID: 1, ProductCode: "abc"}
+
+		mockRepo.On("Restore", mock.Anything, expected.ID).Return(nil)
+		mockRepo.On("Get", mock.Anything, expected.ID).Return(expected, nil)
+		restored, err := svc.Restore(context.TODO(), expected.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, restored)
+	})
+	t.Run("returns not found when the product is not deleted", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
+
+		mockRepo.On("Restore", mock.Anything, 1).Return(product.NewErrNotFound(1))
+		_, err := svc.Restore(context.TODO(), 1)
+
+		var notFound *product.ErrNotFound
+		assert.ErrorAs(t, err, &notFound)
+	})
+}
+
 func TestCreateRecord(t *testing.T) {
 	t.Run("Creates valid product record", func(t *testing.T) {
 		mockRepo := RepositoryMock{}
-		svc := product.NewService(&mockRepo)
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
 
 		dto := product.CreateRecordDTO{
-			LastDate:      "2022-15-11",
+			LastDate:      time.Date(2022, 11, 15, 0, 0, 0, 0, time.UTC),
 			PurchasePrice: 23.7,
 			SalePrice:     31.8,
 			ProductID:     1,
@@ -320,10 +444,10 @@ func TestCreateRecord(t *testing.T) {
 	})
 	t.Run("Returns generic domain error if repository fails", func(t *testing.T) {
 		mockRepo := RepositoryMock{}
-		svc := product.NewService(&mockRepo)
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
 
 		dto := product.CreateRecordDTO{
-			LastDate:      "2022-15-11",
+			LastDate:      time.Date(2022, 11, 15, 0, 0, 0, 0, time.UTC),
 			PurchasePrice: 23.7,
 			SalePrice:     31.8,
 			ProductID:     1,
@@ -338,10 +462,10 @@ func TestCreateRecord(t *testing.T) {
 	})
 	t.Run("Returns generic domain error if repository fails", func(t *testing.T) {
 		mockRepo := RepositoryMock{}
-		svc := product.NewService(&mockRepo)
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
 
 		dto := product.CreateRecordDTO{
-			LastDate:      "2022-15-11",
+			LastDate:      time.Date(2022, 11, 15, 0, 0, 0, 0, time.UTC),
 			PurchasePrice: 23.7,
 			SalePrice:     31.8,
 			ProductID:     1000,
@@ -356,10 +480,49 @@ func TestCreateRecord(t *testing.T) {
 	})
 }
 
+func TestCreateRecordValidation(t *testing.T) {
+	t.Run("Fails if sale price is negative", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
+
+		dto := product.CreateRecordDTO{
+			LastDate:      time.Date(2022, 11, 15, 0, 0, 0, 0, time.UTC),
+			PurchasePrice: 23.7,
+			SalePrice:     -1,
+			ProductID:     1,
+		}
+
+		var expectedErr *product.ErrInvalidRecord
+
+		mockRepo.On("Get", mock.Anything, mock.Anything).Return(domain.Product{}, nil)
+		_, err := svc.CreateRecord(context.TODO(), dto)
+		assert.ErrorAs(t, err, &expectedErr)
+		mockRepo.AssertNotCalled(t, "SaveRecord", mock.Anything, mock.Anything)
+	})
+	t.Run("Fails if date is in the future", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
+
+		dto := product.CreateRecordDTO{
+			LastDate:      time.Now().Add(time.Hour),
+			PurchasePrice: 23.7,
+			SalePrice:     31.8,
+			ProductID:     1,
+		}
+
+		var expectedErr *product.ErrInvalidRecord
+
+		mockRepo.On("Get", mock.Anything, mock.Anything).Return(domain.Product{}, nil)
+		_, err := svc.CreateRecord(context.TODO(), dto)
+		assert.ErrorAs(t, err, &expectedErr)
+		mockRepo.AssertNotCalled(t, "SaveRecord", mock.Anything, mock.Anything)
+	})
+}
+
 func TestReadRecords(t *testing.T) {
 	t.Run("Gets all product records", func(t *testing.T) {
 		mockRepo := RepositoryMock{}
-		svc := product.NewService(&mockRepo)
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
 
 		expected := getTestProductRecord()
 
@@ -371,7 +534,7 @@ func TestReadRecords(t *testing.T) {
 	})
 	t.Run("Gets correct product records by ID", func(t *testing.T) {
 		mockRepo := RepositoryMock{}
-		svc := product.NewService(&mockRepo)
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
 
 		expected := getTestProductRecord()
 
@@ -383,7 +546,7 @@ func TestReadRecords(t *testing.T) {
 	})
 	t.Run("Returns not found for nonexistent ID", func(t *testing.T) {
 		mockRepo := RepositoryMock{}
-		svc := product.NewService(&mockRepo)
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
 
 		p := getTestProductRecord()
 		var expectedErr *product.ErrNotFound
@@ -395,7 +558,7 @@ func TestReadRecords(t *testing.T) {
 	})
 	t.Run("Returns generic domain error if repository fails", func(t *testing.T) {
 		mockRepo := RepositoryMock{}
-		svc := product.NewService(&mockRepo)
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
 
 		var expectedErr *product.ErrGeneric
 
@@ -406,6 +569,181 @@ func TestReadRecords(t *testing.T) {
 	})
 }
 
+func TestStreamRecords(t *testing.T) {
+	t.Run("Streams every product record", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
+
+		expected := getTestProductRecord()
+		mockRepo.On("StreamRecords", mock.Anything, 0).Return(expected, nil)
+
+		var received []domain.Product_Records
+		err := svc.StreamRecords(context.TODO(), 0, func(r domain.Product_Records) error {
+			received = append(received, r)
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, received)
+	})
+	t.Run("Returns not found for nonexistent product ID", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
+
+		var expectedErr *product.ErrNotFound
+		mockRepo.On("Get", mock.Anything, 7).Return(domain.Product{}, product.NewErrNotFound(7))
+
+		err := svc.StreamRecords(context.TODO(), 7, func(r domain.Product_Records) error { return nil })
+
+		assert.ErrorAs(t, err, &expectedErr)
+		mockRepo.AssertNotCalled(t, "StreamRecords", mock.Anything, mock.Anything)
+	})
+	t.Run("Returns generic domain error if repository fails", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
+
+		var expectedErr *product.ErrGeneric
+		mockRepo.On("StreamRecords", mock.Anything, 0).Return([]domain.Product_Records{}, ErrRepository)
+
+		err := svc.StreamRecords(context.TODO(), 0, func(r domain.Product_Records) error { return nil })
+
+		assert.ErrorAs(t, err, &expectedErr)
+	})
+}
+
+func TestPriceHistory(t *testing.T) {
+	t.Run("Returns timeline in date order with deltas", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
+
+		mockRepo.On("Get", mock.Anything, 3).Return(domain.Product{ID: 3}, nil)
+		mockRepo.On("GetRecordsbyProd", mock.Anything, 3).Return(getTestPriceRecords(), nil)
+
+		h, err := svc.PriceHistory(context.TODO(), 3, *optional.New[time.Time](), *optional.New[time.Time]())
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1, 2, 3}, timelineIDs(h))
+		assert.Equal(t, 0.0, h.Timeline[0].SaleDelta)
+		assert.Equal(t, 50.0, h.Timeline[0].MarginPct)
+		assert.Equal(t, 5.0, h.Timeline[1].SaleDelta)
+		assert.Equal(t, -2.0, h.Timeline[2].SaleDelta)
+		assert.Equal(t, 3.0, h.SaleDelta)
+		assert.Equal(t, 2.0, h.PurchaseDelta)
+	})
+	t.Run("Compares the range with the price in effect before it", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
+
+		mockRepo.On("Get", mock.Anything, 3).Return(domain.Product{ID: 3}, nil)
+		mockRepo.On("GetRecordsbyProd", mock.Anything, 3).Return(getTestPriceRecords(), nil)
+
+		from := optional.FromVal(time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC))
+		to := optional.FromVal(time.Date(2022, 2, 28, 0, 0, 0, 0, time.UTC))
+		h, err := svc.PriceHistory(context.TODO(), 3, *from, *to)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{2}, timelineIDs(h))
+		assert.Equal(t, 5.0, h.Timeline[0].SaleDelta)
+		assert.Equal(t, 5.0, h.SaleDelta)
+	})
+	t.Run("Returns not found if product does not exist", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
+
+		mockRepo.On("Get", mock.Anything, 3).Return(domain.Product{}, ErrRepository)
+
+		var expectedErr *product.ErrNotFound
+		_, err := svc.PriceHistory(context.TODO(), 3, *optional.New[time.Time](), *optional.New[time.Time]())
+		assert.ErrorAs(t, err, &expectedErr)
+	})
+}
+
+func TestEffectivePrice(t *testing.T) {
+	t.Run("Returns the latest price up to the given date", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
+
+		mockRepo.On("Get", mock.Anything, 3).Return(domain.Product{ID: 3}, nil)
+		mockRepo.On("GetRecordsbyProd", mock.Anything, 3).Return(getTestPriceRecords(), nil)
+
+		p, err := svc.EffectivePrice(context.TODO(), 3, time.Date(2022, 2, 15, 0, 0, 0, 0, time.UTC))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, p.RecordID)
+		assert.Equal(t, 25.0, p.SalePrice)
+	})
+	t.Run("Fails if there is no price yet at the given date", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
+
+		mockRepo.On("Get", mock.Anything, 3).Return(domain.Product{ID: 3}, nil)
+		mockRepo.On("GetRecordsbyProd", mock.Anything, 3).Return(getTestPriceRecords(), nil)
+
+		var expectedErr *product.ErrNoPrice
+		_, err := svc.EffectivePrice(context.TODO(), 3, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+		assert.ErrorAs(t, err, &expectedErr)
+	})
+}
+
+func TestSearch(t *testing.T) {
+	t.Run("Returns the requested page of matches", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
+
+		f := product.SearchFilter{Text: "potato", SellerID: *optional.FromVal(1)}
+		expected := getTestProducts()
+		mockRepo.On("Search", mock.Anything, f, 2, 4).Return(expected, 7, nil)
+
+		page, err := svc.Search(context.TODO(), product.SearchFilter{Text: "  potato ", SellerID: *optional.FromVal(1)}, 3, 2)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.ProductPage{Products: expected, Page: 3, PageSize: 2, Total: 7}, page)
+	})
+	t.Run("Rejects invalid pages", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
+
+		var expectedErr *product.ErrInvalidSearch
+		_, err := svc.Search(context.TODO(), product.SearchFilter{}, 0, 10)
+		assert.ErrorAs(t, err, &expectedErr)
+		_, err = svc.Search(context.TODO(), product.SearchFilter{}, 1, product.MaxPageSize+1)
+		assert.ErrorAs(t, err, &expectedErr)
+		mockRepo.AssertNotCalled(t, "Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("Fails if the repository fails", func(t *testing.T) {
+		mockRepo := RepositoryMock{}
+		svc := product.NewService(&mockRepo, &ProductTypesMock{})
+
+		mockRepo.On("Search", mock.Anything, product.SearchFilter{}, 20, 0).Return([]domain.Product{}, 0, ErrRepository)
+
+		var expectedErr *product.ErrGeneric
+		_, err := svc.Search(context.TODO(), product.SearchFilter{}, 1, 20)
+		assert.ErrorAs(t, err, &expectedErr)
+	})
+}
+
+func TestMarginPct(t *testing.T) {
+	assert.Equal(t, 25.0, product.MarginPct(15, 20))
+	assert.Equal(t, 0.0, product.MarginPct(15, 0))
+}
+
+// getTestPriceRecords returns records out of date order on purpose.
+func getTestPriceRecords() []domain.Product_Records {
+	return []domain.Product_Records{
+		{ID: 3, LastUpdateDate: time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC), PurchasePrice: 12, SalePrice: 23, ProductID: 3},
+		{ID: 1, LastUpdateDate: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), PurchasePrice: 10, SalePrice: 20, ProductID: 3},
+		{ID: 2, LastUpdateDate: time.Date(2022, 2, 10, 0, 0, 0, 0, time.UTC), PurchasePrice: 12, SalePrice: 25, ProductID: 3},
+	}
+}
+
+func timelineIDs(h domain.PriceHistory) []int {
+	ids := []int{}
+	for _, entry := range h.Timeline {
+		ids = append(ids, entry.RecordID)
+	}
+	return ids
+}
+
 func getTestProducts() []domain.Product {
 	return []domain.Product{
 		{
@@ -443,14 +781,14 @@ func getTestProductRecord() []domain.Product_Records {
 	return []domain.Product_Records{
 		{
 			ID:             1,
-			LastUpdateDate: "2022-10-11",
+			LastUpdateDate: time.Date(2022, 10, 11, 0, 0, 0, 0, time.UTC),
 			PurchasePrice:  10.5,
 			SalePrice:      27.6,
 			ProductID:      3,
 		},
 		{
 			ID:             2,
-			LastUpdateDate: "2022-02-01",
+			LastUpdateDate: time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC),
 			PurchasePrice:  10.5,
 			SalePrice:      27.6,
 			ProductID:      3,
@@ -472,16 +810,31 @@ func (r *RepositoryMock) Get(ctx context.Context, id int) (domain.Product, error
 	return args.Get(0).(domain.Product), args.Error(1)
 }
 
+func (r *RepositoryMock) GetByIDs(ctx context.Context, ids []int) ([]domain.Product, error) {
+	args := r.Called(ctx, ids)
+	return args.Get(0).([]domain.Product), args.Error(1)
+}
+
 func (r *RepositoryMock) Exists(ctx context.Context, productCode string) bool {
 	args := r.Called(ctx, productCode)
 	return args.Get(0).(bool)
 }
 
+func (r *RepositoryMock) ExistingCodes(ctx context.Context, codes []string) ([]string, error) {
+	args := r.Called(ctx, codes)
+	return args.Get(0).([]string), args.Error(1)
+}
+
 func (r *RepositoryMock) Save(ctx context.Context, p domain.Product) (int, error) {
 	args := r.Called(ctx, p)
 	return args.Get(0).(int), args.Error(1)
 }
 
+func (r *RepositoryMock) SaveAll(ctx context.Context, ps []domain.Product) ([]int, error) {
+	args := r.Called(ctx, ps)
+	return args.Get(0).([]int), args.Error(1)
+}
+
 func (r *RepositoryMock) Update(ctx context.Context, p domain.Product) error {
 	args := r.Called(ctx, p)
 	return args.Error(0)
@@ -492,6 +845,11 @@ func (r *RepositoryMock) Delete(ctx context.Context, id int) error {
 	return args.Error(0)
 }
 
+func (r *RepositoryMock) Restore(ctx context.Context, id int) error {
+	args := r.Called(ctx, id)
+	return args.Error(0)
+}
+
 func (r *RepositoryMock) SaveRecord(ctx context.Context, p domain.Product_Records) (int, error) {
 	args := r.Called(ctx, p)
 	return args.Get(0).(int), args.Error(1)
@@ -506,3 +864,32 @@ func (r *RepositoryMock) GetRecordsbyProd(ctx context.Context, id int) ([]domain
 	args := r.Called(ctx, id)
 	return args.Get(0).([]domain.Product_Records), args.Error(1)
 }
+
+func (r *RepositoryMock) GetRecord(ctx context.Context, id int) (domain.Product_Records, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).(domain.Product_Records), args.Error(1)
+}
+
+func (r *RepositoryMock) Search(ctx context.Context, f product.SearchFilter, limit, offset int) ([]domain.Product, int, error) {
+	args := r.Called(ctx, f, limit, offset)
+	return args.Get(0).([]domain.Product), args.Int(1), args.Error(2)
+}
+
+func (r *RepositoryMock) StreamRecords(ctx context.Context, id int, fn func(domain.Product_Records) error) error {
+	args := r.Called(ctx, id)
+	for _, record := range args.Get(0).([]domain.Product_Records) {
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+type ProductTypesMock struct {
+	mock.Mock
+}
+
+func (m *ProductTypesMock) Get(ctx context.Context, id int) (domain.ProductType, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(domain.ProductType), args.Error(1)
+}
@@ -0,0 +1,144 @@
+package producttype
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
+)
+
+// Usage counts the rows that reference a product type.
+type Usage struct {
+	Products int
+	Sections int
+}
+
+type Repository interface {
+	GetAll(ctx context.Context) ([]domain.ProductType, error)
+	Get(ctx context.Context, id int) (domain.ProductType, error)
+	// Exists reports whether another product type,
+	// other than excludeID, has the given description.
+	Exists(ctx context.Context, description string, excludeID int) bool
+	Save(ctx context.Context, t domain.ProductType) (int, error)
+	// Update saves t unless the product type is no longer in
+	// t.Version, in which case it fails with sqlutil.ErrVersionMismatch.
+	Update(ctx context.Context, t domain.ProductType) error
+	// Delete removes the product type id if it is in one of the
+	// versions expected by ctx.
+	Delete(ctx context.Context, id int) error
+	Usage(ctx context.Context, id int) (Usage, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db}
+}
+
+func (r *repository) GetAll(ctx context.Context) ([]domain.ProductType, error) {
+	query := "SELECT id, description, version FROM product_types ORDER BY id;"
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	types := []domain.ProductType{}
+	for rows.Next() {
+		t := domain.ProductType{}
+		if err := rows.Scan(&t.ID, &t.Description, &t.Version); err != nil {
+			return nil, err
+		}
+		types = append(types, t)
+	}
+	return types, rows.Err()
+}
+
+func (r *repository) Get(ctx context.Context, id int) (domain.ProductType, error) {
+	query := "SELECT id, description, version FROM product_types WHERE id = ?;"
+	t := domain.ProductType{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&t.ID, &t.Description, &t.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ProductType{}, ErrNotFound
+	}
+	if err != nil {
+		return domain.ProductType{}, err
+	}
+	return t, nil
+}
+
+func (r *repository) Exists(ctx context.Context, description string, excludeID int) bool {
+	query := "SELECT id FROM product_types WHERE description = ? AND id <> ?;"
+	var id int
+	err := r.db.QueryRowContext(ctx, query, description, excludeID).Scan(&id)
+	return err == nil
+}
+
+func (r *repository) Save(ctx context.Context, t domain.ProductType) (int, error) {
+	query := "INSERT INTO product_types (description) VALUES (?);"
+	res, err := r.db.ExecContext(ctx, query, t.Description)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func (r *repository) Update(ctx context.Context, t domain.ProductType) error {
+	query := "UPDATE product_types SET description = ?, version = version + 1 WHERE id = ? AND version = ?;"
+	res, err := r.db.ExecContext(ctx, query, t.Description, t.ID, t.Version)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected < 1 {
+		return sqlutil.StaleOrDeleted(ctx, r.db, "product_types", t.ID, ErrNotFound)
+	}
+	return nil
+}
+
+func (r *repository) Delete(ctx context.Context, id int) error {
+	query := "DELETE FROM product_types WHERE id = ? AND " + sqlutil.VersionMatches(ctx, "version") + ";"
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		// Products or sections were classified under the
+		// type after it was checked that there were none.
+		if strings.HasPrefix(err.Error(), "Error 1451") {
+			return ErrInUse
+		}
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected < 1 {
+		return sqlutil.StaleOrDeleted(ctx, r.db, "product_types", id, ErrNotFound)
+	}
+	return nil
+}
+
+func (r *repository) Usage(ctx context.Context, id int) (Usage, error) {
+	query := `SELECT
+			(SELECT COUNT(*) FROM products WHERE product_type_id = ?),
+			(SELECT COUNT(*) FROM sections WHERE product_type_id = ?);`
+	var u Usage
+	if err := r.db.QueryRowContext(ctx, query, id, id).Scan(&u.Products, &u.Sections); err != nil {
+		return Usage{}, err
+	}
+	return u, nil
+}
@@ -0,0 +1,80 @@
+package producttype_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	producttype "github.com/extmatperez/meli_bootcamp_go_w2-4/internal/product_type"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryProductType(t *testing.T) {
+	t.Run("Saves and gets a product type", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := producttype.NewRepository(db)
+
+		id, err := repo.Save(context.TODO(), domain.ProductType{Description: "test-type"})
+		assert.NoError(t, err)
+
+		received, err := repo.Get(context.TODO(), id)
+		assert.NoError(t, err)
+		assert.Equal(t, "test-type", received.Description)
+		assert.True(t, repo.Exists(context.TODO(), "test-type", 0))
+		assert.False(t, repo.Exists(context.TODO(), "test-type", id))
+	})
+	t.Run("Does not delete a product type in use", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := producttype.NewRepository(db)
+
+		usage, err := repo.Usage(context.TODO(), 1)
+		assert.NoError(t, err)
+		assert.Positive(t, usage.Products+usage.Sections)
+
+		err = repo.Delete(context.TODO(), 1)
+		assert.ErrorIs(t, err, producttype.ErrInUse)
+	})
+	t.Run("Returns not found for a missing product type", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := producttype.NewRepository(db)
+
+		_, err := repo.Get(context.TODO(), 9999)
+		assert.ErrorIs(t, err, producttype.ErrNotFound)
+
+		err = repo.Delete(context.TODO(), 9999)
+		assert.ErrorIs(t, err, producttype.ErrNotFound)
+	})
+	t.Run("Updates a product type only in the version it was read", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := producttype.NewRepository(db)
+
+		id, err := repo.Save(context.TODO(), domain.ProductType{Description: "test-type"})
+		assert.NoError(t, err)
+		stored, err := repo.Get(context.TODO(), id)
+		assert.NoError(t, err)
+
+		stored.Description = "renamed-type"
+		assert.NoError(t, repo.Update(context.TODO(), stored))
+
+		err = repo.Update(context.TODO(), stored)
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+
+		ctx := sqlutil.WithIfMatch(context.TODO(), []int{stored.Version})
+		err = repo.Delete(ctx, id)
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+
+		received, err := repo.Get(context.TODO(), id)
+		assert.NoError(t, err)
+		assert.Equal(t, stored.Version+1, received.Version)
+	})
+}
@@ -0,0 +1,129 @@
+package producttype
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
+)
+
+var (
+	ErrNotFound            = errors.New("product type not found")
+	ErrAlreadyExists       = errors.New("product type description already exists")
+	ErrInvalidDescription  = errors.New("product type description can't be empty")
+	ErrInUse               = errors.New("product type is referenced by products or sections")
+	ErrInternalServerError = errors.New("internal server error")
+)
+
+type Service interface {
+	Create(c context.Context, description string) (domain.ProductType, error)
+	GetAll(c context.Context) ([]domain.ProductType, error)
+	Get(c context.Context, id int) (domain.ProductType, error)
+	Update(c context.Context, id int, description string) (domain.ProductType, error)
+	// Delete removes a product type, unless products
+	// or sections are classified under it.
+	Delete(c context.Context, id int) error
+}
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo}
+}
+
+func (s *service) Create(c context.Context, description string) (domain.ProductType, error) {
+	t := domain.ProductType{Description: strings.TrimSpace(description)}
+	if err := s.validate(c, t); err != nil {
+		return domain.ProductType{}, err
+	}
+
+	id, err := s.repo.Save(c, t)
+	if err != nil {
+		return domain.ProductType{}, ErrInternalServerError
+	}
+	t.ID = id
+	return t, nil
+}
+
+func (s *service) GetAll(c context.Context) ([]domain.ProductType, error) {
+	types, err := s.repo.GetAll(c)
+	if err != nil {
+		return nil, ErrInternalServerError
+	}
+	return types, nil
+}
+
+func (s *service) Get(c context.Context, id int) (domain.ProductType, error) {
+	t, err := s.repo.Get(c, id)
+	if errors.Is(err, ErrNotFound) {
+		return domain.ProductType{}, ErrNotFound
+	}
+	if err != nil {
+		return domain.ProductType{}, ErrInternalServerError
+	}
+	return t, nil
+}
+
+func (s *service) Update(c context.Context, id int, description string) (domain.ProductType, error) {
+	t, err := s.Get(c, id)
+	if err != nil {
+		return domain.ProductType{}, err
+	}
+	if err := sqlutil.CheckVersion(c, t.Version); err != nil {
+		return domain.ProductType{}, err
+	}
+
+	t.Description = strings.TrimSpace(description)
+	if err := s.validate(c, t); err != nil {
+		return domain.ProductType{}, err
+	}
+	if err := s.repo.Update(c, t); err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, sqlutil.ErrVersionMismatch) {
+			return domain.ProductType{}, err
+		}
+		return domain.ProductType{}, ErrInternalServerError
+	}
+	t.Version++
+	return t, nil
+}
+
+func (s *service) Delete(c context.Context, id int) error {
+	t, err := s.Get(c, id)
+	if err != nil {
+		return err
+	}
+	if err := sqlutil.CheckVersion(c, t.Version); err != nil {
+		return err
+	}
+
+	usage, err := s.repo.Usage(c, id)
+	if err != nil {
+		return ErrInternalServerError
+	}
+	if usage.Products > 0 || usage.Sections > 0 {
+		return ErrInUse
+	}
+
+	err = s.repo.Delete(c, id)
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrInUse) || errors.Is(err, sqlutil.ErrVersionMismatch) {
+		return err
+	}
+	if err != nil {
+		return ErrInternalServerError
+	}
+	return nil
+}
+
+func (s *service) validate(c context.Context, t domain.ProductType) error {
+	if t.Description == "" {
+		return ErrInvalidDescription
+	}
+	if s.repo.Exists(c, t.Description, t.ID) {
+		return ErrAlreadyExists
+	}
+	return nil
+}
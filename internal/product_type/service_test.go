@@ -0,0 +1,189 @@
+package producttype_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	producttype "github.com/extmatperez/meli_bootcamp_go_w2-4/internal/product_type"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var ErrRepository = errors.New("error in the repository layer")
+
+func TestCreate(t *testing.T) {
+	t.Run("Creates product type with trimmed description", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := producttype.NewService(&repo)
+
+		repo.On("Exists", mock.Anything, "frozen", 0).Return(false)
+		repo.On("Save", mock.Anything, domain.ProductType{Description: "frozen"}).Return(1, nil)
+
+		received, err := svc.Create(context.TODO(), "  frozen ")
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.ProductType{ID: 1, Description: "frozen"}, received)
+	})
+	t.Run("Doesn't create product type with empty description", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := producttype.NewService(&repo)
+
+		_, err := svc.Create(context.TODO(), "   ")
+
+		assert.ErrorIs(t, err, producttype.ErrInvalidDescription)
+	})
+	t.Run("Doesn't create duplicate product type", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := producttype.NewService(&repo)
+
+		repo.On("Exists", mock.Anything, "frozen", 0).Return(true)
+
+		_, err := svc.Create(context.TODO(), "frozen")
+
+		assert.ErrorIs(t, err, producttype.ErrAlreadyExists)
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	t.Run("Renames product type", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := producttype.NewService(&repo)
+
+		expected := domain.ProductType{ID: 1, Description: "chilled", Version: 1}
+		repo.On("Get", mock.Anything, 1).Return(domain.ProductType{ID: 1, Description: "frozen", Version: 1}, nil)
+		repo.On("Exists", mock.Anything, "chilled", 1).Return(false)
+		repo.On("Update", mock.Anything, expected).Return(nil)
+
+		received, err := svc.Update(context.TODO(), 1, "chilled")
+
+		expected.Version++
+		assert.NoError(t, err)
+		assert.Equal(t, expected, received)
+	})
+	t.Run("Returns not found for a missing product type", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := producttype.NewService(&repo)
+
+		repo.On("Get", mock.Anything, 9).Return(domain.ProductType{}, producttype.ErrNotFound)
+
+		_, err := svc.Update(context.TODO(), 9, "chilled")
+
+		assert.ErrorIs(t, err, producttype.ErrNotFound)
+	})
+	t.Run("Update fails if the product type is not in the expected version", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := producttype.NewService(&repo)
+
+		repo.On("Get", mock.Anything, 1).Return(domain.ProductType{ID: 1, Description: "frozen", Version: 2}, nil)
+
+		ctx := sqlutil.WithIfMatch(context.TODO(), []int{1})
+		_, err := svc.Update(ctx, 1, "chilled")
+
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+		repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+	t.Run("Update fails if the product type changed after it was read", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := producttype.NewService(&repo)
+
+		repo.On("Get", mock.Anything, 1).Return(domain.ProductType{ID: 1, Description: "frozen", Version: 1}, nil)
+		repo.On("Exists", mock.Anything, "chilled", 1).Return(false)
+		repo.On("Update", mock.Anything, mock.Anything).Return(sqlutil.ErrVersionMismatch)
+
+		_, err := svc.Update(context.TODO(), 1, "chilled")
+
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+	})
+}
+
+func TestDelete(t *testing.T) {
+	t.Run("Deletes an unused product type", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := producttype.NewService(&repo)
+
+		repo.On("Get", mock.Anything, 1).Return(domain.ProductType{ID: 1}, nil)
+		repo.On("Usage", mock.Anything, 1).Return(producttype.Usage{}, nil)
+		repo.On("Delete", mock.Anything, 1).Return(nil)
+
+		err := svc.Delete(context.TODO(), 1)
+
+		assert.NoError(t, err)
+	})
+	t.Run("Doesn't delete a product type in use", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := producttype.NewService(&repo)
+
+		repo.On("Get", mock.Anything, 1).Return(domain.ProductType{ID: 1}, nil)
+		repo.On("Usage", mock.Anything, 1).Return(producttype.Usage{Sections: 2}, nil)
+
+		err := svc.Delete(context.TODO(), 1)
+
+		assert.ErrorIs(t, err, producttype.ErrInUse)
+		repo.AssertNotCalled(t, "Delete", mock.Anything, 1)
+	})
+	t.Run("Doesn't delete a product type in another version", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := producttype.NewService(&repo)
+
+		repo.On("Get", mock.Anything, 1).Return(domain.ProductType{ID: 1, Version: 2}, nil)
+
+		ctx := sqlutil.WithIfMatch(context.TODO(), []int{1})
+		err := svc.Delete(ctx, 1)
+
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+		repo.AssertNotCalled(t, "Delete", mock.Anything, 1)
+	})
+	t.Run("Returns generic error if repository fails", func(t *testing.T) {
+		repo := RepositoryMock{}
+		svc := producttype.NewService(&repo)
+
+		repo.On("Get", mock.Anything, 1).Return(domain.ProductType{ID: 1}, nil)
+		repo.On("Usage", mock.Anything, 1).Return(producttype.Usage{}, ErrRepository)
+
+		err := svc.Delete(context.TODO(), 1)
+
+		assert.ErrorIs(t, err, producttype.ErrInternalServerError)
+	})
+}
+
+type RepositoryMock struct {
+	mock.Mock
+}
+
+func (r *RepositoryMock) GetAll(ctx context.Context) ([]domain.ProductType, error) {
+	args := r.Called(ctx)
+	return args.Get(0).([]domain.ProductType), args.Error(1)
+}
+
+func (r *RepositoryMock) Get(ctx context.Context, id int) (domain.ProductType, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).(domain.ProductType), args.Error(1)
+}
+
+func (r *RepositoryMock) Exists(ctx context.Context, description string, excludeID int) bool {
+	args := r.Called(ctx, description, excludeID)
+	return args.Bool(0)
+}
+
+func (r *RepositoryMock) Save(ctx context.Context, t domain.ProductType) (int, error) {
+	args := r.Called(ctx, t)
+	return args.Get(0).(int), args.Error(1)
+}
+
+func (r *RepositoryMock) Update(ctx context.Context, t domain.ProductType) error {
+	args := r.Called(ctx, t)
+	return args.Error(0)
+}
+
+func (r *RepositoryMock) Delete(ctx context.Context, id int) error {
+	args := r.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (r *RepositoryMock) Usage(ctx context.Context, id int) (producttype.Usage, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).(producttype.Usage), args.Error(1)
+}
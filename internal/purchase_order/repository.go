@@ -3,14 +3,27 @@ package purchaseorder
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 )
 
 type Repository interface {
 	Create(ctx context.Context, i domain.PurchaseOrder) (int, error)
 	Exists(ctx context.Context, orderNumber string) bool
+	Get(ctx context.Context, id int) (domain.PurchaseOrder, error)
+	GetByTrackingCode(ctx context.Context, trackingCode string) (domain.PurchaseOrder, error)
+	// Dispatch assigns the carrier and source warehouse of an
+	// order and records its first shipment event, in a single
+	// transaction.
+	Dispatch(ctx context.Context, id, carrierID, warehouseID int) (domain.ShipmentEvent, error)
+	AddShipmentEvent(ctx context.Context, id int, status string) (domain.ShipmentEvent, error)
+	// ShipmentEvents returns the shipment events of an order,
+	// oldest first.
+	ShipmentEvents(ctx context.Context, id int) ([]domain.ShipmentEvent, error)
 }
 
 type repository struct {
@@ -31,12 +44,23 @@ func (r *repository) Exists(ctx context.Context, orderNumber string) bool {
 }
 
 func (r *repository) Create(ctx context.Context, i domain.PurchaseOrder) (int, error) {
+	// An order and its details are inserted together, so that a failure
+	// never leaves an order without them.
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
 	// To insert a purchase_order it is necessary to have a product_record_id as a foreign key
-	queryPurchaseOrders := "INSERT INTO purchase_orders(order_number,order_date,tracking_code,buyer_id,order_status_id,product_record_id) SELECT ?,?,?,?,?,? FROM product_records pr WHERE pr.id = ?"
-	res, err := r.db.Exec(queryPurchaseOrders, i.OrderNumber, i.OrderDate, i.TrackingCode, i.BuyerID, i.OrderStatusID, i.ProductRecordID, i.ProductRecordID)
+	queryPurchaseOrders := "INSERT INTO purchase_orders(order_number,order_date,tracking_code,buyer_id,order_status_id,product_record_id,carrier_id,warehouse_id) SELECT ?,?,?,?,?,?,?,? FROM product_records pr WHERE pr.id = ?"
+	res, err := tx.ExecContext(ctx, queryPurchaseOrders, i.OrderNumber, i.OrderDate, i.TrackingCode, i.BuyerID, i.OrderStatusID, i.ProductRecordID, i.CarrierID, i.WarehouseID, i.ProductRecordID)
 	if err != nil {
 		if strings.HasPrefix(err.Error(), "Error 1452") {
-			return 0, ErrFKNotFound
+			return 0, missingReference(err)
+		}
+		if strings.HasPrefix(err.Error(), "Error 1062") && strings.Contains(err.Error(), "tracking_code") {
+			return 0, ErrTrackingCodeExists
 		}
 		return 0, err
 	}
@@ -47,12 +71,169 @@ func (r *repository) Create(ctx context.Context, i domain.PurchaseOrder) (int, e
 	}
 
 	queryOrderDetails := "INSERT INTO order_details(clean_liness_status,quantity,temperature,product_record_id,purchase_order_id) VALUES (?,?,?,?,?)"
-	_, err = r.db.Exec(queryOrderDetails, "good", 1, 32, i.ProductRecordID, id)
+	_, err = tx.ExecContext(ctx, queryOrderDetails, "good", i.Quantity, 32, i.ProductRecordID, id)
 	if err != nil {
 		if strings.HasPrefix(err.Error(), "Error 1452") {
 			return 0, ErrProductRecordIDNotFound
 		}
 		return 0, err
 	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
 	return int(id), nil
 }
+
+const selectPurchaseOrder = "SELECT id, order_number, order_date, tracking_code, buyer_id, product_record_id, order_status_id, carrier_id, warehouse_id FROM purchase_orders"
+
+func (r *repository) Get(ctx context.Context, id int) (domain.PurchaseOrder, error) {
+	row := r.db.QueryRowContext(ctx, selectPurchaseOrder+" WHERE id = ?;", id)
+	return scanPurchaseOrder(row)
+}
+
+func (r *repository) GetByTrackingCode(ctx context.Context, trackingCode string) (domain.PurchaseOrder, error) {
+	row := r.db.QueryRowContext(ctx, selectPurchaseOrder+" WHERE tracking_code = ?;", trackingCode)
+	return scanPurchaseOrder(row)
+}
+
+func scanPurchaseOrder(row *sql.Row) (domain.PurchaseOrder, error) {
+	i := domain.PurchaseOrder{}
+	var carrierID, warehouseID sql.NullInt64
+	err := row.Scan(&i.ID, &i.OrderNumber, sqlutil.Time(&i.OrderDate), &i.TrackingCode, &i.BuyerID,
+		&i.ProductRecordID, &i.OrderStatusID, &carrierID, &warehouseID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.PurchaseOrder{}, ErrNotFound
+	}
+	if err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+	if carrierID.Valid {
+		id := int(carrierID.Int64)
+		i.CarrierID = &id
+	}
+	if warehouseID.Valid {
+		id := int(warehouseID.Int64)
+		i.WarehouseID = &id
+	}
+	return i, nil
+}
+
+func (r *repository) Dispatch(ctx context.Context, id, carrierID, warehouseID int) (domain.ShipmentEvent, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.ShipmentEvent{}, err
+	}
+	defer tx.Rollback()
+
+	if err := exists(ctx, tx, "carriers", carrierID, ErrCarrierNotFound); err != nil {
+		return domain.ShipmentEvent{}, err
+	}
+	if err := exists(ctx, tx, "warehouses", warehouseID, ErrWarehouseNotFound); err != nil {
+		return domain.ShipmentEvent{}, err
+	}
+
+	query := "UPDATE purchase_orders SET carrier_id = ?, warehouse_id = ? WHERE id = ?;"
+	if _, err := tx.ExecContext(ctx, query, carrierID, warehouseID, id); err != nil {
+		if strings.HasPrefix(err.Error(), "Error 1452") {
+			return domain.ShipmentEvent{}, missingReference(err)
+		}
+		return domain.ShipmentEvent{}, err
+	}
+
+	e, err := insertShipmentEvent(ctx, tx, id, domain.ShipmentPicked)
+	if err != nil {
+		return domain.ShipmentEvent{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return domain.ShipmentEvent{}, err
+	}
+	return e, nil
+}
+
+func (r *repository) AddShipmentEvent(ctx context.Context, id int, status string) (domain.ShipmentEvent, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.ShipmentEvent{}, err
+	}
+	defer tx.Rollback()
+
+	e, err := insertShipmentEvent(ctx, tx, id, status)
+	if err != nil {
+		return domain.ShipmentEvent{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return domain.ShipmentEvent{}, err
+	}
+	return e, nil
+}
+
+// insertShipmentEvent records a shipment event of an order. Since an
+// order has at most one event per status, a duplicate means the event
+// was recorded concurrently.
+func insertShipmentEvent(ctx context.Context, tx *sql.Tx, id int, status string) (domain.ShipmentEvent, error) {
+	e := domain.ShipmentEvent{
+		PurchaseOrderID: id,
+		Status:          status,
+		CreatedAt:       time.Now().UTC(),
+	}
+
+	query := "INSERT INTO shipment_events(purchase_order_id,status,created_at) VALUES (?,?,?)"
+	res, err := tx.ExecContext(ctx, query, e.PurchaseOrderID, e.Status, e.CreatedAt)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "Error 1062") {
+			return domain.ShipmentEvent{}, ErrInvalidTransition
+		}
+		return domain.ShipmentEvent{}, err
+	}
+
+	eventID, err := res.LastInsertId()
+	if err != nil {
+		return domain.ShipmentEvent{}, err
+	}
+	e.ID = int(eventID)
+	return e, nil
+}
+
+func (r *repository) ShipmentEvents(ctx context.Context, id int) ([]domain.ShipmentEvent, error) {
+	query := "SELECT id, purchase_order_id, status, created_at FROM shipment_events WHERE purchase_order_id = ? ORDER BY created_at, id;"
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []domain.ShipmentEvent{}
+	for rows.Next() {
+		e := domain.ShipmentEvent{}
+		if err := rows.Scan(&e.ID, &e.PurchaseOrderID, &e.Status, sqlutil.Time(&e.CreatedAt)); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// exists returns notFound unless the row of table with the given ID
+// exists and is not deleted, locking it until the end of tx.
+func exists(ctx context.Context, tx *sql.Tx, table string, id int, notFound error) error {
+	var found int
+	err := tx.QueryRowContext(ctx, "SELECT id FROM "+table+" WHERE id = ? AND deleted_at IS NULL FOR SHARE;", id).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound
+	}
+	return err
+}
+
+// missingReference tells apart the foreign keys of purchase_orders
+// by the name of the constraint that failed.
+func missingReference(err error) error {
+	switch {
+	case strings.Contains(err.Error(), "fk_carrier_purchase_orders"):
+		return ErrCarrierNotFound
+	case strings.Contains(err.Error(), "fk_warehouse_purchase_orders"):
+		return ErrWarehouseNotFound
+	}
+	return ErrFKNotFound
+}
@@ -53,3 +53,76 @@ func TestCreate(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestShipmentEvents(t *testing.T) {
+	t.Run("Dispatches an order and records its events", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := purchaseorder.NewRepository(db)
+
+		e, err := repo.Dispatch(context.TODO(), 2, 1, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.ShipmentPicked, e.Status)
+
+		_, err = repo.AddShipmentEvent(context.TODO(), 2, domain.ShipmentInTransit)
+		assert.NoError(t, err)
+
+		order, err := repo.GetByTrackingCode(context.TODO(), "TRACK002")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, *order.CarrierID)
+		assert.Equal(t, 1, *order.WarehouseID)
+
+		events, err := repo.ShipmentEvents(context.TODO(), 2)
+		assert.NoError(t, err)
+		assert.Len(t, events, 2)
+		assert.Equal(t, domain.ShipmentInTransit, events[1].Status)
+	})
+	t.Run("Does not record a status twice", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := purchaseorder.NewRepository(db)
+
+		_, err := repo.AddShipmentEvent(context.TODO(), 1, domain.ShipmentPicked)
+		assert.ErrorIs(t, err, purchaseorder.ErrInvalidTransition)
+	})
+	t.Run("Does not dispatch with an unknown carrier", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := purchaseorder.NewRepository(db)
+
+		_, err := repo.Dispatch(context.TODO(), 2, 9999, 1)
+		assert.ErrorIs(t, err, purchaseorder.ErrCarrierNotFound)
+	})
+	t.Run("Does not dispatch with a deleted carrier or warehouse", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := purchaseorder.NewRepository(db)
+
+		_, err := db.Exec("UPDATE warehouses SET deleted_at = NOW() WHERE id = 1;")
+		assert.NoError(t, err)
+		_, err = repo.Dispatch(context.TODO(), 2, 1, 1)
+		assert.ErrorIs(t, err, purchaseorder.ErrWarehouseNotFound)
+
+		_, err = db.Exec("UPDATE carriers SET deleted_at = NOW() WHERE id = 1;")
+		assert.NoError(t, err)
+		_, err = repo.Dispatch(context.TODO(), 2, 1, 1)
+		assert.ErrorIs(t, err, purchaseorder.ErrCarrierNotFound)
+
+		events, err := repo.ShipmentEvents(context.TODO(), 2)
+		assert.NoError(t, err)
+		assert.Empty(t, events)
+	})
+	t.Run("Returns not found for an unknown tracking code", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := purchaseorder.NewRepository(db)
+
+		_, err := repo.GetByTrackingCode(context.TODO(), "NOPE")
+		assert.ErrorIs(t, err, purchaseorder.ErrNotFound)
+	})
+}
@@ -3,9 +3,13 @@ package purchaseorder
 import (
 	"context"
 	"errors"
+	"math"
 	"time"
 
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/carrier"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/product"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
 )
 
 var (
@@ -13,43 +17,104 @@ var (
 	ErrInternalServerError     = errors.New("internal server error")
 	ErrFKNotFound              = errors.New("buyer_id or order_status_id not found")
 	ErrProductRecordIDNotFound = errors.New("product_record_id not found")
+	ErrNoPriceAtDate           = errors.New("product_id has no price in effect at order_date")
+	ErrInvalidQuantity         = errors.New("quantity must be greater than zero")
+	ErrNotFound                = errors.New("purchase order not found")
+	ErrTrackingCodeExists      = errors.New("tracking_code already exists")
+	ErrCarrierNotFound         = errors.New("carrier_id not found")
+	ErrWarehouseNotFound       = errors.New("warehouse_id not found")
+	ErrAlreadyDispatched       = errors.New("purchase order was already dispatched")
+	ErrNotDispatched           = errors.New("purchase order was not dispatched")
+	ErrInvalidShipmentStatus   = errors.New("status must be one of picked, in_transit or delivered")
+	ErrInvalidTransition       = errors.New("shipment events must be recorded in order: picked, in_transit, delivered")
+	ErrNoWarehouse             = errors.New("warehouse_id is required until the purchase order is dispatched")
 )
 
+// PriceRecords is the part of the product repository
+// used to price the line of an order.
+type PriceRecords interface {
+	GetRecordsbyProd(ctx context.Context, id int) ([]domain.Product_Records, error)
+	GetRecord(ctx context.Context, id int) (domain.Product_Records, error)
+}
+
+// CarrierCoverage is the part of the carrier repository
+// used to recommend carriers for an order.
+type CarrierCoverage interface {
+	Coverage(ctx context.Context, warehouseID int) ([]domain.CarrierCandidate, error)
+}
+
 type PurchaseOrderDTO struct {
-	ID              int
-	OrderNumber     string
-	OrderDate       time.Time
+	ID          int
+	OrderNumber string
+	OrderDate   time.Time
+	// DateOnly tells that OrderDate has no time of day, so prices
+	// set at any time of that day are in effect for the order.
+	DateOnly        bool
 	TrackingCode    string
 	BuyerID         int
 	ProductRecordID int
 	OrderStatusID   int
+	// If ProductID is set, ProductRecordID is ignored and the
+	// record in effect at OrderDate is used instead.
+	ProductID   int
+	Quantity    int
+	CarrierID   *int
+	WarehouseID *int
 }
 
 type Service interface {
 	Create(c context.Context, purchaseOrder PurchaseOrderDTO) (domain.PurchaseOrder, error)
+	// Dispatch assigns the carrier and source warehouse of an order,
+	// and records it as picked. An order is dispatched only once.
+	Dispatch(c context.Context, id, carrierID, warehouseID int) (domain.Shipment, error)
+	// RecordShipmentEvent records the next status of the shipment
+	// with the given tracking code.
+	RecordShipmentEvent(c context.Context, trackingCode, status string) (domain.Shipment, error)
+	Track(c context.Context, trackingCode string) (domain.Shipment, error)
+	// RecommendCarriers ranks the carriers that serve the source
+	// warehouse of an order: first those in its locality, then in
+	// its province, then in its country. The warehouse the order was
+	// dispatched from is used, unless warehouseID is given.
+	RecommendCarriers(c context.Context, id int, warehouseID optional.Opt[int]) (domain.CarrierRecommendation, error)
 }
 
 type service struct {
-	repo Repository
+	repo     Repository
+	prices   PriceRecords
+	carriers CarrierCoverage
 }
 
-func NewService(repo Repository) Service {
-	return &service{repo}
+func NewService(repo Repository, prices PriceRecords, carriers CarrierCoverage) Service {
+	return &service{repo, prices, carriers}
 }
 
 func (s *service) Create(c context.Context, purchaseOrder PurchaseOrderDTO) (domain.PurchaseOrder, error) {
+	if purchaseOrder.Quantity <= 0 {
+		return domain.PurchaseOrder{}, ErrInvalidQuantity
+	}
 	if s.repo.Exists(c, purchaseOrder.OrderNumber) {
 		return domain.PurchaseOrder{}, ErrAlreadyExists
 	}
 
+	record, err := s.priceRecord(c, purchaseOrder)
+	if err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+
 	i := MapPurchaseOrderDTOToDomain(&purchaseOrder)
+	i.ProductRecordID = record.ID
+	i.UnitPrice = record.SalePrice
+	i.Total = math.Round(record.SalePrice*float64(i.Quantity)*100) / 100
+
 	id, err := s.repo.Create(c, i)
 	if err != nil {
-		if errors.Is(err, ErrFKNotFound) {
-			return domain.PurchaseOrder{}, ErrFKNotFound
-		}
-		if errors.Is(err, ErrProductRecordIDNotFound) {
-			return domain.PurchaseOrder{}, ErrProductRecordIDNotFound
+		switch {
+		case errors.Is(err, ErrFKNotFound),
+			errors.Is(err, ErrProductRecordIDNotFound),
+			errors.Is(err, ErrCarrierNotFound),
+			errors.Is(err, ErrWarehouseNotFound),
+			errors.Is(err, ErrTrackingCodeExists):
+			return domain.PurchaseOrder{}, err
 		}
 		return domain.PurchaseOrder{}, ErrInternalServerError
 	}
@@ -58,6 +123,174 @@ func (s *service) Create(c context.Context, purchaseOrder PurchaseOrderDTO) (dom
 	return i, nil
 }
 
+func (s *service) Dispatch(c context.Context, id, carrierID, warehouseID int) (domain.Shipment, error) {
+	order, err := s.repo.Get(c, id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return domain.Shipment{}, ErrNotFound
+		}
+		return domain.Shipment{}, ErrInternalServerError
+	}
+
+	events, err := s.repo.ShipmentEvents(c, id)
+	if err != nil {
+		return domain.Shipment{}, ErrInternalServerError
+	}
+	if len(events) > 0 {
+		return domain.Shipment{}, ErrAlreadyDispatched
+	}
+
+	e, err := s.repo.Dispatch(c, id, carrierID, warehouseID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrCarrierNotFound),
+			errors.Is(err, ErrWarehouseNotFound):
+			return domain.Shipment{}, err
+		case errors.Is(err, ErrInvalidTransition):
+			return domain.Shipment{}, ErrAlreadyDispatched
+		}
+		return domain.Shipment{}, ErrInternalServerError
+	}
+
+	order.CarrierID = &carrierID
+	order.WarehouseID = &warehouseID
+	return shipment(order, []domain.ShipmentEvent{e}), nil
+}
+
+func (s *service) RecordShipmentEvent(c context.Context, trackingCode, status string) (domain.Shipment, error) {
+	if !validShipmentStatus(status) {
+		return domain.Shipment{}, ErrInvalidShipmentStatus
+	}
+
+	current, err := s.Track(c, trackingCode)
+	if err != nil {
+		return domain.Shipment{}, err
+	}
+	if len(current.Events) == 0 {
+		return domain.Shipment{}, ErrNotDispatched
+	}
+	if len(current.Events) == len(domain.ShipmentStatuses) ||
+		domain.ShipmentStatuses[len(current.Events)] != status {
+		return domain.Shipment{}, ErrInvalidTransition
+	}
+
+	e, err := s.repo.AddShipmentEvent(c, current.PurchaseOrderID, status)
+	if err != nil {
+		if errors.Is(err, ErrInvalidTransition) {
+			return domain.Shipment{}, ErrInvalidTransition
+		}
+		return domain.Shipment{}, ErrInternalServerError
+	}
+
+	current.Events = append(current.Events, e)
+	current.Status = e.Status
+	return current, nil
+}
+
+func (s *service) Track(c context.Context, trackingCode string) (domain.Shipment, error) {
+	order, err := s.repo.GetByTrackingCode(c, trackingCode)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return domain.Shipment{}, ErrNotFound
+		}
+		return domain.Shipment{}, ErrInternalServerError
+	}
+
+	events, err := s.repo.ShipmentEvents(c, order.ID)
+	if err != nil {
+		return domain.Shipment{}, ErrInternalServerError
+	}
+	return shipment(order, events), nil
+}
+
+func (s *service) RecommendCarriers(c context.Context, id int, warehouseID optional.Opt[int]) (domain.CarrierRecommendation, error) {
+	order, err := s.repo.Get(c, id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return domain.CarrierRecommendation{}, ErrNotFound
+		}
+		return domain.CarrierRecommendation{}, ErrInternalServerError
+	}
+
+	source, ok := warehouseID.Value()
+	if !ok && order.WarehouseID != nil {
+		source, ok = *order.WarehouseID, true
+	}
+	if !ok {
+		return domain.CarrierRecommendation{}, ErrNoWarehouse
+	}
+
+	candidates, err := s.carriers.Coverage(c, source)
+	if err != nil {
+		if errors.Is(err, carrier.ErrWarehouseNotFound) {
+			return domain.CarrierRecommendation{}, ErrWarehouseNotFound
+		}
+		return domain.CarrierRecommendation{}, ErrInternalServerError
+	}
+
+	return domain.CarrierRecommendation{
+		PurchaseOrderID: order.ID,
+		WarehouseID:     source,
+		Candidates:      carrier.Rank(candidates),
+	}, nil
+}
+
+// shipment builds the tracking view of an order from its events,
+// given oldest first.
+func shipment(order domain.PurchaseOrder, events []domain.ShipmentEvent) domain.Shipment {
+	sh := domain.Shipment{
+		PurchaseOrderID: order.ID,
+		OrderNumber:     order.OrderNumber,
+		TrackingCode:    order.TrackingCode,
+		CarrierID:       order.CarrierID,
+		WarehouseID:     order.WarehouseID,
+		Events:          events,
+	}
+	if len(events) > 0 {
+		sh.Status = events[len(events)-1].Status
+	}
+	return sh
+}
+
+func validShipmentStatus(status string) bool {
+	for _, s := range domain.ShipmentStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// priceRecord returns the product record that sets the price of the
+// order: either the one given, or the one in effect at the order date.
+func (s *service) priceRecord(c context.Context, purchaseOrder PurchaseOrderDTO) (domain.Product_Records, error) {
+	if purchaseOrder.ProductID != 0 {
+		records, err := s.prices.GetRecordsbyProd(c, purchaseOrder.ProductID)
+		if err != nil {
+			return domain.Product_Records{}, ErrInternalServerError
+		}
+		at := purchaseOrder.OrderDate
+		if purchaseOrder.DateOnly {
+			at = at.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		record, ok := product.EffectiveRecord(records, at)
+		if !ok {
+			return domain.Product_Records{}, ErrNoPriceAtDate
+		}
+		return record, nil
+	}
+
+	record, err := s.prices.GetRecord(c, purchaseOrder.ProductRecordID)
+	if err != nil {
+		var notFound *product.ErrNotFound
+		if errors.As(err, &notFound) {
+			return domain.Product_Records{}, ErrProductRecordIDNotFound
+		}
+		return domain.Product_Records{}, ErrInternalServerError
+	}
+	return record, nil
+}
+
 func MapPurchaseOrderDTOToDomain(purchaseOrder *PurchaseOrderDTO) domain.PurchaseOrder {
 	return domain.PurchaseOrder{
 		OrderNumber:     purchaseOrder.OrderNumber,
@@ -66,5 +299,8 @@ func MapPurchaseOrderDTOToDomain(purchaseOrder *PurchaseOrderDTO) domain.Purchas
 		BuyerID:         purchaseOrder.BuyerID,
 		ProductRecordID: purchaseOrder.ProductRecordID,
 		OrderStatusID:   purchaseOrder.OrderStatusID,
+		Quantity:        purchaseOrder.Quantity,
+		CarrierID:       purchaseOrder.CarrierID,
+		WarehouseID:     purchaseOrder.WarehouseID,
 	}
 }
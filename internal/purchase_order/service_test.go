@@ -5,8 +5,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/carrier"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/product"
 	purchaseOrder "github.com/extmatperez/meli_bootcamp_go_w2-4/internal/purchase_order"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/optional"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -14,7 +17,9 @@ import (
 func TestCreatePurchaseOrder(t *testing.T) {
 	t.Run("if fields are correct should create a purchase order", func(t *testing.T) {
 		mockedRepository := RepositoryMock{}
-		s := purchaseOrder.NewService(&mockedRepository)
+		mockedPrices := PriceRecordsMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &mockedPrices, &CarrierCoverageMock{})
+		mockedPrices.On("GetRecord", mock.Anything, 1).Return(getTestPriceRecords()[0], nil)
 
 		p := purchaseOrder.PurchaseOrderDTO{
 			ID:              1,
@@ -24,9 +29,11 @@ func TestCreatePurchaseOrder(t *testing.T) {
 			BuyerID:         1,
 			ProductRecordID: 1,
 			OrderStatusID:   1,
+			Quantity:        1,
 		}
 
 		expected := purchaseOrder.MapPurchaseOrderDTOToDomain(&p)
+		expected.UnitPrice, expected.Total = 20, 20
 		mockedRepository.On("Exists", mock.Anything, p.OrderNumber).Return(false)
 		mockedRepository.On("Create", mock.Anything, expected).Return(1, nil)
 
@@ -38,7 +45,9 @@ func TestCreatePurchaseOrder(t *testing.T) {
 	})
 	t.Run("if order number already exist", func(t *testing.T) {
 		mockedRepository := RepositoryMock{}
-		s := purchaseOrder.NewService(&mockedRepository)
+		mockedPrices := PriceRecordsMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &mockedPrices, &CarrierCoverageMock{})
+		mockedPrices.On("GetRecord", mock.Anything, 1).Return(getTestPriceRecords()[0], nil)
 
 		p := purchaseOrder.PurchaseOrderDTO{
 			ID:              1,
@@ -48,6 +57,7 @@ func TestCreatePurchaseOrder(t *testing.T) {
 			BuyerID:         1,
 			ProductRecordID: 1,
 			OrderStatusID:   1,
+			Quantity:        1,
 		}
 
 		mockedRepository.On("Exists", mock.Anything, p.OrderNumber).Return(true)
@@ -57,7 +67,9 @@ func TestCreatePurchaseOrder(t *testing.T) {
 	})
 	t.Run("if one of the foreign keys are not found", func(t *testing.T) {
 		mockedRepository := RepositoryMock{}
-		s := purchaseOrder.NewService(&mockedRepository)
+		mockedPrices := PriceRecordsMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &mockedPrices, &CarrierCoverageMock{})
+		mockedPrices.On("GetRecord", mock.Anything, 1).Return(getTestPriceRecords()[0], nil)
 
 		p := purchaseOrder.PurchaseOrderDTO{
 			ID:              1,
@@ -67,9 +79,11 @@ func TestCreatePurchaseOrder(t *testing.T) {
 			BuyerID:         1,
 			ProductRecordID: 1,
 			OrderStatusID:   1,
+			Quantity:        1,
 		}
 
 		expected := purchaseOrder.MapPurchaseOrderDTOToDomain(&p)
+		expected.UnitPrice, expected.Total = 20, 20
 		mockedRepository.On("Exists", mock.Anything, p.OrderNumber).Return(false)
 		mockedRepository.On("Create", mock.Anything, expected).Return(0, purchaseOrder.ErrFKNotFound)
 
@@ -79,7 +93,9 @@ func TestCreatePurchaseOrder(t *testing.T) {
 	})
 	t.Run("if product record is not found", func(t *testing.T) {
 		mockedRepository := RepositoryMock{}
-		s := purchaseOrder.NewService(&mockedRepository)
+		mockedPrices := PriceRecordsMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &mockedPrices, &CarrierCoverageMock{})
+		mockedPrices.On("GetRecord", mock.Anything, 1).Return(getTestPriceRecords()[0], nil)
 
 		p := purchaseOrder.PurchaseOrderDTO{
 			ID:              1,
@@ -89,9 +105,11 @@ func TestCreatePurchaseOrder(t *testing.T) {
 			BuyerID:         1,
 			ProductRecordID: 1,
 			OrderStatusID:   1,
+			Quantity:        1,
 		}
 
 		expected := purchaseOrder.MapPurchaseOrderDTOToDomain(&p)
+		expected.UnitPrice, expected.Total = 20, 20
 		mockedRepository.On("Exists", mock.Anything, p.OrderNumber).Return(false)
 		mockedRepository.On("Create", mock.Anything, expected).Return(0, purchaseOrder.ErrProductRecordIDNotFound)
 
@@ -101,7 +119,9 @@ func TestCreatePurchaseOrder(t *testing.T) {
 	})
 	t.Run("if internal server error occurs", func(t *testing.T) {
 		mockedRepository := RepositoryMock{}
-		s := purchaseOrder.NewService(&mockedRepository)
+		mockedPrices := PriceRecordsMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &mockedPrices, &CarrierCoverageMock{})
+		mockedPrices.On("GetRecord", mock.Anything, 1).Return(getTestPriceRecords()[0], nil)
 
 		p := purchaseOrder.PurchaseOrderDTO{
 			ID:              1,
@@ -111,9 +131,11 @@ func TestCreatePurchaseOrder(t *testing.T) {
 			BuyerID:         1,
 			ProductRecordID: 1,
 			OrderStatusID:   1,
+			Quantity:        1,
 		}
 
 		expected := purchaseOrder.MapPurchaseOrderDTOToDomain(&p)
+		expected.UnitPrice, expected.Total = 20, 20
 		mockedRepository.On("Exists", mock.Anything, p.OrderNumber).Return(false)
 		mockedRepository.On("Create", mock.Anything, expected).Return(0, purchaseOrder.ErrInternalServerError)
 
@@ -123,6 +145,335 @@ func TestCreatePurchaseOrder(t *testing.T) {
 	})
 }
 
+func TestCreatePricedPurchaseOrder(t *testing.T) {
+	t.Run("uses the price in effect at the order date", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		mockedPrices := PriceRecordsMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &mockedPrices, &CarrierCoverageMock{})
+
+		p := purchaseOrder.PurchaseOrderDTO{
+			OrderNumber:   "125",
+			OrderDate:     time.Date(2022, 2, 15, 0, 0, 0, 0, time.UTC),
+			TrackingCode:  "124",
+			BuyerID:       1,
+			ProductID:     3,
+			Quantity:      3,
+			OrderStatusID: 1,
+		}
+
+		mockedRepository.On("Exists", mock.Anything, p.OrderNumber).Return(false)
+		mockedRepository.On("Create", mock.Anything, mock.Anything).Return(1, nil)
+		mockedPrices.On("GetRecordsbyProd", mock.Anything, 3).Return(getTestPriceRecords(), nil)
+
+		order, err := s.Create(context.TODO(), p)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, order.ProductRecordID)
+		assert.Equal(t, 25.1, order.UnitPrice)
+		assert.Equal(t, 75.3, order.Total)
+		mockedRepository.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(o domain.PurchaseOrder) bool {
+			return o.ProductRecordID == 2 && o.Quantity == 3
+		}))
+	})
+	t.Run("uses a price set later on a plain order date", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		mockedPrices := PriceRecordsMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &mockedPrices, &CarrierCoverageMock{})
+
+		p := purchaseOrder.PurchaseOrderDTO{
+			OrderNumber:   "125",
+			OrderDate:     time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC),
+			DateOnly:      true,
+			ProductID:     3,
+			Quantity:      1,
+			OrderStatusID: 1,
+		}
+		records := append(getTestPriceRecords(),
+			domain.Product_Records{ID: 4, LastUpdateDate: time.Date(2022, 3, 1, 15, 30, 0, 0, time.UTC), PurchasePrice: 12, SalePrice: 24, ProductID: 3})
+
+		mockedRepository.On("Exists", mock.Anything, p.OrderNumber).Return(false)
+		mockedRepository.On("Create", mock.Anything, mock.Anything).Return(1, nil)
+		mockedPrices.On("GetRecordsbyProd", mock.Anything, 3).Return(records, nil)
+
+		order, err := s.Create(context.TODO(), p)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, order.ProductRecordID)
+		assert.Equal(t, p.OrderDate, order.OrderDate)
+
+		p.DateOnly = false
+		order, err = s.Create(context.TODO(), p)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, order.ProductRecordID)
+	})
+	t.Run("if the product has no price at the order date", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		mockedPrices := PriceRecordsMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &mockedPrices, &CarrierCoverageMock{})
+
+		p := purchaseOrder.PurchaseOrderDTO{
+			OrderNumber:   "125",
+			OrderDate:     time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			ProductID:     3,
+			Quantity:      1,
+			OrderStatusID: 1,
+		}
+
+		mockedRepository.On("Exists", mock.Anything, p.OrderNumber).Return(false)
+		mockedPrices.On("GetRecordsbyProd", mock.Anything, 3).Return(getTestPriceRecords(), nil)
+
+		_, err := s.Create(context.TODO(), p)
+		assert.ErrorIs(t, err, purchaseOrder.ErrNoPriceAtDate)
+		mockedRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+	t.Run("if the product record does not exist", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		mockedPrices := PriceRecordsMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &mockedPrices, &CarrierCoverageMock{})
+
+		p := purchaseOrder.PurchaseOrderDTO{
+			OrderNumber:     "125",
+			OrderDate:       time.Date(2022, 2, 15, 0, 0, 0, 0, time.UTC),
+			ProductRecordID: 9,
+			Quantity:        1,
+		}
+
+		mockedRepository.On("Exists", mock.Anything, p.OrderNumber).Return(false)
+		mockedPrices.On("GetRecord", mock.Anything, 9).Return(domain.Product_Records{}, product.NewErrNotFound(9))
+
+		_, err := s.Create(context.TODO(), p)
+		assert.ErrorIs(t, err, purchaseOrder.ErrProductRecordIDNotFound)
+	})
+	t.Run("if quantity is not positive", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		mockedPrices := PriceRecordsMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &mockedPrices, &CarrierCoverageMock{})
+
+		_, err := s.Create(context.TODO(), purchaseOrder.PurchaseOrderDTO{ProductID: 3})
+		assert.ErrorIs(t, err, purchaseOrder.ErrInvalidQuantity)
+	})
+}
+
+func getTestPriceRecords() []domain.Product_Records {
+	return []domain.Product_Records{
+		{ID: 1, LastUpdateDate: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), PurchasePrice: 10, SalePrice: 20, ProductID: 3},
+		{ID: 3, LastUpdateDate: time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC), PurchasePrice: 12, SalePrice: 23, ProductID: 3},
+		{ID: 2, LastUpdateDate: time.Date(2022, 2, 10, 0, 0, 0, 0, time.UTC), PurchasePrice: 12, SalePrice: 25.1, ProductID: 3},
+	}
+}
+
+func TestShipment(t *testing.T) {
+	order := domain.PurchaseOrder{ID: 1, OrderNumber: "PO001", TrackingCode: "TRACK001"}
+	picked := domain.ShipmentEvent{ID: 1, PurchaseOrderID: 1, Status: domain.ShipmentPicked}
+	inTransit := domain.ShipmentEvent{ID: 2, PurchaseOrderID: 1, Status: domain.ShipmentInTransit}
+	delivered := domain.ShipmentEvent{ID: 3, PurchaseOrderID: 1, Status: domain.ShipmentDelivered}
+
+	t.Run("dispatch assigns carrier and warehouse and records pickup", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &PriceRecordsMock{}, &CarrierCoverageMock{})
+		mockedRepository.On("Get", mock.Anything, 1).Return(order, nil)
+		mockedRepository.On("ShipmentEvents", mock.Anything, 1).Return([]domain.ShipmentEvent{}, nil)
+		mockedRepository.On("Dispatch", mock.Anything, 1, 2, 3).Return(picked, nil)
+
+		result, err := s.Dispatch(context.TODO(), 1, 2, 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, *result.CarrierID)
+		assert.Equal(t, 3, *result.WarehouseID)
+		assert.Equal(t, domain.ShipmentPicked, result.Status)
+		assert.Equal(t, []domain.ShipmentEvent{picked}, result.Events)
+	})
+	t.Run("dispatch fails if the order was already dispatched", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &PriceRecordsMock{}, &CarrierCoverageMock{})
+		mockedRepository.On("Get", mock.Anything, 1).Return(order, nil)
+		mockedRepository.On("ShipmentEvents", mock.Anything, 1).Return([]domain.ShipmentEvent{picked}, nil)
+
+		_, err := s.Dispatch(context.TODO(), 1, 2, 3)
+
+		assert.ErrorIs(t, err, purchaseOrder.ErrAlreadyDispatched)
+		mockedRepository.AssertNumberOfCalls(t, "Dispatch", 0)
+	})
+	t.Run("dispatch fails if the carrier does not exist", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &PriceRecordsMock{}, &CarrierCoverageMock{})
+		mockedRepository.On("Get", mock.Anything, 1).Return(order, nil)
+		mockedRepository.On("ShipmentEvents", mock.Anything, 1).Return([]domain.ShipmentEvent{}, nil)
+		mockedRepository.On("Dispatch", mock.Anything, 1, 2, 3).Return(domain.ShipmentEvent{}, purchaseOrder.ErrCarrierNotFound)
+
+		_, err := s.Dispatch(context.TODO(), 1, 2, 3)
+
+		assert.ErrorIs(t, err, purchaseOrder.ErrCarrierNotFound)
+	})
+	t.Run("dispatch fails if the order does not exist", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &PriceRecordsMock{}, &CarrierCoverageMock{})
+		mockedRepository.On("Get", mock.Anything, 1).Return(domain.PurchaseOrder{}, purchaseOrder.ErrNotFound)
+
+		_, err := s.Dispatch(context.TODO(), 1, 2, 3)
+
+		assert.ErrorIs(t, err, purchaseOrder.ErrNotFound)
+	})
+	t.Run("records the next shipment event", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &PriceRecordsMock{}, &CarrierCoverageMock{})
+		mockedRepository.On("GetByTrackingCode", mock.Anything, "TRACK001").Return(order, nil)
+		mockedRepository.On("ShipmentEvents", mock.Anything, 1).Return([]domain.ShipmentEvent{picked}, nil)
+		mockedRepository.On("AddShipmentEvent", mock.Anything, 1, domain.ShipmentInTransit).Return(inTransit, nil)
+
+		result, err := s.RecordShipmentEvent(context.TODO(), "TRACK001", domain.ShipmentInTransit)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.ShipmentInTransit, result.Status)
+		assert.Equal(t, []domain.ShipmentEvent{picked, inTransit}, result.Events)
+	})
+	t.Run("does not skip shipment statuses", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &PriceRecordsMock{}, &CarrierCoverageMock{})
+		mockedRepository.On("GetByTrackingCode", mock.Anything, "TRACK001").Return(order, nil)
+		mockedRepository.On("ShipmentEvents", mock.Anything, 1).Return([]domain.ShipmentEvent{picked}, nil)
+
+		_, err := s.RecordShipmentEvent(context.TODO(), "TRACK001", domain.ShipmentDelivered)
+
+		assert.ErrorIs(t, err, purchaseOrder.ErrInvalidTransition)
+		mockedRepository.AssertNumberOfCalls(t, "AddShipmentEvent", 0)
+	})
+	t.Run("does not record events after delivery", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &PriceRecordsMock{}, &CarrierCoverageMock{})
+		mockedRepository.On("GetByTrackingCode", mock.Anything, "TRACK001").Return(order, nil)
+		mockedRepository.On("ShipmentEvents", mock.Anything, 1).Return([]domain.ShipmentEvent{picked, inTransit, delivered}, nil)
+
+		_, err := s.RecordShipmentEvent(context.TODO(), "TRACK001", domain.ShipmentDelivered)
+
+		assert.ErrorIs(t, err, purchaseOrder.ErrInvalidTransition)
+	})
+	t.Run("does not record events before dispatch", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &PriceRecordsMock{}, &CarrierCoverageMock{})
+		mockedRepository.On("GetByTrackingCode", mock.Anything, "TRACK001").Return(order, nil)
+		mockedRepository.On("ShipmentEvents", mock.Anything, 1).Return([]domain.ShipmentEvent{}, nil)
+
+		_, err := s.RecordShipmentEvent(context.TODO(), "TRACK001", domain.ShipmentInTransit)
+
+		assert.ErrorIs(t, err, purchaseOrder.ErrNotDispatched)
+	})
+	t.Run("rejects unknown shipment statuses", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &PriceRecordsMock{}, &CarrierCoverageMock{})
+
+		_, err := s.RecordShipmentEvent(context.TODO(), "TRACK001", "lost")
+
+		assert.ErrorIs(t, err, purchaseOrder.ErrInvalidShipmentStatus)
+	})
+	t.Run("tracks the timeline of an order", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &PriceRecordsMock{}, &CarrierCoverageMock{})
+		mockedRepository.On("GetByTrackingCode", mock.Anything, "TRACK001").Return(order, nil)
+		mockedRepository.On("ShipmentEvents", mock.Anything, 1).Return([]domain.ShipmentEvent{picked, inTransit}, nil)
+
+		result, err := s.Track(context.TODO(), "TRACK001")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "PO001", result.OrderNumber)
+		assert.Equal(t, domain.ShipmentInTransit, result.Status)
+		assert.Len(t, result.Events, 2)
+	})
+	t.Run("tracking fails for an unknown tracking code", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &PriceRecordsMock{}, &CarrierCoverageMock{})
+		mockedRepository.On("GetByTrackingCode", mock.Anything, "NOPE").Return(domain.PurchaseOrder{}, purchaseOrder.ErrNotFound)
+
+		_, err := s.Track(context.TODO(), "NOPE")
+
+		assert.ErrorIs(t, err, purchaseOrder.ErrNotFound)
+	})
+}
+
+func TestRecommendCarriers(t *testing.T) {
+	candidates := []domain.CarrierCandidate{
+		{Carrier: domain.Carrier{ID: 1, CompanyName: "Far"}, Coverage: domain.CoverageCountry},
+		{Carrier: domain.Carrier{ID: 2, CompanyName: "Near"}, Coverage: domain.CoverageLocality},
+		{Carrier: domain.Carrier{ID: 3, CompanyName: "Middle"}, Coverage: domain.CoverageProvince},
+	}
+
+	t.Run("ranks carriers by locality, then province, then country", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		mockedCarriers := CarrierCoverageMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &PriceRecordsMock{}, &mockedCarriers)
+		mockedRepository.On("Get", mock.Anything, 1).Return(domain.PurchaseOrder{ID: 1}, nil)
+		mockedCarriers.On("Coverage", mock.Anything, 4).Return(candidates, nil)
+
+		result, err := s.RecommendCarriers(context.TODO(), 1, *optional.FromVal(4))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 4, result.WarehouseID)
+		assert.Len(t, result.Candidates, 3)
+		for i, id := range []int{2, 3, 1} {
+			assert.Equal(t, id, result.Candidates[i].ID)
+			assert.Equal(t, i+1, result.Candidates[i].Rank)
+			assert.NotEmpty(t, result.Candidates[i].Reason)
+		}
+	})
+	t.Run("uses the warehouse the order was dispatched from", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		mockedCarriers := CarrierCoverageMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &PriceRecordsMock{}, &mockedCarriers)
+		warehouseID := 7
+		mockedRepository.On("Get", mock.Anything, 1).Return(domain.PurchaseOrder{ID: 1, WarehouseID: &warehouseID}, nil)
+		mockedCarriers.On("Coverage", mock.Anything, 7).Return([]domain.CarrierCandidate{}, nil)
+
+		result, err := s.RecommendCarriers(context.TODO(), 1, *optional.New[int]())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 7, result.WarehouseID)
+		assert.Empty(t, result.Candidates)
+	})
+	t.Run("requires a warehouse if the order was not dispatched", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		mockedCarriers := CarrierCoverageMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &PriceRecordsMock{}, &mockedCarriers)
+		mockedRepository.On("Get", mock.Anything, 1).Return(domain.PurchaseOrder{ID: 1}, nil)
+
+		_, err := s.RecommendCarriers(context.TODO(), 1, *optional.New[int]())
+
+		assert.ErrorIs(t, err, purchaseOrder.ErrNoWarehouse)
+		mockedCarriers.AssertNumberOfCalls(t, "Coverage", 0)
+	})
+	t.Run("fails if the warehouse does not exist", func(t *testing.T) {
+		mockedRepository := RepositoryMock{}
+		mockedCarriers := CarrierCoverageMock{}
+		s := purchaseOrder.NewService(&mockedRepository, &PriceRecordsMock{}, &mockedCarriers)
+		mockedRepository.On("Get", mock.Anything, 1).Return(domain.PurchaseOrder{ID: 1}, nil)
+		mockedCarriers.On("Coverage", mock.Anything, 9).Return([]domain.CarrierCandidate{}, carrier.ErrWarehouseNotFound)
+
+		_, err := s.RecommendCarriers(context.TODO(), 1, *optional.FromVal(9))
+
+		assert.ErrorIs(t, err, purchaseOrder.ErrWarehouseNotFound)
+	})
+}
+
+type CarrierCoverageMock struct {
+	mock.Mock
+}
+
+func (r *CarrierCoverageMock) Coverage(ctx context.Context, warehouseID int) ([]domain.CarrierCandidate, error) {
+	args := r.Called(ctx, warehouseID)
+	return args.Get(0).([]domain.CarrierCandidate), args.Error(1)
+}
+
+type PriceRecordsMock struct {
+	mock.Mock
+}
+
+func (r *PriceRecordsMock) GetRecordsbyProd(ctx context.Context, id int) ([]domain.Product_Records, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).([]domain.Product_Records), args.Error(1)
+}
+
+func (r *PriceRecordsMock) GetRecord(ctx context.Context, id int) (domain.Product_Records, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).(domain.Product_Records), args.Error(1)
+}
+
 type RepositoryMock struct {
 	mock.Mock
 }
@@ -135,3 +486,28 @@ func (r *RepositoryMock) Exists(ctx context.Context, orderNumber string) bool {
 	args := r.Called(ctx, orderNumber)
 	return args.Get(0).(bool)
 }
+
+func (r *RepositoryMock) Get(ctx context.Context, id int) (domain.PurchaseOrder, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).(domain.PurchaseOrder), args.Error(1)
+}
+
+func (r *RepositoryMock) GetByTrackingCode(ctx context.Context, trackingCode string) (domain.PurchaseOrder, error) {
+	args := r.Called(ctx, trackingCode)
+	return args.Get(0).(domain.PurchaseOrder), args.Error(1)
+}
+
+func (r *RepositoryMock) Dispatch(ctx context.Context, id, carrierID, warehouseID int) (domain.ShipmentEvent, error) {
+	args := r.Called(ctx, id, carrierID, warehouseID)
+	return args.Get(0).(domain.ShipmentEvent), args.Error(1)
+}
+
+func (r *RepositoryMock) AddShipmentEvent(ctx context.Context, id int, status string) (domain.ShipmentEvent, error) {
+	args := r.Called(ctx, id, status)
+	return args.Get(0).(domain.ShipmentEvent), args.Error(1)
+}
+
+func (r *RepositoryMock) ShipmentEvents(ctx context.Context, id int) ([]domain.ShipmentEvent, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).([]domain.ShipmentEvent), args.Error(1)
+}
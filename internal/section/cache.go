@@ -0,0 +1,44 @@
+package section
+
+import (
+	"context"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/cache"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
+)
+
+type cachedRepository struct {
+	Repository
+	sections cache.Cache[int, domain.Section]
+}
+
+// NewCachedRepository decorates r so that Get reads sections through c,
+// unless deleted ones are included. Writes to a section remove it from c.
+func NewCachedRepository(r Repository, c cache.Cache[int, domain.Section]) Repository {
+	return &cachedRepository{r, c}
+}
+
+func (r *cachedRepository) Get(ctx context.Context, id int) (domain.Section, error) {
+	if sqlutil.IncludeDeleted(ctx) {
+		return r.Repository.Get(ctx, id)
+	}
+	return cache.GetOrLoad(ctx, r.sections, id, func() (domain.Section, error) {
+		return r.Repository.Get(ctx, id)
+	})
+}
+
+func (r *cachedRepository) Update(ctx context.Context, s domain.Section) error {
+	defer r.sections.Delete(ctx, s.ID)
+	return r.Repository.Update(ctx, s)
+}
+
+func (r *cachedRepository) Delete(ctx context.Context, id int) error {
+	defer r.sections.Delete(ctx, id)
+	return r.Repository.Delete(ctx, id)
+}
+
+func (r *cachedRepository) Restore(ctx context.Context, id int) error {
+	defer r.sections.Delete(ctx, id)
+	return r.Repository.Restore(ctx, id)
+}
@@ -4,20 +4,39 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 )
 
 // Repository encapsulates the storage of a section.
 type Repository interface {
 	GetAll(ctx context.Context) ([]domain.Section, error)
 	Get(ctx context.Context, id int) (domain.Section, error)
+	// GetByIDs returns the sections with the given ids, in a single
+	// query. Ids of missing sections are skipped.
+	GetByIDs(ctx context.Context, ids []int) ([]domain.Section, error)
 	Exists(ctx context.Context, sectionNumber int) bool
+	// ExistingNumbers returns which of the given section numbers are taken, in a
+	// single query.
+	ExistingNumbers(ctx context.Context, numbers []int) ([]int, error)
 	Save(ctx context.Context, s domain.Section) (int, error)
+	// SaveAll saves sections in a single insert and returns their ids.
+	// If one of them fails, none is saved.
+	SaveAll(ctx context.Context, sections []domain.Section) ([]int, error)
+	// Update saves s unless the section is no longer in s.Version,
+	// in which case it fails with sqlutil.ErrVersionMismatch.
 	Update(ctx context.Context, s domain.Section) error
+	// Delete soft deletes a section,
+	// if it is in one of the versions expected by ctx.
 	Delete(ctx context.Context, id int) error
+	// Restore undoes the deletion of a section.
+	Restore(ctx context.Context, id int) error
 	GetAllReportProducts(ctx context.Context) ([]domain.GetOneData, error)
+	// StreamReportProducts calls fn for the product count of each
+	// section, as it is read from the database.
+	StreamReportProducts(ctx context.Context, fn func(domain.GetOneData) error) error
 }
 
 type repository struct {
@@ -31,7 +50,10 @@ func NewRepository(db *sql.DB) Repository {
 }
 
 func (r *repository) GetAll(ctx context.Context) ([]domain.Section, error) {
-	query := "SELECT * FROM sections;"
+	query := `SELECT id, section_number, current_temperature, minimum_temperature,
+		current_capacity, minimum_capacity, maximum_capacity, warehouse_id,
+		product_type_id, version, deleted_at
+		FROM sections WHERE ` + sqlutil.NotDeleted(ctx, "deleted_at") + ";"
 	rows, err := r.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -41,7 +63,7 @@ func (r *repository) GetAll(ctx context.Context) ([]domain.Section, error) {
 
 	for rows.Next() {
 		s := domain.Section{}
-		_ = rows.Scan(&s.ID, &s.SectionNumber, &s.CurrentTemperature, &s.MinimumTemperature, &s.CurrentCapacity, &s.MinimumCapacity, &s.MaximumCapacity, &s.WarehouseID, &s.ProductTypeID)
+		_ = rows.Scan(&s.ID, &s.SectionNumber, &s.CurrentTemperature, &s.MinimumTemperature, &s.CurrentCapacity, &s.MinimumCapacity, &s.MaximumCapacity, &s.WarehouseID, &s.ProductTypeID, &s.Version, sqlutil.NullTime(&s.DeletedAt))
 		sections = append(sections, s)
 	}
 
@@ -49,10 +71,13 @@ func (r *repository) GetAll(ctx context.Context) ([]domain.Section, error) {
 }
 
 func (r *repository) Get(ctx context.Context, id int) (domain.Section, error) {
-	query := "SELECT * FROM sections WHERE id=?;"
+	query := `SELECT id, section_number, current_temperature, minimum_temperature,
+		current_capacity, minimum_capacity, maximum_capacity, warehouse_id,
+		product_type_id, version, deleted_at
+		FROM sections WHERE id=? AND ` + sqlutil.NotDeleted(ctx, "deleted_at") + ";"
 	row := r.db.QueryRow(query, id)
 	s := domain.Section{}
-	err := row.Scan(&s.ID, &s.SectionNumber, &s.CurrentTemperature, &s.MinimumTemperature, &s.CurrentCapacity, &s.MinimumCapacity, &s.MaximumCapacity, &s.WarehouseID, &s.ProductTypeID)
+	err := row.Scan(&s.ID, &s.SectionNumber, &s.CurrentTemperature, &s.MinimumTemperature, &s.CurrentCapacity, &s.MinimumCapacity, &s.MaximumCapacity, &s.WarehouseID, &s.ProductTypeID, &s.Version, sqlutil.NullTime(&s.DeletedAt))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.Section{}, ErrNotFound
@@ -63,6 +88,33 @@ func (r *repository) Get(ctx context.Context, id int) (domain.Section, error) {
 	return s, nil
 }
 
+func (r *repository) GetByIDs(ctx context.Context, ids []int) ([]domain.Section, error) {
+	if len(ids) == 0 {
+		return []domain.Section{}, nil
+	}
+	in, args := sqlutil.In(ids)
+	query := `SELECT id, section_number, current_temperature, minimum_temperature,
+		current_capacity, minimum_capacity, maximum_capacity, warehouse_id,
+		product_type_id, version, deleted_at
+		FROM sections WHERE id IN ` + in + ` AND ` + sqlutil.NotDeleted(ctx, "deleted_at") + ";"
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sections := []domain.Section{}
+	for rows.Next() {
+		s := domain.Section{}
+		if err := rows.Scan(&s.ID, &s.SectionNumber, &s.CurrentTemperature, &s.MinimumTemperature, &s.CurrentCapacity, &s.MinimumCapacity, &s.MaximumCapacity, &s.WarehouseID, &s.ProductTypeID, &s.Version, sqlutil.NullTime(&s.DeletedAt)); err != nil {
+			return nil, err
+		}
+		sections = append(sections, s)
+	}
+
+	return sections, rows.Err()
+}
+
 func (r *repository) Exists(ctx context.Context, sectionNumber int) bool {
 	query := "SELECT section_number FROM sections WHERE section_number=?;"
 	row := r.db.QueryRow(query, sectionNumber)
@@ -70,6 +122,10 @@ func (r *repository) Exists(ctx context.Context, sectionNumber int) bool {
 	return err == nil
 }
 
+func (r *repository) ExistingNumbers(ctx context.Context, numbers []int) ([]int, error) {
+	return sqlutil.ExistingKeys(ctx, r.db, "sections", "section_number", numbers)
+}
+
 func (r *repository) Save(ctx context.Context, s domain.Section) (int, error) {
 	query := `INSERT INTO sections
 		(section_number, current_temperature, minimum_temperature,
@@ -94,17 +150,49 @@ func (r *repository) Save(ctx context.Context, s domain.Section) (int, error) {
 	return int(id), nil
 }
 
+func (r *repository) SaveAll(ctx context.Context, sections []domain.Section) ([]int, error) {
+	query := `INSERT INTO sections
+		(section_number, current_temperature, minimum_temperature,
+		current_capacity, minimum_capacity, maximum_capacity,
+		warehouse_id, product_type_id)
+		VALUES ` + sqlutil.Rows(len(sections), 8) + ";"
+	args := make([]any, 0, len(sections)*8)
+	for _, s := range sections {
+		args = append(args, s.SectionNumber, s.CurrentTemperature, s.MinimumTemperature, s.CurrentCapacity, s.MinimumCapacity, s.MaximumCapacity, s.WarehouseID, s.ProductTypeID)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return nil, err
+	}
+
+	keys := make([]int, len(sections))
+	for i, s := range sections {
+		keys[i] = s.SectionNumber
+	}
+	ids, err := sqlutil.IDsByKey(ctx, tx, "sections", "section_number", keys)
+	if err != nil {
+		return nil, err
+	}
+	return ids, tx.Commit()
+}
+
 func (r *repository) Update(ctx context.Context, s domain.Section) error {
 	query := `UPDATE sections SET section_number=?, current_temperature=?,
 		minimum_temperature=?, current_capacity=?, minimum_capacity=?,
-		maximum_capacity=?, warehouse_id=?, product_type_id=?
-		WHERE id=?;`
+		maximum_capacity=?, warehouse_id=?, product_type_id=?, version=version+1
+		WHERE id=? AND version=? AND deleted_at IS NULL;`
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return err
 	}
 
-	res, err := stmt.Exec(&s.SectionNumber, &s.CurrentTemperature, &s.MinimumTemperature, &s.CurrentCapacity, &s.MinimumCapacity, &s.MaximumCapacity, &s.WarehouseID, &s.ProductTypeID, &s.ID)
+	res, err := stmt.Exec(&s.SectionNumber, &s.CurrentTemperature, &s.MinimumTemperature, &s.CurrentCapacity, &s.MinimumCapacity, &s.MaximumCapacity, &s.WarehouseID, &s.ProductTypeID, &s.ID, &s.Version)
 	if err != nil {
 		return err
 	}
@@ -114,20 +202,20 @@ func (r *repository) Update(ctx context.Context, s domain.Section) error {
 		return err
 	}
 	if affected < 1 {
-		return ErrNotFound
+		return sqlutil.StaleOrMissing(ctx, r.db, "sections", s.ID, ErrNotFound)
 	}
 
 	return nil
 }
 
 func (r *repository) Delete(ctx context.Context, id int) error {
-	query := "DELETE FROM sections WHERE id=?;"
+	query := "UPDATE sections SET deleted_at=?, version=version+1 WHERE id=? AND deleted_at IS NULL AND " + sqlutil.VersionMatches(ctx, "version") + ";"
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return err
 	}
 
-	res, err := stmt.Exec(id)
+	res, err := stmt.Exec(time.Now().UTC(), id)
 	if err != nil {
 		return err
 	}
@@ -137,6 +225,23 @@ func (r *repository) Delete(ctx context.Context, id int) error {
 		return err
 	}
 
+	if affected < 1 {
+		return sqlutil.StaleOrMissing(ctx, r.db, "sections", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+func (r *repository) Restore(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE sections SET deleted_at=NULL, version=version+1 WHERE id=? AND deleted_at IS NOT NULL;", id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
 	if affected < 1 {
 		return ErrNotFound
 	}
@@ -146,20 +251,29 @@ func (r *repository) Delete(ctx context.Context, id int) error {
 
 func (r *repository) GetAllReportProducts(ctx context.Context) ([]domain.GetOneData, error) {
 	var sections []domain.GetOneData
-	query := "SELECT s.id, s.section_number, COUNT(p.id) AS products_count FROM sections s INNER JOIN product_batches pb ON s.ID = pb.section_id INNER JOIN products p ON pb.product_id = p.id GROUP by s.id, s.section_number;"
-	rows, err := r.db.Query(query)
+	err := r.StreamReportProducts(ctx, func(s domain.GetOneData) error {
+		sections = append(sections, s)
+		return nil
+	})
+	return sections, err
+}
+
+func (r *repository) StreamReportProducts(ctx context.Context, fn func(domain.GetOneData) error) error {
+	query := "SELECT s.id, s.section_number, COUNT(p.id) AS products_count FROM sections s INNER JOIN product_batches pb ON s.ID = pb.section_id INNER JOIN products p ON pb.product_id = p.id WHERE s.deleted_at IS NULL AND p.deleted_at IS NULL GROUP by s.id, s.section_number;"
+	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
-		fmt.Println(err.Error())
-		return sections, err
+		return err
 	}
+	defer rows.Close()
+
 	for rows.Next() {
 		s := domain.GetOneData{}
-		err = rows.Scan(&s.SectionId, &s.SectionNumber, &s.ProductCount)
-		if err != nil {
-			fmt.Println(err.Error())
-			return sections, err
+		if err := rows.Scan(&s.SectionId, &s.SectionNumber, &s.ProductCount); err != nil {
+			return err
+		}
+		if err := fn(s); err != nil {
+			return err
 		}
-		sections = append(sections, s)
 	}
-	return sections, nil
+	return rows.Err()
 }
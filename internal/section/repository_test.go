@@ -44,6 +44,21 @@ func TestRepoExists(t *testing.T) {
 
 		assert.True(t, exists)
 	})
+	t.Run("Finds the taken section numbers in a single query", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := section.NewRepository(db)
+		expected := getTestSection()
+
+		id, _ := repo.Save(context.TODO(), expected)
+		repo.Delete(context.TODO(), id)
+
+		taken, err := repo.ExistingNumbers(context.TODO(), []int{expected.SectionNumber, 9999})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{expected.SectionNumber}, taken)
+	})
 }
 
 func TestRepoGet(t *testing.T) {
@@ -100,6 +115,7 @@ func TestRepoUpdate(t *testing.T) {
 		id, _ := repo.Save(context.TODO(), wh)
 
 		wh.ID = id
+		wh.Version = 1
 		wh.SectionNumber = 99
 
 		err := repo.Update(context.TODO(), wh)
@@ -128,6 +144,27 @@ func TestRepoDelete(t *testing.T) {
 	})
 }
 
+func TestRepoReportProducts(t *testing.T) {
+	t.Run("Leaves out soft deleted sections and products", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := section.NewRepository(db)
+		report, err := repo.GetAllReportProducts(context.TODO())
+		assert.NoError(t, err)
+		assert.Len(t, report, 2)
+
+		_, err = db.Exec("UPDATE sections SET deleted_at = NOW() WHERE id = 1;")
+		assert.NoError(t, err)
+		_, err = db.Exec("UPDATE products SET deleted_at = NOW() WHERE id = 2;")
+		assert.NoError(t, err)
+
+		report, err = repo.GetAllReportProducts(context.TODO())
+		assert.NoError(t, err)
+		assert.Empty(t, report)
+	})
+}
+
 func getTestSection() domain.Section {
 	return domain.Section{
 		SectionNumber:      18,
@@ -5,6 +5,8 @@ import (
 	"errors"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	producttype "github.com/extmatperez/meli_bootcamp_go_w2-4/internal/product_type"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 )
 
 type CreateSection struct {
@@ -35,25 +37,48 @@ var (
 	ErrInvalidSectionNumber = errors.New("section number alredy exists")
 	ErrSavingSection        = errors.New("error saving section")
 	ErrGetSections          = errors.New("error getting sections")
+	ErrProductTypeNotFound  = errors.New("product type not found")
 )
 
 type Service interface {
 	Create(ctx context.Context, section CreateSection) (domain.Section, error)
+	// CreateBatch creates sections, saving all the valid ones at once.
+	// The result and error of each section are at its index.
+	CreateBatch(ctx context.Context, sections []CreateSection) ([]domain.Section, []error)
 	GetAll(ctx context.Context) ([]domain.Section, error)
 	Get(ctx context.Context, id int) (domain.Section, error)
+	// GetByIDs returns the sections with the given ids, skipping those
+	// that don't exist.
+	GetByIDs(ctx context.Context, ids []int) ([]domain.Section, error)
 	Update(ctx context.Context, dto UpdateSection, id int) (domain.Section, error)
+	// UpdateBatch updates the sections with the given ids like Update
+	// does. The result and error of each section are at its index.
+	UpdateBatch(ctx context.Context, ids []int, dtos []UpdateSection) ([]domain.Section, []error)
 	Delete(ctx context.Context, id int) error
+	// Restore undoes the deletion of a section.
+	Restore(ctx context.Context, id int) (domain.Section, error)
 	GetReportProducts(ctx context.Context, id int) (domain.GetOneData, error)
 	GetAllReportProducts(ctx context.Context) ([]domain.GetOneData, error)
+	// StreamReportProducts calls fn for the product count of each
+	// section without loading them all in memory.
+	StreamReportProducts(ctx context.Context, fn func(domain.GetOneData) error) error
+}
+
+// ProductTypes is the part of the product type repository
+// used to check the classification of a section.
+type ProductTypes interface {
+	Get(ctx context.Context, id int) (domain.ProductType, error)
 }
 
 type service struct {
-	repository Repository
+	repository   Repository
+	productTypes ProductTypes
 }
 
-func NewService(r Repository) Service {
+func NewService(r Repository, types ProductTypes) Service {
 	return &service{
-		repository: r,
+		repository:   r,
+		productTypes: types,
 	}
 }
 
@@ -62,6 +87,12 @@ func (s *service) Create(ctx context.Context, createSection CreateSection) (doma
 	if existsSectionNumber {
 		return domain.Section{}, ErrInvalidSectionNumber
 	}
+	if _, err := s.productTypes.Get(ctx, createSection.ProductTypeID); err != nil {
+		if errors.Is(err, producttype.ErrNotFound) {
+			return domain.Section{}, ErrProductTypeNotFound
+		}
+		return domain.Section{}, ErrSavingSection
+	}
 	section := mapCreateToDomain(&createSection)
 	i, err := s.repository.Save(ctx, *section)
 	if err != nil {
@@ -83,6 +114,74 @@ func (s *service) Create(ctx context.Context, createSection CreateSection) (doma
 	return sec, nil
 }
 
+func (s *service) CreateBatch(ctx context.Context, sections []CreateSection) ([]domain.Section, []error) {
+	created := make([]domain.Section, len(sections))
+	errs := make([]error, len(sections))
+	var valid []domain.Section
+	var indexes []int
+	keys := make([]int, len(sections))
+	for i, dto := range sections {
+		keys[i] = dto.SectionNumber
+	}
+	taken, err := s.repository.ExistingNumbers(ctx, keys)
+	if err != nil {
+		for i := range errs {
+			errs[i] = ErrSavingSection
+		}
+		return created, errs
+	}
+	numbers := map[int]bool{}
+	for _, number := range taken {
+		numbers[number] = true
+	}
+	types := map[int]error{}
+	for i, dto := range sections {
+		if numbers[dto.SectionNumber] {
+			errs[i] = ErrInvalidSectionNumber
+			continue
+		}
+		typeErr, checked := types[dto.ProductTypeID]
+		if !checked {
+			if _, err := s.productTypes.Get(ctx, dto.ProductTypeID); err != nil {
+				typeErr = ErrSavingSection
+				if errors.Is(err, producttype.ErrNotFound) {
+					typeErr = ErrProductTypeNotFound
+				}
+			}
+			types[dto.ProductTypeID] = typeErr
+		}
+		if typeErr != nil {
+			errs[i] = typeErr
+			continue
+		}
+		numbers[dto.SectionNumber] = true
+		created[i] = *mapCreateToDomain(&dto)
+		valid = append(valid, created[i])
+		indexes = append(indexes, i)
+	}
+	if len(valid) == 0 {
+		return created, errs
+	}
+
+	ids, err := s.repository.SaveAll(ctx, valid)
+	if err != nil {
+		// One section failed the whole insert, so they are saved one by
+		// one to tell which.
+		ids = make([]int, len(valid))
+		for j, section := range valid {
+			ids[j], errs[indexes[j]] = s.repository.Save(ctx, section)
+		}
+	}
+	for j, i := range indexes {
+		if errs[i] != nil {
+			errs[i] = ErrSavingSection
+			continue
+		}
+		created[i].ID = ids[j]
+	}
+	return created, errs
+}
+
 func (s *service) GetAll(ctx context.Context) ([]domain.Section, error) {
 	sec, err := s.repository.GetAll(ctx)
 	if err != nil {
@@ -101,6 +200,14 @@ func (s *service) Get(ctx context.Context, id int) (domain.Section, error) {
 
 }
 
+func (s *service) GetByIDs(ctx context.Context, ids []int) ([]domain.Section, error) {
+	sec, err := s.repository.GetByIDs(ctx, ids)
+	if err != nil {
+		return []domain.Section{}, ErrGetSections
+	}
+	return sec, nil
+}
+
 func (s *service) Delete(ctx context.Context, id int) error {
 	_, err := s.Get(ctx, id)
 	if err != nil {
@@ -109,11 +216,57 @@ func (s *service) Delete(ctx context.Context, id int) error {
 	return s.repository.Delete(ctx, id)
 }
 
+func (s *service) Restore(ctx context.Context, id int) (domain.Section, error) {
+	if err := s.repository.Restore(ctx, id); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return domain.Section{}, ErrNotFound
+		}
+		return domain.Section{}, ErrSavingSection
+	}
+	return s.Get(ctx, id)
+}
+
 func (s *service) Update(ctx context.Context, dto UpdateSection, id int) (domain.Section, error) {
 	sec, err := s.Get(ctx, id)
 	if err != nil {
 		return domain.Section{}, ErrNotFound
 	}
+	return s.update(ctx, sec, dto)
+}
+
+func (s *service) UpdateBatch(ctx context.Context, ids []int, dtos []UpdateSection) ([]domain.Section, []error) {
+	updated := make([]domain.Section, len(ids))
+	errs := make([]error, len(ids))
+	current, err := s.repository.GetByIDs(ctx, ids)
+	if err != nil {
+		for i := range errs {
+			errs[i] = ErrGetSections
+		}
+		return updated, errs
+	}
+	byID := make(map[int]domain.Section, len(current))
+	for _, sec := range current {
+		byID[sec.ID] = sec
+	}
+	for i, id := range ids {
+		sec, ok := byID[id]
+		if !ok {
+			errs[i] = ErrNotFound
+			continue
+		}
+		updated[i], errs[i] = s.update(ctx, sec, dtos[i])
+		if errs[i] == nil {
+			byID[id] = updated[i]
+		}
+	}
+	return updated, errs
+}
+
+// update applies dto over sec and saves it.
+func (s *service) update(ctx context.Context, sec domain.Section, dto UpdateSection) (domain.Section, error) {
+	if err := sqlutil.CheckVersion(ctx, sec.Version); err != nil {
+		return domain.Section{}, err
+	}
 	if dto.SectionNumber != nil {
 		existsSectionNumber := s.repository.Exists(ctx, *dto.SectionNumber)
 		if existsSectionNumber && sec.SectionNumber != *dto.SectionNumber {
@@ -121,8 +274,11 @@ func (s *service) Update(ctx context.Context, dto UpdateSection, id int) (domain
 		}
 	}
 	applyValues(&sec, dto)
-	err = s.repository.Update(ctx, sec)
-	return sec, err
+	if err := s.repository.Update(ctx, sec); err != nil {
+		return domain.Section{}, err
+	}
+	sec.Version++
+	return sec, nil
 }
 
 func applyValues(sec *domain.Section, dto UpdateSection) {
@@ -193,3 +349,10 @@ func (s *service) GetAllReportProducts(ctx context.Context) ([]domain.GetOneData
 	}
 	return sec, nil
 }
+
+func (s *service) StreamReportProducts(ctx context.Context, fn func(domain.GetOneData) error) error {
+	if err := s.repository.StreamReportProducts(ctx, fn); err != nil {
+		return ErrGetSections
+	}
+	return nil
+}
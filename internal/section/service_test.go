@@ -5,7 +5,9 @@ import (
 	"testing"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	producttype "github.com/extmatperez/meli_bootcamp_go_w2-4/internal/product_type"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/section"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -18,7 +20,7 @@ var sectionID = 1
 func TestRead(t *testing.T) {
 	t.Run("Return all sections successfully", func(t *testing.T) {
 		repositoryMock := RepositoryMock{}
-		svc := section.NewService(&repositoryMock)
+		svc := section.NewService(&repositoryMock, &ProductTypesMock{})
 
 		expected := getTestSections()
 
@@ -31,7 +33,7 @@ func TestRead(t *testing.T) {
 	})
 	t.Run("Does not get any section and returns error: getting sections", func(t *testing.T) {
 		repositoryMock := RepositoryMock{}
-		svc := section.NewService(&repositoryMock)
+		svc := section.NewService(&repositoryMock, &ProductTypesMock{})
 
 		repositoryMock.On("GetAll", mock.Anything).Return([]domain.Section{}, section.ErrGetSections)
 		_, err := svc.GetAll(context.TODO())
@@ -41,7 +43,7 @@ func TestRead(t *testing.T) {
 	})
 	t.Run("Return a section by ID successfully", func(t *testing.T) {
 		repositoryMock := RepositoryMock{}
-		svc := section.NewService(&repositoryMock)
+		svc := section.NewService(&repositoryMock, &ProductTypesMock{})
 
 		expected := getTestSections()[0]
 
@@ -53,7 +55,7 @@ func TestRead(t *testing.T) {
 	})
 	t.Run("Does not get any section and returns error: not found", func(t *testing.T) {
 		repositoryMock := RepositoryMock{}
-		svc := section.NewService(&repositoryMock)
+		svc := section.NewService(&repositoryMock, &ProductTypesMock{})
 
 		repositoryMock.On("Get", mock.Anything, mock.Anything).Return(domain.Section{}, section.ErrNotFound)
 		_, err := svc.Get(context.TODO(), sectionID)
@@ -66,13 +68,15 @@ func TestRead(t *testing.T) {
 func TestCreate(t *testing.T) {
 	t.Run("Create a section successfully", func(t *testing.T) {
 		repositoryMock := RepositoryMock{}
-		svc := section.NewService(&repositoryMock)
+		types := ProductTypesMock{}
+		svc := section.NewService(&repositoryMock, &types)
 
 		body := getTestCreateSections()
 
 		expected := getTestSections()[0]
 
 		repositoryMock.On("Exists", mock.Anything, mock.Anything).Return(false)
+		types.On("Get", mock.Anything, mock.Anything).Return(domain.ProductType{ID: 1}, nil)
 		repositoryMock.On("Save", mock.Anything, mock.Anything).Return(sectionID, nil)
 
 		result, err := svc.Create(context.TODO(), body)
@@ -83,7 +87,8 @@ func TestCreate(t *testing.T) {
 	})
 	t.Run("Does not create any section and returns error: section number alredy exists", func(t *testing.T) {
 		repositoryMock := RepositoryMock{}
-		svc := section.NewService(&repositoryMock)
+		types := ProductTypesMock{}
+		svc := section.NewService(&repositoryMock, &types)
 
 		body := getTestCreateSections()
 
@@ -96,11 +101,13 @@ func TestCreate(t *testing.T) {
 	})
 	t.Run("Does not create any section and returns error: saving section", func(t *testing.T) {
 		repositoryMock := RepositoryMock{}
-		svc := section.NewService(&repositoryMock)
+		types := ProductTypesMock{}
+		svc := section.NewService(&repositoryMock, &types)
 
 		body := getTestCreateSections()
 
 		repositoryMock.On("Exists", mock.Anything, mock.Anything).Return(false)
+		types.On("Get", mock.Anything, mock.Anything).Return(domain.ProductType{ID: 1}, nil)
 		repositoryMock.On("Save", mock.Anything, mock.Anything).Return(0, section.ErrSavingSection)
 
 		_, err := svc.Create(context.TODO(), body)
@@ -108,12 +115,27 @@ func TestCreate(t *testing.T) {
 		assert.Error(t, err)
 		assert.ErrorIs(t, err, section.ErrSavingSection)
 	})
+	t.Run("Does not create any section and returns error: product type not found", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		types := ProductTypesMock{}
+		svc := section.NewService(&repositoryMock, &types)
+
+		body := getTestCreateSections()
+
+		repositoryMock.On("Exists", mock.Anything, mock.Anything).Return(false)
+		types.On("Get", mock.Anything, body.ProductTypeID).Return(domain.ProductType{}, producttype.ErrNotFound)
+
+		_, err := svc.Create(context.TODO(), body)
+
+		assert.ErrorIs(t, err, section.ErrProductTypeNotFound)
+		repositoryMock.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	})
 }
 
 func TestUpdate(t *testing.T) {
 	t.Run("Update a section successfully", func(t *testing.T) {
 		repositoryMock := RepositoryMock{}
-		svc := section.NewService(&repositoryMock)
+		svc := section.NewService(&repositoryMock, &ProductTypesMock{})
 
 		actualSection := domain.Section{
 			ID:                 1,
@@ -148,6 +170,7 @@ func TestUpdate(t *testing.T) {
 			MaximumCapacity:    21,
 			WarehouseID:        3210,
 			ProductTypeID:      3,
+			Version:            1,
 		}
 
 		repositoryMock.On("Get", mock.Anything, mock.Anything).Return(actualSection, nil)
@@ -162,7 +185,7 @@ func TestUpdate(t *testing.T) {
 	})
 	t.Run("Does not update any section and returns error: not found", func(t *testing.T) {
 		repositoryMock := RepositoryMock{}
-		svc := section.NewService(&repositoryMock)
+		svc := section.NewService(&repositoryMock, &ProductTypesMock{})
 
 		body := getUpdateSection()
 
@@ -177,7 +200,7 @@ func TestUpdate(t *testing.T) {
 	})
 	t.Run("Does not update any section and returns error: section number alredy exists", func(t *testing.T) {
 		repositoryMock := RepositoryMock{}
-		svc := section.NewService(&repositoryMock)
+		svc := section.NewService(&repositoryMock, &ProductTypesMock{})
 
 		body := getUpdateSection()
 
@@ -190,12 +213,61 @@ func TestUpdate(t *testing.T) {
 		assert.ErrorIs(t, err, section.ErrInvalidSectionNumber)
 		repositoryMock.AssertNumberOfCalls(t, "Update", 0)
 	})
+	t.Run("Update fails if the section is not in the expected version", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := section.NewService(&repositoryMock, &ProductTypesMock{})
+
+		repositoryMock.On("Get", mock.Anything, 1).Return(domain.Section{ID: 1, Version: 2}, nil)
+
+		ctx := sqlutil.WithIfMatch(context.TODO(), []int{1})
+		_, err := svc.Update(ctx, section.UpdateSection{}, 1)
+
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+		repositoryMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+}
+
+func TestBatch(t *testing.T) {
+	t.Run("Creates the valid sections in a single save", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		types := ProductTypesMock{}
+		svc := section.NewService(&repositoryMock, &types)
+
+		sections := []section.CreateSection{
+			{SectionNumber: 1, ProductTypeID: 1},
+			{SectionNumber: 2, ProductTypeID: 2},
+			{SectionNumber: 3, ProductTypeID: 1},
+			{SectionNumber: 4, ProductTypeID: 1},
+		}
+		repositoryMock.On("ExistingNumbers", mock.Anything, []int{1, 2, 3, 4}).Return([]int{4}, nil)
+		types.On("Get", mock.Anything, 1).Return(domain.ProductType{ID: 1}, nil).Once()
+		types.On("Get", mock.Anything, 2).Return(domain.ProductType{}, producttype.ErrNotFound)
+		valid := []domain.Section{{SectionNumber: 1, ProductTypeID: 1}, {SectionNumber: 3, ProductTypeID: 1}}
+		repositoryMock.On("SaveAll", mock.Anything, valid).Return([]int{4, 5}, nil)
+
+		created, errs := svc.CreateBatch(context.TODO(), sections)
+
+		assert.Equal(t, []error{nil, section.ErrProductTypeNotFound, nil, section.ErrInvalidSectionNumber}, errs)
+		assert.Equal(t, 4, created[0].ID)
+		assert.Equal(t, 5, created[2].ID)
+	})
+	t.Run("Does not update sections that don't exist", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := section.NewService(&repositoryMock, &ProductTypesMock{})
+
+		repositoryMock.On("GetByIDs", mock.Anything, []int{sectionID}).Return([]domain.Section{}, nil)
+
+		_, errs := svc.UpdateBatch(context.TODO(), []int{sectionID}, []section.UpdateSection{{}})
+
+		assert.Equal(t, []error{section.ErrNotFound}, errs)
+		repositoryMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
 }
 
 func TestDelete(t *testing.T) {
 	t.Run("Delete a section successfully", func(t *testing.T) {
 		repositoryMock := RepositoryMock{}
-		svc := section.NewService(&repositoryMock)
+		svc := section.NewService(&repositoryMock, &ProductTypesMock{})
 
 		repositoryMock.On("Get", mock.Anything, mock.Anything).Return(domain.Section{}, nil)
 		repositoryMock.On("Delete", mock.Anything, mock.Anything).Return(nil)
@@ -205,7 +277,7 @@ func TestDelete(t *testing.T) {
 	})
 	t.Run("Does not delete any section and returns error: not found", func(t *testing.T) {
 		repositoryMock := RepositoryMock{}
-		svc := section.NewService(&repositoryMock)
+		svc := section.NewService(&repositoryMock, &ProductTypesMock{})
 
 		repositoryMock.On("Get", mock.Anything, mock.Anything).Return(domain.Section{}, section.ErrNotFound)
 		err := svc.Delete(context.TODO(), sectionID)
@@ -216,10 +288,34 @@ func TestDelete(t *testing.T) {
 	})
 }
 
+func TestRestore(t *testing.T) {
+	t.Run("restores a deleted section", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := section.NewService(&repositoryMock, &ProductTypesMock{})
+		expected := domain.Section{ID: 1, SectionNumber: 10}
+
+		repositoryMock.On("Restore", mock.Anything, expected.ID).Return(nil)
+		repositoryMock.On("Get", mock.Anything, expected.ID).Return(expected, nil)
+		restored, err := svc.Restore(context.TODO(), expected.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, restored)
+	})
+	t.Run("returns not found when the section is not deleted", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := section.NewService(&repositoryMock, &ProductTypesMock{})
+
+		repositoryMock.On("Restore", mock.Anything, 1).Return(section.ErrNotFound)
+		_, err := svc.Restore(context.TODO(), 1)
+
+		assert.ErrorIs(t, err, section.ErrNotFound)
+	})
+}
+
 func TestGetAllReportProducts(t *testing.T) {
 	t.Run("get all the products in a section successfully", func(t *testing.T) {
 		repositoryMock := RepositoryMock{}
-		svc := section.NewService(&repositoryMock)
+		svc := section.NewService(&repositoryMock, &ProductTypesMock{})
 
 		expected := []domain.GetOneData{
 			{
@@ -243,7 +339,7 @@ func TestGetAllReportProducts(t *testing.T) {
 	})
 	t.Run("Does not get any section and returns error: getting sections", func(t *testing.T) {
 		repositoryMock := RepositoryMock{}
-		svc := section.NewService(&repositoryMock)
+		svc := section.NewService(&repositoryMock, &ProductTypesMock{})
 
 		repositoryMock.On("GetAllReportProducts", mock.Anything).Return([]domain.GetOneData{}, section.ErrGetSections)
 		_, err := svc.GetAllReportProducts(context.Background())
@@ -251,12 +347,37 @@ func TestGetAllReportProducts(t *testing.T) {
 		assert.Error(t, err)
 		assert.ErrorIs(t, err, section.ErrGetSections)
 	})
+	t.Run("streams the products of every section", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := section.NewService(&repositoryMock, &ProductTypesMock{})
+
+		expected := []domain.GetOneData{{SectionId: 1, SectionNumber: 10, ProductCount: 2}}
+		repositoryMock.On("StreamReportProducts", mock.Anything).Return(expected, nil)
+
+		var received []domain.GetOneData
+		err := svc.StreamReportProducts(context.Background(), func(d domain.GetOneData) error {
+			received = append(received, d)
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, received)
+	})
+	t.Run("Does not stream any section and returns error: getting sections", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := section.NewService(&repositoryMock, &ProductTypesMock{})
+
+		repositoryMock.On("StreamReportProducts", mock.Anything).Return([]domain.GetOneData{}, section.ErrGetSections)
+		err := svc.StreamReportProducts(context.Background(), func(domain.GetOneData) error { return nil })
+
+		assert.ErrorIs(t, err, section.ErrGetSections)
+	})
 }
 
 func TestGetReportProducts(t *testing.T) {
 	t.Run("get the product in a section successfully", func(t *testing.T) {
 		repositoryMock := RepositoryMock{}
-		svc := section.NewService(&repositoryMock)
+		svc := section.NewService(&repositoryMock, &ProductTypesMock{})
 
 		expected := []domain.GetOneData{
 			{
@@ -281,7 +402,7 @@ func TestGetReportProducts(t *testing.T) {
 
 	t.Run("Does not get any section and returns error: getting sections", func(t *testing.T) {
 		repositoryMock := RepositoryMock{}
-		svc := section.NewService(&repositoryMock)
+		svc := section.NewService(&repositoryMock, &ProductTypesMock{})
 
 		repositoryMock.On("GetAllReportProducts", mock.Anything).Return([]domain.GetOneData{}, section.ErrGetSections)
 		_, err := svc.GetReportProducts(context.Background(), 1)
@@ -292,7 +413,7 @@ func TestGetReportProducts(t *testing.T) {
 
 	t.Run("Does not get any section and returns error: not found", func(t *testing.T) {
 		repositoryMock := RepositoryMock{}
-		svc := section.NewService(&repositoryMock)
+		svc := section.NewService(&repositoryMock, &ProductTypesMock{})
 
 		repositoryMock.On("GetAllReportProducts", mock.Anything).Return([]domain.GetOneData{}, nil)
 		repositoryMock.On("GetReportProducts", mock.Anything).Return(domain.GetOneData{}, section.ErrNotFound)
@@ -374,16 +495,31 @@ func (r *RepositoryMock) Get(ctx context.Context, id int) (domain.Section, error
 	return args.Get(0).(domain.Section), args.Error(1)
 }
 
+func (r *RepositoryMock) GetByIDs(ctx context.Context, ids []int) ([]domain.Section, error) {
+	args := r.Called(ctx, ids)
+	return args.Get(0).([]domain.Section), args.Error(1)
+}
+
 func (r *RepositoryMock) Exists(ctx context.Context, sectionNumber int) bool {
 	args := r.Called(ctx, sectionNumber)
 	return args.Get(0).(bool)
 }
 
+func (r *RepositoryMock) ExistingNumbers(ctx context.Context, numbers []int) ([]int, error) {
+	args := r.Called(ctx, numbers)
+	return args.Get(0).([]int), args.Error(1)
+}
+
 func (r *RepositoryMock) Save(ctx context.Context, s domain.Section) (int, error) {
 	args := r.Called(ctx, s)
 	return args.Get(0).(int), args.Error(1)
 }
 
+func (r *RepositoryMock) SaveAll(ctx context.Context, sections []domain.Section) ([]int, error) {
+	args := r.Called(ctx, sections)
+	return args.Get(0).([]int), args.Error(1)
+}
+
 func (r *RepositoryMock) Update(ctx context.Context, s domain.Section) error {
 	args := r.Called(ctx, s)
 	return args.Error(0)
@@ -394,12 +530,36 @@ func (r *RepositoryMock) Delete(ctx context.Context, id int) error {
 	return args.Error(0)
 }
 
+func (r *RepositoryMock) Restore(ctx context.Context, id int) error {
+	args := r.Called(ctx, id)
+	return args.Error(0)
+}
+
 func (r *RepositoryMock) GetAllReportProducts(ctx context.Context) ([]domain.GetOneData, error) {
 	args := r.Called(ctx)
 	return args.Get(0).([]domain.GetOneData), args.Error(1)
 }
 
+func (r *RepositoryMock) StreamReportProducts(ctx context.Context, fn func(domain.GetOneData) error) error {
+	args := r.Called(ctx)
+	for _, entry := range args.Get(0).([]domain.GetOneData) {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
 func (r *RepositoryMock) GetReportProducts(ctx context.Context, id int) (domain.GetOneData, error) {
 	args := r.Called(ctx, id)
 	return args.Get(0).(domain.GetOneData), args.Error(1)
 }
+
+type ProductTypesMock struct {
+	mock.Mock
+}
+
+func (m *ProductTypesMock) Get(ctx context.Context, id int) (domain.ProductType, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(domain.ProductType), args.Error(1)
+}
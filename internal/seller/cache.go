@@ -0,0 +1,35 @@
+package seller
+
+import (
+	"context"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/cache"
+)
+
+type purgingRepository struct {
+	Repository
+	caches []cache.Purger
+}
+
+// NewPurgingRepository decorates repo to purge the given caches when
+// a seller is deleted or restored, as that soft deletes or restores
+// its products too.
+func NewPurgingRepository(repo Repository, caches ...cache.Purger) Repository {
+	return &purgingRepository{repo, caches}
+}
+
+func (r *purgingRepository) Delete(ctx context.Context, id int) error {
+	defer r.purge(ctx)
+	return r.Repository.Delete(ctx, id)
+}
+
+func (r *purgingRepository) Restore(ctx context.Context, id int) error {
+	defer r.purge(ctx)
+	return r.Repository.Restore(ctx, id)
+}
+
+func (r *purgingRepository) purge(ctx context.Context) {
+	for _, c := range r.caches {
+		c.Purge(ctx)
+	}
+}
@@ -4,18 +4,42 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 )
 
 // Repository encapsulates the storage of a Seller.
 type Repository interface {
 	GetAll(ctx context.Context) ([]domain.Seller, error)
 	Get(ctx context.Context, id int) (domain.Seller, error)
+	// GetByIDs returns the sellers with the given ids, in a single
+	// query. Ids of missing sellers are skipped.
+	GetByIDs(ctx context.Context, ids []int) ([]domain.Seller, error)
 	Exists(ctx context.Context, cid int) bool
+	// ExistingCIDs returns which of the given cids are taken, in a
+	// single query.
+	ExistingCIDs(ctx context.Context, cids []int) ([]int, error)
 	Save(ctx context.Context, s domain.Seller) (int, error)
+	// SaveAll saves sellers in a single insert and returns their ids.
+	// If one of them fails, none is saved.
+	SaveAll(ctx context.Context, sellers []domain.Seller) ([]int, error)
+	// Update saves s unless the seller is no longer in s.Version,
+	// in which case it fails with sqlutil.ErrVersionMismatch.
 	Update(ctx context.Context, s domain.Seller) error
+	// Delete soft deletes a seller along with its products,
+	// if it is in one of the versions expected by ctx.
 	Delete(ctx context.Context, id int) error
+	// Restore undoes the deletion of a seller and of the products
+	// deleted with it.
+	Restore(ctx context.Context, id int) error
+	Products(ctx context.Context, id int) ([]domain.Product, error)
+	Performance(ctx context.Context, id int) (domain.SellerPerformance, error)
+	// DeleteImpact counts the products that deleting the seller would
+	// hide, and the rows that depend on them.
+	DeleteImpact(ctx context.Context, id int) (domain.SellerDeleteImpact, error)
 }
 
 type repository struct {
@@ -29,7 +53,7 @@ func NewRepository(db *sql.DB) Repository {
 }
 
 func (r *repository) GetAll(ctx context.Context) ([]domain.Seller, error) {
-	query := "SELECT id, cid, company_name, address, telephone, locality_id FROM sellers"
+	query := "SELECT id, cid, company_name, address, telephone, locality_id, version, deleted_at FROM sellers WHERE " + sqlutil.NotDeleted(ctx, "deleted_at")
 	rows, err := r.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -39,7 +63,7 @@ func (r *repository) GetAll(ctx context.Context) ([]domain.Seller, error) {
 
 	for rows.Next() {
 		s := domain.Seller{}
-		_ = rows.Scan(&s.ID, &s.CID, &s.CompanyName, &s.Address, &s.Telephone, &s.LocalityID)
+		_ = rows.Scan(&s.ID, &s.CID, &s.CompanyName, &s.Address, &s.Telephone, &s.LocalityID, &s.Version, sqlutil.NullTime(&s.DeletedAt))
 		sellers = append(sellers, s)
 	}
 
@@ -47,10 +71,10 @@ func (r *repository) GetAll(ctx context.Context) ([]domain.Seller, error) {
 }
 
 func (r *repository) Get(ctx context.Context, id int) (domain.Seller, error) {
-	query := "SELECT id, cid, company_name, address, telephone, locality_id FROM sellers WHERE id=?;"
+	query := "SELECT id, cid, company_name, address, telephone, locality_id, version, deleted_at FROM sellers WHERE id=? AND " + sqlutil.NotDeleted(ctx, "deleted_at") + ";"
 	row := r.db.QueryRow(query, id)
 	s := domain.Seller{}
-	err := row.Scan(&s.ID, &s.CID, &s.CompanyName, &s.Address, &s.Telephone, &s.LocalityID)
+	err := row.Scan(&s.ID, &s.CID, &s.CompanyName, &s.Address, &s.Telephone, &s.LocalityID, &s.Version, sqlutil.NullTime(&s.DeletedAt))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.Seller{}, ErrNotFound
@@ -61,6 +85,29 @@ func (r *repository) Get(ctx context.Context, id int) (domain.Seller, error) {
 	return s, nil
 }
 
+func (r *repository) GetByIDs(ctx context.Context, ids []int) ([]domain.Seller, error) {
+	if len(ids) == 0 {
+		return []domain.Seller{}, nil
+	}
+	in, args := sqlutil.In(ids)
+	query := "SELECT id, cid, company_name, address, telephone, locality_id, version, deleted_at FROM sellers WHERE id IN " + in + " AND " + sqlutil.NotDeleted(ctx, "deleted_at")
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sellers := []domain.Seller{}
+	for rows.Next() {
+		s := domain.Seller{}
+		if err := rows.Scan(&s.ID, &s.CID, &s.CompanyName, &s.Address, &s.Telephone, &s.LocalityID, &s.Version, sqlutil.NullTime(&s.DeletedAt)); err != nil {
+			return nil, err
+		}
+		sellers = append(sellers, s)
+	}
+	return sellers, rows.Err()
+}
+
 func (r *repository) Exists(ctx context.Context, cid int) bool {
 	query := "SELECT cid FROM sellers WHERE cid=?;"
 	row := r.db.QueryRow(query, cid)
@@ -68,6 +115,10 @@ func (r *repository) Exists(ctx context.Context, cid int) bool {
 	return err == nil
 }
 
+func (r *repository) ExistingCIDs(ctx context.Context, cids []int) ([]int, error) {
+	return sqlutil.ExistingKeys(ctx, r.db, "sellers", "cid", cids)
+}
+
 func (r *repository) Save(ctx context.Context, s domain.Seller) (int, error) {
 	query := "INSERT INTO sellers (cid, company_name, address, telephone, locality_id) VALUES (?, ?, ?, ?, ?)"
 	stmt, err := r.db.Prepare(query)
@@ -77,6 +128,9 @@ func (r *repository) Save(ctx context.Context, s domain.Seller) (int, error) {
 
 	res, err := stmt.Exec(s.CID, s.CompanyName, s.Address, s.Telephone, s.LocalityID)
 	if err != nil {
+		if strings.HasPrefix(err.Error(), "Error 1452") {
+			return 0, ErrLocalityNotFound
+		}
 		return 0, err
 	}
 
@@ -88,15 +142,47 @@ func (r *repository) Save(ctx context.Context, s domain.Seller) (int, error) {
 	return int(id), nil
 }
 
+func (r *repository) SaveAll(ctx context.Context, sellers []domain.Seller) ([]int, error) {
+	query := "INSERT INTO sellers (cid, company_name, address, telephone, locality_id) VALUES " + sqlutil.Rows(len(sellers), 5)
+	args := make([]any, 0, len(sellers)*5)
+	for _, s := range sellers {
+		args = append(args, s.CID, s.CompanyName, s.Address, s.Telephone, s.LocalityID)
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		if strings.HasPrefix(err.Error(), "Error 1452") {
+			return nil, ErrLocalityNotFound
+		}
+		return nil, err
+	}
+	keys := make([]int, len(sellers))
+	for i, s := range sellers {
+		keys[i] = s.CID
+	}
+	ids, err := sqlutil.IDsByKey(ctx, tx, "sellers", "cid", keys)
+	if err != nil {
+		return nil, err
+	}
+	return ids, tx.Commit()
+}
+
 func (r *repository) Update(ctx context.Context, s domain.Seller) error {
-	query := "UPDATE sellers SET cid=?, company_name=?, address=?, telephone=?, locality_id=? WHERE id=?"
+	query := "UPDATE sellers SET cid=?, company_name=?, address=?, telephone=?, locality_id=?, version=version+1 WHERE id=? AND version=? AND deleted_at IS NULL"
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return err
 	}
 
-	res, err := stmt.Exec(s.CID, s.CompanyName, s.Address, s.Telephone, s.LocalityID, s.ID)
+	res, err := stmt.Exec(s.CID, s.CompanyName, s.Address, s.Telephone, s.LocalityID, s.ID, s.Version)
 	if err != nil {
+		if strings.HasPrefix(err.Error(), "Error 1452") {
+			return ErrLocalityNotFound
+		}
 		return err
 	}
 
@@ -105,32 +191,119 @@ func (r *repository) Update(ctx context.Context, s domain.Seller) error {
 		return err
 	}
 	if affected < 1 {
-		return ErrNotFound
+		return sqlutil.StaleOrMissing(ctx, r.db, "sellers", s.ID, ErrNotFound)
 	}
 
 	return nil
 }
 
 func (r *repository) Delete(ctx context.Context, id int) error {
-	query := "DELETE FROM sellers WHERE id=?"
-	stmt, err := r.db.Prepare(query)
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	res, err := stmt.Exec(id)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	query := "UPDATE sellers SET deleted_at=?, version=version+1 WHERE id=? AND deleted_at IS NULL AND " + sqlutil.VersionMatches(ctx, "version") + ";"
+	res, err := tx.ExecContext(ctx, query, now, id)
 	if err != nil {
 		return err
 	}
-
 	affected, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
-
 	if affected < 1 {
-		return ErrNotFound
+		return sqlutil.StaleOrMissing(ctx, tx, "sellers", id, ErrNotFound)
 	}
+	if _, err := tx.ExecContext(ctx, "UPDATE products SET deleted_at=?, version=version+1 WHERE seller_id=? AND deleted_at IS NULL;", now, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
-	return nil
+func (r *repository) Restore(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+	err = tx.QueryRowContext(ctx, "SELECT deleted_at FROM sellers WHERE id=? AND deleted_at IS NOT NULL FOR UPDATE;", id).Scan(sqlutil.Time(&deletedAt))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE sellers SET deleted_at=NULL, version=version+1 WHERE id=?;", id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE products SET deleted_at=NULL, version=version+1 WHERE seller_id=? AND deleted_at=?;", id, deletedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *repository) Products(ctx context.Context, id int) ([]domain.Product, error) {
+	query := `SELECT id,description,expiration_rate,freezing_rate,
+		height,length,net_weight,product_code,recommended_freezing_temperature,
+		width,product_type_id,seller_id FROM products WHERE seller_id=? AND deleted_at IS NULL ORDER BY id;`
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []domain.Product{}
+	for rows.Next() {
+		p := domain.Product{}
+		if err := rows.Scan(&p.ID, &p.Description, &p.ExpirationRate, &p.FreezingRate, &p.Height, &p.Length, &p.Netweight, &p.ProductCode, &p.RecomFreezTemp, &p.Width, &p.ProductTypeID, &p.SellerID); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+func (r *repository) Performance(ctx context.Context, id int) (domain.SellerPerformance, error) {
+	query := `SELECT
+		(SELECT COUNT(*) FROM products p WHERE p.seller_id = ? AND p.deleted_at IS NULL),
+		(SELECT COALESCE(SUM(b.current_quantity), 0) FROM product_batches b
+			INNER JOIN products p ON p.id = b.product_id WHERE p.seller_id = ? AND p.deleted_at IS NULL),
+		(SELECT COALESCE(SUM(od.quantity), 0) FROM order_details od
+			INNER JOIN product_records pr ON pr.id = od.product_record_id
+			INNER JOIN products p ON p.id = pr.product_id WHERE p.seller_id = ?),
+		(SELECT COALESCE(SUM(od.quantity * pr.sale_price), 0) FROM order_details od
+			INNER JOIN product_records pr ON pr.id = od.product_record_id
+			INNER JOIN products p ON p.id = pr.product_id WHERE p.seller_id = ?);`
+	perf := domain.SellerPerformance{SellerID: id}
+	err := r.db.QueryRowContext(ctx, query, id, id, id, id).Scan(&perf.ProductsCount, &perf.UnitsInStock, &perf.UnitsSold, &perf.Revenue)
+	if err != nil {
+		return domain.SellerPerformance{}, err
+	}
+	return perf, nil
+}
+
+func (r *repository) DeleteImpact(ctx context.Context, id int) (domain.SellerDeleteImpact, error) {
+	query := `SELECT
+		(SELECT COUNT(*) FROM products p WHERE p.seller_id = ? AND p.deleted_at IS NULL),
+		(SELECT COUNT(*) FROM product_batches b
+			INNER JOIN products p ON p.id = b.product_id WHERE p.seller_id = ? AND p.deleted_at IS NULL),
+		(SELECT COUNT(*) FROM inbound_orders io
+			INNER JOIN product_batches b ON b.id = io.product_batch_id
+			INNER JOIN products p ON p.id = b.product_id WHERE p.seller_id = ? AND p.deleted_at IS NULL),
+		(SELECT COUNT(*) FROM product_records pr
+			INNER JOIN products p ON p.id = pr.product_id WHERE p.seller_id = ? AND p.deleted_at IS NULL),
+		(SELECT COUNT(*) FROM purchase_orders po
+			INNER JOIN product_records pr ON pr.id = po.product_record_id
+			INNER JOIN products p ON p.id = pr.product_id WHERE p.seller_id = ? AND p.deleted_at IS NULL);`
+	impact := domain.SellerDeleteImpact{SellerID: id}
+	err := r.db.QueryRowContext(ctx, query, id, id, id, id, id).Scan(&impact.Products, &impact.ProductBatches, &impact.InboundOrders, &impact.ProductRecords, &impact.PurchaseOrders)
+	if err != nil {
+		return domain.SellerDeleteImpact{}, err
+	}
+	return impact, nil
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/seller"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
 	"github.com/stretchr/testify/assert"
 )
@@ -96,6 +97,7 @@ func TestRepoUpdate(t *testing.T) {
 		id, _ := repo.Save(context.TODO(), s)
 
 		s.ID = id
+		s.Version = 1
 		s.CID = 999
 
 		err := repo.Update(context.TODO(), s)
@@ -103,6 +105,29 @@ func TestRepoUpdate(t *testing.T) {
 
 		received, _ := repo.Get(context.TODO(), id)
 		assert.Equal(t, s.CID, received.CID)
+		assert.Equal(t, 2, received.Version)
+	})
+	t.Run("Does not overwrite a newer version", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := seller.NewRepository(db)
+		s := getTestSeller()
+
+		id, _ := repo.Save(context.TODO(), s)
+		read, _ := repo.Get(context.TODO(), id)
+
+		first, second := read, read
+		first.CompanyName = "first"
+		second.CompanyName = "second"
+		assert.NoError(t, repo.Update(context.TODO(), first))
+		assert.ErrorIs(t, repo.Update(context.TODO(), second), sqlutil.ErrVersionMismatch)
+
+		second.ID = 9999
+		assert.ErrorIs(t, repo.Update(context.TODO(), second), seller.ErrNotFound)
+
+		received, _ := repo.Get(context.TODO(), id)
+		assert.Equal(t, "first", received.CompanyName)
 	})
 }
 func TestRepoDelete(t *testing.T) {
@@ -120,6 +145,85 @@ func TestRepoDelete(t *testing.T) {
 
 		_, err = repo.Get(context.TODO(), id)
 		assert.Error(t, err)
+
+		deleted, err := repo.Get(sqlutil.WithDeleted(context.TODO()), id)
+		assert.NoError(t, err)
+		assert.NotNil(t, deleted.DeletedAt)
+	})
+	t.Run("Restores a seller with the products deleted with it", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := seller.NewRepository(db)
+		s := getTestSeller()
+		s.CID = 9002
+		id, err := repo.Save(context.TODO(), s)
+		assert.NoError(t, err)
+		_, err = db.Exec(`INSERT INTO products(description,expiration_rate,freezing_rate,height,length,net_weight,
+			product_code,recommended_freezing_temperature,width,product_type_id,seller_id)
+			VALUES ('abc',1,2,3,4,5,'SELLER-RESTORE-1',6,7,1,?);`, id)
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.Delete(context.TODO(), id))
+		products, err := repo.Products(context.TODO(), id)
+		assert.NoError(t, err)
+		assert.Empty(t, products)
+
+		assert.NoError(t, repo.Restore(context.TODO(), id))
+		restored, err := repo.Get(context.TODO(), id)
+		assert.NoError(t, err)
+		assert.Nil(t, restored.DeletedAt)
+		products, err = repo.Products(context.TODO(), id)
+		assert.NoError(t, err)
+		assert.Len(t, products, 1)
+	})
+	t.Run("Does not restore a seller that is not deleted", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := seller.NewRepository(db)
+		err := repo.Restore(context.TODO(), 1)
+		assert.ErrorIs(t, err, seller.ErrNotFound)
+	})
+}
+
+func TestRepoSellerCatalog(t *testing.T) {
+	db := testutil.InitDatabase(t)
+	defer db.Close()
+
+	repo := seller.NewRepository(db)
+	s := getTestSeller()
+	s.CID = 9001
+	id, err := repo.Save(context.TODO(), s)
+	assert.NoError(t, err)
+
+	res, err := db.Exec(`INSERT INTO products(description,expiration_rate,freezing_rate,height,length,net_weight,
+		product_code,recommended_freezing_temperature,width,product_type_id,seller_id)
+		VALUES ('abc',1,2,3,4,5,'SELLER-CATALOG-1',6,7,1,?);`, id)
+	assert.NoError(t, err)
+	productID, _ := res.LastInsertId()
+	_, err = db.Exec(`INSERT INTO product_records(last_update_date,purchase_price,sale_price,product_id)
+		VALUES ('2022-01-01',10,15,?);`, productID)
+	assert.NoError(t, err)
+
+	t.Run("Gets the products of a seller", func(t *testing.T) {
+		products, err := repo.Products(context.TODO(), id)
+
+		assert.NoError(t, err)
+		assert.Len(t, products, 1)
+		assert.Equal(t, int(productID), products[0].ID)
+	})
+	t.Run("Sums up the performance of a seller", func(t *testing.T) {
+		perf, err := repo.Performance(context.TODO(), id)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.SellerPerformance{SellerID: id, ProductsCount: 1}, perf)
+	})
+	t.Run("Counts what is hidden with the seller", func(t *testing.T) {
+		impact, err := repo.DeleteImpact(context.TODO(), id)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.SellerDeleteImpact{SellerID: id, Products: 1, ProductRecords: 1}, impact)
 	})
 }
 
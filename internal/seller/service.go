@@ -3,8 +3,11 @@ package seller
 import (
 	"context"
 	"errors"
+	"math"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/patch"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 )
 
 // Errors
@@ -13,14 +16,34 @@ var (
 	ErrCidAlreadyExists = errors.New("cid already registered")
 	ErrRepository       = errors.New("error saving seller")
 	ErrFindSellers      = errors.New("there are no registered sellers")
+	ErrLocalityNotFound = errors.New("locality not found")
 )
 
 type Service interface {
 	Create(c context.Context, s domain.Seller) (domain.Seller, error)
 	GetAll(c context.Context) ([]domain.Seller, error)
 	Get(ctx context.Context, id int) (domain.Seller, error)
+	// GetByIDs returns the sellers with the given ids, skipping those
+	// that don't exist.
+	GetByIDs(ctx context.Context, ids []int) ([]domain.Seller, error)
+	// CreateBatch creates sellers, saving all the valid ones at once.
+	// The result and error of each seller are at its index.
+	CreateBatch(ctx context.Context, sellers []domain.Seller) ([]domain.Seller, []error)
 	Update(ctx context.Context, id int, s domain.Seller) (domain.Seller, error)
-	Delete(ctx context.Context, id int) error
+	// UpdateBatch updates the sellers with the given ids like Update
+	// does. The result and error of each seller are at its index.
+	UpdateBatch(ctx context.Context, ids []int, sellers []domain.Seller) ([]domain.Seller, []error)
+	// Patch applies a JSON Merge Patch or JSON Patch to a seller.
+	// Unlike Update, it saves zero values, so fields can be cleared.
+	Patch(ctx context.Context, id int, p patch.Patch) (domain.Seller, error)
+	// Delete soft deletes a seller along with its products, and
+	// returns what was hidden with it. If dryRun is true, it only
+	// returns what would be hidden.
+	Delete(ctx context.Context, id int, dryRun bool) (domain.SellerDeleteImpact, error)
+	// Restore undoes the deletion of a seller and its products.
+	Restore(ctx context.Context, id int) (domain.Seller, error)
+	Products(ctx context.Context, id int) ([]domain.Product, error)
+	Performance(ctx context.Context, id int) (domain.SellerPerformance, error)
 }
 
 type service struct {
@@ -62,11 +85,108 @@ func (s *service) Create(c context.Context, seller domain.Seller) (domain.Seller
 	return seller, nil
 }
 
+func (s *service) GetByIDs(c context.Context, ids []int) ([]domain.Seller, error) {
+	sellers, err := s.repository.GetByIDs(c, ids)
+	if err != nil {
+		return nil, ErrFindSellers
+	}
+	return sellers, nil
+}
+
+func (s *service) CreateBatch(c context.Context, sellers []domain.Seller) ([]domain.Seller, []error) {
+	errs := make([]error, len(sellers))
+	var valid []domain.Seller
+	var indexes []int
+	keys := make([]int, len(sellers))
+	for i, seller := range sellers {
+		keys[i] = seller.CID
+	}
+	taken, err := s.repository.ExistingCIDs(c, keys)
+	if err != nil {
+		for i := range errs {
+			errs[i] = ErrRepository
+		}
+		return sellers, errs
+	}
+	cids := map[int]bool{}
+	for _, cid := range taken {
+		cids[cid] = true
+	}
+	for i, seller := range sellers {
+		if cids[seller.CID] {
+			errs[i] = ErrCidAlreadyExists
+			continue
+		}
+		cids[seller.CID] = true
+		valid = append(valid, seller)
+		indexes = append(indexes, i)
+	}
+	if len(valid) == 0 {
+		return sellers, errs
+	}
+
+	ids, err := s.repository.SaveAll(c, valid)
+	if err != nil {
+		// One seller failed the whole insert, so they are saved one by
+		// one to tell which.
+		ids = make([]int, len(valid))
+		for j, seller := range valid {
+			ids[j], errs[indexes[j]] = s.repository.Save(c, seller)
+		}
+	}
+	for j, i := range indexes {
+		if errs[i] != nil {
+			if !errors.Is(errs[i], ErrLocalityNotFound) {
+				errs[i] = ErrRepository
+			}
+			continue
+		}
+		sellers[i].ID = ids[j]
+	}
+	return sellers, errs
+}
+
+func (s *service) UpdateBatch(c context.Context, ids []int, sellers []domain.Seller) ([]domain.Seller, []error) {
+	errs := make([]error, len(ids))
+	updated := make([]domain.Seller, len(ids))
+	current, err := s.repository.GetByIDs(c, ids)
+	if err != nil {
+		for i := range errs {
+			errs[i] = ErrRepository
+		}
+		return updated, errs
+	}
+	byID := make(map[int]domain.Seller, len(current))
+	for _, seller := range current {
+		byID[seller.ID] = seller
+	}
+	for i, id := range ids {
+		seller, ok := byID[id]
+		if !ok {
+			errs[i] = ErrNotFound
+			continue
+		}
+		updated[i], errs[i] = s.update(c, seller, sellers[i])
+		if errs[i] == nil {
+			byID[id] = updated[i]
+		}
+	}
+	return updated, errs
+}
+
 func (s *service) Update(c context.Context, id int, newSeller domain.Seller) (domain.Seller, error) {
 	seller, err := s.repository.Get(c, id)
 	if err != nil {
 		return domain.Seller{}, ErrNotFound
 	}
+	return s.update(c, seller, newSeller)
+}
+
+// update saves the non zero fields of newSeller over seller.
+func (s *service) update(c context.Context, seller, newSeller domain.Seller) (domain.Seller, error) {
+	if err := sqlutil.CheckVersion(c, seller.Version); err != nil {
+		return domain.Seller{}, err
+	}
 	//Validates when CID is sent
 	if newSeller.CID != 0 {
 		//Validates if the past CID is different from the current one
@@ -94,19 +214,102 @@ func (s *service) Update(c context.Context, id int, newSeller domain.Seller) (do
 
 	errUpdate := s.repository.Update(c, seller)
 	if errUpdate != nil {
+		if errors.Is(errUpdate, sqlutil.ErrVersionMismatch) || errors.Is(errUpdate, ErrLocalityNotFound) {
+			return domain.Seller{}, errUpdate
+		}
 		return domain.Seller{}, ErrRepository
 	}
+	seller.Version++
 	return seller, nil
 }
 
-func (s *service) Delete(c context.Context, id int) error {
-	_, err := s.repository.Get(c, id)
+func (s *service) Patch(c context.Context, id int, p patch.Patch) (domain.Seller, error) {
+	seller, err := s.repository.Get(c, id)
 	if err != nil {
-		return ErrNotFound
+		return domain.Seller{}, ErrNotFound
+	}
+	if err := sqlutil.CheckVersion(c, seller.Version); err != nil {
+		return domain.Seller{}, err
+	}
+	patched, err := patch.Apply(seller, p)
+	if err != nil {
+		return domain.Seller{}, err
+	}
+	// Only the fields of the seller itself can be patched.
+	patched.ID, patched.DeletedAt, patched.Version = seller.ID, seller.DeletedAt, seller.Version
+	if patched.CID != seller.CID && s.repository.Exists(c, patched.CID) {
+		return domain.Seller{}, ErrCidAlreadyExists
+	}
+
+	errUpdate := s.repository.Update(c, patched)
+	if errUpdate != nil {
+		if errors.Is(errUpdate, sqlutil.ErrVersionMismatch) || errors.Is(errUpdate, ErrLocalityNotFound) {
+			return domain.Seller{}, errUpdate
+		}
+		return domain.Seller{}, ErrRepository
+	}
+	patched.Version++
+	return patched, nil
+}
+
+func (s *service) Delete(c context.Context, id int, dryRun bool) (domain.SellerDeleteImpact, error) {
+	seller, err := s.repository.Get(c, id)
+	if err != nil {
+		return domain.SellerDeleteImpact{}, ErrNotFound
+	}
+	if err := sqlutil.CheckVersion(c, seller.Version); err != nil {
+		return domain.SellerDeleteImpact{}, err
+	}
+	impact, err := s.repository.DeleteImpact(c, id)
+	if err != nil {
+		return domain.SellerDeleteImpact{}, ErrRepository
+	}
+	if dryRun {
+		return impact, nil
 	}
 	errDelete := s.repository.Delete(c, id)
 	if errDelete != nil {
-		return ErrRepository
+		if errors.Is(errDelete, sqlutil.ErrVersionMismatch) {
+			return domain.SellerDeleteImpact{}, errDelete
+		}
+		return domain.SellerDeleteImpact{}, ErrRepository
+	}
+	return impact, nil
+}
+
+func (s *service) Restore(c context.Context, id int) (domain.Seller, error) {
+	if err := s.repository.Restore(c, id); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return domain.Seller{}, ErrNotFound
+		}
+		return domain.Seller{}, ErrRepository
+	}
+	seller, err := s.repository.Get(c, id)
+	if err != nil {
+		return domain.Seller{}, ErrRepository
+	}
+	return seller, nil
+}
+
+func (s *service) Products(c context.Context, id int) ([]domain.Product, error) {
+	if _, err := s.repository.Get(c, id); err != nil {
+		return nil, ErrNotFound
+	}
+	products, err := s.repository.Products(c, id)
+	if err != nil {
+		return nil, ErrRepository
+	}
+	return products, nil
+}
+
+func (s *service) Performance(c context.Context, id int) (domain.SellerPerformance, error) {
+	if _, err := s.repository.Get(c, id); err != nil {
+		return domain.SellerPerformance{}, ErrNotFound
+	}
+	perf, err := s.repository.Performance(c, id)
+	if err != nil {
+		return domain.SellerPerformance{}, ErrRepository
 	}
-	return nil
+	perf.Revenue = math.Round(perf.Revenue*100) / 100
+	return perf, nil
 }
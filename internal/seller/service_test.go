@@ -7,6 +7,8 @@ import (
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/seller"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/patch"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -80,7 +82,7 @@ func TestDelete(t *testing.T) {
 		idToDelete := 1
 
 		repositoryMock.On("Get", mock.Anything, idToDelete).Return(domain.Seller{}, seller.ErrNotFound)
-		err := svc.Delete(context.TODO(), idToDelete)
+		_, err := svc.Delete(context.TODO(), idToDelete, false)
 
 		assert.ErrorIs(t, err, seller.ErrNotFound)
 	})
@@ -94,12 +96,15 @@ func TestDelete(t *testing.T) {
 			Address:     "test street",
 			Telephone:   "9999999",
 		}
+		impact := domain.SellerDeleteImpact{SellerID: expected.ID, Products: 2}
 		repositoryMock.On("Get", mock.Anything, expected.ID).Return(expected, nil)
+		repositoryMock.On("DeleteImpact", mock.Anything, expected.ID).Return(impact, nil)
 		repositoryMock.On("Delete", mock.Anything, expected.ID).Return(nil)
 
-		err := svc.Delete(context.TODO(), expected.ID)
+		received, err := svc.Delete(context.TODO(), expected.ID, false)
 
 		assert.NoError(t, err)
+		assert.Equal(t, impact, received)
 
 	})
 	t.Run("returns domain error when error occurs on repository", func(t *testing.T) {
@@ -113,13 +118,90 @@ func TestDelete(t *testing.T) {
 			Telephone:   "9999999",
 		}
 		repositoryMock.On("Get", mock.Anything, expected.ID).Return(expected, nil)
+		repositoryMock.On("DeleteImpact", mock.Anything, expected.ID).Return(domain.SellerDeleteImpact{}, nil)
 		repositoryMock.On("Delete", mock.Anything, expected.ID).Return(ErrRepository)
 
-		err := svc.Delete(context.TODO(), expected.ID)
+		_, err := svc.Delete(context.TODO(), expected.ID, false)
 
 		assert.ErrorIs(t, err, seller.ErrRepository)
 
 	})
+	t.Run("only previews the impact on a dry run", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := seller.NewService(&repositoryMock)
+
+		impact := domain.SellerDeleteImpact{SellerID: 1, Products: 2, ProductBatches: 3}
+		repositoryMock.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1}, nil)
+		repositoryMock.On("DeleteImpact", mock.Anything, 1).Return(impact, nil)
+
+		received, err := svc.Delete(context.TODO(), 1, true)
+
+		assert.NoError(t, err)
+		assert.Equal(t, impact, received)
+		repositoryMock.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+}
+
+func TestRestore(t *testing.T) {
+	t.Run("restores a deleted seller", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := seller.NewService(&repositoryMock)
+		expected := domain.Seller{ID: 1, CID: 1}
+
+		repositoryMock.On("Restore", mock.Anything, expected.ID).Return(nil)
+		repositoryMock.On("Get", mock.Anything, expected.ID).Return(expected, nil)
+		restored, err := svc.Restore(context.TODO(), expected.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, restored)
+	})
+	t.Run("returns not found when the seller is not deleted", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := seller.NewService(&repositoryMock)
+
+		repositoryMock.On("Restore", mock.Anything, 1).Return(seller.ErrNotFound)
+		_, err := svc.Restore(context.TODO(), 1)
+
+		assert.ErrorIs(t, err, seller.ErrNotFound)
+	})
+}
+
+func TestSellerCatalog(t *testing.T) {
+	t.Run("returns the products of a seller", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := seller.NewService(&repositoryMock)
+
+		expected := []domain.Product{{ID: 1, SellerID: 1}, {ID: 2, SellerID: 1}}
+		repositoryMock.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1}, nil)
+		repositoryMock.On("Products", mock.Anything, 1).Return(expected, nil)
+
+		received, err := svc.Products(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, received)
+	})
+	t.Run("returns error not found for the products of a missing seller", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := seller.NewService(&repositoryMock)
+
+		repositoryMock.On("Get", mock.Anything, 1).Return(domain.Seller{}, seller.ErrNotFound)
+
+		_, err := svc.Products(context.TODO(), 1)
+
+		assert.ErrorIs(t, err, seller.ErrNotFound)
+	})
+	t.Run("returns the performance with rounded revenue", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := seller.NewService(&repositoryMock)
+
+		repositoryMock.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1}, nil)
+		repositoryMock.On("Performance", mock.Anything, 1).Return(domain.SellerPerformance{SellerID: 1, UnitsSold: 3, Revenue: 30.300000000000004}, nil)
+
+		received, err := svc.Performance(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 30.3, received.Revenue)
+	})
 }
 func TestUpdateSeller(t *testing.T) {
 	t.Run("Update valid seller", func(t *testing.T) {
@@ -147,6 +229,7 @@ func TestUpdateSeller(t *testing.T) {
 
 		received, err := svc.Update(context.TODO(), 1, sellerUpdate)
 
+		sellerUpdate.Version = 1
 		assert.NoError(t, err)
 		assert.Equal(t, sellerUpdate, received)
 	})
@@ -218,6 +301,165 @@ func TestUpdateSeller(t *testing.T) {
 
 		assert.ErrorIs(t, err, seller.ErrCidAlreadyExists)
 	})
+	t.Run("Update fails if the seller is not in the expected version", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := seller.NewService(&repositoryMock)
+
+		repositoryMock.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, Version: 2}, nil)
+
+		ctx := sqlutil.WithIfMatch(context.TODO(), []int{1})
+		_, err := svc.Update(ctx, 1, domain.Seller{CompanyName: "Meli"})
+
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+		repositoryMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+	t.Run("Update fails if the seller changed after it was read", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := seller.NewService(&repositoryMock)
+
+		repositoryMock.On("Get", mock.Anything, 1).Return(domain.Seller{ID: 1, Version: 2}, nil)
+		repositoryMock.On("Update", mock.Anything, mock.Anything).Return(sqlutil.ErrVersionMismatch)
+
+		_, err := svc.Update(context.TODO(), 1, domain.Seller{CompanyName: "Meli"})
+
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+	})
+}
+
+func TestPatchSeller(t *testing.T) {
+	current := domain.Seller{
+		ID:          1,
+		CID:         123,
+		CompanyName: "TEST",
+		Address:     "test street",
+		Telephone:   "9999999",
+		LocalityID:  1,
+		Version:     2,
+	}
+	t.Run("saves zero values and cleared fields", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := seller.NewService(&repositoryMock)
+
+		patched := current
+		patched.Address = ""
+		patched.LocalityID = 0
+		repositoryMock.On("Get", mock.Anything, 1).Return(current, nil)
+		repositoryMock.On("Update", mock.Anything, patched).Return(nil)
+
+		p := patch.MergePatch{Patch: map[string]any{"address": nil, "locality_id": float64(0)}}
+		received, err := svc.Patch(context.TODO(), 1, p)
+
+		patched.Version = 3
+		assert.NoError(t, err)
+		assert.Equal(t, patched, received)
+	})
+	t.Run("does not patch the id", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := seller.NewService(&repositoryMock)
+
+		repositoryMock.On("Get", mock.Anything, 1).Return(current, nil)
+		repositoryMock.On("Update", mock.Anything, current).Return(nil)
+
+		p := patch.JSONPatch{{Op: "replace", Path: "/id", Value: []byte("7")}}
+		received, err := svc.Patch(context.TODO(), 1, p)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, received.ID)
+	})
+	t.Run("returns an error when the cid already exist", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := seller.NewService(&repositoryMock)
+
+		repositoryMock.On("Get", mock.Anything, 1).Return(current, nil)
+		repositoryMock.On("Exists", mock.Anything, 1234).Return(true)
+
+		_, err := svc.Patch(context.TODO(), 1, patch.MergePatch{Patch: map[string]any{"cid": float64(1234)}})
+
+		assert.ErrorIs(t, err, seller.ErrCidAlreadyExists)
+		repositoryMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+	t.Run("returns the error of a patch that can't be applied", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := seller.NewService(&repositoryMock)
+
+		repositoryMock.On("Get", mock.Anything, 1).Return(current, nil)
+
+		p := patch.JSONPatch{{Op: "test", Path: "/cid", Value: []byte("1")}}
+		_, err := svc.Patch(context.TODO(), 1, p)
+
+		assert.ErrorIs(t, err, patch.ErrTestFailed)
+		repositoryMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+	t.Run("fails if the seller is not in the expected version", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := seller.NewService(&repositoryMock)
+
+		repositoryMock.On("Get", mock.Anything, 1).Return(current, nil)
+
+		ctx := sqlutil.WithIfMatch(context.TODO(), []int{1})
+		_, err := svc.Patch(ctx, 1, patch.MergePatch{Patch: map[string]any{}})
+
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+	})
+}
+
+func TestSellerBatch(t *testing.T) {
+	t.Run("creates the valid sellers in a single save", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := seller.NewService(&repositoryMock)
+
+		sellers := []domain.Seller{{CID: 1, CompanyName: "A"}, {CID: 2, CompanyName: "B"}, {CID: 1, CompanyName: "C"}, {CID: 3, CompanyName: "D"}}
+		repositoryMock.On("ExistingCIDs", mock.Anything, []int{1, 2, 1, 3}).Return([]int{2}, nil)
+		repositoryMock.On("SaveAll", mock.Anything, []domain.Seller{{CID: 1, CompanyName: "A"}, {CID: 3, CompanyName: "D"}}).Return([]int{10, 11}, nil)
+
+		created, errs := svc.CreateBatch(context.TODO(), sellers)
+
+		assert.Equal(t, []error{nil, seller.ErrCidAlreadyExists, seller.ErrCidAlreadyExists, nil}, errs)
+		assert.Equal(t, 10, created[0].ID)
+		assert.Equal(t, 11, created[3].ID)
+	})
+	t.Run("saves the sellers one by one to tell which failed the single save", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := seller.NewService(&repositoryMock)
+
+		repositoryMock.On("ExistingCIDs", mock.Anything, []int{1, 2, 3}).Return([]int(nil), nil)
+		repositoryMock.On("SaveAll", mock.Anything, mock.Anything).Return([]int(nil), seller.ErrLocalityNotFound)
+		repositoryMock.On("Save", mock.Anything, domain.Seller{CID: 1}).Return(10, nil)
+		repositoryMock.On("Save", mock.Anything, domain.Seller{CID: 2}).Return(0, seller.ErrLocalityNotFound)
+		repositoryMock.On("Save", mock.Anything, domain.Seller{CID: 3}).Return(0, errors.New("connection lost"))
+
+		created, errs := svc.CreateBatch(context.TODO(), []domain.Seller{{CID: 1}, {CID: 2}, {CID: 3}})
+
+		assert.Equal(t, []error{nil, seller.ErrLocalityNotFound, seller.ErrRepository}, errs)
+		assert.Equal(t, 10, created[0].ID)
+	})
+	t.Run("does not save any seller if their cids cannot be checked", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := seller.NewService(&repositoryMock)
+
+		repositoryMock.On("ExistingCIDs", mock.Anything, []int{1, 2}).Return([]int(nil), errors.New("connection lost"))
+
+		_, errs := svc.CreateBatch(context.TODO(), []domain.Seller{{CID: 1}, {CID: 2}})
+
+		assert.Equal(t, []error{seller.ErrRepository, seller.ErrRepository}, errs)
+		repositoryMock.AssertNotCalled(t, "SaveAll", mock.Anything, mock.Anything)
+	})
+	t.Run("updates the sellers it finds", func(t *testing.T) {
+		repositoryMock := RepositoryMock{}
+		svc := seller.NewService(&repositoryMock)
+
+		current := domain.Seller{ID: 1, CID: 1, CompanyName: "A", Version: 1}
+		updated := current
+		updated.CompanyName = "B"
+		repositoryMock.On("GetByIDs", mock.Anything, []int{1, 2}).Return([]domain.Seller{current}, nil)
+		repositoryMock.On("Update", mock.Anything, updated).Return(nil)
+
+		received, errs := svc.UpdateBatch(context.TODO(), []int{1, 2}, []domain.Seller{{CompanyName: "B"}, {CompanyName: "C"}})
+
+		updated.Version = 2
+		assert.Equal(t, []error{nil, seller.ErrNotFound}, errs)
+		assert.Equal(t, updated, received[0])
+	})
 }
 
 func TestGetSeller(t *testing.T) {
@@ -303,22 +545,57 @@ func (r *RepositoryMock) Get(ctx context.Context, id int) (domain.Seller, error)
 	return args.Get(0).(domain.Seller), args.Error(1)
 }
 
+func (r *RepositoryMock) GetByIDs(ctx context.Context, ids []int) ([]domain.Seller, error) {
+	args := r.Called(ctx, ids)
+	return args.Get(0).([]domain.Seller), args.Error(1)
+}
+
 func (r *RepositoryMock) Exists(ctx context.Context, cid int) bool {
 	args := r.Called(ctx, cid)
 	return args.Get(0).(bool)
 }
 
+func (r *RepositoryMock) ExistingCIDs(ctx context.Context, cids []int) ([]int, error) {
+	args := r.Called(ctx, cids)
+	return args.Get(0).([]int), args.Error(1)
+}
+
 func (r *RepositoryMock) Save(ctx context.Context, s domain.Seller) (int, error) {
 	args := r.Called(ctx, s)
 	return args.Get(0).(int), args.Error(1)
 }
 
+func (r *RepositoryMock) SaveAll(ctx context.Context, sellers []domain.Seller) ([]int, error) {
+	args := r.Called(ctx, sellers)
+	return args.Get(0).([]int), args.Error(1)
+}
+
 func (r *RepositoryMock) Update(ctx context.Context, s domain.Seller) error {
 	args := r.Called(ctx, s)
 	return args.Error(0)
 }
 
+func (r *RepositoryMock) Products(ctx context.Context, id int) ([]domain.Product, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).([]domain.Product), args.Error(1)
+}
+
+func (r *RepositoryMock) Performance(ctx context.Context, id int) (domain.SellerPerformance, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).(domain.SellerPerformance), args.Error(1)
+}
+
+func (r *RepositoryMock) DeleteImpact(ctx context.Context, id int) (domain.SellerDeleteImpact, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).(domain.SellerDeleteImpact), args.Error(1)
+}
+
 func (r *RepositoryMock) Delete(ctx context.Context, id int) error {
 	args := r.Called(ctx, id)
 	return args.Error(0)
 }
+
+func (r *RepositoryMock) Restore(ctx context.Context, id int) error {
+	args := r.Called(ctx, id)
+	return args.Error(0)
+}
@@ -0,0 +1,45 @@
+package warehouse
+
+import (
+	"context"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/cache"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
+)
+
+type cachedRepository struct {
+	Repository
+	warehouses cache.Cache[int, domain.Warehouse]
+}
+
+// NewCachedRepository returns a Repository that keeps the warehouses
+// read by id in c, and removes them from it when they are written.
+// Deleted warehouses are never cached.
+func NewCachedRepository(r Repository, c cache.Cache[int, domain.Warehouse]) Repository {
+	return &cachedRepository{r, c}
+}
+
+func (r *cachedRepository) Get(ctx context.Context, id int) (domain.Warehouse, error) {
+	if sqlutil.IncludeDeleted(ctx) {
+		return r.Repository.Get(ctx, id)
+	}
+	return cache.GetOrLoad(ctx, r.warehouses, id, func() (domain.Warehouse, error) {
+		return r.Repository.Get(ctx, id)
+	})
+}
+
+func (r *cachedRepository) Update(ctx context.Context, w domain.Warehouse) error {
+	defer r.warehouses.Delete(ctx, w.ID)
+	return r.Repository.Update(ctx, w)
+}
+
+func (r *cachedRepository) Delete(ctx context.Context, id int) error {
+	defer r.warehouses.Delete(ctx, id)
+	return r.Repository.Delete(ctx, id)
+}
+
+func (r *cachedRepository) Restore(ctx context.Context, id int) error {
+	defer r.warehouses.Delete(ctx, id)
+	return r.Repository.Restore(ctx, id)
+}
@@ -5,18 +5,43 @@ import (
 	"database/sql"
 	"errors"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 )
 
 // Repository encapsulates the storage of a warehouse.
 type Repository interface {
 	GetAll(ctx context.Context) ([]domain.Warehouse, error)
 	Get(ctx context.Context, id int) (domain.Warehouse, error)
+	// GetByIDs returns the warehouses with the given ids, in a single
+	// query. Ids of missing warehouses are skipped.
+	GetByIDs(ctx context.Context, ids []int) ([]domain.Warehouse, error)
 	Exists(ctx context.Context, warehouseCode string) bool
+	// ExistingCodes returns which of the given warehouse codes are taken, in a
+	// single query.
+	ExistingCodes(ctx context.Context, codes []string) ([]string, error)
 	Save(ctx context.Context, w domain.Warehouse) (int, error)
+	// SaveAll saves warehouses in a single insert and returns their ids.
+	// If one of them fails, none is saved.
+	SaveAll(ctx context.Context, warehouses []domain.Warehouse) ([]int, error)
+	// Update saves w unless the warehouse is no longer in w.Version,
+	// in which case it fails with sqlutil.ErrVersionMismatch.
 	Update(ctx context.Context, w domain.Warehouse) error
+	// Delete soft deletes a warehouse,
+	// if it is in one of the versions expected by ctx.
 	Delete(ctx context.Context, id int) error
+	// Restore undoes the deletion of a warehouse.
+	Restore(ctx context.Context, id int) error
+	Sections(ctx context.Context, id int) ([]domain.SectionUsage, error)
+	CountEmployees(ctx context.Context, id int) (int, error)
+	StockByProductType(ctx context.Context, id int) ([]domain.ProductTypeStock, error)
+	// ExpiringBatches returns the batches in stock that are due
+	// between the given times, soonest first.
+	ExpiringBatches(ctx context.Context, id int, from, to time.Time) ([]domain.Batches, error)
+	InboundOrders(ctx context.Context, id int, from, to time.Time) ([]domain.InboundOrder, error)
 }
 
 type repository struct {
@@ -30,7 +55,7 @@ func NewRepository(db *sql.DB) Repository {
 }
 
 func (r *repository) GetAll(ctx context.Context) ([]domain.Warehouse, error) {
-	query := "SELECT id, address, telephone, warehouse_code, minimum_capacity, minimum_temperature, locality_id FROM warehouses;"
+	query := "SELECT id, address, telephone, warehouse_code, minimum_capacity, minimum_temperature, locality_id, version, deleted_at FROM warehouses WHERE " + sqlutil.NotDeleted(ctx, "deleted_at") + ";"
 	rows, err := r.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -40,7 +65,7 @@ func (r *repository) GetAll(ctx context.Context) ([]domain.Warehouse, error) {
 
 	for rows.Next() {
 		w := domain.Warehouse{}
-		err = rows.Scan(&w.ID, &w.Address, &w.Telephone, &w.WarehouseCode, &w.MinimumCapacity, &w.MinimumTemperature, &w.LocalityID)
+		err = rows.Scan(&w.ID, &w.Address, &w.Telephone, &w.WarehouseCode, &w.MinimumCapacity, &w.MinimumTemperature, &w.LocalityID, &w.Version, sqlutil.NullTime(&w.DeletedAt))
 		if err != nil {
 			log.Print(err.Error())
 		}
@@ -51,10 +76,10 @@ func (r *repository) GetAll(ctx context.Context) ([]domain.Warehouse, error) {
 }
 
 func (r *repository) Get(ctx context.Context, id int) (domain.Warehouse, error) {
-	query := "SELECT id, address, telephone, warehouse_code, minimum_capacity, minimum_temperature, locality_id FROM warehouses WHERE id=?;"
+	query := "SELECT id, address, telephone, warehouse_code, minimum_capacity, minimum_temperature, locality_id, version, deleted_at FROM warehouses WHERE id=? AND " + sqlutil.NotDeleted(ctx, "deleted_at") + ";"
 	row := r.db.QueryRow(query, id)
 	w := domain.Warehouse{}
-	err := row.Scan(&w.ID, &w.Address, &w.Telephone, &w.WarehouseCode, &w.MinimumCapacity, &w.MinimumTemperature, &w.LocalityID)
+	err := row.Scan(&w.ID, &w.Address, &w.Telephone, &w.WarehouseCode, &w.MinimumCapacity, &w.MinimumTemperature, &w.LocalityID, &w.Version, sqlutil.NullTime(&w.DeletedAt))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.Warehouse{}, ErrNotFound
@@ -65,6 +90,29 @@ func (r *repository) Get(ctx context.Context, id int) (domain.Warehouse, error)
 	return w, nil
 }
 
+func (r *repository) GetByIDs(ctx context.Context, ids []int) ([]domain.Warehouse, error) {
+	if len(ids) == 0 {
+		return []domain.Warehouse{}, nil
+	}
+	in, args := sqlutil.In(ids)
+	query := "SELECT id, address, telephone, warehouse_code, minimum_capacity, minimum_temperature, locality_id, version, deleted_at FROM warehouses WHERE id IN " + in + " AND " + sqlutil.NotDeleted(ctx, "deleted_at")
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	warehouses := []domain.Warehouse{}
+	for rows.Next() {
+		w := domain.Warehouse{}
+		if err := rows.Scan(&w.ID, &w.Address, &w.Telephone, &w.WarehouseCode, &w.MinimumCapacity, &w.MinimumTemperature, &w.LocalityID, &w.Version, sqlutil.NullTime(&w.DeletedAt)); err != nil {
+			return nil, err
+		}
+		warehouses = append(warehouses, w)
+	}
+	return warehouses, rows.Err()
+}
+
 func (r *repository) Exists(ctx context.Context, warehouseCode string) bool {
 	query := "SELECT warehouse_code FROM warehouses WHERE warehouse_code=?;"
 	row := r.db.QueryRow(query, warehouseCode)
@@ -72,6 +120,10 @@ func (r *repository) Exists(ctx context.Context, warehouseCode string) bool {
 	return err == nil
 }
 
+func (r *repository) ExistingCodes(ctx context.Context, codes []string) ([]string, error) {
+	return sqlutil.ExistingKeys(ctx, r.db, "warehouses", "warehouse_code", codes)
+}
+
 func (r *repository) Save(ctx context.Context, w domain.Warehouse) (int, error) {
 	query := "INSERT INTO warehouses (address, telephone, warehouse_code, minimum_capacity, minimum_temperature, locality_id) VALUES (?, ?, ?, ?, ?, ?)"
 	stmt, err := r.db.Prepare(query)
@@ -81,6 +133,9 @@ func (r *repository) Save(ctx context.Context, w domain.Warehouse) (int, error)
 
 	res, err := stmt.Exec(&w.Address, &w.Telephone, &w.WarehouseCode, &w.MinimumCapacity, &w.MinimumTemperature, &w.LocalityID)
 	if err != nil {
+		if strings.HasPrefix(err.Error(), "Error 1452") {
+			return 0, ErrLocalityNotFound
+		}
 		return 0, err
 	}
 
@@ -92,15 +147,47 @@ func (r *repository) Save(ctx context.Context, w domain.Warehouse) (int, error)
 	return int(id), nil
 }
 
+func (r *repository) SaveAll(ctx context.Context, warehouses []domain.Warehouse) ([]int, error) {
+	query := "INSERT INTO warehouses (address, telephone, warehouse_code, minimum_capacity, minimum_temperature, locality_id) VALUES " + sqlutil.Rows(len(warehouses), 6)
+	args := make([]any, 0, len(warehouses)*6)
+	for _, w := range warehouses {
+		args = append(args, w.Address, w.Telephone, w.WarehouseCode, w.MinimumCapacity, w.MinimumTemperature, w.LocalityID)
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		if strings.HasPrefix(err.Error(), "Error 1452") {
+			return nil, ErrLocalityNotFound
+		}
+		return nil, err
+	}
+	keys := make([]string, len(warehouses))
+	for i, w := range warehouses {
+		keys[i] = w.WarehouseCode
+	}
+	ids, err := sqlutil.IDsByKey(ctx, tx, "warehouses", "warehouse_code", keys)
+	if err != nil {
+		return nil, err
+	}
+	return ids, tx.Commit()
+}
+
 func (r *repository) Update(ctx context.Context, w domain.Warehouse) error {
-	query := "UPDATE warehouses SET address=?, telephone=?, warehouse_code=?, minimum_capacity=?, minimum_temperature=?, locality_id=? WHERE id=?"
+	query := "UPDATE warehouses SET address=?, telephone=?, warehouse_code=?, minimum_capacity=?, minimum_temperature=?, locality_id=?, version=version+1 WHERE id=? AND version=? AND deleted_at IS NULL"
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return err
 	}
 
-	res, err := stmt.Exec(&w.Address, &w.Telephone, &w.WarehouseCode, &w.MinimumCapacity, &w.MinimumTemperature, &w.LocalityID, &w.ID)
+	res, err := stmt.Exec(&w.Address, &w.Telephone, &w.WarehouseCode, &w.MinimumCapacity, &w.MinimumTemperature, &w.LocalityID, &w.ID, &w.Version)
 	if err != nil {
+		if strings.HasPrefix(err.Error(), "Error 1452") {
+			return ErrLocalityNotFound
+		}
 		return err
 	}
 
@@ -109,20 +196,20 @@ func (r *repository) Update(ctx context.Context, w domain.Warehouse) error {
 		return err
 	}
 	if affected < 1 {
-		return ErrNotFound
+		return sqlutil.StaleOrMissing(ctx, r.db, "warehouses", w.ID, ErrNotFound)
 	}
 
 	return nil
 }
 
 func (r *repository) Delete(ctx context.Context, id int) error {
-	query := "DELETE FROM warehouses WHERE id=?"
+	query := "UPDATE warehouses SET deleted_at=?, version=version+1 WHERE id=? AND deleted_at IS NULL AND " + sqlutil.VersionMatches(ctx, "version")
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return err
 	}
 
-	res, err := stmt.Exec(id)
+	res, err := stmt.Exec(time.Now().UTC(), id)
 	if err != nil {
 		return err
 	}
@@ -132,9 +219,119 @@ func (r *repository) Delete(ctx context.Context, id int) error {
 		return err
 	}
 
+	if affected < 1 {
+		return sqlutil.StaleOrMissing(ctx, r.db, "warehouses", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+func (r *repository) Restore(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE warehouses SET deleted_at=NULL, version=version+1 WHERE id=? AND deleted_at IS NOT NULL;", id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
 	if affected < 1 {
 		return ErrNotFound
 	}
 
 	return nil
 }
+
+func (r *repository) Sections(ctx context.Context, id int) ([]domain.SectionUsage, error) {
+	query := "SELECT id, section_number, current_capacity, maximum_capacity, current_temperature, minimum_temperature FROM sections WHERE warehouse_id=? AND deleted_at IS NULL ORDER BY section_number;"
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sections := []domain.SectionUsage{}
+	for rows.Next() {
+		s := domain.SectionUsage{}
+		if err := rows.Scan(&s.SectionID, &s.SectionNumber, &s.UsedCapacity, &s.MaximumCapacity, &s.CurrentTemperature, &s.MinimumTemperature); err != nil {
+			return nil, err
+		}
+		sections = append(sections, s)
+	}
+	return sections, rows.Err()
+}
+
+func (r *repository) CountEmployees(ctx context.Context, id int) (int, error) {
+	query := "SELECT COUNT(*) FROM employees WHERE warehouse_id=? AND deleted_at IS NULL;"
+	var count int
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&count)
+	return count, err
+}
+
+func (r *repository) StockByProductType(ctx context.Context, id int) ([]domain.ProductTypeStock, error) {
+	query := "SELECT pt.id, pt.description, SUM(b.current_quantity) FROM product_batches b " +
+		"INNER JOIN sections s ON s.id = b.section_id " +
+		"INNER JOIN products p ON p.id = b.product_id " +
+		"INNER JOIN product_types pt ON pt.id = p.product_type_id " +
+		"WHERE s.warehouse_id=? GROUP BY pt.id, pt.description ORDER BY pt.id;"
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stock := []domain.ProductTypeStock{}
+	for rows.Next() {
+		s := domain.ProductTypeStock{}
+		if err := rows.Scan(&s.ProductTypeID, &s.Description, &s.Quantity); err != nil {
+			return nil, err
+		}
+		stock = append(stock, s)
+	}
+	return stock, rows.Err()
+}
+
+func (r *repository) ExpiringBatches(ctx context.Context, id int, from, to time.Time) ([]domain.Batches, error) {
+	query := "SELECT b.id, b.batch_number, b.current_quantity, b.current_temperature, b.due_date, b.initial_quantity, " +
+		"b.manufacturing_date, b.manufacturing_hour, b.minimum_temperature, b.product_id, b.section_id FROM product_batches b " +
+		"INNER JOIN sections s ON s.id = b.section_id " +
+		"WHERE s.warehouse_id=? AND b.current_quantity > 0 AND b.due_date BETWEEN ? AND ? ORDER BY b.due_date, b.id;"
+	rows, err := r.db.QueryContext(ctx, query, id, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	batches := []domain.Batches{}
+	for rows.Next() {
+		b := domain.Batches{}
+		err := rows.Scan(&b.ID, &b.BatchNumber, &b.CurrentQuantity, &b.CurrentTemperature, sqlutil.Time(&b.DueDate), &b.InitialQuantity,
+			sqlutil.Time(&b.ManufacturingDate), &b.ManufacturingHour, &b.MinimumTemperature, &b.ProductID, &b.SectionID)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, b)
+	}
+	return batches, rows.Err()
+}
+
+func (r *repository) InboundOrders(ctx context.Context, id int, from, to time.Time) ([]domain.InboundOrder, error) {
+	query := "SELECT id, order_date, order_number, employee_id, product_batch_id, warehouse_id FROM inbound_orders " +
+		"WHERE warehouse_id=? AND order_date BETWEEN ? AND ? ORDER BY order_date, id;"
+	rows, err := r.db.QueryContext(ctx, query, id, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []domain.InboundOrder{}
+	for rows.Next() {
+		i := domain.InboundOrder{}
+		if err := rows.Scan(&i.ID, sqlutil.Time(&i.OrderDate), &i.OrderNumber, &i.EmployeeID, &i.ProductBatchID, &i.WarehouseID); err != nil {
+			return nil, err
+		}
+		orders = append(orders, i)
+	}
+	return orders, rows.Err()
+}
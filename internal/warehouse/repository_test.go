@@ -3,6 +3,7 @@ package warehouse_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/warehouse"
@@ -100,6 +101,7 @@ func TestRepoUpdate(t *testing.T) {
 		id, _ := repo.Save(context.TODO(), wh)
 
 		wh.ID = id
+		wh.Version = 1
 		wh.WarehouseCode = "NEW CODE"
 
 		err := repo.Update(context.TODO(), wh)
@@ -128,6 +130,32 @@ func TestRepoDelete(t *testing.T) {
 	})
 }
 
+func TestRepoDashboard(t *testing.T) {
+	t.Run("Lists the sections of a warehouse", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := warehouse.NewRepository(db)
+
+		sections, err := repo.Sections(context.TODO(), 1)
+		assert.NoError(t, err)
+		for _, s := range sections {
+			assert.LessOrEqual(t, s.UsedCapacity, s.MaximumCapacity)
+		}
+	})
+	t.Run("Returns no inbound orders outside the range", func(t *testing.T) {
+		db := testutil.InitDatabase(t)
+		defer db.Close()
+
+		repo := warehouse.NewRepository(db)
+		from := time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		orders, err := repo.InboundOrders(context.TODO(), 1, from, from.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Empty(t, orders)
+	})
+}
+
 func getTestWarehouse() domain.Warehouse {
 	return domain.Warehouse{
 		Address:            "test street",
@@ -3,8 +3,11 @@ package warehouse
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/patch"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 )
 
 // Errors
@@ -13,6 +16,8 @@ var (
 	ErrInvalidWarehouseCode = errors.New("warehouse code has to be unique")
 	ErrorSavingWarehouse    = errors.New("error saving warehouse")
 	ErrorProcessedData      = errors.New("action could not be processed correctly due to invalid data provided")
+	ErrorDashboard          = errors.New("error building warehouse dashboard")
+	ErrLocalityNotFound     = errors.New("locality not found")
 )
 
 // Service is the interface for warehouse operations.
@@ -20,8 +25,25 @@ type Service interface {
 	Create(ctx context.Context, w domain.Warehouse) (domain.Warehouse, error)
 	GetAll(ctx context.Context) ([]domain.Warehouse, error)
 	Get(ctx context.Context, id int) (domain.Warehouse, error)
+	// GetByIDs returns the warehouses with the given ids, skipping
+	// those that don't exist.
+	GetByIDs(ctx context.Context, ids []int) ([]domain.Warehouse, error)
+	// CreateBatch creates warehouses, saving all the valid ones at
+	// once. The result and error of each warehouse are at its index.
+	CreateBatch(ctx context.Context, warehouses []domain.Warehouse) ([]domain.Warehouse, []error)
 	Update(ctx context.Context, w domain.Warehouse) (domain.Warehouse, error)
+	// UpdateBatch updates the warehouses with the given ids like Update
+	// does. The result and error of each warehouse are at its index.
+	UpdateBatch(ctx context.Context, ids []int, warehouses []domain.Warehouse) ([]domain.Warehouse, []error)
+	// Patch applies a JSON Merge Patch or JSON Patch to a warehouse.
+	// Unlike Update, it saves zero values, so fields can be cleared.
+	Patch(ctx context.Context, id int, p patch.Patch) (domain.Warehouse, error)
 	Delete(ctx context.Context, id int) error
+	// Restore undoes the deletion of a warehouse.
+	Restore(ctx context.Context, id int) (domain.Warehouse, error)
+	// Dashboard gathers the sections, staff, stock, batches due within
+	// the given number of days and today's inbound orders of a warehouse.
+	Dashboard(ctx context.Context, id int, expiringWithinDays int) (domain.WarehouseDashboard, error)
 }
 
 type service struct {
@@ -102,6 +124,14 @@ func (s *service) Update(ctx context.Context, w domain.Warehouse) (domain.Wareho
 	if err != nil {
 		return domain.Warehouse{}, ErrNotFound
 	}
+	return s.update(ctx, currentWarehouse, w)
+}
+
+// update saves the non zero fields of w over currentWarehouse.
+func (s *service) update(ctx context.Context, currentWarehouse, w domain.Warehouse) (domain.Warehouse, error) {
+	if err := sqlutil.CheckVersion(ctx, currentWarehouse.Version); err != nil {
+		return domain.Warehouse{}, err
+	}
 
 	if w.Address != "" {
 		currentWarehouse.Address = w.Address
@@ -128,14 +158,137 @@ func (s *service) Update(ctx context.Context, w domain.Warehouse) (domain.Wareho
 		currentWarehouse.LocalityID = w.LocalityID
 	}
 
-	err = s.repository.Update(ctx, currentWarehouse)
+	err := s.repository.Update(ctx, currentWarehouse)
+	if errors.Is(err, sqlutil.ErrVersionMismatch) || errors.Is(err, ErrLocalityNotFound) {
+		return domain.Warehouse{}, err
+	}
 	if err != nil {
 		return domain.Warehouse{}, ErrorProcessedData
 	}
 
+	currentWarehouse.Version++
 	return currentWarehouse, nil
 }
 
+func (s *service) Patch(ctx context.Context, id int, p patch.Patch) (domain.Warehouse, error) {
+	currentWarehouse, err := s.repository.Get(ctx, id)
+	if err != nil {
+		return domain.Warehouse{}, ErrNotFound
+	}
+	if err := sqlutil.CheckVersion(ctx, currentWarehouse.Version); err != nil {
+		return domain.Warehouse{}, err
+	}
+	patched, err := patch.Apply(currentWarehouse, p)
+	if err != nil {
+		return domain.Warehouse{}, err
+	}
+	// Only the fields of the warehouse itself can be patched.
+	patched.ID, patched.DeletedAt, patched.Version = currentWarehouse.ID, currentWarehouse.DeletedAt, currentWarehouse.Version
+	if patched.WarehouseCode != currentWarehouse.WarehouseCode && s.repository.Exists(ctx, patched.WarehouseCode) {
+		return domain.Warehouse{}, ErrInvalidWarehouseCode
+	}
+
+	err = s.repository.Update(ctx, patched)
+	if errors.Is(err, sqlutil.ErrVersionMismatch) || errors.Is(err, ErrLocalityNotFound) {
+		return domain.Warehouse{}, err
+	}
+	if err != nil {
+		return domain.Warehouse{}, ErrorProcessedData
+	}
+
+	patched.Version++
+	return patched, nil
+}
+
+func (s *service) GetByIDs(ctx context.Context, ids []int) ([]domain.Warehouse, error) {
+	ware, err := s.repository.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, ErrorProcessedData
+	}
+	return ware, nil
+}
+
+func (s *service) CreateBatch(ctx context.Context, warehouses []domain.Warehouse) ([]domain.Warehouse, []error) {
+	errs := make([]error, len(warehouses))
+	var valid []domain.Warehouse
+	var indexes []int
+	keys := make([]string, len(warehouses))
+	for i, w := range warehouses {
+		keys[i] = w.WarehouseCode
+	}
+	taken, err := s.repository.ExistingCodes(ctx, keys)
+	if err != nil {
+		for i := range errs {
+			errs[i] = ErrorSavingWarehouse
+		}
+		return warehouses, errs
+	}
+	codes := map[string]bool{}
+	for _, code := range taken {
+		codes[code] = true
+	}
+	for i, w := range warehouses {
+		if codes[w.WarehouseCode] {
+			errs[i] = ErrInvalidWarehouseCode
+			continue
+		}
+		codes[w.WarehouseCode] = true
+		valid = append(valid, w)
+		indexes = append(indexes, i)
+	}
+	if len(valid) == 0 {
+		return warehouses, errs
+	}
+
+	ids, err := s.repository.SaveAll(ctx, valid)
+	if err != nil {
+		// One warehouse failed the whole insert, so they are saved one
+		// by one to tell which.
+		ids = make([]int, len(valid))
+		for j, w := range valid {
+			ids[j], errs[indexes[j]] = s.repository.Save(ctx, w)
+		}
+	}
+	for j, i := range indexes {
+		if errs[i] != nil {
+			if !errors.Is(errs[i], ErrLocalityNotFound) {
+				errs[i] = ErrorSavingWarehouse
+			}
+			continue
+		}
+		warehouses[i].ID = ids[j]
+	}
+	return warehouses, errs
+}
+
+func (s *service) UpdateBatch(ctx context.Context, ids []int, warehouses []domain.Warehouse) ([]domain.Warehouse, []error) {
+	errs := make([]error, len(ids))
+	updated := make([]domain.Warehouse, len(ids))
+	current, err := s.repository.GetByIDs(ctx, ids)
+	if err != nil {
+		for i := range errs {
+			errs[i] = ErrorProcessedData
+		}
+		return updated, errs
+	}
+	byID := make(map[int]domain.Warehouse, len(current))
+	for _, w := range current {
+		byID[w.ID] = w
+	}
+	for i, id := range ids {
+		w, ok := byID[id]
+		if !ok {
+			errs[i] = ErrNotFound
+			continue
+		}
+		updated[i], errs[i] = s.update(ctx, w, warehouses[i])
+		if errs[i] == nil {
+			byID[id] = updated[i]
+		}
+	}
+	return updated, errs
+}
+
 // Delete deletes a warehouse by its ID.
 //
 //	@summary	Deletes a warehouse by ID.
@@ -145,9 +298,84 @@ func (s *service) Update(ctx context.Context, w domain.Warehouse) (domain.Wareho
 //	@tags		Warehouse
 func (s *service) Delete(ctx context.Context, id int) error {
 	err := s.repository.Delete(ctx, id)
+	if errors.Is(err, sqlutil.ErrVersionMismatch) {
+		return err
+	}
 	if err != nil {
 		return ErrNotFound
 	}
 
 	return nil
 }
+
+// Restore undoes the deletion of a warehouse.
+//
+//	@summary	Restores a deleted warehouse by ID.
+//	@param		id	path	int	true	"Warehouse ID"
+//	@return		200 {object} domain.Warehouse
+//	@return		404 {object} NotFoundError "Warehouse not found"
+//	@tags		Warehouse
+func (s *service) Restore(ctx context.Context, id int) (domain.Warehouse, error) {
+	err := s.repository.Restore(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return domain.Warehouse{}, ErrNotFound
+	}
+	if err != nil {
+		return domain.Warehouse{}, ErrorProcessedData
+	}
+
+	return s.repository.Get(ctx, id)
+}
+
+// Dashboard gathers the current state of a warehouse.
+//
+//	@summary	Retrieves the dashboard of a warehouse.
+//	@param		id	path	int	true	"Warehouse ID"
+//	@return		200 {object} domain.WarehouseDashboard
+//	@return		404 {object} NotFoundError "Warehouse not found"
+//	@tags		Warehouse
+func (s *service) Dashboard(ctx context.Context, id int, expiringWithinDays int) (domain.WarehouseDashboard, error) {
+	w, err := s.repository.Get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return domain.WarehouseDashboard{}, ErrNotFound
+	}
+	if err != nil {
+		return domain.WarehouseDashboard{}, ErrorDashboard
+	}
+	dashboard := domain.WarehouseDashboard{Warehouse: w}
+
+	if dashboard.Sections, err = s.repository.Sections(ctx, id); err != nil {
+		return domain.WarehouseDashboard{}, ErrorDashboard
+	}
+	for i := range dashboard.Sections {
+		dashboard.Sections[i].TemperatureStatus = temperatureStatus(dashboard.Sections[i])
+	}
+
+	if dashboard.EmployeesCount, err = s.repository.CountEmployees(ctx, id); err != nil {
+		return domain.WarehouseDashboard{}, ErrorDashboard
+	}
+	if dashboard.StockByProductType, err = s.repository.StockByProductType(ctx, id); err != nil {
+		return domain.WarehouseDashboard{}, ErrorDashboard
+	}
+
+	now := time.Now().UTC()
+	dashboard.ExpiringBatches, err = s.repository.ExpiringBatches(ctx, id, now, now.AddDate(0, 0, expiringWithinDays))
+	if err != nil {
+		return domain.WarehouseDashboard{}, ErrorDashboard
+	}
+
+	today := now.Truncate(24 * time.Hour)
+	dashboard.TodaysInbound, err = s.repository.InboundOrders(ctx, id, today, today.Add(24*time.Hour-time.Nanosecond))
+	if err != nil {
+		return domain.WarehouseDashboard{}, ErrorDashboard
+	}
+
+	return dashboard, nil
+}
+
+func temperatureStatus(s domain.SectionUsage) string {
+	if s.CurrentTemperature < s.MinimumTemperature {
+		return domain.TemperatureBelowMinimum
+	}
+	return domain.TemperatureOK
+}
@@ -2,10 +2,15 @@ package warehouse_test
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/domain"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/internal/warehouse"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/patch"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -171,6 +176,7 @@ func TestUpdateWarehouse(t *testing.T) {
 
 		received, err := svc.Update(context.TODO(), expectedWarehouse)
 
+		expectedWarehouse.Version = 1
 		assert.NoError(t, err)
 		assert.Equal(t, expectedWarehouse, received)
 	})
@@ -234,6 +240,7 @@ func TestUpdateWarehouse(t *testing.T) {
 
 		received, err := svc.Update(context.TODO(), updatedWarehouse)
 
+		updatedWarehouse.Version = 1
 		assert.NoError(t, err)
 		assert.Equal(t, updatedWarehouse, received)
 	})
@@ -282,6 +289,114 @@ func TestUpdateWarehouse(t *testing.T) {
 		assert.Equal(t, domain.Warehouse{}, received)
 
 	})
+	t.Run("test update warehouse in another version", func(t *testing.T) {
+		repositoryMock := RepositoryWarehouseMock{}
+		svc := warehouse.NewService(&repositoryMock)
+
+		repositoryMock.On("Get", mock.Anything, 1).Return(domain.Warehouse{ID: 1, Version: 2}, nil)
+
+		ctx := sqlutil.WithIfMatch(context.TODO(), []int{1})
+		_, err := svc.Update(ctx, domain.Warehouse{ID: 1, Address: "Rua da Hora"})
+
+		assert.ErrorIs(t, err, sqlutil.ErrVersionMismatch)
+		repositoryMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+}
+
+func TestPatchWarehouse(t *testing.T) {
+	currentWarehouse := domain.Warehouse{
+		ID:                 1,
+		WarehouseCode:      "cod1",
+		Address:            "Rua da Hora",
+		Telephone:          "11111111",
+		MinimumCapacity:    10,
+		MinimumTemperature: 2,
+		LocalityID:         2,
+	}
+	t.Run("test patch warehouse with zero values", func(t *testing.T) {
+		repositoryMock := RepositoryWarehouseMock{}
+		svc := warehouse.NewService(&repositoryMock)
+
+		expectedWarehouse := currentWarehouse
+		expectedWarehouse.MinimumCapacity = 0
+		expectedWarehouse.MinimumTemperature = 0
+		repositoryMock.On("Get", mock.Anything, 1).Return(currentWarehouse, nil)
+		repositoryMock.On("Update", mock.Anything, expectedWarehouse).Return(nil)
+
+		p := patch.JSONPatch{
+			{Op: "replace", Path: "/minimum_capacity", Value: []byte("0")},
+			{Op: "replace", Path: "/minimum_temperature", Value: []byte("null")},
+		}
+		received, err := svc.Patch(context.TODO(), 1, p)
+
+		expectedWarehouse.Version = 1
+		assert.NoError(t, err)
+		assert.Equal(t, expectedWarehouse, received)
+	})
+	t.Run("test patch warehouse with duplicate code", func(t *testing.T) {
+		repositoryMock := RepositoryWarehouseMock{}
+		svc := warehouse.NewService(&repositoryMock)
+
+		repositoryMock.On("Get", mock.Anything, 1).Return(currentWarehouse, nil)
+		repositoryMock.On("Exists", mock.Anything, "cod2").Return(true)
+
+		_, err := svc.Patch(context.TODO(), 1, patch.MergePatch{Patch: map[string]any{"warehouse_code": "cod2"}})
+
+		assert.ErrorIs(t, err, warehouse.ErrInvalidWarehouseCode)
+		repositoryMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+	t.Run("test patch warehouse with an unknown field", func(t *testing.T) {
+		repositoryMock := RepositoryWarehouseMock{}
+		svc := warehouse.NewService(&repositoryMock)
+
+		repositoryMock.On("Get", mock.Anything, 1).Return(currentWarehouse, nil)
+
+		_, err := svc.Patch(context.TODO(), 1, patch.MergePatch{Patch: map[string]any{"color": "red"}})
+
+		assert.ErrorIs(t, err, patch.ErrInvalidPatch)
+	})
+	t.Run("test patch warehouse not found", func(t *testing.T) {
+		repositoryMock := RepositoryWarehouseMock{}
+		svc := warehouse.NewService(&repositoryMock)
+
+		repositoryMock.On("Get", mock.Anything, 1).Return(domain.Warehouse{}, sql.ErrNoRows)
+
+		_, err := svc.Patch(context.TODO(), 1, patch.MergePatch{Patch: map[string]any{}})
+
+		assert.ErrorIs(t, err, warehouse.ErrNotFound)
+	})
+}
+
+func TestBatchWarehouse(t *testing.T) {
+	t.Run("creates the valid warehouses in a single save", func(t *testing.T) {
+		repositoryMock := RepositoryWarehouseMock{}
+		svc := warehouse.NewService(&repositoryMock)
+
+		warehouses := []domain.Warehouse{{WarehouseCode: "cod1"}, {WarehouseCode: "cod2"}, {WarehouseCode: "cod1"}}
+		repositoryMock.On("ExistingCodes", mock.Anything, []string{"cod1", "cod2", "cod1"}).Return([]string{"cod2"}, nil)
+		repositoryMock.On("SaveAll", mock.Anything, []domain.Warehouse{{WarehouseCode: "cod1"}}).Return([]int{8}, nil)
+
+		created, errs := svc.CreateBatch(context.TODO(), warehouses)
+
+		assert.Equal(t, []error{nil, warehouse.ErrInvalidWarehouseCode, warehouse.ErrInvalidWarehouseCode}, errs)
+		assert.Equal(t, 8, created[0].ID)
+	})
+	t.Run("updates the warehouses it finds", func(t *testing.T) {
+		repositoryMock := RepositoryWarehouseMock{}
+		svc := warehouse.NewService(&repositoryMock)
+
+		current := domain.Warehouse{ID: 1, WarehouseCode: "cod1", Address: "Rua da Hora", Version: 1}
+		updated := current
+		updated.Address = "Rua Nova"
+		repositoryMock.On("GetByIDs", mock.Anything, []int{1, 2}).Return([]domain.Warehouse{current}, nil)
+		repositoryMock.On("Update", mock.Anything, updated).Return(nil)
+
+		received, errs := svc.UpdateBatch(context.TODO(), []int{1, 2}, []domain.Warehouse{{Address: "Rua Nova"}, {Address: "Rua Nova"}})
+
+		updated.Version = 2
+		assert.Equal(t, []error{nil, warehouse.ErrNotFound}, errs)
+		assert.Equal(t, updated, received[0])
+	})
 }
 
 func TestDeleteWarehouse(t *testing.T) {
@@ -319,6 +434,87 @@ func TestDeleteWarehouse(t *testing.T) {
 	})
 }
 
+func TestRestoreWarehouse(t *testing.T) {
+	t.Run("restores a deleted warehouse", func(t *testing.T) {
+		repositoryMock := RepositoryWarehouseMock{}
+		svc := warehouse.NewService(&repositoryMock)
+		expected := domain.Warehouse{ID: 1, WarehouseCode: "cod1"}
+
+		repositoryMock.On("Restore", mock.Anything, expected.ID).Return(nil)
+		repositoryMock.On("Get", mock.Anything, expected.ID).Return(expected, nil)
+		restored, err := svc.Restore(context.TODO(), expected.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, restored)
+	})
+	t.Run("returns not found when the warehouse is not deleted", func(t *testing.T) {
+		repositoryMock := RepositoryWarehouseMock{}
+		svc := warehouse.NewService(&repositoryMock)
+
+		repositoryMock.On("Restore", mock.Anything, 1).Return(warehouse.ErrNotFound)
+		_, err := svc.Restore(context.TODO(), 1)
+
+		assert.ErrorIs(t, err, warehouse.ErrNotFound)
+	})
+}
+
+func TestWarehouseDashboard(t *testing.T) {
+	t.Run("gathers the dashboard of a warehouse", func(t *testing.T) {
+		repositoryMock := RepositoryWarehouseMock{}
+		svc := warehouse.NewService(&repositoryMock)
+
+		w := domain.Warehouse{ID: 1, WarehouseCode: "cod1"}
+		sections := []domain.SectionUsage{
+			{SectionID: 1, UsedCapacity: 5, MaximumCapacity: 10, CurrentTemperature: 2, MinimumTemperature: 0},
+			{SectionID: 2, UsedCapacity: 1, MaximumCapacity: 10, CurrentTemperature: -3, MinimumTemperature: 0},
+		}
+		stock := []domain.ProductTypeStock{{ProductTypeID: 1, Description: "frozen", Quantity: 40}}
+		batches := []domain.Batches{{ID: 3, CurrentQuantity: 10}}
+		orders := []domain.InboundOrder{{ID: 4, WarehouseID: 1}}
+
+		repositoryMock.On("Get", mock.Anything, 1).Return(w, nil)
+		repositoryMock.On("Sections", mock.Anything, 1).Return(sections, nil)
+		repositoryMock.On("CountEmployees", mock.Anything, 1).Return(3, nil)
+		repositoryMock.On("StockByProductType", mock.Anything, 1).Return(stock, nil)
+		repositoryMock.On("ExpiringBatches", mock.Anything, 1, mock.Anything, mock.Anything).Return(batches, nil)
+		repositoryMock.On("InboundOrders", mock.Anything, 1, mock.Anything, mock.Anything).Return(orders, nil)
+
+		received, err := svc.Dashboard(context.TODO(), 1, 7)
+
+		assert.NoError(t, err)
+		assert.Equal(t, w, received.Warehouse)
+		assert.Equal(t, domain.TemperatureOK, received.Sections[0].TemperatureStatus)
+		assert.Equal(t, domain.TemperatureBelowMinimum, received.Sections[1].TemperatureStatus)
+		assert.Equal(t, 3, received.EmployeesCount)
+		assert.Equal(t, stock, received.StockByProductType)
+		assert.Equal(t, batches, received.ExpiringBatches)
+		assert.Equal(t, orders, received.TodaysInbound)
+	})
+
+	t.Run("returns not found for a missing warehouse", func(t *testing.T) {
+		repositoryMock := RepositoryWarehouseMock{}
+		svc := warehouse.NewService(&repositoryMock)
+
+		repositoryMock.On("Get", mock.Anything, 1).Return(domain.Warehouse{}, warehouse.ErrNotFound)
+
+		_, err := svc.Dashboard(context.TODO(), 1, 7)
+
+		assert.ErrorIs(t, err, warehouse.ErrNotFound)
+	})
+
+	t.Run("returns dashboard error if repository fails", func(t *testing.T) {
+		repositoryMock := RepositoryWarehouseMock{}
+		svc := warehouse.NewService(&repositoryMock)
+
+		repositoryMock.On("Get", mock.Anything, 1).Return(domain.Warehouse{ID: 1}, nil)
+		repositoryMock.On("Sections", mock.Anything, 1).Return([]domain.SectionUsage{}, errors.New("db down"))
+
+		_, err := svc.Dashboard(context.TODO(), 1, 7)
+
+		assert.ErrorIs(t, err, warehouse.ErrorDashboard)
+	})
+}
+
 type RepositoryWarehouseMock struct {
 	mock.Mock
 }
@@ -333,16 +529,31 @@ func (r *RepositoryWarehouseMock) Get(ctx context.Context, id int) (domain.Wareh
 	return args.Get(0).(domain.Warehouse), args.Error(1)
 }
 
+func (r *RepositoryWarehouseMock) GetByIDs(ctx context.Context, ids []int) ([]domain.Warehouse, error) {
+	args := r.Called(ctx, ids)
+	return args.Get(0).([]domain.Warehouse), args.Error(1)
+}
+
 func (r *RepositoryWarehouseMock) Exists(ctx context.Context, WarehouseCode string) bool {
 	args := r.Called(ctx, WarehouseCode)
 	return args.Get(0).(bool)
 }
 
+func (r *RepositoryWarehouseMock) ExistingCodes(ctx context.Context, codes []string) ([]string, error) {
+	args := r.Called(ctx, codes)
+	return args.Get(0).([]string), args.Error(1)
+}
+
 func (r *RepositoryWarehouseMock) Save(ctx context.Context, s domain.Warehouse) (int, error) {
 	args := r.Called(ctx, s)
 	return args.Get(0).(int), args.Error(1)
 }
 
+func (r *RepositoryWarehouseMock) SaveAll(ctx context.Context, warehouses []domain.Warehouse) ([]int, error) {
+	args := r.Called(ctx, warehouses)
+	return args.Get(0).([]int), args.Error(1)
+}
+
 func (r *RepositoryWarehouseMock) Update(ctx context.Context, s domain.Warehouse) error {
 	args := r.Called(ctx, s)
 	return args.Error(0)
@@ -352,3 +563,33 @@ func (r *RepositoryWarehouseMock) Delete(ctx context.Context, id int) error {
 	args := r.Called(ctx, id)
 	return args.Error(0)
 }
+
+func (r *RepositoryWarehouseMock) Restore(ctx context.Context, id int) error {
+	args := r.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (r *RepositoryWarehouseMock) Sections(ctx context.Context, id int) ([]domain.SectionUsage, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).([]domain.SectionUsage), args.Error(1)
+}
+
+func (r *RepositoryWarehouseMock) CountEmployees(ctx context.Context, id int) (int, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).(int), args.Error(1)
+}
+
+func (r *RepositoryWarehouseMock) StockByProductType(ctx context.Context, id int) ([]domain.ProductTypeStock, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).([]domain.ProductTypeStock), args.Error(1)
+}
+
+func (r *RepositoryWarehouseMock) ExpiringBatches(ctx context.Context, id int, from, to time.Time) ([]domain.Batches, error) {
+	args := r.Called(ctx, id, from, to)
+	return args.Get(0).([]domain.Batches), args.Error(1)
+}
+
+func (r *RepositoryWarehouseMock) InboundOrders(ctx context.Context, id int, from, to time.Time) ([]domain.InboundOrder, error) {
+	args := r.Called(ctx, id, from, to)
+	return args.Get(0).([]domain.InboundOrder), args.Error(1)
+}
@@ -0,0 +1,52 @@
+package cache
+
+import "context"
+
+// Cache keeps values by key for a while. It is safe for concurrent use.
+// Implementations backed by external stores should treat their failures
+// as misses, so that the cache never fails a request.
+type Cache[K comparable, V any] interface {
+	// Get returns the value of key, if it is cached.
+	Get(ctx context.Context, key K) (V, bool)
+	// Set caches value under key.
+	Set(ctx context.Context, key K, value V)
+	// Delete removes the given keys from the cache.
+	Delete(ctx context.Context, keys ...K)
+	// Purge removes every key from the cache.
+	Purge(ctx context.Context)
+	// Stats returns how the cache performed so far.
+	Stats() Stats
+}
+
+// Stats counts the lookups and evictions of a cache.
+type Stats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+	Size      int   `json:"size"`
+}
+
+// Purger is any cache that can be purged, whatever its keys and values.
+type Purger interface {
+	Purge(ctx context.Context)
+}
+
+// StatsReporter is any cache that reports its stats, whatever its keys
+// and values.
+type StatsReporter interface {
+	Stats() Stats
+}
+
+// GetOrLoad returns the value of key from c or, on a miss, the one
+// returned by load, which is cached if load succeeds.
+func GetOrLoad[K comparable, V any](ctx context.Context, c Cache[K, V], key K, load func() (V, error)) (V, error) {
+	if v, ok := c.Get(ctx, key); ok {
+		return v, nil
+	}
+	v, err := load()
+	if err != nil {
+		return v, err
+	}
+	c.Set(ctx, key, v)
+	return v, nil
+}
@@ -0,0 +1,97 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("Returns the cached values", func(t *testing.T) {
+		c := cache.NewLRU[int, string](2, time.Minute)
+
+		c.Set(ctx, 1, "one")
+		v, ok := c.Get(ctx, 1)
+		_, missing := c.Get(ctx, 2)
+
+		assert.True(t, ok)
+		assert.Equal(t, "one", v)
+		assert.False(t, missing)
+		assert.Equal(t, cache.Stats{Hits: 1, Misses: 1, Size: 1}, c.Stats())
+	})
+	t.Run("Evicts the least recently used key", func(t *testing.T) {
+		c := cache.NewLRU[int, string](2, time.Minute)
+
+		c.Set(ctx, 1, "one")
+		c.Set(ctx, 2, "two")
+		c.Get(ctx, 1)
+		c.Set(ctx, 3, "three")
+
+		_, ok1 := c.Get(ctx, 1)
+		_, ok2 := c.Get(ctx, 2)
+		_, ok3 := c.Get(ctx, 3)
+		assert.True(t, ok1)
+		assert.False(t, ok2)
+		assert.True(t, ok3)
+		assert.Equal(t, int64(1), c.Stats().Evictions)
+	})
+	t.Run("Expires values after the ttl", func(t *testing.T) {
+		c := cache.NewLRU[int, string](2, 10*time.Millisecond)
+
+		c.Set(ctx, 1, "one")
+		time.Sleep(20 * time.Millisecond)
+
+		_, ok := c.Get(ctx, 1)
+		assert.False(t, ok)
+		assert.Equal(t, 0, c.Stats().Size)
+	})
+	t.Run("Deletes and purges keys", func(t *testing.T) {
+		c := cache.NewLRU[int, string](3, time.Minute)
+
+		c.Set(ctx, 1, "one")
+		c.Set(ctx, 2, "two")
+		c.Set(ctx, 3, "three")
+		c.Delete(ctx, 1, 2)
+		_, ok := c.Get(ctx, 1)
+		assert.False(t, ok)
+		assert.Equal(t, 1, c.Stats().Size)
+
+		c.Purge(ctx)
+		assert.Equal(t, 0, c.Stats().Size)
+	})
+}
+
+func TestGetOrLoad(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("Loads and caches missing values", func(t *testing.T) {
+		c := cache.NewLRU[int, string](2, time.Minute)
+		loads := 0
+		load := func() (string, error) {
+			loads++
+			return "one", nil
+		}
+
+		cache.GetOrLoad[int, string](ctx, c, 1, load)
+		v, err := cache.GetOrLoad[int, string](ctx, c, 1, load)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "one", v)
+		assert.Equal(t, 1, loads)
+	})
+	t.Run("Does not cache errors", func(t *testing.T) {
+		c := cache.NewLRU[int, string](2, time.Minute)
+		errLoad := errors.New("load failed")
+
+		_, err := cache.GetOrLoad[int, string](ctx, c, 1, func() (string, error) { return "", errLoad })
+
+		assert.ErrorIs(t, err, errLoad)
+		assert.Equal(t, 0, c.Stats().Size)
+	})
+}
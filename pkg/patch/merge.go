@@ -0,0 +1,31 @@
+package patch
+
+// MergePatch is a JSON Merge Patch (RFC 7396): an object whose members
+// replace those of the document, recursively, and whose null members
+// remove them. Anything else replaces the whole document.
+type MergePatch struct {
+	Patch any
+}
+
+func (p MergePatch) ApplyJSON(doc any) (any, error) {
+	return merge(doc, p.Patch), nil
+}
+
+func merge(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = map[string]any{}
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = merge(targetObject[name], value)
+	}
+	return targetObject
+}
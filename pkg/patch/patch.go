@@ -0,0 +1,96 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	// MergePatchContentType is the media type of a JSON Merge Patch
+	// (RFC 7396).
+	MergePatchContentType = "application/merge-patch+json"
+	// JSONPatchContentType is the media type of a JSON Patch
+	// (RFC 6902).
+	JSONPatchContentType = "application/json-patch+json"
+)
+
+var (
+	// ErrInvalidPatch is returned for patches that are malformed or
+	// that can't be applied to the resource.
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrTestFailed is returned when a test operation of a JSON Patch
+	// does not hold.
+	ErrTestFailed = errors.New("patch test failed")
+)
+
+// Patch is a change to a JSON document.
+type Patch interface {
+	// ApplyJSON returns doc, a decoded JSON document, with the change
+	// applied. doc may be modified.
+	ApplyJSON(doc any) (any, error)
+}
+
+// Supported reports whether a patch of contentType can be parsed.
+func Supported(contentType string) bool {
+	return contentType == MergePatchContentType || contentType == JSONPatchContentType
+}
+
+// Parse parses the body of a request with the given content type
+// into a patch.
+func Parse(contentType string, body []byte) (Patch, error) {
+	switch contentType {
+	case MergePatchContentType:
+		var p any
+		if err := json.Unmarshal(body, &p); err != nil {
+			return nil, invalid("%v", err)
+		}
+		return MergePatch{Patch: p}, nil
+	case JSONPatchContentType:
+		var p JSONPatch
+		if err := json.Unmarshal(body, &p); err != nil {
+			return nil, invalid("%v", err)
+		}
+		for _, op := range p {
+			if err := op.validate(); err != nil {
+				return nil, err
+			}
+		}
+		return p, nil
+	}
+	return nil, invalid("unsupported content type %q", contentType)
+}
+
+// Apply returns v with p applied to its JSON representation. Members
+// that the patch removes or sets to null are left with their zero
+// value, and members that T does not have are rejected.
+func Apply[T any](v T, p Patch) (T, error) {
+	var patched T
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return patched, err
+	}
+	var doc any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return patched, err
+	}
+	doc, err = p.ApplyJSON(doc)
+	if err != nil {
+		return patched, err
+	}
+	raw, err = json.Marshal(doc)
+	if err != nil {
+		return patched, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		return patched, invalid("%v", err)
+	}
+	return patched, nil
+}
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidPatch, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,107 @@
+package patch_test
+
+import (
+	"testing"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/patch"
+	"github.com/stretchr/testify/assert"
+)
+
+type resource struct {
+	ID     int      `json:"id"`
+	Name   string   `json:"name"`
+	Amount int      `json:"amount"`
+	Tags   []string `json:"tags"`
+}
+
+var current = resource{ID: 1, Name: "box", Amount: 3, Tags: []string{"a", "b"}}
+
+func apply(t *testing.T, contentType, body string) (resource, error) {
+	t.Helper()
+	p, err := patch.Parse(contentType, []byte(body))
+	if err != nil {
+		return resource{}, err
+	}
+	return patch.Apply(current, p)
+}
+
+func TestMergePatch(t *testing.T) {
+	t.Run("Replaces the members of the patch only", func(t *testing.T) {
+		patched, err := apply(t, patch.MergePatchContentType, `{"name": "crate"}`)
+		assert.NoError(t, err)
+		assert.Equal(t, resource{ID: 1, Name: "crate", Amount: 3, Tags: []string{"a", "b"}}, patched)
+	})
+	t.Run("Sets zero values", func(t *testing.T) {
+		patched, err := apply(t, patch.MergePatchContentType, `{"amount": 0, "tags": []}`)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, patched.Amount)
+		assert.Equal(t, []string{}, patched.Tags)
+	})
+	t.Run("Clears the members set to null", func(t *testing.T) {
+		patched, err := apply(t, patch.MergePatchContentType, `{"name": null}`)
+		assert.NoError(t, err)
+		assert.Equal(t, "", patched.Name)
+		assert.Equal(t, 3, patched.Amount)
+	})
+	t.Run("Rejects unknown members", func(t *testing.T) {
+		_, err := apply(t, patch.MergePatchContentType, `{"color": "red"}`)
+		assert.ErrorIs(t, err, patch.ErrInvalidPatch)
+	})
+	t.Run("Rejects values of the wrong type", func(t *testing.T) {
+		_, err := apply(t, patch.MergePatchContentType, `{"amount": "3"}`)
+		assert.ErrorIs(t, err, patch.ErrInvalidPatch)
+	})
+	t.Run("Does not modify the resource", func(t *testing.T) {
+		_, _ = apply(t, patch.MergePatchContentType, `{"tags": ["c"]}`)
+		assert.Equal(t, []string{"a", "b"}, current.Tags)
+	})
+}
+
+func TestJSONPatch(t *testing.T) {
+	t.Run("Applies the operations in order", func(t *testing.T) {
+		patched, err := apply(t, patch.JSONPatchContentType, `[
+			{"op": "test", "path": "/amount", "value": 3},
+			{"op": "replace", "path": "/amount", "value": 0},
+			{"op": "add", "path": "/tags/-", "value": "c"},
+			{"op": "remove", "path": "/tags/0"},
+			{"op": "copy", "from": "/tags/1", "path": "/name"},
+			{"op": "move", "from": "/tags/0", "path": "/tags/1"}
+		]`)
+		assert.NoError(t, err)
+		assert.Equal(t, resource{ID: 1, Name: "c", Amount: 0, Tags: []string{"c", "b"}}, patched)
+	})
+	t.Run("Sets null values", func(t *testing.T) {
+		patched, err := apply(t, patch.JSONPatchContentType, `[{"op": "replace", "path": "/name", "value": null}]`)
+		assert.NoError(t, err)
+		assert.Equal(t, "", patched.Name)
+	})
+	t.Run("Fails if a test does not hold", func(t *testing.T) {
+		_, err := apply(t, patch.JSONPatchContentType, `[
+			{"op": "replace", "path": "/name", "value": "crate"},
+			{"op": "test", "path": "/amount", "value": 4}
+		]`)
+		assert.ErrorIs(t, err, patch.ErrTestFailed)
+	})
+	t.Run("Fails if a path does not exist", func(t *testing.T) {
+		_, err := apply(t, patch.JSONPatchContentType, `[{"op": "replace", "path": "/tags/2", "value": "c"}]`)
+		assert.ErrorIs(t, err, patch.ErrInvalidPatch)
+	})
+	t.Run("Rejects malformed operations", func(t *testing.T) {
+		for _, body := range []string{
+			`{"op": "add"}`,
+			`[{"op": "increment", "path": "/amount"}]`,
+			`[{"op": "add", "path": "/amount"}]`,
+			`[{"op": "remove", "path": "amount"}]`,
+		} {
+			_, err := apply(t, patch.JSONPatchContentType, body)
+			assert.ErrorIs(t, err, patch.ErrInvalidPatch, body)
+		}
+	})
+	t.Run("Decodes escaped paths", func(t *testing.T) {
+		p, err := patch.Parse(patch.JSONPatchContentType, []byte(`[{"op": "add", "path": "/a~1b~0c", "value": 1}]`))
+		assert.NoError(t, err)
+		doc, err := p.ApplyJSON(map[string]any{})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"a/b~c": float64(1)}, doc)
+	})
+}
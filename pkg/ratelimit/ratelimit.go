@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidLimit is returned by ParseLimit for malformed limits.
+var ErrInvalidLimit = errors.New("invalid rate limit")
+
+// Limit lets a client make Requests per Period, in bursts of up to
+// Requests. It is a token bucket that holds Requests tokens and
+// refills at Requests per Period.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseLimit parses limits written as requests/period, e.g. "100/1m".
+func ParseLimit(s string) (Limit, error) {
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("%w %q: should be requests/period", ErrInvalidLimit, s)
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("%w %q: requests should be a positive integer", ErrInvalidLimit, s)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("%w %q: period should be a positive duration", ErrInvalidLimit, s)
+	}
+	return Limit{Requests: n, Period: d}, nil
+}
+
+// rate returns the tokens the bucket refills per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Result tells whether a request was allowed, and how much of its
+// client's budget is left.
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long until the next request is allowed, if
+	// this one was not.
+	RetryAfter time.Duration
+	// Reset is how long until the whole budget is available again.
+	Reset time.Duration
+}
+
+// Store keeps the token buckets of the clients. Implementations backed
+// by external stores must take tokens atomically, so that every
+// instance of the API shares the budget of a client.
+type Store interface {
+	// Take takes a token from the bucket of key, which has limit l.
+	Take(ctx context.Context, key string, l Limit) (Result, error)
+}
@@ -0,0 +1,54 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLimit(t *testing.T) {
+	t.Run("Parses requests per period", func(t *testing.T) {
+		l, err := ratelimit.ParseLimit("100/1m")
+		assert.NoError(t, err)
+		assert.Equal(t, ratelimit.Limit{Requests: 100, Period: time.Minute}, l)
+	})
+	t.Run("Fails on malformed limits", func(t *testing.T) {
+		for _, s := range []string{"", "100", "0/1m", "a/1m", "100/0s", "100/a"} {
+			_, err := ratelimit.ParseLimit(s)
+			assert.ErrorIs(t, err, ratelimit.ErrInvalidLimit, s)
+		}
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("Refills the bucket over time", func(t *testing.T) {
+		store := ratelimit.NewMemoryStore()
+		l := ratelimit.Limit{Requests: 1, Period: 20 * time.Millisecond}
+
+		first, _ := store.Take(ctx, "a", l)
+		second, _ := store.Take(ctx, "a", l)
+		time.Sleep(30 * time.Millisecond)
+		third, _ := store.Take(ctx, "a", l)
+
+		assert.True(t, first.Allowed)
+		assert.False(t, second.Allowed)
+		assert.Greater(t, second.RetryAfter, time.Duration(0))
+		assert.True(t, third.Allowed)
+	})
+	t.Run("Keeps a bucket per key", func(t *testing.T) {
+		store := ratelimit.NewMemoryStore()
+		l := ratelimit.Limit{Requests: 1, Period: time.Minute}
+
+		store.Take(ctx, "a", l)
+		res, _ := store.Take(ctx, "b", l)
+
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 0, res.Remaining)
+		assert.Equal(t, time.Minute, res.Reset.Round(time.Second))
+	})
+}
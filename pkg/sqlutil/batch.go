@@ -0,0 +1,88 @@
+package sqlutil
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// In returns an IN list with a placeholder for each of values, e.g.
+// "(?,?,?)", and the values as query arguments. values must not be
+// empty.
+func In[T any](values []T) (string, []any) {
+	args := make([]any, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return "(?" + strings.Repeat(",?", len(values)-1) + ")", args
+}
+
+// Rows returns the placeholders of a multi-row INSERT of n rows with
+// the given number of columns, e.g. "(?,?),(?,?)".
+func Rows(n, columns int) string {
+	row := "(?" + strings.Repeat(",?", columns-1) + ")"
+	return row + strings.Repeat(","+row, n-1)
+}
+
+// ExistingKeys returns which of keys the unique column of table
+// already holds, deleted rows included, in a single query.
+func ExistingKeys[K comparable](ctx context.Context, q Querier, table, column string, keys []K) ([]K, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	list, args := In(keys)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s IN %s", column, table, column, list)
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var existing []K
+	for rows.Next() {
+		var key K
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		existing = append(existing, key)
+	}
+	return existing, rows.Err()
+}
+
+// IDsByKey returns the ids of the rows of table whose unique column
+// holds each of keys, in the order of keys. It reads back the ids of
+// the rows of a multi-row INSERT, since MySQL only reports the first
+// one and the rest need not follow it, e.g. with an
+// auto_increment_increment other than 1. keys must not be empty.
+func IDsByKey[K comparable](ctx context.Context, q Querier, table, column string, keys []K) ([]int, error) {
+	list, args := In(keys)
+	query := fmt.Sprintf("SELECT id, %s FROM %s WHERE %s IN %s", column, table, column, list)
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byKey := make(map[K]int, len(keys))
+	for rows.Next() {
+		var id int
+		var key K
+		if err := rows.Scan(&id, &key); err != nil {
+			return nil, err
+		}
+		byKey[key] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, len(keys))
+	for i, key := range keys {
+		id, ok := byKey[key]
+		if !ok {
+			return nil, fmt.Errorf("%s %v was not inserted", column, key)
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
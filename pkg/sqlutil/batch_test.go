@@ -0,0 +1,20 @@
+package sqlutil_test
+
+import (
+	"testing"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatch(t *testing.T) {
+	t.Run("Lists a placeholder for each value", func(t *testing.T) {
+		list, args := sqlutil.In([]int{4, 8, 15})
+		assert.Equal(t, "(?,?,?)", list)
+		assert.Equal(t, []any{4, 8, 15}, args)
+	})
+	t.Run("Lists the placeholders of every row", func(t *testing.T) {
+		assert.Equal(t, "(?,?)", sqlutil.Rows(1, 2))
+		assert.Equal(t, "(?,?,?),(?,?,?)", sqlutil.Rows(2, 3))
+	})
+}
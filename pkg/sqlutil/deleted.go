@@ -0,0 +1,30 @@
+package sqlutil
+
+import "context"
+
+// IncludeDeletedKey is the context key that makes repositories return
+// soft deleted rows. It is a string so that it can be set on a
+// gin.Context with Set.
+const IncludeDeletedKey = "include_deleted"
+
+// WithDeleted returns a copy of ctx in which soft deleted rows are
+// visible.
+func WithDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, IncludeDeletedKey, true)
+}
+
+// IncludeDeleted reports whether soft deleted rows are visible in ctx.
+func IncludeDeleted(ctx context.Context) bool {
+	include, _ := ctx.Value(IncludeDeletedKey).(bool)
+	return include
+}
+
+// NotDeleted returns the condition that hides the soft deleted rows
+// of a query, given their deleted_at column. It is always true if
+// soft deleted rows are visible in ctx.
+func NotDeleted(ctx context.Context, column string) string {
+	if IncludeDeleted(ctx) {
+		return "TRUE"
+	}
+	return column + " IS NULL"
+}
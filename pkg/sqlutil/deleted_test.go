@@ -0,0 +1,20 @@
+package sqlutil_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotDeleted(t *testing.T) {
+	t.Run("Hides soft deleted rows by default", func(t *testing.T) {
+		assert.Equal(t, "s.deleted_at IS NULL", sqlutil.NotDeleted(context.TODO(), "s.deleted_at"))
+	})
+	t.Run("Shows soft deleted rows if requested", func(t *testing.T) {
+		ctx := sqlutil.WithDeleted(context.TODO())
+		assert.True(t, sqlutil.IncludeDeleted(ctx))
+		assert.Equal(t, "TRUE", sqlutil.NotDeleted(ctx, "s.deleted_at"))
+	})
+}
@@ -0,0 +1,69 @@
+package sqlutil
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Layout of DATETIME values as they are sent by MySQL.
+const DateTimeLayout = "2006-01-02 15:04:05.999999999"
+
+type timeScanner time.Time
+
+type nullTimeScanner struct {
+	t **time.Time
+}
+
+// Time returns a sql.Scanner that stores a DATETIME column into t.
+//
+// The connection string does not enable parseTime, so the driver
+// returns DATETIME columns as text. This scanner accepts both the
+// text and the time.Time representations.
+func Time(t *time.Time) sql.Scanner {
+	return (*timeScanner)(t)
+}
+
+// NullTime returns a sql.Scanner that stores a nullable DATETIME
+// column into t, setting it to nil on NULL.
+func NullTime(t **time.Time) sql.Scanner {
+	return nullTimeScanner{t: t}
+}
+
+func (n nullTimeScanner) Scan(src any) error {
+	if src == nil {
+		*n.t = nil
+		return nil
+	}
+	var v time.Time
+	if err := Time(&v).Scan(src); err != nil {
+		return err
+	}
+	*n.t = &v
+	return nil
+}
+
+func (t *timeScanner) Scan(src any) error {
+	switch v := src.(type) {
+	case time.Time:
+		*t = timeScanner(v)
+	case []byte:
+		return t.parse(string(v))
+	case string:
+		return t.parse(v)
+	case nil:
+		*t = timeScanner(time.Time{})
+	default:
+		return fmt.Errorf("cannot scan %T into time.Time", src)
+	}
+	return nil
+}
+
+func (t *timeScanner) parse(s string) error {
+	parsed, err := time.Parse(DateTimeLayout, s)
+	if err != nil {
+		return err
+	}
+	*t = timeScanner(parsed)
+	return nil
+}
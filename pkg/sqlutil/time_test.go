@@ -0,0 +1,49 @@
+package sqlutil_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTime(t *testing.T) {
+	t.Run("Scans DATETIME text", func(t *testing.T) {
+		var received time.Time
+		err := sqlutil.Time(&received).Scan([]byte("2023-07-05 10:00:00.000000"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2023, 7, 5, 10, 0, 0, 0, time.UTC), received)
+	})
+	t.Run("Scans time.Time values", func(t *testing.T) {
+		expected := time.Date(2023, 7, 5, 10, 0, 0, 0, time.UTC)
+		var received time.Time
+		err := sqlutil.Time(&received).Scan(expected)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, received)
+	})
+	t.Run("Fails on invalid values", func(t *testing.T) {
+		var received time.Time
+		assert.Error(t, sqlutil.Time(&received).Scan("yesterday"))
+		assert.Error(t, sqlutil.Time(&received).Scan(42))
+	})
+}
+
+func TestNullTime(t *testing.T) {
+	t.Run("Scans DATETIME text", func(t *testing.T) {
+		var received *time.Time
+		err := sqlutil.NullTime(&received).Scan([]byte("2023-07-05 10:00:00.000000"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2023, 7, 5, 10, 0, 0, 0, time.UTC), *received)
+	})
+	t.Run("Scans NULL as nil", func(t *testing.T) {
+		received := new(time.Time)
+		err := sqlutil.NullTime(&received).Scan(nil)
+
+		assert.NoError(t, err)
+		assert.Nil(t, received)
+	})
+}
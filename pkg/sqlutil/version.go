@@ -0,0 +1,95 @@
+package sqlutil
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrVersionMismatch is returned when a row is not in the version the
+// caller expected, either because it asked for another one with
+// If-Match or because the row changed since it was read.
+var ErrVersionMismatch = errors.New("version mismatch")
+
+// IfMatchKey is the context key of the versions a write expects to
+// find. It is a string so that it can be set on a gin.Context with Set.
+const IfMatchKey = "if_match"
+
+// WithIfMatch returns a copy of ctx in which writes only apply to rows
+// in one of versions.
+func WithIfMatch(ctx context.Context, versions []int) context.Context {
+	return context.WithValue(ctx, IfMatchKey, versions)
+}
+
+// IfMatch returns the versions expected by ctx, if any.
+func IfMatch(ctx context.Context) ([]int, bool) {
+	versions, ok := ctx.Value(IfMatchKey).([]int)
+	return versions, ok
+}
+
+// CheckVersion returns ErrVersionMismatch if ctx expects a version other
+// than version.
+func CheckVersion(ctx context.Context, version int) error {
+	versions, ok := IfMatch(ctx)
+	if !ok {
+		return nil
+	}
+	for _, v := range versions {
+		if v == version {
+			return nil
+		}
+	}
+	return ErrVersionMismatch
+}
+
+// VersionMatches returns the condition that restricts a write to the
+// versions expected by ctx, given their version column. It is always
+// true if ctx does not expect any version.
+func VersionMatches(ctx context.Context, column string) string {
+	versions, ok := IfMatch(ctx)
+	if !ok {
+		return "TRUE"
+	}
+	if len(versions) == 0 {
+		return "FALSE"
+	}
+	in := make([]string, len(versions))
+	for i, v := range versions {
+		in[i] = strconv.Itoa(v)
+	}
+	return fmt.Sprintf("%s IN (%s)", column, strings.Join(in, ", "))
+}
+
+// Querier is implemented by both *sql.DB and *sql.Tx.
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// StaleOrMissing tells why a write guarded by a version changed no row
+// of table: it returns ErrVersionMismatch if the row id is still live,
+// and notFound if it is gone or soft deleted.
+func StaleOrMissing(ctx context.Context, q Querier, table string, id int, notFound error) error {
+	return staleOrMissing(ctx, q, fmt.Sprintf("SELECT version FROM %s WHERE id=? AND deleted_at IS NULL;", table), id, notFound)
+}
+
+// StaleOrDeleted is StaleOrMissing for tables whose rows are deleted
+// rather than soft deleted.
+func StaleOrDeleted(ctx context.Context, q Querier, table string, id int, notFound error) error {
+	return staleOrMissing(ctx, q, fmt.Sprintf("SELECT version FROM %s WHERE id=?;", table), id, notFound)
+}
+
+func staleOrMissing(ctx context.Context, q Querier, query string, id int, notFound error) error {
+	var version int
+	err := q.QueryRowContext(ctx, query, id).Scan(&version)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return notFound
+	case err != nil:
+		return err
+	}
+	return ErrVersionMismatch
+}
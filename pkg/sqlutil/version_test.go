@@ -0,0 +1,30 @@
+package sqlutil_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckVersion(t *testing.T) {
+	t.Run("Accepts any version by default", func(t *testing.T) {
+		assert.NoError(t, sqlutil.CheckVersion(context.TODO(), 7))
+		assert.Equal(t, "TRUE", sqlutil.VersionMatches(context.TODO(), "version"))
+	})
+	t.Run("Accepts the expected versions", func(t *testing.T) {
+		ctx := sqlutil.WithIfMatch(context.TODO(), []int{3, 7})
+		assert.NoError(t, sqlutil.CheckVersion(ctx, 7))
+		assert.Equal(t, "version IN (3, 7)", sqlutil.VersionMatches(ctx, "version"))
+	})
+	t.Run("Rejects other versions", func(t *testing.T) {
+		ctx := sqlutil.WithIfMatch(context.TODO(), []int{3})
+		assert.ErrorIs(t, sqlutil.CheckVersion(ctx, 7), sqlutil.ErrVersionMismatch)
+	})
+	t.Run("Rejects every version if none can match", func(t *testing.T) {
+		ctx := sqlutil.WithIfMatch(context.TODO(), []int{})
+		assert.ErrorIs(t, sqlutil.CheckVersion(ctx, 1), sqlutil.ErrVersionMismatch)
+		assert.Equal(t, "FALSE", sqlutil.VersionMatches(ctx, "version"))
+	})
+}
@@ -0,0 +1,84 @@
+package web
+
+import "encoding/json"
+
+// MaxBatchSize is the most items a bulk read or a batch write can have.
+const MaxBatchSize = 100
+
+// BatchRequest creates the items of Create and updates those of Update
+// in a single call.
+type BatchRequest[C, U any] struct {
+	Create []C              `json:"create" binding:"dive"`
+	Update []BatchUpdate[U] `json:"update" binding:"dive"`
+}
+
+// Len returns the number of items of the batch.
+func (r BatchRequest[C, U]) Len() int {
+	return len(r.Create) + len(r.Update)
+}
+
+// Updates splits the updates of the batch into their ids and fields.
+func (r BatchRequest[C, U]) Updates() ([]int, []U) {
+	ids := make([]int, len(r.Update))
+	fields := make([]U, len(r.Update))
+	for i, u := range r.Update {
+		ids[i], fields[i] = u.ID, u.Fields
+	}
+	return ids, fields
+}
+
+// BatchUpdate is an item to update in a batch. It is sent as the
+// fields of U along with the id of the item.
+type BatchUpdate[U any] struct {
+	ID     int `binding:"required"`
+	Fields U
+}
+
+func (u *BatchUpdate[U]) UnmarshalJSON(data []byte) error {
+	var id struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(data, &id); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &u.Fields); err != nil {
+		return err
+	}
+	u.ID = id.ID
+	return nil
+}
+
+// BatchResult is the outcome of an item of a batch.
+type BatchResult struct {
+	Status int    `json:"status"`
+	Data   any    `json:"data,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchResponse has the outcome of each item of a batch, in the order
+// they were sent.
+type BatchResponse struct {
+	Create []BatchResult `json:"create"`
+	Update []BatchResult `json:"update"`
+}
+
+// BatchResults pairs up the items and errors of a batch into results,
+// with the given status for the items that succeeded and the one given
+// by errStatus for the others.
+func BatchResults[T any](items []T, errs []error, status int, errStatus func(error) int) []BatchResult {
+	results := make([]BatchResult, len(items))
+	for i, item := range items {
+		if errs[i] != nil {
+			results[i] = BatchResult{Status: errStatus(errs[i]), Error: errs[i].Error()}
+			continue
+		}
+		results[i] = BatchResult{Status: status, Data: item}
+	}
+	return results
+}
+
+// batchRequest documents BatchRequest, as swag can't read generic types.
+type batchRequest struct {
+	Create []any `json:"create"`
+	Update []any `json:"update"`
+}
@@ -0,0 +1,76 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ETag returns the entity tag of a version of a resource.
+func ETag(version int) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// SetETag sets the entity tag of a version of a resource on the response.
+func SetETag(c *gin.Context, version int) {
+	c.Header("ETag", ETag(version))
+}
+
+// NotModified sets the entity tag of a version of a resource on the
+// response and, if the request's If-None-Match says the client already
+// has that version, writes a 304 and returns true.
+func NotModified(c *gin.Context, version int) bool {
+	SetETag(c, version)
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+	versions, wildcard := ParseETags(header, true)
+	if !wildcard && !contains(versions, version) {
+		return false
+	}
+	c.Status(http.StatusNotModified)
+	c.Writer.WriteHeaderNow()
+	return true
+}
+
+// ParseETags parses the entity tags of an If-Match or If-None-Match
+// header into the versions they name, and reports whether it is "*".
+// Weak tags are skipped unless weak is true, as If-Match only allows
+// strong comparison. Tags that no version can have are skipped.
+func ParseETags(header string, weak bool) (versions []int, wildcard bool) {
+	versions = []int{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil, true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		version, err := strconv.Atoi(tag[1 : len(tag)-1])
+		if err != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	return versions, false
+}
+
+func contains(versions []int, version int) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,23 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) Writer {
+	return &csvWriter{csv.NewWriter(w)}
+}
+
+func (cw *csvWriter) Write(row []string) error {
+	return cw.w.Write(row)
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
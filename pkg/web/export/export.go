@@ -0,0 +1,159 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Format string
+
+const (
+	JSON Format = "json"
+	CSV  Format = "csv"
+	XLSX Format = "xlsx"
+)
+
+const (
+	csvContentType  = "text/csv"
+	xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	jsonContentType = "application/json"
+)
+
+// How many rows are written between two flushes of the response.
+const flushEvery = 100
+
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+// Writer encodes a table one row at a time.
+// Close must be called after the last row so the
+// encoding can be finalized.
+type Writer interface {
+	Write(row []string) error
+	Close() error
+}
+
+// Negotiate returns the format requested by the client.
+// The `format` query parameter takes precedence over the
+// Accept header; JSON is returned when neither is given.
+func Negotiate(c *gin.Context) (Format, error) {
+	if f, ok := c.GetQuery("format"); ok {
+		switch Format(strings.ToLower(f)) {
+		case JSON:
+			return JSON, nil
+		case CSV:
+			return CSV, nil
+		case XLSX:
+			return XLSX, nil
+		}
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, f)
+	}
+
+	for _, accepted := range strings.Split(c.GetHeader("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case csvContentType:
+			return CSV, nil
+		case xlsxContentType:
+			return XLSX, nil
+		case jsonContentType:
+			return JSON, nil
+		}
+	}
+	return JSON, nil
+}
+
+// NewWriter returns a Writer that encodes rows to w in format f.
+func NewWriter(f Format, w io.Writer) (Writer, error) {
+	switch f {
+	case CSV:
+		return newCSVWriter(w), nil
+	case XLSX:
+		return newXLSXWriter(w), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, f)
+}
+
+func ContentType(f Format) string {
+	switch f {
+	case CSV:
+		return csvContentType
+	case XLSX:
+		return xlsxContentType
+	}
+	return jsonContentType
+}
+
+// Stream writes a table to the response as a file attachment.
+//
+// Rows are produced by calling the given write function from
+// rows, and are sent to the client as they are produced instead
+// of being buffered. If rows fails before anything was written,
+// the error is returned so that the caller can still respond
+// with an error status.
+func Stream(c *gin.Context, f Format, filename string, header []string, rows func(write func(row []string) error) error) error {
+	var w Writer
+	count := 0
+
+	write := func(row []string) error {
+		if w == nil {
+			var err error
+			w, err = start(c, f, filename)
+			if err != nil {
+				return err
+			}
+			if err := w.Write(header); err != nil {
+				return err
+			}
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+		count++
+		if count%flushEvery == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	}
+
+	if err := rows(write); err != nil {
+		if w == nil {
+			return err
+		}
+		// Headers were already sent, so the best we can do is
+		// to stop writing and let the client see a truncated file.
+		c.Abort()
+		return nil
+	}
+
+	if w == nil {
+		// No rows: still send a file with only the header.
+		var err error
+		if w, err = start(c, f, filename); err != nil {
+			return err
+		}
+		if err := w.Write(header); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+func start(c *gin.Context, f Format, filename string) (Writer, error) {
+	w, err := NewWriter(f, c.Writer)
+	if err != nil {
+		return nil, err
+	}
+	c.Header("Content-Type", ContentType(f))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, f))
+	c.Status(http.StatusOK)
+	return w, nil
+}
@@ -0,0 +1,149 @@
+package export_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/export"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	t.Run("Defaults to JSON", func(t *testing.T) {
+		f, status := negotiate(t, "/", "")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, export.JSON, f)
+	})
+	t.Run("Uses the format query parameter", func(t *testing.T) {
+		f, _ := negotiate(t, "/?format=XLSX", "text/csv")
+		assert.Equal(t, export.XLSX, f)
+	})
+	t.Run("Uses the Accept header", func(t *testing.T) {
+		f, _ := negotiate(t, "/", "text/html, text/csv;q=0.9")
+		assert.Equal(t, export.CSV, f)
+	})
+	t.Run("Fails on unsupported format", func(t *testing.T) {
+		_, status := negotiate(t, "/?format=pdf", "")
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+}
+
+func TestStream(t *testing.T) {
+	t.Run("Writes CSV rows as an attachment", func(t *testing.T) {
+		server := testutil.CreateServer()
+		server.GET("/", func(c *gin.Context) {
+			err := export.Stream(c, export.CSV, "report", []string{"id", "name"}, func(write func([]string) error) error {
+				write([]string{"1", "Jane, Doe"})
+				return write([]string{"2", "John"})
+			})
+			assert.NoError(t, err)
+		})
+
+		req, res := testutil.MakeRequest(http.MethodGet, "/", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "text/csv", res.Header().Get("Content-Type"))
+		assert.Contains(t, res.Header().Get("Content-Disposition"), `filename="report.csv"`)
+		assert.Equal(t, "id,name\n1,\"Jane, Doe\"\n2,John\n", res.Body.String())
+	})
+	t.Run("Writes a valid XLSX workbook", func(t *testing.T) {
+		server := testutil.CreateServer()
+		server.GET("/", func(c *gin.Context) {
+			export.Stream(c, export.XLSX, "report", []string{"card", "total"}, func(write func([]string) error) error {
+				if err := write([]string{"007", "12.50"}); err != nil {
+					return err
+				}
+				return write([]string{"1234567890123456", "123456789012345"})
+			})
+		})
+
+		req, res := testutil.MakeRequest(http.MethodGet, "/", "")
+		server.ServeHTTP(res, req)
+
+		body := res.Body.Bytes()
+		zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+		assert.NoError(t, err)
+
+		sheet := readZipFile(t, zr, "xl/worksheets/sheet1.xml")
+		assert.Contains(t, sheet, `<c r="A2" t="inlineStr"><is><t>007</t></is></c>`)
+		assert.Contains(t, sheet, `<c r="B2"><v>12.50</v></c>`)
+		assert.Contains(t, sheet, `<c r="A3" t="inlineStr"><is><t>1234567890123456</t></is></c>`)
+		assert.Contains(t, sheet, `<c r="B3"><v>123456789012345</v></c>`)
+	})
+	t.Run("Returns the error if nothing was written", func(t *testing.T) {
+		server := testutil.CreateServer()
+		server.GET("/", func(c *gin.Context) {
+			err := export.Stream(c, export.CSV, "report", []string{"id"}, func(write func([]string) error) error {
+				return errors.New("not found")
+			})
+			web.Error(c, http.StatusNotFound, err.Error())
+		})
+
+		req, res := testutil.MakeRequest(http.MethodGet, "/", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+	t.Run("Writes only the header if there are no rows", func(t *testing.T) {
+		server := testutil.CreateServer()
+		server.GET("/", func(c *gin.Context) {
+			export.Stream(c, export.CSV, "report", []string{"id"}, func(write func([]string) error) error {
+				return nil
+			})
+		})
+
+		req, res := testutil.MakeRequest(http.MethodGet, "/", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "id\n", res.Body.String())
+	})
+}
+
+func negotiate(t *testing.T, url, accept string) (export.Format, int) {
+	t.Helper()
+	var format export.Format
+	server := testutil.CreateServer()
+	server.GET("/", func(c *gin.Context) {
+		f, err := export.Negotiate(c)
+		if err != nil {
+			web.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		format = f
+		web.Success(c, http.StatusOK, nil)
+	})
+
+	req, res := testutil.MakeRequest(http.MethodGet, url, "")
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	server.ServeHTTP(res, req)
+	return format, res.Code
+}
+
+func readZipFile(t *testing.T, zr *zip.Reader, name string) string {
+	t.Helper()
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		assert.NoError(t, err)
+		defer rc.Close()
+		var b strings.Builder
+		io.Copy(&b, rc)
+		return b.String()
+	}
+	t.Fatalf("file %s not found in archive", name)
+	return ""
+}
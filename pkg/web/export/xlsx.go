@@ -0,0 +1,144 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// The static parts of a workbook with a single worksheet.
+// Only the worksheet itself is generated while streaming.
+var xlsxStaticParts = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Report" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+const (
+	sheetHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	sheetFooter = `</sheetData></worksheet>`
+)
+
+// Values matching this are written as numeric cells. Values with
+// leading zeros (e.g. card numbers) are kept as text on purpose.
+var numericCell = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?$`)
+
+// maxNumericDigits is the most significant digits a spreadsheet keeps
+// in a number. Longer values, such as card numbers, are kept as text
+// so that their last digits are not lost.
+const maxNumericDigits = 15
+
+// isNumeric tells whether val is written as a numeric cell.
+func isNumeric(val string) bool {
+	if !numericCell.MatchString(val) {
+		return false
+	}
+	digits := strings.TrimLeft(strings.NewReplacer("-", "", ".", "").Replace(val), "0")
+	return len(digits) <= maxNumericDigits
+}
+
+type xlsxWriter struct {
+	zw    *zip.Writer
+	sheet io.Writer
+	row   int
+}
+
+func newXLSXWriter(w io.Writer) Writer {
+	return &xlsxWriter{zw: zip.NewWriter(w)}
+}
+
+func (xw *xlsxWriter) Write(row []string) error {
+	if xw.sheet == nil {
+		if err := xw.begin(); err != nil {
+			return err
+		}
+	}
+	xw.row++
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, xw.row)
+	for i, val := range row {
+		ref := fmt.Sprintf("%s%d", columnName(i), xw.row)
+		if isNumeric(val) {
+			fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, val)
+			continue
+		}
+		fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t>`, ref)
+		if err := xml.EscapeText(&b, []byte(val)); err != nil {
+			return err
+		}
+		b.WriteString(`</t></is></c>`)
+	}
+	b.WriteString(`</row>`)
+
+	_, err := io.WriteString(xw.sheet, b.String())
+	return err
+}
+
+func (xw *xlsxWriter) Close() error {
+	if xw.sheet == nil {
+		if err := xw.begin(); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(xw.sheet, sheetFooter); err != nil {
+		return err
+	}
+	return xw.zw.Close()
+}
+
+func (xw *xlsxWriter) begin() error {
+	for _, part := range xlsxStaticParts {
+		f, err := xw.zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+
+	sheet, err := xw.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(sheet, sheetHeader); err != nil {
+		return err
+	}
+	xw.sheet = sheet
+	return nil
+}
+
+// columnName converts a zero-based column index to its
+// spreadsheet name: 0 -> A, 25 -> Z, 26 -> AA...
+func columnName(i int) string {
+	name := ""
+	for i >= 0 {
+		name = string(rune('A'+i%26)) + name
+		i = i/26 - 1
+	}
+	return name
+}
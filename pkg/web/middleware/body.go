@@ -1,13 +1,18 @@
 package middleware
 
 import (
+	"io"
 	"net/http"
 
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/patch"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
 	"github.com/gin-gonic/gin"
 )
 
-const CONTEXT_BODY_VAR_NAME = "__body"
+const (
+	CONTEXT_BODY_VAR_NAME  = "__body"
+	CONTEXT_PATCH_VAR_NAME = "__patch"
+)
 
 func Body[T any]() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -25,3 +30,41 @@ func Body[T any]() gin.HandlerFunc {
 func GetBody[T any](c *gin.Context) T {
 	return c.MustGet(CONTEXT_BODY_VAR_NAME).(T)
 }
+
+// Parses the body of an update. JSON Merge Patches and JSON Patches,
+// told apart by their Content-Type, are left for the handler to apply
+// to the resource with GetPatch; any other body is bound to T, like
+// Body does.
+func Patch[T any]() gin.HandlerFunc {
+	body := Body[T]()
+	return func(c *gin.Context) {
+		contentType := c.ContentType()
+		if !patch.Supported(contentType) {
+			body(c)
+			return
+		}
+		raw, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			web.Error(c, http.StatusBadRequest, "could not read the request body")
+			c.Abort()
+			return
+		}
+		p, err := patch.Parse(contentType, raw)
+		if err != nil {
+			web.Error(c, http.StatusUnprocessableEntity, err.Error())
+			c.Abort()
+			return
+		}
+		c.Set(CONTEXT_PATCH_VAR_NAME, p)
+		c.Next()
+	}
+}
+
+// GetPatch returns the patch parsed by Patch, if the request sent one.
+func GetPatch(c *gin.Context) (patch.Patch, bool) {
+	p, ok := c.Get(CONTEXT_PATCH_VAR_NAME)
+	if !ok {
+		return nil, false
+	}
+	return p.(patch.Patch), true
+}
@@ -2,8 +2,10 @@ package middleware_test
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/patch"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
 	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
@@ -86,6 +88,43 @@ func TestBodyMapper(t *testing.T) {
 	})
 }
 
+func TestPatch(t *testing.T) {
+	server := testutil.CreateServer()
+	server.PATCH("/", middleware.Patch[PrimitiveTypesBody](), func(ctx *gin.Context) {
+		if p, ok := middleware.GetPatch(ctx); ok {
+			web.Success(ctx, http.StatusOK, p)
+			return
+		}
+		web.Success(ctx, http.StatusOK, middleware.GetBody[PrimitiveTypesBody](ctx))
+	})
+	request := func(contentType string, body any) *httptest.ResponseRecorder {
+		req, res := testutil.MakeRequest(http.MethodPatch, "/", body)
+		req.Header.Set("Content-Type", contentType)
+		server.ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("Keeps merge patches for the handler", func(t *testing.T) {
+		res := request(patch.MergePatchContentType, map[string]any{"Name": nil})
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.JSONEq(t, `{"data": {"Patch": {"Name": null}}}`, res.Body.String())
+	})
+	t.Run("Keeps JSON patches for the handler", func(t *testing.T) {
+		res := request(patch.JSONPatchContentType, []map[string]any{{"op": "remove", "path": "/Name"}})
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.JSONEq(t, `{"data": [{"op": "remove", "path": "/Name"}]}`, res.Body.String())
+	})
+	t.Run("Binds any other body", func(t *testing.T) {
+		res := request("application/json", PrimitiveTypesBody{ID: 1, Name: "John Doe", Height: 1.91})
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.JSONEq(t, `{"data": {"ID": 1, "Name": "John Doe", "Height": 1.91}}`, res.Body.String())
+	})
+	t.Run("Should return 422 if the patch is malformed", func(t *testing.T) {
+		res := request(patch.JSONPatchContentType, map[string]any{"op": "remove"})
+		assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	})
+}
+
 type PrimitiveTypesBody struct {
 	ID     int     `binding:"required"`
 	Name   string  `binding:"required"`
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+const ADMIN_TOKEN_HEADER = "X-Admin-Token"
+
+// Shows soft deleted rows when the query has include_deleted=true.
+// Only admins may ask for them: the request must send adminToken in
+// the X-Admin-Token header, and nobody can if adminToken is empty.
+func IncludeDeleted(adminToken string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw, ok := c.GetQuery("include_deleted")
+		if !ok {
+			c.Next()
+			return
+		}
+		include, err := strconv.ParseBool(raw)
+		if err != nil {
+			web.Error(c, http.StatusBadRequest, "include_deleted should be a boolean")
+			c.Abort()
+			return
+		}
+		if !include {
+			c.Next()
+			return
+		}
+		if !isAdmin(c, adminToken) {
+			web.Error(c, http.StatusForbidden, "include_deleted is restricted to admins")
+			c.Abort()
+			return
+		}
+		// Handlers pass either the gin.Context or the request context
+		// down to the repositories, so set both.
+		c.Set(sqlutil.IncludeDeletedKey, true)
+		c.Request = c.Request.WithContext(sqlutil.WithDeleted(c.Request.Context()))
+		c.Next()
+	}
+}
+
+// Restricts the route to admins, who send adminToken in the
+// X-Admin-Token header. Nobody can use it if adminToken is empty.
+func AdminOnly(adminToken string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isAdmin(c, adminToken) {
+			web.Error(c, http.StatusForbidden, "restricted to admins")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func isAdmin(c *gin.Context, adminToken string) bool {
+	token := c.GetHeader(ADMIN_TOKEN_HEADER)
+	return adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+}
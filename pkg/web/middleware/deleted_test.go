@@ -0,0 +1,86 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncludeDeleted(t *testing.T) {
+	newServer := func(adminToken string) *gin.Engine {
+		server := testutil.CreateServer()
+		handler := func(ctx *gin.Context) {
+			web.Success(ctx, 200, sqlutil.IncludeDeleted(ctx) && sqlutil.IncludeDeleted(ctx.Request.Context()))
+		}
+		server.GET("/sellers", middleware.IncludeDeleted(adminToken), handler)
+		return server
+	}
+	request := func(server *gin.Engine, url, token string) (int, bool) {
+		req, res := testutil.MakeRequest(http.MethodGet, url, "")
+		if token != "" {
+			req.Header.Set(middleware.ADMIN_TOKEN_HEADER, token)
+		}
+		server.ServeHTTP(res, req)
+		var received testutil.SuccessResponse[bool]
+		_ = json.Unmarshal(res.Body.Bytes(), &received)
+		return res.Code, received.Data
+	}
+
+	t.Run("Hides deleted rows by default", func(t *testing.T) {
+		code, include := request(newServer("secret"), "/sellers", "")
+		assert.Equal(t, http.StatusOK, code)
+		assert.False(t, include)
+	})
+	t.Run("Shows deleted rows to admins", func(t *testing.T) {
+		code, include := request(newServer("secret"), "/sellers?include_deleted=true", "secret")
+		assert.Equal(t, http.StatusOK, code)
+		assert.True(t, include)
+	})
+	t.Run("Should fail with status 403 without the admin token", func(t *testing.T) {
+		code, _ := request(newServer("secret"), "/sellers?include_deleted=true", "wrong")
+		assert.Equal(t, http.StatusForbidden, code)
+	})
+	t.Run("Should fail with status 403 when no admin token is configured", func(t *testing.T) {
+		code, _ := request(newServer(""), "/sellers?include_deleted=true", "")
+		assert.Equal(t, http.StatusForbidden, code)
+	})
+	t.Run("Should fail with status 400 on an invalid value", func(t *testing.T) {
+		code, _ := request(newServer("secret"), "/sellers?include_deleted=maybe", "secret")
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+}
+
+func TestAdminOnly(t *testing.T) {
+	newServer := func(adminToken string) *gin.Engine {
+		server := testutil.CreateServer()
+		server.POST("/sellers/1/restore", middleware.AdminOnly(adminToken), func(ctx *gin.Context) {
+			web.Success(ctx, http.StatusOK, nil)
+		})
+		return server
+	}
+	request := func(server *gin.Engine, token string) int {
+		req, res := testutil.MakeRequest(http.MethodPost, "/sellers/1/restore", "")
+		if token != "" {
+			req.Header.Set(middleware.ADMIN_TOKEN_HEADER, token)
+		}
+		server.ServeHTTP(res, req)
+		return res.Code
+	}
+
+	t.Run("Lets admins through", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request(newServer("secret"), "secret"))
+	})
+	t.Run("Should fail with status 403 without the admin token", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, request(newServer("secret"), "wrong"))
+	})
+	t.Run("Should fail with status 403 when no admin token is configured", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, request(newServer(""), ""))
+	})
+}
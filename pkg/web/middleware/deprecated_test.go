@@ -0,0 +1,28 @@
+package middleware_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecated(t *testing.T) {
+	t.Run("Should set deprecation headers and call handler", func(t *testing.T) {
+		server := testutil.CreateServer()
+
+		handler := func(ctx *gin.Context) { web.Success(ctx, 200, nil) }
+		server.GET("/old", middleware.Deprecated("/new"), handler)
+
+		req, res := testutil.MakeRequest(http.MethodGet, "/old", "")
+		server.ServeHTTP(res, req)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "true", res.Header().Get("Deprecation"))
+		assert.Equal(t, `</new>; rel="successor-version"`, res.Header().Get("Link"))
+	})
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+// Makes writes conditional on the If-Match header: the versions it
+// names are passed down to the services, which fail with
+// sqlutil.ErrVersionMismatch if the resource is in another one.
+// If-Match: * matches any version, like a missing header.
+func IfMatch() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("If-Match")
+		if header == "" {
+			c.Next()
+			return
+		}
+		versions, wildcard := web.ParseETags(header, false)
+		if wildcard {
+			c.Next()
+			return
+		}
+		// Handlers pass either the gin.Context or the request context
+		// down to the repositories, so set both.
+		c.Set(sqlutil.IfMatchKey, versions)
+		c.Request = c.Request.WithContext(sqlutil.WithIfMatch(c.Request.Context(), versions))
+		c.Next()
+	}
+}
@@ -0,0 +1,83 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/sqlutil"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIfMatch(t *testing.T) {
+	server := testutil.CreateServer()
+	server.PATCH("/sellers/1", middleware.IfMatch(), func(ctx *gin.Context) {
+		ginVersions, _ := sqlutil.IfMatch(ctx)
+		versions, ok := sqlutil.IfMatch(ctx.Request.Context())
+		assert.Equal(t, ginVersions, versions)
+		if !ok {
+			versions = nil
+		}
+		web.Success(ctx, http.StatusOK, versions)
+	})
+	request := func(header string) []int {
+		req, res := testutil.MakeRequest(http.MethodPatch, "/sellers/1", "")
+		if header != "" {
+			req.Header.Set("If-Match", header)
+		}
+		server.ServeHTTP(res, req)
+		var received testutil.SuccessResponse[[]int]
+		_ = json.Unmarshal(res.Body.Bytes(), &received)
+		return received.Data
+	}
+
+	t.Run("Expects no version without the header", func(t *testing.T) {
+		assert.Nil(t, request(""))
+	})
+	t.Run("Expects no version with a wildcard", func(t *testing.T) {
+		assert.Nil(t, request("*"))
+	})
+	t.Run("Expects the versions of the strong tags", func(t *testing.T) {
+		assert.Equal(t, []int{3, 4}, request(`"3", W/"5", "4"`))
+	})
+	t.Run("Expects an impossible version for unknown tags", func(t *testing.T) {
+		assert.Equal(t, []int{}, request(`"abc"`))
+	})
+}
+
+func TestNotModified(t *testing.T) {
+	server := testutil.CreateServer()
+	server.GET("/sellers/1", func(ctx *gin.Context) {
+		if web.NotModified(ctx, 3) {
+			return
+		}
+		web.Success(ctx, http.StatusOK, 1)
+	})
+	request := func(header string) (int, string) {
+		req, res := testutil.MakeRequest(http.MethodGet, "/sellers/1", "")
+		if header != "" {
+			req.Header.Set("If-None-Match", header)
+		}
+		server.ServeHTTP(res, req)
+		return res.Code, res.Header().Get("ETag")
+	}
+
+	t.Run("Sends the entity tag", func(t *testing.T) {
+		code, etag := request("")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, `"3"`, etag)
+	})
+	t.Run("Should return 304 if the client has the version", func(t *testing.T) {
+		code, etag := request(`"2", W/"3"`)
+		assert.Equal(t, http.StatusNotModified, code)
+		assert.Equal(t, `"3"`, etag)
+	})
+	t.Run("Sends the resource if the client has another version", func(t *testing.T) {
+		code, _ := request(`"2"`)
+		assert.Equal(t, http.StatusOK, code)
+	})
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+const IDEMPOTENCY_KEY_HEADER = "Idempotency-Key"
+
+// maxIdempotencyKeyLength is the longest key that can be stored.
+const maxIdempotencyKeyLength = 255
+
+// StoredResponse is what a request sent with an idempotency key got,
+// so that it can be replayed to its retries.
+type StoredResponse struct {
+	// RequestHash identifies the request that used the key.
+	RequestHash string
+	// Done is false while the request is being handled.
+	Done        bool
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// IdempotencyStore keeps the responses of requests by idempotency key.
+// Keys are only unique within a scope, which is the route they were
+// sent to.
+type IdempotencyStore interface {
+	// Claim reserves a key for the request with the given hash. If the
+	// key was already used, it returns what is stored for it instead,
+	// with claimed set to false.
+	Claim(ctx context.Context, scope, key, requestHash string) (stored StoredResponse, claimed bool, err error)
+	// Complete stores the response of the request that claimed a key.
+	Complete(ctx context.Context, scope, key string, res StoredResponse) error
+	// Release frees a claimed key, so that the request can be retried.
+	Release(ctx context.Context, scope, key string) error
+}
+
+// Makes the route safe to retry with the Idempotency-Key header: the
+// first request with a key is handled and its response stored, and
+// its retries get that response back, with Idempotent-Replayed: true.
+// A key sent again with a different request fails with 422, and one
+// whose request is still being handled with 409. Server errors are not
+// stored, so the request can be retried with the same key.
+func Idempotent(store IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IDEMPOTENCY_KEY_HEADER)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			web.Error(c, http.StatusBadRequest, "%s must be at most %d characters", IDEMPOTENCY_KEY_HEADER, maxIdempotencyKeyLength)
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			web.Error(c, http.StatusBadRequest, "could not read the request body")
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := c.Request.Method + " " + c.FullPath()
+		hash := requestHash(c.Request, body)
+		stored, claimed, err := store.Claim(c.Request.Context(), scope, key, hash)
+		if err != nil {
+			web.Error(c, http.StatusInternalServerError, "could not check the %s", IDEMPOTENCY_KEY_HEADER)
+			c.Abort()
+			return
+		}
+		if !claimed {
+			replay(c, stored, hash)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// The request context may be canceled by now, and the key must
+		// not stay claimed because of it.
+		ctx := context.Background()
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			_ = store.Release(ctx, scope, key)
+			return
+		}
+		res := StoredResponse{
+			RequestHash: hash,
+			Done:        true,
+			Status:      status,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		}
+		if err := store.Complete(ctx, scope, key, res); err != nil {
+			_ = store.Release(ctx, scope, key)
+		}
+	}
+}
+
+func replay(c *gin.Context, stored StoredResponse, hash string) {
+	switch {
+	case !stored.Done:
+		web.Error(c, http.StatusConflict, "a request with this %s is still being processed", IDEMPOTENCY_KEY_HEADER)
+	case stored.RequestHash != hash:
+		web.Error(c, http.StatusUnprocessableEntity, "%s was already used for a different request", IDEMPOTENCY_KEY_HEADER)
+	default:
+		c.Header("Idempotent-Replayed", "true")
+		c.Data(stored.Status, stored.ContentType, stored.Body)
+	}
+}
+
+// requestHash identifies a request by its method, URL and body.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder keeps a copy of the body written to the response.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
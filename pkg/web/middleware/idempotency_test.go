@@ -0,0 +1,152 @@
+package middleware_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryStore struct {
+	mu        sync.Mutex
+	responses map[string]middleware.StoredResponse
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{responses: map[string]middleware.StoredResponse{}}
+}
+
+func (s *memoryStore) Claim(ctx context.Context, scope, key, hash string) (middleware.StoredResponse, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stored, ok := s.responses[scope+key]; ok {
+		return stored, false, nil
+	}
+	s.responses[scope+key] = middleware.StoredResponse{RequestHash: hash}
+	return middleware.StoredResponse{}, true, nil
+}
+
+func (s *memoryStore) Complete(ctx context.Context, scope, key string, res middleware.StoredResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[scope+key] = res
+	return nil
+}
+
+func (s *memoryStore) Release(ctx context.Context, scope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.responses, scope+key)
+	return nil
+}
+
+func TestIdempotent(t *testing.T) {
+	store := newMemoryStore()
+	calls := 0
+	status := http.StatusCreated
+	server := testutil.CreateServer()
+	server.POST("/sellers", middleware.Idempotent(store), func(ctx *gin.Context) {
+		calls++
+		web.Success(ctx, status, calls)
+	})
+	request := func(key string, body any) *http.Response {
+		req, res := testutil.MakeRequest(http.MethodPost, "/sellers", body)
+		if key != "" {
+			req.Header.Set(middleware.IDEMPOTENCY_KEY_HEADER, key)
+		}
+		server.ServeHTTP(res, req)
+		return res.Result()
+	}
+
+	t.Run("Handles every request without a key", func(t *testing.T) {
+		calls = 0
+		request("", map[string]int{"cid": 1})
+		request("", map[string]int{"cid": 1})
+		assert.Equal(t, 2, calls)
+	})
+	t.Run("Replays the response of a retried request", func(t *testing.T) {
+		calls = 0
+		first := request("a", map[string]int{"cid": 1})
+		second := request("a", map[string]int{"cid": 1})
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, http.StatusCreated, second.StatusCode)
+		assert.Equal(t, first.Header.Get("Content-Type"), second.Header.Get("Content-Type"))
+		assert.Equal(t, "true", second.Header.Get("Idempotent-Replayed"))
+		assert.Empty(t, first.Header.Get("Idempotent-Replayed"))
+	})
+	t.Run("Replays the stored status and body", func(t *testing.T) {
+		calls = 0
+		status = http.StatusAccepted
+		first := request("d", map[string]int{"cid": 1})
+		status = http.StatusCreated
+		second := request("d", map[string]int{"cid": 1})
+
+		firstBody, _ := io.ReadAll(first.Body)
+		secondBody, _ := io.ReadAll(second.Body)
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, http.StatusAccepted, second.StatusCode)
+		assert.Equal(t, `{"data":1}`, string(firstBody))
+		assert.Equal(t, firstBody, secondBody)
+	})
+	t.Run("Should return 422 if the key is reused for another request", func(t *testing.T) {
+		res := request("a", map[string]int{"cid": 2})
+		assert.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
+	})
+	t.Run("Should return 409 if the request is still being handled", func(t *testing.T) {
+		_, _, _ = store.Claim(context.TODO(), "POST /sellers", "b", "")
+		res := request("b", map[string]int{"cid": 1})
+		assert.Equal(t, http.StatusConflict, res.StatusCode)
+	})
+	t.Run("Should return 400 if the key is too long", func(t *testing.T) {
+		res := request(strings.Repeat("k", 256), map[string]int{"cid": 1})
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+	t.Run("Does not store server errors", func(t *testing.T) {
+		calls = 0
+		status = http.StatusInternalServerError
+		request("c", map[string]int{"cid": 1})
+		status = http.StatusCreated
+		res := request("c", map[string]int{"cid": 1})
+		assert.Equal(t, 2, calls)
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+	})
+}
+
+func TestIdempotentInFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := testutil.CreateServer()
+	server.POST("/sellers", middleware.Idempotent(newMemoryStore()), func(ctx *gin.Context) {
+		close(started)
+		<-release
+		web.Success(ctx, http.StatusCreated, nil)
+	})
+	request := func() *http.Response {
+		req, res := testutil.MakeRequest(http.MethodPost, "/sellers", map[string]int{"cid": 1})
+		req.Header.Set(middleware.IDEMPOTENCY_KEY_HEADER, "a")
+		server.ServeHTTP(res, req)
+		return res.Result()
+	}
+
+	first := make(chan *http.Response)
+	go func() { first <- request() }()
+	<-started
+
+	concurrent := request()
+	close(release)
+	completed := <-first
+	retry := request()
+
+	assert.Equal(t, http.StatusConflict, concurrent.StatusCode)
+	assert.Equal(t, http.StatusCreated, completed.StatusCode)
+	assert.Equal(t, http.StatusCreated, retry.StatusCode)
+	assert.Equal(t, "true", retry.Header.Get("Idempotent-Replayed"))
+}
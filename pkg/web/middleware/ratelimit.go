@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/ratelimit"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+// Limits the requests of each client IP to the route with a token bucket
+// kept in store. Routes that share a name share the budget of a client.
+// Clients are told their budget in the RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers, and those that run
+// out of it get 429 with a Retry-After header. Requests are let through
+// if store fails.
+//
+// The client IP is the remote address of the request, or the one a
+// proxy forwarded if the engine trusts it (see SetTrustedProxies). No
+// header the client sets is trusted, since the API authenticates none.
+func RateLimit(store ratelimit.Store, name string, limit ratelimit.Limit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res, err := store.Take(c.Request.Context(), name+":"+c.ClientIP(), limit)
+		if err != nil {
+			c.Next()
+			return
+		}
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		if !res.Allowed {
+			retryAfter := ceilSeconds(res.RetryAfter)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			web.Error(c, http.StatusTooManyRequests, "rate limit exceeded, retry in %d seconds", retryAfter)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
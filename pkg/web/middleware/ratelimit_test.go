@@ -0,0 +1,93 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/ratelimit"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/testutil"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web"
+	"github.com/extmatperez/meli_bootcamp_go_w2-4/pkg/web/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, l ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store is down")
+}
+
+func TestRateLimit(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 2, Period: time.Minute}
+	ok := func(c *gin.Context) { web.Success(c, http.StatusOK, nil) }
+	newServer := func(store ratelimit.Store) *gin.Engine {
+		server := testutil.CreateServer()
+		server.SetTrustedProxies(nil)
+		server.GET("/products", middleware.RateLimit(store, "api", limit), ok)
+		server.GET("/reports", middleware.RateLimit(store, "reports", limit), ok)
+		return server
+	}
+	request := func(server *gin.Engine, url, ip string, headers ...string) *http.Response {
+		req, res := testutil.MakeRequest(http.MethodGet, url, nil)
+		req.RemoteAddr = ip + ":1234"
+		for i := 0; i < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		server.ServeHTTP(res, req)
+		return res.Result()
+	}
+
+	t.Run("Returns 429 once the budget runs out", func(t *testing.T) {
+		server := newServer(ratelimit.NewMemoryStore())
+
+		first := request(server, "/products", "10.0.0.1")
+		request(server, "/products", "10.0.0.1")
+		limited := request(server, "/products", "10.0.0.1")
+
+		assert.Equal(t, http.StatusOK, first.StatusCode)
+		assert.Equal(t, "2", first.Header.Get("RateLimit-Limit"))
+		assert.Equal(t, "1", first.Header.Get("RateLimit-Remaining"))
+		assert.Equal(t, "30", first.Header.Get("RateLimit-Reset"))
+		assert.Equal(t, http.StatusTooManyRequests, limited.StatusCode)
+		assert.Equal(t, "0", limited.Header.Get("RateLimit-Remaining"))
+		assert.Equal(t, "30", limited.Header.Get("Retry-After"))
+	})
+	t.Run("Keeps a budget per IP and per route name", func(t *testing.T) {
+		server := newServer(ratelimit.NewMemoryStore())
+
+		request(server, "/products", "10.0.0.1")
+		request(server, "/products", "10.0.0.1")
+
+		assert.Equal(t, http.StatusTooManyRequests, request(server, "/products", "10.0.0.1").StatusCode)
+		assert.Equal(t, http.StatusOK, request(server, "/products", "10.0.0.2").StatusCode)
+		assert.Equal(t, http.StatusOK, request(server, "/reports", "10.0.0.1").StatusCode)
+	})
+	t.Run("Ignores made up API keys", func(t *testing.T) {
+		server := newServer(ratelimit.NewMemoryStore())
+
+		request(server, "/products", "10.0.0.1", "X-API-Key", "a")
+		request(server, "/products", "10.0.0.1", "X-API-Key", "b")
+
+		assert.Equal(t, http.StatusTooManyRequests, request(server, "/products", "10.0.0.1", "X-API-Key", "c").StatusCode)
+	})
+	t.Run("Ignores forwarded IPs from untrusted proxies", func(t *testing.T) {
+		server := newServer(ratelimit.NewMemoryStore())
+
+		request(server, "/products", "10.0.0.1", "X-Forwarded-For", "1.1.1.1")
+		request(server, "/products", "10.0.0.1", "X-Forwarded-For", "2.2.2.2")
+
+		assert.Equal(t, http.StatusTooManyRequests, request(server, "/products", "10.0.0.1", "X-Forwarded-For", "3.3.3.3").StatusCode)
+	})
+	t.Run("Lets requests through if the store fails", func(t *testing.T) {
+		server := newServer(failingStore{})
+
+		res := request(server, "/products", "10.0.0.1")
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Empty(t, res.Header.Get("RateLimit-Limit"))
+	})
+}